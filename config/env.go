@@ -13,7 +13,9 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/lib/phi"
 	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/resource/storage"
 )
 
 const (
@@ -23,6 +25,9 @@ const (
 
 var appConfig *configuration
 
+// 読み込んだ設定ファイルの環境名。
+var serverEnv string
+
 // appConfiguration アプリケーション設定
 //  `.env.{SERVER_ENV}` ファイルに含まれる設定値を取得し管理する
 type configuration struct {
@@ -34,6 +39,8 @@ type configuration struct {
 	JWT        lib.JWTConfiguration
 	InfluxDB   lib.InfluxDBConfiguration
 	Assessment AssessmentConfiguration
+	Research   ResearchConfiguration
+	PHI        phi.Configuration
 }
 
 // ServerConfig サーバ設定情報。
@@ -64,6 +71,29 @@ type AssessmentConfiguration struct {
 	Delay      int
 }
 
+// 研究用データセット出力の設定。
+// Secretは仮名と日時のずらし量の生成に用いる鍵で、変更すると過去の出力と仮名が一致しなくなる。
+type ResearchConfiguration struct {
+	Secret    string
+	ShiftDays int `envconfig:"SHIFT_DAYS"`
+}
+
+// 研究用データセットの鍵として本番環境で必要な最短の長さ。
+const researchSecretMinimumLength int = 32
+
+// 研究用データセットの出力に必要な設定がされているか調べる。本番環境では十分な長さの鍵を必須とする。
+func (c *ResearchConfiguration) Validate() error {
+	if c.Secret == "" {
+		return fmt.Errorf("RESEARCH_SECRET is not configured")
+	} else if IsProduction() && len(c.Secret) < researchSecretMinimumLength {
+		return fmt.Errorf("RESEARCH_SECRET must be at least %d characters in production", researchSecretMinimumLength)
+	} else if c.ShiftDays < 0 {
+		return fmt.Errorf("RESEARCH_SHIFT_DAYS must not be negative")
+	}
+
+	return nil
+}
+
 func SetupAll() {
 	if appConfig == nil {
 		env := strings.ToLower(os.Getenv("SERVER_ENV"))
@@ -71,6 +101,8 @@ func SetupAll() {
 			env = "test"
 		}
 
+		serverEnv = env
+
 		root := os.Getenv("SERVER_ROOT")
 
 		paths := []string{path.Join(root, dataBasePath, ".env."+env)}
@@ -99,6 +131,8 @@ func SetupAll() {
 		load("jwt", &appConfig.JWT)
 		load("influxdb", &appConfig.InfluxDB)
 		load("ctg_assessment", &appConfig.Assessment)
+		load("research", &appConfig.Research)
+		load("phi", &appConfig.PHI)
 
 		if env != "test" {
 			log.Println(&appConfig.DB)
@@ -119,13 +153,32 @@ func SetupAll() {
 		if err := lib.SetupInfluxDB(&appConfig.InfluxDB); err != nil {
 			log.Fatalf("Failed to setup influxDB %v\n", err.Error())
 		}
+
+		storage.SetupStorage(
+			appConfig.S3Config.Region,
+			appConfig.S3Config.Endpoint,
+			appConfig.S3Config.Bucket,
+			appConfig.S3Config.Expires,
+			appConfig.S3Config.ExpiresForPush,
+		)
+
 		if err := lib.SetupAuthentication(&appConfig.JWT); err != nil {
 			log.Fatalf("Failed to setup authentication %v\n", err.Error())
 		}
 
+		// 本番環境では患者情報を平文で保存しないよう、鍵が無ければ起動しない。
+		if IsProduction() && appConfig.PHI.MasterKey == "" {
+			log.Fatalln("PHI_MASTER_KEY must be set in production")
+		}
+
+		if err := phi.Setup(&appConfig.PHI); err != nil {
+			log.Fatalf("Failed to setup PHI encryption %v\n", err.Error())
+		}
+
 		lib.SetupI18n(&appConfig.Lang)
 
 		model.SetupModels()
+		model.SetupAdditionalModels()
 
 		setLogger()
 	}
@@ -158,4 +211,13 @@ func ServerConfig() *ServerConfiguration {
 
 func AssessmentConfig() *AssessmentConfiguration {
 	return &appConfig.Assessment
-}
\ No newline at end of file
+}
+
+// 本番環境の設定を読み込んでいるかを返す。
+func IsProduction() bool {
+	return serverEnv == "prod"
+}
+
+func ResearchConfig() *ResearchConfiguration {
+	return &appConfig.Research
+}
@@ -42,3 +42,20 @@ func TestEnv_Localizer(t *testing.T) {
 	localizer = lib.NewLocalizer("en")
 	assert.Equal(t, "Hello World!", localizer.Localize("hello world!", nil))
 }
+
+func TestEnv_ResearchValidate(t *testing.T) {
+	SetupAll()
+
+	assert.NoError(t, ResearchConfig().Validate())
+	assert.Error(t, (&ResearchConfiguration{Secret: "", ShiftDays: 365}).Validate())
+	assert.Error(t, (&ResearchConfiguration{Secret: "secret", ShiftDays: -1}).Validate())
+
+	// 本番環境では短い鍵を受け付けない。
+	env := serverEnv
+	defer func() { serverEnv = env }()
+
+	serverEnv = "prod"
+
+	assert.Error(t, (&ResearchConfiguration{Secret: "research-secret-test", ShiftDays: 365}).Validate())
+	assert.NoError(t, (&ResearchConfiguration{Secret: "research-secret-for-production-use", ShiftDays: 365}).Validate())
+}
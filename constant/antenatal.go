@@ -0,0 +1,51 @@
+package constant
+
+import (
+	"time"
+)
+
+// 分娩前のNST解析(Dawes-Redman基準)関連。
+const (
+	// 拍動間隔を平均する単位(1/16分)。
+	AntenatalEpoch time.Duration = time.Duration(3750) * time.Millisecond
+	// 1分あたりのエポック数。
+	AntenatalEpochsPerMinute int = 16
+	// 解析の既定の対象期間。また、対象期間の上限。
+	AntenatalMaximumDuration time.Duration = time.Duration(60) * time.Minute
+	// 基準を満たすために必要な最短の記録時間。
+	AntenatalMinimumDuration time.Duration = time.Duration(10) * time.Minute
+	// 有効な心拍数の範囲。
+	AntenatalMinimumBpm int = 50
+	AntenatalMaximumBpm int = 210
+	// 1分間の拍動間隔の範囲がこの値(ms)以上で高変動、未満で低変動とみなす。
+	AntenatalHighVariation float64 = 32
+	AntenatalLowVariation  float64 = 30
+	// 6分間のうちこの分数以上が高変動(低変動)であればエピソードとする。
+	AntenatalEpisodeWindow  int = 6
+	AntenatalEpisodeMinutes int = 5
+	// 基準心拍数の範囲。
+	AntenatalMinimumBasalRate float64 = 116
+	AntenatalMaximumBasalRate float64 = 160
+	// STVの下限(ms)。加速が無い場合はより高い値を要する。
+	AntenatalMinimumSTV                    float64 = 3.0
+	AntenatalMinimumSTVWithoutAcceleration float64 = 4.5
+	// 一過性頻脈、一過性徐脈とみなす基準心拍数からの変化幅(bpm)と持続時間。
+	AntenatalAccelerationBpm      float64       = 10
+	AntenatalAccelerationDuration time.Duration = time.Duration(15) * time.Second
+	AntenatalDecelerationBpm      float64       = 10
+	AntenatalDecelerationDuration time.Duration = time.Duration(60) * time.Second
+	// 一過性徐脈の失われた拍動数がこの値を超える場合、基準を満たさない。
+	AntenatalMaximumLostBeats float64 = 20
+	// 信号損失の割合の上限。
+	AntenatalMaximumSignalLoss float64 = 0.3
+)
+
+// NST基準を満たさない理由。
+const (
+	AntenatalReasonInsufficientDuration = "insufficient_duration"
+	AntenatalReasonSignalLoss           = "signal_loss"
+	AntenatalReasonBasalRate            = "basal_rate_out_of_range"
+	AntenatalReasonNoHighVariation      = "no_high_variation"
+	AntenatalReasonLowSTV               = "low_stv"
+	AntenatalReasonLargeDeceleration    = "large_deceleration"
+)
@@ -0,0 +1,38 @@
+package constant
+
+// 臨床イベントの種別。
+type ClinicalEventCategory string
+
+const (
+	ClinicalEventOxytocinStart   ClinicalEventCategory = "OXYTOCIN_START"   // 子宮収縮薬投与開始。
+	ClinicalEventOxytocinChange  ClinicalEventCategory = "OXYTOCIN_CHANGE"  // 子宮収縮薬投与量変更。
+	ClinicalEventOxytocinStop    ClinicalEventCategory = "OXYTOCIN_STOP"    // 子宮収縮薬投与終了。
+	ClinicalEventEpidural        ClinicalEventCategory = "EPIDURAL"         // 硬膜外麻酔。
+	ClinicalEventVaginalExam     ClinicalEventCategory = "VAGINAL_EXAM"     // 内診。所見はメモに記録する。
+	ClinicalEventMembraneRupture ClinicalEventCategory = "MEMBRANE_RUPTURE" // 破水。
+	ClinicalEventPositionChange  ClinicalEventCategory = "POSITION_CHANGE"  // 体位変換。
+	ClinicalEventMedication      ClinicalEventCategory = "MEDICATION"       // その他の投薬。
+	ClinicalEventOther           ClinicalEventCategory = "OTHER"            // その他。
+)
+
+var ClinicalEventCategories = []ClinicalEventCategory{
+	ClinicalEventOxytocinStart,
+	ClinicalEventOxytocinChange,
+	ClinicalEventOxytocinStop,
+	ClinicalEventEpidural,
+	ClinicalEventVaginalExam,
+	ClinicalEventMembraneRupture,
+	ClinicalEventPositionChange,
+	ClinicalEventMedication,
+	ClinicalEventOther,
+}
+
+// 臨床イベントの種別として有効か調べる。
+func (c ClinicalEventCategory) IsValid() bool {
+	for _, x := range ClinicalEventCategories {
+		if x == c {
+			return true
+		}
+	}
+	return false
+}
@@ -20,6 +20,14 @@ const (
 	MeasurementTypeTOCO      MeasurementType = "toco"
 )
 
+// 計測の状態。
+type MeasurementState string
+
+const (
+	MeasurementStateOpen   MeasurementState = "open"
+	MeasurementStateClosed MeasurementState = "closed"
+)
+
 // アラート関連。
 const (
 	AlertBackingDuration     time.Duration = time.Duration(60) * time.Minute
@@ -27,4 +35,120 @@ const (
 	AlertExecutionDuration   time.Duration = time.Duration(30) * time.Second
 	AlertSilentDuration      time.Duration = time.Duration(5) * time.Minute
 	AlertMeasurementInterval time.Duration = time.Duration(5) * time.Minute
-)
\ No newline at end of file
+)
+
+// リスク。
+const (
+	RiskMinimum int = 1
+	RiskMaximum int = 5
+)
+
+// 転帰分析関連。
+const (
+	// 出生前の集計期間の既定値と上限(分)。
+	OutcomeDefaultMinutes int = 30
+	OutcomeMaximumMinutes int = 24 * 60
+)
+
+// データ集約関連。
+const (
+	// 集約期間の上限(秒)。
+	DownsamplingMaximumResolution int = 60 * 60
+	// 集約後の点数として指定できる上限。
+	DownsamplingMaximumPoints int = 100000
+)
+
+// 一括データ取得関連。
+const (
+	// 指定できる計測数の上限。
+	DataBatchMaximumMeasurements int = 20
+	// 取得できる期間の上限。
+	DataBatchMaximumDuration time.Duration = time.Duration(6) * time.Hour
+)
+
+// CTG記録紙関連。
+const (
+	// 記録紙の送り速度の既定値(cm/分)。
+	ReportDefaultSpeed int = 3
+	// 1つの記録紙に出力できる期間の上限。
+	ReportMaximumDuration time.Duration = time.Duration(24) * time.Hour
+)
+
+// イベントのスナップショット関連。
+const (
+	// スナップショットとして描画する期間。
+	SnapshotDuration time.Duration = time.Duration(30) * time.Minute
+	// スナップショットの記録紙の送り速度(cm/分)と解像度(画素/mm)。
+	SnapshotSpeed     int     = 1
+	SnapshotDotsPerMM float64 = 4
+)
+
+// エクスポート形式。
+type ExportFormat string
+
+const (
+	ExportFormatEDF ExportFormat = "edf"
+	ExportFormatCSV ExportFormat = "csv"
+)
+
+// エクスポート関連。
+const (
+	// 1つの計測について出力できる期間の上限。
+	ExportMaximumDuration time.Duration = time.Duration(72) * time.Hour
+	// 一括で出力できる計測数の上限。
+	ExportMaximumMeasurements int = 20
+)
+
+// FHIR API関連。
+const (
+	// 検索結果の件数の既定値と上限。
+	FHIRDefaultCount int = 100
+	FHIRMaximumCount int = 1000
+	// Observationの検索で1度に返す計測数の上限。Observationは期間中の全ての値を含むため、他の検索より小さくする。
+	FHIRObservationMaximumCount int = 10
+)
+
+// 計測データの保存期間関連。
+const (
+	// 病院に設定できる保存期間の上限(年)。
+	RetentionMaximumYears int = 100
+	// 復元した計測データを再びアーカイブするまでの期間。
+	RetentionRestoredDuration time.Duration = time.Duration(30*24) * time.Hour
+)
+
+// 計測の自動完了関連。
+const (
+	// 自動完了するまでの無受信期間(分)の既定値と、病院に設定できる範囲。
+	AutoCloseDefaultMinutes int = 12 * 60
+	AutoCloseMinimumMinutes int = 10
+	AutoCloseMaximumMinutes int = 7 * 24 * 60
+	// 自動完了した計測の完了メモ。
+	AutoCloseMemo string = "一定期間データを受信しなかったため、自動的に完了しました。"
+)
+
+// 一覧のソートに指定できる列。降順の場合は先頭に"-"を付ける。
+var (
+	MeasurementSorts = []string{"id", "createdAt", "firstTime", "lastTime"}
+	PatientSorts     = []string{"id", "createdAt", "lastTime"}
+	TerminalSorts    = []string{"id", "code", "name"}
+	HospitalSorts    = []string{"id", "name"}
+	DoctorSorts      = []string{"id", "loginId", "name"}
+)
+
+// 研究用データセットの出力ジョブの状態。
+type ResearchExportState string
+
+const (
+	ResearchExportPending   ResearchExportState = "pending"
+	ResearchExportRunning   ResearchExportState = "running"
+	ResearchExportCompleted ResearchExportState = "completed"
+	ResearchExportFailed    ResearchExportState = "failed"
+)
+
+// 研究用データセット関連。
+const (
+	// 1つの出力ジョブで指定できる病院数の上限。
+	ResearchExportMaximumHospitals int = 50
+	// データセットの形式の版。列の構成を変更した場合に上げる。
+	ResearchExportVersion int = 1
+)
@@ -0,0 +1,37 @@
+package constant
+
+import (
+	"time"
+)
+
+// 子宮収縮検出関連。
+const (
+	// 静止圧からの上昇幅がこの値以上の区間を収縮とみなす。
+	ContractionThreshold int = 15
+	// 収縮とみなす最短の持続時間。
+	ContractionMinimumDuration time.Duration = time.Duration(30) * time.Second
+	// これより短い閾値未満の区間は、前後の収縮を一続きとみなす。
+	ContractionMergingGap time.Duration = time.Duration(10) * time.Second
+	// 静止圧とみなす分位点。
+	ContractionRestingQuantile float64 = 0.25
+	// 収縮頻度の集計単位。
+	ContractionFrequencyUnit time.Duration = time.Duration(10) * time.Minute
+	// 頻収縮判定のために収縮頻度を平均する期間。
+	ContractionAveragingDuration time.Duration = time.Duration(30) * time.Minute
+	// 一過性徐脈の中央が収縮の頂点からこの時間以上遅れる場合、遅発とみなす。
+	DecelerationLateLag time.Duration = time.Duration(20) * time.Second
+)
+
+// 頻収縮を表す自動診断イベントのパラメータキー。
+const CTG_Tachysystole = "Tachysystole"
+
+// 子宮収縮との時間関係による一過性徐脈の分類。
+type DecelerationTiming string
+
+const (
+	DecelerationTimingEarly DecelerationTiming = "EARLY"
+	DecelerationTimingLate  DecelerationTiming = "LATE"
+)
+
+// 頻収縮イベントのリスク。JSOGの分類では頻収縮そのものの区分がないため、アラート対象となる値とする。
+const TachysystoleRisk int = AlertRiskThreshold
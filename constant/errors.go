@@ -86,4 +86,7 @@ func DB_OPERATION_ERROR(e error) *InternalServerError {
 
 func INFLUXDB_OPERATION_ERROR(e error) *InternalServerError {
 	return NewInternalServerError("influxdb_operation_failed", e.Error(), map[string]interface{}{})
-}
\ No newline at end of file
+}
+func STORAGE_OPERATION_ERROR(e error) *InternalServerError {
+	return NewInternalServerError("storage_operation_failed", e.Error(), map[string]interface{}{})
+}
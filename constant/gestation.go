@@ -0,0 +1,111 @@
+package constant
+
+// 在胎週数による判読基準の区分。
+type GestationalBand string
+
+const (
+	GestationalBandEarlyPreterm GestationalBand = "EARLY_PRETERM" // 32週未満。
+	GestationalBandPreterm      GestationalBand = "PRETERM"       // 32週以上37週未満。
+	GestationalBandTerm         GestationalBand = "TERM"          // 37週以上。
+	GestationalBandUnknown      GestationalBand = "UNKNOWN"       // 在胎週数が不明。正期産の基準で判読する。
+)
+
+const (
+	// 32週、37週の日数。
+	EarlyPretermDays int = 32 * 7
+	TermDays         int = 37 * 7
+	// これ未満の基線を高度徐脈とみなす。
+	SevereBradycardiaBpm int = 80
+	// これ以下の基線細変動を消失とみなす。
+	VariabilityLostBpm int = 2
+)
+
+// 区分毎の正常範囲。いずれも両端を含む。
+type GestationalThresholds struct {
+	BaselineMinimum    int
+	BaselineMaximum    int
+	VariabilityMinimum int
+	VariabilityMaximum int
+}
+
+var gestationalThresholds = map[GestationalBand]*GestationalThresholds{
+	// 早期早産では基線が高く、基線細変動が小さい。
+	GestationalBandEarlyPreterm: &GestationalThresholds{120, 170, 3, 25},
+	GestationalBandPreterm:      &GestationalThresholds{110, 165, 5, 25},
+	GestationalBandTerm:         &GestationalThresholds{110, 160, 6, 25},
+	GestationalBandUnknown:      &GestationalThresholds{110, 160, 6, 25},
+}
+
+// 在胎日数から区分を決定する。
+func GestationalBandOf(days *int) GestationalBand {
+	switch {
+	case days == nil:
+		return GestationalBandUnknown
+	case *days < EarlyPretermDays:
+		return GestationalBandEarlyPreterm
+	case *days < TermDays:
+		return GestationalBandPreterm
+	default:
+		return GestationalBandTerm
+	}
+}
+
+// 区分の正常範囲。
+func (b GestationalBand) Thresholds() *GestationalThresholds {
+	if t, be := gestationalThresholds[b]; be {
+		return t
+	}
+	return gestationalThresholds[GestationalBandUnknown]
+}
+
+// 自動診断の判定をそのまま用いず、区分の正常範囲で判定し直すか調べる。
+// 自動診断は正期産の基準で判定されるため、早産の区分でのみ判定し直す。
+func (b GestationalBand) Reinterprets() bool {
+	return b == GestationalBandEarlyPreterm || b == GestationalBandPreterm
+}
+
+// 基線の種別を、基線と基線細変動の値から区分の正常範囲で判定し直した新しい基線を返す。
+// 判定し直さない区分では元の基線を返す。正弦波様は値によらないため維持する。
+func (b GestationalBand) Interpret(baseline *Baseline) *Baseline {
+	if baseline == nil || !b.Reinterprets() {
+		return baseline
+	}
+
+	t := b.Thresholds()
+
+	result := &Baseline{
+		Type: CTG_BaselineNormal,
+		Value: baseline.Value,
+	}
+
+	switch {
+	case baseline.Value < SevereBradycardiaBpm:
+		result.Type = CTG_BaselineHiDeceleration
+	case baseline.Value < t.BaselineMinimum:
+		result.Type = CTG_BaselineDeceleration
+	case baseline.Value > t.BaselineMaximum:
+		result.Type = CTG_BaselineAcceleration
+	}
+
+	if v := baseline.Variability; v != nil {
+		variability := &BaselineVariability{
+			Type: CTG_BaselineVariabilityNormal,
+			Value: v.Value,
+		}
+
+		switch {
+		case v.Type == CTG_BaselineVariabilitySinusoidal:
+			variability.Type = CTG_BaselineVariabilitySinusoidal
+		case v.Value <= VariabilityLostBpm:
+			variability.Type = CTG_BaselineVariabilityLost
+		case v.Value < t.VariabilityMinimum:
+			variability.Type = CTG_BaselineVariabilityDecrease
+		case v.Value > t.VariabilityMaximum:
+			variability.Type = CTG_BaselineVariabilityIncrease
+		}
+
+		result.Variability = variability
+	}
+
+	return result
+}
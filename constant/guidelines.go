@@ -0,0 +1,297 @@
+package constant
+
+// 判読ガイドライン。
+type Guideline string
+
+const (
+	GuidelineJSOG Guideline = "JSOG"     // 日本産科婦人科学会 5段階分類。リスク値そのもの。
+	GuidelineFIGO Guideline = "FIGO2015" // FIGO 2015 normal/suspicious/pathological。
+	GuidelineNICE Guideline = "NICE"     // NICE normal/suspicious/pathological。
+	GuidelineACOG Guideline = "ACOG"     // ACOG Category I-III。
+)
+
+var (
+	Guidelines = []Guideline{
+		GuidelineJSOG,
+		GuidelineFIGO,
+		GuidelineNICE,
+		GuidelineACOG,
+	}
+
+	// JSOG以外の、所見から分類を算出するガイドライン。
+	ClassifiedGuidelines = []Guideline{
+		GuidelineFIGO,
+		GuidelineNICE,
+		GuidelineACOG,
+	}
+)
+
+// 分類カテゴリ。
+const (
+	CategoryNormal       = "NORMAL"
+	CategorySuspicious   = "SUSPICIOUS"
+	CategoryPathological = "PATHOLOGICAL"
+	CategoryI            = "CATEGORY_I"
+	CategoryII           = "CATEGORY_II"
+	CategoryIII          = "CATEGORY_III"
+)
+
+// 子宮収縮頻度(回/10分)の上限。これを超えると頻収縮とみなす。
+const TachysystoleThreshold float64 = 5
+
+// ガイドラインとして有効か調べる。
+func (g Guideline) IsValid() bool {
+	for _, x := range Guidelines {
+		if x == g {
+			return true
+		}
+	}
+	return false
+}
+
+// アラート対象となる分類レベルの下限。
+func (g Guideline) AlertLevel() int {
+	switch g {
+	case GuidelineJSOG:
+		return AlertRiskThreshold
+	default:
+		return 2
+	}
+}
+
+// JSOGのリスクを分類レベルに対応付けたとき、指定したレベル以上となる最小のリスク。
+// リスク1~2を正常、3を要注意、4~5を病的とみなす。JSOGではレベルがそのままリスクとなる。
+func (g Guideline) MinimumRiskForLevel(level int) int {
+	if g == GuidelineJSOG {
+		return level
+	}
+
+	switch {
+	case level <= 1:
+		return 1
+	case level == 2:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// 分類に用いる所見。基線は基線細変動を含み、一過性徐脈と同時に評価される。
+// Contractionsは10分あたりの子宮収縮回数、Timingは一過性徐脈の子宮収縮に対する時間関係。
+// Accelerationは一過性頻脈が認められた場合に設定される。
+type CTGFindings struct {
+	Baseline     *Baseline
+	Deceleration *DecelerationType
+	Acceleration *AccelerationType
+	Timing       *DecelerationTiming
+	Contractions *float64
+}
+
+// 子宮収縮との時間関係を考慮した一過性徐脈の種別。
+// 早発と判定されたものが遅れていれば軽度遅発、遅発と判定されたものが遅れていなければ早発とみなす。
+func (f *CTGFindings) EffectiveDeceleration() *DecelerationType {
+	if f.Deceleration == nil || f.Timing == nil {
+		return f.Deceleration
+	}
+
+	t := *f.Deceleration
+
+	switch {
+	case t == CTG_DecelerationED && *f.Timing == DecelerationTimingLate:
+		t = CTG_DecelerationLowLD
+	case (t == CTG_DecelerationLowLD || t == CTG_DecelerationHiLD) && *f.Timing == DecelerationTimingEarly:
+		t = CTG_DecelerationED
+	}
+
+	return &t
+}
+
+// ガイドラインによる分類結果。レベルはカテゴリの重症度順の序数で、1が正常。
+type Classification struct {
+	Guideline Guideline
+	Category  string
+	Level     int
+}
+
+// 所見をガイドラインに従って分類する。分類できない場合はnilを返す。
+func Classify(guideline Guideline, findings *CTGFindings) *Classification {
+	if findings == nil || findings.Baseline == nil || findings.Baseline.Variability == nil {
+		return nil
+	}
+
+	switch guideline {
+	case GuidelineFIGO:
+		return classifyFIGO(findings)
+	case GuidelineNICE:
+		return classifyNICE(findings)
+	case GuidelineACOG:
+		return classifyACOG(findings)
+	default:
+		return nil
+	}
+}
+
+// 所見の評価。
+type featureAssessment int
+
+const (
+	featureReassuring featureAssessment = iota
+	featureNonReassuring
+	featureAbnormal
+)
+
+func assessBaseline(t BaselineType) featureAssessment {
+	switch t {
+	case CTG_BaselineNormal:
+		return featureReassuring
+	case CTG_BaselineHiDeceleration:
+		return featureAbnormal
+	default:
+		return featureNonReassuring
+	}
+}
+
+// 一過性頻脈を伴う基線細変動の減少は、胎児の状態が良好であることを示すため正常とみなす(FIGO 2015、NICE)。
+func assessVariability(t BaselineVariabilityType, acceleration *AccelerationType) featureAssessment {
+	switch t {
+	case CTG_BaselineVariabilityNormal:
+		return featureReassuring
+	case CTG_BaselineVariabilityLost, CTG_BaselineVariabilitySinusoidal:
+		return featureAbnormal
+	case CTG_BaselineVariabilityDecrease:
+		if acceleration != nil {
+			return featureReassuring
+		}
+		return featureNonReassuring
+	default:
+		return featureNonReassuring
+	}
+}
+
+func assessDeceleration(t *DecelerationType) featureAssessment {
+	if t == nil {
+		return featureReassuring
+	}
+
+	switch *t {
+	case CTG_DecelerationNone, CTG_DecelerationED:
+		return featureReassuring
+	case CTG_DecelerationHiLD, CTG_DecelerationHiVD, CTG_DecelerationLowPD, CTG_DecelerationHiPD:
+		return featureAbnormal
+	default:
+		return featureNonReassuring
+	}
+}
+
+// 10分あたりの子宮収縮回数が頻収縮にあたるか調べる。
+func IsTachysystole(contractions *float64) bool {
+	return contractions != nil && *contractions > TachysystoleThreshold
+}
+
+// 頻収縮そのものの分類。いずれのガイドラインでも要注意(Category II)となる。
+func ClassifyTachysystole(guideline Guideline) *Classification {
+	switch guideline {
+	case GuidelineFIGO:
+		return &Classification{GuidelineFIGO, CategorySuspicious, 2}
+	case GuidelineNICE:
+		return &Classification{GuidelineNICE, CategorySuspicious, 2}
+	case GuidelineACOG:
+		return &Classification{GuidelineACOG, CategoryII, 2}
+	default:
+		return nil
+	}
+}
+
+// FIGO 2015。いずれかの所見が病的ならpathological、正常でない所見があればsuspicious。
+func classifyFIGO(f *CTGFindings) *Classification {
+	assessments := []featureAssessment{
+		assessBaseline(f.Baseline.Type),
+		assessVariability(f.Baseline.Variability.Type, f.Acceleration),
+		assessDeceleration(f.EffectiveDeceleration()),
+	}
+
+	if IsTachysystole(f.Contractions) {
+		assessments = append(assessments, featureNonReassuring)
+	}
+
+	worst := featureReassuring
+	for _, a := range assessments {
+		if a > worst {
+			worst = a
+		}
+	}
+
+	switch worst {
+	case featureAbnormal:
+		return &Classification{GuidelineFIGO, CategoryPathological, 3}
+	case featureNonReassuring:
+		return &Classification{GuidelineFIGO, CategorySuspicious, 2}
+	default:
+		return &Classification{GuidelineFIGO, CategoryNormal, 1}
+	}
+}
+
+// NICE。異常所見が1つ、または非安心所見が2つ以上でpathological、非安心所見が1つでsuspicious。
+func classifyNICE(f *CTGFindings) *Classification {
+	assessments := []featureAssessment{
+		assessBaseline(f.Baseline.Type),
+		assessVariability(f.Baseline.Variability.Type, f.Acceleration),
+		assessDeceleration(f.EffectiveDeceleration()),
+	}
+
+	if IsTachysystole(f.Contractions) {
+		assessments = append(assessments, featureNonReassuring)
+	}
+
+	abnormal := 0
+	nonReassuring := 0
+
+	for _, a := range assessments {
+		switch a {
+		case featureAbnormal:
+			abnormal++
+		case featureNonReassuring:
+			nonReassuring++
+		}
+	}
+
+	if abnormal > 0 || nonReassuring >= 2 {
+		return &Classification{GuidelineNICE, CategoryPathological, 3}
+	} else if nonReassuring == 1 {
+		return &Classification{GuidelineNICE, CategorySuspicious, 2}
+	} else {
+		return &Classification{GuidelineNICE, CategoryNormal, 1}
+	}
+}
+
+// ACOG。正弦波様、または基線細変動消失に遅発・変動一過性徐脈か徐脈を伴う場合はCategory III、
+// 正常基線、中等度の基線細変動で、早発一過性徐脈以外の一過性徐脈が無い場合はCategory I、それ以外はCategory II。
+// ACOGでは一過性頻脈の有無はCategory Iの要件ではないが、基線細変動の減少を伴う場合はCategory IIに留まる。
+func classifyACOG(f *CTGFindings) *Classification {
+	variability := f.Baseline.Variability.Type
+
+	deceleration := f.EffectiveDeceleration()
+
+	recurrent := false
+	if deceleration != nil {
+		switch *deceleration {
+		case CTG_DecelerationLowLD, CTG_DecelerationHiLD, CTG_DecelerationLowVD, CTG_DecelerationHiVD:
+			recurrent = true
+		}
+	}
+
+	bradycardia := f.Baseline.Type == CTG_BaselineDeceleration || f.Baseline.Type == CTG_BaselineHiDeceleration
+
+	if variability == CTG_BaselineVariabilitySinusoidal ||
+		(variability == CTG_BaselineVariabilityLost && (recurrent || bradycardia)) {
+		return &Classification{GuidelineACOG, CategoryIII, 3}
+	}
+
+	if f.Baseline.Type == CTG_BaselineNormal &&
+		variability == CTG_BaselineVariabilityNormal &&
+		assessDeceleration(deceleration) == featureReassuring {
+		return &Classification{GuidelineACOG, CategoryI, 1}
+	}
+
+	return &Classification{GuidelineACOG, CategoryII, 2}
+}
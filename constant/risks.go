@@ -1,5 +1,9 @@
 package constant
 
+import (
+	"fmt"
+)
+
 // 診断記録。
 type CTGEventType string
 type BaselineType CTGEventType
@@ -128,7 +132,32 @@ func (e Acceleration) EventType() CTGEventType {
 	return CTGEventType(e.Type)
 }
 
+// リスク判定表。基線、基線細変動、一過性徐脈の組み合わせからリスクを決める。
+// 行列は基線(BaselineEvents)と一過性徐脈(DecelerationEvents)の順に並び、-1は該当なしを表す。
+type RiskMatrices struct {
+	VariabilityNormal     [][]int `json:"variabilityNormal"`
+	VariabilityDecrease   [][]int `json:"variabilityDecrease"`
+	VariabilityLost       []int   `json:"variabilityLost"`
+	VariabilityIncrease   []int   `json:"variabilityIncrease"`
+	VariabilitySinusoidal []int   `json:"variabilitySinusoidal"`
+}
+
+// 既定のリスク判定表。病院にリスクテーブルが設定されていない場合に利用する。
+var DefaultRiskMatrices = &RiskMatrices{
+	VariabilityNormal: VariabilityNormalRisks,
+	VariabilityDecrease: VariabilityDecreaseRisks,
+	VariabilityLost: VariabilityLostRisks,
+	VariabilityIncrease: VariabilityIncreaseRisks,
+	VariabilitySinusoidal: VariabilitySinusoidalRisks,
+}
+
+// 既定のリスク判定表からリスクを取得する。
 func GetRisk(baseline BaselineType, variability BaselineVariabilityType, deceleration DecelerationType) int {
+	return DefaultRiskMatrices.GetRisk(baseline, variability, deceleration)
+}
+
+// リスクを取得する。該当しない組み合わせの場合は-1を返す。
+func (m *RiskMatrices) GetRisk(baseline BaselineType, variability BaselineVariabilityType, deceleration DecelerationType) int {
 	bi := -1
 
 	for i, evt := range BaselineEvents {
@@ -153,16 +182,61 @@ func GetRisk(baseline BaselineType, variability BaselineVariabilityType, deceler
 
 	switch variability {
 	case CTG_BaselineVariabilityNormal:
-		return VariabilityNormalRisks[bi][di]
+		return m.VariabilityNormal[bi][di]
 	case CTG_BaselineVariabilityDecrease:
-		return VariabilityDecreaseRisks[bi][di]
+		return m.VariabilityDecrease[bi][di]
 	case CTG_BaselineVariabilityLost:
-		return VariabilityLostRisks[di]
+		return m.VariabilityLost[di]
 	case CTG_BaselineVariabilityIncrease:
-		return VariabilityIncreaseRisks[di]
+		return m.VariabilityIncrease[di]
 	case CTG_BaselineVariabilitySinusoidal:
-		return VariabilitySinusoidalRisks[di]
+		return m.VariabilitySinusoidal[di]
 	default:
 		return -1
 	}
-}
\ No newline at end of file
+}
+
+// 行列の形状と値の範囲を検証する。
+func (m *RiskMatrices) Validate() error {
+	checkRow := func(name string, row []int) error {
+		if len(row) != len(DecelerationEvents) {
+			return fmt.Errorf("%s must have %d columns", name, len(DecelerationEvents))
+		}
+		for _, r := range row {
+			if r != -1 && (r < RiskMinimum || r > RiskMaximum) {
+				return fmt.Errorf("%s must consist of -1 or %d to %d", name, RiskMinimum, RiskMaximum)
+			}
+		}
+		return nil
+	}
+
+	checkMatrix := func(name string, matrix [][]int) error {
+		if len(matrix) != len(BaselineEvents) {
+			return fmt.Errorf("%s must have %d rows", name, len(BaselineEvents))
+		}
+		for _, row := range matrix {
+			if e := checkRow(name, row); e != nil {
+				return e
+			}
+		}
+		return nil
+	}
+
+	if e := checkMatrix("variabilityNormal", m.VariabilityNormal); e != nil {
+		return e
+	}
+	if e := checkMatrix("variabilityDecrease", m.VariabilityDecrease); e != nil {
+		return e
+	}
+	if e := checkRow("variabilityLost", m.VariabilityLost); e != nil {
+		return e
+	}
+	if e := checkRow("variabilityIncrease", m.VariabilityIncrease); e != nil {
+		return e
+	}
+	if e := checkRow("variabilitySinusoidal", m.VariabilitySinusoidal); e != nil {
+		return e
+	}
+
+	return nil
+}
@@ -0,0 +1,282 @@
+// 超音波ドップラーで計測された胎児心拍のアーチファクトを検出、補正する。
+//
+// 検出するアーチファクトは以下の通り。
+//   - 信号損失: 心拍が得られていない0以下の値と、上限を超える値。
+//   - 半減、倍加: 直前の心拍に対しておよそ1/2、2倍の値が短時間だけ現れ、元の心拍に戻るもの。倍数の誤検出によるもの。
+//   - 母体心拍: 急激な低下の後、母体心拍の範囲で一定時間以上持続する区間。胎児ではなく母体の脈拍を拾ったもの。
+//
+// 持続する低下や下限を下回る心拍は重度の徐脈である可能性があるため、アーチファクトとはみなさない。
+package artifact
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// アーチファクト種別のビットマスク。
+type Flag int
+
+const (
+	FlagLoss Flag = 1 << iota
+	FlagHalving
+	FlagDoubling
+	FlagMaternal
+)
+
+const FlagNone Flag = 0
+
+// フラグを含むか調べる。
+func (f Flag) Has(flag Flag) bool {
+	return f & flag != 0
+}
+
+const (
+	// 半減、倍加の判定に用いる胎児心拍の範囲。下限を下回る心拍はそのまま残し、上限を超える心拍は信号損失とする。
+	MinimumBpm int = 50
+	MaximumBpm int = 240
+	// 参照値とする直前の有効な心拍の数と、参照可能な時間。
+	ReferenceCount  int           = 10
+	ReferenceWindow time.Duration = time.Duration(30) * time.Second
+	// 半減、倍加とみなす比率の許容幅。
+	HalvingTolerance  float64 = 0.05
+	DoublingTolerance float64 = 0.1
+	// 半減、倍加とみなす最長の持続時間と、元の心拍に戻ったとみなす比率の許容幅。
+	MultipleMaximumDuration time.Duration = time.Duration(5) * time.Second
+	RecoveryTolerance       float64       = 0.15
+	// 母体心拍とみなす範囲。
+	MaternalMinimumBpm int = 50
+	MaternalMaximumBpm int = 110
+	// 母体心拍への切り替わりとみなす急激な変化幅と、その変化が起こる最大の間隔。
+	MaternalJump         int           = 30
+	MaternalJumpInterval time.Duration = time.Duration(2) * time.Second
+	// 母体心拍の区間内での変化の許容幅。
+	MaternalTolerance float64 = 15
+	// 母体心拍とみなす最短の持続時間。
+	MaternalMinimumDuration time.Duration = time.Duration(2) * time.Minute
+)
+
+// 心拍の標本。
+type Sample struct {
+	Value     int
+	Timestamp time.Time
+}
+
+// 時系列順に並んだ標本のアーチファクトを検出する。結果は標本と同じ順序で並ぶ。
+func Detect(samples []Sample) []Flag {
+	return DetectContinued(samples, nil)
+}
+
+// 判定済みのフラグを引き継いで、時系列順に並んだ標本のアーチファクトを検出する。結果は標本と同じ順序で並ぶ。
+// knownは先頭からの標本の判定済みのフラグで、標本より短くてもよい。
+// 判定済みの母体心拍は結果に引き継がれ、その区間に続く標本は、区間の始まりが標本に含まれなくても同じ区間の続きとして判定する。
+func DetectContinued(samples []Sample, known []Flag) []Flag {
+	flags := make([]Flag, len(samples))
+
+	for i, f := range known {
+		if i < len(flags) {
+			flags[i] = f & FlagMaternal
+		}
+	}
+
+	detectMultiples(samples, flags)
+	detectMaternal(samples, flags)
+
+	return flags
+}
+
+// アーチファクトを補正した値を返す。補正できないアーチファクトの場合はfalseを返す。
+func Correct(value int, flag Flag) (int, bool) {
+	switch {
+	case flag.Has(FlagLoss) || flag.Has(FlagMaternal):
+		return 0, false
+	case flag.Has(FlagHalving):
+		return value * 2, true
+	case flag.Has(FlagDoubling):
+		return value / 2, true
+	default:
+		return value, true
+	}
+}
+
+// 信号損失、半減、倍加を検出する。参照値はアーチファクトでない直前の心拍の中央値とする。
+// 半減、倍加は、短時間で参照値付近に戻る場合に限る。戻らない場合は実際の心拍の変化として参照値に加える。
+// 判定済みの母体心拍は参照値に含めず、半減、倍加の区間はそこで途切れるものとする。
+func detectMultiples(samples []Sample, flags []Flag) {
+	references := []Sample{}
+
+	for i := 0; i < len(samples); i++ {
+		s := samples[i]
+
+		if flags[i].Has(FlagMaternal) {
+			continue
+		} else if s.Value <= 0 || s.Value > MaximumBpm {
+			flags[i] |= FlagLoss
+			continue
+		} else if s.Value < MinimumBpm {
+			continue
+		}
+
+		// 古い参照値を捨てる。
+		for len(references) > 0 && s.Timestamp.Sub(references[0].Timestamp) > ReferenceWindow {
+			references = references[1:]
+		}
+
+		if len(references) > 0 {
+			reference := median(references)
+
+			if flag := multipleFlag(s.Value, reference); flag != FlagNone {
+				last := i
+				recovered := false
+
+				// 同じ種別が続く区間の終端を探す。信号損失は区間に含める。最長の持続時間内に戻らなければ補正しない。
+				for j := i + 1; j < len(samples); j++ {
+					v := samples[j].Value
+
+					if samples[j].Timestamp.Sub(s.Timestamp) > MultipleMaximumDuration || flags[j].Has(FlagMaternal) {
+						break
+					} else if v <= 0 || v > MaximumBpm {
+						flags[j] |= FlagLoss
+						continue
+					} else if multipleFlag(v, reference) == flag {
+						last = j
+						continue
+					}
+
+					recovered = math.Abs(float64(v) / reference - 1) <= RecoveryTolerance
+					break
+				}
+
+				if recovered {
+					for j := i; j <= last; j++ {
+						if !flags[j].Has(FlagLoss) {
+							flags[j] |= flag
+						}
+					}
+
+					i = last
+					continue
+				}
+			}
+		}
+
+		references = append(references, s)
+
+		if len(references) > ReferenceCount {
+			references = references[1:]
+		}
+	}
+}
+
+// 参照値に対して半減、倍加にあたる場合はその種別を返す。
+func multipleFlag(value int, reference float64) Flag {
+	ratio := float64(value) / reference
+
+	if math.Abs(ratio - 0.5) <= HalvingTolerance {
+		return FlagHalving
+	} else if math.Abs(ratio - 2) <= DoublingTolerance {
+		return FlagDoubling
+	} else {
+		return FlagNone
+	}
+}
+
+// 母体心拍を検出する。信号損失、半減、倍加と判定された標本は対象外とする。
+// 判定済みの母体心拍の区間に続く標本は、区間内の心拍の平均から外れるまで同じ区間とする。
+func detectMaternal(samples []Sample, flags []Flag) {
+	previous := -1
+
+	// 判定済みの母体心拍の区間が続いているか。levelとcountは区間内の心拍の平均と標本数。
+	continued := false
+	level := 0.0
+	count := 0.0
+
+	for i := 0; i < len(samples); i++ {
+		if flags[i].Has(FlagMaternal) {
+			if !continued {
+				continued, level, count = true, 0, 0
+			}
+
+			level = (level * count + float64(samples[i].Value)) / (count + 1)
+			count++
+			continue
+		} else if flags[i] != FlagNone {
+			continue
+		}
+
+		if continued {
+			if isMaternalLevel(samples[i].Value, level) {
+				flags[i] |= FlagMaternal
+				level = (level * count + float64(samples[i].Value)) / (count + 1)
+				count++
+				continue
+			}
+
+			continued = false
+		}
+
+		if previous >= 0 && isMaternalEntry(samples[previous], samples[i]) {
+			// 区間の終端を探す。
+			level := float64(samples[i].Value)
+			count := 1.0
+			last := i
+
+			for j := i + 1; j < len(samples); j++ {
+				if flags[j] != FlagNone {
+					continue
+				}
+
+				v := samples[j].Value
+
+				if !isMaternalLevel(v, level) {
+					break
+				}
+
+				level = (level * count + float64(v)) / (count + 1)
+				count++
+				last = j
+			}
+
+			if samples[last].Timestamp.Sub(samples[i].Timestamp) >= MaternalMinimumDuration {
+				for j := i; j <= last; j++ {
+					if flags[j] == FlagNone {
+						flags[j] |= FlagMaternal
+					}
+				}
+
+				i = last
+			}
+		}
+
+		previous = i
+	}
+}
+
+func isMaternalLevel(value int, level float64) bool {
+	return value >= MaternalMinimumBpm &&
+		value <= MaternalMaximumBpm &&
+		math.Abs(float64(value) - level) <= MaternalTolerance
+}
+
+func isMaternalEntry(from Sample, to Sample) bool {
+	return to.Value >= MaternalMinimumBpm &&
+		to.Value <= MaternalMaximumBpm &&
+		from.Value - to.Value >= MaternalJump &&
+		to.Timestamp.Sub(from.Timestamp) <= MaternalJumpInterval
+}
+
+func median(samples []Sample) float64 {
+	values := []int{}
+
+	for _, s := range samples {
+		values = append(values, s.Value)
+	}
+
+	sort.Ints(values)
+
+	if len(values) % 2 == 0 {
+		return float64(values[len(values)/2-1] + values[len(values)/2]) / 2
+	} else {
+		return float64(values[len(values)/2])
+	}
+}
@@ -0,0 +1,168 @@
+package artifact
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func generate(values ...int) []Sample {
+	base := time.Date(2021, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	samples := []Sample{}
+
+	for i, v := range values {
+		samples = append(samples, Sample{v, base.Add(time.Duration(i) * time.Second)})
+	}
+
+	return samples
+}
+
+func repeat(value int, count int) []int {
+	values := []int{}
+	for i := 0; i < count; i++ {
+		values = append(values, value)
+	}
+	return values
+}
+
+func concat(values ...[]int) []int {
+	results := []int{}
+	for _, vs := range values {
+		results = append(results, vs...)
+	}
+	return results
+}
+
+func TestArtifact_DetectMultiples(t *testing.T) {
+	flags := Detect(generate(140, 142, 0, 71, 141, 280, 139, 250, 140))
+
+	assert.EqualValues(t, []Flag{
+		FlagNone, FlagNone, FlagLoss, FlagHalving, FlagNone, FlagLoss, FlagNone, FlagLoss, FlagNone,
+	}, flags)
+
+	flags = Detect(generate(110, 112, 222, 111))
+
+	assert.EqualValues(t, []Flag{FlagNone, FlagNone, FlagDoubling, FlagNone}, flags)
+
+	t.Run("持続する低下は補正しない", func(t *testing.T) {
+		flags := Detect(generate(concat(repeat(130, 30), repeat(65, 60), repeat(130, 30))...))
+
+		for i, f := range flags {
+			assert.EqualValues(t, FlagNone, f, "index %d", i)
+		}
+	})
+
+	t.Run("元に戻らない半減は補正しない", func(t *testing.T) {
+		flags := Detect(generate(concat(repeat(130, 30), repeat(65, 3))...))
+
+		for i, f := range flags {
+			assert.EqualValues(t, FlagNone, f, "index %d", i)
+		}
+	})
+
+	t.Run("下限を下回る心拍は残す", func(t *testing.T) {
+		flags := Detect(generate(concat(repeat(140, 30), repeat(45, 60), repeat(140, 30))...))
+
+		for i, f := range flags {
+			assert.EqualValues(t, FlagNone, f, "index %d", i)
+		}
+	})
+}
+
+func TestArtifact_DetectMaternal(t *testing.T) {
+	t.Run("持続する母体心拍", func(t *testing.T) {
+		flags := Detect(generate(concat(repeat(140, 30), repeat(85, 150), repeat(140, 30))...))
+
+		for i, f := range flags {
+			if i >= 30 && i < 180 {
+				assert.EqualValues(t, FlagMaternal, f, "index %d", i)
+			} else {
+				assert.EqualValues(t, FlagNone, f, "index %d", i)
+			}
+		}
+	})
+
+	t.Run("短い低下は一過性徐脈とみなす", func(t *testing.T) {
+		flags := Detect(generate(concat(repeat(140, 30), repeat(85, 60), repeat(140, 30))...))
+
+		for _, f := range flags {
+			assert.EqualValues(t, FlagNone, f)
+		}
+	})
+
+	t.Run("緩やかな低下", func(t *testing.T) {
+		values := repeat(140, 30)
+		for v := 138; v > 85; v -= 2 {
+			values = append(values, v)
+		}
+		values = append(values, repeat(85, 150)...)
+
+		for _, f := range Detect(generate(values...)) {
+			assert.EqualValues(t, FlagNone, f)
+		}
+	})
+}
+
+func TestArtifact_DetectContinued(t *testing.T) {
+	t.Run("判定済みの母体心拍に続く標本", func(t *testing.T) {
+		samples := generate(concat(repeat(85, 30), repeat(86, 30), repeat(140, 30))...)
+		known := make([]Flag, 30)
+		for i := range known {
+			known[i] = FlagMaternal
+		}
+
+		flags := DetectContinued(samples, known)
+
+		for i, f := range flags {
+			if i < 60 {
+				assert.EqualValues(t, FlagMaternal, f, "index %d", i)
+			} else {
+				assert.EqualValues(t, FlagNone, f, "index %d", i)
+			}
+		}
+
+		// 判定済みのフラグが無ければ、区間の始まりが無いため母体心拍とはみなさない。
+		for i, f := range Detect(samples) {
+			assert.EqualValues(t, FlagNone, f, "index %d", i)
+		}
+	})
+
+	t.Run("判定済みの母体心拍は参照値に含めない", func(t *testing.T) {
+		samples := generate(concat(repeat(140, 10), repeat(70, 10), []int{141, 140, 70, 142})...)
+		known := make([]Flag, 20)
+		for i := 10; i < 20; i++ {
+			known[i] = FlagMaternal
+		}
+
+		flags := DetectContinued(samples, known)
+
+		for i, f := range flags {
+			if i >= 10 && i < 20 {
+				assert.EqualValues(t, FlagMaternal, f, "index %d", i)
+			} else if i == 22 {
+				assert.EqualValues(t, FlagHalving, f, "index %d", i)
+			} else {
+				assert.EqualValues(t, FlagNone, f, "index %d", i)
+			}
+		}
+
+		// 母体心拍を参照値とすると、胎児心拍を倍加とみなしてしまう。
+		assert.EqualValues(t, FlagDoubling, Detect(samples)[20])
+	})
+}
+
+func TestArtifact_Correct(t *testing.T) {
+	check := func(value int, flag Flag, expected int, ok bool) {
+		v, b := Correct(value, flag)
+		assert.EqualValues(t, expected, v)
+		assert.EqualValues(t, ok, b)
+	}
+
+	check(140, FlagNone, 140, true)
+	check(70, FlagHalving, 140, true)
+	check(280, FlagDoubling, 140, true)
+	check(0, FlagLoss, 0, false)
+	check(85, FlagMaternal, 0, false)
+}
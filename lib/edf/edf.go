@@ -0,0 +1,232 @@
+// EDF+形式(European Data Format)のファイルを生成する。
+//
+// 1秒を1データレコードとする連続記録(EDF+C)のみを扱い、各信号はレコード毎に1標本を持つ。
+// 注釈はEDF Annotations信号として、開始時点を含むデータレコードに格納する。
+package edf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 信号。標本は1秒毎に並び、物理量の範囲外の値は範囲内に丸める。
+type Signal struct {
+	Label           string
+	Transducer      string
+	Dimension       string
+	PhysicalMinimum int
+	PhysicalMaximum int
+	Samples         []int
+}
+
+// 注釈。Onsetは記録開始からの経過時間。
+type Annotation struct {
+	Onset    time.Duration
+	Duration time.Duration
+	Text     string
+}
+
+// 記録。Startのタイムゾーンで開始日時を記載する。
+type Recording struct {
+	PatientCode string
+	Equipment   string
+	Start       time.Time
+	Signals     []*Signal
+	Annotations []*Annotation
+}
+
+const (
+	headerSize      int    = 256
+	annotationLabel string = "EDF Annotations"
+)
+
+var months = []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+
+// ヘッダに記載できない文字を置き換える。ヘッダは表示可能なASCII文字のみからなる。
+func headerText(s string) string {
+	var b strings.Builder
+
+	for _, r := range s {
+		if r < 32 || r > 126 {
+			b.WriteByte('_')
+		} else {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+// EDF+の患者、記録の識別子の項目。空白は項目の区切りとなるため置き換え、空の場合はXとする。
+func subfield(s string) string {
+	s = strings.ReplaceAll(headerText(s), " ", "_")
+
+	if s == "" {
+		return "X"
+	}
+	return s
+}
+
+// 注釈の文字列から区切り文字を除く。
+func annotationText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == 0 || r == 0x14 || r == 0x15 {
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+// データレコード数。最も長い信号の標本数とし、少なくとも1とする。
+func (r *Recording) recordCount() int {
+	count := 1
+
+	for _, s := range r.Signals {
+		if len(s.Samples) > count {
+			count = len(s.Samples)
+		}
+	}
+
+	return count
+}
+
+// データレコード毎のEDF Annotations信号の内容。先頭にレコードの開始時点を示すTALを置く。
+func (r *Recording) annotationRecords(count int) [][]byte {
+	records := make([][]byte, count)
+
+	for i := range records {
+		records[i] = []byte(fmt.Sprintf("+%d\x14\x14\x00", i))
+	}
+
+	for _, a := range r.Annotations {
+		index := int(a.Onset / time.Second)
+
+		if index < 0 {
+			index = 0
+		} else if index >= count {
+			index = count - 1
+		}
+
+		tal := "+" + seconds(a.Onset)
+		if a.Duration > 0 {
+			tal += "\x15" + seconds(a.Duration)
+		}
+		tal += "\x14" + annotationText(a.Text) + "\x14\x00"
+
+		records[index] = append(records[index], []byte(tal)...)
+	}
+
+	return records
+}
+
+// 固定長の項目を左詰めで書き込む。長すぎる場合は切り詰める。
+func writeField(buf *bytes.Buffer, value string, size int) {
+	if len(value) > size {
+		value = value[:size]
+	}
+
+	buf.WriteString(value)
+	buf.WriteString(strings.Repeat(" ", size - len(value)))
+}
+
+// EDF+形式で出力する。
+func (r *Recording) Bytes() ([]byte, error) {
+	for _, s := range r.Signals {
+		if s.PhysicalMinimum >= s.PhysicalMaximum {
+			return nil, fmt.Errorf("Physical range of signal '%s' is empty", s.Label)
+		} else if s.PhysicalMinimum < -32768 || s.PhysicalMaximum > 32767 {
+			return nil, fmt.Errorf("Physical range of signal '%s' exceeds 16 bits", s.Label)
+		}
+	}
+
+	count := r.recordCount()
+	annotations := r.annotationRecords(count)
+
+	// 注釈の領域は全レコードで同じ長さとし、最長のものに合わせる。
+	annotationSize := 0
+
+	for _, a := range annotations {
+		if len(a) > annotationSize {
+			annotationSize = len(a)
+		}
+	}
+
+	annotationSamples := (annotationSize + 1) / 2
+
+	signalCount := len(r.Signals) + 1
+
+	buf := &bytes.Buffer{}
+
+	start := r.Start
+
+	writeField(buf, "0", 8)
+	writeField(buf, fmt.Sprintf("%s X X X", subfield(r.PatientCode)), 80)
+	writeField(buf, fmt.Sprintf(
+		"Startdate %02d-%s-%04d X X %s",
+		start.Day(), months[start.Month()-1], start.Year(), subfield(r.Equipment),
+	), 80)
+	writeField(buf, start.Format("02.01.06"), 8)
+	writeField(buf, start.Format("15.04.05"), 8)
+	writeField(buf, strconv.Itoa(headerSize * (signalCount + 1)), 8)
+	writeField(buf, "EDF+C", 44)
+	writeField(buf, strconv.Itoa(count), 8)
+	writeField(buf, "1", 8)
+	writeField(buf, strconv.Itoa(signalCount), 4)
+
+	// 信号毎のヘッダは、項目毎に全信号分を並べる。
+	fields := []struct {
+		size   int
+		value  func(*Signal) string
+		annotation string
+	}{
+		{16, func(s *Signal) string { return headerText(s.Label) }, annotationLabel},
+		{80, func(s *Signal) string { return headerText(s.Transducer) }, ""},
+		{8, func(s *Signal) string { return headerText(s.Dimension) }, ""},
+		{8, func(s *Signal) string { return strconv.Itoa(s.PhysicalMinimum) }, "-1"},
+		{8, func(s *Signal) string { return strconv.Itoa(s.PhysicalMaximum) }, "1"},
+		{8, func(s *Signal) string { return strconv.Itoa(s.PhysicalMinimum) }, "-32768"},
+		{8, func(s *Signal) string { return strconv.Itoa(s.PhysicalMaximum) }, "32767"},
+		{80, func(s *Signal) string { return "" }, ""},
+		{8, func(s *Signal) string { return "1" }, strconv.Itoa(annotationSamples)},
+		{32, func(s *Signal) string { return "" }, ""},
+	}
+
+	for _, f := range fields {
+		for _, s := range r.Signals {
+			writeField(buf, f.value(s), f.size)
+		}
+		writeField(buf, f.annotation, f.size)
+	}
+
+	// データレコード。
+	for i := 0; i < count; i++ {
+		for _, s := range r.Signals {
+			value := 0
+
+			if i < len(s.Samples) {
+				value = s.Samples[i]
+			}
+
+			if value < s.PhysicalMinimum {
+				value = s.PhysicalMinimum
+			} else if value > s.PhysicalMaximum {
+				value = s.PhysicalMaximum
+			}
+
+			binary.Write(buf, binary.LittleEndian, int16(value))
+		}
+
+		buf.Write(annotations[i])
+		buf.Write(make([]byte, annotationSamples * 2 - len(annotations[i])))
+	}
+
+	return buf.Bytes(), nil
+}
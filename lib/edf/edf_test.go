@@ -0,0 +1,81 @@
+package edf
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEDF_Bytes(t *testing.T) {
+	start := time.Date(2024, time.March, 2, 13, 4, 5, 0, time.UTC)
+
+	recording := &Recording{
+		PatientCode: "m 0001",
+		Equipment: "t-0001",
+		Start: start,
+		Signals: []*Signal{
+			{"FHR1", "Ultrasound", "bpm", 0, 300, []int{140, 141, 400}},
+			{"UC", "TOCO", "a.u.", 0, 127, []int{10, 20}},
+		},
+		Annotations: []*Annotation{
+			{time.Duration(1500) * time.Millisecond, time.Duration(30) * time.Second, "R4"},
+		},
+	}
+
+	bs, err := recording.Bytes()
+
+	assert.NoError(t, err)
+
+	field := func(offset int, size int) string {
+		return strings.TrimRight(string(bs[offset:offset+size]), " ")
+	}
+
+	assert.Equal(t, "0", field(0, 8))
+	assert.Equal(t, "m_0001 X X X", field(8, 80))
+	assert.Equal(t, "Startdate 02-MAR-2024 X X t-0001", field(88, 80))
+	assert.Equal(t, "02.03.24", field(168, 8))
+	assert.Equal(t, "13.04.05", field(176, 8))
+	// 2信号と注釈。
+	assert.Equal(t, "1024", field(184, 8))
+	assert.Equal(t, "EDF+C", field(192, 44))
+	assert.Equal(t, "3", field(236, 8))
+	assert.Equal(t, "1", field(244, 8))
+	assert.Equal(t, "3", field(252, 4))
+
+	assert.Equal(t, "FHR1", field(256, 16))
+	assert.Equal(t, "EDF Annotations", field(256 + 32, 16))
+
+	// 注釈の領域は最長の"+1\x14\x14\x00+1.5\x1530\x14R4\x14\x00"に合わせて偶数バイトとなる。
+	samples := field(256 + 3*(16+80+8*5+80), 8*3)
+	assert.Equal(t, "1       1       9", samples)
+
+	recordSize := 2 + 2 + 18
+
+	assert.Equal(t, 1024 + recordSize*3, len(bs))
+
+	// 範囲外の値は丸められ、欠損は0となる。
+	record := func(i int) []byte {
+		return bs[1024 + recordSize*i:1024 + recordSize*(i+1)]
+	}
+
+	assert.EqualValues(t, 140, int16(binary.LittleEndian.Uint16(record(0)[0:2])))
+	assert.EqualValues(t, 300, int16(binary.LittleEndian.Uint16(record(2)[0:2])))
+	assert.EqualValues(t, 0, int16(binary.LittleEndian.Uint16(record(2)[2:4])))
+
+	assert.Equal(t, "+0\x14\x14\x00", string(record(0)[4:9]))
+	assert.Equal(t, "+1\x14\x14\x00+1.5\x1530\x14R4\x14\x00", string(record(1)[4:21]))
+}
+
+func TestEDF_InvalidRange(t *testing.T) {
+	recording := &Recording{
+		Start: time.Now(),
+		Signals: []*Signal{{"FHR1", "", "bpm", 0, 0, []int{}}},
+	}
+
+	_, err := recording.Bytes()
+
+	assert.Error(t, err)
+}
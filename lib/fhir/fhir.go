@@ -0,0 +1,320 @@
+// FHIR R4のリソースのうち、読み取りAPIで返すものを定義する。
+//
+// 各リソースは必要な要素のみを持ち、JSON形式でシリアライズする。
+package fhir
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ContentType string = "application/fhir+json"
+
+	// 本システムの識別子の名前空間。
+	SystemMeasurementCode string = "urn:spiker:measurement-code"
+	SystemTerminalCode    string = "urn:spiker:terminal-code"
+
+	SystemUCUM                string = "http://unitsofmeasure.org"
+	SystemObservationCategory string = "http://terminology.hl7.org/CodeSystem/observation-category"
+	SystemActCode             string = "http://terminology.hl7.org/CodeSystem/v3-ActCode"
+	SystemRisk                string = "urn:spiker:risk"
+)
+
+type Coding struct {
+	System  string `json:"system,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+type CodeableConcept struct {
+	Coding []*Coding `json:"coding,omitempty"`
+	Text   string    `json:"text,omitempty"`
+}
+
+type Identifier struct {
+	System string `json:"system,omitempty"`
+	Value  string `json:"value"`
+}
+
+type Reference struct {
+	Reference string `json:"reference,omitempty"`
+	Display   string `json:"display,omitempty"`
+}
+
+type Period struct {
+	Start *time.Time `json:"start,omitempty"`
+	End   *time.Time `json:"end,omitempty"`
+}
+
+type Quantity struct {
+	Value  float64 `json:"value"`
+	Unit   string  `json:"unit,omitempty"`
+	System string  `json:"system,omitempty"`
+	Code   string  `json:"code,omitempty"`
+}
+
+type Meta struct {
+	LastUpdated *time.Time `json:"lastUpdated,omitempty"`
+}
+
+type HumanName struct {
+	Text string `json:"text"`
+}
+
+type Annotation struct {
+	Text string `json:"text"`
+}
+
+// 患者。
+type Patient struct {
+	ResourceType         string        `json:"resourceType"`
+	Id                   string        `json:"id"`
+	Meta                 *Meta         `json:"meta,omitempty"`
+	Identifier           []*Identifier `json:"identifier,omitempty"`
+	Name                 []*HumanName  `json:"name,omitempty"`
+	Gender               string        `json:"gender,omitempty"`
+	ManagingOrganization *Reference    `json:"managingOrganization,omitempty"`
+}
+
+// 計測端末。
+type Device struct {
+	ResourceType string        `json:"resourceType"`
+	Id           string        `json:"id"`
+	Meta         *Meta         `json:"meta,omitempty"`
+	Identifier   []*Identifier `json:"identifier,omitempty"`
+	DeviceName   []*DeviceName `json:"deviceName,omitempty"`
+	Owner        *Reference    `json:"owner,omitempty"`
+	Note         []*Annotation `json:"note,omitempty"`
+}
+
+type DeviceName struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// 計測記録。
+type Encounter struct {
+	ResourceType    string        `json:"resourceType"`
+	Id              string        `json:"id"`
+	Meta            *Meta         `json:"meta,omitempty"`
+	Identifier      []*Identifier `json:"identifier,omitempty"`
+	Status          string        `json:"status"`
+	Class           *Coding       `json:"class"`
+	Subject         *Reference    `json:"subject,omitempty"`
+	Period          *Period       `json:"period,omitempty"`
+	ServiceProvider *Reference    `json:"serviceProvider,omitempty"`
+	Extension       []*Extension  `json:"extension,omitempty"`
+}
+
+// 拡張。計測と計測端末の関連のように、標準の要素で表せない関連に用いる。
+type Extension struct {
+	Url            string     `json:"url"`
+	ValueReference *Reference `json:"valueReference,omitempty"`
+}
+
+const ExtensionEncounterDevice string = "urn:spiker:encounter-device"
+
+// 波形データ。値の無い点はEとする。
+type SampledData struct {
+	Origin     *Quantity `json:"origin"`
+	Period     float64   `json:"period"`
+	LowerLimit *float64  `json:"lowerLimit,omitempty"`
+	UpperLimit *float64  `json:"upperLimit,omitempty"`
+	Dimensions int       `json:"dimensions"`
+	Data       string    `json:"data,omitempty"`
+}
+
+// 観察。
+type Observation struct {
+	ResourceType     string             `json:"resourceType"`
+	Id               string             `json:"id"`
+	Status           string             `json:"status"`
+	Category         []*CodeableConcept `json:"category,omitempty"`
+	Code             *CodeableConcept   `json:"code"`
+	Subject          *Reference         `json:"subject,omitempty"`
+	Encounter        *Reference         `json:"encounter,omitempty"`
+	EffectivePeriod  *Period            `json:"effectivePeriod,omitempty"`
+	Device           *Reference         `json:"device,omitempty"`
+	ValueSampledData *SampledData       `json:"valueSampledData,omitempty"`
+}
+
+// 診断レポート。
+type DiagnosticReport struct {
+	ResourceType    string             `json:"resourceType"`
+	Id              string             `json:"id"`
+	Meta            *Meta              `json:"meta,omitempty"`
+	Status          string             `json:"status"`
+	Code            *CodeableConcept   `json:"code"`
+	Subject         *Reference         `json:"subject,omitempty"`
+	Encounter       *Reference         `json:"encounter,omitempty"`
+	EffectivePeriod *Period            `json:"effectivePeriod,omitempty"`
+	Issued          *time.Time         `json:"issued,omitempty"`
+	Conclusion      string             `json:"conclusion,omitempty"`
+	ConclusionCode  []*CodeableConcept `json:"conclusionCode,omitempty"`
+}
+
+// リスク評価。
+type RiskAssessment struct {
+	ResourceType     string        `json:"resourceType"`
+	Id               string        `json:"id"`
+	Meta             *Meta         `json:"meta,omitempty"`
+	Status           string        `json:"status"`
+	Subject          *Reference    `json:"subject,omitempty"`
+	Encounter        *Reference    `json:"encounter,omitempty"`
+	OccurrencePeriod *Period       `json:"occurrencePeriod,omitempty"`
+	Prediction       []*Prediction `json:"prediction,omitempty"`
+	Note             []*Annotation `json:"note,omitempty"`
+}
+
+type Prediction struct {
+	QualitativeRisk *CodeableConcept `json:"qualitativeRisk,omitempty"`
+}
+
+// 検索結果。
+type Bundle struct {
+	ResourceType string         `json:"resourceType"`
+	Type         string         `json:"type"`
+	Total        int64          `json:"total"`
+	Link         []*BundleLink  `json:"link,omitempty"`
+	Entry        []*BundleEntry `json:"entry"`
+}
+
+type BundleLink struct {
+	Relation string `json:"relation"`
+	Url      string `json:"url"`
+}
+
+type BundleEntry struct {
+	FullUrl  string        `json:"fullUrl,omitempty"`
+	Resource interface{}   `json:"resource"`
+	Search   *BundleSearch `json:"search,omitempty"`
+}
+
+type BundleSearch struct {
+	Mode string `json:"mode"`
+}
+
+// 検索結果を作成する。
+func NewSearchset(total int64, resources ...interface{}) *Bundle {
+	bundle := &Bundle{
+		ResourceType: "Bundle",
+		Type:         "searchset",
+		Total:        total,
+		Link:         []*BundleLink{},
+		Entry:        []*BundleEntry{},
+	}
+
+	for _, r := range resources {
+		bundle.Entry = append(bundle.Entry, &BundleEntry{Resource: r, Search: &BundleSearch{"match"}})
+	}
+
+	return bundle
+}
+
+// リソース種別とIDからリファレンスを作成する。
+func NewReference(resourceType string, id interface{}, display string) *Reference {
+	return &Reference{fmt.Sprintf("%s/%v", resourceType, id), display}
+}
+
+// 検索パラメータのリファレンスからIDを取り出す。IDのみ、もしくは"種別/ID"の形式を受け付ける。
+func ParseReference(resourceType string, value string) (string, error) {
+	if i := strings.LastIndex(value, "/"); i >= 0 {
+		if value[:i] != resourceType {
+			return "", fmt.Errorf("Reference '%s' is not a %s", value, resourceType)
+		}
+		value = value[i+1:]
+	}
+
+	if value == "" {
+		return "", fmt.Errorf("Reference to %s is empty", resourceType)
+	}
+
+	return value, nil
+}
+
+// 検索パラメータのリファレンスから整数のIDを取り出す。
+func ParseIntReference(resourceType string, value string) (int, error) {
+	if id, e := ParseReference(resourceType, value); e != nil {
+		return 0, e
+	} else if i, e := strconv.Atoi(id); e != nil {
+		return 0, fmt.Errorf("Reference '%s' is not a %s", value, resourceType)
+	} else {
+		return i, nil
+	}
+}
+
+var dateLayouts = []struct {
+	layout    string
+	precision func(time.Time) time.Time
+}{
+	{time.RFC3339Nano, func(t time.Time) time.Time { return t.Add(time.Second) }},
+	{"2006-01-02T15:04:05", func(t time.Time) time.Time { return t.Add(time.Second) }},
+	{"2006-01-02T15:04", func(t time.Time) time.Time { return t.Add(time.Minute) }},
+	{"2006-01-02", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+	{"2006-01", func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }},
+	{"2006", func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }},
+}
+
+// 日付の検索パラメータを期間に変換する。
+//
+// 値の接頭辞はge、gt、le、lt、eqに対応し、接頭辞の無い値はeqとみなす。
+// タイムゾーンの無い値はlocationのタイムゾーンとし、値の精度の期間を表すものとする(例: 2024-01-02はその1日)。
+// 複数の値はいずれも満たす期間とし、指定の無い側はnilとなる。
+func ParseDateRange(values []string, location *time.Location) (*time.Time, *time.Time, error) {
+	var begin, end *time.Time
+
+	narrowBegin := func(t time.Time) {
+		if begin == nil || t.After(*begin) {
+			begin = &t
+		}
+	}
+	narrowEnd := func(t time.Time) {
+		if end == nil || t.Before(*end) {
+			end = &t
+		}
+	}
+
+	for _, value := range values {
+		prefix := "eq"
+
+		if len(value) > 2 && value[0] >= 'a' && value[0] <= 'z' {
+			prefix, value = value[:2], value[2:]
+		}
+
+		var from, until time.Time
+		parsed := false
+
+		for _, l := range dateLayouts {
+			if t, e := time.ParseInLocation(l.layout, value, location); e == nil {
+				from, until = t, l.precision(t)
+				parsed = true
+				break
+			}
+		}
+
+		if !parsed {
+			return nil, nil, fmt.Errorf("Date '%s' is not in a valid format", value)
+		}
+
+		switch prefix {
+		case "eq":
+			narrowBegin(from)
+			narrowEnd(until)
+		case "ge":
+			narrowBegin(from)
+		case "gt":
+			narrowBegin(until)
+		case "le":
+			narrowEnd(until)
+		case "lt":
+			narrowEnd(from)
+		default:
+			return nil, nil, fmt.Errorf("Date prefix '%s' is not supported", prefix)
+		}
+	}
+
+	return begin, end, nil
+}
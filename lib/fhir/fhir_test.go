@@ -0,0 +1,89 @@
+package fhir
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFHIR_ParseDateRange(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+
+	t.Run("日付のみ", func(t *testing.T) {
+		begin, end, err := ParseDateRange([]string{"2024-03-02"}, jst)
+
+		assert.NoError(t, err)
+		assert.True(t, time.Date(2024, time.March, 2, 0, 0, 0, 0, jst).Equal(*begin))
+		assert.True(t, time.Date(2024, time.March, 3, 0, 0, 0, 0, jst).Equal(*end))
+	})
+
+	t.Run("接頭辞の組み合わせ", func(t *testing.T) {
+		begin, end, err := ParseDateRange([]string{"ge2024-03-02T10:00:00Z", "lt2024-03-02T12:00:00Z"}, jst)
+
+		assert.NoError(t, err)
+		assert.True(t, time.Date(2024, time.March, 2, 10, 0, 0, 0, time.UTC).Equal(*begin))
+		assert.True(t, time.Date(2024, time.March, 2, 12, 0, 0, 0, time.UTC).Equal(*end))
+	})
+
+	t.Run("gtとleは値の精度の期間の末尾", func(t *testing.T) {
+		begin, end, err := ParseDateRange([]string{"gt2024-03", "le2024-04-10"}, jst)
+
+		assert.NoError(t, err)
+		assert.True(t, time.Date(2024, time.April, 1, 0, 0, 0, 0, jst).Equal(*begin))
+		assert.True(t, time.Date(2024, time.April, 11, 0, 0, 0, 0, jst).Equal(*end))
+	})
+
+	t.Run("片側のみ", func(t *testing.T) {
+		begin, end, err := ParseDateRange([]string{"ge2024"}, jst)
+
+		assert.NoError(t, err)
+		assert.True(t, time.Date(2024, time.January, 1, 0, 0, 0, 0, jst).Equal(*begin))
+		assert.Nil(t, end)
+	})
+
+	t.Run("不正な形式", func(t *testing.T) {
+		_, _, err := ParseDateRange([]string{"2024/03/02"}, jst)
+		assert.Error(t, err)
+
+		_, _, err = ParseDateRange([]string{"sa2024-03-02"}, jst)
+		assert.Error(t, err)
+	})
+}
+
+func TestFHIR_ParseIntReference(t *testing.T) {
+	if id, e := ParseIntReference("Patient", "12"); assert.NoError(t, e) {
+		assert.Equal(t, 12, id)
+	}
+
+	if id, e := ParseIntReference("Patient", "Patient/34"); assert.NoError(t, e) {
+		assert.Equal(t, 34, id)
+	}
+
+	_, err := ParseIntReference("Patient", "Encounter/34")
+	assert.Error(t, err)
+
+	_, err = ParseIntReference("Patient", "Patient/x")
+	assert.Error(t, err)
+}
+
+func TestFHIR_NewSearchset(t *testing.T) {
+	bundle := NewSearchset(5, &Patient{ResourceType: "Patient", Id: "1"})
+
+	bs, err := json.Marshal(bundle)
+
+	assert.NoError(t, err)
+
+	result := map[string]interface{}{}
+
+	assert.NoError(t, json.Unmarshal(bs, &result))
+	assert.Equal(t, "Bundle", result["resourceType"])
+	assert.Equal(t, "searchset", result["type"])
+	assert.EqualValues(t, 5, result["total"])
+
+	entries := result["entry"].([]interface{})
+
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "Patient", entries[0].(map[string]interface{})["resource"].(map[string]interface{})["resourceType"])
+}
@@ -0,0 +1,532 @@
+// HL7 v2のメッセージのうち、患者登録に用いるADTメッセージを扱う。
+//
+// メッセージはMLLPで区切られて送られ、セグメントは\r、フィールドはMSH-1、成分等の区切りはMSH-2の文字で区切られる。
+// フィールドと成分の番号は規格に合わせて1から数え、MSHのフィールドもMSH-1を区切り文字として数える。
+package hl7
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// MLLPの開始、終了を示すバイト。
+	StartBlock     byte = 0x0b
+	EndBlock       byte = 0x1c
+	CarriageReturn byte = 0x0d
+)
+
+// 区切り文字。
+type Encoding struct {
+	Field        byte
+	Component    byte
+	Repetition   byte
+	Escape       byte
+	Subcomponent byte
+}
+
+var DefaultEncoding = Encoding{'|', '^', '~', '\\', '&'}
+
+// MLLPのメッセージが最大バイト数を超えた。
+var ErrFrameTooLarge = errors.New("MLLP frame is too large")
+
+// セグメント。fieldsの先頭はセグメント名。
+type Segment struct {
+	Name   string
+	fields []string
+	enc    Encoding
+}
+
+// フィールドの値をそのまま取得する。存在しない場合は空文字列。
+func (s *Segment) Field(i int) string {
+	if s == nil || i < 1 || i >= len(s.fields) {
+		return ""
+	}
+
+	return s.fields[i]
+}
+
+// フィールドの繰り返しの数を取得する。
+func (s *Segment) Repetitions(i int) int {
+	if f := s.Field(i); f == "" {
+		return 0
+	} else {
+		return strings.Count(f, string(s.enc.Repetition)) + 1
+	}
+}
+
+// フィールドのrepetition番目(0から)の繰り返しのcomponent番目(1から)の成分を、エスケープを戻して取得する。
+func (s *Segment) Get(field int, repetition int, component int) string {
+	if s == nil {
+		return ""
+	}
+
+	reps := strings.Split(s.Field(field), string(s.enc.Repetition))
+
+	if repetition < 0 || repetition >= len(reps) {
+		return ""
+	}
+
+	comps := strings.Split(reps[repetition], string(s.enc.Component))
+
+	if component < 1 || component > len(comps) {
+		return ""
+	}
+
+	// 副成分は先頭のみを用いる。
+	value := strings.SplitN(comps[component-1], string(s.enc.Subcomponent), 2)[0]
+
+	return s.enc.unescape(value)
+}
+
+// メッセージ。
+type Message struct {
+	Segments []*Segment
+	Encoding Encoding
+}
+
+// メッセージを解析する。先頭はMSHセグメントでなければならない。
+func Parse(data []byte) (*Message, error) {
+	text := strings.ReplaceAll(strings.ReplaceAll(string(data), "\r\n", "\r"), "\n", "\r")
+	text = strings.Trim(text, "\r")
+
+	if !strings.HasPrefix(text, "MSH") || len(text) < 8 {
+		return nil, fmt.Errorf("Message does not start with MSH segment")
+	}
+
+	enc := Encoding{text[3], text[4], text[5], text[6], text[7]}
+
+	message := &Message{[]*Segment{}, enc}
+
+	for _, line := range strings.Split(text, "\r") {
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Split(line, string(enc.Field))
+
+		if fields[0] == "MSH" {
+			// MSH-1は区切り文字そのもの。
+			fields = append([]string{"MSH", string(enc.Field)}, fields[1:]...)
+		}
+
+		message.Segments = append(message.Segments, &Segment{fields[0], fields, enc})
+	}
+
+	return message, nil
+}
+
+// 名前が一致する最初のセグメントを取得する。無い場合はnil。
+func (m *Message) Segment(name string) *Segment {
+	for _, s := range m.Segments {
+		if s.Name == name {
+			return s
+		}
+	}
+
+	return nil
+}
+
+// 名前が一致する全てのセグメントを取得する。
+func (m *Message) All(name string) []*Segment {
+	segments := []*Segment{}
+
+	for _, s := range m.Segments {
+		if s.Name == name {
+			segments = append(segments, s)
+		}
+	}
+
+	return segments
+}
+
+// メッセージ種別(MSH-9.1)。
+func (m *Message) Type() string {
+	return m.Segment("MSH").Get(9, 0, 1)
+}
+
+// イベント種別(MSH-9.2)。MSHに無い場合はEVN-1を用いる。
+func (m *Message) Event() string {
+	if e := m.Segment("MSH").Get(9, 0, 2); e != "" {
+		return e
+	} else {
+		return m.Segment("EVN").Get(1, 0, 1)
+	}
+}
+
+// メッセージ制御ID(MSH-10)。
+func (m *Message) ControlId() string {
+	return m.Segment("MSH").Get(10, 0, 1)
+}
+
+func (enc Encoding) unescape(value string) string {
+	esc := string(enc.Escape)
+
+	if !strings.Contains(value, esc) {
+		return value
+	}
+
+	parts := strings.Split(value, esc)
+
+	var b strings.Builder
+
+	// 区切られた奇数番目がエスケープシーケンス。
+	for i, p := range parts {
+		if i%2 == 0 {
+			b.WriteString(p)
+			continue
+		}
+
+		switch p {
+		case "F":
+			b.WriteByte(enc.Field)
+		case "S":
+			b.WriteByte(enc.Component)
+		case "T":
+			b.WriteByte(enc.Subcomponent)
+		case "R":
+			b.WriteByte(enc.Repetition)
+		case "E":
+			b.WriteByte(enc.Escape)
+		case ".br":
+			b.WriteString("\n")
+		}
+	}
+
+	return b.String()
+}
+
+func (enc Encoding) escape(value string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case enc.Escape:
+			b.WriteString(string([]byte{enc.Escape, 'E', enc.Escape}))
+		case enc.Field:
+			b.WriteString(string([]byte{enc.Escape, 'F', enc.Escape}))
+		case enc.Component:
+			b.WriteString(string([]byte{enc.Escape, 'S', enc.Escape}))
+		case enc.Subcomponent:
+			b.WriteString(string([]byte{enc.Escape, 'T', enc.Escape}))
+		case enc.Repetition:
+			b.WriteString(string([]byte{enc.Escape, 'R', enc.Escape}))
+		case '\r', '\n':
+			b.WriteString(string(enc.Escape) + ".br" + string(enc.Escape))
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
+
+// 日時(DTM)を解析する。時差が無い場合はlocの時刻とする。空の場合はnilを返す。
+func ParseTime(value string, loc *time.Location) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	zone := ""
+
+	if i := strings.IndexAny(value, "+-"); i >= 0 {
+		value, zone = value[:i], value[i:]
+	}
+
+	fraction := ""
+
+	if i := strings.Index(value, "."); i >= 0 {
+		value, fraction = value[:i], value[i:]
+	}
+
+	layouts := map[int]string{
+		4: "2006", 6: "200601", 8: "20060102", 10: "2006010215", 12: "200601021504", 14: "20060102150405",
+	}
+
+	layout, be := layouts[len(value)]
+
+	if !be {
+		return nil, fmt.Errorf("Invalid HL7 datetime: %s", value+fraction+zone)
+	}
+
+	if zone != "" {
+		if len(zone) != 5 {
+			return nil, fmt.Errorf("Invalid HL7 time zone: %s", zone)
+		}
+
+		hours, e1 := strconv.Atoi(zone[1:3])
+		minutes, e2 := strconv.Atoi(zone[3:5])
+
+		if e1 != nil || e2 != nil {
+			return nil, fmt.Errorf("Invalid HL7 time zone: %s", zone)
+		}
+
+		offset := hours*3600 + minutes*60
+
+		if zone[0] == '-' {
+			offset = -offset
+		}
+
+		loc = time.FixedZone(zone, offset)
+	}
+
+	t, err := time.ParseInLocation(layout, value, loc)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if fraction != "" {
+		if f, e := strconv.ParseFloat("0"+fraction, 64); e == nil {
+			t = t.Add(time.Duration(f * float64(time.Second)))
+		}
+	}
+
+	return &t, nil
+}
+
+// 在胎期間を表すOBXの観察項目(LOINC)。
+var gestationalAgeCodes = map[string]bool{
+	"11884-4": true, // Gestational age Estimated
+	"11885-1": true, // Gestational age Estimated from last menstrual period
+	"18185-9": true, // Gestational age
+	"49051-6": true, // Gestational age in weeks
+}
+
+// ADTメッセージから取り出した患者情報。値が無い項目はゼロ値もしくはnil。
+type ADT struct {
+	// イベント種別。A01(入院)、A04(登録)、A08(更新)、A03(退院)、A40(統合)など。
+	Event     string
+	ControlId string
+	// 病院の患者ID(MRN)。PID-3のうち識別子種別がMRのもの、無ければ先頭のもの。
+	MRN string
+	// 統合元の患者ID(MRG-1)。A40のみ。
+	PriorMRN   string
+	FamilyName string
+	GivenName  string
+	BirthDate  *time.Time
+	// 在胎日数。OBXの在胎期間から求める。
+	GestationalDays *int
+	// イベントの発生日時(EVN-2、無ければMSH-7)。
+	RecordedAt *time.Time
+}
+
+// ADTメッセージから患者情報を取り出す。時差の無い日時はlocの時刻とする。
+func ParseADT(m *Message, loc *time.Location) (*ADT, error) {
+	if t := m.Type(); t != "ADT" {
+		return nil, fmt.Errorf("Message type is not ADT: %s", t)
+	}
+
+	adt := &ADT{Event: m.Event(), ControlId: m.ControlId()}
+
+	pid := m.Segment("PID")
+
+	if pid == nil {
+		return nil, fmt.Errorf("PID segment is not found")
+	}
+
+	for i := 0; i < pid.Repetitions(3); i++ {
+		if id := pid.Get(3, i, 1); id == "" {
+			continue
+		} else if adt.MRN == "" || pid.Get(3, i, 5) == "MR" {
+			adt.MRN = id
+
+			if pid.Get(3, i, 5) == "MR" {
+				break
+			}
+		}
+	}
+
+	if adt.MRN == "" {
+		return nil, fmt.Errorf("Patient identifier (PID-3) is empty")
+	}
+
+	adt.FamilyName = pid.Get(5, 0, 1)
+	adt.GivenName = pid.Get(5, 0, 2)
+
+	if t, e := ParseTime(pid.Get(7, 0, 1), loc); e != nil {
+		return nil, fmt.Errorf("Invalid birth date (PID-7): %v", e)
+	} else {
+		adt.BirthDate = t
+	}
+
+	if mrg := m.Segment("MRG"); mrg != nil {
+		adt.PriorMRN = mrg.Get(1, 0, 1)
+	}
+
+	for _, obx := range m.All("OBX") {
+		if !gestationalAgeCodes[obx.Get(3, 0, 1)] {
+			continue
+		}
+
+		value, err := strconv.ParseFloat(obx.Get(5, 0, 1), 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid gestational age (OBX-5): %s", obx.Get(5, 0, 1))
+		}
+
+		var days int
+
+		switch strings.ToLower(obx.Get(6, 0, 1)) {
+		case "d", "day", "days":
+			days = int(math.Round(value))
+		case "wk", "week", "weeks", "":
+			days = int(math.Round(value * 7))
+		default:
+			return nil, fmt.Errorf("Unknown unit of gestational age (OBX-6): %s", obx.Get(6, 0, 1))
+		}
+
+		adt.GestationalDays = &days
+	}
+
+	recordedAt := m.Segment("EVN").Get(2, 0, 1)
+
+	if recordedAt == "" {
+		recordedAt = m.Segment("MSH").Get(7, 0, 1)
+	}
+
+	if t, e := ParseTime(recordedAt, loc); e != nil {
+		return nil, fmt.Errorf("Invalid event datetime: %v", e)
+	} else {
+		adt.RecordedAt = t
+	}
+
+	return adt, nil
+}
+
+// 氏名。姓と名を空白で繋ぐ。いずれも無い場合はnil。
+func (a *ADT) Name() *string {
+	name := strings.TrimSpace(strings.TrimSpace(a.FamilyName) + " " + strings.TrimSpace(a.GivenName))
+
+	if name == "" {
+		return nil
+	}
+
+	return &name
+}
+
+// 指定日時における満年齢。生年月日が無い場合はnil。
+func (a *ADT) Age(at time.Time) *int {
+	if a.BirthDate == nil {
+		return nil
+	}
+
+	b := a.BirthDate.In(at.Location())
+
+	age := at.Year() - b.Year()
+
+	if at.Month() < b.Month() || (at.Month() == b.Month() && at.Day() < b.Day()) {
+		age--
+	}
+
+	return &age
+}
+
+// 応答の種別。
+type AckCode string
+
+const (
+	AckAccept AckCode = "AA"
+	AckError  AckCode = "AE"
+	AckReject AckCode = "AR"
+)
+
+// 受信したメッセージへの応答(ACK)を生成する。解析できなかったメッセージの場合、mはnil。
+func Ack(m *Message, code AckCode, text string, now time.Time) []byte {
+	enc := DefaultEncoding
+
+	msh := &Segment{}
+	event := ""
+	controlId := ""
+
+	if m != nil {
+		msh = m.Segment("MSH")
+		event = m.Event()
+		controlId = m.ControlId()
+	}
+
+	fields := []string{
+		"MSH",
+		string([]byte{enc.Component, enc.Repetition, enc.Escape, enc.Subcomponent}),
+		// 送信側と受信側を入れ替える。
+		msh.Field(5), msh.Field(6), msh.Field(3), msh.Field(4),
+		now.Format("20060102150405"),
+		"",
+		"ACK" + string(enc.Component) + enc.escape(event) + string(enc.Component) + "ACK",
+		enc.escape("ACK" + controlId),
+		"P",
+		msh.Field(12),
+	}
+
+	var b bytes.Buffer
+
+	b.WriteString(strings.Join(fields, string(enc.Field)))
+	b.WriteByte(CarriageReturn)
+	b.WriteString(strings.Join([]string{"MSA", string(code), enc.escape(controlId), enc.escape(text)}, string(enc.Field)))
+	b.WriteByte(CarriageReturn)
+
+	return b.Bytes()
+}
+
+// MLLPで区切られたメッセージを1つ読み込む。開始バイトより前のデータは読み捨てる。
+// メッセージがmaxSizeバイトを超える場合は、読み込みを止めてErrFrameTooLargeを返す。
+func ReadFrame(r *bufio.Reader, maxSize int) ([]byte, error) {
+	for {
+		if c, e := r.ReadByte(); e != nil {
+			return nil, e
+		} else if c == StartBlock {
+			break
+		}
+	}
+
+	data := []byte{}
+
+	for {
+		chunk, err := r.ReadSlice(EndBlock)
+
+		// 終了バイトの分を含めて比較する。
+		if len(data)+len(chunk) > maxSize+1 {
+			return nil, ErrFrameTooLarge
+		}
+
+		data = append(data, chunk...)
+
+		if err == nil {
+			break
+		} else if err == bufio.ErrBufferFull {
+			continue
+		} else if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		} else {
+			return nil, err
+		}
+	}
+
+	if c, e := r.ReadByte(); e != nil {
+		return nil, e
+	} else if c != CarriageReturn {
+		return nil, fmt.Errorf("MLLP frame is not terminated with CR: 0x%02x", c)
+	}
+
+	return data[:len(data)-1], nil
+}
+
+// メッセージをMLLPで区切って書き込む。
+func WriteFrame(w io.Writer, data []byte) error {
+	frame := make([]byte, 0, len(data)+3)
+
+	frame = append(frame, StartBlock)
+	frame = append(frame, data...)
+	frame = append(frame, EndBlock, CarriageReturn)
+
+	_, err := w.Write(frame)
+
+	return err
+}
@@ -0,0 +1,172 @@
+package hl7
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func message(segments ...string) []byte {
+	return []byte(strings.Join(segments, "\r") + "\r")
+}
+
+func TestHL7_ParseADT(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+
+	t.Run("入院", func(t *testing.T) {
+		m, err := Parse(message(
+			`MSH|^~\&|HIS|HOSP|SPIKER|HOSP|20240302101500||ADT^A01^ADT_A01|MSG0001|P|2.5`,
+			`EVN|A01|20240302101000`,
+			`PID|1||0001^^^HOSP^PI~12345678^^^HOSP^MR||山田^花子||19900415|F`,
+			`PV1|1|I|W3^301^1`,
+			`OBX|1|NM|49051-6^Gestational age in weeks^LN||38.5|wk`,
+		))
+
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		adt, err := ParseADT(m, jst)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.EqualValues(t, "A01", adt.Event)
+		assert.EqualValues(t, "MSG0001", adt.ControlId)
+		assert.EqualValues(t, "12345678", adt.MRN)
+		assert.EqualValues(t, "", adt.PriorMRN)
+		assert.EqualValues(t, "山田 花子", *adt.Name())
+		assert.True(t, time.Date(1990, time.April, 15, 0, 0, 0, 0, jst).Equal(*adt.BirthDate))
+		assert.EqualValues(t, 33, *adt.Age(time.Date(2024, time.April, 14, 0, 0, 0, 0, jst)))
+		assert.EqualValues(t, 34, *adt.Age(time.Date(2024, time.April, 15, 0, 0, 0, 0, jst)))
+		assert.EqualValues(t, 270, *adt.GestationalDays)
+		assert.True(t, time.Date(2024, time.March, 2, 10, 10, 0, 0, jst).Equal(*adt.RecordedAt))
+	})
+
+	t.Run("統合", func(t *testing.T) {
+		m, err := Parse(message(
+			`MSH|^~\&|HIS|HOSP|SPIKER|HOSP|20240302101500+0000||ADT^A40|MSG0002|P|2.5`,
+			`PID|1||12345678||山田^花子`,
+			`MRG|87654321`,
+		))
+
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		adt, err := ParseADT(m, jst)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.EqualValues(t, "A40", adt.Event)
+		assert.EqualValues(t, "12345678", adt.MRN)
+		assert.EqualValues(t, "87654321", adt.PriorMRN)
+		assert.Nil(t, adt.BirthDate)
+		assert.Nil(t, adt.Age(time.Now()))
+		assert.Nil(t, adt.GestationalDays)
+		assert.True(t, time.Date(2024, time.March, 2, 10, 15, 0, 0, time.UTC).Equal(*adt.RecordedAt))
+	})
+
+	t.Run("区切り文字とエスケープ", func(t *testing.T) {
+		m, err := Parse(message(
+			`MSH#*~!$#HIS#HOSP#SPIKER#HOSP#20240302##ADT*A08#MSG0003#P#2.5`,
+			`PID#1##A!F!1##Smith*Jane!S!Ann`,
+			`OBX#1#NM#11884-4#1#265#d`,
+		))
+
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		adt, err := ParseADT(m, jst)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.EqualValues(t, "A08", adt.Event)
+		assert.EqualValues(t, "A#1", adt.MRN)
+		assert.EqualValues(t, "Smith Jane*Ann", *adt.Name())
+		assert.EqualValues(t, 265, *adt.GestationalDays)
+	})
+
+	t.Run("ADTではない", func(t *testing.T) {
+		m, _ := Parse(message(`MSH|^~\&|HIS|HOSP|SPIKER|HOSP|20240302||ORU^R01|MSG0004|P|2.5`))
+
+		_, err := ParseADT(m, jst)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("患者IDが無い", func(t *testing.T) {
+		m, _ := Parse(message(`MSH|^~\&|HIS|HOSP|SPIKER|HOSP|20240302||ADT^A04|MSG0005|P|2.5`, `PID|1||`))
+
+		_, err := ParseADT(m, jst)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("MSHで始まらない", func(t *testing.T) {
+		_, err := Parse(message(`PID|1||12345678`))
+
+		assert.Error(t, err)
+	})
+}
+
+func TestHL7_Ack(t *testing.T) {
+	m, _ := Parse(message(`MSH|^~\&|HIS|HOSP|SPIKER|WARD|20240302||ADT^A01|MSG0001|P|2.5`))
+
+	ack := Ack(m, AckError, "Patient|not found", time.Date(2024, time.March, 2, 10, 0, 0, 0, time.UTC))
+
+	assert.EqualValues(t, message(
+		`MSH|^~\&|SPIKER|WARD|HIS|HOSP|20240302100000||ACK^A01^ACK|ACKMSG0001|P|2.5`,
+		`MSA|AE|MSG0001|Patient\F\not found`,
+	), ack)
+
+	parsed, err := Parse(ack)
+
+	assert.NoError(t, err)
+	assert.EqualValues(t, "Patient|not found", parsed.Segment("MSA").Get(3, 0, 1))
+}
+
+func TestHL7_Frame(t *testing.T) {
+	var b bytes.Buffer
+
+	assert.NoError(t, WriteFrame(&b, []byte("MSH|1")))
+	assert.NoError(t, WriteFrame(&b, []byte("MSH|2")))
+
+	r := bufio.NewReader(bytes.NewReader(append([]byte("noise"), b.Bytes()...)))
+
+	for _, expected := range []string{"MSH|1", "MSH|2"} {
+		data, err := ReadFrame(r, 5)
+
+		assert.NoError(t, err)
+		assert.EqualValues(t, expected, string(data))
+	}
+
+	_, err := ReadFrame(r, 5)
+
+	assert.Error(t, err)
+}
+
+func TestHL7_FrameTooLarge(t *testing.T) {
+	var b bytes.Buffer
+
+	assert.NoError(t, WriteFrame(&b, []byte(strings.Repeat("x", 100))))
+
+	_, err := ReadFrame(bufio.NewReaderSize(bytes.NewReader(b.Bytes()), 16), 99)
+
+	assert.Equal(t, ErrFrameTooLarge, err)
+
+	data, err := ReadFrame(bufio.NewReaderSize(bytes.NewReader(b.Bytes()), 16), 100)
+
+	assert.NoError(t, err)
+	assert.Len(t, data, 100)
+}
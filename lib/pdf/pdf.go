@@ -0,0 +1,318 @@
+// 線、矩形、文字列のみからなる単純なPDF文書を生成する。
+//
+// 座標はページ左上を原点とするmm単位で指定する。
+// 文字列はASCIIのみの場合はHelvetica、それ以外は埋め込みを行わない日本語フォント(平成角ゴシック)で描画する。
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// 1mmあたりのポイント数。
+const pointsPerMM float64 = 72 / 25.4
+
+// 用紙サイズ(mm)。
+const (
+	A4Width  float64 = 210
+	A4Height float64 = 297
+)
+
+// PDF文書。
+type Document struct {
+	Width  float64
+	Height float64
+	Title  string
+	pages  []*Page
+}
+
+// ページ。描画命令を蓄積する。
+type Page struct {
+	document *Document
+	content  bytes.Buffer
+}
+
+// 座標。
+type Point struct {
+	X, Y float64
+}
+
+// 指定したサイズ(mm)の文書を作成する。
+func New(width float64, height float64) *Document {
+	return &Document{Width: width, Height: height, pages: []*Page{}}
+}
+
+// ページを追加する。
+func (d *Document) AddPage() *Page {
+	page := &Page{document: d}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// ページ数を返す。
+func (d *Document) PageCount() int {
+	return len(d.pages)
+}
+
+func (p *Page) x(v float64) float64 {
+	return v * pointsPerMM
+}
+
+func (p *Page) y(v float64) float64 {
+	return (p.document.Height - v) * pointsPerMM
+}
+
+func (p *Page) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&p.content, format, args...)
+	p.content.WriteByte('\n')
+}
+
+// 線の色を設定する。
+func (p *Page) SetStrokeColor(c color.RGBA) {
+	p.printf("%.3f %.3f %.3f RG", float64(c.R)/255, float64(c.G)/255, float64(c.B)/255)
+}
+
+// 塗りつぶしと文字の色を設定する。
+func (p *Page) SetFillColor(c color.RGBA) {
+	p.printf("%.3f %.3f %.3f rg", float64(c.R)/255, float64(c.G)/255, float64(c.B)/255)
+}
+
+// 線の太さ(mm)を設定する。
+func (p *Page) SetLineWidth(width float64) {
+	p.printf("%.3f w", width * pointsPerMM)
+}
+
+// 直線を描画する。
+func (p *Page) Line(x1, y1, x2, y2 float64) {
+	p.printf("%.2f %.2f m %.2f %.2f l S", p.x(x1), p.y(y1), p.x(x2), p.y(y2))
+}
+
+// 折れ線を描画する。
+func (p *Page) Polyline(points []Point) {
+	if len(points) < 2 {
+		return
+	}
+
+	p.printf("%.2f %.2f m", p.x(points[0].X), p.y(points[0].Y))
+
+	for _, pt := range points[1:] {
+		p.printf("%.2f %.2f l", p.x(pt.X), p.y(pt.Y))
+	}
+
+	p.printf("S")
+}
+
+// 矩形を描画する。fillがtrueの場合は塗りつぶし、falseの場合は枠線のみとする。
+func (p *Page) Rect(x, y, width, height float64, fill bool) {
+	op := "S"
+	if fill {
+		op = "f"
+	}
+
+	p.printf("%.2f %.2f %.2f %.2f re %s", p.x(x), p.y(y + height), width * pointsPerMM, height * pointsPerMM, op)
+}
+
+// 描画状態を保存する。
+func (p *Page) SaveState() {
+	p.printf("q")
+}
+
+// 描画状態を復元する。
+func (p *Page) RestoreState() {
+	p.printf("Q")
+}
+
+// 以降の描画を矩形内に制限する。RestoreStateで解除する。
+func (p *Page) ClipRect(x, y, width, height float64) {
+	p.printf("%.2f %.2f %.2f %.2f re W n", p.x(x), p.y(y + height), width * pointsPerMM, height * pointsPerMM)
+}
+
+// 文字列を描画する。yはベースラインの位置、sizeはポイント単位。
+func (p *Page) Text(x, y, size float64, text string) {
+	if text == "" {
+		return
+	}
+
+	if isASCII(text) {
+		p.printf("BT /F1 %.2f Tf %.2f %.2f Td (%s) Tj ET", size, p.x(x), p.y(y), escapeLiteral(text))
+	} else {
+		p.printf("BT /F2 %.2f Tf %.2f %.2f Td <%s> Tj ET", size, p.x(x), p.y(y), encodeUCS2(text))
+	}
+}
+
+// 文字列の幅(mm)を返す。
+func (p *Page) TextWidth(size float64, text string) float64 {
+	units := 0
+
+	if isASCII(text) {
+		for _, r := range text {
+			if r >= 32 && int(r) - 32 < len(helveticaWidths) {
+				units += helveticaWidths[r-32]
+			} else {
+				units += 556
+			}
+		}
+	} else {
+		for _, r := range text {
+			if r < 0x80 || (r >= 0xFF61 && r <= 0xFF9F) {
+				units += 500
+			} else {
+				units += 1000
+			}
+		}
+	}
+
+	return float64(units) / 1000 * size / pointsPerMM
+}
+
+// 文書をPDF形式で出力する。
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	buf := &bytes.Buffer{}
+	offsets := []int{}
+
+	begin := func() int {
+		offsets = append(offsets, buf.Len())
+		id := len(offsets)
+		fmt.Fprintf(buf, "%d 0 obj\n", id)
+		return id
+	}
+
+	end := func() {
+		buf.WriteString("endobj\n")
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// 1: カタログ、2: ページツリー、3-6: フォント、7: 文書情報。
+	begin()
+	buf.WriteString("<< /Type /Catalog /Pages 2 0 R >>\n")
+	end()
+
+	pageBase := 8
+	kids := []string{}
+	for i := range d.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageBase + i*2))
+	}
+
+	begin()
+	fmt.Fprintf(buf, "<< /Type /Pages /Kids [%s] /Count %d >>\n", strings.Join(kids, " "), len(d.pages))
+	end()
+
+	begin()
+	buf.WriteString("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>\n")
+	end()
+
+	begin()
+	buf.WriteString("<< /Type /Font /Subtype /Type0 /BaseFont /HeiseiKakuGo-W5 /Encoding /UniJIS-UCS2-HW-H /DescendantFonts [5 0 R] >>\n")
+	end()
+
+	begin()
+	buf.WriteString("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /HeiseiKakuGo-W5 " +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (Japan1) /Supplement 2 >> " +
+		"/FontDescriptor 6 0 R /DW 1000 /W [231 389 500 631 631 500] >>\n")
+	end()
+
+	begin()
+	buf.WriteString("<< /Type /FontDescriptor /FontName /HeiseiKakuGo-W5 /Flags 4 /FontBBox [-92 -250 1010 922] " +
+		"/ItalicAngle 0 /Ascent 752 /Descent -221 /CapHeight 737 /StemV 114 >>\n")
+	end()
+
+	begin()
+	fmt.Fprintf(buf, "<< /Producer (spiker-server) /Title <%s> >>\n", "FEFF" + encodeUCS2(d.Title))
+	end()
+
+	for _, page := range d.pages {
+		id := begin()
+		fmt.Fprintf(
+			buf,
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>\n",
+			d.Width * pointsPerMM, d.Height * pointsPerMM, id + 1,
+		)
+		end()
+
+		compressed := &bytes.Buffer{}
+		writer := zlib.NewWriter(compressed)
+
+		if _, e := writer.Write(page.content.Bytes()); e != nil {
+			return 0, e
+		}
+
+		if e := writer.Close(); e != nil {
+			return 0, e
+		}
+
+		begin()
+		fmt.Fprintf(buf, "<< /Length %d /Filter /FlateDecode >>\nstream\n", compressed.Len())
+		buf.Write(compressed.Bytes())
+		buf.WriteString("\nendstream\n")
+		end()
+	}
+
+	xref := buf.Len()
+
+	fmt.Fprintf(buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets) + 1)
+
+	for _, o := range offsets {
+		fmt.Fprintf(buf, "%010d 00000 n \n", o)
+	}
+
+	fmt.Fprintf(buf, "trailer\n<< /Size %d /Root 1 0 R /Info 7 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets) + 1, xref)
+
+	n, err := w.Write(buf.Bytes())
+
+	return int64(n), err
+}
+
+// 文書をPDF形式のバイト列として返す。
+func (d *Document) Bytes() ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	if _, e := d.WriteTo(buf); e != nil {
+		return nil, e
+	}
+
+	return buf.Bytes(), nil
+}
+
+func isASCII(text string) bool {
+	for _, r := range text {
+		if r >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+func escapeLiteral(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`, "\r", `\r`, "\n", `\n`)
+	return replacer.Replace(text)
+}
+
+// UCS-2のビッグエンディアンで16進表記する。BMP外の文字は下駄記号に置き換える。
+func encodeUCS2(text string) string {
+	b := strings.Builder{}
+
+	for _, r := range text {
+		if r > 0xFFFF || utf16.IsSurrogate(r) {
+			r = '〓'
+		}
+		fmt.Fprintf(&b, "%04X", r)
+	}
+
+	return b.String()
+}
+
+// Helveticaの文字幅(1000単位)。ASCIIの32から126まで。
+var helveticaWidths = []int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
@@ -0,0 +1,69 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image/color"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPdf_Bytes(t *testing.T) {
+	document := New(A4Height, A4Width)
+
+	first := document.AddPage()
+	first.SetStrokeColor(color.RGBA{255, 0, 0, 255})
+	first.SetLineWidth(0.5)
+	first.Line(10, 10, 100, 10)
+	first.Polyline([]Point{{10, 20}, {20, 30}, {30, 20}})
+	first.Text(10, 50, 12, "Heart rate (bpm)")
+	first.Text(10, 60, 12, "心拍数")
+
+	document.AddPage().Rect(10, 10, 50, 20, true)
+
+	bs, err := document.Bytes()
+
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(bs, []byte("%PDF-1.4\n")))
+	assert.True(t, bytes.HasSuffix(bs, []byte("%%EOF\n")))
+	assert.Contains(t, string(bs), "/Count 2")
+
+	// 相互参照表の位置が各オブジェクトの先頭を指す。
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(bs)
+	assert.NotNil(t, m)
+
+	xref, _ := strconv.Atoi(string(m[1]))
+	assert.True(t, bytes.HasPrefix(bs[xref:], []byte("xref\n")))
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(bs[xref:], -1)
+	assert.Len(t, entries, 11)
+
+	for i, e := range entries {
+		offset, _ := strconv.Atoi(string(e[1]))
+		assert.True(t, bytes.HasPrefix(bs[offset:], []byte(fmt.Sprintf("%d 0 obj\n", i+1))))
+	}
+
+	// 描画命令はページ毎に圧縮される。
+	stream := regexp.MustCompile(`(?s)stream\n(.*?)\nendstream`).FindSubmatch(bs)
+	reader, err := zlib.NewReader(bytes.NewReader(stream[1]))
+	assert.NoError(t, err)
+
+	content, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "(Heart rate \\(bpm\\)) Tj")
+	assert.Contains(t, string(content), "<5FC362CD6570> Tj")
+}
+
+func TestPdf_TextWidth(t *testing.T) {
+	page := New(A4Width, A4Height).AddPage()
+
+	// Helveticaの数字は556/1000。
+	assert.InDelta(t, 5.56 * 10 / pointsPerMM, page.TextWidth(10, "0123456789"), 0.001)
+	// 日本語フォントは全角1000/1000、半角500/1000。
+	assert.InDelta(t, 25 / pointsPerMM, page.TextWidth(10, "心拍a"), 0.001)
+}
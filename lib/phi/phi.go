@@ -0,0 +1,188 @@
+// 患者情報(PHI)の暗号化を行う。
+//
+// 暗号化した値は接頭辞に続いてノンスと暗号文をBase64で記載した文字列とし、接頭辞の無い値は暗号化前の平文とみなす。
+// マスター鍵が設定されていない場合は暗号化を行わない。
+package phi
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+const (
+	// 暗号化した値の接頭辞。
+	Prefix string = "phi1:"
+	// 鍵のバイト長。AES-256を用いる。
+	KeySize int = 32
+	// 索引のバイト長。
+	blindIndexSize int = 8
+)
+
+type Configuration struct {
+	// Base64形式のマスター鍵。病院毎の鍵の暗号化に用いる。
+	MasterKey string `envconfig:"MASTER_KEY"`
+}
+
+var masterKey []byte = nil
+
+func Setup(cfg *Configuration) error {
+	if cfg.MasterKey == "" {
+		masterKey = nil
+		return nil
+	}
+
+	if key, e := base64.StdEncoding.DecodeString(cfg.MasterKey); e != nil {
+		return fmt.Errorf("Master key is not in Base64 format: %v", e)
+	} else if len(key) != KeySize {
+		return fmt.Errorf("Master key must be %d bytes but %d bytes", KeySize, len(key))
+	} else {
+		masterKey = key
+	}
+
+	return nil
+}
+
+// 暗号化が有効かどうかを返す。
+func Enabled() bool {
+	return masterKey != nil
+}
+
+// 値が暗号化されているかどうかを返す。
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// 新しい鍵を生成する。
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+
+	if _, e := rand.Read(key); e != nil {
+		return nil, e
+	}
+
+	return key, nil
+}
+
+// 鍵をマスター鍵で暗号化する。
+func WrapKey(key []byte) (string, error) {
+	if masterKey == nil {
+		return "", fmt.Errorf("Master key is not configured")
+	}
+
+	return Seal(masterKey, string(key))
+}
+
+// マスター鍵で暗号化した鍵を復号する。
+func UnwrapKey(wrapped string) ([]byte, error) {
+	if masterKey == nil {
+		return nil, fmt.Errorf("Master key is not configured")
+	}
+
+	if key, e := Open(masterKey, wrapped); e != nil {
+		return nil, e
+	} else {
+		return []byte(key), nil
+	}
+}
+
+// 鍵の用途毎の派生鍵を作成する。
+func derive(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("Key must be %d bytes but %d bytes", KeySize, len(key))
+	}
+
+	block, err := aes.NewCipher(derive(key, "encryption"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func seal(aead cipher.AEAD, nonce []byte, plaintext string) string {
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return Prefix + base64.RawURLEncoding.EncodeToString(sealed)
+}
+
+// 平文を暗号化する。ノンスは乱数とし、同じ平文でも暗号化する度に異なる値となる。
+func Seal(key []byte, plaintext string) (string, error) {
+	aead, err := newAEAD(key)
+
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+
+	if _, e := rand.Read(nonce); e != nil {
+		return "", e
+	}
+
+	return seal(aead, nonce, plaintext), nil
+}
+
+// 平文を決定的に暗号化する。ノンスは平文のHMACから作成し、同じ鍵と平文からは同じ値となるため、暗号化したまま一致検索できる。
+// 値の一致は秘匿されないため、検索に用いる値にのみ使用する。
+func SealDeterministic(key []byte, plaintext string) (string, error) {
+	aead, err := newAEAD(key)
+
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, derive(key, "nonce"))
+	mac.Write([]byte(plaintext))
+
+	return seal(aead, mac.Sum(nil)[:aead.NonceSize()], plaintext), nil
+}
+
+// 値の索引を作成する。HMACによる一方向の値で、同じ鍵と値からは同じ索引となるため、暗号化した値の絞り込みに用いる。
+// 値の一致は秘匿されないため、照合は復号した値で改めて行う前提で、衝突を許容する長さに切り詰める。
+func BlindIndex(key []byte, value string) string {
+	mac := hmac.New(sha256.New, derive(key, "index"))
+	mac.Write([]byte(value))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:blindIndexSize])
+}
+
+// 暗号化した値を復号する。暗号化されていない値はそのまま返す。
+func Open(key []byte, value string) (string, error) {
+	if !IsSealed(value) {
+		return value, nil
+	}
+
+	aead, err := newAEAD(key)
+
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(value[len(Prefix):])
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("Sealed value is too short")
+	}
+
+	if plaintext, e := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil); e != nil {
+		return "", e
+	} else {
+		return string(plaintext), nil
+	}
+}
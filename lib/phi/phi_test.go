@@ -0,0 +1,109 @@
+package phi
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPHI_Seal(t *testing.T) {
+	key, err := GenerateKey()
+
+	assert.NoError(t, err)
+
+	a, err := Seal(key, "山田花子")
+
+	assert.NoError(t, err)
+	assert.True(t, IsSealed(a))
+	assert.NotContains(t, a, "山田花子")
+
+	b, _ := Seal(key, "山田花子")
+
+	// ノンスが異なるため暗号文は一致しない。
+	assert.NotEqual(t, a, b)
+
+	if v, e := Open(key, a); assert.NoError(t, e) {
+		assert.Equal(t, "山田花子", v)
+	}
+
+	// 異なる鍵では復号できない。
+	other, _ := GenerateKey()
+
+	_, err = Open(other, a)
+	assert.Error(t, err)
+}
+
+func TestPHI_SealDeterministic(t *testing.T) {
+	key, _ := GenerateKey()
+	other, _ := GenerateKey()
+
+	a, _ := SealDeterministic(key, "m0001")
+	b, _ := SealDeterministic(key, "m0001")
+	c, _ := SealDeterministic(key, "m0002")
+	d, _ := SealDeterministic(other, "m0001")
+
+	assert.Equal(t, a, b)
+	assert.NotEqual(t, a, c)
+	assert.NotEqual(t, a, d)
+
+	if v, e := Open(key, a); assert.NoError(t, e) {
+		assert.Equal(t, "m0001", v)
+	}
+}
+
+func TestPHI_Open(t *testing.T) {
+	key, _ := GenerateKey()
+
+	// 暗号化されていない値はそのまま返す。
+	if v, e := Open(key, "m0001"); assert.NoError(t, e) {
+		assert.Equal(t, "m0001", v)
+	}
+
+	_, err := Open(key, Prefix+"!!")
+	assert.Error(t, err)
+
+	_, err = Open(key, Prefix+"AAAA")
+	assert.Error(t, err)
+}
+
+func TestPHI_WrapKey(t *testing.T) {
+	assert.NoError(t, Setup(&Configuration{}))
+	assert.False(t, Enabled())
+
+	_, err := WrapKey([]byte(strings.Repeat("k", KeySize)))
+	assert.Error(t, err)
+
+	assert.Error(t, Setup(&Configuration{"short"}))
+	assert.Error(t, Setup(&Configuration{base64.StdEncoding.EncodeToString([]byte("short"))}))
+
+	master := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("m", KeySize)))
+
+	assert.NoError(t, Setup(&Configuration{master}))
+	assert.True(t, Enabled())
+
+	key, _ := GenerateKey()
+
+	wrapped, err := WrapKey(key)
+
+	assert.NoError(t, err)
+
+	if k, e := UnwrapKey(wrapped); assert.NoError(t, e) {
+		assert.Equal(t, key, k)
+	}
+
+	assert.NoError(t, Setup(&Configuration{}))
+}
+
+func TestPHI_BlindIndex(t *testing.T) {
+	key, _ := GenerateKey()
+	other, _ := GenerateKey()
+
+	a := BlindIndex(key, "山田")
+
+	assert.Equal(t, a, BlindIndex(key, "山田"))
+	assert.NotEqual(t, a, BlindIndex(key, "田中"))
+	assert.NotEqual(t, a, BlindIndex(other, "山田"))
+	assert.NotContains(t, a, "山田")
+}
@@ -0,0 +1,139 @@
+// 検索語の正規化と分割を行う。
+//
+// 全角英数字や半角カナはNFKCで統一し、英字は小文字に、カタカナはひらがなに揃える。
+// 日本語は単語を空白で区切らないため、空白で区切った語をそれぞれ部分一致で検索する前提とする。
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	kanaOffset rune = 'ァ' - 'ぁ'
+)
+
+// 文字列を検索用に正規化する。
+func Normalize(value string) string {
+	normalized := []rune(strings.ToLower(norm.NFKC.String(value)))
+
+	for i, r := range normalized {
+		if r >= 'ァ' && r <= 'ヶ' {
+			normalized[i] = r - kanaOffset
+		}
+	}
+
+	return string(normalized)
+}
+
+// 検索文字列を正規化し、空白で区切った語に分割する。重複する語は除く。
+func Terms(query string) []string {
+	terms := []string{}
+	found := map[string]bool{}
+
+	for _, t := range strings.FieldsFunc(Normalize(query), unicode.IsSpace) {
+		if !found[t] {
+			terms = append(terms, t)
+			found[t] = true
+		}
+	}
+
+	return terms
+}
+
+// 語が英数字のみで構成され、全文検索の単語として扱えるかを返す。
+func IsWord(term string) bool {
+	if term == "" {
+		return false
+	}
+
+	for _, r := range term {
+		if !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// 正規化されていない値と照合するための語の表記。ひらがなを含む場合はカタカナに置き換えた表記も含める。
+func Variants(term string) []string {
+	katakana := []rune(term)
+
+	for i, r := range katakana {
+		if r >= 'ぁ' && r <= 'ゖ' {
+			katakana[i] = r + kanaOffset
+		}
+	}
+
+	if string(katakana) == term {
+		return []string{term}
+	} else {
+		return []string{term, string(katakana)}
+	}
+}
+
+// 値が全ての語を含むかを返す。
+func Match(value string, terms []string) bool {
+	normalized := Normalize(value)
+
+	for _, t := range terms {
+		if !strings.Contains(normalized, t) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// 暗号化した値を部分一致で絞り込むための索引語を作成する。
+// 正規化した値を空白で区切り、各語の1文字と連続する2文字を重複なく返す。
+func Grams(value string) []string {
+	grams := []string{}
+	found := map[string]bool{}
+
+	add := func(gram string) {
+		if !found[gram] {
+			grams = append(grams, gram)
+			found[gram] = true
+		}
+	}
+
+	for _, word := range strings.FieldsFunc(Normalize(value), unicode.IsSpace) {
+		runes := []rune(word)
+
+		for i := range runes {
+			add(string(runes[i]))
+
+			if i+1 < len(runes) {
+				add(string(runes[i : i+2]))
+			}
+		}
+	}
+
+	return grams
+}
+
+// 正規化した語を含む値の索引語に、必ず含まれる索引語を返す。
+// 1文字の語はその文字、2文字以上の語は連続する2文字とする。
+func TermGrams(term string) []string {
+	runes := []rune(term)
+
+	if len(runes) == 1 {
+		return []string{term}
+	}
+
+	grams := []string{}
+	found := map[string]bool{}
+
+	for i := 0; i+1 < len(runes); i++ {
+		if gram := string(runes[i : i+2]); !found[gram] {
+			grams = append(grams, gram)
+			found[gram] = true
+		}
+	}
+
+	return grams
+}
@@ -0,0 +1,54 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "abc123", Normalize("ＡＢＣ１２３"))
+	assert.Equal(t, "やまだ たろう", Normalize("ヤマダ　タロウ"))
+	assert.Equal(t, "やまだ", Normalize("ﾔﾏﾀﾞ"))
+	assert.Equal(t, "山田 はなこ", Normalize("山田 ハナコ"))
+}
+
+func TestTerms(t *testing.T) {
+	assert.Equal(t, []string{"山田", "はなこ"}, Terms(" 山田　ハナコ  はなこ "))
+	assert.Equal(t, []string{}, Terms("　 "))
+}
+
+func TestIsWord(t *testing.T) {
+	assert.True(t, IsWord("m0001"))
+	assert.False(t, IsWord("m-0001"))
+	assert.False(t, IsWord("山田"))
+	assert.False(t, IsWord(""))
+}
+
+func TestVariants(t *testing.T) {
+	assert.Equal(t, []string{"山田"}, Variants("山田"))
+	assert.Equal(t, []string{"やまだ", "ヤマダ"}, Variants("やまだ"))
+}
+
+func TestMatch(t *testing.T) {
+	assert.True(t, Match("山田 ハナコ", []string{"山田", "はなこ"}))
+	assert.True(t, Match("ＹＡＭＡＤＡ", []string{"yama"}))
+	assert.False(t, Match("山田 ハナコ", []string{"山田", "たろう"}))
+}
+
+func TestGrams(t *testing.T) {
+	assert.Equal(t, []string{"山", "山田", "田", "は", "はな", "な", "なこ", "こ"}, Grams("山田 ハナコ"))
+	assert.Equal(t, []string{"あ", "あい", "い", "いあ"}, Grams("あいあい"))
+	assert.Equal(t, []string{}, Grams("　"))
+}
+
+func TestTermGrams(t *testing.T) {
+	assert.Equal(t, []string{"田"}, TermGrams("田"))
+	assert.Equal(t, []string{"はな", "なこ"}, TermGrams("はなこ"))
+
+	// 語を含む値の索引語は、語の索引語を全て含む。
+	grams := Grams("山田 ハナコ")
+	for _, g := range TermGrams("はなこ") {
+		assert.Contains(t, grams, g)
+	}
+}
@@ -0,0 +1,197 @@
+package strip
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/spiker/spiker-server/lib/pdf"
+)
+
+// 画像に描画するCanvas。座標はmm単位で受け取り、dotsPerMMを乗じて画素に変換する。
+//
+// 文字列は固定幅のビットマップフォントで描画するため、sizeは無視され、ASCII以外の文字は描画されない。
+type Raster struct {
+	Image     *image.RGBA
+	dotsPerMM float64
+	state     rasterState
+	stack     []rasterState
+}
+
+type rasterState struct {
+	stroke    color.RGBA
+	fill      color.RGBA
+	lineWidth float64
+	clip      image.Rectangle
+}
+
+// 指定したサイズ(mm)の白地の画像を作成する。
+func NewRaster(width float64, height float64, dotsPerMM float64) *Raster {
+	bounds := image.Rect(0, 0, int(math.Ceil(width * dotsPerMM)), int(math.Ceil(height * dotsPerMM)))
+
+	img := image.NewRGBA(bounds)
+	draw.Draw(img, bounds, image.White, image.Point{}, draw.Src)
+
+	return &Raster{
+		Image: img,
+		dotsPerMM: dotsPerMM,
+		state: rasterState{colorBlack, colorBlack, 0.1, bounds},
+		stack: []rasterState{},
+	}
+}
+
+func (r *Raster) px(v float64) int {
+	return int(math.Round(v * r.dotsPerMM))
+}
+
+// 塗りつぶしの色で、クリップ領域内の矩形を塗る。
+func (r *Raster) fillPixels(rect image.Rectangle, c color.RGBA) {
+	rect = rect.Intersect(r.state.clip)
+
+	if !rect.Empty() {
+		draw.Draw(r.Image, rect, &image.Uniform{c}, image.Point{}, draw.Over)
+	}
+}
+
+func (r *Raster) strokePixels() int {
+	if w := r.px(r.state.lineWidth); w > 1 {
+		return w
+	}
+	return 1
+}
+
+// 線の色を設定する。
+func (r *Raster) SetStrokeColor(c color.RGBA) {
+	r.state.stroke = c
+}
+
+// 塗りつぶしと文字の色を設定する。
+func (r *Raster) SetFillColor(c color.RGBA) {
+	r.state.fill = c
+}
+
+// 線の太さ(mm)を設定する。細い線も1画素以上で描画する。
+func (r *Raster) SetLineWidth(width float64) {
+	r.state.lineWidth = width
+}
+
+// 直線を描画する。線の太さの正方形を線に沿って並べる。
+func (r *Raster) Line(x1, y1, x2, y2 float64) {
+	w := r.strokePixels()
+	half := w / 2
+
+	px1, py1, px2, py2 := r.px(x1), r.px(y1), r.px(x2), r.px(y2)
+
+	// 水平、垂直な線は矩形として描画する。
+	if px1 == px2 || py1 == py2 {
+		rect := image.Rect(px1, py1, px2, py2).Canon()
+		r.fillPixels(image.Rect(rect.Min.X - half, rect.Min.Y - half, rect.Max.X - half + w, rect.Max.Y - half + w), r.state.stroke)
+		return
+	}
+
+	steps := int(math.Max(math.Abs(float64(px2 - px1)), math.Abs(float64(py2 - py1))))
+
+	for i := 0; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		x := int(math.Round(float64(px1) + float64(px2 - px1) * t))
+		y := int(math.Round(float64(py1) + float64(py2 - py1) * t))
+		r.fillPixels(image.Rect(x - half, y - half, x - half + w, y - half + w), r.state.stroke)
+	}
+}
+
+// 折れ線を描画する。
+func (r *Raster) Polyline(points []pdf.Point) {
+	for i := 1; i < len(points); i++ {
+		r.Line(points[i-1].X, points[i-1].Y, points[i].X, points[i].Y)
+	}
+}
+
+// 矩形を描画する。fillがtrueの場合は塗りつぶし、falseの場合は枠線のみとする。
+func (r *Raster) Rect(x, y, width, height float64, fill bool) {
+	if fill {
+		r.fillPixels(image.Rect(r.px(x), r.px(y), r.px(x + width), r.px(y + height)).Canon(), r.state.fill)
+		return
+	}
+
+	r.Line(x, y, x + width, y)
+	r.Line(x + width, y, x + width, y + height)
+	r.Line(x, y + height, x + width, y + height)
+	r.Line(x, y, x, y + height)
+}
+
+// 描画状態を保存する。
+func (r *Raster) SaveState() {
+	r.stack = append(r.stack, r.state)
+}
+
+// 描画状態を復元する。
+func (r *Raster) RestoreState() {
+	if len(r.stack) > 0 {
+		r.state = r.stack[len(r.stack)-1]
+		r.stack = r.stack[:len(r.stack)-1]
+	}
+}
+
+// 以降の描画を矩形内に制限する。RestoreStateで解除する。
+func (r *Raster) ClipRect(x, y, width, height float64) {
+	r.state.clip = r.state.clip.Intersect(image.Rect(r.px(x), r.px(y), r.px(x + width), r.px(y + height)).Canon())
+}
+
+// 文字列を描画する。yはベースラインの位置。
+func (r *Raster) Text(x, y, size float64, text string) {
+	if text == "" {
+		return
+	}
+
+	dst := r.Image.SubImage(r.state.clip).(*image.RGBA)
+
+	drawer := &font.Drawer{
+		Dst: dst,
+		Src: &image.Uniform{r.state.fill},
+		Face: basicfont.Face7x13,
+		Dot: fixed.P(r.px(x), r.px(y)),
+	}
+
+	drawer.DrawString(text)
+}
+
+// 文字列の幅(mm)を返す。
+func (r *Raster) TextWidth(size float64, text string) float64 {
+	return float64(font.MeasureString(basicfont.Face7x13, text).Round()) / r.dotsPerMM
+}
+
+// 期間全体を1枚に収めた記録紙をPNG画像として描画する。
+func RenderPNG(chart *Chart, speed int, dotsPerMM float64) ([]byte, error) {
+	minutes := int(math.Ceil(chart.End.Sub(chart.Begin).Minutes()))
+
+	if minutes < 1 {
+		minutes = 1
+	}
+
+	// 描画幅がちょうど期間の分数となる用紙幅とする。高さはA4横と同じとする。
+	width := margin + axisWidth + float64(minutes * speed * 10) + margin
+
+	layout := NewLayout(width, pdf.A4Width, speed)
+	raster := NewRaster(layout.Width, layout.Height, dotsPerMM)
+
+	page := *chart
+	page.End = chart.Begin.Add(time.Duration(minutes) * time.Minute)
+
+	Draw(raster, layout, &page, 0, 1)
+
+	buf := &bytes.Buffer{}
+
+	if e := png.Encode(buf, raster.Image); e != nil {
+		return nil, e
+	}
+
+	return buf.Bytes(), nil
+}
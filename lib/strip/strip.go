@@ -0,0 +1,463 @@
+// CTG記録紙を模したストリップチャートを描画する。
+//
+// 心拍は50-210bpmを20bpm/cm、子宮収縮は0-100を25/cmで描画し、時間軸は記録紙の送り速度(cm/分)に従う。
+// 描画先はCanvasとして抽象化し、PDFのページに出力する。
+package strip
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/spiker/spiker-server/lib/pdf"
+)
+
+// 描画先。座標は左上を原点とするmm単位。
+type Canvas interface {
+	SetStrokeColor(c color.RGBA)
+	SetFillColor(c color.RGBA)
+	SetLineWidth(width float64)
+	Line(x1, y1, x2, y2 float64)
+	Polyline(points []pdf.Point)
+	Rect(x, y, width, height float64, fill bool)
+	SaveState()
+	RestoreState()
+	ClipRect(x, y, width, height float64)
+	Text(x, y, size float64, text string)
+	TextWidth(size float64, text string) float64
+}
+
+// センサ値の標本。
+type Sample struct {
+	Value     int
+	Timestamp time.Time
+}
+
+// 期間を持つイベント。
+type Event struct {
+	Label      string
+	Risk       *int
+	RangeFrom  time.Time
+	RangeUntil time.Time
+}
+
+// 診断によるリスク値の区間。
+type RiskRange struct {
+	Risk       int
+	RangeFrom  time.Time
+	RangeUntil time.Time
+}
+
+// 描画する計測の内容。
+type Chart struct {
+	Title           string
+	Headers         []string
+	Begin           time.Time
+	End             time.Time
+	Location        *time.Location
+	HeartRates      []Sample
+	TOCOs           []Sample
+	ComputedEvents  []Event
+	AnnotatedEvents []Event
+	Risks           []RiskRange
+	Memo            string
+	CreatedAt       time.Time
+}
+
+// 選択可能な記録紙の送り速度(cm/分)。
+var Speeds = []int{1, 2, 3}
+
+const (
+	HeartRateMinimum int = 50
+	HeartRateMaximum int = 210
+	TOCOMinimum      int = 0
+	TOCOMaximum      int = 100
+	// 正常範囲として網掛けする心拍数。
+	NormalHeartRateMinimum int = 110
+	NormalHeartRateMaximum int = 160
+	// 欄外に記載するメモの最大行数。
+	MaximumMemoLines int = 5
+	// この間隔を超えて標本が途切れた場合は線を繋がない。
+	MaximumSampleGap time.Duration = time.Duration(5) * time.Second
+)
+
+var (
+	colorBlack      = color.RGBA{0, 0, 0, 255}
+	colorGrid       = color.RGBA{240, 180, 180, 255}
+	colorBoldGrid   = color.RGBA{220, 110, 110, 255}
+	colorNormal     = color.RGBA{232, 246, 232, 255}
+	colorHeartRate  = color.RGBA{20, 40, 160, 255}
+	colorTOCO       = color.RGBA{20, 120, 40, 255}
+	colorAnnotation = color.RGBA{120, 60, 160, 255}
+	// リスク値1から5の色。
+	riskColors = []color.RGBA{
+		{90, 180, 90, 255},
+		{170, 200, 80, 255},
+		{240, 200, 60, 255},
+		{240, 130, 50, 255},
+		{210, 40, 40, 255},
+	}
+)
+
+// 描画領域の配置。
+type Layout struct {
+	Width  float64
+	Height float64
+	Speed  int
+	// ページあたりの分数。
+	Minutes int
+	// 描画領域。
+	PlotLeft  float64
+	PlotWidth float64
+	HeaderTop float64
+	RiskTop   float64
+	EventTop  float64
+	FHRTop    float64
+	FHRHeight float64
+	UCTop     float64
+	UCHeight  float64
+	FooterTop float64
+}
+
+const (
+	margin      float64 = 10
+	axisWidth   float64 = 14
+	bandHeight  float64 = 4
+	headerSpace float64 = 16
+)
+
+// 用紙サイズ(mm)と送り速度から配置を決定する。ページあたりの時間は1分単位とする。
+func NewLayout(width float64, height float64, speed int) *Layout {
+	l := &Layout{Width: width, Height: height, Speed: speed}
+
+	l.PlotLeft = margin + axisWidth
+	l.Minutes = int((width - l.PlotLeft - margin) / l.mmPerMinute())
+
+	if l.Minutes < 1 {
+		l.Minutes = 1
+	}
+
+	l.PlotWidth = float64(l.Minutes) * l.mmPerMinute()
+
+	l.HeaderTop = margin
+	l.RiskTop = l.HeaderTop + headerSpace
+	l.EventTop = l.RiskTop + bandHeight + 1
+	l.FHRTop = l.EventTop + bandHeight*2 + 2
+	l.FHRHeight = float64(HeartRateMaximum - HeartRateMinimum) / 20 * 10
+	l.UCTop = l.FHRTop + l.FHRHeight + 8
+	l.UCHeight = float64(TOCOMaximum - TOCOMinimum) / 25 * 10
+	l.FooterTop = l.UCTop + l.UCHeight + 6
+
+	return l
+}
+
+func (l *Layout) mmPerMinute() float64 {
+	return float64(l.Speed) * 10
+}
+
+// ページあたりの時間。
+func (l *Layout) PageDuration() time.Duration {
+	return time.Duration(l.Minutes) * time.Minute
+}
+
+// 期間を描画するのに必要なページ数。
+func (l *Layout) PageCount(begin time.Time, end time.Time) int {
+	if !begin.Before(end) {
+		return 1
+	}
+	return int(math.Ceil(float64(end.Sub(begin)) / float64(l.PageDuration())))
+}
+
+func (l *Layout) timeX(pageBegin time.Time, t time.Time) float64 {
+	return l.PlotLeft + t.Sub(pageBegin).Minutes() * l.mmPerMinute()
+}
+
+func (l *Layout) heartRateY(v float64) float64 {
+	return l.FHRTop + (float64(HeartRateMaximum) - v) / float64(HeartRateMaximum - HeartRateMinimum) * l.FHRHeight
+}
+
+func (l *Layout) tocoY(v float64) float64 {
+	return l.UCTop + (float64(TOCOMaximum) - v) / float64(TOCOMaximum - TOCOMinimum) * l.UCHeight
+}
+
+// 記録紙をA4横のPDFとして描画する。
+func RenderPDF(chart *Chart, speed int) ([]byte, error) {
+	document := pdf.New(pdf.A4Height, pdf.A4Width)
+	document.Title = chart.Title
+
+	layout := NewLayout(document.Width, document.Height, speed)
+
+	pages := layout.PageCount(chart.Begin, chart.End)
+
+	for i := 0; i < pages; i++ {
+		Draw(document.AddPage(), layout, chart, i, pages)
+	}
+
+	return document.Bytes()
+}
+
+// 指定したページを描画する。pageは0から始まる。
+func Draw(canvas Canvas, layout *Layout, chart *Chart, page int, pages int) {
+	pageBegin := chart.Begin.Add(time.Duration(page) * layout.PageDuration())
+	pageEnd := pageBegin.Add(layout.PageDuration())
+
+	location := chart.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	drawHeader(canvas, layout, chart, location)
+	drawGrid(canvas, layout, pageBegin, location)
+
+	canvas.SaveState()
+	canvas.ClipRect(layout.PlotLeft, layout.RiskTop, layout.PlotWidth, layout.UCTop + layout.UCHeight - layout.RiskTop)
+
+	drawRisks(canvas, layout, chart.Risks, pageBegin, pageEnd)
+	drawEvents(canvas, layout, chart.ComputedEvents, layout.EventTop, nil, pageBegin, pageEnd)
+	drawEvents(canvas, layout, chart.AnnotatedEvents, layout.EventTop + bandHeight + 1, &colorAnnotation, pageBegin, pageEnd)
+
+	canvas.SetLineWidth(0.3)
+	canvas.SetStrokeColor(colorHeartRate)
+	drawTrace(canvas, layout, chart.HeartRates, pageBegin, pageEnd, HeartRateMinimum, HeartRateMaximum, layout.heartRateY)
+
+	canvas.SetStrokeColor(colorTOCO)
+	drawTrace(canvas, layout, chart.TOCOs, pageBegin, pageEnd, TOCOMinimum, TOCOMaximum, layout.tocoY)
+
+	canvas.RestoreState()
+
+	drawFooter(canvas, layout, chart, location, page, pages)
+}
+
+func drawHeader(canvas Canvas, layout *Layout, chart *Chart, location *time.Location) {
+	canvas.SetFillColor(colorBlack)
+	canvas.Text(margin, layout.HeaderTop + 4, 12, chart.Title)
+
+	for i, h := range chart.Headers {
+		canvas.Text(margin, layout.HeaderTop + 9 + float64(i) * 4, 8, h)
+	}
+
+	right := []string{
+		fmt.Sprintf("%s - %s", chart.Begin.In(location).Format("2006-01-02 15:04"), chart.End.In(location).Format("2006-01-02 15:04")),
+		fmt.Sprintf("%d cm/min", layout.Speed),
+	}
+
+	for i, r := range right {
+		canvas.Text(layout.Width - margin - canvas.TextWidth(8, r), layout.HeaderTop + 4 + float64(i) * 4, 8, r)
+	}
+
+	canvas.Text(margin, layout.RiskTop + bandHeight - 0.8, 6, "Risk")
+	canvas.Text(margin, layout.EventTop + bandHeight - 0.8, 6, "Auto")
+	canvas.Text(margin, layout.EventTop + bandHeight*2 + 0.2, 6, "Annot.")
+}
+
+func drawGrid(canvas Canvas, layout *Layout, pageBegin time.Time, location *time.Location) {
+	right := layout.PlotLeft + layout.PlotWidth
+
+	// 正常範囲。
+	canvas.SetFillColor(colorNormal)
+	canvas.Rect(
+		layout.PlotLeft, layout.heartRateY(float64(NormalHeartRateMaximum)),
+		layout.PlotWidth, layout.heartRateY(float64(NormalHeartRateMinimum)) - layout.heartRateY(float64(NormalHeartRateMaximum)),
+		true,
+	)
+
+	// 横線。
+	for v := HeartRateMinimum; v <= HeartRateMaximum; v += 10 {
+		bold := (v - 60) % 30 == 0
+		gridLine(canvas, bold)
+		y := layout.heartRateY(float64(v))
+		canvas.Line(layout.PlotLeft, y, right, y)
+
+		if bold {
+			canvas.SetFillColor(colorBlack)
+			label := fmt.Sprintf("%d", v)
+			canvas.Text(layout.PlotLeft - 1 - canvas.TextWidth(6, label), y + 1, 6, label)
+		}
+	}
+
+	for v := TOCOMinimum; v <= TOCOMaximum; v += 10 {
+		bold := v % 20 == 0
+		gridLine(canvas, bold)
+		y := layout.tocoY(float64(v))
+		canvas.Line(layout.PlotLeft, y, right, y)
+
+		if bold {
+			canvas.SetFillColor(colorBlack)
+			label := fmt.Sprintf("%d", v)
+			canvas.Text(layout.PlotLeft - 1 - canvas.TextWidth(6, label), y + 1, 6, label)
+		}
+	}
+
+	canvas.SetFillColor(colorBlack)
+	canvas.Text(margin, layout.FHRTop + 3, 6, "FHR")
+	canvas.Text(margin, layout.UCTop + 3, 6, "UC")
+
+	// 縦線。1cm毎に細線、ラベル間隔毎に太線とする。
+	labelMinutes := 1
+	if layout.Speed == 1 {
+		labelMinutes = 5
+	}
+
+	for cm := 0; cm <= layout.Minutes * layout.Speed; cm++ {
+		x := layout.PlotLeft + float64(cm) * 10
+		offset := time.Duration(cm) * time.Minute / time.Duration(layout.Speed)
+		bold := cm % layout.Speed == 0 && (cm / layout.Speed) % labelMinutes == 0
+
+		gridLine(canvas, bold)
+		canvas.Line(x, layout.FHRTop, x, layout.FHRTop + layout.FHRHeight)
+		canvas.Line(x, layout.UCTop, x, layout.UCTop + layout.UCHeight)
+
+		if bold {
+			label := pageBegin.Add(offset).In(location).Format("15:04")
+			canvas.SetFillColor(colorBlack)
+			canvas.Text(x - canvas.TextWidth(6, label) / 2, layout.FHRTop + layout.FHRHeight + 4, 6, label)
+		}
+	}
+
+	// 外枠。
+	canvas.SetStrokeColor(colorBoldGrid)
+	canvas.SetLineWidth(0.3)
+	canvas.Rect(layout.PlotLeft, layout.FHRTop, layout.PlotWidth, layout.FHRHeight, false)
+	canvas.Rect(layout.PlotLeft, layout.UCTop, layout.PlotWidth, layout.UCHeight, false)
+	canvas.Rect(layout.PlotLeft, layout.RiskTop, layout.PlotWidth, bandHeight, false)
+	canvas.Rect(layout.PlotLeft, layout.EventTop, layout.PlotWidth, bandHeight*2 + 1, false)
+}
+
+func gridLine(canvas Canvas, bold bool) {
+	if bold {
+		canvas.SetStrokeColor(colorBoldGrid)
+		canvas.SetLineWidth(0.2)
+	} else {
+		canvas.SetStrokeColor(colorGrid)
+		canvas.SetLineWidth(0.1)
+	}
+}
+
+func riskColor(risk int) color.RGBA {
+	if risk < 1 {
+		risk = 1
+	} else if risk > len(riskColors) {
+		risk = len(riskColors)
+	}
+	return riskColors[risk-1]
+}
+
+func drawRisks(canvas Canvas, layout *Layout, risks []RiskRange, pageBegin time.Time, pageEnd time.Time) {
+	for _, r := range risks {
+		if !r.RangeFrom.Before(pageEnd) || !r.RangeUntil.After(pageBegin) {
+			continue
+		}
+
+		x1 := layout.timeX(pageBegin, r.RangeFrom)
+		x2 := layout.timeX(pageBegin, r.RangeUntil)
+
+		canvas.SetFillColor(riskColor(r.Risk))
+		canvas.Rect(x1, layout.RiskTop, x2 - x1, bandHeight, true)
+	}
+}
+
+func drawEvents(
+	canvas Canvas,
+	layout *Layout,
+	events []Event,
+	top float64,
+	fill *color.RGBA,
+	pageBegin time.Time,
+	pageEnd time.Time,
+) {
+	for _, e := range events {
+		if !e.RangeFrom.Before(pageEnd) || !e.RangeUntil.After(pageBegin) {
+			continue
+		}
+
+		x1 := layout.timeX(pageBegin, e.RangeFrom)
+		x2 := layout.timeX(pageBegin, e.RangeUntil)
+
+		if x2 - x1 < 0.5 {
+			x2 = x1 + 0.5
+		}
+
+		if fill != nil {
+			canvas.SetFillColor(*fill)
+		} else if e.Risk != nil {
+			canvas.SetFillColor(riskColor(*e.Risk))
+		} else {
+			canvas.SetFillColor(colorGrid)
+		}
+
+		canvas.Rect(x1, top, x2 - x1, bandHeight, true)
+
+		// イベント区間を縦線で示す。
+		canvas.SetStrokeColor(colorBoldGrid)
+		canvas.SetLineWidth(0.1)
+		canvas.Line(x1, layout.FHRTop, x1, layout.UCTop + layout.UCHeight)
+
+		canvas.SetFillColor(colorBlack)
+		canvas.Text(x1 + 0.5, top + bandHeight - 0.8, 6, e.Label)
+	}
+}
+
+func drawTrace(
+	canvas Canvas,
+	layout *Layout,
+	samples []Sample,
+	pageBegin time.Time,
+	pageEnd time.Time,
+	minimum int,
+	maximum int,
+	yOf func(float64) float64,
+) {
+	points := []pdf.Point{}
+	var last *Sample = nil
+
+	flush := func() {
+		canvas.Polyline(points)
+		points = []pdf.Point{}
+	}
+
+	for i := range samples {
+		s := &samples[i]
+
+		if s.Timestamp.Before(pageBegin) || !s.Timestamp.Before(pageEnd) {
+			continue
+		}
+
+		// 範囲外の値は信号損失とみなして線を途切れさせる。
+		if s.Value < minimum || s.Value > maximum {
+			flush()
+			last = nil
+			continue
+		}
+
+		if last != nil && s.Timestamp.Sub(last.Timestamp) > MaximumSampleGap {
+			flush()
+		}
+
+		points = append(points, pdf.Point{X: layout.timeX(pageBegin, s.Timestamp), Y: yOf(float64(s.Value))})
+		last = s
+	}
+
+	flush()
+}
+
+func drawFooter(canvas Canvas, layout *Layout, chart *Chart, location *time.Location, page int, pages int) {
+	canvas.SetFillColor(colorBlack)
+
+	lines := strings.Split(strings.TrimSpace(chart.Memo), "\n")
+
+	for i, line := range lines {
+		if i >= MaximumMemoLines {
+			break
+		}
+		canvas.Text(margin, layout.FooterTop + 4 + float64(i) * 4, 8, strings.TrimRight(line, "\r"))
+	}
+
+	if !chart.CreatedAt.IsZero() {
+		canvas.Text(margin, layout.Height - margin, 6, chart.CreatedAt.In(location).Format("2006-01-02 15:04:05"))
+	}
+
+	label := fmt.Sprintf("%d / %d", page + 1, pages)
+	canvas.Text(layout.Width - margin - canvas.TextWidth(8, label), layout.Height - margin, 8, label)
+}
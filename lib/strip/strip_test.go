@@ -0,0 +1,108 @@
+package strip
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStrip_NewLayout(t *testing.T) {
+	// A4横の描画幅は263mm。
+	assert.EqualValues(t, 26, NewLayout(297, 210, 1).Minutes)
+	assert.EqualValues(t, 13, NewLayout(297, 210, 2).Minutes)
+	assert.EqualValues(t, 8, NewLayout(297, 210, 3).Minutes)
+
+	layout := NewLayout(297, 210, 3)
+	begin := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.EqualValues(t, 1, layout.PageCount(begin, begin.Add(time.Duration(8) * time.Minute)))
+	assert.EqualValues(t, 2, layout.PageCount(begin, begin.Add(time.Duration(8) * time.Minute + time.Second)))
+
+	assert.EqualValues(t, layout.FHRTop, layout.heartRateY(float64(HeartRateMaximum)))
+	assert.EqualValues(t, layout.FHRTop + layout.FHRHeight, layout.heartRateY(float64(HeartRateMinimum)))
+	// 送り速度3cm/分では1分が30mm。
+	assert.EqualValues(t, layout.PlotLeft + 30, layout.timeX(begin, begin.Add(time.Minute)))
+
+	// 描画領域が用紙に収まる。
+	assert.True(t, layout.PlotLeft + layout.PlotWidth <= 297 - margin)
+	assert.True(t, layout.FooterTop + float64(MaximumMemoLines) * 4 <= 210 - margin)
+}
+
+func TestStrip_RenderPDF(t *testing.T) {
+	begin := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	risk := 4
+
+	chart := &Chart{
+		Title: "CTG記録 measurement-0001",
+		Headers: []string{"患者: 山田 花子 (ID 1)", "端末: T1 (t-0001)"},
+		Begin: begin,
+		End: begin.Add(time.Duration(20) * time.Minute),
+		HeartRates: []Sample{},
+		TOCOs: []Sample{},
+		ComputedEvents: []Event{{"R4", &risk, begin.Add(time.Minute), begin.Add(time.Duration(3) * time.Minute)}},
+		AnnotatedEvents: []Event{},
+		Risks: []RiskRange{{2, begin, begin.Add(time.Duration(10) * time.Minute)}},
+		Memo: "終了メモ: 経過良好\n2行目",
+		CreatedAt: begin,
+	}
+
+	for i := 0; i < 20 * 60; i++ {
+		chart.HeartRates = append(chart.HeartRates, Sample{140, begin.Add(time.Duration(i) * time.Second)})
+		chart.TOCOs = append(chart.TOCOs, Sample{20, begin.Add(time.Duration(i) * time.Second)})
+	}
+
+	bs, err := RenderPDF(chart, 3)
+
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(bs, []byte("%PDF-")))
+	// 8分毎に3ページ。
+	assert.Contains(t, string(bs), "/Count 3")
+}
+
+func TestStrip_RenderPNG(t *testing.T) {
+	begin := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	risk := 4
+
+	chart := &Chart{
+		Title: "Event #1 measurement-0001",
+		Headers: []string{},
+		Begin: begin,
+		End: begin.Add(time.Duration(30) * time.Minute),
+		HeartRates: []Sample{},
+		TOCOs: []Sample{},
+		ComputedEvents: []Event{{"R4", &risk, begin.Add(time.Duration(14) * time.Minute), begin.Add(time.Duration(16) * time.Minute)}},
+		AnnotatedEvents: []Event{},
+		Risks: []RiskRange{},
+	}
+
+	for i := 0; i < 30 * 60; i++ {
+		chart.HeartRates = append(chart.HeartRates, Sample{140, begin.Add(time.Duration(i) * time.Second)})
+		chart.TOCOs = append(chart.TOCOs, Sample{20, begin.Add(time.Duration(i) * time.Second)})
+	}
+
+	bs, err := RenderPNG(chart, 1, 2)
+
+	assert.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(bs))
+
+	assert.NoError(t, err)
+
+	// 30分を1cm/分で描画するため、描画幅は300mm。
+	layout := NewLayout(margin + axisWidth + 300 + margin, 210, 1)
+
+	assert.EqualValues(t, 30, layout.Minutes)
+	assert.EqualValues(t, int(layout.Width * 2), img.Bounds().Dx())
+	assert.EqualValues(t, 420, img.Bounds().Dy())
+
+	// 心拍の線が描画されている。
+	x := int((layout.PlotLeft + 100) * 2)
+	y := int(math.Round(layout.heartRateY(140) * 2))
+
+	assert.EqualValues(t, colorHeartRate, color.RGBAModel.Convert(img.At(x, y)))
+}
@@ -0,0 +1,18 @@
+package model
+
+import (
+	"time"
+)
+
+// 計測端末への患者の割り当て(入院)。退院するまでの間、端末から送られた計測データは端末側の患者IDに関わらずPatientIdの患者の計測とする。
+// MeasurementIdは割り当て中に開始した計測で、データを受信するまではnil。DischargedAtは退院した日時で、割り当て中はnil。
+type TerminalAdmission struct {
+	Id            int        `db:"id" json:"id"`
+	HospitalId    int        `db:"hospital_id" json:"hospitalId"`
+	TerminalId    int        `db:"terminal_id" json:"terminalId"`
+	PatientId     int        `db:"patient_id" json:"patientId"`
+	DoctorId      int        `db:"doctor_id" json:"doctorId"`
+	MeasurementId *int       `db:"measurement_id" json:"measurementId"`
+	AdmittedAt    time.Time  `db:"admitted_at" json:"admittedAt"`
+	DischargedAt  *time.Time `db:"discharged_at" json:"dischargedAt"`
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+)
+
+// 分娩前のNST解析結果。変動の指標は拍動間隔(ms)、基準心拍数はbpmで表す。
+// Reasonsは基準を満たさない理由の配列。
+type AntenatalReport struct {
+	Id                    int       `db:"id" json:"id"`
+	MeasurementId         int       `db:"measurement_id" json:"measurementId"`
+	RangeFrom             time.Time `db:"range_from" json:"rangeFrom"`
+	RangeUntil            time.Time `db:"range_until" json:"rangeUntil"`
+	BasalHeartRate        *float64  `db:"basal_heart_rate" json:"basalHeartRate"`
+	ShortTermVariability  *float64  `db:"short_term_variability" json:"shortTermVariability"`
+	LongTermVariability   *float64  `db:"long_term_variability" json:"longTermVariability"`
+	HighVariationMinutes  int       `db:"high_variation_minutes" json:"highVariationMinutes"`
+	LowVariationMinutes   int       `db:"low_variation_minutes" json:"lowVariationMinutes"`
+	HighVariationEpisodes int       `db:"high_variation_episodes" json:"highVariationEpisodes"`
+	Accelerations         int       `db:"accelerations" json:"accelerations"`
+	Decelerations         int       `db:"decelerations" json:"decelerations"`
+	SignalLoss            float64   `db:"signal_loss" json:"signalLoss"`
+	CriteriaMet           bool      `db:"criteria_met" json:"criteriaMet"`
+	Reasons               JSON      `db:"reasons" json:"reasons"`
+	CreatedAt             time.Time `db:"created_at" json:"createdAt"`
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/spiker/spiker-server/lib"
+)
+
+// 心拍のアーチファクト。アーチファクトと判定された心拍と同じ日時に、種別のビットマスクを記録する。
+type HeartRateArtifact struct {
+	MeasurementId int
+	Flags         int
+	Timestamp     time.Time
+}
+
+func (p HeartRateArtifact) Measurement() string {
+	return "heartrate_artifact"
+}
+
+func (p *HeartRateArtifact) ToRecord(record *lib.SchemaRecord) {
+	record.Tags["measurement_id"] = strconv.Itoa(p.MeasurementId)
+	record.Fields["flags"] = int64(p.Flags)
+	record.Timestamp = p.Timestamp
+}
+
+func (p *HeartRateArtifact) FromRecord(record *lib.PointRecord) error {
+	if id, e := strconv.Atoi(record.Tags["measurement_id"]); e != nil {
+		return e
+	} else {
+		p.MeasurementId = id
+	}
+
+	switch record.Field {
+	case "flags":
+		if v, ok := record.Value.(int64); !ok {
+			return fmt.Errorf("Invalid value for artifact flags: %v", record.Value)
+		} else {
+			p.Flags = int(v)
+		}
+	}
+
+	p.Timestamp = record.Timestamp
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+)
+
+// 診断項目のガイドライン別分類。JSOGの分類は診断項目のリスクとして記録される。
+type DiagnosisContentClassification struct {
+	DiagnosisContentId int    `db:"diagnosis_content_id" json:"diagnosisContentId"`
+	Guideline          string `db:"guideline" json:"guideline"`
+	Category           string `db:"category" json:"category"`
+	Level              int    `db:"level" json:"level"`
+}
+
+// 自動診断イベントのガイドライン別分類。JSOGの分類はイベントのリスクとして記録される。
+type ComputedEventClassification struct {
+	ComputedEventId int    `db:"computed_event_id" json:"computedEventId"`
+	Guideline       string `db:"guideline" json:"guideline"`
+	Category        string `db:"category" json:"category"`
+	Level           int    `db:"level" json:"level"`
+}
+
+// 病院でアラートに利用するガイドライン。
+type HospitalGuideline struct {
+	HospitalId int       `db:"hospital_id" json:"hospitalId"`
+	Guideline  string    `db:"guideline" json:"guideline"`
+	ModifiedAt time.Time `db:"modified_at" json:"modifiedAt"`
+}
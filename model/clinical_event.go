@@ -0,0 +1,18 @@
+package model
+
+import (
+	"time"
+)
+
+// 計測中に医師が記録した臨床イベント。EndedAtは持続する処置の終了日時で、時点のイベントではnil。
+type ClinicalEvent struct {
+	Id            int        `db:"id" json:"id"`
+	MeasurementId int        `db:"measurement_id" json:"measurementId"`
+	DoctorId      *int       `db:"doctor_id" json:"doctorId"`
+	Category      string     `db:"category" json:"category"`
+	OccurredAt    time.Time  `db:"occurred_at" json:"occurredAt"`
+	EndedAt       *time.Time `db:"ended_at" json:"endedAt"`
+	Memo          string     `db:"memo" json:"memo"`
+	CreatedAt     time.Time  `db:"created_at" json:"createdAt"`
+	ModifiedAt    time.Time  `db:"modified_at" json:"modifiedAt"`
+}
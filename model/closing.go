@@ -0,0 +1,15 @@
+package model
+
+import (
+	"time"
+)
+
+// 病院毎の計測の自動完了の設定。最後にデータを受信してからMinutes分経過した計測は自動的に完了状態とする。
+// 完了した計測のコードにMinutes分以上空けてデータが届いた場合は、完了した計測を延長せずに新規の計測とする。
+// Minutesがnilの場合は自動完了せず、完了した計測のコードに届いたデータは完了した計測を延長する。
+// 設定の無い病院では既定の無受信期間を用いる。
+type HospitalAutoClose struct {
+	HospitalId int       `db:"hospital_id" json:"hospitalId"`
+	Minutes    *int      `db:"minutes" json:"minutes"`
+	ModifiedAt time.Time `db:"modified_at" json:"modifiedAt"`
+}
@@ -13,8 +13,15 @@ type measurementComposite struct {
 // 計測内の診断記録情報。
 type DiagnosisEntity struct {
 	*Diagnosis
-	Algorithm *DiagnosisAlgorithm `json:"algorithm"`
-	Contents  []*DiagnosisContent `json:"contents"`
+	Algorithm       *DiagnosisAlgorithm               `json:"algorithm"`
+	Contents        []*DiagnosisContent               `json:"contents"`
+	RiskTable       *RiskTable                        `json:"riskTable"`
+	Classifications []*DiagnosisContentClassification `json:"classifications"`
+	Contraction     *DiagnosisContraction             `json:"contraction"`
+	Timings         []*DecelerationTiming             `json:"timings"`
+	Gestation       *DiagnosisGestation               `json:"gestation"`
+	// 自動診断時に検出された子宮収縮。登録時に計測の子宮収縮として保存される。
+	Contractions []*Contraction `json:"-"`
 	//Annotator *Annotator `json:"annotator"`
 }
 
@@ -26,6 +33,14 @@ type SensorValue struct {
 	ObservedAt int64 `json:"observedAt"`
 }
 
+// 一定期間毎に集約したセンサ値。ObservedAtは期間の先頭。
+type SensorEnvelope struct {
+	Minimum    int     `json:"minimum"`
+	Maximum    int     `json:"maximum"`
+	Mean       float64 `json:"mean"`
+	ObservedAt int64   `json:"observedAt"`
+}
+
 // ログイン中の医師。
 type HospitalDoctor struct {
 	*Doctor
@@ -43,8 +58,10 @@ type DiagnosisMeasurmentEntity struct {
 // 自動診断イベント。
 type ComputedEventEntity struct {
 	*ComputedEvent
-	Measurement *Measurement       `json:"measurement"`
-	Annotations []*AnnotatedEvent  `json:"annotations"`
+	Measurement     *Measurement                   `json:"measurement"`
+	Annotations     []*AnnotatedEvent              `json:"annotations"`
+	Classifications []*ComputedEventClassification `json:"classifications"`
+	SnapshotUrl     *string                        `json:"snapshotUrl"`
 }
 
 // アノテーター登録イベント。
@@ -52,4 +69,19 @@ type AnnotatedEventEntity struct {
 	*AnnotatedEvent
 	Measurement *Measurement   `json:"measurement"`
 	Event       *ComputedEvent `json:"event"`
-}
\ No newline at end of file
+	// 病院のガイドラインでアラート対象となるか。アラートとして取得した場合のみ設定される。
+	IsAlert *bool `json:"isAlert,omitempty"`
+}
+
+// 一致度集計のための自動診断イベントとアノテーションの組。自動診断イベントが無い場合はnil。
+type EventAgreementEntity struct {
+	Computed  *ComputedEvent  `json:"computed"`
+	Annotated *AnnotatedEvent `json:"annotated"`
+}
+// 病院内の検索結果。一致したものを種類毎に持つ。
+type SearchResult struct {
+	Patients     []*Patient             `json:"patients"`
+	Measurements []*MeasurementEntity   `json:"measurements"`
+	Terminals    []*MeasurementTerminal `json:"terminals"`
+	Diagnoses    []*Diagnosis           `json:"diagnoses"`
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+)
+
+// TOCOから検出された子宮収縮。Amplitudeは静止圧からの上昇幅。
+type Contraction struct {
+	Id            int       `db:"id" json:"id"`
+	MeasurementId int       `db:"measurement_id" json:"measurementId"`
+	OnsetAt       time.Time `db:"onset_at" json:"onsetAt"`
+	PeakAt        time.Time `db:"peak_at" json:"peakAt"`
+	EndAt         time.Time `db:"end_at" json:"endAt"`
+	Amplitude     int       `db:"amplitude" json:"amplitude"`
+	CreatedAt     time.Time `db:"created_at" json:"createdAt"`
+}
+
+// 診断時点の子宮収縮頻度。Countは診断期間内の収縮回数、Frequencyは直近の10分あたりの平均収縮回数。
+type DiagnosisContraction struct {
+	DiagnosisId int     `db:"diagnosis_id" json:"diagnosisId"`
+	Count       int     `db:"count" json:"count"`
+	Frequency   float64 `db:"frequency" json:"frequency"`
+}
+
+// 子宮収縮との時間関係による一過性徐脈の分類。Lagは収縮の頂点から一過性徐脈の中央までの秒数。
+type DecelerationTiming struct {
+	DiagnosisContentId int     `db:"diagnosis_content_id" json:"diagnosisContentId"`
+	Timing             string  `db:"timing" json:"timing"`
+	Lag                float64 `db:"lag" json:"lag"`
+}
@@ -0,0 +1,8 @@
+package model
+
+// 診断時に判読基準として用いた在胎週数の区分。GestationalDaysは診断時点で患者に登録されていた在胎日数。
+type DiagnosisGestation struct {
+	DiagnosisId     int    `db:"diagnosis_id" json:"diagnosisId"`
+	GestationalDays *int   `db:"gestational_days" json:"gestationalDays"`
+	Band            string `db:"band" json:"band"`
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/spiker/spiker-server/lib"
+)
+
+// 双胎の第2児の心拍(FHR2)。PatientCodeは登録時の計測の特定にのみ用い、InfluxDBには記録しない。
+type SecondHeartRate struct {
+	MeasurementId int
+	PatientCode   string
+	MachineCode   string
+	Value         int
+	Timestamp     time.Time
+}
+
+func (p SecondHeartRate) Measurement() string {
+	return "heartrate2"
+}
+
+func (p *SecondHeartRate) ToRecord(record *lib.SchemaRecord) {
+	record.Tags["measurement_id"] = strconv.Itoa(p.MeasurementId)
+	record.Tags["machine_code"] = p.MachineCode
+	record.Fields["value"] = int64(p.Value)
+	record.Timestamp = p.Timestamp
+}
+
+func (p *SecondHeartRate) FromRecord(record *lib.PointRecord) error {
+	if id, e := strconv.Atoi(record.Tags["measurement_id"]); e != nil {
+		return e
+	} else {
+		p.MeasurementId = id
+	}
+
+	p.PatientCode = record.Tags["patient_code"]
+	p.MachineCode = record.Tags["machine_code"]
+
+	switch record.Field {
+	case "value":
+		if v, ok := record.Value.(int64); !ok {
+			return fmt.Errorf("Invalid value for second heart rate: %v", record.Value)
+		} else {
+			p.Value = int(v)
+		}
+	}
+
+	p.Timestamp = record.Timestamp
+
+	return nil
+}
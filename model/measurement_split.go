@@ -0,0 +1,17 @@
+package model
+
+import (
+	"time"
+)
+
+// 分割、統合した計測の計測データの付け替え待ち。
+// InfluxDBの計測データはトランザクション外のため、分割、統合をコミットした後に、FromMeasurementIdのBeginTimeからEndTimeまでの計測データを
+// ToMeasurementIdに付け替え、付け替えたものから取り除く。
+type MeasurementRetag struct {
+	Id                int       `db:"id" json:"id"`
+	FromMeasurementId int       `db:"from_measurement_id" json:"fromMeasurementId"`
+	ToMeasurementId   int       `db:"to_measurement_id" json:"toMeasurementId"`
+	BeginTime         time.Time `db:"begin_time" json:"beginTime"`
+	EndTime           time.Time `db:"end_time" json:"endTime"`
+	RequestedAt       time.Time `db:"requested_at" json:"requestedAt"`
+}
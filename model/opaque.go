@@ -0,0 +1,37 @@
+package model
+
+import (
+	"github.com/spiker/spiker-server/lib"
+)
+
+// InfluxDBに記録しない患者情報のタグ。
+var phiTags = []string{"patient_code"}
+
+// 患者情報のタグを除いて記録する計測データ。計測データは計測IDのみで患者と関連付ける。
+type opaquePoint struct {
+	lib.Point
+}
+
+func (p opaquePoint) ToRecord(record *lib.SchemaRecord) {
+	p.Point.ToRecord(record)
+
+	StripPHITags(record.Tags)
+}
+
+// タグから患者情報を除く。
+func StripPHITags(tags map[string]string) {
+	for _, t := range phiTags {
+		delete(tags, t)
+	}
+}
+
+// 計測データを患者情報のタグを除いて記録するものに変換する。
+func OpaquePoints(points ...lib.Point) []lib.Point {
+	results := []lib.Point{}
+
+	for _, p := range points {
+		results = append(results, opaquePoint{p})
+	}
+
+	return results
+}
@@ -0,0 +1,14 @@
+package model
+
+import (
+	"time"
+)
+
+// 転帰分析のための、リスクを持つ自動診断項目。
+type OutcomeContent struct {
+	PatientId   int       `db:"patient_id" json:"patientId"`
+	AlgorithmId int       `db:"algorithm_id" json:"algorithmId"`
+	Risk        int       `db:"risk" json:"risk"`
+	RangeFrom   time.Time `db:"range_from" json:"rangeFrom"`
+	RangeUntil  time.Time `db:"range_until" json:"rangeUntil"`
+}
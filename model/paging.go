@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+)
+
+// 一覧の取得範囲。
+// Cursorが空でない場合は前の一覧のNextCursorが指す行の次から取得し、Offsetは用いない。
+// Sortはソートする列の名前で、降順の場合は先頭に"-"を付ける。空の場合は一覧毎の既定の順とする。
+type Paging struct {
+	Limit  int
+	Offset int
+	Cursor string
+	Sort   string
+}
+
+// 取得した一覧の付加情報。
+// Totalは条件に合う全件数で、カーソルを指定した場合は数えず-1となる。NextCursorは続きの行がある場合のみ持つ。
+type PageInfo struct {
+	Total      int64
+	NextCursor *string
+}
+
+// 計測一覧の絞り込み条件。nilの条件では絞り込まない。
+// MinimumRisk、MaximumRiskは非表示でない自動診断イベントの最大リスクの下限と上限で、イベントの無い計測の最大リスクは0とする。
+// From、Untilは計測のデータの期間と重なる範囲とする。
+type MeasurementFilter struct {
+	PatientId   *int
+	TerminalId  *int
+	State       *string
+	MinimumRisk *int
+	MaximumRisk *int
+	From        *time.Time
+	Until       *time.Time
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"time"
+)
+
+// 患者の識別子。診察券番号など病院内で患者を特定する値で、計測コードと同様に病院の鍵で決定的に暗号化して保存する。
+// 病院内で一意とする。
+type PatientIdentifier struct {
+	Id         int       `db:"id" json:"id"`
+	HospitalId int       `db:"hospital_id" json:"hospitalId"`
+	PatientId  int       `db:"patient_id" json:"patientId"`
+	Value      string    `db:"value" json:"value"`
+	CreatedAt  time.Time `db:"created_at" json:"createdAt"`
+}
+
+// 病院毎の計測コードから患者を識別する規則。
+// Patternに一致する新規の計測コードは、最初のサブマッチ(無ければ一致した全体)を識別子とし、識別子を持つ既存の患者の計測とする。
+type HospitalPatientRule struct {
+	HospitalId int       `db:"hospital_id" json:"hospitalId"`
+	Pattern    string    `db:"pattern" json:"pattern"`
+	ModifiedAt time.Time `db:"modified_at" json:"modifiedAt"`
+}
+
+// 患者の統合記録。MergedPatientIdの患者の計測と識別子をPatientIdの患者に移し、MergedPatientIdの患者は削除する。
+// Fieldsは統合先に値が無く、統合元の値を引き継いだ項目の名前。DoctorIdはADTメッセージにより統合した場合はnil。
+type PatientMerge struct {
+	Id              int       `db:"id" json:"id"`
+	HospitalId      int       `db:"hospital_id" json:"hospitalId"`
+	PatientId       int       `db:"patient_id" json:"patientId"`
+	MergedPatientId int       `db:"merged_patient_id" json:"mergedPatientId"`
+	DoctorId        *int      `db:"doctor_id" json:"doctorId"`
+	Measurements    int       `db:"measurements" json:"measurements"`
+	Fields          JSON      `db:"fields" json:"fields"`
+	MergedAt        time.Time `db:"merged_at" json:"mergedAt"`
+}
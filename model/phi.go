@@ -0,0 +1,42 @@
+package model
+
+import (
+	"time"
+)
+
+// 病院毎の患者情報の暗号鍵。WrappedKeyはマスター鍵で暗号化した鍵で、計測コードの暗号化と患者毎の鍵の暗号化に用いる。
+type HospitalKey struct {
+	HospitalId int       `db:"hospital_id" json:"hospitalId"`
+	WrappedKey string    `db:"wrapped_key" json:"-"`
+	CreatedAt  time.Time `db:"created_at" json:"createdAt"`
+}
+
+// 患者毎の患者情報の暗号鍵。WrappedKeyは病院の鍵で暗号化した鍵で、削除すると患者の氏名は復号できなくなる。
+type PatientKey struct {
+	PatientId  int       `db:"patient_id" json:"patientId"`
+	WrappedKey string    `db:"wrapped_key" json:"-"`
+	CreatedAt  time.Time `db:"created_at" json:"createdAt"`
+}
+
+// 暗号化された患者の氏名の索引。Tokenは氏名の1文字と連続する2文字毎の、病院の鍵による索引で、検索時の候補の絞り込みに用いる。
+type PatientNameToken struct {
+	PatientId int    `db:"patient_id" json:"patientId"`
+	Token     string `db:"token" json:"-"`
+}
+
+// 患者の消去記録。消去した患者の情報は持たず、件数のみを記録する。
+type PatientErasure struct {
+	Id              int       `db:"id" json:"id"`
+	HospitalId      int       `db:"hospital_id" json:"hospitalId"`
+	PatientId       int       `db:"patient_id" json:"patientId"`
+	AdministratorId int       `db:"administrator_id" json:"administratorId"`
+	Measurements    int       `db:"measurements" json:"measurements"`
+	ErasedAt        time.Time `db:"erased_at" json:"erasedAt"`
+}
+
+// 消去した計測の、InfluxDBの計測データとストレージのスナップショット画像、アーカイブの削除待ち。
+// これらはトランザクション外のため、消去をコミットした後に削除し、削除できたものから取り除く。
+type MeasurementPurge struct {
+	MeasurementId int       `db:"measurement_id" json:"measurementId"`
+	RequestedAt   time.Time `db:"requested_at" json:"requestedAt"`
+}
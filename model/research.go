@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+)
+
+// 研究用データセットの出力ジョブ。HospitalIdsは対象病院IDの配列。
+// 対象の計測はデータの期間がRangeFrom、RangeUntilと重なるもので、nilの側は制限しない。
+// StorageKeyは完了時のストレージ上のファイル名、Errorは失敗時のエラー内容。
+type ResearchExport struct {
+	Id              int        `db:"id" json:"id"`
+	AdministratorId int        `db:"administrator_id" json:"administratorId"`
+	HospitalIds     JSON       `db:"hospital_ids" json:"hospitalIds"`
+	RangeFrom       *time.Time `db:"range_from" json:"rangeFrom"`
+	RangeUntil      *time.Time `db:"range_until" json:"rangeUntil"`
+	State           string     `db:"state" json:"state"`
+	StorageKey      *string    `db:"storage_key" json:"-"`
+	Patients        int        `db:"patients" json:"patients"`
+	Measurements    int        `db:"measurements" json:"measurements"`
+	Error           *string    `db:"error" json:"error"`
+	CreatedAt       time.Time  `db:"created_at" json:"createdAt"`
+	StartedAt       *time.Time `db:"started_at" json:"startedAt"`
+	CompletedAt     *time.Time `db:"completed_at" json:"completedAt"`
+	ModifiedAt      time.Time  `db:"modified_at" json:"modifiedAt"`
+}
+
+// ダウンロードURLを含む出力ジョブ。URLは完了したジョブのみ持つ。
+type ResearchExportEntity struct {
+	*ResearchExport
+	DownloadUrl *string `json:"downloadUrl"`
+}
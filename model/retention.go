@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+)
+
+// 病院毎の計測データの保存期間。計測の完了からYears年経過した計測データはアーカイブした上でInfluxDBから削除する。
+type HospitalRetention struct {
+	HospitalId int       `db:"hospital_id" json:"hospitalId"`
+	Years      int       `db:"years" json:"years"`
+	ModifiedAt time.Time `db:"modified_at" json:"modifiedAt"`
+}
+
+// 計測データのアーカイブ。StorageKeyはストレージ上のアーカイブのディレクトリ。
+// RestoredAtはアーカイブからInfluxDBに復元した日時で、復元していない場合はnil。
+type MeasurementArchive struct {
+	MeasurementId   int        `db:"measurement_id" json:"measurementId"`
+	StorageKey      string     `db:"storage_key" json:"-"`
+	Records         int        `db:"records" json:"records"`
+	ComputedEvents  int        `db:"computed_events" json:"computedEvents"`
+	AnnotatedEvents int        `db:"annotated_events" json:"annotatedEvents"`
+	ArchivedAt      time.Time  `db:"archived_at" json:"archivedAt"`
+	RestoredAt      *time.Time `db:"restored_at" json:"restoredAt"`
+}
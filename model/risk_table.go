@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+)
+
+// リスクテーブル。同じ名前のテーブルはバージョンで区別され、登録後に判定表は変更されない。
+type RiskTable struct {
+	Id         int       `db:"id" json:"id"`
+	Name       string    `db:"name" json:"name"`
+	Version    int       `db:"version" json:"version"`
+	Matrices   JSON      `db:"matrices" json:"matrices"`
+	Memo       string    `db:"memo" json:"memo"`
+	CreatedAt  time.Time `db:"created_at" json:"createdAt"`
+	ModifiedAt time.Time `db:"modified_at" json:"modifiedAt"`
+}
+
+// 病院で利用するリスクテーブル。
+type HospitalRiskTable struct {
+	HospitalId  int       `db:"hospital_id" json:"hospitalId"`
+	RiskTableId int       `db:"risk_table_id" json:"riskTableId"`
+	ModifiedAt  time.Time `db:"modified_at" json:"modifiedAt"`
+}
+
+// 診断のリスク算出に利用したリスクテーブル。
+type DiagnosisRiskTable struct {
+	DiagnosisId int `db:"diagnosis_id" json:"diagnosisId"`
+	RiskTableId int `db:"risk_table_id" json:"riskTableId"`
+}
@@ -0,0 +1,14 @@
+package model
+
+import (
+	"time"
+)
+
+// 高リスクの自動診断イベントについて、アラート時点のCTGを描画した画像。StorageKeyはストレージ上のファイル名。
+type EventSnapshot struct {
+	ComputedEventId int       `db:"computed_event_id" json:"computedEventId"`
+	StorageKey      string    `db:"storage_key" json:"storageKey"`
+	RangeFrom       time.Time `db:"range_from" json:"rangeFrom"`
+	RangeUntil      time.Time `db:"range_until" json:"rangeUntil"`
+	CreatedAt       time.Time `db:"created_at" json:"createdAt"`
+}
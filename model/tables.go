@@ -0,0 +1,64 @@
+package model
+
+import (
+	"github.com/spiker/spiker-server/lib"
+)
+
+type additionalTable struct {
+	holder        interface{}
+	name          string
+	autoIncrement bool
+	keys          []string
+}
+
+// SetupModels()で生成されるテーブル以外のテーブル定義。
+var additionalTables = []additionalTable{
+	{RiskTable{}, "risk_table", true, []string{"id"}},
+	{HospitalRiskTable{}, "hospital_risk_table", false, []string{"hospital_id"}},
+	{DiagnosisRiskTable{}, "diagnosis_risk_table", false, []string{"diagnosis_id"}},
+	{DiagnosisContentClassification{}, "diagnosis_content_classification", false, []string{"diagnosis_content_id", "guideline"}},
+	{ComputedEventClassification{}, "computed_event_classification", false, []string{"computed_event_id", "guideline"}},
+	{HospitalGuideline{}, "hospital_guideline", false, []string{"hospital_id"}},
+	{Contraction{}, "contraction", true, []string{"id"}},
+	{DiagnosisContraction{}, "diagnosis_contraction", false, []string{"diagnosis_id"}},
+	{DecelerationTiming{}, "deceleration_timing", false, []string{"diagnosis_content_id"}},
+	{AntenatalReport{}, "antenatal_report", true, []string{"id"}},
+	{DiagnosisGestation{}, "diagnosis_gestation", false, []string{"diagnosis_id"}},
+	{ClinicalEvent{}, "clinical_event", true, []string{"id"}},
+	{EventSnapshot{}, "event_snapshot", false, []string{"computed_event_id"}},
+	{ResearchExport{}, "research_export", true, []string{"id"}},
+	{HospitalKey{}, "hospital_key", false, []string{"hospital_id"}},
+	{PatientKey{}, "patient_key", false, []string{"patient_id"}},
+	{PatientNameToken{}, "patient_name_token", false, []string{"patient_id", "token"}},
+	{PatientErasure{}, "patient_erasure", true, []string{"id"}},
+	{MeasurementPurge{}, "measurement_purge", false, []string{"measurement_id"}},
+	{HospitalRetention{}, "hospital_retention", false, []string{"hospital_id"}},
+	{MeasurementArchive{}, "measurement_archive", false, []string{"measurement_id"}},
+	{MeasurementRetag{}, "measurement_retag", true, []string{"id"}},
+	{PatientIdentifier{}, "patient_identifier", true, []string{"id"}},
+	{HospitalPatientRule{}, "hospital_patient_rule", false, []string{"hospital_id"}},
+	{PatientMerge{}, "patient_merge", true, []string{"id"}},
+	{TerminalAdmission{}, "terminal_admission", true, []string{"id"}},
+	{HospitalAutoClose{}, "hospital_auto_close", false, []string{"hospital_id"}},
+}
+
+// SetupModels()で登録される以外のInfluxDBの計測種別。
+var additionalPointTypes = []func() lib.Point{
+	func() lib.Point { return &HeartRateArtifact{} },
+	func() lib.Point { return &SecondHeartRate{} },
+}
+
+// 追加テーブルを各DBに、追加の計測種別をInfluxDBに登録する。
+func SetupAdditionalModels() {
+	for _, key := range []string{lib.WriteDBKey, lib.ReadDBKey} {
+		if db := lib.GetDB(key); db != nil {
+			for _, t := range additionalTables {
+				db.AddTableWithName(t.holder, t.name).SetKeys(t.autoIncrement, t.keys...)
+			}
+		}
+	}
+
+	for _, factory := range additionalPointTypes {
+		lib.RegisterPointType(factory)
+	}
+}
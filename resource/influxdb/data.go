@@ -2,6 +2,7 @@ package influxdb
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	C "github.com/spiker/spiker-server/constant"
@@ -73,4 +74,226 @@ func ListTOCO(
 	}
 
 	return results, nil
-}
\ No newline at end of file
+}
+
+// 第2児の心拍データを古い順に取得する。
+func ListSecondHeartRate(
+	influx lib.InfluxDBClient,
+	measurementId int,
+	begin time.Time,
+	end time.Time,
+) ([]*model.SecondHeartRate, error) {
+	query := fmt.Sprintf(
+		`from(bucket:"spiker")
+			|> range(start:%d, stop:%d)
+			|> filter(fn: (r) => r._measurement == "%s" and r.measurement_id == "%d")
+			|> group()
+			|> sort(columns:["_time"])`,
+		begin.Unix(), end.Unix(),
+		model.SecondHeartRate{}.Measurement(), measurementId,
+	)
+
+	results := []*model.SecondHeartRate{}
+
+	if e := influx.Select(query, lib.PointConsumer(func(p lib.Point, field string) error {
+		if m, ok := p.(*model.SecondHeartRate); !ok {
+			return fmt.Errorf("Invalid measurement type for second heart rate: %s", p.Measurement())
+		} else {
+			results = append(results, m)
+			return nil
+		}
+	})); e != nil {
+		return nil, e
+	}
+
+	return results, nil
+}
+
+// 心拍のアーチファクトを古い順に取得する。
+func ListHeartRateArtifacts(
+	influx lib.InfluxDBClient,
+	measurementId int,
+	begin time.Time,
+	end time.Time,
+) ([]*model.HeartRateArtifact, error) {
+	query := fmt.Sprintf(
+		`from(bucket:"spiker")
+			|> range(start:%d, stop:%d)
+			|> filter(fn: (r) => r._measurement == "%s" and r.measurement_id == "%d")
+			|> group()
+			|> sort(columns:["_time"])`,
+		begin.Unix(), end.Unix(),
+		model.HeartRateArtifact{}.Measurement(), measurementId,
+	)
+
+	results := []*model.HeartRateArtifact{}
+
+	if e := influx.Select(query, lib.PointConsumer(func(p lib.Point, field string) error {
+		if m, ok := p.(*model.HeartRateArtifact); !ok {
+			return fmt.Errorf("Invalid measurement type for heart rate artifact: %s", p.Measurement())
+		} else {
+			results = append(results, m)
+			return nil
+		}
+	})); e != nil {
+		return nil, e
+	}
+
+	return results, nil
+}
+
+// 心拍もしくはTOCOデータを一定期間毎の最小、最大、平均に集約し、古い順に取得する。
+// 期間はUnixエポックを起点に区切り、データの無い期間は含まない。
+func ListSensorEnvelopes(
+	influx lib.InfluxDBClient,
+	measurementType C.MeasurementType,
+	measurementId int,
+	begin time.Time,
+	end time.Time,
+	window time.Duration,
+) ([]*model.SensorEnvelope, error) {
+	aggregate := func(fn string) string {
+		return fmt.Sprintf(
+			`data
+				|> aggregateWindow(every:%ds, fn:%s, timeSrc:"_start", createEmpty:false)
+				|> set(key:"_field", value:"%s")
+				|> keep(columns:["_time", "_field", "_value"])`,
+			int64(window.Seconds()), fn, fn,
+		)
+	}
+
+	query := fmt.Sprintf(
+		`data = from(bucket:"spiker")
+			|> range(start:%d, stop:%d)
+			|> filter(fn: (r) => r._measurement == "%s" and r.measurement_id == "%d")
+			|> group()
+		union(tables:[%s, %s, %s])
+			|> group()
+			|> sort(columns:["_time"])`,
+		begin.Unix(), end.Unix(),
+		measurementType, measurementId,
+		aggregate("min"), aggregate("max"), aggregate("mean"),
+	)
+
+	results := []*model.SensorEnvelope{}
+	envelopes := map[int64]*model.SensorEnvelope{}
+
+	if e := influx.Select(query, func(i int, field string, r *lib.PointRecord) error {
+		var value float64
+
+		switch v := r.Value.(type) {
+		case int64:
+			value = float64(v)
+		case uint64:
+			value = float64(v)
+		case float64:
+			value = v
+		default:
+			return fmt.Errorf("Invalid value for %s envelope: %v", measurementType, r.Value)
+		}
+
+		observedAt := r.Timestamp.UnixNano() / 1000000
+
+		envelope, be := envelopes[observedAt]
+
+		if !be {
+			envelope = &model.SensorEnvelope{ObservedAt: observedAt}
+			envelopes[observedAt] = envelope
+			results = append(results, envelope)
+		}
+
+		switch field {
+		case "min":
+			envelope.Minimum = int(value)
+		case "max":
+			envelope.Maximum = int(value)
+		case "mean":
+			envelope.Mean = value
+		}
+
+		return nil
+	}); e != nil {
+		return nil, e
+	}
+
+	return results, nil
+}
+
+// 複数の計測の心拍、TOCOデータを1つのクエリで古い順に取得する。
+func ListSensorPoints(
+	influx lib.InfluxDBClient,
+	measurementTypes []C.MeasurementType,
+	measurementIds []int,
+	begin time.Time,
+	end time.Time,
+) ([]lib.Point, error) {
+	results := []lib.Point{}
+
+	if len(measurementTypes) == 0 || len(measurementIds) == 0 {
+		return results, nil
+	}
+
+	types := []string{}
+	for _, t := range measurementTypes {
+		types = append(types, fmt.Sprintf(`"%s"`, t))
+	}
+
+	ids := []string{}
+	for _, id := range measurementIds {
+		ids = append(ids, fmt.Sprintf(`"%d"`, id))
+	}
+
+	query := fmt.Sprintf(
+		`from(bucket:"spiker")
+			|> range(start:%d, stop:%d)
+			|> filter(fn: (r) => contains(value: r._measurement, set: [%s]) and contains(value: r.measurement_id, set: [%s]))
+			|> group()
+			|> sort(columns:["_time"])`,
+		begin.Unix(), end.Unix(),
+		strings.Join(types, ", "), strings.Join(ids, ", "),
+	)
+
+	if e := influx.Select(query, lib.PointConsumer(func(p lib.Point, field string) error {
+		switch p.(type) {
+		case *model.HeartRate, *model.TOCO:
+			results = append(results, p)
+			return nil
+		default:
+			return fmt.Errorf("Invalid measurement type for sensor data: %s", p.Measurement())
+		}
+	})); e != nil {
+		return nil, e
+	}
+
+	return results, nil
+}
+
+// 計測の全ての計測種別のデータを、レコードのまま古い順に取得する。
+// 取得したデータを同じ範囲で削除できるよう、範囲は秒未満まで指定する。
+func ListRecords(
+	influx lib.InfluxDBClient,
+	measurementId int,
+	begin time.Time,
+	end time.Time,
+) ([]*lib.PointRecord, error) {
+	query := fmt.Sprintf(
+		`from(bucket:"spiker")
+			|> range(start:%s, stop:%s)
+			|> filter(fn: (r) => r.measurement_id == "%d")
+			|> group()
+			|> sort(columns:["_time"])`,
+		begin.UTC().Format(time.RFC3339Nano), end.UTC().Format(time.RFC3339Nano),
+		measurementId,
+	)
+
+	results := []*lib.PointRecord{}
+
+	if e := influx.Select(query, func(i int, field string, r *lib.PointRecord) error {
+		results = append(results, r)
+		return nil
+	}); e != nil {
+		return nil, e
+	}
+
+	return results, nil
+}
@@ -0,0 +1,87 @@
+package rds
+
+import (
+	"database/sql"
+
+	"github.com/spiker/spiker-server/model"
+)
+
+// 端末に割り当て中の患者を取得する。割り当てられていない場合はnilを返す。
+func FetchActiveAdmission(
+	db model.QueryExecutor,
+	terminalId int,
+) (*model.TerminalAdmission, error) {
+	record := model.TerminalAdmission{}
+
+	if e := db.SelectOne(
+		&record,
+		`SELECT * FROM terminal_admission WHERE terminal_id = $1 AND discharged_at IS NULL`,
+		terminalId,
+	); e != nil {
+		if e == sql.ErrNoRows {
+			return nil, nil
+		} else {
+			return nil, e
+		}
+	}
+
+	return &record, nil
+}
+
+// 複数の端末に割り当て中の患者を取得する。
+func InquireActiveAdmissions(
+	db model.QueryExecutor,
+	terminalIds []int,
+) ([]*model.TerminalAdmission, error) {
+	records := []*model.TerminalAdmission{}
+
+	if len(terminalIds) == 0 {
+		return records, nil
+	}
+
+	if _, e := db.Select(
+		&records,
+		`SELECT * FROM terminal_admission WHERE terminal_id IN (:ids) AND discharged_at IS NULL`,
+		map[string]interface{}{"ids": terminalIds},
+	); e != nil {
+		return nil, e
+	}
+
+	return records, nil
+}
+
+// 病院内で割り当て中の患者一覧を端末のID順に取得する。
+func ListActiveAdmissions(
+	db model.QueryExecutor,
+	hospitalId int,
+) ([]*model.TerminalAdmission, error) {
+	records := []*model.TerminalAdmission{}
+
+	if _, e := db.Select(
+		&records,
+		`SELECT * FROM terminal_admission WHERE hospital_id = $1 AND discharged_at IS NULL ORDER BY terminal_id ASC`,
+		hospitalId,
+	); e != nil {
+		return nil, e
+	}
+
+	return records, nil
+}
+
+// 患者が割り当てられている端末への割り当てを取得する。
+func ListPatientActiveAdmissions(
+	db model.QueryExecutor,
+	patientId int,
+) ([]*model.TerminalAdmission, error) {
+	records := []*model.TerminalAdmission{}
+
+	if _, e := db.Select(
+		&records,
+		`SELECT * FROM terminal_admission WHERE patient_id = $1 AND discharged_at IS NULL ORDER BY terminal_id ASC`,
+		patientId,
+	); e != nil {
+		return nil, e
+	}
+
+	return records, nil
+}
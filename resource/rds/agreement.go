@@ -0,0 +1,202 @@
+package rds
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/spiker/spiker-server/model"
+)
+
+// 一致度集計の対象となるイベントの条件を作成する。
+// イベントはエイリアスeで参照され、指定したアルゴリズムによる診断が期間の重なる形で存在しなければならない。
+func agreementConditions(
+	ip *incrementalPlaceholder,
+	algorithmId int,
+	hospitalId *int,
+	begin *time.Time,
+	end *time.Time,
+) *querying {
+	q := andQuery()
+
+	q.add(fmt.Sprintf(`EXISTS (
+		SELECT
+			*
+		FROM
+			diagnosis AS d
+			INNER JOIN computed_diagnosis AS cd ON d.id = cd.diagnosis_id
+		WHERE
+			d.measurement_id = e.measurement_id
+			AND cd.algorithm_id = $%d
+			AND d.range_from < e.range_until
+			AND d.range_until > e.range_from
+	)`, ip.GetIndex()), algorithmId)
+
+	if hospitalId != nil {
+		q.add(fmt.Sprintf(`p.hospital_id = $%d`, ip.GetIndex()), *hospitalId)
+	}
+
+	if begin != nil {
+		q.add(fmt.Sprintf(`e.range_until >= $%d`, ip.GetIndex()), *begin)
+	}
+
+	if end != nil {
+		q.add(fmt.Sprintf(`e.range_from < $%d`, ip.GetIndex()), *end)
+	}
+
+	return q
+}
+
+// 自動診断イベントとアノテーションの組を取得する。
+// 自動診断イベントに対しては、それを参照する最新のアノテーションが組となる。
+// アノテーションの無いイベントは、アノテーションのある計測(レビュー済みの計測)のものに限り、アノテーションをnilとした組として含まれる。
+// 自動診断イベントを参照しないアノテーションは、自動診断イベントをnilとした組として末尾に含まれる。
+func ListEventAgreementPairs(
+	db model.QueryExecutor,
+	algorithmId int,
+	hospitalId *int,
+	begin *time.Time,
+	end *time.Time,
+) ([]*model.EventAgreementEntity, error) {
+	records := []*model.EventAgreementEntity{}
+
+	// 自動診断イベントと最新のアノテーション。
+	ip := &incrementalPlaceholder{0}
+
+	where, params := agreementConditions(ip, algorithmId, hospitalId, begin, end).where()
+
+	query := fmt.Sprintf(
+		`SELECT
+			%s, %s
+		FROM
+			computed_event AS e
+			INNER JOIN (
+				SELECT
+					id, computed_event_id,
+					ROW_NUMBER() OVER (PARTITION BY computed_event_id ORDER BY created_at DESC, id DESC) AS row
+				FROM
+					annotated_event
+				WHERE
+					computed_event_id IS NOT NULL
+			) AS ae_ ON e.id = ae_.computed_event_id AND ae_.row = 1
+			INNER JOIN annotated_event AS ae ON ae_.id = ae.id
+			INNER JOIN measurement AS m ON e.measurement_id = m.id
+			INNER JOIN patient AS p ON m.patient_id = p.id
+		%s
+		ORDER BY e.range_from ASC, e.id ASC`,
+		prefixColumns(model.ComputedEvent{}, "e", "e"),
+		prefixColumns(model.AnnotatedEvent{}, "ae", "ae"),
+		where,
+	)
+
+	if rows, e := db.Query(query, params.values...); e != nil {
+		return nil, e
+	} else {
+		safeRowsIterator(rows, func(rows *sql.Rows) {
+			computed := &model.ComputedEvent{}
+			annotated := &model.AnnotatedEvent{}
+
+			scanRows(db, rows, computed, "e")
+			scanRows(db, rows, annotated, "ae")
+
+			records = append(records, &model.EventAgreementEntity{Computed: computed, Annotated: annotated})
+		})
+	}
+
+	// レビュー済みの計測の、アノテーションの無い自動診断イベント。
+	ip = &incrementalPlaceholder{0}
+
+	where, params = agreementConditions(ip, algorithmId, hospitalId, begin, end).
+		add(`NOT EXISTS (SELECT * FROM annotated_event WHERE computed_event_id = e.id)`).
+		add(`EXISTS (SELECT * FROM annotated_event WHERE measurement_id = e.measurement_id)`).
+		where()
+
+	query = fmt.Sprintf(
+		`SELECT
+			%s
+		FROM
+			computed_event AS e
+			INNER JOIN measurement AS m ON e.measurement_id = m.id
+			INNER JOIN patient AS p ON m.patient_id = p.id
+		%s
+		ORDER BY e.range_from ASC, e.id ASC`,
+		prefixColumns(model.ComputedEvent{}, "e", "e"),
+		where,
+	)
+
+	if rows, e := db.Query(query, params.values...); e != nil {
+		return nil, e
+	} else {
+		safeRowsIterator(rows, func(rows *sql.Rows) {
+			computed := &model.ComputedEvent{}
+
+			scanRows(db, rows, computed, "e")
+
+			records = append(records, &model.EventAgreementEntity{Computed: computed, Annotated: nil})
+		})
+	}
+
+	// 自動診断イベントを参照しないアノテーション。
+	ip = &incrementalPlaceholder{0}
+
+	where, params = agreementConditions(ip, algorithmId, hospitalId, begin, end).add(`e.computed_event_id IS NULL`).where()
+
+	query = fmt.Sprintf(
+		`SELECT
+			%s
+		FROM
+			annotated_event AS e
+			INNER JOIN measurement AS m ON e.measurement_id = m.id
+			INNER JOIN patient AS p ON m.patient_id = p.id
+		%s
+		ORDER BY e.range_from ASC, e.id ASC`,
+		prefixColumns(model.AnnotatedEvent{}, "e", "e"),
+		where,
+	)
+
+	if rows, e := db.Query(query, params.values...); e != nil {
+		return nil, e
+	} else {
+		safeRowsIterator(rows, func(rows *sql.Rows) {
+			annotated := &model.AnnotatedEvent{}
+
+			scanRows(db, rows, annotated, "e")
+
+			records = append(records, &model.EventAgreementEntity{Computed: nil, Annotated: annotated})
+		})
+	}
+
+	return records, nil
+}
+
+// アノテーションの無い計測(未レビューの計測)の自動診断イベント数を取得する。
+func CountUnreviewedComputedEvents(
+	db model.QueryExecutor,
+	algorithmId int,
+	hospitalId *int,
+	begin *time.Time,
+	end *time.Time,
+) (int, error) {
+	ip := &incrementalPlaceholder{0}
+
+	where, params := agreementConditions(ip, algorithmId, hospitalId, begin, end).
+		add(`NOT EXISTS (SELECT * FROM annotated_event WHERE measurement_id = e.measurement_id)`).
+		where()
+
+	query := fmt.Sprintf(
+		`SELECT
+			COUNT(*)
+		FROM
+			computed_event AS e
+			INNER JOIN measurement AS m ON e.measurement_id = m.id
+			INNER JOIN patient AS p ON m.patient_id = p.id
+		%s`,
+		where,
+	)
+
+	if n, e := db.SelectInt(query, params.values...); e != nil {
+		return 0, e
+	} else {
+		return int(n), nil
+	}
+}
@@ -0,0 +1,48 @@
+package rds
+
+import (
+	"database/sql"
+
+	"github.com/spiker/spiker-server/model"
+)
+
+// 計測のNST解析結果を新しい順に取得する。
+func ListAntenatalReports(
+	db model.QueryExecutor,
+	measurementId int,
+	limit int,
+	offset int,
+) ([]*model.AntenatalReport, int64, error) {
+	records := []*model.AntenatalReport{}
+
+	query := `SELECT * FROM antenatal_report WHERE measurement_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2 OFFSET $3`
+
+	if _, e := db.Select(&records, query, measurementId, limit, offset); e != nil {
+		return nil, 0, e
+	}
+
+	if total, e := db.SelectInt(`SELECT COUNT(*) FROM antenatal_report WHERE measurement_id = $1`, measurementId); e != nil {
+		return nil, 0, e
+	} else {
+		return records, total, nil
+	}
+}
+
+// 計測のNST解析結果をIDから取得する。
+func FetchAntenatalReport(
+	db model.QueryExecutor,
+	measurementId int,
+	id int,
+) (*model.AntenatalReport, error) {
+	record := model.AntenatalReport{}
+
+	if e := db.SelectOne(&record, `SELECT * FROM antenatal_report WHERE id = $1 AND measurement_id = $2`, id, measurementId); e != nil {
+		if e == sql.ErrNoRows {
+			return nil, nil
+		} else {
+			return nil, e
+		}
+	} else {
+		return &record, nil
+	}
+}
@@ -0,0 +1,136 @@
+package rds
+
+import (
+	"database/sql"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/model"
+)
+
+// 病院でアラートに利用するガイドラインを取得する。設定されていない場合はJSOGを返す。
+func FetchHospitalGuideline(
+	db model.QueryExecutor,
+	hospitalId int,
+) (C.Guideline, error) {
+	record := model.HospitalGuideline{}
+
+	if e := db.SelectOne(&record, `SELECT * FROM hospital_guideline WHERE hospital_id = $1`, hospitalId); e != nil {
+		if e == sql.ErrNoRows {
+			return C.GuidelineJSOG, nil
+		} else {
+			return "", e
+		}
+	} else {
+		return C.Guideline(record.Guideline), nil
+	}
+}
+
+// 自動診断イベントの分類を置き換える。
+func ReplaceEventClassifications(
+	db model.QueryExecutor,
+	eventId int,
+	classifications []*model.ComputedEventClassification,
+) error {
+	if _, e := db.Exec(`DELETE FROM computed_event_classification WHERE computed_event_id = $1`, eventId); e != nil {
+		return e
+	}
+
+	if len(classifications) > 0 {
+		holder := []interface{}{}
+
+		for _, c := range classifications {
+			c.ComputedEventId = eventId
+			holder = append(holder, c)
+		}
+
+		if e := db.Insert(holder...); e != nil {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// ComputedEventEntityに分類をセットする。
+func feedEventClassifications(
+	db model.QueryExecutor,
+	entities ...*model.ComputedEventEntity,
+) error {
+	if len(entities) == 0 {
+		return nil
+	}
+
+	ids := []int{}
+
+	for _, e := range entities {
+		ids = append(ids, e.ComputedEvent.Id)
+	}
+
+	records := []*model.ComputedEventClassification{}
+
+	if _, e := db.Select(
+		&records,
+		`SELECT * FROM computed_event_classification WHERE computed_event_id IN (:ids) ORDER BY guideline ASC`,
+		map[string]interface{}{"ids": ids},
+	); e != nil {
+		return e
+	}
+
+	recordMap := map[int][]*model.ComputedEventClassification{}
+
+	for _, r := range records {
+		recordMap[r.ComputedEventId] = append(recordMap[r.ComputedEventId], r)
+	}
+
+	for _, e := range entities {
+		if rs, be := recordMap[e.ComputedEvent.Id]; be {
+			e.Classifications = rs
+		} else {
+			e.Classifications = []*model.ComputedEventClassification{}
+		}
+	}
+
+	return nil
+}
+
+// DiagnosisEntityに診断項目の分類をセットする。
+func feedDiagnosisClassifications(
+	db model.QueryExecutor,
+	entities ...*model.DiagnosisEntity,
+) error {
+	ids := []int{}
+
+	for _, e := range entities {
+		for _, c := range e.Contents {
+			ids = append(ids, c.Id)
+		}
+	}
+
+	recordMap := map[int][]*model.DiagnosisContentClassification{}
+
+	if len(ids) > 0 {
+		records := []*model.DiagnosisContentClassification{}
+
+		if _, e := db.Select(
+			&records,
+			`SELECT * FROM diagnosis_content_classification WHERE diagnosis_content_id IN (:ids) ORDER BY diagnosis_content_id ASC, guideline ASC`,
+			map[string]interface{}{"ids": ids},
+		); e != nil {
+			return e
+		}
+
+		for _, r := range records {
+			recordMap[r.DiagnosisContentId] = append(recordMap[r.DiagnosisContentId], r)
+		}
+	}
+
+	for _, e := range entities {
+		e.Classifications = []*model.DiagnosisContentClassification{}
+
+		for _, c := range e.Contents {
+			e.Classifications = append(e.Classifications, recordMap[c.Id]...)
+		}
+	}
+
+	return nil
+}
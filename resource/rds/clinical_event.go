@@ -0,0 +1,47 @@
+package rds
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/spiker/spiker-server/model"
+)
+
+// 計測における期間内の臨床イベントを新しい順に取得する。持続する処置は期間と重なるものを含める。
+func ListClinicalEventsInRange(
+	db model.QueryExecutor,
+	measurementId int,
+	begin time.Time,
+	end time.Time,
+) ([]*model.ClinicalEvent, error) {
+	records := []*model.ClinicalEvent{}
+
+	query := `SELECT * FROM clinical_event
+		WHERE measurement_id = $1 AND occurred_at <= $2 AND COALESCE(ended_at, occurred_at) >= $3
+		ORDER BY occurred_at DESC, id DESC`
+
+	if _, e := db.Select(&records, query, measurementId, end, begin); e != nil {
+		return nil, e
+	}
+
+	return records, nil
+}
+
+// 計測の臨床イベントをIDから取得する。
+func FetchClinicalEvent(
+	db model.QueryExecutor,
+	measurementId int,
+	id int,
+) (*model.ClinicalEvent, error) {
+	record := model.ClinicalEvent{}
+
+	if e := db.SelectOne(&record, `SELECT * FROM clinical_event WHERE id = $1 AND measurement_id = $2`, id, measurementId); e != nil {
+		if e == sql.ErrNoRows {
+			return nil, nil
+		} else {
+			return nil, e
+		}
+	} else {
+		return &record, nil
+	}
+}
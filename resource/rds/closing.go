@@ -0,0 +1,53 @@
+package rds
+
+import (
+	"time"
+
+	"github.com/spiker/spiker-server/model"
+)
+
+// 病院の計測の自動完了の設定を取得する。設定されていない場合はnilを返す。
+func FetchHospitalAutoClose(
+	db model.QueryExecutor,
+	hospitalId int,
+) (*model.HospitalAutoClose, error) {
+	if r, e := db.Get(model.HospitalAutoClose{}, hospitalId); e != nil {
+		return nil, e
+	} else if r == nil {
+		return nil, nil
+	} else {
+		return r.(*model.HospitalAutoClose), nil
+	}
+}
+
+// 病院の無受信期間を過ぎても完了していない計測を取得する。
+// 無受信期間は病院の設定により、設定されていない病院ではdefaultMinutesとする。自動完了しない設定の病院の計測は対象としない。
+// データを受信していない計測は作成日時から数える。
+func ListInactiveMeasurements(
+	db model.QueryExecutor,
+	now time.Time,
+	defaultMinutes int,
+) ([]*model.Measurement, error) {
+	records := []*model.Measurement{}
+
+	if _, e := db.Select(
+		&records,
+		`SELECT
+			m.*
+		FROM
+			measurement AS m
+			INNER JOIN patient AS p ON m.patient_id = p.id
+			LEFT OUTER JOIN hospital_auto_close AS c ON p.hospital_id = c.hospital_id
+		WHERE
+			NOT m.is_closed
+			AND (c.hospital_id IS NULL OR c.minutes IS NOT NULL)
+			AND COALESCE(m.last_time, m.created_at) + make_interval(mins => COALESCE(c.minutes, $2)) <= $1
+		ORDER BY
+			m.id ASC`,
+		now, defaultMinutes,
+	); e != nil {
+		return nil, e
+	}
+
+	return records, nil
+}
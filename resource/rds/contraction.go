@@ -0,0 +1,173 @@
+package rds
+
+import (
+	"database/sql"
+	"time"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/model"
+)
+
+// 計測の子宮収縮を、収縮開始日時の古い順に取得する。
+func ListContractions(
+	db model.QueryExecutor,
+	measurementId int,
+	begin time.Time,
+	end time.Time,
+) ([]*model.Contraction, error) {
+	records := []*model.Contraction{}
+
+	query := `SELECT * FROM contraction WHERE measurement_id = $1 AND onset_at >= $2 AND onset_at < $3 ORDER BY onset_at ASC`
+
+	if _, e := db.Select(&records, query, measurementId, begin, end); e != nil {
+		return nil, e
+	}
+
+	return records, nil
+}
+
+// 指定期間に収縮開始日時を持つ計測の子宮収縮を、新たに検出されたもので置き換える。
+func ReplaceContractions(
+	db model.QueryExecutor,
+	measurementId int,
+	begin time.Time,
+	end time.Time,
+	contractions []*model.Contraction,
+) error {
+	if _, e := db.Exec(
+		`DELETE FROM contraction WHERE measurement_id = $1 AND onset_at >= $2 AND onset_at < $3`,
+		measurementId, begin, end,
+	); e != nil {
+		return e
+	}
+
+	if len(contractions) > 0 {
+		holder := []interface{}{}
+
+		for _, c := range contractions {
+			c.MeasurementId = measurementId
+			holder = append(holder, c)
+		}
+
+		if e := db.Insert(holder...); e != nil {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// 指定期間に収縮開始日時を持つ計測の子宮収縮の数を取得する。
+func CountContractions(
+	db model.QueryExecutor,
+	measurementId int,
+	begin time.Time,
+	end time.Time,
+) (int64, error) {
+	return db.SelectInt(
+		`SELECT COUNT(*) FROM contraction WHERE measurement_id = $1 AND onset_at >= $2 AND onset_at < $3`,
+		measurementId, begin, end,
+	)
+}
+
+// 頻収縮イベントを登録する。
+// 既存の頻収縮イベントの期間と重なる場合は、そのイベントを延長して頻収縮の値を更新する。
+func MergeTachysystole(
+	db model.QueryExecutor,
+	event *model.ComputedEvent,
+) (*model.ComputedEvent, error) {
+	current := model.ComputedEvent{}
+
+	query := `SELECT * FROM computed_event
+		WHERE measurement_id = $1 AND parameters->>'` + C.CTG_Tachysystole + `' IS NOT NULL AND range_until >= $2
+		ORDER BY range_from DESC LIMIT 1`
+
+	if e := db.SelectOne(&current, query, event.MeasurementId, event.RangeFrom); e != nil {
+		if e != sql.ErrNoRows {
+			return nil, e
+		}
+	} else {
+		if event.RangeUntil.After(current.RangeUntil) {
+			current.RangeUntil = event.RangeUntil
+		}
+		current.Risk = event.Risk
+		current.Parameters = event.Parameters
+		current.ModifiedAt = event.ModifiedAt
+
+		if _, e := db.Update(&current); e != nil {
+			return nil, e
+		}
+
+		return &current, nil
+	}
+
+	if e := db.Insert(event); e != nil {
+		return nil, e
+	}
+
+	return event, nil
+}
+
+// DiagnosisEntityに子宮収縮頻度と一過性徐脈の時間関係をセットする。
+func feedDiagnosisContractions(
+	db model.QueryExecutor,
+	entities ...*model.DiagnosisEntity,
+) error {
+	ids := []int{}
+	contentIds := []int{}
+
+	for _, e := range entities {
+		ids = append(ids, e.Diagnosis.Id)
+
+		for _, c := range e.Contents {
+			contentIds = append(contentIds, c.Id)
+		}
+	}
+
+	contractions := []*model.DiagnosisContraction{}
+
+	if _, e := db.Select(
+		&contractions,
+		`SELECT * FROM diagnosis_contraction WHERE diagnosis_id IN (:ids)`,
+		map[string]interface{}{"ids": ids},
+	); e != nil {
+		return e
+	}
+
+	contractionMap := map[int]*model.DiagnosisContraction{}
+
+	for _, c := range contractions {
+		contractionMap[c.DiagnosisId] = c
+	}
+
+	timingMap := map[int]*model.DecelerationTiming{}
+
+	if len(contentIds) > 0 {
+		timings := []*model.DecelerationTiming{}
+
+		if _, e := db.Select(
+			&timings,
+			`SELECT * FROM deceleration_timing WHERE diagnosis_content_id IN (:ids)`,
+			map[string]interface{}{"ids": contentIds},
+		); e != nil {
+			return e
+		}
+
+		for _, t := range timings {
+			timingMap[t.DiagnosisContentId] = t
+		}
+	}
+
+	for _, e := range entities {
+		e.Contraction = contractionMap[e.Diagnosis.Id]
+		e.Timings = []*model.DecelerationTiming{}
+
+		for _, c := range e.Contents {
+			if t, be := timingMap[c.Id]; be {
+				e.Timings = append(e.Timings, t)
+			}
+		}
+	}
+
+	return nil
+}
@@ -117,19 +117,52 @@ func ListDiagnoses(
 		if e := feedDiagnosisContents(db, records...); e != nil {
 			return nil, e
 		}
+
+		if e := feedDiagnosisRiskTables(db, records...); e != nil {
+			return nil, e
+		}
+
+		if e := feedDiagnosisClassifications(db, records...); e != nil {
+			return nil, e
+		}
+
+		if e := feedDiagnosisContractions(db, records...); e != nil {
+			return nil, e
+		}
+
+		if e := feedDiagnosisGestations(db, records...); e != nil {
+			return nil, e
+		}
 	}
 
 	return records, nil
 }
 
 // ある病院において、指定した日時以降の既定値以上のリスクを持つ自動診断を全て取得する。
+// JSOG以外のガイドラインでは、そのガイドラインにおける診断項目の分類レベルをリスクとして比較する。
 func ListFollowingDiagnoses(
 	db model.QueryExecutor,
 	hospitalId int,
+	guideline C.Guideline,
 	risk int,
 	beginTime time.Time,
 ) ([]*model.DiagnosisEntity, error) {
 	// REVIEW リスクが規定値を超える診断項目を、指定日時以降に持つ診断に絞り込む。
+	params := []interface{}{hospitalId, beginTime, risk}
+
+	maxRisk := `SELECT MAX(risk) FROM diagnosis_content WHERE diagnosis_id = d.id AND range_from >= $2`
+
+	if guideline != C.GuidelineJSOG {
+		maxRisk = `SELECT
+				MAX(cc.level)
+			FROM
+				diagnosis_content AS c
+				INNER JOIN diagnosis_content_classification AS cc ON c.id = cc.diagnosis_content_id
+			WHERE
+				c.diagnosis_id = d.id AND c.range_from >= $2 AND cc.guideline = $4`
+		params = append(params, string(guideline))
+	}
+
 	query := fmt.Sprintf(
 		`SELECT
 			%s, %s
@@ -141,16 +174,17 @@ func ListFollowingDiagnoses(
 			INNER JOIN diagnosis_algorithm AS a ON cd.algorithm_id = a.id
 		WHERE
 			p.hospital_id = $1
-			AND (SELECT MAX(risk) FROM diagnosis_content WHERE diagnosis_id = d.id AND range_from >= $2) >= $3
+			AND (%s) >= $3
 		ORDER BY
 			d.range_from ASC`,
 		prefixColumns(model.Diagnosis{}, "d", "d"),
 		prefixColumns(model.DiagnosisAlgorithm{}, "a", "a"),
+		maxRisk,
 	)
 
 	records := []*model.DiagnosisEntity{}
 
-	if rows, e := db.Query(query, hospitalId, beginTime, risk); e != nil {
+	if rows, e := db.Query(query, params...); e != nil {
 		return nil, e
 	} else {
 		safeRowsIterator(rows, func(rows *sql.Rows) {
@@ -171,6 +205,22 @@ func ListFollowingDiagnoses(
 		if e := feedDiagnosisContents(db, records...); e != nil {
 			return nil, e
 		}
+
+		if e := feedDiagnosisRiskTables(db, records...); e != nil {
+			return nil, e
+		}
+
+		if e := feedDiagnosisClassifications(db, records...); e != nil {
+			return nil, e
+		}
+
+		if e := feedDiagnosisContractions(db, records...); e != nil {
+			return nil, e
+		}
+
+		if e := feedDiagnosisGestations(db, records...); e != nil {
+			return nil, e
+		}
 	}
 
 	return records, nil
@@ -215,6 +265,22 @@ func FetchDiagnosis(
 		if e := feedDiagnosisContents(db, entity); e != nil {
 			return nil, e
 		}
+
+		if e := feedDiagnosisRiskTables(db, entity); e != nil {
+			return nil, e
+		}
+
+		if e := feedDiagnosisClassifications(db, entity); e != nil {
+			return nil, e
+		}
+
+		if e := feedDiagnosisContractions(db, entity); e != nil {
+			return nil, e
+		}
+
+		if e := feedDiagnosisGestations(db, entity); e != nil {
+			return nil, e
+		}
 	}
 
 	return entity, nil
@@ -297,4 +363,84 @@ func feedDiagnosisContents(
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+// 病院内の診断を、患者、計測と期間で検索する。期間は診断の期間が重なるものとする。
+// 返すエンティティは診断項目を持たない。
+func SearchDiagnoses(
+	db model.QueryExecutor,
+	hospitalId int,
+	patientId *int,
+	measurementId *int,
+	begin *time.Time,
+	end *time.Time,
+	limit int,
+	offset int,
+) ([]*model.DiagnosisEntity, int64, error) {
+	ip := incrementalPlaceholder{0}
+
+	q := andQuery().add(fmt.Sprintf("p.hospital_id = $%d", ip.GetIndex()), hospitalId)
+
+	if patientId != nil {
+		q.add(fmt.Sprintf("m.patient_id = $%d", ip.GetIndex()), *patientId)
+	}
+	if measurementId != nil {
+		q.add(fmt.Sprintf("d.measurement_id = $%d", ip.GetIndex()), *measurementId)
+	}
+	if begin != nil {
+		q.add(fmt.Sprintf("d.range_until >= $%d", ip.GetIndex()), *begin)
+	}
+	if end != nil {
+		q.add(fmt.Sprintf("d.range_from < $%d", ip.GetIndex()), *end)
+	}
+
+	where, params := q.where()
+
+	query := fmt.Sprintf(
+		`SELECT
+			%s, %s
+		FROM
+			diagnosis AS d
+			INNER JOIN measurement AS m ON d.measurement_id = m.id
+			INNER JOIN patient AS p ON m.patient_id = p.id
+			LEFT JOIN computed_diagnosis AS cd ON d.id = cd.diagnosis_id
+			LEFT JOIN diagnosis_algorithm AS a ON cd.algorithm_id = a.id
+		%s
+		ORDER BY
+			d.range_from ASC, d.id ASC
+		LIMIT $%d OFFSET $%d`,
+		prefixColumns(model.Diagnosis{}, "d", "d"),
+		prefixColumns(model.DiagnosisAlgorithm{}, "a", "a"),
+		where, ip.GetIndex(), ip.GetIndex(),
+	)
+
+	records := []*model.DiagnosisEntity{}
+
+	if rows, e := db.Query(query, params.clone().add(limit, offset).values...); e != nil {
+		return nil, 0, e
+	} else {
+		safeRowsIterator(rows, func(rows *sql.Rows) {
+			entity := model.DiagnosisEntity{
+				Diagnosis: &model.Diagnosis{},
+				Algorithm: &model.DiagnosisAlgorithm{},
+				Contents: []*model.DiagnosisContent{},
+			}
+
+			scanRows(db, rows, entity.Diagnosis, "d")
+			scanRows(db, rows, entity.Algorithm, "a")
+
+			records = append(records, &entity)
+		})
+	}
+
+	if total, e := db.SelectInt(fmt.Sprintf(`SELECT
+			COUNT(*)
+		FROM
+			diagnosis AS d
+			INNER JOIN measurement AS m ON d.measurement_id = m.id
+			INNER JOIN patient AS p ON m.patient_id = p.id
+		%s`, where), params.values...); e != nil {
+		return nil, 0, e
+	} else {
+		return records, total, nil
+	}
+}
@@ -65,26 +65,69 @@ func UpdateDoctorTokenVersion(
 	return err
 }
 
-// 病院内の医者をID順に取得する。
+// 医者一覧のソートに指定できる列。
+var doctorSorts = map[string]string{
+	"id":      "d.id",
+	"loginId": "d.login_id",
+	"name":    "d.name",
+}
+
+// 病院内の医者を取得する。既定ではID順にソートする。
 func ListDoctorsInHospital(
 	db model.QueryExecutor,
 	hospitalId int,
-	limit int,
-	offset int,
-) ([]*model.Doctor, int64, error) {
-	query := `SELECT * FROM doctor WHERE hospital_id = $1 ORDER BY id ASC LIMIT $2 OFFSET $3`
+	paging *model.Paging,
+) ([]*model.Doctor, *model.PageInfo, error) {
+	order, err := newSortOrder(paging.Sort, "id", doctorSorts, "d.id")
 
-	records := []*model.Doctor{}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pager := newPager(paging, order)
+
+	ip := incrementalPlaceholder{0}
 
-	if _, e := db.Select(&records, query, hospitalId, limit, offset); e != nil {
-		return nil, 0, e
+	q := andQuery().add(fmt.Sprintf("d.hospital_id = $%d", ip.GetIndex()), hospitalId)
+
+	if e := pager.filter(q, &ip); e != nil {
+		return nil, nil, e
 	}
 
-	if total, e := db.SelectInt(`SELECT COUNT(*) FROM doctor WHERE hospital_id = $1`, hospitalId); e != nil {
-		return nil, 0, e
+	where, params := q.where()
+	clause, clauseParams := pager.clause(&ip)
+
+	query := fmt.Sprintf(
+		`SELECT %s, %s FROM doctor AS d %s %s`,
+		prefixColumns(model.Doctor{}, "d", "d"),
+		order.cursorColumns(),
+		where, clause,
+	)
+
+	records := []*model.Doctor{}
+
+	if rows, e := db.Query(query, params.add(clauseParams...).values...); e != nil {
+		return nil, nil, e
 	} else {
-		return records, total, nil
+		safeRowsIterator(rows, func(rows *sql.Rows) {
+			doctor := &model.Doctor{}
+
+			scanRows(db, rows, doctor, "d")
+			pager.scan(rows)
+
+			records = append(records, doctor)
+		})
 	}
+
+	length, info, err := pager.page(len(records), func() (int64, error) {
+		return db.SelectInt(`SELECT COUNT(*) FROM doctor WHERE hospital_id = $1`, hospitalId)
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return records[0:length], info, nil
 }
 
 // 病院と医者の関連を調べる。
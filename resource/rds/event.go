@@ -7,7 +7,7 @@ import (
 	"strings"
 	"time"
 
-	//C "github.com/spiker/spiker-server/constant"
+	C "github.com/spiker/spiker-server/constant"
 	"github.com/spiker/spiker-server/model"
 )
 
@@ -71,7 +71,7 @@ func FetchComputedEvent(
 			scanRows(db, rows, annotation, "ae")
 
 			if entity == nil {
-				entity = &model.ComputedEventEntity{event, measurement, []*model.AnnotatedEvent{}}
+				entity = &model.ComputedEventEntity{event, measurement, []*model.AnnotatedEvent{}, []*model.ComputedEventClassification{}, nil}
 			}
 
 			if annotation.Id != 0 {
@@ -79,6 +79,12 @@ func FetchComputedEvent(
 			}
 		})
 
+		if entity != nil {
+			if e := feedEventClassifications(db, entity); e != nil {
+				return nil, e
+			}
+		}
+
 		return entity, nil
 	}
 }
@@ -178,9 +184,11 @@ func ListAnnotatedEventsInRange(
 }
 
 // ある病院において、指定した日時以降の既定値以上のリスクを持つ自動診断を古い順に取得する。
+// JSOG以外のガイドラインでは、そのガイドラインの分類レベルをリスクとして比較する。
 func ListUnreadComputedEvents(
 	db model.QueryExecutor,
 	hospitalId int,
+	guideline C.Guideline,
 	risk int,
 	beginTime time.Time,
 	measurementFrom time.Time,
@@ -191,7 +199,16 @@ func ListUnreadComputedEvents(
 	q.add(`NOT e.is_suspended`)
 	q.add(fmt.Sprintf(`m.last_time >= $%d`, ip.GetIndex()), measurementFrom)
 	q.add(fmt.Sprintf(`p.hospital_id = $%d`, ip.GetIndex()), hospitalId)
-	q.add(fmt.Sprintf(`e.risk >= $%d`, ip.GetIndex()), risk)
+
+	if guideline == C.GuidelineJSOG {
+		q.add(fmt.Sprintf(`e.risk >= $%d`, ip.GetIndex()), risk)
+	} else {
+		q.add(fmt.Sprintf(
+			`EXISTS (SELECT * FROM computed_event_classification WHERE computed_event_id = e.id AND guideline = $%d AND level >= $%d)`,
+			ip.GetIndex(), ip.GetIndex(),
+		), string(guideline), risk)
+	}
+
 	q.add(fmt.Sprintf(`e.range_from >= $%d`, ip.GetIndex()), beginTime)
 
 	where, params := q.where()
@@ -214,9 +231,12 @@ func ListUnreadComputedEvents(
 }
 
 // 指定した計測に関して、それぞれ最新のアノテーションを取得する。
+// アノテーションには、ガイドラインにおいて指定したレベル以上かどうかがアラート対象としてセットされる。
 func FetchLatestAnnotationsForMeasurements(
 	db model.QueryExecutor,
 	measurements []int,
+	guideline C.Guideline,
+	level int,
 	now time.Time,
 ) ([]*model.AnnotatedEventEntity, error) {
 	if len(measurements) == 0 {
@@ -241,9 +261,12 @@ func FetchLatestAnnotationsForMeasurements(
 
 	where, params := q.where()
 
+	alert, alertParams := annotationLevelCondition(ip, guideline, level)
+	params.add(alertParams...)
+
 	query := fmt.Sprintf(
 		`SELECT
-			%s, %s, %s
+			%s, %s, %s, COALESCE(%s, false) AS a_is_alert
 		FROM
 			annotated_event AS e
 			INNER JOIN (
@@ -260,78 +283,108 @@ func FetchLatestAnnotationsForMeasurements(
 		prefixColumns(model.AnnotatedEvent{}, "e", "e"),
 		prefixColumns(model.Measurement{}, "m", "m"),
 		prefixColumns(model.ComputedEvent{}, "ce", "ce"),
+		alert,
 		where,
 	)
 
 	return constructAnnotatedEntities(db, query, params)
 }
 
-// ある病院において、指定した日時以降の既定値以上のリスクを持つアノテーションを古い順に取得する。
-// 自動診断イベントを参照しないアノテーションは、条件を満たせば取得対象となる。
-// 参照する場合、同じイベントを参照するアノテーションの内、最も新しいものだけが取得対象となる。
-func ListAlertAnnotatedEvents(
+// アノテーションが、計測の病院がアラートに用いるガイドラインでアラート対象となるか調べる。
+func IsAlertAnnotation(
 	db model.QueryExecutor,
-	hospitalId int,
-	risk int,
-	beginTime time.Time,
-	now time.Time,
-) ([]*model.AnnotatedEventEntity, error) {
-	ip := &incrementalPlaceholder{0}
+	annotationId int,
+) (bool, error) {
+	hospitalId, err := db.SelectInt(
+		`SELECT
+			p.hospital_id
+		FROM
+			annotated_event AS e
+			INNER JOIN measurement AS m ON e.measurement_id = m.id
+			INNER JOIN patient AS p ON m.patient_id = p.id
+		WHERE
+			e.id = $1`,
+		annotationId,
+	)
 
-	q := andQuery()
-	q.add(fmt.Sprintf(`p.hospital_id = $%d`, ip.GetIndex()), hospitalId)
-	q.add(fmt.Sprintf(`e.range_until >= $%d`, ip.GetIndex()), beginTime)
-	q.add(fmt.Sprintf(`e.risk >= $%d`, ip.GetIndex()), risk)
-	q.add(fmt.Sprintf(`NOT EXISTS (
-		SELECT * FROM measurement_alert WHERE measurement_id = m.id AND silent_from <= $%d AND silent_until >= $%d
-	)`, ip.GetIndex(), ip.GetIndex()), now, now)
+	if err != nil {
+		return false, err
+	}
 
-	where, params := q.where()
+	guideline, err := FetchHospitalGuideline(db, int(hospitalId))
+
+	if err != nil {
+		return false, err
+	}
+
+	ip := &incrementalPlaceholder{1}
+
+	cond, params := annotationLevelCondition(ip, guideline, guideline.AlertLevel())
 
 	query := fmt.Sprintf(
 		`SELECT
-			%s, %s, %s
+			CASE WHEN COALESCE(%s, false) THEN 1 ELSE 0 END
 		FROM
 			annotated_event AS e
-			INNER JOIN (
-				SELECT
-					id, computed_event_id,
-					ROW_NUMBER() OVER (PARTITION BY computed_event_id ORDER BY created_at DESC, id DESC) AS row
-				FROM
-					annotated_event
-			) AS e_ ON e.id = e_.id AND (e_.computed_event_id IS NULL OR e_.row = 1)
-			INNER JOIN measurement AS m ON e.measurement_id = m.id
-			INNER JOIN patient AS p ON m.patient_id = p.id
 			LEFT JOIN computed_event AS ce ON e.computed_event_id = ce.id
-		%s
-		ORDER BY e.range_from ASC`,
-		prefixColumns(model.AnnotatedEvent{}, "e", "e"),
-		prefixColumns(model.Measurement{}, "m", "m"),
-		prefixColumns(model.ComputedEvent{}, "ce", "ce"),
-		where,
+		WHERE
+			e.id = $1`,
+		cond,
 	)
 
-	return constructAnnotatedEntities(db, query, params)
+	if n, e := db.SelectInt(query, append([]interface{}{annotationId}, params...)...); e != nil {
+		return false, e
+	} else {
+		return n == 1, nil
+	}
+}
+
+// アノテーションeがガイドラインにおいて指定したレベル以上かを判定する式。参照する自動診断イベントをceとする。
+// JSOG以外では、イベントのリスクを変更していないアノテーションはイベントの分類で判定し、
+// 変更したもの、及びイベントを参照しないものはアノテーターによるリスクを分類レベルに対応付けて判定する。
+func annotationLevelCondition(
+	ip *incrementalPlaceholder,
+	guideline C.Guideline,
+	level int,
+) (string, []interface{}) {
+	if guideline == C.GuidelineJSOG {
+		return fmt.Sprintf(`e.risk >= $%d`, ip.GetIndex()), []interface{}{level}
+	}
+
+	return fmt.Sprintf(
+		`CASE
+			WHEN ce.risk = e.risk AND EXISTS (
+				SELECT * FROM computed_event_classification WHERE computed_event_id = ce.id AND guideline = $%d
+			) THEN EXISTS (
+				SELECT * FROM computed_event_classification WHERE computed_event_id = ce.id AND guideline = $%d AND level >= $%d
+			)
+			ELSE e.risk >= $%d
+		END`,
+		ip.GetIndex(), ip.GetIndex(), ip.GetIndex(), ip.GetIndex(),
+	), []interface{}{string(guideline), string(guideline), level, guideline.MinimumRiskForLevel(level)}
 }
 
 // ある計測の自動判読イベントに新規診断結果を接続する。
+// 診断項目IDから、その項目が接続されたイベントへのマップを返す。捨てられた診断項目は含まれない。
 func MergeNewDiagnosis(
 	db model.QueryExecutor,
 	measurementId int,
 	contents []*model.DiagnosisContent,
 	now time.Time,
-) error {
+) (map[int]*model.ComputedEvent, error) {
+	merged := map[int]*model.ComputedEvent{}
+
 	if len(contents) == 0 {
-		return nil
+		return merged, nil
 	}
 
-	// 最も古い診断結果以降のイベントを取得。
+	// 最も古い診断結果以降のイベントを取得。頻収縮イベントは診断結果とは独立に管理されるため除く。
 	currentEvents := []*model.ComputedEvent{}
 
-	query := `SELECT * FROM computed_event WHERE measurement_id = $1 AND range_until >= $2 ORDER BY range_from ASC`
+	query := `SELECT * FROM computed_event WHERE measurement_id = $1 AND range_until >= $2 AND parameters->>'` + C.CTG_Tachysystole + `' IS NULL ORDER BY range_from ASC`
 
 	if _, e := db.Select(&currentEvents, query, measurementId, contents[0].RangeFrom); e != nil {
-		return e
+		return nil, e
 	}
 
 	// 最新の既存イベントの期間の先頭より前の新規診断結果は捨てる。
@@ -368,6 +421,8 @@ func MergeNewDiagnosis(
 				latestEvent.Risk = c.Risk
 				latestEvent.RangeUntil = c.RangeUntil
 				latestEvent.Parameters = c.Parameters
+
+				merged[c.Id] = latestEvent
 			} else {
 				break
 			}
@@ -379,7 +434,7 @@ func MergeNewDiagnosis(
 	// 変更があれば既存の最新イベントを更新。
 	if latestModified {
 		if _, e := db.Update(latestEvent); e != nil {
-			return e
+			return nil, e
 		}
 	}
 
@@ -388,7 +443,7 @@ func MergeNewDiagnosis(
 		holder := []interface{}{}
 
 		for _, c := range contents {
-			holder = append(holder, &model.ComputedEvent{
+			event := &model.ComputedEvent{
 				MeasurementId: measurementId,
 				Risk: c.Risk,
 				Memo: "",
@@ -398,15 +453,19 @@ func MergeNewDiagnosis(
 				RangeUntil: c.RangeUntil,
 				CreatedAt: now,
 				ModifiedAt: now,
-			})
+			}
+
+			holder = append(holder, event)
+
+			merged[c.Id] = event
 		}
 
 		if e := db.Insert(holder...); e != nil {
-			return e
+			return nil, e
 		}
 	}
 
-	return nil
+	return merged, nil
 }
 
 func constructComputedEntities(
@@ -429,7 +488,7 @@ func constructComputedEntities(
 			scanRows(db, rows, event, "e")
 			scanRows(db, rows, measurement, "m")
 
-			entity := &model.ComputedEventEntity{event, measurement, []*model.AnnotatedEvent{}}
+			entity := &model.ComputedEventEntity{event, measurement, []*model.AnnotatedEvent{}, []*model.ComputedEventClassification{}, nil}
 
 			records = append(records, entity)
 
@@ -454,11 +513,21 @@ func constructComputedEntities(
 			entity := entityMap[*ann.ComputedEventId]
 			entity.Annotations = append(entity.Annotations, ann)
 		}
+
+		// 分類を追加。
+		if e := feedEventClassifications(db, records...); e != nil {
+			return nil, e
+		}
 	}
 
 	return records, nil
 }
 
+// アラート対象かどうかの列。取得した場合のみセットされる。
+type annotationAlert struct {
+	IsAlert *bool `db:"is_alert"`
+}
+
 func constructAnnotatedEntities(
 	db model.QueryExecutor,
 	query string,
@@ -473,12 +542,14 @@ func constructAnnotatedEntities(
 			event := &model.AnnotatedEvent{}
 			measurement := &model.Measurement{}
 			computed := &model.ComputedEvent{}
+			alert := &annotationAlert{}
 
 			scanRows(db, rows, event, "e")
 			scanRows(db, rows, measurement, "m")
 			scanRows(db, rows, computed, "ce")
+			scanRows(db, rows, alert, "a")
 
-			entity := &model.AnnotatedEventEntity{event, measurement, nil}
+			entity := &model.AnnotatedEventEntity{event, measurement, nil, alert.IsAlert}
 
 			if computed.Id > 0 {
 				entity.Event = computed
@@ -489,4 +560,68 @@ func constructAnnotatedEntities(
 	}
 
 	return records, nil
-}
\ No newline at end of file
+}
+// 病院内の非表示でない自動診断イベントを、患者、計測と期間で検索する。期間はイベントの期間が重なるものとする。
+func SearchComputedEvents(
+	db model.QueryExecutor,
+	hospitalId int,
+	patientId *int,
+	measurementId *int,
+	begin *time.Time,
+	end *time.Time,
+	limit int,
+	offset int,
+) ([]*model.ComputedEventEntity, int64, error) {
+	ip := incrementalPlaceholder{0}
+
+	q := andQuery().add(fmt.Sprintf("p.hospital_id = $%d", ip.GetIndex()), hospitalId)
+	q.add("NOT e.is_hidden")
+
+	if patientId != nil {
+		q.add(fmt.Sprintf("m.patient_id = $%d", ip.GetIndex()), *patientId)
+	}
+	if measurementId != nil {
+		q.add(fmt.Sprintf("e.measurement_id = $%d", ip.GetIndex()), *measurementId)
+	}
+	if begin != nil {
+		q.add(fmt.Sprintf("e.range_until >= $%d", ip.GetIndex()), *begin)
+	}
+	if end != nil {
+		q.add(fmt.Sprintf("e.range_from < $%d", ip.GetIndex()), *end)
+	}
+
+	where, params := q.where()
+
+	query := fmt.Sprintf(
+		`SELECT
+			%s, %s
+		FROM
+			computed_event AS e
+			INNER JOIN measurement AS m ON e.measurement_id = m.id
+			INNER JOIN patient AS p ON m.patient_id = p.id
+		%s
+		ORDER BY e.range_from ASC, e.id ASC
+		LIMIT $%d OFFSET $%d`,
+		prefixColumns(model.ComputedEvent{}, "e", "e"),
+		prefixColumns(model.Measurement{}, "m", "m"),
+		where, ip.GetIndex(), ip.GetIndex(),
+	)
+
+	records, err := constructComputedEntities(db, query, params.clone().add(limit, offset))
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if total, e := db.SelectInt(fmt.Sprintf(`SELECT
+			COUNT(*)
+		FROM
+			computed_event AS e
+			INNER JOIN measurement AS m ON e.measurement_id = m.id
+			INNER JOIN patient AS p ON m.patient_id = p.id
+		%s`, where), params.values...); e != nil {
+		return nil, 0, e
+	} else {
+		return records, total, nil
+	}
+}
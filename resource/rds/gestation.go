@@ -0,0 +1,60 @@
+package rds
+
+import (
+	"github.com/spiker/spiker-server/model"
+)
+
+// 計測の患者に登録された在胎日数を取得する。登録されていない場合はnilを返す。
+func FetchGestationalDays(
+	db model.QueryExecutor,
+	measurementId int,
+) (*int, error) {
+	v, err := db.SelectNullInt(
+		`SELECT p.gestational_days FROM measurement AS m INNER JOIN patient AS p ON m.patient_id = p.id WHERE m.id = $1`,
+		measurementId,
+	)
+
+	if err != nil {
+		return nil, err
+	} else if !v.Valid {
+		return nil, nil
+	}
+
+	days := int(v.Int64)
+
+	return &days, nil
+}
+
+// DiagnosisEntityに判読基準とした在胎週数の区分をセットする。
+func feedDiagnosisGestations(
+	db model.QueryExecutor,
+	entities ...*model.DiagnosisEntity,
+) error {
+	ids := []int{}
+
+	for _, e := range entities {
+		ids = append(ids, e.Diagnosis.Id)
+	}
+
+	records := []*model.DiagnosisGestation{}
+
+	if _, e := db.Select(
+		&records,
+		`SELECT * FROM diagnosis_gestation WHERE diagnosis_id IN (:ids)`,
+		map[string]interface{}{"ids": ids},
+	); e != nil {
+		return e
+	}
+
+	recordMap := map[int]*model.DiagnosisGestation{}
+
+	for _, r := range records {
+		recordMap[r.DiagnosisId] = r
+	}
+
+	for _, e := range entities {
+		e.Gestation = recordMap[e.Diagnosis.Id]
+	}
+
+	return nil
+}
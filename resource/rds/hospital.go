@@ -11,25 +11,67 @@ import (
 	"github.com/spiker/spiker-server/model"
 )
 
-// 病院をID順に取得する。
+// 病院一覧のソートに指定できる列。
+var hospitalSorts = map[string]string{
+	"id":   "h.id",
+	"name": "h.name",
+}
+
+// 病院一覧を取得する。既定ではID順にソートする。
 func ListHospitals(
 	db model.QueryExecutor,
-	limit int,
-	offset int,
-) ([]*model.Hospital, int64, error) {
-	records := []*model.Hospital{}
+	paging *model.Paging,
+) ([]*model.Hospital, *model.PageInfo, error) {
+	order, err := newSortOrder(paging.Sort, "id", hospitalSorts, "h.id")
+
+	if err != nil {
+		return nil, nil, err
+	}
 
-	query := fmt.Sprintf(`SELECT * FROM hospital ORDER BY id ASC LIMIT $1 OFFSET $2`)
+	pager := newPager(paging, order)
 
-	if _, e := db.Select(&records, query, limit, offset); e != nil {
-		return nil, 0, e
+	ip := incrementalPlaceholder{0}
+
+	q := andQuery()
+
+	if e := pager.filter(q, &ip); e != nil {
+		return nil, nil, e
 	}
 
-	if total, e := db.SelectInt(`SELECT COUNT(*) FROM hospital`); e != nil {
-		return nil, 0, e
+	where, params := q.where()
+	clause, clauseParams := pager.clause(&ip)
+
+	query := fmt.Sprintf(
+		`SELECT %s, %s FROM hospital AS h %s %s`,
+		prefixColumns(model.Hospital{}, "h", "h"),
+		order.cursorColumns(),
+		where, clause,
+	)
+
+	records := []*model.Hospital{}
+
+	if rows, e := db.Query(query, params.add(clauseParams...).values...); e != nil {
+		return nil, nil, e
 	} else {
-		return records, total, nil
+		safeRowsIterator(rows, func(rows *sql.Rows) {
+			hospital := &model.Hospital{}
+
+			scanRows(db, rows, hospital, "h")
+			pager.scan(rows)
+
+			records = append(records, hospital)
+		})
 	}
+
+	length, info, err := pager.page(len(records), func() (int64, error) {
+		return db.SelectInt(`SELECT COUNT(*) FROM hospital`)
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return records[0:length], info, nil
 }
 
 // 病院をIDから取得する。
@@ -39,51 +39,76 @@ func InquireTerminal(
 	return FetchTerminal(db, id)
 }
 
-// 計測端末一覧を取得する。
+// 計測端末一覧のソートに指定できる列。
+var terminalSorts = map[string]string{
+	"id":   "t.id",
+	"code": "t.code",
+	"name": "t.name",
+}
+
+// 計測端末一覧を取得する。既定ではID順にソートする。
 func ListTerminals(
 	db model.QueryExecutor,
 	hospitalId int,
-	limit int,
-	offset int,
-) ([]*model.MeasurementTerminal, int64, error) {
+	paging *model.Paging,
+) ([]*model.MeasurementTerminal, *model.PageInfo, error) {
+	order, err := newSortOrder(paging.Sort, "id", terminalSorts, "t.id")
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pager := newPager(paging, order)
+
 	ip := incrementalPlaceholder{0}
 
 	q := andQuery().add(fmt.Sprintf("t.hospital_id = $%d", ip.GetIndex()), hospitalId)
 
 	where, params := q.where()
 
+	if e := pager.filter(q, &ip); e != nil {
+		return nil, nil, e
+	}
+
+	pagedWhere, pagedParams := q.where()
+	clause, clauseParams := pager.clause(&ip)
+
 	query := fmt.Sprintf(
 		`SELECT
-			%s
+			%s, %s
 		FROM
 			measurement_terminal AS t
 		%s
-		ORDER BY
-			t.id ASC
-		LIMIT $%d OFFSET $%d`,
+		%s`,
 		prefixColumns(model.MeasurementTerminal{}, "t", "t"),
-		where, ip.GetIndex(), ip.GetIndex(),
+		order.cursorColumns(),
+		pagedWhere, clause,
 	)
 
 	records := []*model.MeasurementTerminal{}
 
-	if rows, e := db.Query(query, params.clone().add(limit, offset).values...); e != nil {
-		return nil, 0, e
+	if rows, e := db.Query(query, pagedParams.add(clauseParams...).values...); e != nil {
+		return nil, nil, e
 	} else {
 		safeRowsIterator(rows, func(rows *sql.Rows) {
 			terminal := &model.MeasurementTerminal{}
 
 			scanRows(db, rows, terminal, "t")
+			pager.scan(rows)
 
 			records = append(records, terminal)
 		})
 	}
 
-	if total, e := db.SelectInt(fmt.Sprintf(`SELECT COUNT(*) FROM measurement_terminal AS t %s`, where), params.values...); e != nil {
-		return nil, 0, e
-	} else {
-		return records, total, nil
+	length, info, err := pager.page(len(records), func() (int64, error) {
+		return db.SelectInt(fmt.Sprintf(`SELECT COUNT(*) FROM measurement_terminal AS t %s`, where), params.values...)
+	})
+
+	if err != nil {
+		return nil, nil, err
 	}
+
+	return records[0:length], info, nil
 }
 
 // 計測端末情報を取得する。
@@ -7,7 +7,7 @@ import (
 	//"strings"
 	"time"
 
-	//C "github.com/spiker/spiker-server/constant"
+	C "github.com/spiker/spiker-server/constant"
 	"github.com/spiker/spiker-server/model"
 )
 
@@ -41,7 +41,7 @@ func LockMeasurement(
 	return &record, nil
 }
 
-// 病院と計測コードから計測記録を取得する。
+// 病院と計測コードから計測記録を取得する。同じコードの計測が複数ある場合は新しい順に並ぶ。
 func InquireMeasurementsByCodes(
 	db model.QueryExecutor,
 	hospitalId int,
@@ -53,7 +53,9 @@ func InquireMeasurementsByCodes(
 		measurement AS m
 		INNER JOIN patient AS p ON m.patient_id = p.id
 	WHERE
-		m.code IN (:codes) AND p.hospital_id = :hospital_id`
+		m.code IN (:codes) AND p.hospital_id = :hospital_id
+	ORDER BY
+		m.id DESC`
 
 	records := []*model.Measurement{}
 
@@ -67,50 +69,116 @@ func InquireMeasurementsByCodes(
 	return records, nil
 }
 
-// 患者もしくは端末に関する計測記録一覧を取得する。
+// IDから計測記録を取得する。
+func InquireMeasurementsByIds(
+	db model.QueryExecutor,
+	ids []int,
+) ([]*model.Measurement, error) {
+	records := []*model.Measurement{}
+
+	if len(ids) == 0 {
+		return records, nil
+	}
+
+	if _, e := db.Select(
+		&records,
+		`SELECT * FROM measurement WHERE id IN (:ids) ORDER BY id ASC`,
+		map[string]interface{}{"ids": ids},
+	); e != nil {
+		return nil, e
+	}
+
+	return records, nil
+}
+
+// 計測記録一覧のソートに指定できる列。
+var measurementSorts = map[string]string{
+	"id":        "m.id",
+	"createdAt": "m.created_at",
+	"firstTime": "COALESCE(m.first_time, '-infinity')",
+	"lastTime":  "COALESCE(m.last_time, '-infinity')",
+}
+
+// 条件に合う計測記録一覧を取得する。
+// 既定では作成日時の新しい順にソートする。
 // TODO 医師によるアクセス制限を必要に応じて追加。
 func ListMeasurements(
 	db model.QueryExecutor,
 	hospitalId int,
-	patientId *int,
-	terminalId *int,
-	limit int,
-	offset int,
-) ([]*model.MeasurementEntity, int64, error) {
+	filter *model.MeasurementFilter,
+	paging *model.Paging,
+) ([]*model.MeasurementEntity, *model.PageInfo, error) {
+	order, err := newSortOrder(paging.Sort, "-createdAt", measurementSorts, "m.id")
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pager := newPager(paging, order)
+
 	ip := incrementalPlaceholder{0}
 
 	// REVIEW 患者側から病院との関連を取る。
 	q := andQuery().add(fmt.Sprintf("p.hospital_id = $%d", ip.GetIndex()), hospitalId)
 
-	if patientId != nil {
-		q.add(fmt.Sprintf("m.patient_id = $%d", ip.GetIndex()), *patientId)
-	} else if terminalId != nil {
-		q.add(fmt.Sprintf("m.terminal_id = $%d", ip.GetIndex()), *terminalId)
+	if filter.PatientId != nil {
+		q.add(fmt.Sprintf("m.patient_id = $%d", ip.GetIndex()), *filter.PatientId)
+	}
+	if filter.TerminalId != nil {
+		q.add(fmt.Sprintf("m.terminal_id = $%d", ip.GetIndex()), *filter.TerminalId)
+	}
+	if filter.State != nil {
+		q.add(fmt.Sprintf("m.is_closed = $%d", ip.GetIndex()), *filter.State == string(C.MeasurementStateClosed))
+	}
+	if filter.MinimumRisk != nil {
+		q.add(fmt.Sprintf(
+			`(SELECT MAX(ce.risk) FROM computed_event AS ce WHERE ce.measurement_id = m.id AND NOT ce.is_hidden) >= $%d`,
+			ip.GetIndex(),
+		), *filter.MinimumRisk)
+	}
+	if filter.MaximumRisk != nil {
+		q.add(fmt.Sprintf(
+			`COALESCE((SELECT MAX(ce.risk) FROM computed_event AS ce WHERE ce.measurement_id = m.id AND NOT ce.is_hidden), 0) <= $%d`,
+			ip.GetIndex(),
+		), *filter.MaximumRisk)
+	}
+	// 計測期間が指定した期間と重なる計測に限定する。
+	if filter.From != nil {
+		q.add(fmt.Sprintf("m.last_time >= $%d", ip.GetIndex()), *filter.From)
+	}
+	if filter.Until != nil {
+		q.add(fmt.Sprintf("m.first_time <= $%d", ip.GetIndex()), *filter.Until)
 	}
 
 	where, params := q.where()
 
+	if e := pager.filter(q, &ip); e != nil {
+		return nil, nil, e
+	}
+
+	pagedWhere, pagedParams := q.where()
+	clause, clauseParams := pager.clause(&ip)
+
 	query := fmt.Sprintf(
 		`SELECT
-			%s, %s, %s
+			%s, %s, %s, %s
 		FROM
 			measurement AS m
 			INNER JOIN patient AS p ON m.patient_id = p.id
 			INNER JOIN measurement_terminal AS t ON m.terminal_id = t.id
 		%s
-		ORDER BY
-			m.created_at DESC
-		LIMIT $%d OFFSET $%d`,
+		%s`,
 		prefixColumns(model.Measurement{}, "m", "m"),
 		prefixColumns(model.Patient{}, "p", "p"),
 		prefixColumns(model.MeasurementTerminal{}, "t", "t"),
-		where, ip.GetIndex(), ip.GetIndex(),
+		order.cursorColumns(),
+		pagedWhere, clause,
 	)
 
 	results := []*model.MeasurementEntity{}
 
-	if rows, e := db.Query(query, params.clone().add(limit, offset).values...); e != nil {
-		return nil, 0, e
+	if rows, e := db.Query(query, pagedParams.add(clauseParams...).values...); e != nil {
+		return nil, nil, e
 	} else {
 		safeRowsIterator(rows, func(rows *sql.Rows) {
 			entity := model.MeasurementEntity{
@@ -122,22 +190,27 @@ func ListMeasurements(
 			scanRows(db, rows, entity.Measurement, "m")
 			scanRows(db, rows, entity.Terminal, "t")
 			scanRows(db, rows, entity.Patient, "p")
+			pager.scan(rows)
 
 			results = append(results, &entity)
 		})
 	}
 
-	if total, e := db.SelectInt(fmt.Sprintf(`SELECT
-			COUNT(*)
-		FROM
-			measurement AS m
-			INNER JOIN patient AS p ON m.patient_id = p.id
-			INNER JOIN measurement_terminal AS t ON m.terminal_id = t.id
-		%s`, where), params.values...); e != nil {
-		return nil, 0, e
-	} else {
-		return results, total, nil
+	length, info, err := pager.page(len(results), func() (int64, error) {
+		return db.SelectInt(fmt.Sprintf(`SELECT
+				COUNT(*)
+			FROM
+				measurement AS m
+				INNER JOIN patient AS p ON m.patient_id = p.id
+				INNER JOIN measurement_terminal AS t ON m.terminal_id = t.id
+			%s`, where), params.values...)
+	})
+
+	if err != nil {
+		return nil, nil, err
 	}
+
+	return results[0:length], info, nil
 }
 
 // IDから計測記録を取得する。
@@ -383,4 +456,84 @@ func StopCurrentSilent(
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+// 病院内の計測記録を、患者と期間で検索する。
+// 期間は最初と最後のデータ日時が重なる計測を対象とし、データの無い計測は期間の指定がある場合、またはwithDataがtrueの場合に除かれる。
+func SearchMeasurements(
+	db model.QueryExecutor,
+	hospitalId int,
+	patientId *int,
+	begin *time.Time,
+	end *time.Time,
+	withData bool,
+	limit int,
+	offset int,
+) ([]*model.MeasurementEntity, int64, error) {
+	ip := incrementalPlaceholder{0}
+
+	q := andQuery().add(fmt.Sprintf("p.hospital_id = $%d", ip.GetIndex()), hospitalId)
+
+	if withData {
+		q.add("m.first_time IS NOT NULL AND m.last_time IS NOT NULL")
+	}
+
+	if patientId != nil {
+		q.add(fmt.Sprintf("m.patient_id = $%d", ip.GetIndex()), *patientId)
+	}
+	if begin != nil {
+		q.add(fmt.Sprintf("m.last_time >= $%d", ip.GetIndex()), *begin)
+	}
+	if end != nil {
+		q.add(fmt.Sprintf("m.first_time < $%d", ip.GetIndex()), *end)
+	}
+
+	where, params := q.where()
+
+	query := fmt.Sprintf(
+		`SELECT
+			%s, %s, %s
+		FROM
+			measurement AS m
+			INNER JOIN patient AS p ON m.patient_id = p.id
+			INNER JOIN measurement_terminal AS t ON m.terminal_id = t.id
+		%s
+		ORDER BY
+			m.id ASC
+		LIMIT $%d OFFSET $%d`,
+		prefixColumns(model.Measurement{}, "m", "m"),
+		prefixColumns(model.Patient{}, "p", "p"),
+		prefixColumns(model.MeasurementTerminal{}, "t", "t"),
+		where, ip.GetIndex(), ip.GetIndex(),
+	)
+
+	results := []*model.MeasurementEntity{}
+
+	if rows, e := db.Query(query, params.clone().add(limit, offset).values...); e != nil {
+		return nil, 0, e
+	} else {
+		safeRowsIterator(rows, func(rows *sql.Rows) {
+			entity := model.MeasurementEntity{
+				Measurement: &model.Measurement{},
+				Terminal:    &model.MeasurementTerminal{},
+				Patient:     &model.Patient{},
+			}
+
+			scanRows(db, rows, entity.Measurement, "m")
+			scanRows(db, rows, entity.Terminal, "t")
+			scanRows(db, rows, entity.Patient, "p")
+
+			results = append(results, &entity)
+		})
+	}
+
+	if total, e := db.SelectInt(fmt.Sprintf(`SELECT
+			COUNT(*)
+		FROM
+			measurement AS m
+			INNER JOIN patient AS p ON m.patient_id = p.id
+		%s`, where), params.values...); e != nil {
+		return nil, 0, e
+	} else {
+		return results, total, nil
+	}
+}
@@ -0,0 +1,126 @@
+package rds
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spiker/spiker-server/model"
+)
+
+// 計測に紐付くレコードのテーブルと、レコードの開始日時の式。
+// アノテーションは参照する自動診断イベントと同じ計測に移すため、イベントを参照する場合はイベントの開始日時とする。
+var measurementRecordTables = [][]string{
+	{"diagnosis", "range_from"},
+	{"computed_event", "range_from"},
+	{"annotated_event", "COALESCE((SELECT ce.range_from FROM computed_event AS ce WHERE ce.id = annotated_event.computed_event_id), annotated_event.range_from)"},
+	{"contraction", "onset_at"},
+	{"antenatal_report", "range_from"},
+	{"clinical_event", "occurred_at"},
+	{"measurement_alert", "silent_from"},
+}
+
+// 計測の診断、イベント等のレコードを別の計測に移す。sinceを指定した場合は、その日時以降に始まるレコードのみを移す。
+// アノテーションは参照する自動診断イベントと分かれないよう、イベントの開始日時で判断する。
+func MoveMeasurementRecords(
+	db model.QueryExecutor,
+	from int,
+	to int,
+	since *time.Time,
+) error {
+	for _, t := range measurementRecordTables {
+		query := fmt.Sprintf(`UPDATE %s SET measurement_id = $1 WHERE measurement_id = $2`, t[0])
+		params := []interface{}{to, from}
+
+		if since != nil {
+			query += fmt.Sprintf(` AND %s >= $3`, t[1])
+			params = append(params, *since)
+		}
+
+		if _, e := db.Exec(query, params...); e != nil {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// 端末への割り当てで記録した計測を別の計測に付け替える。activeOnlyがtrueの場合は割り当て中のもののみを対象とする。
+func MoveAdmissionMeasurement(
+	db model.QueryExecutor,
+	from int,
+	to int,
+	activeOnly bool,
+) error {
+	query := `UPDATE terminal_admission SET measurement_id = $1 WHERE measurement_id = $2`
+
+	if activeOnly {
+		query += ` AND discharged_at IS NULL`
+	}
+
+	if _, e := db.Exec(query, to, from); e != nil {
+		return e
+	}
+
+	return nil
+}
+
+// 統合した計測を削除する。端末への割り当てと計測データの付け替え待ちは統合先の計測に付け替える。
+func DeleteMergedMeasurement(
+	db model.QueryExecutor,
+	measurementId int,
+	mergedMeasurementId int,
+) error {
+	if e := MoveAdmissionMeasurement(db, mergedMeasurementId, measurementId, false); e != nil {
+		return e
+	}
+
+	// 統合される計測への付け替え待ちは、統合先の計測への付け替えとする。
+	// 統合先の計測から統合される計測への付け替え待ちは、統合先の計測に残すため取り除く。
+	for _, q := range []string{
+		`DELETE FROM measurement_retag WHERE from_measurement_id = $1 AND to_measurement_id = $2`,
+		`UPDATE measurement_retag SET to_measurement_id = $1 WHERE to_measurement_id = $2`,
+	} {
+		if _, e := db.Exec(q, measurementId, mergedMeasurementId); e != nil {
+			return e
+		}
+	}
+
+	for _, q := range []string{
+		`DELETE FROM measurement_archive WHERE measurement_id = $1`,
+		`DELETE FROM measurement WHERE id = $1`,
+	} {
+		if _, e := db.Exec(q, mergedMeasurementId); e != nil {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// 計測データの付け替え待ちを古い順に取得する。同時に処理しないよう、取得した行をロックし、ロック中の行は除く。
+func ListMeasurementRetags(
+	db model.QueryExecutor,
+) ([]*model.MeasurementRetag, error) {
+	records := []*model.MeasurementRetag{}
+
+	if _, e := db.Select(
+		&records,
+		`SELECT * FROM measurement_retag ORDER BY id ASC FOR UPDATE SKIP LOCKED`,
+	); e != nil {
+		return nil, e
+	}
+
+	return records, nil
+}
+
+// 計測データの付け替え待ちを取り除く。
+func DeleteMeasurementRetag(
+	db model.QueryExecutor,
+	id int,
+) error {
+	if _, e := db.Exec(`DELETE FROM measurement_retag WHERE id = $1`, id); e != nil {
+		return e
+	}
+
+	return nil
+}
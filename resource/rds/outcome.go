@@ -0,0 +1,95 @@
+package rds
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spiker/spiker-server/model"
+)
+
+// 出生日時が期間内にある患者を、出生日時の古い順に取得する。
+func ListBornPatients(
+	db model.QueryExecutor,
+	hospitalId *int,
+	begin *time.Time,
+	end *time.Time,
+) ([]*model.Patient, error) {
+	ip := incrementalPlaceholder{0}
+
+	q := andQuery().add("birth_datetime IS NOT NULL")
+
+	if hospitalId != nil {
+		q.add(fmt.Sprintf("hospital_id = $%d", ip.GetIndex()), *hospitalId)
+	}
+
+	if begin != nil {
+		q.add(fmt.Sprintf("birth_datetime >= $%d", ip.GetIndex()), *begin)
+	}
+
+	if end != nil {
+		q.add(fmt.Sprintf("birth_datetime < $%d", ip.GetIndex()), *end)
+	}
+
+	where, params := q.where()
+
+	records := []*model.Patient{}
+
+	if _, e := db.Select(&records, fmt.Sprintf(`SELECT * FROM patient %s ORDER BY birth_datetime ASC, id ASC`, where), params.values...); e != nil {
+		return nil, e
+	}
+
+	return records, nil
+}
+
+// 患者の計測に対する自動診断の診断項目のうち、リスクを持つものを取得する。
+func ListOutcomeContents(
+	db model.QueryExecutor,
+	patientIds []int,
+) ([]*model.OutcomeContent, error) {
+	records := []*model.OutcomeContent{}
+
+	if len(patientIds) == 0 {
+		return records, nil
+	}
+
+	query := `SELECT
+			m.patient_id, cd.algorithm_id, dc.risk, dc.range_from, dc.range_until
+		FROM
+			diagnosis_content AS dc
+			INNER JOIN diagnosis AS d ON dc.diagnosis_id = d.id
+			INNER JOIN computed_diagnosis AS cd ON d.id = cd.diagnosis_id
+			INNER JOIN measurement AS m ON d.measurement_id = m.id
+		WHERE
+			m.patient_id IN (:ids)
+			AND dc.risk IS NOT NULL
+		ORDER BY
+			dc.range_from ASC`
+
+	if _, e := db.Select(&records, query, map[string]interface{}{"ids": patientIds}); e != nil {
+		return nil, e
+	}
+
+	return records, nil
+}
+
+// 指定したIDのアルゴリズムを取得する。
+func ListAlgorithmsByIds(
+	db model.QueryExecutor,
+	ids []int,
+) ([]*model.DiagnosisAlgorithm, error) {
+	records := []*model.DiagnosisAlgorithm{}
+
+	if len(ids) == 0 {
+		return records, nil
+	}
+
+	if _, e := db.Select(
+		&records,
+		`SELECT * FROM diagnosis_algorithm WHERE id IN (:ids) ORDER BY name ASC, version ASC`,
+		map[string]interface{}{"ids": ids},
+	); e != nil {
+		return nil, e
+	}
+
+	return records, nil
+}
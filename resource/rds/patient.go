@@ -19,17 +19,31 @@ func InquirePatient(
 	return FetchPatient(db, id)
 }
 
+// 患者一覧のソートに指定できる列。
+var patientSorts = map[string]string{
+	"id":        "p.id",
+	"createdAt": "p.created_at",
+	"lastTime":  "COALESCE(pm.last, '-infinity')",
+}
+
 // 患者一覧を取得する。
 // begin, endが指定されている場合、その期間に計測を持つ患者に限定する。
-// 計測のある患者を優先し、最終計測日時が最も遅い順にソートする。
+// 既定では計測のある患者を優先し、最終計測日時が最も遅い順にソートする。
 func ListPatients(
 	db model.QueryExecutor,
 	hospitalId int,
 	begin *time.Time,
 	end *time.Time,
-	limit int,
-	offset int,
-) ([]*model.Patient, int64, error) {
+	paging *model.Paging,
+) ([]*model.Patient, *model.PageInfo, error) {
+	order, err := newSortOrder(paging.Sort, "-lastTime", patientSorts, "p.id")
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pager := newPager(paging, order)
+
 	ip := incrementalPlaceholder{0}
 
 	q := andQuery().add(fmt.Sprintf("p.hospital_id = $%d", ip.GetIndex()), hospitalId)
@@ -43,69 +57,62 @@ func ListPatients(
 
 	where, params := q.where()
 
+	if e := pager.filter(q, &ip); e != nil {
+		return nil, nil, e
+	}
+
+	pagedWhere, pagedParams := q.where()
+	clause, clauseParams := pager.clause(&ip)
+
+	from := `patient AS p
+		LEFT JOIN (
+			SELECT
+				p_.id, MIN(m.first_time) AS first, MAX(m.last_time) AS last
+			FROM
+				patient AS p_
+				INNER JOIN measurement AS m ON p_.id = m.patient_id
+			WHERE
+				p_.hospital_id = $1
+			GROUP BY
+				p_.id
+		) AS pm ON p.id = pm.id`
+
 	query := fmt.Sprintf(
 		`SELECT
-			%s
+			%s, %s
 		FROM
-			patient AS p
-			LEFT JOIN (
-				SELECT
-					p_.id, MIN(m.first_time) AS first, MAX(m.last_time) AS last
-				FROM
-					patient AS p_
-					INNER JOIN measurement AS m ON p_.id = m.patient_id
-				WHERE
-					p_.hospital_id = $1
-				GROUP BY
-					p_.id
-			) AS pm ON p.id = pm.id
+			%s
 		%s
-		ORDER BY
-			pm.last DESC NULLS LAST, p.id ASC
-		LIMIT $%d OFFSET $%d`,
+		%s`,
 		prefixColumns(model.Patient{}, "p", "p"),
-		where, ip.GetIndex(), ip.GetIndex(),
+		order.cursorColumns(),
+		from, pagedWhere, clause,
 	)
 
 	records := []*model.Patient{}
 
-	if rows, e := db.Query(query, params.clone().add(limit, offset).values...); e != nil {
-		return nil, 0, e
+	if rows, e := db.Query(query, pagedParams.add(clauseParams...).values...); e != nil {
+		return nil, nil, e
 	} else {
 		safeRowsIterator(rows, func(rows *sql.Rows) {
 			patient := &model.Patient{}
 
 			scanRows(db, rows, patient, "p")
+			pager.scan(rows)
 
 			records = append(records, patient)
 		})
 	}
 
-	totalQuery := fmt.Sprintf(
-		`SELECT
-			COUNT(*)
-		FROM
-			patient AS p
-			LEFT JOIN (
-				SELECT
-					p_.id, MIN(m.first_time) AS first, MAX(m.last_time) AS last
-				FROM
-					patient AS p_
-					INNER JOIN measurement AS m ON p_.id = m.patient_id
-				WHERE
-					p_.hospital_id = $1
-				GROUP BY
-					p_.id
-			) AS pm ON p.id = pm.id
-		%s`,
-		where,
-	)
+	length, info, err := pager.page(len(records), func() (int64, error) {
+		return db.SelectInt(fmt.Sprintf(`SELECT COUNT(*) FROM %s %s`, from, where), params.values...)
+	})
 
-	if total, e := db.SelectInt(totalQuery, params.values...); e != nil {
-		return nil, 0, e
-	} else {
-		return records, total, nil
+	if err != nil {
+		return nil, nil, err
 	}
+
+	return records[0:length], info, nil
 }
 
 // 患者情報を取得する。
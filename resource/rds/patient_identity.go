@@ -0,0 +1,125 @@
+package rds
+
+import (
+	"database/sql"
+
+	"github.com/spiker/spiker-server/model"
+)
+
+// 患者の識別子を登録順に取得する。
+func ListPatientIdentifiers(
+	db model.QueryExecutor,
+	patientId int,
+) ([]*model.PatientIdentifier, error) {
+	records := []*model.PatientIdentifier{}
+
+	if _, e := db.Select(
+		&records,
+		`SELECT * FROM patient_identifier WHERE patient_id = $1 ORDER BY id ASC`,
+		patientId,
+	); e != nil {
+		return nil, e
+	}
+
+	return records, nil
+}
+
+// 病院内の識別子を値から取得する。
+func InquirePatientIdentifiersByValues(
+	db model.QueryExecutor,
+	hospitalId int,
+	values []string,
+) ([]*model.PatientIdentifier, error) {
+	records := []*model.PatientIdentifier{}
+
+	if len(values) == 0 {
+		return records, nil
+	}
+
+	if _, e := db.Select(
+		&records,
+		`SELECT * FROM patient_identifier WHERE hospital_id = :hospital_id AND value IN (:values)`,
+		map[string]interface{}{
+			"hospital_id": hospitalId,
+			"values": values,
+		},
+	); e != nil {
+		return nil, e
+	}
+
+	return records, nil
+}
+
+// 識別子をIDから取得する。
+func FetchPatientIdentifier(
+	db model.QueryExecutor,
+	id int,
+) (*model.PatientIdentifier, error) {
+	record := model.PatientIdentifier{}
+
+	if e := db.SelectOne(&record, `SELECT * FROM patient_identifier WHERE id = $1`, id); e != nil {
+		if e == sql.ErrNoRows {
+			return nil, nil
+		} else {
+			return nil, e
+		}
+	}
+
+	return &record, nil
+}
+
+// 病院の患者の識別規則を取得する。設定されていない場合はnilを返す。
+func FetchHospitalPatientRule(
+	db model.QueryExecutor,
+	hospitalId int,
+) (*model.HospitalPatientRule, error) {
+	if r, e := db.Get(model.HospitalPatientRule{}, hospitalId); e != nil {
+		return nil, e
+	} else if r == nil {
+		return nil, nil
+	} else {
+		return r.(*model.HospitalPatientRule), nil
+	}
+}
+
+// 患者の計測を別の患者に移す。移した計測の数を返す。
+func MovePatientMeasurements(
+	db model.QueryExecutor,
+	fromPatientId int,
+	toPatientId int,
+) (int64, error) {
+	if r, e := db.Exec(`UPDATE measurement SET patient_id = $1 WHERE patient_id = $2`, toPatientId, fromPatientId); e != nil {
+		return 0, e
+	} else {
+		return r.RowsAffected()
+	}
+}
+
+// 統合元の患者の識別子と端末への割り当てを統合先に移した上で、統合元の患者と鍵、氏名の索引を削除する。計測は予め移しておく。
+func DeleteMergedPatient(
+	db model.QueryExecutor,
+	patientId int,
+	mergedPatientId int,
+) error {
+	if _, e := db.Exec(`UPDATE patient_identifier SET patient_id = $1 WHERE patient_id = $2`, patientId, mergedPatientId); e != nil {
+		return e
+	}
+
+	if _, e := db.Exec(`UPDATE terminal_admission SET patient_id = $1 WHERE patient_id = $2`, patientId, mergedPatientId); e != nil {
+		return e
+	}
+
+	if _, e := db.Exec(`DELETE FROM patient_name_token WHERE patient_id = $1`, mergedPatientId); e != nil {
+		return e
+	}
+
+	if _, e := db.Exec(`DELETE FROM patient_key WHERE patient_id = $1`, mergedPatientId); e != nil {
+		return e
+	}
+
+	if _, e := db.Exec(`DELETE FROM patient WHERE id = $1`, mergedPatientId); e != nil {
+		return e
+	}
+
+	return nil
+}
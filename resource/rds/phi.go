@@ -0,0 +1,302 @@
+package rds
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/spiker/spiker-server/lib/phi"
+	"github.com/spiker/spiker-server/model"
+)
+
+func FetchHospitalKey(
+	db model.QueryExecutor,
+	hospitalId int,
+) (*model.HospitalKey, error) {
+	if r, e := db.Get(model.HospitalKey{}, hospitalId); e != nil {
+		return nil, e
+	} else if r == nil {
+		return nil, nil
+	} else {
+		return r.(*model.HospitalKey), nil
+	}
+}
+
+// 病院の鍵が無ければ登録する。同時に登録された場合は先に登録された鍵が残る。
+func InsertHospitalKeyIfAbsent(
+	db model.QueryExecutor,
+	key *model.HospitalKey,
+) error {
+	_, err := db.Exec(
+		`INSERT INTO hospital_key (hospital_id, wrapped_key, created_at) VALUES ($1, $2, $3) ON CONFLICT (hospital_id) DO NOTHING`,
+		key.HospitalId, key.WrappedKey, key.CreatedAt,
+	)
+
+	return err
+}
+
+// 患者の鍵を取得する。鍵の無い患者は含まれない。
+func ListPatientKeys(
+	db model.QueryExecutor,
+	patientIds []int,
+) ([]*model.PatientKey, error) {
+	records := []*model.PatientKey{}
+
+	if len(patientIds) == 0 {
+		return records, nil
+	}
+
+	if _, e := db.Select(
+		&records,
+		`SELECT * FROM patient_key WHERE patient_id IN (:ids)`,
+		map[string]interface{}{"ids": patientIds},
+	); e != nil {
+		return nil, e
+	}
+
+	return records, nil
+}
+
+// 患者の鍵が無ければ登録する。同時に登録された場合は先に登録された鍵が残る。
+func InsertPatientKeyIfAbsent(
+	db model.QueryExecutor,
+	key *model.PatientKey,
+) error {
+	_, err := db.Exec(
+		`INSERT INTO patient_key (patient_id, wrapped_key, created_at) VALUES ($1, $2, $3) ON CONFLICT (patient_id) DO NOTHING`,
+		key.PatientId, key.WrappedKey, key.CreatedAt,
+	)
+
+	return err
+}
+
+// 患者IDから病院IDへのマップを取得する。
+func MapPatientHospitals(
+	db model.QueryExecutor,
+	patientIds []int,
+) (map[int]int, error) {
+	results := map[int]int{}
+
+	if len(patientIds) == 0 {
+		return results, nil
+	}
+
+	records := []*model.Patient{}
+
+	if _, e := db.Select(
+		&records,
+		`SELECT * FROM patient WHERE id IN (:ids)`,
+		map[string]interface{}{"ids": patientIds},
+	); e != nil {
+		return nil, e
+	}
+
+	for _, r := range records {
+		results[r.Id] = r.HospitalId
+	}
+
+	return results, nil
+}
+
+// 患者の計測IDを取得する。
+func ListPatientMeasurementIds(
+	db model.QueryExecutor,
+	patientId int,
+) ([]int, error) {
+	ids := []int{}
+
+	if rows, e := db.Query(`SELECT id FROM measurement WHERE patient_id = $1 ORDER BY id ASC`, patientId); e != nil {
+		return nil, e
+	} else {
+		safeRowsIterator(rows, func(rows *sql.Rows) {
+			var id int
+			rows.Scan(&id)
+			ids = append(ids, id)
+		})
+	}
+
+	return ids, nil
+}
+
+// 患者の鍵と、患者及びその計測に関する全てのレコードを削除する。
+func ErasePatient(
+	db model.QueryExecutor,
+	patientId int,
+	measurementIds []int,
+) error {
+	if len(measurementIds) > 0 {
+		ip := incrementalPlaceholder{0}
+
+		holders := []string{}
+		ids := []interface{}{}
+
+		for _, id := range measurementIds {
+			holders = append(holders, fmt.Sprintf("$%d", ip.GetIndex()))
+			ids = append(ids, id)
+		}
+
+		in := strings.Join(holders, ",")
+
+		queries := []string{
+			`DELETE FROM event_snapshot WHERE computed_event_id IN (SELECT id FROM computed_event WHERE measurement_id IN (%s))`,
+			`DELETE FROM computed_event_classification WHERE computed_event_id IN (SELECT id FROM computed_event WHERE measurement_id IN (%s))`,
+			`DELETE FROM annotated_event WHERE measurement_id IN (%s)`,
+			`DELETE FROM computed_event WHERE measurement_id IN (%s)`,
+			`DELETE FROM deceleration_timing WHERE diagnosis_content_id IN (
+				SELECT dc.id FROM diagnosis_content AS dc INNER JOIN diagnosis AS d ON dc.diagnosis_id = d.id WHERE d.measurement_id IN (%s))`,
+			`DELETE FROM diagnosis_content_classification WHERE diagnosis_content_id IN (
+				SELECT dc.id FROM diagnosis_content AS dc INNER JOIN diagnosis AS d ON dc.diagnosis_id = d.id WHERE d.measurement_id IN (%s))`,
+			`DELETE FROM diagnosis_content WHERE diagnosis_id IN (SELECT id FROM diagnosis WHERE measurement_id IN (%s))`,
+			`DELETE FROM diagnosis_risk_table WHERE diagnosis_id IN (SELECT id FROM diagnosis WHERE measurement_id IN (%s))`,
+			`DELETE FROM diagnosis_contraction WHERE diagnosis_id IN (SELECT id FROM diagnosis WHERE measurement_id IN (%s))`,
+			`DELETE FROM diagnosis_gestation WHERE diagnosis_id IN (SELECT id FROM diagnosis WHERE measurement_id IN (%s))`,
+			`DELETE FROM computed_diagnosis WHERE diagnosis_id IN (SELECT id FROM diagnosis WHERE measurement_id IN (%s))`,
+			`DELETE FROM diagnosis WHERE measurement_id IN (%s)`,
+			`DELETE FROM contraction WHERE measurement_id IN (%s)`,
+			`DELETE FROM antenatal_report WHERE measurement_id IN (%s)`,
+			`DELETE FROM clinical_event WHERE measurement_id IN (%s)`,
+			`DELETE FROM measurement_alert WHERE measurement_id IN (%s)`,
+			`DELETE FROM measurement_archive WHERE measurement_id IN (%s)`,
+			`DELETE FROM measurement_retag WHERE from_measurement_id IN (%[1]s) OR to_measurement_id IN (%[1]s)`,
+			`DELETE FROM measurement WHERE id IN (%s)`,
+		}
+
+		for _, q := range queries {
+			if _, e := db.Exec(fmt.Sprintf(q, in), ids...); e != nil {
+				return e
+			}
+		}
+	}
+
+	if _, e := db.Exec(`DELETE FROM terminal_admission WHERE patient_id = $1`, patientId); e != nil {
+		return e
+	}
+
+	if _, e := db.Exec(`DELETE FROM patient_identifier WHERE patient_id = $1`, patientId); e != nil {
+		return e
+	}
+
+	if _, e := db.Exec(`DELETE FROM patient_name_token WHERE patient_id = $1`, patientId); e != nil {
+		return e
+	}
+
+	if _, e := db.Exec(`DELETE FROM patient_key WHERE patient_id = $1`, patientId); e != nil {
+		return e
+	}
+
+	if _, e := db.Exec(`DELETE FROM patient WHERE id = $1`, patientId); e != nil {
+		return e
+	}
+
+	return nil
+}
+
+// 患者の氏名の索引を置き換える。
+func ReplacePatientNameTokens(
+	db model.QueryExecutor,
+	patientId int,
+	tokens []string,
+) error {
+	if _, e := db.Exec(`DELETE FROM patient_name_token WHERE patient_id = $1`, patientId); e != nil {
+		return e
+	}
+
+	records := []interface{}{}
+
+	for _, t := range tokens {
+		records = append(records, &model.PatientNameToken{PatientId: patientId, Token: t})
+	}
+
+	if len(records) > 0 {
+		if e := db.Insert(records...); e != nil {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// 氏名の索引を持たない、暗号化された氏名を持つ患者を、指定したIDより大きいものから昇順に取得する。
+func ListSealedPatientsWithoutNameTokens(
+	db model.QueryExecutor,
+	afterId int,
+	limit int,
+) ([]*model.Patient, error) {
+	query := fmt.Sprintf(
+		`SELECT %s FROM patient AS p
+		WHERE p.id > $1 AND p.name LIKE $2 AND NOT EXISTS (SELECT 1 FROM patient_name_token AS nt WHERE nt.patient_id = p.id)
+		ORDER BY p.id ASC
+		LIMIT $3`,
+		prefixColumns(model.Patient{}, "p", "p"),
+	)
+
+	records := []*model.Patient{}
+
+	if rows, e := db.Query(query, afterId, escapeLike(phi.Prefix)+"%", limit); e != nil {
+		return nil, e
+	} else {
+		safeRowsIterator(rows, func(rows *sql.Rows) {
+			patient := &model.Patient{}
+
+			scanRows(db, rows, patient, "p")
+
+			records = append(records, patient)
+		})
+	}
+
+	return records, nil
+}
+
+// 計測データの削除待ちを古い順に取得する。
+func ListMeasurementPurges(
+	db model.QueryExecutor,
+	limit int,
+) ([]*model.MeasurementPurge, error) {
+	records := []*model.MeasurementPurge{}
+
+	if _, e := db.Select(
+		&records,
+		`SELECT * FROM measurement_purge ORDER BY requested_at ASC, measurement_id ASC LIMIT $1`,
+		limit,
+	); e != nil {
+		return nil, e
+	}
+
+	return records, nil
+}
+
+// 計測データの削除待ちを取り除く。
+func DeleteMeasurementPurge(
+	db model.QueryExecutor,
+	measurementId int,
+) error {
+	if _, e := db.Exec(`DELETE FROM measurement_purge WHERE measurement_id = $1`, measurementId); e != nil {
+		return e
+	}
+
+	return nil
+}
+
+// データを受信した計測のIDを、指定したIDより大きいものから昇順に取得する。
+func ListMeasurementIdsWithData(
+	db model.QueryExecutor,
+	afterId int,
+	limit int,
+) ([]int, error) {
+	ids := []int{}
+
+	if rows, e := db.Query(
+		`SELECT id FROM measurement WHERE id > $1 AND first_time IS NOT NULL ORDER BY id ASC LIMIT $2`,
+		afterId, limit,
+	); e != nil {
+		return nil, e
+	} else {
+		safeRowsIterator(rows, func(rows *sql.Rows) {
+			var id int
+			rows.Scan(&id)
+			ids = append(ids, id)
+		})
+	}
+
+	return ids, nil
+}
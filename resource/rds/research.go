@@ -0,0 +1,155 @@
+package rds
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/model"
+)
+
+func InquireResearchExport(
+	db model.QueryExecutor,
+	id int,
+) (*model.ResearchExport, error) {
+	if r, e := db.Get(model.ResearchExport{}, id); e != nil {
+		return nil, e
+	} else if r == nil {
+		return nil, nil
+	} else {
+		return r.(*model.ResearchExport), nil
+	}
+}
+
+// 研究用データセットの出力ジョブを新しい順に取得する。
+func ListResearchExports(
+	db model.QueryExecutor,
+	limit int,
+	offset int,
+) ([]*model.ResearchExport, int64, error) {
+	records := []*model.ResearchExport{}
+
+	if _, e := db.Select(
+		&records,
+		`SELECT * FROM research_export ORDER BY id DESC LIMIT $1 OFFSET $2`,
+		limit, offset,
+	); e != nil {
+		return nil, 0, e
+	}
+
+	if total, e := db.SelectInt(`SELECT COUNT(*) FROM research_export`); e != nil {
+		return nil, 0, e
+	} else {
+		return records, total, nil
+	}
+}
+
+// 未実行の出力ジョブを古い順に取得する。
+func ListPendingResearchExports(
+	db model.QueryExecutor,
+) ([]*model.ResearchExport, error) {
+	records := []*model.ResearchExport{}
+
+	if _, e := db.Select(
+		&records,
+		`SELECT * FROM research_export WHERE state = $1 ORDER BY id ASC`,
+		string(C.ResearchExportPending),
+	); e != nil {
+		return nil, e
+	}
+
+	return records, nil
+}
+
+// 未実行の出力ジョブを実行中とする。他のプロセスが先に実行中とした場合はfalseを返す。
+func ClaimResearchExport(
+	db model.QueryExecutor,
+	id int,
+	now time.Time,
+) (bool, error) {
+	query := `UPDATE research_export
+		SET state = $1, started_at = $2, modified_at = $2
+		WHERE id = $3 AND state = $4`
+
+	if r, e := db.Exec(query, string(C.ResearchExportRunning), now, id, string(C.ResearchExportPending)); e != nil {
+		return false, e
+	} else if n, e := r.RowsAffected(); e != nil {
+		return false, e
+	} else {
+		return n > 0, nil
+	}
+}
+
+// 複数の病院の計測記録のうち、データを持ち、データの期間が指定期間と重なるものを病院、計測のID順に取得する。
+func ListResearchMeasurements(
+	db model.QueryExecutor,
+	hospitalIds []int,
+	begin *time.Time,
+	end *time.Time,
+) ([]*model.MeasurementEntity, error) {
+	results := []*model.MeasurementEntity{}
+
+	if len(hospitalIds) == 0 {
+		return results, nil
+	}
+
+	ip := incrementalPlaceholder{0}
+
+	holders := []string{}
+	ids := []interface{}{}
+
+	for _, id := range hospitalIds {
+		holders = append(holders, fmt.Sprintf("$%d", ip.GetIndex()))
+		ids = append(ids, id)
+	}
+
+	q := andQuery().add(fmt.Sprintf("p.hospital_id IN (%s)", strings.Join(holders, ",")), ids...)
+	q.add("m.first_time IS NOT NULL AND m.last_time IS NOT NULL")
+
+	if begin != nil {
+		q.add(fmt.Sprintf("m.last_time >= $%d", ip.GetIndex()), *begin)
+	}
+	if end != nil {
+		q.add(fmt.Sprintf("m.first_time < $%d", ip.GetIndex()), *end)
+	}
+
+	where, params := q.where()
+
+	query := fmt.Sprintf(
+		`SELECT
+			%s, %s, %s
+		FROM
+			measurement AS m
+			INNER JOIN patient AS p ON m.patient_id = p.id
+			INNER JOIN measurement_terminal AS t ON m.terminal_id = t.id
+		%s
+		ORDER BY
+			p.hospital_id ASC, m.id ASC`,
+		prefixColumns(model.Measurement{}, "m", "m"),
+		prefixColumns(model.Patient{}, "p", "p"),
+		prefixColumns(model.MeasurementTerminal{}, "t", "t"),
+		where,
+	)
+
+	if rows, e := db.Query(query, params.values...); e != nil {
+		return nil, e
+	} else {
+		safeRowsIterator(rows, func(rows *sql.Rows) {
+			entity := model.MeasurementEntity{
+				Measurement: &model.Measurement{},
+				Terminal:    &model.MeasurementTerminal{},
+				Patient:     &model.Patient{},
+			}
+
+			scanRows(db, rows, entity.Measurement, "m")
+			scanRows(db, rows, entity.Terminal, "t")
+			scanRows(db, rows, entity.Patient, "p")
+
+			results = append(results, &entity)
+		})
+	}
+
+	return results, nil
+}
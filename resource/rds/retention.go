@@ -0,0 +1,67 @@
+package rds
+
+import (
+	"time"
+
+	"github.com/spiker/spiker-server/model"
+)
+
+// 病院の計測データの保存期間を取得する。設定されていない場合はnilを返す。
+func FetchHospitalRetention(
+	db model.QueryExecutor,
+	hospitalId int,
+) (*model.HospitalRetention, error) {
+	if r, e := db.Get(model.HospitalRetention{}, hospitalId); e != nil {
+		return nil, e
+	} else if r == nil {
+		return nil, nil
+	} else {
+		return r.(*model.HospitalRetention), nil
+	}
+}
+
+// 保存期間を過ぎた計測を取得する。
+// 保存期間が設定された病院の完了した計測のうち、アーカイブされていないものと、restoredBefore以前に復元されたものが対象となる。
+func ListArchivableMeasurements(
+	db model.QueryExecutor,
+	now time.Time,
+	restoredBefore time.Time,
+) ([]*model.Measurement, error) {
+	records := []*model.Measurement{}
+
+	if _, e := db.Select(
+		&records,
+		`SELECT
+			m.*
+		FROM
+			measurement AS m
+			INNER JOIN patient AS p ON m.patient_id = p.id
+			INNER JOIN hospital_retention AS r ON p.hospital_id = r.hospital_id
+			LEFT OUTER JOIN measurement_archive AS a ON m.id = a.measurement_id
+		WHERE
+			m.is_closed
+			AND m.closed_at + make_interval(years => r.years) <= $1
+			AND (a.measurement_id IS NULL OR a.restored_at <= $2)
+		ORDER BY
+			m.id ASC`,
+		now, restoredBefore,
+	); e != nil {
+		return nil, e
+	}
+
+	return records, nil
+}
+
+// 計測データのアーカイブを取得する。アーカイブされていない場合はnilを返す。
+func FetchMeasurementArchive(
+	db model.QueryExecutor,
+	measurementId int,
+) (*model.MeasurementArchive, error) {
+	if r, e := db.Get(model.MeasurementArchive{}, measurementId); e != nil {
+		return nil, e
+	} else if r == nil {
+		return nil, nil
+	} else {
+		return r.(*model.MeasurementArchive), nil
+	}
+}
@@ -0,0 +1,201 @@
+package rds
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/spiker/spiker-server/model"
+)
+
+// リスクテーブルを名前、バージョン順に取得する。
+func ListRiskTables(
+	db model.QueryExecutor,
+	name *string,
+	limit int,
+	offset int,
+) ([]*model.RiskTable, int64, error) {
+	ip := incrementalPlaceholder{0}
+
+	q := andQuery()
+
+	if name != nil {
+		q.add(fmt.Sprintf("name = $%d", ip.GetIndex()), *name)
+	}
+
+	where, params := q.where()
+
+	records := []*model.RiskTable{}
+
+	query := fmt.Sprintf(
+		`SELECT * FROM risk_table %s ORDER BY name ASC, version DESC LIMIT $%d OFFSET $%d`,
+		where, ip.GetIndex(), ip.GetIndex(),
+	)
+
+	if _, e := db.Select(&records, query, params.clone().add(limit, offset).values...); e != nil {
+		return nil, 0, e
+	}
+
+	if total, e := db.SelectInt(fmt.Sprintf(`SELECT COUNT(*) FROM risk_table %s`, where), params.values...); e != nil {
+		return nil, 0, e
+	} else {
+		return records, total, nil
+	}
+}
+
+// リスクテーブルをIDから取得する。
+func FetchRiskTable(
+	db model.QueryExecutor,
+	id int,
+) (*model.RiskTable, error) {
+	if r, e := db.Get(model.RiskTable{}, id); e != nil {
+		return nil, e
+	} else if r == nil {
+		return nil, nil
+	} else {
+		return r.(*model.RiskTable), nil
+	}
+}
+
+// 同じ名前のリスクテーブルの最新バージョンを取得する。存在しない場合は0を返す。
+func FetchLatestRiskTableVersion(
+	db model.QueryExecutor,
+	name string,
+) (int, error) {
+	if v, e := db.SelectInt(`SELECT COALESCE(MAX(version), 0) FROM risk_table WHERE name = $1`, name); e != nil {
+		return 0, e
+	} else {
+		return int(v), nil
+	}
+}
+
+// リスクテーブルが病院または診断から参照されているか調べる。
+func IsRiskTableReferred(
+	db model.QueryExecutor,
+	id int,
+) (bool, error) {
+	query := `SELECT
+			(SELECT COUNT(*) FROM hospital_risk_table WHERE risk_table_id = $1)
+			+ (SELECT COUNT(*) FROM diagnosis_risk_table WHERE risk_table_id = $1)`
+
+	if n, e := db.SelectInt(query, id); e != nil {
+		return false, e
+	} else {
+		return n > 0, nil
+	}
+}
+
+// 病院で利用するリスクテーブルを取得する。設定されていない場合はnilを返す。
+func FetchHospitalRiskTable(
+	db model.QueryExecutor,
+	hospitalId int,
+) (*model.RiskTable, error) {
+	record := model.RiskTable{}
+
+	query := `SELECT
+			rt.*
+		FROM
+			risk_table AS rt
+			INNER JOIN hospital_risk_table AS hrt ON rt.id = hrt.risk_table_id
+		WHERE
+			hrt.hospital_id = $1`
+
+	if e := db.SelectOne(&record, query, hospitalId); e != nil {
+		if e == sql.ErrNoRows {
+			return nil, nil
+		} else {
+			return nil, e
+		}
+	} else {
+		return &record, nil
+	}
+}
+
+// 計測の患者が属する病院で利用するリスクテーブルを取得する。設定されていない場合はnilを返す。
+func FetchRiskTableForMeasurement(
+	db model.QueryExecutor,
+	measurementId int,
+) (*model.RiskTable, error) {
+	record := model.RiskTable{}
+
+	query := `SELECT
+			rt.*
+		FROM
+			risk_table AS rt
+			INNER JOIN hospital_risk_table AS hrt ON rt.id = hrt.risk_table_id
+			INNER JOIN patient AS p ON hrt.hospital_id = p.hospital_id
+			INNER JOIN measurement AS m ON p.id = m.patient_id
+		WHERE
+			m.id = $1`
+
+	if e := db.SelectOne(&record, query, measurementId); e != nil {
+		if e == sql.ErrNoRows {
+			return nil, nil
+		} else {
+			return nil, e
+		}
+	} else {
+		return &record, nil
+	}
+}
+
+// DiagnosisEntityに算出に利用したリスクテーブルをセットする。
+func feedDiagnosisRiskTables(
+	db model.QueryExecutor,
+	entities ...*model.DiagnosisEntity,
+) error {
+	ids := []int{}
+
+	for _, e := range entities {
+		ids = append(ids, e.Diagnosis.Id)
+	}
+
+	links := []*model.DiagnosisRiskTable{}
+
+	if _, e := db.Select(
+		&links,
+		`SELECT * FROM diagnosis_risk_table WHERE diagnosis_id IN (:ids)`,
+		map[string]interface{}{"ids": ids},
+	); e != nil {
+		return e
+	}
+
+	if len(links) == 0 {
+		return nil
+	}
+
+	tableIds := []int{}
+
+	for _, l := range links {
+		tableIds = append(tableIds, l.RiskTableId)
+	}
+
+	tables := []*model.RiskTable{}
+
+	if _, e := db.Select(
+		&tables,
+		`SELECT * FROM risk_table WHERE id IN (:ids)`,
+		map[string]interface{}{"ids": tableIds},
+	); e != nil {
+		return e
+	}
+
+	tableMap := map[int]*model.RiskTable{}
+
+	for _, t := range tables {
+		tableMap[t.Id] = t
+	}
+
+	linkMap := map[int]*model.RiskTable{}
+
+	for _, l := range links {
+		linkMap[l.DiagnosisId] = tableMap[l.RiskTableId]
+	}
+
+	for _, e := range entities {
+		if t, be := linkMap[e.Diagnosis.Id]; be {
+			e.RiskTable = t
+		}
+	}
+
+	return nil
+}
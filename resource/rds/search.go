@@ -0,0 +1,360 @@
+package rds
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/spiker/spiker-server/lib/phi"
+	"github.com/spiker/spiker-server/lib/search"
+	"github.com/spiker/spiker-server/model"
+)
+
+// 病院内の検索。
+//
+// 語は全て一致する必要があり、各語はいずれかの列に部分一致すればよい。
+// 部分一致はILIKEで行うため、対象の列にpg_trgmのGINインデックス(gin_trgm_ops)を作成しておく。
+// 英数字の語はメモに対して全文検索(to_tsvector('simple', ...))の前方一致も行う。
+// 暗号化された患者の氏名と計測コードはデータベース上で部分一致できないため、呼び出し側で照合した結果を受け取る。
+// 暗号化された氏名は索引(patient_name_token)で候補を絞り込んだ上で、呼び出し側で復号して照合する。
+// インデックスはscript/create_search_indexesで作成する。
+
+// 語が列に部分一致する条件。fullTextがtrueの場合、英数字の語は全文検索の前方一致も条件に含める。
+func matchTerm(ip *incrementalPlaceholder, column string, term string, fullText bool) *querying {
+	q := orQuery()
+
+	for _, v := range search.Variants(term) {
+		q.add(fmt.Sprintf(`%s ILIKE $%d`, column, ip.GetIndex()), "%"+escapeLike(v)+"%")
+	}
+
+	if fullText && search.IsWord(term) {
+		q.add(fmt.Sprintf(`to_tsvector('simple', %s) @@ to_tsquery('simple', $%d)`, column, ip.GetIndex()), term+":*")
+	}
+
+	return q
+}
+
+// 列の値がいずれかに一致する条件。
+func matchAny(ip *incrementalPlaceholder, column string, values []interface{}) *querying {
+	placeholders := []string{}
+
+	for range values {
+		placeholders = append(placeholders, fmt.Sprintf("$%d", ip.GetIndex()))
+	}
+
+	return andQuery().add(fmt.Sprintf(`%s IN (%s)`, column, strings.Join(placeholders, ", ")), values...)
+}
+
+// 暗号化された氏名を持つ患者のうち、氏名の索引を全て持つ患者を取得する。氏名の照合のため、呼び出し側で復号する。
+// 索引は衝突を許容するため、氏名が一致しない患者も含まれ得る。
+func ListPatientsWithSealedName(
+	db model.QueryExecutor,
+	hospitalId int,
+	tokens []string,
+) ([]*model.Patient, error) {
+	records := []*model.Patient{}
+
+	if len(tokens) == 0 {
+		return records, nil
+	}
+
+	ip := incrementalPlaceholder{0}
+
+	q := andQuery().
+		add(fmt.Sprintf("p.hospital_id = $%d", ip.GetIndex()), hospitalId).
+		add(fmt.Sprintf("p.name LIKE $%d", ip.GetIndex()), escapeLike(phi.Prefix)+"%")
+
+	unique := []string{}
+	found := map[string]bool{}
+
+	for _, t := range tokens {
+		if !found[t] {
+			unique = append(unique, t)
+			found[t] = true
+		}
+	}
+
+	tokenMatch := matchAny(&ip, "nt.token", newInterfaces().addStrings(unique...).values)
+
+	q.add(fmt.Sprintf(
+		`p.id IN (SELECT nt.patient_id FROM patient_name_token AS nt WHERE %s GROUP BY nt.patient_id HAVING COUNT(DISTINCT nt.token) = $%d)`,
+		tokenMatch.Clause(), ip.GetIndex(),
+	), append(tokenMatch.Params(), len(unique))...)
+
+	where, params := q.where()
+
+	query := fmt.Sprintf(
+		`SELECT %s FROM patient AS p %s`,
+		prefixColumns(model.Patient{}, "p", "p"),
+		where,
+	)
+
+	if rows, e := db.Query(query, params.values...); e != nil {
+		return nil, e
+	} else {
+		safeRowsIterator(rows, func(rows *sql.Rows) {
+			patient := &model.Patient{}
+
+			scanRows(db, rows, patient, "p")
+
+			records = append(records, patient)
+		})
+	}
+
+	return records, nil
+}
+
+// 氏名、メモから患者を検索する。nameMatchedは呼び出し側で暗号化された氏名と照合した患者のID。
+// 計測のある患者を優先し、最終計測日時が最も遅い順にソートする。
+func SearchPatients(
+	db model.QueryExecutor,
+	hospitalId int,
+	terms []string,
+	nameMatched []int,
+	limit int,
+) ([]*model.Patient, error) {
+	ip := incrementalPlaceholder{0}
+
+	q := andQuery().add(fmt.Sprintf("p.hospital_id = $%d", ip.GetIndex()), hospitalId)
+
+	matches := andQuery()
+
+	for _, t := range terms {
+		name := andQuery().add(fmt.Sprintf("p.name NOT LIKE $%d", ip.GetIndex()), escapeLike(phi.Prefix)+"%")
+		name.addCondition(matchTerm(&ip, "p.name", t, false))
+
+		matches.addCondition(orQuery().addCondition(name).addCondition(matchTerm(&ip, "p.memo", t, true)))
+	}
+
+	if len(nameMatched) > 0 {
+		q.addCondition(orQuery().addCondition(matches).addCondition(matchAny(&ip, "p.id", newInterfaces().addInts(nameMatched...).values)))
+	} else {
+		q.addCondition(matches)
+	}
+
+	where, params := q.where()
+
+	query := fmt.Sprintf(
+		`SELECT
+			%s
+		FROM
+			patient AS p
+			LEFT JOIN (
+				SELECT m.patient_id, MAX(m.last_time) AS last FROM measurement AS m GROUP BY m.patient_id
+			) AS pm ON p.id = pm.patient_id
+		%s
+		ORDER BY
+			pm.last DESC NULLS LAST, p.id DESC
+		LIMIT $%d`,
+		prefixColumns(model.Patient{}, "p", "p"),
+		where, ip.GetIndex(),
+	)
+
+	records := []*model.Patient{}
+
+	if rows, e := db.Query(query, params.add(limit).values...); e != nil {
+		return nil, e
+	} else {
+		safeRowsIterator(rows, func(rows *sql.Rows) {
+			patient := &model.Patient{}
+
+			scanRows(db, rows, patient, "p")
+
+			records = append(records, patient)
+		})
+	}
+
+	return records, nil
+}
+
+// 計測コード、完了時のメモから計測を検索する。codesは計測コードの検索に用いる値で、完全に一致する計測も含める。
+// 暗号化されていない計測コードは前方一致とし、計測コードの一致する計測を優先して新しい順にソートする。
+func SearchMeasurementsByTerms(
+	db model.QueryExecutor,
+	hospitalId int,
+	terms []string,
+	codes []string,
+	limit int,
+) ([]*model.MeasurementEntity, error) {
+	ip := incrementalPlaceholder{0}
+
+	q := andQuery().add(fmt.Sprintf("p.hospital_id = $%d", ip.GetIndex()), hospitalId)
+
+	matches := andQuery()
+
+	for _, t := range terms {
+		matches.addCondition(orQuery().
+			addCondition(matchCodePrefix(&ip, t)).
+			addCondition(matchTerm(&ip, "m.closing_memo", t, true)))
+	}
+
+	if len(codes) > 0 {
+		q.addCondition(orQuery().addCondition(matches).addCondition(matchAny(&ip, "m.code", newInterfaces().addStrings(codes...).values)))
+	} else {
+		q.addCondition(matches)
+	}
+
+	where, params := q.where()
+
+	// 計測コードの一致を優先する。
+	priority := orQuery()
+
+	for _, t := range terms {
+		priority.addCondition(matchCodePrefix(&ip, t))
+	}
+
+	if len(codes) > 0 {
+		priority.addCondition(matchAny(&ip, "m.code", newInterfaces().addStrings(codes...).values))
+	}
+
+	query := fmt.Sprintf(
+		`SELECT
+			%s, %s, %s
+		FROM
+			measurement AS m
+			INNER JOIN patient AS p ON m.patient_id = p.id
+			INNER JOIN measurement_terminal AS t ON m.terminal_id = t.id
+		%s
+		ORDER BY
+			(%s) DESC, m.created_at DESC, m.id DESC
+		LIMIT $%d`,
+		prefixColumns(model.Measurement{}, "m", "m"),
+		prefixColumns(model.Patient{}, "p", "p"),
+		prefixColumns(model.MeasurementTerminal{}, "t", "t"),
+		where, priority.Clause(), ip.GetIndex(),
+	)
+
+	results := []*model.MeasurementEntity{}
+
+	if rows, e := db.Query(query, params.add(priority.Params()...).add(limit).values...); e != nil {
+		return nil, e
+	} else {
+		safeRowsIterator(rows, func(rows *sql.Rows) {
+			entity := model.MeasurementEntity{
+				Measurement: &model.Measurement{},
+				Terminal:    &model.MeasurementTerminal{},
+				Patient:     &model.Patient{},
+			}
+
+			scanRows(db, rows, entity.Measurement, "m")
+			scanRows(db, rows, entity.Terminal, "t")
+			scanRows(db, rows, entity.Patient, "p")
+
+			results = append(results, &entity)
+		})
+	}
+
+	return results, nil
+}
+
+// 暗号化されていない計測コードが語に前方一致する条件。
+func matchCodePrefix(ip *incrementalPlaceholder, term string) *querying {
+	return andQuery().
+		add(fmt.Sprintf("m.code NOT LIKE $%d", ip.GetIndex()), escapeLike(phi.Prefix)+"%").
+		add(fmt.Sprintf("m.code ILIKE $%d", ip.GetIndex()), escapeLike(term)+"%")
+}
+
+// コード、名前、メモから計測端末を検索する。コードが前方一致する端末を優先してID順にソートする。
+func SearchTerminals(
+	db model.QueryExecutor,
+	hospitalId int,
+	terms []string,
+	limit int,
+) ([]*model.MeasurementTerminal, error) {
+	ip := incrementalPlaceholder{0}
+
+	q := andQuery().add(fmt.Sprintf("t.hospital_id = $%d", ip.GetIndex()), hospitalId)
+
+	prefixes := orQuery()
+
+	for _, t := range terms {
+		q.addCondition(orQuery().
+			addCondition(matchTerm(&ip, "t.code", t, false)).
+			addCondition(matchTerm(&ip, "t.name", t, false)).
+			addCondition(matchTerm(&ip, "t.memo", t, true)))
+	}
+
+	for _, t := range terms {
+		prefixes.add(fmt.Sprintf("t.code ILIKE $%d", ip.GetIndex()), escapeLike(t)+"%")
+	}
+
+	where, params := q.where()
+
+	query := fmt.Sprintf(
+		`SELECT
+			%s
+		FROM
+			measurement_terminal AS t
+		%s
+		ORDER BY
+			(%s) DESC, t.id ASC
+		LIMIT $%d`,
+		prefixColumns(model.MeasurementTerminal{}, "t", "t"),
+		where, prefixes.Clause(), ip.GetIndex(),
+	)
+
+	records := []*model.MeasurementTerminal{}
+
+	if rows, e := db.Query(query, params.add(prefixes.Params()...).add(limit).values...); e != nil {
+		return nil, e
+	} else {
+		safeRowsIterator(rows, func(rows *sql.Rows) {
+			terminal := &model.MeasurementTerminal{}
+
+			scanRows(db, rows, terminal, "t")
+
+			records = append(records, terminal)
+		})
+	}
+
+	return records, nil
+}
+
+// メモから診断を検索する。新しい順にソートする。
+func SearchDiagnosisMemos(
+	db model.QueryExecutor,
+	hospitalId int,
+	terms []string,
+	limit int,
+) ([]*model.Diagnosis, error) {
+	ip := incrementalPlaceholder{0}
+
+	q := andQuery().add(fmt.Sprintf("p.hospital_id = $%d", ip.GetIndex()), hospitalId)
+
+	for _, t := range terms {
+		q.addCondition(matchTerm(&ip, "d.memo", t, true))
+	}
+
+	where, params := q.where()
+
+	query := fmt.Sprintf(
+		`SELECT
+			%s
+		FROM
+			diagnosis AS d
+			INNER JOIN measurement AS m ON d.measurement_id = m.id
+			INNER JOIN patient AS p ON m.patient_id = p.id
+		%s
+		ORDER BY
+			d.created_at DESC, d.id DESC
+		LIMIT $%d`,
+		prefixColumns(model.Diagnosis{}, "d", "d"),
+		where, ip.GetIndex(),
+	)
+
+	records := []*model.Diagnosis{}
+
+	if rows, e := db.Query(query, params.add(limit).values...); e != nil {
+		return nil, e
+	} else {
+		safeRowsIterator(rows, func(rows *sql.Rows) {
+			diagnosis := &model.Diagnosis{}
+
+			scanRows(db, rows, diagnosis, "d")
+
+			records = append(records, diagnosis)
+		})
+	}
+
+	return records, nil
+}
@@ -0,0 +1,74 @@
+package rds
+
+import (
+	"fmt"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/model"
+)
+
+// 自動診断イベントのスナップショットを取得する。スナップショットの無いイベントは含まれない。
+func ListEventSnapshots(
+	db model.QueryExecutor,
+	eventIds []int,
+) ([]*model.EventSnapshot, error) {
+	records := []*model.EventSnapshot{}
+
+	if len(eventIds) == 0 {
+		return records, nil
+	}
+
+	if _, e := db.Select(
+		&records,
+		`SELECT * FROM event_snapshot WHERE computed_event_id IN (:ids)`,
+		map[string]interface{}{"ids": eventIds},
+	); e != nil {
+		return nil, e
+	}
+
+	return records, nil
+}
+
+// 計測の自動診断イベントのうち、病院がアラートに用いるガイドラインが指定したものであり、
+// そのガイドラインで指定したレベル以上となるスナップショットを持たないものを古い順に取得する。
+// ガイドラインを設定していない病院はJSOGとみなす。
+func ListEventsWithoutSnapshot(
+	db model.QueryExecutor,
+	measurementIds []int,
+	guideline C.Guideline,
+	level int,
+) ([]*model.ComputedEvent, error) {
+	records := []*model.ComputedEvent{}
+
+	if len(measurementIds) == 0 {
+		return records, nil
+	}
+
+	condition := `e.risk >= :level`
+
+	if guideline != C.GuidelineJSOG {
+		condition = `EXISTS (
+			SELECT * FROM computed_event_classification WHERE computed_event_id = e.id AND guideline = :guideline AND level >= :level
+		)`
+	}
+
+	query := fmt.Sprintf(`SELECT e.* FROM computed_event AS e
+			INNER JOIN measurement AS m ON e.measurement_id = m.id
+			INNER JOIN patient AS p ON m.patient_id = p.id
+			LEFT JOIN hospital_guideline AS g ON p.hospital_id = g.hospital_id
+		WHERE e.measurement_id IN (:ids) AND %s AND NOT e.is_hidden
+			AND COALESCE(g.guideline, :jsog) = :guideline
+			AND NOT EXISTS (SELECT * FROM event_snapshot WHERE computed_event_id = e.id)
+		ORDER BY e.range_from ASC, e.id ASC`, condition)
+
+	if _, e := db.Select(&records, query, map[string]interface{}{
+		"ids": measurementIds,
+		"level": level,
+		"guideline": string(guideline),
+		"jsog": string(C.GuidelineJSOG),
+	}); e != nil {
+		return nil, e
+	}
+
+	return records, nil
+}
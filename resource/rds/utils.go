@@ -2,6 +2,9 @@ package rds
 
 import (
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"strings"
@@ -163,6 +166,20 @@ func (i *interfaces) add(values ...interface{}) *interfaces {
 	return i
 }
 
+func (i *interfaces) addInts(values ...int) *interfaces {
+	for _, v := range values {
+		i.values = append(i.values, v)
+	}
+	return i
+}
+
+func (i *interfaces) addStrings(values ...string) *interfaces {
+	for _, v := range values {
+		i.values = append(i.values, v)
+	}
+	return i
+}
+
 func (i *interfaces) prepend(values ...interface{}) *interfaces {
 	i.values = append(values, i.values...)
 	return i
@@ -244,3 +261,199 @@ func (q querying) where() (string, *interfaces) {
 		return "", newInterfaces(params...)
 	}
 }
+
+var (
+	ErrInvalidSort   = errors.New("Invalid sort")
+	ErrInvalidCursor = errors.New("Invalid cursor")
+)
+
+// 一覧のソートに用いる式。NULLとならない式とする。
+type sortColumn struct {
+	expr string
+	desc bool
+}
+
+// 一覧のソート順。最後の式で行が一意に定まるものとする。
+type sortOrder struct {
+	spec    string
+	columns []sortColumn
+}
+
+// ソートする列の名前からソート順を作成する。降順の場合は名前の先頭に"-"を付ける。
+// keysは名前から式へのマップで、idは行を一意に定める式。同順の行はidの昇順とする。
+func newSortOrder(spec string, defaultSpec string, keys map[string]string, id string) (*sortOrder, error) {
+	if spec == "" {
+		spec = defaultSpec
+	}
+
+	expr, be := keys[strings.TrimPrefix(spec, "-")]
+
+	if !be {
+		return nil, ErrInvalidSort
+	}
+
+	columns := []sortColumn{{expr, strings.HasPrefix(spec, "-")}}
+
+	if expr != id {
+		columns = append(columns, sortColumn{id, false})
+	}
+
+	return &sortOrder{spec, columns}, nil
+}
+
+func (o *sortOrder) orderBy() string {
+	clauses := []string{}
+
+	for _, c := range o.columns {
+		if c.desc {
+			clauses = append(clauses, fmt.Sprintf("%s DESC", c.expr))
+		} else {
+			clauses = append(clauses, fmt.Sprintf("%s ASC", c.expr))
+		}
+	}
+
+	return strings.Join(clauses, ", ")
+}
+
+// カーソルとするソートの式の値を文字列として取得する列。
+func (o *sortOrder) cursorColumns() string {
+	columns := []string{}
+
+	for i, c := range o.columns {
+		columns = append(columns, fmt.Sprintf("(%s)::text AS cursor_%d", c.expr, i))
+	}
+
+	return strings.Join(columns, ", ")
+}
+
+// カーソルの値の行より後の行に限定する条件。
+func (o *sortOrder) after(ip *incrementalPlaceholder, values []string) Conditional {
+	q := orQuery()
+
+	for i, c := range o.columns {
+		and := andQuery()
+
+		for j := 0; j < i; j++ {
+			and.add(fmt.Sprintf("%s = $%d", o.columns[j].expr, ip.GetIndex()), values[j])
+		}
+
+		if c.desc {
+			and.add(fmt.Sprintf("%s < $%d", c.expr, ip.GetIndex()), values[i])
+		} else {
+			and.add(fmt.Sprintf("%s > $%d", c.expr, ip.GetIndex()), values[i])
+		}
+
+		q.addCondition(and)
+	}
+
+	return q
+}
+
+type pagingCursor struct {
+	Sort   string   `json:"sort"`
+	Values []string `json:"values"`
+}
+
+// キーセットによるページング。
+// 続きの行の有無を調べるため、LIMITは1件多く取得する。
+type pager struct {
+	paging  *model.Paging
+	order   *sortOrder
+	cursors [][]string
+}
+
+func newPager(paging *model.Paging, order *sortOrder) *pager {
+	return &pager{paging, order, [][]string{}}
+}
+
+// カーソルが指定されている場合、カーソルより後の行に限定する条件を加える。
+func (p *pager) filter(q *querying, ip *incrementalPlaceholder) error {
+	if p.paging.Cursor == "" {
+		return nil
+	}
+
+	bs, err := base64.RawURLEncoding.DecodeString(p.paging.Cursor)
+
+	if err != nil {
+		return ErrInvalidCursor
+	}
+
+	cursor := &pagingCursor{}
+
+	if e := json.Unmarshal(bs, cursor); e != nil {
+		return ErrInvalidCursor
+	} else if cursor.Sort != p.order.spec || len(cursor.Values) != len(p.order.columns) {
+		return ErrInvalidCursor
+	}
+
+	q.addCondition(p.order.after(ip, cursor.Values))
+
+	return nil
+}
+
+// ORDER BY、LIMIT、OFFSET句とそのパラメータ。
+func (p *pager) clause(ip *incrementalPlaceholder) (string, []interface{}) {
+	offset := p.paging.Offset
+
+	if p.paging.Cursor != "" {
+		offset = 0
+	}
+
+	return fmt.Sprintf(
+		"ORDER BY %s LIMIT $%d OFFSET $%d",
+		p.order.orderBy(), ip.GetIndex(), ip.GetIndex(),
+	), []interface{}{p.paging.Limit + 1, offset}
+}
+
+// 行のカーソルの値を取得する。行毎に呼び出す。
+func (p *pager) scan(rows *sql.Rows) {
+	columns, _ := rows.Columns()
+
+	var ignored interface{}
+	values := make([]interface{}, len(columns))
+	cursor := make([]string, len(p.order.columns))
+
+	for i, c := range columns {
+		values[i] = &ignored
+
+		for j := range cursor {
+			if c == fmt.Sprintf("cursor_%d", j) {
+				values[i] = &cursor[j]
+			}
+		}
+	}
+
+	rows.Scan(values...)
+
+	p.cursors = append(p.cursors, cursor)
+}
+
+// 取得した行数から、一覧に含める行数と付加情報を求める。総数はカーソルが指定されていない場合のみcountで数える。
+func (p *pager) page(length int, count func() (int64, error)) (int, *model.PageInfo, error) {
+	info := &model.PageInfo{Total: -1}
+
+	if length > p.paging.Limit {
+		length = p.paging.Limit
+
+		if length > 0 {
+			bs, err := json.Marshal(&pagingCursor{p.order.spec, p.cursors[length-1]})
+
+			if err != nil {
+				return 0, nil, err
+			}
+
+			cursor := base64.RawURLEncoding.EncodeToString(bs)
+			info.NextCursor = &cursor
+		}
+	}
+
+	if p.paging.Cursor == "" {
+		if total, e := count(); e != nil {
+			return 0, nil, e
+		} else {
+			info.Total = total
+		}
+	}
+
+	return length, info, nil
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io/ioutil"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -31,6 +32,7 @@ type storageConfiguration struct {
 type Storage interface {
 	ListAll() ([]string, error)
 	Put(filename string, body []byte) error
+	Get(filename string) ([]byte, error)
 	Delete(filename string) error
 	DeleteByPrefix(prefix string) error
 	PresignedUrl(filename string) (string, error)
@@ -91,6 +93,23 @@ func (s *storageResource) Put(filename string, body []byte) error {
 	return nil
 }
 
+// Get ファイルダウンロード。
+func (s *storageResource) Get(filename string) ([]byte, error) {
+	key := s.saveKey(filename)
+
+	res, err := s.svc().GetObjectRequest(&s3.GetObjectInput{
+		Bucket: &s.bucket,
+		Key:    &key,
+	}).Send(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	return ioutil.ReadAll(res.Body)
+}
+
 func (s *storageResource) Delete(filename string) error {
 	svc := s.svc()
 
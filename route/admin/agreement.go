@@ -0,0 +1,77 @@
+package admin
+
+import (
+	"net/http"
+	"time"
+
+	v "github.com/go-ozzo/ozzo-validation/v4"
+
+	C "github.com/spiker/spiker-server/constant"
+	S "github.com/spiker/spiker-server/service"
+	"github.com/spiker/spiker-server/route/shared"
+)
+
+type computeAgreementQuery struct {
+	Algorithm string     `query:"algorithm"`
+	Version   string     `query:"version"`
+	Hospital  *int       `query:"hospital"`
+	From      *time.Time `query:"from"`
+	Until     *time.Time `query:"until"`
+}
+
+// computeAgreement godoc
+// @summary アルゴリズムとアノテーターの一致度を集計する。
+// @description 病院、期間の少なくとも一方の指定が必要。自動診断イベントは最新のアノテーションと比較し、自動診断イベントを参照しないアノテーションは見逃しとして扱う。アノテーションのある計測でアノテーションの無い自動診断イベントはイベント無しと判断されたものとし、アノテーションの無い計測のイベントは集計せずunreviewedに件数を示す。
+// @tags [admin] Agreement
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param algorithm query string true "アルゴリズム名。"
+// @param version query string true "アルゴリズムバージョン。"
+// @param hospital query int false "病院ID。"
+// @param from query string false "期間の先頭日時。RFC3339形式。"
+// @param until query string false "期間の末尾日時。RFC3339形式。"
+// @success 200 {object} service.AgreementReport "一致度。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
+// @failure 404 {object} shared.ErrorResponse "アルゴリズムが存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /admin/agreements [get]
+func computeAgreement(c *shared.Context) error {
+	query := &computeAgreementQuery{}
+
+	if e := c.Bind(query); e != nil {
+		return e
+	}
+
+	if e := (v.Errors{
+		"algorithm": v.Validate(query.Algorithm, v.Required),
+		"version": v.Validate(query.Version, v.Required),
+	}).Filter(); e != nil {
+		return e
+	}
+
+	if query.Hospital == nil && (query.From == nil || query.Until == nil) {
+		return C.NewBadRequestError(
+			"agreement_scope_required",
+			"Either hospital or both from and until must be specified",
+			map[string]interface{}{},
+		)
+	}
+
+	if query.From != nil && query.Until != nil && !query.From.Before(*query.Until) {
+		return C.NewBadRequestError(
+			"invalid_range",
+			"from must be before until",
+			map[string]interface{}{},
+		)
+	}
+
+	service := shared.CreateService(S.AgreementService{}, c).(*S.AgreementService)
+
+	result, err := service.Compute(query.Algorithm, query.Version, query.Hospital, query.From, query.Until)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
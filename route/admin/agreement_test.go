@@ -0,0 +1,228 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/model"
+	S "github.com/spiker/spiker-server/service"
+	"github.com/spiker/spiker-server/test"
+	F "github.com/spiker/spiker-server/test/fixture"
+)
+
+func TestAdminAgreement_Compute(t *testing.T) {
+	auth := (&F.AdminFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	beginTime := time.Date(2021, time.October, 1, 12, 0, 0, 0, time.UTC)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "病院指定",
+			Method:  http.MethodGet,
+			Path:    "/admin/agreements",
+			Token:   auth.Token(1),
+			Query:   func(q url.Values) {
+				q.Add("algorithm", "alg-0001")
+				q.Add("version", "1.0.0")
+				q.Add("hospital", "1")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &S.AgreementReport{}).(*S.AgreementReport)
+
+				assert.EqualValues(t, 1, res.Algorithm.Id)
+
+				// 自動診断イベント1-3の最新アノテーションと、イベントを参照しないアノテーション4、アノテーションの無いイベント5。
+				assert.EqualValues(t, 5, res.Total)
+				assert.EqualValues(t, 0, res.Skipped)
+				assert.EqualValues(t, 0, res.Unreviewed)
+
+				assert.EqualValues(t, 1, res.ConfusionMatrix[1][1])
+				assert.EqualValues(t, 1, res.ConfusionMatrix[3][3])
+				assert.EqualValues(t, 1, res.ConfusionMatrix[5][2])
+				assert.EqualValues(t, 1, res.ConfusionMatrix[0][4])
+				assert.EqualValues(t, 1, res.ConfusionMatrix[4][0])
+
+				assert.EqualValues(t, 1, res.Alert.TruePositive)
+				assert.EqualValues(t, 2, res.Alert.FalsePositive)
+				assert.EqualValues(t, 1, res.Alert.FalseNegative)
+				assert.EqualValues(t, 1, res.Alert.TrueNegative)
+
+				assert.EqualValues(t, 3, res.Overlap.Pairs)
+			},
+		},
+		{
+			Name:    "未レビューの計測",
+			Method:  http.MethodGet,
+			Path:    "/admin/agreements",
+			Token:   auth.Token(1),
+			Query:   func(q url.Values) {
+				q.Add("algorithm", "alg-0001")
+				q.Add("version", "1.0.0")
+				q.Add("hospital", "2")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &S.AgreementReport{}).(*S.AgreementReport)
+
+				// アノテーションの無い計測2のイベントは集計せず、件数のみを示す。
+				assert.EqualValues(t, 0, res.Total)
+				assert.EqualValues(t, 1, res.Unreviewed)
+			},
+		},
+		{
+			Name:    "期間指定",
+			Method:  http.MethodGet,
+			Path:    "/admin/agreements",
+			Token:   auth.Token(1),
+			Query:   func(q url.Values) {
+				q.Add("algorithm", "alg-0001")
+				q.Add("version", "1.0.0")
+				q.Add("from", "2021-10-01T12:00:00+00:00")
+				q.Add("until", "2021-10-01T13:00:00+00:00")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &S.AgreementReport{}).(*S.AgreementReport)
+
+				assert.EqualValues(t, 1, res.Total)
+				assert.EqualValues(t, 1, res.ConfusionMatrix[1][1])
+			},
+		},
+		{
+			Name:    "他のアルゴリズム",
+			Method:  http.MethodGet,
+			Path:    "/admin/agreements",
+			Token:   auth.Token(1),
+			Query:   func(q url.Values) {
+				q.Add("algorithm", "alg-0002")
+				q.Add("version", "1.0.0")
+				q.Add("hospital", "1")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &S.AgreementReport{}).(*S.AgreementReport)
+
+				assert.EqualValues(t, 0, res.Total)
+				assert.Nil(t, res.Kappa)
+			},
+		},
+		{
+			Name:    "アルゴリズムが無い",
+			Method:  http.MethodGet,
+			Path:    "/admin/agreements",
+			Token:   auth.Token(1),
+			Query:   func(q url.Values) {
+				q.Add("algorithm", "alg-0001")
+				q.Add("version", "2.0.0")
+				q.Add("hospital", "1")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+		{
+			Name:    "対象の指定が無い",
+			Method:  http.MethodGet,
+			Path:    "/admin/agreements",
+			Token:   auth.Token(1),
+			Query:   func(q url.Values) {
+				q.Add("algorithm", "alg-0001")
+				q.Add("version", "1.0.0")
+				q.Add("from", "2021-10-01T12:00:00+00:00")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			Name:    "アルゴリズムの指定が無い",
+			Method:  http.MethodGet,
+			Path:    "/admin/agreements",
+			Token:   auth.Token(1),
+			Query:   func(q url.Values) {
+				q.Add("hospital", "1")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		F.Truncate(db, "annotated_event", "computed_event", "computed_diagnosis", "diagnosis", "diagnosis_algorithm", "measurement", "patient", "hospital")
+
+		hospitals := F.Insert(db, model.Hospital{}, 0, 2, func(i int, r F.Record) {
+			r["Uuid"] = fmt.Sprintf("hospital-%04d", i)
+		}).([]*model.Hospital)
+
+		patients := F.Insert(db, model.Patient{}, 0, 2, func(i int, r F.Record) {
+			r["HospitalId"] = hospitals[i-1].Id
+		}).([]*model.Patient)
+
+		measurements := F.Insert(db, model.Measurement{}, 0, 2, func(i int, r F.Record) {
+			r["Code"] = fmt.Sprintf("measurement-%04d", i)
+			r["PatientId"] = patients[i-1].Id
+		}).([]*model.Measurement)
+
+		F.Insert(db, model.DiagnosisAlgorithm{}, 0, 2, func(i int, r F.Record) {
+			r["Name"] = fmt.Sprintf("alg-%04d", i)
+			r["Version"] = "1.0.0"
+		})
+
+		// 両計測とも5時間分をアルゴリズム1で診断。
+		diagnoses := F.Insert(db, model.Diagnosis{}, 0, 2, func(i int, r F.Record) {
+			r["MeasurementId"] = measurements[i-1].Id
+			r["RangeFrom"] = beginTime
+			r["RangeUntil"] = beginTime.Add(time.Duration(5)*time.Hour)
+		}).([]*model.Diagnosis)
+
+		for _, d := range diagnoses {
+			if e := db.Insert(&model.ComputedDiagnosis{DiagnosisId: d.Id, AlgorithmId: 1}); e != nil {
+				t.Fatal(e)
+			}
+		}
+
+		// 計測1に4件、計測2に1件。
+		risks := []int{1, 3, 5, 2, 4}
+
+		F.Insert(db, model.ComputedEvent{}, 0, 5, func(i int, r F.Record) {
+			r["MeasurementId"] = measurements[F.If(i == 4, 1, 0).(int)].Id
+			r["Risk"] = risks[i-1]
+			r["RangeFrom"] = beginTime.Add(time.Duration(i-1)*time.Hour)
+			r["RangeUntil"] = beginTime.Add(time.Duration(i-1)*time.Hour + time.Duration(30)*time.Minute)
+		})
+
+		// アノテーション1-3はイベント1-3を参照、5は古いアノテーションでイベント3を参照、4はイベントを参照しない。イベント5にはアノテーションが無い。
+		annotatedRisks := []int{1, 3, 2, 4, 5}
+
+		F.Insert(db, model.AnnotatedEvent{}, 0, 5, func(i int, r F.Record) {
+			r["MeasurementId"] = measurements[0].Id
+			r["Risk"] = annotatedRisks[i-1]
+			switch i {
+			case 1, 2, 3:
+				r["ComputedEventId"] = i
+				r["RangeFrom"] = beginTime.Add(time.Duration(i-1)*time.Hour + time.Duration(10)*time.Minute)
+				r["RangeUntil"] = beginTime.Add(time.Duration(i-1)*time.Hour + time.Duration(30)*time.Minute)
+			case 4:
+				r["ComputedEventId"] = nil
+				r["RangeFrom"] = beginTime.Add(time.Duration(3)*time.Hour)
+				r["RangeUntil"] = beginTime.Add(time.Duration(3)*time.Hour + time.Duration(30)*time.Minute)
+			case 5:
+				r["ComputedEventId"] = 3
+				r["RangeFrom"] = beginTime.Add(time.Duration(2)*time.Hour)
+				r["RangeUntil"] = beginTime.Add(time.Duration(2)*time.Hour + time.Duration(30)*time.Minute)
+			}
+			r["CreatedAt"] = F.If(i == 5, beginTime, beginTime.Add(time.Duration(i)*time.Hour))
+		})
+	})
+}
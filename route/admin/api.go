@@ -89,4 +89,46 @@ func registerAPIs(router *echo.Group) {
 	router.PUT("/hospitals/:hospital_id/doctors/:doctor_id", shared.C(updateDoctor))
 	router.PUT("/hospitals/:hospital_id/doctors/:doctor_id/password", shared.C(updateDoctorPassword))
 	router.DELETE("/hospitals/:hospital_id/doctors/:doctor_id", shared.C(deleteDoctor))
+
+	// 患者。
+	router.DELETE("/hospitals/:hospital_id/patients/:patient_id", shared.C(erasePatient))
+	router.GET("/hospitals/:hospital_id/patient_rule", shared.C(fetchHospitalPatientRule))
+	router.PUT("/hospitals/:hospital_id/patient_rule", shared.C(updateHospitalPatientRule))
+
+	// リスクテーブル。
+	router.GET("/risk_tables", shared.C(listRiskTables))
+	router.POST("/risk_tables", shared.C(createRiskTable))
+	router.GET("/risk_tables/:risk_table_id", shared.C(fetchRiskTable))
+	router.PUT("/risk_tables/:risk_table_id", shared.C(updateRiskTable))
+	router.DELETE("/risk_tables/:risk_table_id", shared.C(deleteRiskTable))
+	router.GET("/hospitals/:hospital_id/risk_table", shared.C(fetchHospitalRiskTable))
+	router.PUT("/hospitals/:hospital_id/risk_table", shared.C(updateHospitalRiskTable))
+
+	// ガイドライン。
+	router.GET("/hospitals/:hospital_id/guideline", shared.C(fetchHospitalGuideline))
+	router.PUT("/hospitals/:hospital_id/guideline", shared.C(updateHospitalGuideline))
+
+	// 計測データの保存期間。
+	router.GET("/hospitals/:hospital_id/retention", shared.C(fetchHospitalRetention))
+	router.PUT("/hospitals/:hospital_id/retention", shared.C(updateHospitalRetention))
+	router.POST("/measurements/:measurement_id/restore", shared.C(restoreMeasurementArchive))
+
+	// 計測の自動完了。
+	router.GET("/hospitals/:hospital_id/auto_close", shared.C(fetchHospitalAutoClose))
+	router.PUT("/hospitals/:hospital_id/auto_close", shared.C(updateHospitalAutoClose))
+
+	// 計測の分割と統合。
+	router.POST("/measurements/:measurement_id/split", shared.C(splitMeasurement))
+	router.POST("/measurements/:measurement_id/merge", shared.C(mergeMeasurement))
+
+	// 一致度。
+	router.GET("/agreements", shared.C(computeAgreement))
+
+	// 転帰分析。
+	router.GET("/outcomes", shared.C(computeOutcomes))
+
+	// 研究用データセット。
+	router.GET("/research_exports", shared.C(listResearchExports))
+	router.POST("/research_exports", shared.C(createResearchExport))
+	router.GET("/research_exports/:research_export_id", shared.C(fetchResearchExport))
 }
@@ -0,0 +1,97 @@
+package admin
+
+import (
+	"net/http"
+
+	v "github.com/go-ozzo/ozzo-validation/v4"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/model"
+	S "github.com/spiker/spiker-server/service"
+	"github.com/spiker/spiker-server/route/shared"
+)
+
+type hospitalAutoCloseResponse struct {
+	Minutes    *int `json:"minutes"`
+	IsDisabled bool `json:"isDisabled"`
+}
+
+func newHospitalAutoCloseResponse(record *model.HospitalAutoClose) *hospitalAutoCloseResponse {
+	if record == nil {
+		return &hospitalAutoCloseResponse{nil, false}
+	} else {
+		return &hospitalAutoCloseResponse{record.Minutes, record.Minutes == nil}
+	}
+}
+
+// fetchHospitalAutoClose godoc
+// @summary 病院の計測を自動完了するまでの無受信期間を取得する。
+// @description 設定されていない場合、minutesはnullとなり、既定の無受信期間(12時間)で自動完了する。
+// @description 自動完了しない設定の場合、isDisabledがtrueとなる。
+// @tags [admin] Measurement
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param hospital_id path int true "病院ID。"
+// @success 200 {object} hospitalAutoCloseResponse "無受信期間(分)。"
+// @failure 404 {object} shared.ErrorResponse "病院が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /admin/hospitals/{hospital_id}/auto_close [get]
+func fetchHospitalAutoClose(c *shared.Context) error {
+	id := c.IntParam("hospital_id")
+
+	service := shared.CreateService(S.HospitalService{}, c).(*S.HospitalService)
+
+	result, err := service.FetchAutoClose(id)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, newHospitalAutoCloseResponse(result))
+}
+
+type hospitalAutoCloseBody struct {
+	Minutes    *int `json:"minutes"`
+	IsDisabled bool `json:"isDisabled"`
+}
+
+// updateHospitalAutoClose godoc
+// @summary 病院の計測を自動完了するまでの無受信期間を設定する。
+// @description 最後にデータを受信してから無受信期間を過ぎた計測は、バッチ処理で完了状態になる。完了した計測のコードに無受信期間を空けてデータが届いた場合は新規の計測となる。
+// @description minutesがnullの場合は設定を削除し、既定の無受信期間(12時間)に戻す。
+// @description isDisabledがtrueの場合はminutesによらず自動完了しない設定とし、完了した計測のコードに届いたデータは完了した計測を延長する。
+// @tags [admin] Measurement
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param hospital_id path int true "病院ID。"
+// @param auto_close body hospitalAutoCloseBody true "無受信期間(分)。"
+// @success 200 {object} hospitalAutoCloseResponse "設定した無受信期間(分)。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
+// @failure 404 {object} shared.ErrorResponse "病院が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /admin/hospitals/{hospital_id}/auto_close [put]
+func updateHospitalAutoClose(c *shared.Context) error {
+	id := c.IntParam("hospital_id")
+
+	body := &hospitalAutoCloseBody{}
+
+	if e := c.Bind(body); e != nil {
+		return e
+	}
+
+	if e := (v.Errors{
+		"minutes": v.Validate(body.Minutes, v.Min(C.AutoCloseMinimumMinutes), v.Max(C.AutoCloseMaximumMinutes)),
+	}).Filter(); e != nil {
+		return e
+	}
+
+	service := shared.CreateService(S.HospitalTxService{}, c).(*S.HospitalTxService)
+
+	result, err := service.UpdateAutoClose(id, body.Minutes, body.IsDisabled)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, newHospitalAutoCloseResponse(result))
+}
@@ -0,0 +1,151 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/test"
+	F "github.com/spiker/spiker-server/test/fixture"
+)
+
+func TestAdminAutoClose_Hospital(t *testing.T) {
+	auth := (&F.AdminFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "未設定",
+			Method:  http.MethodGet,
+			Path:    "/admin/hospitals/1/auto_close",
+			Token:   auth.Token(1),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &hospitalAutoCloseResponse{}).(*hospitalAutoCloseResponse)
+
+				assert.Nil(t, res.Minutes)
+				assert.EqualValues(t, false, res.IsDisabled)
+			},
+		},
+		{
+			Name:    "取得",
+			Method:  http.MethodGet,
+			Path:    "/admin/hospitals/2/auto_close",
+			Token:   auth.Token(1),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &hospitalAutoCloseResponse{}).(*hospitalAutoCloseResponse)
+
+				assert.EqualValues(t, 180, *res.Minutes)
+				assert.EqualValues(t, false, res.IsDisabled)
+			},
+		},
+		{
+			Name:    "自動完了しない設定を取得",
+			Method:  http.MethodGet,
+			Path:    "/admin/hospitals/3/auto_close",
+			Token:   auth.Token(1),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &hospitalAutoCloseResponse{}).(*hospitalAutoCloseResponse)
+
+				assert.Nil(t, res.Minutes)
+				assert.EqualValues(t, true, res.IsDisabled)
+			},
+		},
+		{
+			Name:    "設定",
+			Method:  http.MethodPut,
+			Path:    "/admin/hospitals/1/auto_close",
+			Token:   auth.Token(1),
+			Body:    test.JsonBody(map[string]interface{}{"minutes": 60}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+
+				if m, e := db.Get(model.HospitalAutoClose{}, 1); e != nil {
+					assert.FailNow(t, e.Error())
+				} else {
+					assert.EqualValues(t, 60, *m.(*model.HospitalAutoClose).Minutes)
+				}
+
+				assert.EqualValues(t, 3, F.Count(t, db, "hospital_auto_close", nil))
+			},
+		},
+		{
+			Name:    "自動完了しない",
+			Method:  http.MethodPut,
+			Path:    "/admin/hospitals/1/auto_close",
+			Token:   auth.Token(1),
+			Body:    test.JsonBody(map[string]interface{}{"minutes": 60, "isDisabled": true}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &hospitalAutoCloseResponse{}).(*hospitalAutoCloseResponse)
+
+				assert.Nil(t, res.Minutes)
+				assert.EqualValues(t, true, res.IsDisabled)
+
+				if m, e := db.Get(model.HospitalAutoClose{}, 1); e != nil {
+					assert.FailNow(t, e.Error())
+				} else {
+					assert.Nil(t, m.(*model.HospitalAutoClose).Minutes)
+				}
+			},
+		},
+		{
+			Name:    "設定を削除",
+			Method:  http.MethodPut,
+			Path:    "/admin/hospitals/2/auto_close",
+			Token:   auth.Token(1),
+			Body:    test.JsonBody(map[string]interface{}{"minutes": nil}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.EqualValues(t, 1, F.Count(t, db, "hospital_auto_close", nil))
+
+				if m, e := db.Get(model.HospitalAutoClose{}, 2); e != nil {
+					assert.FailNow(t, e.Error())
+				} else {
+					assert.Nil(t, m)
+				}
+			},
+		},
+		{
+			Name:    "期間が短すぎる",
+			Method:  http.MethodPut,
+			Path:    "/admin/hospitals/1/auto_close",
+			Token:   auth.Token(1),
+			Body:    test.JsonBody(map[string]interface{}{"minutes": 1}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			Name:    "病院が存在しない",
+			Method:  http.MethodPut,
+			Path:    "/admin/hospitals/0/auto_close",
+			Token:   auth.Token(1),
+			Body:    test.JsonBody(map[string]interface{}{"minutes": 60}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		F.Truncate(db, "hospital_auto_close", "hospital")
+
+		F.Insert(db, model.Hospital{}, 0, 3, func(i int, r F.Record) {
+			r["Uuid"] = fmt.Sprintf("hospital-%04d", i)
+		})
+
+		minutes := 180
+		db.Insert(&model.HospitalAutoClose{HospitalId: 2, Minutes: &minutes, ModifiedAt: time.Now()})
+		db.Insert(&model.HospitalAutoClose{HospitalId: 3, Minutes: nil, ModifiedAt: time.Now()})
+	})
+}
@@ -6,7 +6,7 @@ import (
 
 	v "github.com/go-ozzo/ozzo-validation/v4"
 
-	//C "github.com/spiker/spiker-server/constant"
+	C "github.com/spiker/spiker-server/constant"
 	"github.com/spiker/spiker-server/model"
 	S "github.com/spiker/spiker-server/service"
 	//"github.com/spiker/spiker-server/resource/rds"
@@ -15,15 +15,18 @@ import (
 )
 
 type listDoctorsQuery struct {
-	Limit  int `query:"limit"`
-	Offset int `query:"offset"`
+	Limit  int    `query:"limit"`
+	Offset int    `query:"offset"`
+	Cursor string `query:"cursor"`
+	Sort   string `query:"sort"`
 }
 
 type listDoctorsResponse struct {
 	Doctors []*model.Doctor `json:"doctors"`
-	Total   int64           `json:"total"`
-	Limit   int             `json:"limit"`
-	Offset  int             `json:"offset"`
+	Total      int64           `json:"total"`
+	Limit      int             `json:"limit"`
+	Offset     int             `json:"offset"`
+	NextCursor *string         `json:"nextCursor"`
 }
 
 // listDoctors godoc
@@ -34,13 +37,16 @@ type listDoctorsResponse struct {
 // @param hospital_id path int true "病院ID。"
 // @param limit query int false "最大取得件数。"
 // @param offset query int false "取得オフセット。"
+// @param cursor query string false "前の一覧の`nextCursor`。指定した場合`offset`は無視し、`total`は数えず-1となる。"
+// @param sort query string false "ソートする列。`id` `loginId` `name`のいずれかで、降順の場合は先頭に`-`を付ける。既定は`id`。"
 // @success 200 {object} listDoctorsResponse "医者一覧。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
 // @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
 // @router /admin/hospitals/{hospital_id}/doctors [get]
 func listDoctors(c *shared.Context) error {
 	hospitalId := c.IntParam("hospital_id")
 
-	query := &listDoctorsQuery{100, 0}
+	query := &listDoctorsQuery{Limit: 100}
 
 	if e := c.Bind(query); e != nil {
 		return e
@@ -49,13 +55,19 @@ func listDoctors(c *shared.Context) error {
 	if e := (v.Errors{
 		"limit": v.Validate(query.Limit, v.Min(0)),
 		"offset": v.Validate(query.Offset, v.Min(0)),
+		"sort": v.Validate(query.Sort, v.In(shared.SortSpecs(C.DoctorSorts)...)),
 	}).Filter(); e != nil {
 		return e
 	}
 
 	service := shared.CreateService(S.DoctorService{}, c).(*S.DoctorService)
 
-	results, total, err := service.List(hospitalId, query.Limit, query.Offset)
+	results, info, err := service.List(hospitalId, &model.Paging{
+		Limit:  query.Limit,
+		Offset: query.Offset,
+		Cursor: query.Cursor,
+		Sort:   query.Sort,
+	})
 
 	if err != nil {
 		return err
@@ -63,9 +75,10 @@ func listDoctors(c *shared.Context) error {
 
 	return c.JSON(http.StatusOK, &listDoctorsResponse{
 		Doctors: results,
-		Total: total,
+		Total: info.Total,
 		Limit: query.Limit,
 		Offset: query.Offset,
+		NextCursor: info.NextCursor,
 	})
 }
 
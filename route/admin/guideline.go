@@ -0,0 +1,88 @@
+package admin
+
+import (
+	"net/http"
+
+	v "github.com/go-ozzo/ozzo-validation/v4"
+
+	C "github.com/spiker/spiker-server/constant"
+	S "github.com/spiker/spiker-server/service"
+	"github.com/spiker/spiker-server/route/shared"
+)
+
+type hospitalGuidelineResponse struct {
+	Guideline C.Guideline `json:"guideline"`
+}
+
+// fetchHospitalGuideline godoc
+// @summary 病院でアラートに利用するガイドラインを取得する。
+// @description 設定されていない場合、JSOGとなる。
+// @tags [admin] Guideline
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param hospital_id path int true "病院ID。"
+// @success 200 {object} hospitalGuidelineResponse "ガイドライン。"
+// @failure 404 {object} shared.ErrorResponse "病院が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /admin/hospitals/{hospital_id}/guideline [get]
+func fetchHospitalGuideline(c *shared.Context) error {
+	id := c.IntParam("hospital_id")
+
+	service := shared.CreateService(S.HospitalService{}, c).(*S.HospitalService)
+
+	result, err := service.FetchGuideline(id)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &hospitalGuidelineResponse{result})
+}
+
+type hospitalGuidelineBody struct {
+	Guideline C.Guideline `json:"guideline" enums:"JSOG,FIGO2015,NICE,ACOG"`
+}
+
+// updateHospitalGuideline godoc
+// @summary 病院でアラートに利用するガイドラインを設定する。
+// @description JSOGではリスク値が閾値以上、それ以外ではsuspicious(Category II)以上のイベントがアラート対象となる。
+// @tags [admin] Guideline
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param hospital_id path int true "病院ID。"
+// @param guideline body hospitalGuidelineBody true "ガイドライン。"
+// @success 200 {object} hospitalGuidelineResponse "設定したガイドライン。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
+// @failure 404 {object} shared.ErrorResponse "病院が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /admin/hospitals/{hospital_id}/guideline [put]
+func updateHospitalGuideline(c *shared.Context) error {
+	id := c.IntParam("hospital_id")
+
+	body := &hospitalGuidelineBody{}
+
+	if e := c.Bind(body); e != nil {
+		return e
+	}
+
+	guidelines := []interface{}{}
+	for _, g := range C.Guidelines {
+		guidelines = append(guidelines, g)
+	}
+
+	if e := (v.Errors{
+		"guideline": v.Validate(body.Guideline, v.Required, v.In(guidelines...)),
+	}).Filter(); e != nil {
+		return e
+	}
+
+	service := shared.CreateService(S.HospitalTxService{}, c).(*S.HospitalTxService)
+
+	result, err := service.UpdateGuideline(id, body.Guideline)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &hospitalGuidelineResponse{result})
+}
@@ -0,0 +1,97 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/test"
+	F "github.com/spiker/spiker-server/test/fixture"
+)
+
+func TestAdminGuideline_Hospital(t *testing.T) {
+	auth := (&F.AdminFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "未設定",
+			Method:  http.MethodGet,
+			Path:    "/admin/hospitals/1/guideline",
+			Token:   auth.Token(1),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &hospitalGuidelineResponse{}).(*hospitalGuidelineResponse)
+
+				assert.EqualValues(t, C.GuidelineJSOG, res.Guideline)
+			},
+		},
+		{
+			Name:    "取得",
+			Method:  http.MethodGet,
+			Path:    "/admin/hospitals/2/guideline",
+			Token:   auth.Token(1),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &hospitalGuidelineResponse{}).(*hospitalGuidelineResponse)
+
+				assert.EqualValues(t, C.GuidelineNICE, res.Guideline)
+			},
+		},
+		{
+			Name:    "設定",
+			Method:  http.MethodPut,
+			Path:    "/admin/hospitals/2/guideline",
+			Token:   auth.Token(1),
+			Body:    test.JsonBody(map[string]interface{}{"guideline": "FIGO2015"}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+
+				if m, e := db.Get(model.HospitalGuideline{}, 2); e != nil {
+					assert.FailNow(t, e.Error())
+				} else {
+					assert.EqualValues(t, C.GuidelineFIGO, m.(*model.HospitalGuideline).Guideline)
+				}
+
+				assert.EqualValues(t, 1, F.Count(t, db, "hospital_guideline", nil))
+			},
+		},
+		{
+			Name:    "不明なガイドライン",
+			Method:  http.MethodPut,
+			Path:    "/admin/hospitals/1/guideline",
+			Token:   auth.Token(1),
+			Body:    test.JsonBody(map[string]interface{}{"guideline": "UNKNOWN"}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			Name:    "病院が存在しない",
+			Method:  http.MethodPut,
+			Path:    "/admin/hospitals/0/guideline",
+			Token:   auth.Token(1),
+			Body:    test.JsonBody(map[string]interface{}{"guideline": "ACOG"}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		F.Truncate(db, "hospital_guideline", "hospital")
+
+		F.Insert(db, model.Hospital{}, 0, 3, func(i int, r F.Record) {
+			r["Uuid"] = fmt.Sprintf("hospital-%04d", i)
+		})
+
+		db.Insert(&model.HospitalGuideline{HospitalId: 2, Guideline: string(C.GuidelineNICE)})
+	})
+}
@@ -1,6 +1,7 @@
 package admin
 
 import (
+	"encoding/base64"
 	"fmt"
 	//"database/sql"
 	"net/http"
@@ -69,6 +70,42 @@ func TestAdminHospital_List(t *testing.T) {
 				}
 			},
 		},
+		{
+			Name:    "カーソル指定",
+			Method:  http.MethodGet,
+			Path:    "/admin/hospitals",
+			Token:   auth.Token(1),
+			Query:   func(q url.Values) {
+				q.Add("limit", "3")
+				q.Add("sort", "-name")
+				q.Add("cursor", base64.RawURLEncoding.EncodeToString([]byte(`{"sort":"-name","values":["病院-0008","8"]}`)))
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &listHospitalsResponse{}).(*listHospitalsResponse)
+
+				assert.EqualValues(t, 3, len(res.Hospitals))
+				assert.EqualValues(t, -1, res.Total)
+				assert.NotNil(t, res.NextCursor)
+
+				expected := []int{7, 6, 5}
+				for i, m := range res.Hospitals {
+					verify(expected[i], m)
+				}
+			},
+		},
+		{
+			Name:    "不正なカーソル",
+			Method:  http.MethodGet,
+			Path:    "/admin/hospitals",
+			Token:   auth.Token(1),
+			Query:   func(q url.Values) {
+				q.Add("cursor", "invalid")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
 	}
 
 	httpTests.Run(testHandler(), t, func() {
@@ -0,0 +1,112 @@
+package admin
+
+import (
+	"net/http"
+	"time"
+
+	v "github.com/go-ozzo/ozzo-validation/v4"
+
+	S "github.com/spiker/spiker-server/service"
+	"github.com/spiker/spiker-server/route/shared"
+)
+
+type splitMeasurementBody struct {
+	At time.Time `json:"at"`
+}
+
+// splitMeasurement godoc
+// @summary 計測を日時で分割する。
+// @description 指定した日時以降の計測データ、診断及びイベントを新規の計測に移す。元の計測は完了状態となる。
+// @tags [admin] Measurement
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param measurement_id path int true "計測ID。"
+// @param split body splitMeasurementBody true "分割する日時。RFC3339形式。"
+// @success 201 {object} model.Measurement "分割して作成した計測。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー、もしくは日時の前後に計測データが無い。"
+// @failure 404 {object} shared.ErrorResponse "計測が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /admin/measurements/{measurement_id}/split [post]
+func splitMeasurement(c *shared.Context) error {
+	id := c.IntParam("measurement_id")
+
+	body := &splitMeasurementBody{}
+
+	if e := c.Bind(body); e != nil {
+		return e
+	}
+
+	if e := (v.Errors{
+		"at": v.Validate(body.At, v.Required),
+	}).Filter(); e != nil {
+		return e
+	}
+
+	service := shared.CreateService(S.MeasurementTxService{}, c).(*S.MeasurementTxService)
+
+	result, err := service.Split(id, body.At)
+
+	if err != nil {
+		return err
+	}
+
+	// 計測データはトランザクション外のため、コミットした後に付け替える。
+	c.AfterCommit(retagMeasurementData)
+
+	return c.JSON(http.StatusCreated, result)
+}
+
+type mergeMeasurementBody struct {
+	MeasurementId int `json:"measurementId"`
+}
+
+// mergeMeasurement godoc
+// @summary 計測を統合する。
+// @description 指定した計測の計測データ、診断及びイベントをパスの計測に移し、指定した計測を削除する。同じ病院の計測のみ統合できる。
+// @tags [admin] Measurement
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param measurement_id path int true "統合先の計測ID。"
+// @param measurement body mergeMeasurementBody true "統合元の計測。"
+// @success 200 {object} model.Measurement "統合した計測。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
+// @failure 404 {object} shared.ErrorResponse "計測が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /admin/measurements/{measurement_id}/merge [post]
+func mergeMeasurement(c *shared.Context) error {
+	id := c.IntParam("measurement_id")
+
+	body := &mergeMeasurementBody{}
+
+	if e := c.Bind(body); e != nil {
+		return e
+	}
+
+	if e := (v.Errors{
+		"measurementId": v.Validate(body.MeasurementId, v.Required),
+	}).Filter(); e != nil {
+		return e
+	}
+
+	service := shared.CreateService(S.MeasurementTxService{}, c).(*S.MeasurementTxService)
+
+	result, err := service.Merge(id, body.MeasurementId)
+
+	if err != nil {
+		return err
+	}
+
+	// 計測データはトランザクション外のため、コミットした後に付け替える。
+	c.AfterCommit(retagMeasurementData)
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// 分割、統合で付け替え待ちとなった計測データを付け替える。
+func retagMeasurementData(c *shared.Context) error {
+	service := shared.CreateService(S.MeasurementTxService{}, c).(*S.MeasurementTxService)
+
+	_, err := service.RetagPending()
+
+	return err
+}
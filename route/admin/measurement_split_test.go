@@ -0,0 +1,79 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/test"
+	F "github.com/spiker/spiker-server/test/fixture"
+)
+
+func TestAdminMeasurement_Merge(t *testing.T) {
+	auth := (&F.AdminFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "統合",
+			Method:  http.MethodPost,
+			Path:    "/admin/measurements/1/merge",
+			Token:   auth.Token(1),
+			Body:    test.JsonBody(map[string]interface{}{"measurementId": 2}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &model.Measurement{}).(*model.Measurement)
+
+				assert.EqualValues(t, 1, res.Id)
+				assert.EqualValues(t, false, res.IsClosed)
+				assert.EqualValues(t, 2, F.Count(t, db, "measurement", nil))
+			},
+		},
+		{
+			Name:    "病院が違う",
+			Method:  http.MethodPost,
+			Path:    "/admin/measurements/1/merge",
+			Token:   auth.Token(1),
+			Body:    test.JsonBody(map[string]interface{}{"measurementId": 3}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+				assert.EqualValues(t, 3, F.Count(t, db, "measurement", nil))
+			},
+		},
+		{
+			Name:    "計測が存在しない",
+			Method:  http.MethodPost,
+			Path:    "/admin/measurements/1/merge",
+			Token:   auth.Token(1),
+			Body:    test.JsonBody(map[string]interface{}{"measurementId": 9}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		F.Truncate(db, "terminal_admission", "measurement_archive", "measurement_terminal", "patient", "measurement")
+
+		F.Insert(db, model.Patient{}, 0, 2, func(i int, r F.Record) {
+			r["HospitalId"] = i
+		})
+
+		F.Insert(db, model.MeasurementTerminal{}, 0, 2, func(i int, r F.Record) {
+			r["HospitalId"] = i
+		})
+
+		F.Insert(db, model.Measurement{}, 0, 3, func(i int, r F.Record) {
+			r["Code"] = fmt.Sprintf("m%04d", i)
+			r["PatientId"] = F.If(i <= 2, 1, 2)
+			r["TerminalId"] = F.If(i <= 2, 1, 2)
+			r["IsClosed"] = i != 2
+		})
+	})
+}
@@ -0,0 +1,87 @@
+package admin
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+
+	v "github.com/go-ozzo/ozzo-validation/v4"
+
+	C "github.com/spiker/spiker-server/constant"
+	S "github.com/spiker/spiker-server/service"
+	"github.com/spiker/spiker-server/route/shared"
+)
+
+type computeOutcomesQuery struct {
+	Hospital *int       `query:"hospital"`
+	From     *time.Time `query:"from"`
+	Until    *time.Time `query:"until"`
+	Minutes  *int       `query:"minutes"`
+	Format   string     `query:"format"`
+}
+
+// computeOutcomes godoc
+// @summary 自動診断のリスクと分娩転帰の関係を集計する。
+// @description 出生日時が期間内にある患者について、アルゴリズムと最大リスク毎に転帰の分布を集計する。format=csvの場合は患者毎の集計をCSVで返す。
+// @tags [admin] Outcome
+// @produce json,text/csv
+// @param Authorization header string true "Bearerトークン。"
+// @param hospital query int false "病院ID。"
+// @param from query string false "出生日時の期間の先頭。RFC3339形式。"
+// @param until query string false "出生日時の期間の末尾。RFC3339形式。"
+// @param minutes query int false "出生前にリスク値毎の時間を集計する分数。既定値は30。"
+// @param format query string false "出力形式。json、csvのいずれか。既定値はjson。"
+// @success 200 {object} service.OutcomeReport "転帰分析。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /admin/outcomes [get]
+func computeOutcomes(c *shared.Context) error {
+	query := &computeOutcomesQuery{}
+
+	if e := c.Bind(query); e != nil {
+		return e
+	}
+
+	if e := (v.Errors{
+		"minutes": v.Validate(query.Minutes, v.NilOrNotEmpty, v.Min(1), v.Max(C.OutcomeMaximumMinutes)),
+		"format": v.Validate(query.Format, v.In("json", "csv")),
+	}).Filter(); e != nil {
+		return e
+	}
+
+	if query.From != nil && query.Until != nil && !query.From.Before(*query.Until) {
+		return C.NewBadRequestError(
+			"invalid_range",
+			"from must be before until",
+			map[string]interface{}{},
+		)
+	}
+
+	minutes := C.OutcomeDefaultMinutes
+
+	if query.Minutes != nil {
+		minutes = *query.Minutes
+	}
+
+	service := shared.CreateService(S.OutcomeService{}, c).(*S.OutcomeService)
+
+	result, err := service.Compute(query.Hospital, query.From, query.Until, minutes)
+
+	if err != nil {
+		return err
+	}
+
+	if query.Format == "csv" {
+		buf := &bytes.Buffer{}
+
+		if e := result.WriteCSV(buf); e != nil {
+			return e
+		}
+
+		c.Response().Header().Set("Content-Disposition", `attachment; filename="outcomes.csv"`)
+
+		return c.Blob(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
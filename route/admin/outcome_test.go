@@ -0,0 +1,161 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/model"
+	S "github.com/spiker/spiker-server/service"
+	"github.com/spiker/spiker-server/test"
+	F "github.com/spiker/spiker-server/test/fixture"
+)
+
+func TestAdminOutcome_Compute(t *testing.T) {
+	auth := (&F.AdminFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	birthTime := time.Date(2021, time.October, 1, 12, 0, 0, 0, time.UTC)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "全病院",
+			Method:  http.MethodGet,
+			Path:    "/admin/outcomes",
+			Token:   auth.Token(1),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &S.OutcomeReport{}).(*S.OutcomeReport)
+
+				assert.EqualValues(t, 30, res.Minutes)
+				assert.EqualValues(t, 3, res.Patients)
+				assert.EqualValues(t, 1, res.Unassessed)
+				assert.Len(t, res.Groups, 2)
+
+				assert.EqualValues(t, 2, res.Groups[0].MaximumRisk)
+				assert.EqualValues(t, 1, res.Groups[0].Patients)
+				assert.EqualValues(t, 20 * 60, res.Groups[0].MeanRiskSeconds[2])
+
+				assert.EqualValues(t, 5, res.Groups[1].MaximumRisk)
+				assert.EqualValues(t, 1, res.Groups[1].Patients)
+				assert.EqualValues(t, 3, *res.Groups[1].ApgarScore1Min.Mean)
+			},
+		},
+		{
+			Name:    "病院と集計分数を指定",
+			Method:  http.MethodGet,
+			Path:    "/admin/outcomes",
+			Token:   auth.Token(1),
+			Query:   func(q url.Values) {
+				q.Add("hospital", "1")
+				q.Add("minutes", "10")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &S.OutcomeReport{}).(*S.OutcomeReport)
+
+				assert.EqualValues(t, 10, res.Minutes)
+				assert.EqualValues(t, 1, res.Patients)
+				assert.Len(t, res.Groups, 1)
+				assert.EqualValues(t, 10 * 60, res.Groups[0].MeanRiskSeconds[2])
+			},
+		},
+		{
+			Name:    "CSV",
+			Method:  http.MethodGet,
+			Path:    "/admin/outcomes",
+			Token:   auth.Token(1),
+			Query:   func(q url.Values) {
+				q.Add("format", "csv")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.True(t, strings.HasPrefix(rec.Header().Get("Content-Type"), "text/csv"))
+
+				lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+
+				assert.Len(t, lines, 3)
+				assert.True(t, strings.HasPrefix(lines[0], "hospital_id,patient_id,"))
+			},
+		},
+		{
+			Name:    "集計分数が不正",
+			Method:  http.MethodGet,
+			Path:    "/admin/outcomes",
+			Token:   auth.Token(1),
+			Query:   func(q url.Values) {
+				q.Add("minutes", "0")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			Name:    "形式が不正",
+			Method:  http.MethodGet,
+			Path:    "/admin/outcomes",
+			Token:   auth.Token(1),
+			Query:   func(q url.Values) {
+				q.Add("format", "xml")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		F.Truncate(db, "diagnosis_content", "computed_diagnosis", "diagnosis", "diagnosis_algorithm", "measurement", "patient", "hospital")
+
+		hospitals := F.Insert(db, model.Hospital{}, 0, 2, func(i int, r F.Record) {
+			r["Uuid"] = fmt.Sprintf("hospital-%04d", i)
+		}).([]*model.Hospital)
+
+		// 患者3は診断を持たない。
+		apgars := []int{8, 3, 9}
+
+		patients := F.Insert(db, model.Patient{}, 0, 3, func(i int, r F.Record) {
+			r["HospitalId"] = hospitals[F.If(i == 1, 0, 1).(int)].Id
+			r["BirthDatetime"] = birthTime
+			r["ApgarScore1Min"] = apgars[i-1]
+		}).([]*model.Patient)
+
+		measurements := F.Insert(db, model.Measurement{}, 0, 2, func(i int, r F.Record) {
+			r["Code"] = fmt.Sprintf("measurement-%04d", i)
+			r["PatientId"] = patients[i-1].Id
+		}).([]*model.Measurement)
+
+		F.Insert(db, model.DiagnosisAlgorithm{}, 0, 1, func(i int, r F.Record) {
+			r["Name"] = "alg-0001"
+			r["Version"] = "1.0.0"
+		})
+
+		diagnoses := F.Insert(db, model.Diagnosis{}, 0, 2, func(i int, r F.Record) {
+			r["MeasurementId"] = measurements[i-1].Id
+			r["RangeFrom"] = birthTime.Add(-time.Duration(1)*time.Hour)
+			r["RangeUntil"] = birthTime
+		}).([]*model.Diagnosis)
+
+		for _, d := range diagnoses {
+			if e := db.Insert(&model.ComputedDiagnosis{DiagnosisId: d.Id, AlgorithmId: 1}); e != nil {
+				t.Fatal(e)
+			}
+		}
+
+		// 診断1は出生前20分間がリスク2、診断2は出生前10分間がリスク5。
+		F.Insert(db, model.DiagnosisContent{}, 0, 2, func(i int, r F.Record) {
+			r["DiagnosisId"] = diagnoses[i-1].Id
+			r["Risk"] = F.If(i == 1, 2, 5)
+			r["RangeFrom"] = birthTime.Add(-time.Duration(F.If(i == 1, 20, 10).(int))*time.Minute)
+			r["RangeUntil"] = birthTime
+		})
+	})
+}
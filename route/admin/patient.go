@@ -0,0 +1,39 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/spiker/spiker-server/model"
+	S "github.com/spiker/spiker-server/service"
+	"github.com/spiker/spiker-server/route/shared"
+)
+
+// erasePatient godoc
+// @summary 患者を消去する。
+// @description 患者の鍵を削除して氏名を復号できなくした上で、患者と計測のデータを全て削除する。消去したことのみが記録される。
+// @description InfluxDBの計測データとストレージの画像、アーカイブは削除待ちとして登録され、バッチ処理で削除される。
+// @tags [admin] Patient
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param hospital_id path int true "病院ID。"
+// @param patient_id path int true "患者ID。"
+// @success 200 {object} model.PatientErasure "消去の記録。"
+// @failure 404 {object} shared.ErrorResponse "患者が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /admin/hospitals/{hospital_id}/patients/{patient_id} [delete]
+func erasePatient(c *shared.Context) error {
+	me := c.Get(shared.ContextMeKey).(*model.Administrator)
+
+	hospitalId := c.IntParam("hospital_id")
+	patientId := c.IntParam("patient_id")
+
+	service := shared.CreateService(S.PatientErasureTxService{}, c).(*S.PatientErasureTxService)
+
+	result, err := service.Erase(me.Id, hospitalId, patientId)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
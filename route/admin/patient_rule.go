@@ -0,0 +1,82 @@
+package admin
+
+import (
+	"net/http"
+
+	v "github.com/go-ozzo/ozzo-validation/v4"
+
+	S "github.com/spiker/spiker-server/service"
+	"github.com/spiker/spiker-server/route/shared"
+)
+
+type hospitalPatientRuleResponse struct {
+	Pattern *string `json:"pattern"`
+}
+
+// fetchHospitalPatientRule godoc
+// @summary 病院の患者の識別規則を取得する。
+// @description 設定されていない場合はnullとなり、新規の計測は常に新規の患者として登録される。
+// @tags [admin] Patient
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param hospital_id path int true "病院ID。"
+// @success 200 {object} hospitalPatientRuleResponse "識別規則。"
+// @failure 404 {object} shared.ErrorResponse "病院が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /admin/hospitals/{hospital_id}/patient_rule [get]
+func fetchHospitalPatientRule(c *shared.Context) error {
+	id := c.IntParam("hospital_id")
+
+	service := shared.CreateService(S.HospitalService{}, c).(*S.HospitalService)
+
+	result, err := service.FetchPatientRule(id)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &hospitalPatientRuleResponse{result})
+}
+
+type hospitalPatientRuleBody struct {
+	Pattern *string `json:"pattern" maxLength:"256"`
+}
+
+// updateHospitalPatientRule godoc
+// @summary 病院の患者の識別規則を設定する。
+// @description 新規の計測コードが正規表現に一致した場合、最初のサブマッチ(無ければ一致した全体)を識別子とし、識別子を持つ既存の患者の計測とする。nullの場合は設定を削除する。
+// @tags [admin] Patient
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param hospital_id path int true "病院ID。"
+// @param rule body hospitalPatientRuleBody true "識別規則。"
+// @success 200 {object} hospitalPatientRuleResponse "設定した識別規則。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
+// @failure 404 {object} shared.ErrorResponse "病院が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /admin/hospitals/{hospital_id}/patient_rule [put]
+func updateHospitalPatientRule(c *shared.Context) error {
+	id := c.IntParam("hospital_id")
+
+	body := &hospitalPatientRuleBody{}
+
+	if e := c.Bind(body); e != nil {
+		return e
+	}
+
+	if e := (v.Errors{
+		"pattern": v.Validate(body.Pattern, v.NilOrNotEmpty, v.RuneLength(1, 256)),
+	}).Filter(); e != nil {
+		return e
+	}
+
+	service := shared.CreateService(S.HospitalTxService{}, c).(*S.HospitalTxService)
+
+	result, err := service.UpdatePatientRule(id, body.Pattern)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &hospitalPatientRuleResponse{result})
+}
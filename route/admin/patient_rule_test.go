@@ -0,0 +1,118 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/test"
+	F "github.com/spiker/spiker-server/test/fixture"
+)
+
+func TestAdminPatientRule_Hospital(t *testing.T) {
+	auth := (&F.AdminFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "未設定",
+			Method:  http.MethodGet,
+			Path:    "/admin/hospitals/1/patient_rule",
+			Token:   auth.Token(1),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &hospitalPatientRuleResponse{}).(*hospitalPatientRuleResponse)
+
+				assert.Nil(t, res.Pattern)
+			},
+		},
+		{
+			Name:    "取得",
+			Method:  http.MethodGet,
+			Path:    "/admin/hospitals/2/patient_rule",
+			Token:   auth.Token(1),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &hospitalPatientRuleResponse{}).(*hospitalPatientRuleResponse)
+
+				assert.EqualValues(t, `^(\d+)-`, *res.Pattern)
+			},
+		},
+		{
+			Name:    "設定",
+			Method:  http.MethodPut,
+			Path:    "/admin/hospitals/1/patient_rule",
+			Token:   auth.Token(1),
+			Body:    test.JsonBody(map[string]interface{}{"pattern": `^P(\d{8})`}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+
+				if m, e := db.Get(model.HospitalPatientRule{}, 1); e != nil {
+					assert.FailNow(t, e.Error())
+				} else {
+					assert.EqualValues(t, `^P(\d{8})`, m.(*model.HospitalPatientRule).Pattern)
+				}
+
+				assert.EqualValues(t, 2, F.Count(t, db, "hospital_patient_rule", nil))
+			},
+		},
+		{
+			Name:    "設定を削除",
+			Method:  http.MethodPut,
+			Path:    "/admin/hospitals/2/patient_rule",
+			Token:   auth.Token(1),
+			Body:    test.JsonBody(map[string]interface{}{"pattern": nil}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.EqualValues(t, 0, F.Count(t, db, "hospital_patient_rule", nil))
+			},
+		},
+		{
+			Name:    "正規表現が不正",
+			Method:  http.MethodPut,
+			Path:    "/admin/hospitals/1/patient_rule",
+			Token:   auth.Token(1),
+			Body:    test.JsonBody(map[string]interface{}{"pattern": `^(\d+`}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			Name:    "空文字列",
+			Method:  http.MethodPut,
+			Path:    "/admin/hospitals/1/patient_rule",
+			Token:   auth.Token(1),
+			Body:    test.JsonBody(map[string]interface{}{"pattern": ""}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			Name:    "病院が存在しない",
+			Method:  http.MethodPut,
+			Path:    "/admin/hospitals/0/patient_rule",
+			Token:   auth.Token(1),
+			Body:    test.JsonBody(map[string]interface{}{"pattern": ".+"}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		F.Truncate(db, "hospital_patient_rule", "hospital")
+
+		F.Insert(db, model.Hospital{}, 0, 3, func(i int, r F.Record) {
+			r["Uuid"] = fmt.Sprintf("hospital-%04d", i)
+		})
+
+		db.Insert(&model.HospitalPatientRule{HospitalId: 2, Pattern: `^(\d+)-`, ModifiedAt: time.Now()})
+	})
+}
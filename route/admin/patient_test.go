@@ -0,0 +1,90 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/test"
+	F "github.com/spiker/spiker-server/test/fixture"
+)
+
+func TestAdminPatient_Erase(t *testing.T) {
+	auth := (&F.AdminFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "消去",
+			Method:  http.MethodDelete,
+			Path:    "/admin/hospitals/1/patients/1",
+			Token:   auth.Token(2),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &model.PatientErasure{}).(*model.PatientErasure)
+
+				assert.EqualValues(t, 1, res.HospitalId)
+				assert.EqualValues(t, 1, res.PatientId)
+				assert.EqualValues(t, 2, res.AdministratorId)
+				assert.EqualValues(t, 2, res.Measurements)
+
+				assert.EqualValues(t, 2, F.Count(t, db, "patient", nil))
+				assert.EqualValues(t, 1, F.Count(t, db, "patient_key", nil))
+				assert.EqualValues(t, 1, F.Count(t, db, "measurement", nil))
+				assert.EqualValues(t, 1, F.Count(t, db, "patient_erasure", nil))
+				assert.EqualValues(t, 2, F.Count(t, db, "measurement_purge", nil))
+			},
+		},
+		{
+			Name:    "病院が異なる",
+			Method:  http.MethodDelete,
+			Path:    "/admin/hospitals/2/patients/1",
+			Token:   auth.Token(1),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+
+				assert.EqualValues(t, 3, F.Count(t, db, "patient", nil))
+				assert.EqualValues(t, 0, F.Count(t, db, "patient_erasure", nil))
+				assert.EqualValues(t, 0, F.Count(t, db, "measurement_purge", nil))
+			},
+		},
+		{
+			Name:    "存在しない",
+			Method:  http.MethodDelete,
+			Path:    "/admin/hospitals/1/patients/0",
+			Token:   auth.Token(1),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		F.Truncate(db, "hospital", "patient", "patient_key", "measurement", "patient_erasure", "measurement_purge")
+
+		F.Insert(db, model.Hospital{}, 0, 2, func(i int, r F.Record) {
+			r["Uuid"] = fmt.Sprintf("hospital-%04d", i)
+		})
+
+		F.Insert(db, model.Patient{}, 0, 3, func(i int, r F.Record) {
+			r["HospitalId"] = F.If(i <= 2, 1, 2)
+		})
+
+		F.Insert(db, model.PatientKey{}, 0, 2, func(i int, r F.Record) {
+			r["PatientId"] = i
+			r["WrappedKey"] = "key"
+		})
+
+		F.Insert(db, model.Measurement{}, 0, 3, func(i int, r F.Record) {
+			r["Code"] = fmt.Sprintf("m%04d", i)
+			r["PatientId"] = F.If(i <= 2, 1, 2)
+			r["TerminalId"] = 1
+		})
+	})
+}
@@ -0,0 +1,142 @@
+package admin
+
+import (
+	"net/http"
+	"time"
+
+	v "github.com/go-ozzo/ozzo-validation/v4"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/model"
+	S "github.com/spiker/spiker-server/service"
+	"github.com/spiker/spiker-server/route/shared"
+)
+
+type listResearchExportsQuery struct {
+	Limit  int `query:"limit"`
+	Offset int `query:"offset"`
+}
+
+type listResearchExportsResponse struct {
+	ResearchExports []*model.ResearchExportEntity `json:"researchExports"`
+	Total           int64                         `json:"total"`
+	Limit           int                           `json:"limit"`
+	Offset          int                           `json:"offset"`
+}
+
+// listResearchExports godoc
+// @summary 研究用データセットの出力ジョブ一覧を新しい順に取得する。
+// @tags [admin] ResearchExport
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param limit query int false "最大取得件数。"
+// @param offset query int false "取得オフセット。"
+// @success 200 {object} listResearchExportsResponse "出力ジョブ一覧。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /admin/research_exports [get]
+func listResearchExports(c *shared.Context) error {
+	query := &listResearchExportsQuery{100, 0}
+
+	if e := c.Bind(query); e != nil {
+		return e
+	}
+
+	if e := (v.Errors{
+		"limit": v.Validate(query.Limit, v.Min(0)),
+		"offset": v.Validate(query.Offset, v.Min(0)),
+	}).Filter(); e != nil {
+		return e
+	}
+
+	service := shared.CreateService(S.ResearchExportService{}, c).(*S.ResearchExportService)
+
+	results, total, err := service.List(query.Limit, query.Offset)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &listResearchExportsResponse{
+		ResearchExports: results,
+		Total: total,
+		Limit: query.Limit,
+		Offset: query.Offset,
+	})
+}
+
+// fetchResearchExport godoc
+// @summary 研究用データセットの出力ジョブを取得する。
+// @description 完了したジョブはデータセットのzipファイルをダウンロードする署名付きURLを持つ。
+// @tags [admin] ResearchExport
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param research_export_id path int true "出力ジョブID。"
+// @success 200 {object} model.ResearchExportEntity "出力ジョブ。"
+// @failure 404 {object} shared.ErrorResponse "出力ジョブが存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /admin/research_exports/{research_export_id} [get]
+func fetchResearchExport(c *shared.Context) error {
+	id := c.IntParam("research_export_id")
+
+	service := shared.CreateService(S.ResearchExportService{}, c).(*S.ResearchExportService)
+
+	result, err := service.Fetch(id)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+type createResearchExportBody struct {
+	Hospitals []int      `json:"hospitals"`
+	From      *time.Time `json:"from"`
+	Until     *time.Time `json:"until"`
+}
+
+// createResearchExport godoc
+// @summary 研究用データセットの出力ジョブを登録する。
+// @description 指定した病院の計測のうち、データの期間が指定期間と重なるものを出力する。ジョブはバッチ処理で実行される。
+// @tags [admin] ResearchExport
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param export body createResearchExportBody true "出力条件。"
+// @success 201 {object} model.ResearchExport "登録した出力ジョブ。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
+// @failure 404 {object} shared.ErrorResponse "病院が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /admin/research_exports [post]
+func createResearchExport(c *shared.Context) error {
+	me := c.Get(shared.ContextMeKey).(*model.Administrator)
+
+	body := &createResearchExportBody{}
+
+	if e := c.Bind(body); e != nil {
+		return e
+	}
+
+	if e := (v.Errors{
+		"hospitals": v.Validate(body.Hospitals, v.Required, v.Length(1, C.ResearchExportMaximumHospitals)),
+	}).Filter(); e != nil {
+		return e
+	}
+
+	if body.From != nil && body.Until != nil && !body.From.Before(*body.Until) {
+		return C.NewBadRequestError(
+			"invalid_range",
+			"from must be before until",
+			map[string]interface{}{},
+		)
+	}
+
+	service := shared.CreateService(S.ResearchExportTxService{}, c).(*S.ResearchExportTxService)
+
+	result, err := service.Create(me.Id, body.Hospitals, body.From, body.Until)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, result)
+}
@@ -0,0 +1,156 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/gorp.v2"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/test"
+	F "github.com/spiker/spiker-server/test/fixture"
+)
+
+func prepareResearchExports(db *gorp.DbMap) {
+	F.Truncate(db, "research_export", "hospital")
+
+	F.Insert(db, model.Hospital{}, 0, 3, func(i int, r F.Record) {
+		r["Uuid"] = fmt.Sprintf("hospital-%04d", i)
+	})
+
+	// 1: 完了、2: 未実行
+	F.Insert(db, model.ResearchExport{}, 0, 2, func(i int, r F.Record) {
+		r["AdministratorId"] = 1
+		r["HospitalIds"] = model.JSON("[1,2]")
+		r["State"] = F.If(i == 1, string(C.ResearchExportCompleted), string(C.ResearchExportPending))
+		r["StorageKey"] = F.If(i == 1, fmt.Sprintf("research/%d.zip", i), nil)
+	})
+}
+
+func TestAdminResearchExport_Create(t *testing.T) {
+	auth := (&F.AdminFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	from := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "登録",
+			Method:  http.MethodPost,
+			Path:    "/admin/research_exports",
+			Token:   auth.Token(2),
+			Body:    test.JsonBody(map[string]interface{}{
+				"hospitals": []int{3, 1, 3},
+				"from": from,
+			}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusCreated, rec.Code)
+				res := F.FromJsonResponse(t, rec, &model.ResearchExport{}).(*model.ResearchExport)
+
+				assert.EqualValues(t, 3, res.Id)
+				assert.EqualValues(t, 2, res.AdministratorId)
+				// 重複は除かれる。
+				assert.JSONEq(t, "[3,1]", string(res.HospitalIds))
+				assert.True(t, from.Equal(*res.RangeFrom))
+				assert.Nil(t, res.RangeUntil)
+				assert.EqualValues(t, C.ResearchExportPending, res.State)
+			},
+		},
+		{
+			Name:    "病院が無い",
+			Method:  http.MethodPost,
+			Path:    "/admin/research_exports",
+			Token:   auth.Token(1),
+			Body:    test.JsonBody(map[string]interface{}{"hospitals": []int{}}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			Name:    "期間が逆",
+			Method:  http.MethodPost,
+			Path:    "/admin/research_exports",
+			Token:   auth.Token(1),
+			Body:    test.JsonBody(map[string]interface{}{
+				"hospitals": []int{1},
+				"from": from,
+				"until": from.Add(-time.Hour),
+			}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			Name:    "存在しない病院",
+			Method:  http.MethodPost,
+			Path:    "/admin/research_exports",
+			Token:   auth.Token(1),
+			Body:    test.JsonBody(map[string]interface{}{"hospitals": []int{1, 0}}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+
+				assert.EqualValues(t, 2, F.Count(t, db, "research_export", nil))
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		prepareResearchExports(db)
+	})
+}
+
+func TestAdminResearchExport_Fetch(t *testing.T) {
+	auth := (&F.AdminFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "一覧",
+			Method:  http.MethodGet,
+			Path:    "/admin/research_exports",
+			Token:   auth.Token(1),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &listResearchExportsResponse{}).(*listResearchExportsResponse)
+
+				assert.EqualValues(t, 2, res.Total)
+				assert.EqualValues(t, 2, res.ResearchExports[0].Id)
+				assert.EqualValues(t, 1, res.ResearchExports[1].Id)
+			},
+		},
+		{
+			Name:    "取得",
+			Method:  http.MethodGet,
+			Path:    "/admin/research_exports/2",
+			Token:   auth.Token(1),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &model.ResearchExportEntity{}).(*model.ResearchExportEntity)
+
+				assert.EqualValues(t, C.ResearchExportPending, res.State)
+				assert.Nil(t, res.DownloadUrl)
+			},
+		},
+		{
+			Name:    "存在しない",
+			Method:  http.MethodGet,
+			Path:    "/admin/research_exports/0",
+			Token:   auth.Token(1),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		prepareResearchExports(db)
+	})
+}
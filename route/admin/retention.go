@@ -0,0 +1,111 @@
+package admin
+
+import (
+	"net/http"
+	"time"
+
+	v "github.com/go-ozzo/ozzo-validation/v4"
+
+	C "github.com/spiker/spiker-server/constant"
+	S "github.com/spiker/spiker-server/service"
+	"github.com/spiker/spiker-server/route/shared"
+)
+
+type hospitalRetentionResponse struct {
+	Years *int `json:"years"`
+}
+
+// fetchHospitalRetention godoc
+// @summary 病院の計測データの保存期間を取得する。
+// @description 設定されていない場合はnullとなり、計測データを削除しない。
+// @tags [admin] Retention
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param hospital_id path int true "病院ID。"
+// @success 200 {object} hospitalRetentionResponse "保存期間(年)。"
+// @failure 404 {object} shared.ErrorResponse "病院が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /admin/hospitals/{hospital_id}/retention [get]
+func fetchHospitalRetention(c *shared.Context) error {
+	id := c.IntParam("hospital_id")
+
+	service := shared.CreateService(S.HospitalService{}, c).(*S.HospitalService)
+
+	result, err := service.FetchRetention(id)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &hospitalRetentionResponse{result})
+}
+
+type hospitalRetentionBody struct {
+	Years *int `json:"years"`
+}
+
+// updateHospitalRetention godoc
+// @summary 病院の計測データの保存期間を設定する。
+// @description 完了から保存期間を過ぎた計測の計測データは、バッチ処理でストレージにアーカイブした上でInfluxDBから削除される。nullの場合は設定を削除する。
+// @description イベントはアーカイブ後もデータベースに残る。
+// @tags [admin] Retention
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param hospital_id path int true "病院ID。"
+// @param retention body hospitalRetentionBody true "保存期間(年)。"
+// @success 200 {object} hospitalRetentionResponse "設定した保存期間(年)。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
+// @failure 404 {object} shared.ErrorResponse "病院が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /admin/hospitals/{hospital_id}/retention [put]
+func updateHospitalRetention(c *shared.Context) error {
+	id := c.IntParam("hospital_id")
+
+	body := &hospitalRetentionBody{}
+
+	if e := c.Bind(body); e != nil {
+		return e
+	}
+
+	if e := (v.Errors{
+		"years": v.Validate(body.Years, v.Min(1), v.Max(C.RetentionMaximumYears)),
+	}).Filter(); e != nil {
+		return e
+	}
+
+	service := shared.CreateService(S.HospitalTxService{}, c).(*S.HospitalTxService)
+
+	result, err := service.UpdateRetention(id, body.Years)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &hospitalRetentionResponse{result})
+}
+
+// restoreMeasurementArchive godoc
+// @summary アーカイブした計測の計測データを復元する。
+// @description 復元した計測データは、一定期間の後に再びアーカイブされる。
+// @tags [admin] Retention
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param measurement_id path int true "計測ID。"
+// @success 200 {object} model.MeasurementArchive "復元したアーカイブ。"
+// @failure 400 {object} shared.ErrorResponse "既に復元されている。"
+// @failure 404 {object} shared.ErrorResponse "計測がアーカイブされていない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /admin/measurements/{measurement_id}/restore [post]
+func restoreMeasurementArchive(c *shared.Context) error {
+	id := c.IntParam("measurement_id")
+
+	service := shared.CreateService(S.MeasurementArchiveTxService{}, c).(*S.MeasurementArchiveTxService)
+
+	result, err := service.Restore(id, time.Now())
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,149 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/test"
+	F "github.com/spiker/spiker-server/test/fixture"
+)
+
+func TestAdminRetention_Hospital(t *testing.T) {
+	auth := (&F.AdminFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "未設定",
+			Method:  http.MethodGet,
+			Path:    "/admin/hospitals/1/retention",
+			Token:   auth.Token(1),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &hospitalRetentionResponse{}).(*hospitalRetentionResponse)
+
+				assert.Nil(t, res.Years)
+			},
+		},
+		{
+			Name:    "取得",
+			Method:  http.MethodGet,
+			Path:    "/admin/hospitals/2/retention",
+			Token:   auth.Token(1),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &hospitalRetentionResponse{}).(*hospitalRetentionResponse)
+
+				assert.EqualValues(t, 5, *res.Years)
+			},
+		},
+		{
+			Name:    "設定",
+			Method:  http.MethodPut,
+			Path:    "/admin/hospitals/1/retention",
+			Token:   auth.Token(1),
+			Body:    test.JsonBody(map[string]interface{}{"years": 10}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+
+				if m, e := db.Get(model.HospitalRetention{}, 1); e != nil {
+					assert.FailNow(t, e.Error())
+				} else {
+					assert.EqualValues(t, 10, m.(*model.HospitalRetention).Years)
+				}
+
+				assert.EqualValues(t, 2, F.Count(t, db, "hospital_retention", nil))
+			},
+		},
+		{
+			Name:    "設定を削除",
+			Method:  http.MethodPut,
+			Path:    "/admin/hospitals/2/retention",
+			Token:   auth.Token(1),
+			Body:    test.JsonBody(map[string]interface{}{"years": nil}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &hospitalRetentionResponse{}).(*hospitalRetentionResponse)
+
+				assert.Nil(t, res.Years)
+				assert.EqualValues(t, 0, F.Count(t, db, "hospital_retention", nil))
+			},
+		},
+		{
+			Name:    "0年",
+			Method:  http.MethodPut,
+			Path:    "/admin/hospitals/1/retention",
+			Token:   auth.Token(1),
+			Body:    test.JsonBody(map[string]interface{}{"years": 0}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			Name:    "病院が存在しない",
+			Method:  http.MethodPut,
+			Path:    "/admin/hospitals/0/retention",
+			Token:   auth.Token(1),
+			Body:    test.JsonBody(map[string]interface{}{"years": 1}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		F.Truncate(db, "hospital_retention", "hospital")
+
+		F.Insert(db, model.Hospital{}, 0, 3, func(i int, r F.Record) {
+			r["Uuid"] = fmt.Sprintf("hospital-%04d", i)
+		})
+
+		db.Insert(&model.HospitalRetention{HospitalId: 2, Years: 5, ModifiedAt: time.Now()})
+	})
+}
+
+func TestAdminRetention_Restore(t *testing.T) {
+	auth := (&F.AdminFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "復元済み",
+			Method:  http.MethodPost,
+			Path:    "/admin/measurements/2/restore",
+			Token:   auth.Token(1),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			Name:    "アーカイブされていない",
+			Method:  http.MethodPost,
+			Path:    "/admin/measurements/3/restore",
+			Token:   auth.Token(1),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		F.Truncate(db, "measurement_archive")
+
+		now := time.Now()
+
+		db.Insert(
+			&model.MeasurementArchive{MeasurementId: 1, StorageKey: "archives/1", ArchivedAt: now},
+			&model.MeasurementArchive{MeasurementId: 2, StorageKey: "archives/2", ArchivedAt: now, RestoredAt: &now},
+		)
+	})
+}
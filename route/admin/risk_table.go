@@ -0,0 +1,265 @@
+package admin
+
+import (
+	"net/http"
+
+	v "github.com/go-ozzo/ozzo-validation/v4"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/model"
+	S "github.com/spiker/spiker-server/service"
+	"github.com/spiker/spiker-server/route/shared"
+)
+
+type listRiskTablesQuery struct {
+	Name   *string `query:"name"`
+	Limit  int     `query:"limit"`
+	Offset int     `query:"offset"`
+}
+
+type listRiskTablesResponse struct {
+	RiskTables []*model.RiskTable `json:"riskTables"`
+	Total      int64              `json:"total"`
+	Limit      int                `json:"limit"`
+	Offset     int                `json:"offset"`
+}
+
+// listRiskTables godoc
+// @summary リスクテーブル一覧を名前、バージョンの新しい順に取得する。
+// @tags [admin] RiskTable
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param name query string false "テーブル名。"
+// @param limit query int false "最大取得件数。"
+// @param offset query int false "取得オフセット。"
+// @success 200 {object} listRiskTablesResponse "リスクテーブル一覧。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /admin/risk_tables [get]
+func listRiskTables(c *shared.Context) error {
+	query := &listRiskTablesQuery{nil, 100, 0}
+
+	if e := c.Bind(query); e != nil {
+		return e
+	}
+
+	if e := (v.Errors{
+		"limit": v.Validate(query.Limit, v.Min(0)),
+		"offset": v.Validate(query.Offset, v.Min(0)),
+	}).Filter(); e != nil {
+		return e
+	}
+
+	service := shared.CreateService(S.RiskTableService{}, c).(*S.RiskTableService)
+
+	results, total, err := service.List(query.Name, query.Limit, query.Offset)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &listRiskTablesResponse{
+		RiskTables: results,
+		Total: total,
+		Limit: query.Limit,
+		Offset: query.Offset,
+	})
+}
+
+// fetchRiskTable godoc
+// @summary リスクテーブルを取得する。
+// @tags [admin] RiskTable
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param risk_table_id path int true "リスクテーブルID。"
+// @success 200 {object} model.RiskTable "リスクテーブル。"
+// @failure 404 {object} shared.ErrorResponse "リスクテーブルが存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /admin/risk_tables/{risk_table_id} [get]
+func fetchRiskTable(c *shared.Context) error {
+	id := c.IntParam("risk_table_id")
+
+	service := shared.CreateService(S.RiskTableService{}, c).(*S.RiskTableService)
+
+	result, err := service.Fetch(id)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+type createRiskTableBody struct {
+	Name     string          `json:"name" maxlength:"64"`
+	Memo     string          `json:"memo" maxlength:"2000"`
+	Matrices *C.RiskMatrices `json:"matrices"`
+}
+
+// createRiskTable godoc
+// @summary リスクテーブルを登録する。
+// @description 同じ名前のテーブルが既にある場合、その次のバージョンとして登録される。登録後に判定表は変更できない。
+// @tags [admin] RiskTable
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param table body createRiskTableBody true "リスクテーブル情報。"
+// @success 201 {object} model.RiskTable "登録したリスクテーブル。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /admin/risk_tables [post]
+func createRiskTable(c *shared.Context) error {
+	body := &createRiskTableBody{}
+
+	if e := c.Bind(body); e != nil {
+		return e
+	}
+
+	if e := (v.Errors{
+		"name": v.Validate(body.Name, v.Required, v.RuneLength(0, 64)),
+		"memo": v.Validate(body.Memo, v.RuneLength(0, 2000)),
+		"matrices": v.Validate(body.Matrices, v.Required),
+	}).Filter(); e != nil {
+		return e
+	}
+
+	service := shared.CreateService(S.RiskTableTxService{}, c).(*S.RiskTableTxService)
+
+	result, err := service.Create(body.Name, body.Memo, body.Matrices)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, result)
+}
+
+type updateRiskTableBody struct {
+	Memo string `json:"memo" maxlength:"2000"`
+}
+
+// updateRiskTable godoc
+// @summary リスクテーブルのメモを更新する。
+// @tags [admin] RiskTable
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param risk_table_id path int true "リスクテーブルID。"
+// @param table body updateRiskTableBody true "リスクテーブル情報。"
+// @success 200 {object} model.RiskTable "更新したリスクテーブル。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
+// @failure 404 {object} shared.ErrorResponse "リスクテーブルが存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /admin/risk_tables/{risk_table_id} [put]
+func updateRiskTable(c *shared.Context) error {
+	id := c.IntParam("risk_table_id")
+
+	body := &updateRiskTableBody{}
+
+	if e := c.Bind(body); e != nil {
+		return e
+	}
+
+	if e := (v.Errors{
+		"memo": v.Validate(body.Memo, v.RuneLength(0, 2000)),
+	}).Filter(); e != nil {
+		return e
+	}
+
+	service := shared.CreateService(S.RiskTableTxService{}, c).(*S.RiskTableTxService)
+
+	result, err := service.Update(id, body.Memo)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// deleteRiskTable godoc
+// @summary リスクテーブルを削除する。
+// @tags [admin] RiskTable
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param risk_table_id path int true "リスクテーブルID。"
+// @success 204 "処理に成功。"
+// @failure 400 {object} shared.ErrorResponse "病院または診断から参照されている。"
+// @failure 404 {object} shared.ErrorResponse "リスクテーブルが存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /admin/risk_tables/{risk_table_id} [delete]
+func deleteRiskTable(c *shared.Context) error {
+	id := c.IntParam("risk_table_id")
+
+	service := shared.CreateService(S.RiskTableTxService{}, c).(*S.RiskTableTxService)
+
+	err := service.Delete(id)
+
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+type hospitalRiskTableResponse struct {
+	RiskTable *model.RiskTable `json:"riskTable"`
+}
+
+// fetchHospitalRiskTable godoc
+// @summary 病院で利用するリスクテーブルを取得する。
+// @description 設定されていない場合、riskTableはnullとなり、既定の判定表が利用される。
+// @tags [admin] RiskTable
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param hospital_id path int true "病院ID。"
+// @success 200 {object} hospitalRiskTableResponse "リスクテーブル。"
+// @failure 404 {object} shared.ErrorResponse "病院が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /admin/hospitals/{hospital_id}/risk_table [get]
+func fetchHospitalRiskTable(c *shared.Context) error {
+	id := c.IntParam("hospital_id")
+
+	service := shared.CreateService(S.RiskTableService{}, c).(*S.RiskTableService)
+
+	result, err := service.FetchForHospital(id)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &hospitalRiskTableResponse{result})
+}
+
+type hospitalRiskTableBody struct {
+	RiskTableId *int `json:"riskTableId"`
+}
+
+// updateHospitalRiskTable godoc
+// @summary 病院で利用するリスクテーブルを設定する。
+// @description riskTableIdにnullを指定すると、既定の判定表を利用するようになる。
+// @tags [admin] RiskTable
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param hospital_id path int true "病院ID。"
+// @param table body hospitalRiskTableBody true "リスクテーブルID。"
+// @success 200 {object} hospitalRiskTableResponse "設定したリスクテーブル。"
+// @failure 404 {object} shared.ErrorResponse "病院またはリスクテーブルが存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /admin/hospitals/{hospital_id}/risk_table [put]
+func updateHospitalRiskTable(c *shared.Context) error {
+	id := c.IntParam("hospital_id")
+
+	body := &hospitalRiskTableBody{}
+
+	if e := c.Bind(body); e != nil {
+		return e
+	}
+
+	service := shared.CreateService(S.RiskTableTxService{}, c).(*S.RiskTableTxService)
+
+	result, err := service.SelectForHospital(id, body.RiskTableId)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &hospitalRiskTableResponse{result})
+}
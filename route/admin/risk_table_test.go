@@ -0,0 +1,251 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/gorp.v2"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/test"
+	F "github.com/spiker/spiker-server/test/fixture"
+)
+
+func prepareRiskTables(db *gorp.DbMap) {
+	F.Truncate(db, "hospital_risk_table", "diagnosis_risk_table", "risk_table", "hospital")
+
+	F.Insert(db, model.Hospital{}, 0, 3, func(i int, r F.Record) {
+		r["Uuid"] = fmt.Sprintf("hospital-%04d", i)
+	})
+
+	matrices, _ := json.Marshal(C.DefaultRiskMatrices)
+
+	// 1: table-a v1, 2: table-a v2, 3: table-b v1
+	F.Insert(db, model.RiskTable{}, 0, 3, func(i int, r F.Record) {
+		r["Name"] = F.If(i <= 2, "table-a", "table-b")
+		r["Version"] = F.If(i <= 2, i, 1)
+		r["Matrices"] = model.JSON(matrices)
+	})
+
+	// 病院1はtable-a v2を利用。
+	db.Insert(&model.HospitalRiskTable{HospitalId: 1, RiskTableId: 2})
+}
+
+func TestAdminRiskTable_Create(t *testing.T) {
+	auth := (&F.AdminFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	testBody := func(name string) map[string]interface{} {
+		return map[string]interface{}{
+			"name": name,
+			"memo": "新規メモ",
+			"matrices": C.DefaultRiskMatrices,
+		}
+	}
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "新しい名前",
+			Method:  http.MethodPost,
+			Path:    "/admin/risk_tables",
+			Token:   auth.Token(1),
+			Body:    test.JsonBody(testBody("table-c")),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusCreated, rec.Code)
+				res := F.FromJsonResponse(t, rec, &model.RiskTable{}).(*model.RiskTable)
+
+				assert.EqualValues(t, 4, res.Id)
+				assert.EqualValues(t, "table-c", res.Name)
+				assert.EqualValues(t, 1, res.Version)
+				assert.EqualValues(t, "新規メモ", res.Memo)
+
+				matrices := &C.RiskMatrices{}
+				assert.NoError(t, json.Unmarshal(res.Matrices, matrices))
+				assert.EqualValues(t, C.DefaultRiskMatrices, matrices)
+			},
+		},
+		{
+			Name:    "既存の名前",
+			Method:  http.MethodPost,
+			Path:    "/admin/risk_tables",
+			Token:   auth.Token(1),
+			Body:    test.JsonBody(testBody("table-a")),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusCreated, rec.Code)
+				res := F.FromJsonResponse(t, rec, &model.RiskTable{}).(*model.RiskTable)
+
+				assert.EqualValues(t, "table-a", res.Name)
+				assert.EqualValues(t, 3, res.Version)
+			},
+		},
+	}
+
+	validation := func(title string, key string, value interface{}) test.HttpTest {
+		body := testBody("table-c")
+		body[key] = value
+
+		return test.HttpTest{
+			Name:    title,
+			Method:  http.MethodPost,
+			Path:    "/admin/risk_tables",
+			Token:   auth.Token(1),
+			Body:    test.JsonBody(body),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		}
+	}
+
+	invalidShape := *C.DefaultRiskMatrices
+	invalidShape.VariabilityNormal = invalidShape.VariabilityNormal[1:]
+
+	invalidValue := *C.DefaultRiskMatrices
+	invalidValue.VariabilityLost = []int{4, 5, 5, 5, 5, 5, 5, 6}
+
+	httpTests = append(httpTests,
+		validation("名前が空", "name", ""),
+		validation("判定表が無い", "matrices", nil),
+		validation("判定表の行数が違う", "matrices", &invalidShape),
+		validation("判定表の値が範囲外", "matrices", &invalidValue),
+	)
+
+	httpTests.Run(testHandler(), t, func() {
+		prepareRiskTables(db)
+	})
+}
+
+func TestAdminRiskTable_Delete(t *testing.T) {
+	auth := (&F.AdminFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "削除",
+			Method:  http.MethodDelete,
+			Path:    "/admin/risk_tables/3",
+			Token:   auth.Token(1),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNoContent, rec.Code)
+
+				assert.EqualValues(t, 2, F.Count(t, db, "risk_table", nil))
+			},
+		},
+		{
+			Name:    "病院から参照されている",
+			Method:  http.MethodDelete,
+			Path:    "/admin/risk_tables/2",
+			Token:   auth.Token(1),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			Name:    "存在しない",
+			Method:  http.MethodDelete,
+			Path:    "/admin/risk_tables/0",
+			Token:   auth.Token(1),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		prepareRiskTables(db)
+	})
+}
+
+func TestAdminRiskTable_Hospital(t *testing.T) {
+	auth := (&F.AdminFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "取得",
+			Method:  http.MethodGet,
+			Path:    "/admin/hospitals/1/risk_table",
+			Token:   auth.Token(1),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &hospitalRiskTableResponse{}).(*hospitalRiskTableResponse)
+
+				assert.EqualValues(t, 2, res.RiskTable.Id)
+			},
+		},
+		{
+			Name:    "未設定",
+			Method:  http.MethodGet,
+			Path:    "/admin/hospitals/2/risk_table",
+			Token:   auth.Token(1),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &hospitalRiskTableResponse{}).(*hospitalRiskTableResponse)
+
+				assert.Nil(t, res.RiskTable)
+			},
+		},
+		{
+			Name:    "設定",
+			Method:  http.MethodPut,
+			Path:    "/admin/hospitals/1/risk_table",
+			Token:   auth.Token(1),
+			Body:    test.JsonBody(map[string]interface{}{"riskTableId": 3}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &hospitalRiskTableResponse{}).(*hospitalRiskTableResponse)
+
+				assert.EqualValues(t, 3, res.RiskTable.Id)
+
+				if m, e := db.Get(model.HospitalRiskTable{}, 1); e != nil {
+					assert.FailNow(t, e.Error())
+				} else {
+					assert.EqualValues(t, 3, m.(*model.HospitalRiskTable).RiskTableId)
+				}
+			},
+		},
+		{
+			Name:    "解除",
+			Method:  http.MethodPut,
+			Path:    "/admin/hospitals/1/risk_table",
+			Token:   auth.Token(1),
+			Body:    test.JsonBody(map[string]interface{}{"riskTableId": nil}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+
+				assert.EqualValues(t, 0, F.Count(t, db, "hospital_risk_table", nil))
+			},
+		},
+		{
+			Name:    "テーブルが存在しない",
+			Method:  http.MethodPut,
+			Path:    "/admin/hospitals/1/risk_table",
+			Token:   auth.Token(1),
+			Body:    test.JsonBody(map[string]interface{}{"riskTableId": 0}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+		{
+			Name:    "病院が存在しない",
+			Method:  http.MethodGet,
+			Path:    "/admin/hospitals/0/risk_table",
+			Token:   auth.Token(1),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		prepareRiskTables(db)
+	})
+}
@@ -0,0 +1,149 @@
+package annotation
+
+import (
+	"net/http"
+	"time"
+
+	v "github.com/go-ozzo/ozzo-validation/v4"
+
+	"github.com/spiker/spiker-server/model"
+	S "github.com/spiker/spiker-server/service"
+	"github.com/spiker/spiker-server/route/shared"
+)
+
+type listAntenatalReportsQuery struct {
+	Limit  int `query:"limit"`
+	Offset int `query:"offset"`
+}
+
+type listAntenatalReportsResponse struct {
+	Reports []*model.AntenatalReport `json:"reports"`
+	Total   int64                    `json:"total"`
+	Limit   int                      `json:"limit"`
+	Offset  int                      `json:"offset"`
+}
+
+// listAntenatalReports godoc
+// @summary 計測のNST解析結果を新しい順に取得する。
+// @tags [annotation] Antenatal
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param hospital_uuid path string true "病院UUID。"
+// @param measurement_id path int true "計測記録ID。"
+// @param limit query int false "最大取得件数。"
+// @param offset query int false "取得オフセット。"
+// @success 200 {object} listAntenatalReportsResponse "NST解析結果一覧。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
+// @failure 404 {object} shared.ErrorResponse "計測記録が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /annotation/hospitals/{hospital_uuid}/measurements/{measurement_id}/antenatal_reports [get]
+func listAntenatalReports(c *shared.Context) error {
+	measurementId := c.IntParam("measurement_id")
+
+	if e := checkMeasurementAccess(c, measurementId, nil); e != nil {
+		return e
+	}
+
+	query := &listAntenatalReportsQuery{100, 0}
+
+	if e := c.Bind(query); e != nil {
+		return e
+	}
+
+	if e := (v.Errors{
+		"limit": v.Validate(query.Limit, v.Min(0)),
+		"offset": v.Validate(query.Offset, v.Min(0)),
+	}).Filter(); e != nil {
+		return e
+	}
+
+	service := shared.CreateService(S.AntenatalService{}, c).(*S.AntenatalService)
+
+	results, total, err := service.List(measurementId, query.Limit, query.Offset)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &listAntenatalReportsResponse{
+		Reports: results,
+		Total: total,
+		Limit: query.Limit,
+		Offset: query.Offset,
+	})
+}
+
+// fetchAntenatalReport godoc
+// @summary 計測のNST解析結果を取得する。
+// @tags [annotation] Antenatal
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param hospital_uuid path string true "病院UUID。"
+// @param measurement_id path int true "計測記録ID。"
+// @param report_id path int true "NST解析結果ID。"
+// @success 200 {object} model.AntenatalReport "NST解析結果。"
+// @failure 404 {object} shared.ErrorResponse "計測記録またはNST解析結果が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /annotation/hospitals/{hospital_uuid}/measurements/{measurement_id}/antenatal_reports/{report_id} [get]
+func fetchAntenatalReport(c *shared.Context) error {
+	measurementId := c.IntParam("measurement_id")
+	id := c.IntParam("report_id")
+
+	if e := checkMeasurementAccess(c, measurementId, nil); e != nil {
+		return e
+	}
+
+	service := shared.CreateService(S.AntenatalService{}, c).(*S.AntenatalService)
+
+	result, err := service.Fetch(measurementId, id)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+type analyzeAntenatalBody struct {
+	From  *time.Time `json:"from"`
+	Until *time.Time `json:"until"`
+}
+
+// analyzeAntenatal godoc
+// @summary 計測の心拍データに対してNST解析を行い、結果を登録する。
+// @description STV、LTV、高変動・低変動エピソード、一過性頻脈・徐脈を算出し、Dawes-Redman基準を満たすか判定する。
+// @description untilを省略した場合は最終データ日時、fromを省略した場合はそこから60分前(計測開始以降)までを対象とする。期間は60分以内。
+// @tags [annotation] Antenatal
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param hospital_uuid path string true "病院UUID。"
+// @param measurement_id path int true "計測記録ID。"
+// @param range body analyzeAntenatalBody true "解析期間。"
+// @success 201 {object} model.AntenatalReport "NST解析結果。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
+// @failure 404 {object} shared.ErrorResponse "計測記録が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /annotation/hospitals/{hospital_uuid}/measurements/{measurement_id}/antenatal_reports [post]
+func analyzeAntenatal(c *shared.Context) error {
+	measurementId := c.IntParam("measurement_id")
+
+	if e := checkMeasurementAccess(c, measurementId, nil); e != nil {
+		return e
+	}
+
+	body := &analyzeAntenatalBody{}
+
+	if e := c.Bind(body); e != nil {
+		return e
+	}
+
+	service := shared.CreateService(S.AntenatalTxService{}, c).(*S.AntenatalTxService)
+
+	result, err := service.Analyze(measurementId, body.From, body.Until)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, result)
+}
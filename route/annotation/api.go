@@ -81,6 +81,12 @@ func registerAPIs(router *echo.Group) {
 	// データ。
 	router.GET("/hospitals/:hospital_uuid/measurements/:measurement_id/heartrates", shared.C(listHeartRate))
 	router.GET("/hospitals/:hospital_uuid/measurements/:measurement_id/tocos", shared.C(listTOCO))
+	router.GET("/hospitals/:hospital_uuid/measurements/:measurement_id/contractions", shared.C(listContractions))
+	router.GET("/hospitals/:hospital_uuid/data", shared.C(listBatchData))
+
+	// エクスポート。
+	router.GET("/hospitals/:hospital_uuid/measurements/:measurement_id/export", shared.C(exportMeasurement))
+	router.GET("/hospitals/:hospital_uuid/export", shared.C(exportMeasurements))
 
 	// 患者。
 	router.GET("/hospitals/:hospital_uuid/patients", shared.C(listPatients))
@@ -104,6 +110,15 @@ func registerAPIs(router *echo.Group) {
 	router.PUT("/hospitals/:hospital_uuid/measurements/:measurement_id/annotations/:annotation_id", shared.C(updateAnnotatedEvent))
 	router.DELETE("/hospitals/:hospital_uuid/measurements/:measurement_id/annotations/:annotation_id", shared.C(deleteAnnotatedEvent))
 
+	// 臨床イベント。
+	router.GET("/hospitals/:hospital_uuid/measurements/:measurement_id/clinical_events", shared.C(listClinicalEvents))
+	router.GET("/hospitals/:hospital_uuid/measurements/:measurement_id/clinical_events/:clinical_event_id", shared.C(fetchClinicalEvent))
+
+	// NST解析。
+	router.GET("/hospitals/:hospital_uuid/measurements/:measurement_id/antenatal_reports", shared.C(listAntenatalReports))
+	router.POST("/hospitals/:hospital_uuid/measurements/:measurement_id/antenatal_reports", shared.C(analyzeAntenatal))
+	router.GET("/hospitals/:hospital_uuid/measurements/:measurement_id/antenatal_reports/:report_id", shared.C(fetchAntenatalReport))
+
 	// アラート。
 	router.GET("/hospitals/:hospital_uuid/alerts", shared.C(collectUnreadAlerts))
 }
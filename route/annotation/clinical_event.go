@@ -0,0 +1,93 @@
+package annotation
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/spiker/spiker-server/model"
+	S "github.com/spiker/spiker-server/service"
+	"github.com/spiker/spiker-server/route/shared"
+)
+
+type listClinicalEventsQuery struct {
+	Minutes int       `query:"minutes"`
+	End     time.Time `query:"end"`
+}
+
+type listClinicalEventsResponse struct {
+	ClinicalEvents []*model.ClinicalEvent `json:"clinicalEvents"`
+}
+
+// listClinicalEvents godoc
+// @summary ある計測に関する、期間内の臨床イベントを新しい順に取得する。
+// @description 終了日時を持つイベントは、期間と重なるものを含む。
+// @tags [annotation] ClinicalEvent
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param hospital_uuid path string true "病院UUID。"
+// @param measurement_id path int true "計測記録ID。"
+// @param minutes query int false "期間長(分)。"
+// @param end query string false "末尾日時。RFC3339形式。"
+// @success 200 {object} listClinicalEventsResponse "臨床イベント一覧。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
+// @failure 404 {object} shared.ErrorResponse "計測が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /annotation/hospitals/{hospital_uuid}/measurements/{measurement_id}/clinical_events [get]
+func listClinicalEvents(c *shared.Context) error {
+	measurementId := c.IntParam("measurement_id")
+
+	if e := checkMeasurementAccess(c, measurementId, nil); e != nil {
+		return e
+	}
+
+	query := &listClinicalEventsQuery{}
+
+	if e := c.Bind(query); e != nil {
+		return e
+	}
+
+	service := shared.CreateService(S.ClinicalEventService{}, c).(*S.ClinicalEventService)
+
+	begin := query.End.Add(time.Duration(-query.Minutes)*time.Minute)
+
+	results, err := service.ListInRange(measurementId, begin, query.End)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &listClinicalEventsResponse{
+		ClinicalEvents: results,
+	})
+}
+
+// fetchClinicalEvent godoc
+// @summary 臨床イベントを取得する。
+// @tags [annotation] ClinicalEvent
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param hospital_uuid path string true "病院UUID。"
+// @param measurement_id path int true "計測記録ID。"
+// @param clinical_event_id path int true "臨床イベントID。"
+// @success 200 {object} model.ClinicalEvent "臨床イベント情報。"
+// @failure 404 {object} shared.ErrorResponse "臨床イベントが存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /annotation/hospitals/{hospital_uuid}/measurements/{measurement_id}/clinical_events/{clinical_event_id} [get]
+func fetchClinicalEvent(c *shared.Context) error {
+	measurementId := c.IntParam("measurement_id")
+	id := c.IntParam("clinical_event_id")
+
+	if e := checkMeasurementAccess(c, measurementId, nil); e != nil {
+		return e
+	}
+
+	service := shared.CreateService(S.ClinicalEventService{}, c).(*S.ClinicalEventService)
+
+	result, err := service.Fetch(measurementId, id)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
@@ -1,10 +1,11 @@
 package annotation
 
 import (
+	"math"
 	"net/http"
 	"time"
 
-	//v "github.com/go-ozzo/ozzo-validation/v4"
+	v "github.com/go-ozzo/ozzo-validation/v4"
 
 	C "github.com/spiker/spiker-server/constant"
 	"github.com/spiker/spiker-server/model"
@@ -15,17 +16,22 @@ import (
 )
 
 type listHeartRateQuery struct {
-	Minutes *int       `query:"minutes"`
-	End     *time.Time `query:"end"`
+	Minutes    *int       `query:"minutes"`
+	End        *time.Time `query:"end"`
+	Cleaned    bool       `query:"cleaned"`
+	Resolution *int       `query:"resolution"`
+	MaxPoints  *int       `query:"maxPoints"`
 }
 
 type listHeartRateResponse struct {
-	Records []*model.SensorValue `json:"records"`
+	Records    []*model.SensorValue    `json:"records"`
+	Envelopes  []*model.SensorEnvelope `json:"envelopes,omitempty"`
+	Resolution *int                    `json:"resolution,omitempty"`
 }
 
 // listHeartRate godoc
 // @summary 指定期間の心拍を古い順に取得する。
-// @description 返されるデータは左閉右開。タイムスタンプはUnixタイム(ミリ秒)。
+// @description 返されるデータは左閉右開。タイムスタンプはUnixタイム(ミリ秒)。集約した場合、recordsには期間毎の平均を四捨五入した値、envelopesには期間毎の最小、最大、平均を返す。
 // @tags [annotation] Data
 // @produce json
 // @param Authorization header string true "Bearerトークン。"
@@ -33,6 +39,9 @@ type listHeartRateResponse struct {
 // @param measurement_id path int true "計測記録ID。"
 // @param minutes query int false "期間長(分)。省略時は先頭から。"
 // @param end query string false "末尾日時。RFC3339形式。省略時は現在日時。"
+// @param cleaned query bool false "trueの場合、アーチファクトを補正、除去した心拍を返す。"
+// @param resolution query int false "集約期間(秒)。指定した場合、期間毎の最小、最大、平均を返す。"
+// @param maxPoints query int false "集約後の最大点数。resolutionを省略した場合に、この点数以下となるよう集約期間を決定する。"
 // @success 200 {object} listHeartRateResponse "心拍記録。"
 // @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
 // @failure 404 {object} shared.ErrorResponse "患者が存在しない。"
@@ -53,29 +62,58 @@ func listHeartRate(c *shared.Context) error {
 
 	service := shared.CreateService(S.DataService{}, c).(*S.DataService)
 
+	if e := validateDownsampling(query.Resolution, query.MaxPoints); e != nil {
+		return e
+	}
+
 	begin, end := determineDataRange(query.Minutes, query.End)
 
-	results, err := service.ListCTGData(measurementId, C.MeasurementTypeHeartRate, begin, end)
+	if query.Resolution != nil || query.MaxPoints != nil {
+		envelopes, window, err := service.ListCTGEnvelopes(
+			measurementId, C.MeasurementTypeHeartRate, begin, end, query.Resolution, query.MaxPoints, query.Cleaned,
+		)
+
+		if err != nil {
+			return err
+		}
+
+		resolution := int(window.Seconds())
+
+		return c.JSON(http.StatusOK, &listHeartRateResponse{envelopeRecords(envelopes), envelopes, &resolution})
+	}
+
+	var results []*model.SensorValue
+	var err error
+
+	if query.Cleaned {
+		results, err = service.ListCleanedHeartRate(measurementId, begin, end)
+	} else {
+		results, err = service.ListCTGData(measurementId, C.MeasurementTypeHeartRate, begin, end)
+	}
 
 	if err != nil {
 		return err
 	}
 
-	return c.JSON(http.StatusOK, &listHeartRateResponse{results})
+	return c.JSON(http.StatusOK, &listHeartRateResponse{results, nil, nil})
 }
 
 type listTOCOQuery struct {
-	Minutes *int       `query:"minutes"`
-	End     *time.Time `query:"end"`
+	Minutes    *int       `query:"minutes"`
+	End        *time.Time `query:"end"`
+	Resolution *int       `query:"resolution"`
+	MaxPoints  *int       `query:"maxPoints"`
 }
 
 type listTOCOResponse struct {
-	Records []*model.SensorValue `json:"records"`
+	Records    []*model.SensorValue    `json:"records"`
+	Envelopes  []*model.SensorEnvelope `json:"envelopes,omitempty"`
+	Resolution *int                    `json:"resolution,omitempty"`
 }
 
 // listTOC godoc
 // @summary 指定期間のTOCOを古い順に取得する。
-// @description 返されるデータは左閉右開。タイムスタンプはUnixタイム(ミリ秒)。
+// @description 返されるデータは左閉右開。タイムスタンプはUnixタイム(ミリ秒)。集約した場合、recordsには期間毎の平均を四捨五入した値、envelopesには期間毎の最小、最大、平均を返す。
 // @tags [annotation] Data
 // @produce json
 // @param Authorization header string true "Bearerトークン。"
@@ -83,6 +121,8 @@ type listTOCOResponse struct {
 // @param measurement_id path int true "計測記録ID。"
 // @param minutes query int false "期間長(分)。省略時は先頭から。"
 // @param end query string false "末尾日時。RFC3339形式。省略時は現在日時。"
+// @param resolution query int false "集約期間(秒)。指定した場合、期間毎の最小、最大、平均を返す。"
+// @param maxPoints query int false "集約後の最大点数。resolutionを省略した場合に、この点数以下となるよう集約期間を決定する。"
 // @success 200 {object} listTOCOResponse "TOCO記録。"
 // @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
 // @failure 404 {object} shared.ErrorResponse "患者が存在しない。"
@@ -103,15 +143,157 @@ func listTOCO(c *shared.Context) error {
 
 	service := shared.CreateService(S.DataService{}, c).(*S.DataService)
 
+	if e := validateDownsampling(query.Resolution, query.MaxPoints); e != nil {
+		return e
+	}
+
 	begin, end := determineDataRange(query.Minutes, query.End)
 
+	if query.Resolution != nil || query.MaxPoints != nil {
+		envelopes, window, err := service.ListCTGEnvelopes(
+			measurementId, C.MeasurementTypeTOCO, begin, end, query.Resolution, query.MaxPoints, false,
+		)
+
+		if err != nil {
+			return err
+		}
+
+		resolution := int(window.Seconds())
+
+		return c.JSON(http.StatusOK, &listTOCOResponse{envelopeRecords(envelopes), envelopes, &resolution})
+	}
+
 	results, err := service.ListCTGData(measurementId, C.MeasurementTypeTOCO, begin, end)
 
 	if err != nil {
 		return err
 	}
 
-	return c.JSON(http.StatusOK, &listTOCOResponse{results})
+	return c.JSON(http.StatusOK, &listTOCOResponse{results, nil, nil})
+}
+
+type listContractionsQuery struct {
+	Minutes *int       `query:"minutes"`
+	End     *time.Time `query:"end"`
+}
+
+type listContractionsResponse struct {
+	Contractions []*model.Contraction `json:"contractions"`
+}
+
+// listContractions godoc
+// @summary 指定期間の子宮収縮を古い順に取得する。
+// @description TOCOから自動診断時に検出された子宮収縮。期間は収縮開始日時で判定し、左閉右開。
+// @tags [annotation] Data
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param hospital_uuid path string true "病院UUID。"
+// @param measurement_id path int true "計測記録ID。"
+// @param minutes query int false "期間長(分)。省略時は先頭から。"
+// @param end query string false "末尾日時。RFC3339形式。省略時は現在日時。"
+// @success 200 {object} listContractionsResponse "子宮収縮。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
+// @failure 404 {object} shared.ErrorResponse "計測記録が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /annotation/hospitals/{hospital_uuid}/measurements/{measurement_id}/contractions [get]
+func listContractions(c *shared.Context) error {
+	measurementId := c.IntParam("measurement_id")
+
+	if e := checkMeasurementAccess(c, measurementId, nil); e != nil {
+		return e
+	}
+
+	query := &listContractionsQuery{}
+
+	if e := c.Bind(query); e != nil {
+		return e
+	}
+
+	service := shared.CreateService(S.ContractionService{}, c).(*S.ContractionService)
+
+	begin, end := determineDataRange(query.Minutes, query.End)
+
+	results, err := service.List(measurementId, begin, end)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &listContractionsResponse{results})
+}
+
+type listBatchDataQuery struct {
+	Measurements []int      `query:"measurements"`
+	Channels     []string   `query:"channels"`
+	Minutes      *int       `query:"minutes"`
+	End          *time.Time `query:"end"`
+}
+
+type listBatchDataResponse struct {
+	Series []*S.DataSeries `json:"series"`
+}
+
+// listBatchData godoc
+// @summary 複数の計測の心拍、TOCOを一括で古い順に取得する。
+// @description 返されるデータは左閉右開。タイムスタンプはUnixタイム(ミリ秒)。系列は計測、チャネルの指定順に並び、データの無い組み合わせも空の系列として含む。
+// @tags [annotation] Data
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param hospital_uuid path string true "病院UUID。"
+// @param measurements query []int true "計測IDリスト。"
+// @param channels query []string true "チャネルリスト。heartrate、tocoのいずれか。"
+// @param minutes query int false "期間長(分)。省略時は計測の先頭から。期間は最大6時間で、省略時に超える場合は末尾から6時間とする。"
+// @param end query string false "末尾日時。RFC3339形式。省略時はminutesを指定した場合は現在日時、それ以外は計測の末尾まで。"
+// @success 200 {object} listBatchDataResponse "計測、チャネル毎の記録。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー、または期間が上限を超える。"
+// @failure 404 {object} shared.ErrorResponse "計測記録が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /annotation/hospitals/{hospital_uuid}/data [get]
+func listBatchData(c *shared.Context) error {
+	query := &listBatchDataQuery{}
+
+	if e := c.Bind(query); e != nil {
+		return e
+	}
+
+	channels := []interface{}{}
+	for _, t := range []C.MeasurementType{C.MeasurementTypeHeartRate, C.MeasurementTypeTOCO} {
+		channels = append(channels, string(t))
+	}
+
+	if e := (v.Errors{
+		"measurements": v.Validate(query.Measurements, v.Required, v.Length(1, C.DataBatchMaximumMeasurements)),
+		"channels": v.Validate(query.Channels, v.Required, v.Each(v.In(channels...))),
+	}).Filter(); e != nil {
+		return e
+	}
+
+	for _, mid := range query.Measurements {
+		if e := checkMeasurementAccess(c, mid, nil); e != nil {
+			return e
+		}
+	}
+
+	types := []C.MeasurementType{}
+	for _, ch := range query.Channels {
+		types = append(types, C.MeasurementType(ch))
+	}
+
+	service := shared.CreateService(S.DataService{}, c).(*S.DataService)
+
+	var begin *time.Time = nil
+
+	if query.Minutes != nil {
+		begin, query.End = determineDataRange(query.Minutes, query.End)
+	}
+
+	results, err := service.ListBatchCTGData(query.Measurements, types, begin, query.End)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &listBatchDataResponse{results})
 }
 
 func determineDataRange(minutes *int, end *time.Time) (*time.Time, *time.Time) {
@@ -128,4 +310,22 @@ func determineDataRange(minutes *int, end *time.Time) (*time.Time, *time.Time) {
 	}
 
 	return begin, end
-}
\ No newline at end of file
+}
+
+func validateDownsampling(resolution *int, maxPoints *int) error {
+	return (v.Errors{
+		"resolution": v.Validate(resolution, v.NilOrNotEmpty, v.Min(1), v.Max(C.DownsamplingMaximumResolution)),
+		"maxPoints": v.Validate(maxPoints, v.NilOrNotEmpty, v.Min(1), v.Max(C.DownsamplingMaximumPoints)),
+	}).Filter()
+}
+
+// 集約したデータの各期間の平均を四捨五入し、センサ値とする。
+func envelopeRecords(envelopes []*model.SensorEnvelope) []*model.SensorValue {
+	results := []*model.SensorValue{}
+
+	for _, e := range envelopes {
+		results = append(results, &model.SensorValue{int(math.Round(e.Mean)), e.ObservedAt})
+	}
+
+	return results
+}
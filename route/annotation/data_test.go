@@ -275,3 +275,84 @@ func TestAnnotationData_ListTOCO(t *testing.T) {
 		influx.Insert("spiker", points...)
 	})
 }
+
+func TestAnnotationData_ListContractions(t *testing.T) {
+	auth := (&F.AnnotationFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	beginTime := time.Date(2021, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "期間指定取得",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/annotation/hospitals/hospital-0001/measurements/1/contractions",
+			Query:   func(q url.Values) {
+				q.Add("minutes", "10")
+				q.Add("end", "2021-01-02T03:14:05+00:00")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &listContractionsResponse{}).(*listContractionsResponse)
+
+				// 3分毎の収縮のうち、0,3,6,9分開始のもの。
+				assert.EqualValues(t, 4, len(res.Contractions))
+
+				for i, r := range res.Contractions {
+					assert.EqualValues(t, 1, r.MeasurementId)
+					assert.EqualValues(t, beginTime.Add(time.Duration(i*3)*time.Minute).Unix(), r.OnsetAt.Unix())
+					assert.EqualValues(t, 40, r.Amplitude)
+				}
+			},
+		},
+		{
+			Name:    "計測が無い",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/annotation/hospitals/hospital-0001/measurements/0/contractions",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		F.Truncate(db, "contraction", "measurement_terminal", "patient", "measurement", "hospital")
+
+		hospitals := F.Insert(db, model.Hospital{}, 0, 3, func(i int, r F.Record) {
+			r["Uuid"] = fmt.Sprintf("hospital-%04d", i)
+		}).([]*model.Hospital)
+
+		patients := F.Insert(db, model.Patient{}, 0, 3, func(i int, r F.Record) {
+			r["HospitalId"] = hospitals[i-1].Id
+		}).([]*model.Patient)
+
+		terminals := F.Insert(db, model.MeasurementTerminal{}, 0, 3, func(i int, r F.Record) {
+			r["HospitalId"] = hospitals[i-1].Id
+		}).([]*model.MeasurementTerminal)
+
+		measurements := F.Insert(db, model.Measurement{}, 0, 2, func(i int, r F.Record) {
+			r["Code"] = fmt.Sprintf("measurement-%04d", i)
+			r["PatientId"] = patients[0].Id
+			r["TerminalId"] = terminals[0].Id
+		}).([]*model.Measurement)
+
+		// 3分毎に1分間の収縮。
+		for _, m := range measurements {
+			for i := 0; i < 10; i++ {
+				onset := beginTime.Add(time.Duration(i*3)*time.Minute)
+
+				db.Insert(&model.Contraction{
+					MeasurementId: m.Id,
+					OnsetAt: onset,
+					PeakAt: onset.Add(30*time.Second),
+					EndAt: onset.Add(60*time.Second),
+					Amplitude: 40,
+					CreatedAt: onset,
+				})
+			}
+		}
+	})
+}
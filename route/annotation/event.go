@@ -21,11 +21,13 @@ type listComputedEventsQuery struct {
 }
 
 type listComputedEventsResponse struct {
-	Events []*model.ComputedEventEntity `json:"events"`
+	Events         []*model.ComputedEventEntity `json:"events"`
+	ClinicalEvents []*model.ClinicalEvent       `json:"clinicalEvents"`
 }
 
 // listComputedEvents godoc
 // @summary ある計測に関する、期間内の自動診断イベントを新しい順に取得する。
+// @description 期間内の臨床イベントを併せて返す。
 // @tags [annotation] Event
 // @produce json
 // @param Authorization header string true "Bearerトークン。"
@@ -61,13 +63,24 @@ func listComputedEvents(c *shared.Context) error {
 		return err
 	}
 
+	// 期間内の臨床イベントを併せて返す。
+	clinicalService := shared.CreateService(S.ClinicalEventService{}, c).(*S.ClinicalEventService)
+
+	clinicalEvents, err := clinicalService.ListInRange(measurementId, begin, query.End)
+
+	if err != nil {
+		return err
+	}
+
 	return c.JSON(http.StatusOK, &listComputedEventsResponse{
 		Events: results,
+		ClinicalEvents: clinicalEvents,
 	})
 }
 
 // fetchComputedEvent godoc
 // @summary 自動診断イベントを取得する。
+// @description スナップショットが保存されたイベントは、その署名付きURLを含む。
 // @tags [annotation] Event
 // @produce json
 // @param Authorization header string true "Bearerトークン。"
@@ -196,11 +209,13 @@ type listAnnotatedEventsQuery struct {
 }
 
 type listAnnotatedEventsResponse struct {
-	Annotations []*model.AnnotatedEventEntity `json:"annotations"`
+	Annotations    []*model.AnnotatedEventEntity `json:"annotations"`
+	ClinicalEvents []*model.ClinicalEvent        `json:"clinicalEvents"`
 }
 
 // listAnnotatedEvents godoc
 // @summary ある計測に関する、期間内のアノテーションを新しい順に取得する。
+// @description 期間内の臨床イベントを併せて返す。
 // @tags [annotation] Event
 // @produce json
 // @param Authorization header string true "Bearerトークン。"
@@ -236,8 +251,18 @@ func listAnnotatedEvents(c *shared.Context) error {
 		return err
 	}
 
+	// 期間内の臨床イベントを併せて返す。
+	clinicalService := shared.CreateService(S.ClinicalEventService{}, c).(*S.ClinicalEventService)
+
+	clinicalEvents, err := clinicalService.ListInRange(measurementId, begin, query.End)
+
+	if err != nil {
+		return err
+	}
+
 	return c.JSON(http.StatusOK, &listAnnotatedEventsResponse{
 		Annotations: results,
+		ClinicalEvents: clinicalEvents,
 	})
 }
 
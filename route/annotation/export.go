@@ -0,0 +1,113 @@
+package annotation
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	v "github.com/go-ozzo/ozzo-validation/v4"
+
+	C "github.com/spiker/spiker-server/constant"
+	S "github.com/spiker/spiker-server/service"
+	"github.com/spiker/spiker-server/route/shared"
+)
+
+type exportMeasurementQuery struct {
+	Format string     `query:"format"`
+	From   *time.Time `query:"from"`
+	Until  *time.Time `query:"until"`
+}
+
+// exportMeasurement godoc
+// @summary 計測の心拍(FHR1、FHR2)とTOCOをEDF+もしくはCSV形式で出力する。
+// @description EDF+形式では自動診断イベントとアノテーションを注釈として含む。値の無い秒は0とし、チャネル毎の欠損区間を"Gap <チャネル名>"の注釈として含む。CSV形式は単位を含むヘッダ行を持ち、1秒1行で出力する。
+// @tags [annotation] Export
+// @produce application/octet-stream,text/csv
+// @param Authorization header string true "Bearerトークン。"
+// @param hospital_uuid path string true "病院UUID。"
+// @param measurement_id path int true "計測記録ID。"
+// @param format query string true "出力形式。edf、csvのいずれか。"
+// @param from query string false "先頭日時。RFC3339形式。省略時は計測の先頭から。"
+// @param until query string false "末尾日時。RFC3339形式。省略時は計測の末尾まで。"
+// @success 200 {file} file "出力ファイル。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
+// @failure 404 {object} shared.ErrorResponse "計測記録が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /annotation/hospitals/{hospital_uuid}/measurements/{measurement_id}/export [get]
+func exportMeasurement(c *shared.Context) error {
+	measurementId := c.IntParam("measurement_id")
+
+	if e := checkMeasurementAccess(c, measurementId, nil); e != nil {
+		return e
+	}
+
+	query := &exportMeasurementQuery{}
+
+	if e := c.Bind(query); e != nil {
+		return e
+	} else if e := (v.Errors{
+		"format": v.Validate(query.Format, v.Required, v.In(string(C.ExportFormatEDF), string(C.ExportFormatCSV))),
+	}).Filter(); e != nil {
+		return e
+	}
+
+	return sendExport(c, C.ExportFormat(query.Format), []int{measurementId}, query.From, query.Until)
+}
+
+type exportMeasurementsQuery struct {
+	Measurements []int      `query:"measurements"`
+	Format       string     `query:"format"`
+	From         *time.Time `query:"from"`
+	Until        *time.Time `query:"until"`
+}
+
+// exportMeasurements godoc
+// @summary 複数の計測の心拍(FHR1、FHR2)とTOCOをEDF+もしくはCSV形式で一括出力する。
+// @description EDF+形式では計測毎のファイルをzipにまとめる。CSV形式では全計測を1つのファイルに計測の指定順で出力する。
+// @tags [annotation] Export
+// @produce application/zip,text/csv
+// @param Authorization header string true "Bearerトークン。"
+// @param hospital_uuid path string true "病院UUID。"
+// @param measurements query []int true "計測IDリスト。"
+// @param format query string true "出力形式。edf、csvのいずれか。"
+// @param from query string false "先頭日時。RFC3339形式。省略時は各計測の先頭から。"
+// @param until query string false "末尾日時。RFC3339形式。省略時は各計測の末尾まで。"
+// @success 200 {file} file "出力ファイル。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
+// @failure 404 {object} shared.ErrorResponse "計測記録が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /annotation/hospitals/{hospital_uuid}/export [get]
+func exportMeasurements(c *shared.Context) error {
+	query := &exportMeasurementsQuery{}
+
+	if e := c.Bind(query); e != nil {
+		return e
+	} else if e := (v.Errors{
+		"measurements": v.Validate(query.Measurements, v.Required, v.Length(1, C.ExportMaximumMeasurements)),
+		"format": v.Validate(query.Format, v.Required, v.In(string(C.ExportFormatEDF), string(C.ExportFormatCSV))),
+	}).Filter(); e != nil {
+		return e
+	}
+
+	for _, mid := range query.Measurements {
+		if e := checkMeasurementAccess(c, mid, nil); e != nil {
+			return e
+		}
+	}
+
+	return sendExport(c, C.ExportFormat(query.Format), query.Measurements, query.From, query.Until)
+}
+
+func sendExport(c *shared.Context, format C.ExportFormat, measurementIds []int, from *time.Time, until *time.Time) error {
+	service := shared.CreateService(S.ExportService{}, c).(*S.ExportService)
+
+	result, err := service.Export(format, measurementIds, from, until, shared.CurrentLocation)
+
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, result.Filename))
+
+	return c.Blob(http.StatusOK, result.ContentType, result.Content)
+}
@@ -2,7 +2,7 @@ package annotation
 
 import (
 	"net/http"
-	//"time"
+	"time"
 
 	v "github.com/go-ozzo/ozzo-validation/v4"
 
@@ -15,10 +15,17 @@ import (
 )
 
 type listMeasurementsQuery struct {
-	Limit    int `query:"limit"`
-	Offset   int `query:"offset"`
-	Patient  *int `query:"patient"`
-	Terminal *int `query:"terminal"`
+	Limit    int        `query:"limit"`
+	Offset   int        `query:"offset"`
+	Cursor   string     `query:"cursor"`
+	Sort     string     `query:"sort"`
+	Patient  *int       `query:"patient"`
+	Terminal *int       `query:"terminal"`
+	State    *string    `query:"state"`
+	Risk     *int       `query:"risk"`
+	MaxRisk  *int       `query:"maxRisk"`
+	From     *time.Time `query:"from"`
+	Until    *time.Time `query:"until"`
 }
 
 type listMeasurementsResponse struct {
@@ -26,19 +33,28 @@ type listMeasurementsResponse struct {
 	Total        int64                      `json:"total"`
 	Limit        int                        `json:"limit"`
 	Offset       int                        `json:"offset"`
+	NextCursor   *string                    `json:"nextCursor"`
 }
 
 // listMeasurements godoc
-// @summary 院内で行われた計測記録一覧を新しい順に取得する。
+// @summary 院内で行われた計測記録一覧を取得する。
 // @description `terminal`と`patient`は排他。両方指定した場合はエラー。いずれも指定しない場合、全ての計測記録を取得する。
+// @description `cursor`を指定した場合、前の一覧の`nextCursor`の続きから取得し、`offset`は無視する。この場合`total`は数えず-1となる。
 // @tags [annotation] Measurement
 // @produce json
 // @param Authorization header string true "`Bearerトークン。"
 // @param hospital_uuid path string true "病院UUID。"
 // @param limit query int false "最大取得件数。"
 // @param offset query int false "取得オフセット。"
+// @param cursor query string false "前の一覧の`nextCursor`。"
+// @param sort query string false "ソートする列。`id` `createdAt` `firstTime` `lastTime`のいずれかで、降順の場合は先頭に`-`を付ける。既定は`-createdAt`。"
 // @param patient query int false "患者ID。`terminal`と排他。"
 // @param terminal query int false "機器端末ID。`patient`と排他。"
+// @param state query string false "計測の状態。`open` `closed`のいずれか。"
+// @param risk query int false "自動診断イベントの最大リスクの下限。"
+// @param maxRisk query int false "自動診断イベントの最大リスクの上限。イベントの無い計測は0として扱う。"
+// @param from query string false "計測期間の絞り込みの先頭日時。RFC3339形式。"
+// @param until query string false "計測期間の絞り込みの末尾日時。RFC3339形式。"
 // @success 200 {object} listMeasurementsResponse "計測記録一覧。"
 // @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
 // @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
@@ -50,7 +66,7 @@ func listMeasurements(c *shared.Context) error {
 		return err
 	}
 
-	query := &listMeasurementsQuery{100, 0, nil, nil}
+	query := &listMeasurementsQuery{Limit: 100}
 
 	if e := c.Bind(query); e != nil {
 		return e
@@ -59,6 +75,10 @@ func listMeasurements(c *shared.Context) error {
 	if e := (v.Errors{
 		"limit": v.Validate(query.Limit, v.Min(0)),
 		"offset": v.Validate(query.Offset, v.Min(0)),
+		"sort": v.Validate(query.Sort, v.In(shared.SortSpecs(C.MeasurementSorts)...)),
+		"state": v.Validate(query.State, v.In(string(C.MeasurementStateOpen), string(C.MeasurementStateClosed))),
+		"risk": v.Validate(query.Risk, v.In(1, 2, 3, 4, 5)),
+		"maxRisk": v.Validate(query.MaxRisk, v.In(0, 1, 2, 3, 4, 5)),
 	}).Filter(); e != nil {
 		return e
 	}
@@ -71,9 +91,30 @@ func listMeasurements(c *shared.Context) error {
 		)
 	}
 
+	if query.Risk != nil && query.MaxRisk != nil && *query.Risk > *query.MaxRisk {
+		return C.NewBadRequestError(
+			"invalid_risk_range",
+			"Minimum risk must not be greater than maximum risk",
+			map[string]interface{}{},
+		)
+	}
+
 	service := shared.CreateService(S.MeasurementService{}, c).(*S.MeasurementService)
 
-	results, total, err := service.List(hospital.Id, query.Patient, query.Terminal, query.Limit, query.Offset)
+	results, info, err := service.List(hospital.Id, &model.MeasurementFilter{
+		PatientId:   query.Patient,
+		TerminalId:  query.Terminal,
+		State:       query.State,
+		MinimumRisk: query.Risk,
+		MaximumRisk: query.MaxRisk,
+		From:        query.From,
+		Until:       query.Until,
+	}, &model.Paging{
+		Limit:  query.Limit,
+		Offset: query.Offset,
+		Cursor: query.Cursor,
+		Sort:   query.Sort,
+	})
 
 	if err != nil {
 		return err
@@ -81,9 +122,10 @@ func listMeasurements(c *shared.Context) error {
 
 	return c.JSON(http.StatusOK, &listMeasurementsResponse{
 		Measurements: results,
-		Total:        total,
+		Total:        info.Total,
 		Limit:        query.Limit,
 		Offset:       query.Offset,
+		NextCursor:   info.NextCursor,
 	})
 }
 
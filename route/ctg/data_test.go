@@ -10,14 +10,40 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/gorp.v2"
 
 	//"github.com/spiker/spiker-server/route/shared"
 	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/lib/phi"
 	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/resource/rds"
 	"github.com/spiker/spiker-server/test"
 	F "github.com/spiker/spiker-server/test/fixture"
 )
 
+// 暗号化された計測コードを病院の鍵で復号する。
+func openCode(t *testing.T, db *gorp.DbMap, hospitalId int, code string) string {
+	assert.True(t, phi.IsSealed(code))
+
+	record, err := rds.FetchHospitalKey(db, hospitalId)
+
+	if !assert.NoError(t, err) || !assert.NotNil(t, record) {
+		return ""
+	}
+
+	key, err := phi.UnwrapKey(record.WrappedKey)
+
+	if !assert.NoError(t, err) {
+		return ""
+	}
+
+	plain, err := phi.Open(key, code)
+
+	assert.NoError(t, err)
+
+	return plain
+}
+
 func TestCTG_Upload(t *testing.T) {
 	auth := (&F.CTGFixture{}).Generate(3)
 
@@ -25,7 +51,7 @@ func TestCTG_Upload(t *testing.T) {
 	influx := lib.GetInfluxDB()
 
 	truncate := func(req *http.Request) {
-		F.Truncate(db, "measurement", "patient", "measurement_terminal")
+		F.Truncate(db, "measurement", "patient", "measurement_terminal", "hospital_key", "patient_identifier", "hospital_patient_rule")
 		influx.Delete("spiker", time.Unix(0, 0), time.Now(), "")
 	}
 
@@ -186,7 +212,7 @@ func TestCTG_Upload(t *testing.T) {
 					assert.EqualValues(t, exp.id, measurements[i+4].Id)
 					assert.EqualValues(t, exp.pid, measurements[i+4].PatientId)
 					assert.EqualValues(t, exp.tid, measurements[i+4].TerminalId)
-					assert.EqualValues(t, exp.code, measurements[i+4].Code)
+					assert.EqualValues(t, exp.code, openCode(t, db, 2, measurements[i+4].Code))
 					assert.EqualValues(t, beginTime.Add(time.Duration(exp.b)*time.Second).UnixNano(), measurements[i+4].FirstTime.UnixNano())
 					assert.EqualValues(t, beginTime.Add(time.Duration(exp.e)*time.Second).UnixNano(), measurements[i+4].LastTime.UnixNano())
 				}
@@ -211,15 +237,15 @@ func TestCTG_Upload(t *testing.T) {
 				assert.EqualValues(t, 9, len(hrs))
 
 				expectedHeartRates := []model.HeartRate{
-					model.HeartRate{5, "m0", "t0", 10, beginTime.Add(time.Duration(1)*time.Second)},
-					model.HeartRate{5, "m0", "t0", 20, beginTime.Add(time.Duration(2)*time.Second)},
-					model.HeartRate{5, "m0", "t0", 30, beginTime.Add(time.Duration(3)*time.Second)},
-					model.HeartRate{6, "m5", "t2", 40, beginTime.Add(time.Duration(4)*time.Second)},
-					model.HeartRate{6, "m5", "t2", 50, beginTime.Add(time.Duration(5)*time.Second)},
-					model.HeartRate{2, "m2", "t2", 60, beginTime.Add(time.Duration(6)*time.Second)},
-					model.HeartRate{2, "m2", "t2", 70, beginTime.Add(time.Duration(7)*time.Second)},
-					model.HeartRate{7, "m1", "t5", 80, beginTime.Add(time.Duration(8)*time.Second)},
-					model.HeartRate{8, "m6", "t1", 90, beginTime.Add(time.Duration(9)*time.Second)},
+					model.HeartRate{5, "", "t0", 10, beginTime.Add(time.Duration(1)*time.Second)},
+					model.HeartRate{5, "", "t0", 20, beginTime.Add(time.Duration(2)*time.Second)},
+					model.HeartRate{5, "", "t0", 30, beginTime.Add(time.Duration(3)*time.Second)},
+					model.HeartRate{6, "", "t2", 40, beginTime.Add(time.Duration(4)*time.Second)},
+					model.HeartRate{6, "", "t2", 50, beginTime.Add(time.Duration(5)*time.Second)},
+					model.HeartRate{2, "", "t2", 60, beginTime.Add(time.Duration(6)*time.Second)},
+					model.HeartRate{2, "", "t2", 70, beginTime.Add(time.Duration(7)*time.Second)},
+					model.HeartRate{7, "", "t5", 80, beginTime.Add(time.Duration(8)*time.Second)},
+					model.HeartRate{8, "", "t1", 90, beginTime.Add(time.Duration(9)*time.Second)},
 				}
 
 				for i, exp := range expectedHeartRates {
@@ -242,15 +268,15 @@ func TestCTG_Upload(t *testing.T) {
 				assert.EqualValues(t, 9, len(tocos))
 
 				expectedTOCOs := []model.TOCO{
-					model.TOCO{5, "m0", "t0", 100, beginTime.Add(time.Duration(1)*time.Second)},
-					model.TOCO{5, "m0", "t0", 200, beginTime.Add(time.Duration(2)*time.Second)},
-					model.TOCO{5, "m0", "t0", 300, beginTime.Add(time.Duration(3)*time.Second)},
-					model.TOCO{6, "m5", "t2", 400, beginTime.Add(time.Duration(4)*time.Second)},
-					model.TOCO{6, "m5", "t2", 500, beginTime.Add(time.Duration(5)*time.Second)},
-					model.TOCO{2, "m2", "t2", 600, beginTime.Add(time.Duration(6)*time.Second)},
-					model.TOCO{2, "m2", "t2", 700, beginTime.Add(time.Duration(7)*time.Second)},
-					model.TOCO{7, "m1", "t5", 800, beginTime.Add(time.Duration(8)*time.Second)},
-					model.TOCO{8, "m6", "t1", 900, beginTime.Add(time.Duration(9)*time.Second)},
+					model.TOCO{5, "", "t0", 100, beginTime.Add(time.Duration(1)*time.Second)},
+					model.TOCO{5, "", "t0", 200, beginTime.Add(time.Duration(2)*time.Second)},
+					model.TOCO{5, "", "t0", 300, beginTime.Add(time.Duration(3)*time.Second)},
+					model.TOCO{6, "", "t2", 400, beginTime.Add(time.Duration(4)*time.Second)},
+					model.TOCO{6, "", "t2", 500, beginTime.Add(time.Duration(5)*time.Second)},
+					model.TOCO{2, "", "t2", 600, beginTime.Add(time.Duration(6)*time.Second)},
+					model.TOCO{2, "", "t2", 700, beginTime.Add(time.Duration(7)*time.Second)},
+					model.TOCO{7, "", "t5", 800, beginTime.Add(time.Duration(8)*time.Second)},
+					model.TOCO{8, "", "t1", 900, beginTime.Add(time.Duration(9)*time.Second)},
 				}
 
 				for i, exp := range expectedTOCOs {
@@ -258,6 +284,297 @@ func TestCTG_Upload(t *testing.T) {
 				}
 			},
 		},
+		{
+			Name:    "識別子による患者の特定",
+			Method:  http.MethodPost,
+			Path:    "/ctg/data",
+			Token:   auth.Token(3),
+			Body:    test.JsonBody([]map[string]interface{}{
+				// 識別子が登録済みの患者の計測となる。
+				map[string]interface{}{
+					"Patient ID": "1001-a", "Machine ID": "t0", "Timestamp": timestamp(1),
+					"FHR1": "10", "UC": "100", "FHR2": "1000",
+				},
+				// 同じ識別子の計測は、同じ新規の患者の計測となる。
+				map[string]interface{}{
+					"Patient ID": "1002-a", "Machine ID": "t0", "Timestamp": timestamp(2),
+					"FHR1": "20", "UC": "200", "FHR2": "2000",
+				},
+				map[string]interface{}{
+					"Patient ID": "1002-b", "Machine ID": "t0", "Timestamp": timestamp(3),
+					"FHR1": "30", "UC": "300", "FHR2": "3000",
+				},
+				// 規則に一致しない計測は新規の患者となる。
+				map[string]interface{}{
+					"Patient ID": "x9", "Machine ID": "t0", "Timestamp": timestamp(4),
+					"FHR1": "40", "UC": "400", "FHR2": "4000",
+				},
+			}),
+			Prepare: test.Prepares(truncate, func(req *http.Request) {
+				F.Insert(db, model.Patient{}, 0, 1, func(i int, r F.Record) {
+					r["HospitalId"] = 2
+				})
+
+				db.Insert(
+					&model.HospitalPatientRule{HospitalId: 2, Pattern: `^(\d+)-`, ModifiedAt: time.Now()},
+					&model.PatientIdentifier{HospitalId: 2, PatientId: 1, Value: "1001", CreatedAt: time.Now()},
+				)
+			}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusCreated, rec.Code)
+				res := F.FromJsonResponse(t, rec, &uploadResponse{}).(*uploadResponse)
+
+				assert.EqualValues(t, 4, res.Success)
+				assert.EqualValues(t, 0, res.Failure)
+
+				assert.EqualValues(t, 3, F.Count(t, db, "patient", nil))
+
+				measurements := []*model.Measurement{}
+				F.Select(t, db, &measurements, F.NewQuery("").Asc("id"))
+
+				assert.EqualValues(t, 4, len(measurements))
+
+				for i, pid := range []int{1, 2, 2, 3} {
+					assert.EqualValues(t, pid, measurements[i].PatientId)
+				}
+
+				identifiers := []*model.PatientIdentifier{}
+				F.Select(t, db, &identifiers, F.NewQuery("").Asc("id"))
+
+				assert.EqualValues(t, 2, len(identifiers))
+				assert.EqualValues(t, 2, identifiers[1].PatientId)
+				assert.EqualValues(t, "1002", openCode(t, db, 2, identifiers[1].Value))
+			},
+		},
+		{
+			Name:    "割り当て中の端末",
+			Method:  http.MethodPost,
+			Path:    "/ctg/data",
+			Token:   auth.Token(3),
+			Body:    test.JsonBody([]map[string]interface{}{
+				// 割り当て中の端末のデータは、端末側の患者IDに関わらず割り当てた患者の計測となる。
+				map[string]interface{}{
+					"Patient ID": "m0", "Machine ID": "t1", "Timestamp": timestamp(1),
+					"FHR1": "10", "UC": "100", "FHR2": "1000",
+				},
+				map[string]interface{}{
+					"Patient ID": "typo", "Machine ID": "t1", "Timestamp": timestamp(2),
+					"FHR1": "20", "UC": "200", "FHR2": "2000",
+				},
+				// 割り当てられていない端末のデータは、端末側の患者IDの計測となる。
+				map[string]interface{}{
+					"Patient ID": "m0", "Machine ID": "t2", "Timestamp": timestamp(3),
+					"FHR1": "30", "UC": "300", "FHR2": "3000",
+				},
+			}),
+			Prepare: test.Prepares(truncate, func(req *http.Request) {
+				F.Truncate(db, "terminal_admission")
+
+				F.Insert(db, model.MeasurementTerminal{}, 0, 2, func(i int, r F.Record) {
+					r["HospitalId"] = 2
+					r["Code"] = fmt.Sprintf("t%d", i)
+				})
+
+				F.Insert(db, model.Patient{}, 0, 1, func(i int, r F.Record) {
+					r["HospitalId"] = 2
+				})
+
+				db.Insert(&model.TerminalAdmission{HospitalId: 2, TerminalId: 1, PatientId: 1, DoctorId: 1, AdmittedAt: time.Now()})
+			}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusCreated, rec.Code)
+				res := F.FromJsonResponse(t, rec, &uploadResponse{}).(*uploadResponse)
+
+				assert.EqualValues(t, 3, res.Success)
+				assert.EqualValues(t, 0, res.Failure)
+
+				measurements := []*model.Measurement{}
+				F.Select(t, db, &measurements, F.NewQuery("").Asc("id"))
+
+				assert.EqualValues(t, 2, len(measurements))
+
+				assert.EqualValues(t, 1, measurements[0].PatientId)
+				assert.EqualValues(t, 1, measurements[0].TerminalId)
+				assert.EqualValues(t, "admission-1", openCode(t, db, 2, measurements[0].Code))
+				assert.EqualValues(t, beginTime.Add(time.Duration(1)*time.Second).UnixNano(), measurements[0].FirstTime.UnixNano())
+				assert.EqualValues(t, beginTime.Add(time.Duration(2)*time.Second).UnixNano(), measurements[0].LastTime.UnixNano())
+
+				assert.EqualValues(t, 2, measurements[1].PatientId)
+				assert.EqualValues(t, "m0", openCode(t, db, 2, measurements[1].Code))
+
+				if a, e := db.Get(model.TerminalAdmission{}, 1); e != nil {
+					assert.FailNow(t, e.Error())
+				} else {
+					assert.EqualValues(t, 1, *a.(*model.TerminalAdmission).MeasurementId)
+				}
+			},
+		},
+		{
+			Name:    "完了した計測のコード",
+			Method:  http.MethodPost,
+			Path:    "/ctg/data",
+			Token:   auth.Token(3),
+			Body:    test.JsonBody([]map[string]interface{}{
+				// 無受信期間を空けて届いたデータは、同じ患者の新規の計測となる。
+				map[string]interface{}{
+					"Patient ID": "m1", "Machine ID": "t1", "Timestamp": timestamp(1),
+					"FHR1": "10", "UC": "100", "FHR2": "1000",
+				},
+				// 無受信期間内に届いたデータは、完了した計測を延長する。
+				map[string]interface{}{
+					"Patient ID": "m2", "Machine ID": "t2", "Timestamp": timestamp(2),
+					"FHR1": "20", "UC": "200", "FHR2": "2000",
+				},
+			}),
+			Prepare: test.Prepares(truncate, func(req *http.Request) {
+				F.Truncate(db, "hospital_auto_close")
+
+				F.Insert(db, model.MeasurementTerminal{}, 0, 2, func(i int, r F.Record) {
+					r["HospitalId"] = 2
+					r["Code"] = fmt.Sprintf("t%d", i)
+				})
+
+				F.Insert(db, model.Patient{}, 0, 2, func(i int, r F.Record) {
+					r["HospitalId"] = 2
+				})
+
+				F.Insert(db, model.Measurement{}, 0, 2, func(i int, r F.Record) {
+					r["Code"] = fmt.Sprintf("m%d", i)
+					r["PatientId"] = i
+					r["TerminalId"] = i
+					r["FirstTime"] = beginTime.Add(time.Duration(-3)*time.Hour)
+					r["LastTime"] = beginTime.Add(time.Duration(F.If(i == 1, -120, -10).(int))*time.Minute)
+					r["IsClosed"] = true
+				})
+
+				minutes := 60
+				db.Insert(&model.HospitalAutoClose{HospitalId: 2, Minutes: &minutes, ModifiedAt: time.Now()})
+			}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusCreated, rec.Code)
+				res := F.FromJsonResponse(t, rec, &uploadResponse{}).(*uploadResponse)
+
+				assert.EqualValues(t, 2, res.Success)
+				assert.EqualValues(t, 0, res.Failure)
+
+				measurements := []*model.Measurement{}
+				F.Select(t, db, &measurements, F.NewQuery("").Asc("id"))
+
+				assert.EqualValues(t, 3, len(measurements))
+
+				assert.EqualValues(t, beginTime.Add(time.Duration(-120)*time.Minute).UnixNano(), measurements[0].LastTime.UnixNano())
+
+				assert.EqualValues(t, beginTime.Add(time.Duration(2)*time.Second).UnixNano(), measurements[1].LastTime.UnixNano())
+
+				assert.EqualValues(t, 1, measurements[2].PatientId)
+				assert.EqualValues(t, 1, measurements[2].TerminalId)
+				assert.EqualValues(t, false, measurements[2].IsClosed)
+				assert.EqualValues(t, "m1", openCode(t, db, 2, measurements[2].Code))
+				assert.EqualValues(t, beginTime.Add(time.Duration(1)*time.Second).UnixNano(), measurements[2].FirstTime.UnixNano())
+			},
+		},
+		{
+			Name:    "自動完了を設定していない病院の完了した計測のコード",
+			Method:  http.MethodPost,
+			Path:    "/ctg/data",
+			Token:   auth.Token(3),
+			Body:    test.JsonBody([]map[string]interface{}{
+				// 既定の無受信期間を空けて届いたデータは、同じ患者の新規の計測となる。
+				map[string]interface{}{
+					"Patient ID": "m1", "Machine ID": "t1", "Timestamp": timestamp(1),
+					"FHR1": "10", "UC": "100", "FHR2": "1000",
+				},
+			}),
+			Prepare: test.Prepares(truncate, func(req *http.Request) {
+				F.Truncate(db, "hospital_auto_close")
+
+				F.Insert(db, model.MeasurementTerminal{}, 0, 1, func(i int, r F.Record) {
+					r["HospitalId"] = 2
+					r["Code"] = fmt.Sprintf("t%d", i)
+				})
+
+				F.Insert(db, model.Patient{}, 0, 1, func(i int, r F.Record) {
+					r["HospitalId"] = 2
+				})
+
+				F.Insert(db, model.Measurement{}, 0, 1, func(i int, r F.Record) {
+					r["Code"] = fmt.Sprintf("m%d", i)
+					r["PatientId"] = i
+					r["TerminalId"] = i
+					r["FirstTime"] = beginTime.Add(time.Duration(-30)*time.Hour)
+					r["LastTime"] = beginTime.Add(time.Duration(-24)*time.Hour)
+					r["IsClosed"] = true
+				})
+			}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusCreated, rec.Code)
+				res := F.FromJsonResponse(t, rec, &uploadResponse{}).(*uploadResponse)
+
+				assert.EqualValues(t, 1, res.Success)
+				assert.EqualValues(t, 0, res.Failure)
+
+				measurements := []*model.Measurement{}
+				F.Select(t, db, &measurements, F.NewQuery("").Asc("id"))
+
+				assert.EqualValues(t, 2, len(measurements))
+
+				assert.EqualValues(t, beginTime.Add(time.Duration(-24)*time.Hour).UnixNano(), measurements[0].LastTime.UnixNano())
+
+				assert.EqualValues(t, 1, measurements[1].PatientId)
+				assert.EqualValues(t, false, measurements[1].IsClosed)
+				assert.EqualValues(t, beginTime.Add(time.Duration(1)*time.Second).UnixNano(), measurements[1].FirstTime.UnixNano())
+			},
+		},
+		{
+			Name:    "自動完了しない病院の完了した計測のコード",
+			Method:  http.MethodPost,
+			Path:    "/ctg/data",
+			Token:   auth.Token(3),
+			Body:    test.JsonBody([]map[string]interface{}{
+				// 自動完了しないため、間を空けて届いたデータも完了した計測を延長する。
+				map[string]interface{}{
+					"Patient ID": "m1", "Machine ID": "t1", "Timestamp": timestamp(1),
+					"FHR1": "10", "UC": "100", "FHR2": "1000",
+				},
+			}),
+			Prepare: test.Prepares(truncate, func(req *http.Request) {
+				F.Truncate(db, "hospital_auto_close")
+
+				F.Insert(db, model.MeasurementTerminal{}, 0, 1, func(i int, r F.Record) {
+					r["HospitalId"] = 2
+					r["Code"] = fmt.Sprintf("t%d", i)
+				})
+
+				F.Insert(db, model.Patient{}, 0, 1, func(i int, r F.Record) {
+					r["HospitalId"] = 2
+				})
+
+				F.Insert(db, model.Measurement{}, 0, 1, func(i int, r F.Record) {
+					r["Code"] = fmt.Sprintf("m%d", i)
+					r["PatientId"] = i
+					r["TerminalId"] = i
+					r["FirstTime"] = beginTime.Add(time.Duration(-30)*time.Hour)
+					r["LastTime"] = beginTime.Add(time.Duration(-24)*time.Hour)
+					r["IsClosed"] = true
+				})
+
+				db.Insert(&model.HospitalAutoClose{HospitalId: 2, Minutes: nil, ModifiedAt: time.Now()})
+			}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusCreated, rec.Code)
+				res := F.FromJsonResponse(t, rec, &uploadResponse{}).(*uploadResponse)
+
+				assert.EqualValues(t, 1, res.Success)
+				assert.EqualValues(t, 0, res.Failure)
+
+				measurements := []*model.Measurement{}
+				F.Select(t, db, &measurements, F.NewQuery("").Asc("id"))
+
+				assert.EqualValues(t, 1, len(measurements))
+
+				assert.EqualValues(t, beginTime.Add(time.Duration(1)*time.Second).UnixNano(), measurements[0].LastTime.UnixNano())
+			},
+		},
 		{
 			Name:    "認証エラー",
 			Method:  http.MethodPost,
@@ -284,7 +601,7 @@ func TestCTG_UploadHeartRate(t *testing.T) {
 	influx := lib.GetInfluxDB()
 
 	truncate := func(req *http.Request) {
-		F.Truncate(db, "measurement", "patient", "measurement_terminal")
+		F.Truncate(db, "measurement", "patient", "measurement_terminal", "hospital_key")
 		influx.Delete("spiker", time.Unix(0, 0), time.Now(), "")
 	}
 
@@ -418,7 +735,7 @@ func TestCTG_UploadHeartRate(t *testing.T) {
 					assert.EqualValues(t, exp.id, measurements[i+4].Id)
 					assert.EqualValues(t, exp.pid, measurements[i+4].PatientId)
 					assert.EqualValues(t, exp.tid, measurements[i+4].TerminalId)
-					assert.EqualValues(t, exp.code, measurements[i+4].Code)
+					assert.EqualValues(t, exp.code, openCode(t, db, 2, measurements[i+4].Code))
 					assert.EqualValues(t, beginTime.Add(time.Duration(exp.b)*time.Second).UnixNano(), measurements[i+4].FirstTime.UnixNano())
 					assert.EqualValues(t, beginTime.Add(time.Duration(exp.e)*time.Second).UnixNano(), measurements[i+4].LastTime.UnixNano())
 				}
@@ -439,15 +756,15 @@ func TestCTG_UploadHeartRate(t *testing.T) {
 				assert.EqualValues(t, 9, len(points))
 
 				expectedPoints := []model.HeartRate{
-					model.HeartRate{5, "m0", "t0", 10, beginTime.Add(time.Duration(1)*time.Second)},
-					model.HeartRate{5, "m0", "t0", 20, beginTime.Add(time.Duration(2)*time.Second)},
-					model.HeartRate{5, "m0", "t0", 30, beginTime.Add(time.Duration(3)*time.Second)},
-					model.HeartRate{6, "m5", "t2", 40, beginTime.Add(time.Duration(4)*time.Second)},
-					model.HeartRate{6, "m5", "t2", 50, beginTime.Add(time.Duration(5)*time.Second)},
-					model.HeartRate{2, "m2", "t2", 60, beginTime.Add(time.Duration(6)*time.Second)},
-					model.HeartRate{2, "m2", "t2", 70, beginTime.Add(time.Duration(7)*time.Second)},
-					model.HeartRate{7, "m1", "t5", 80, beginTime.Add(time.Duration(8)*time.Second)},
-					model.HeartRate{8, "m6", "t1", 90, beginTime.Add(time.Duration(9)*time.Second)},
+					model.HeartRate{5, "", "t0", 10, beginTime.Add(time.Duration(1)*time.Second)},
+					model.HeartRate{5, "", "t0", 20, beginTime.Add(time.Duration(2)*time.Second)},
+					model.HeartRate{5, "", "t0", 30, beginTime.Add(time.Duration(3)*time.Second)},
+					model.HeartRate{6, "", "t2", 40, beginTime.Add(time.Duration(4)*time.Second)},
+					model.HeartRate{6, "", "t2", 50, beginTime.Add(time.Duration(5)*time.Second)},
+					model.HeartRate{2, "", "t2", 60, beginTime.Add(time.Duration(6)*time.Second)},
+					model.HeartRate{2, "", "t2", 70, beginTime.Add(time.Duration(7)*time.Second)},
+					model.HeartRate{7, "", "t5", 80, beginTime.Add(time.Duration(8)*time.Second)},
+					model.HeartRate{8, "", "t1", 90, beginTime.Add(time.Duration(9)*time.Second)},
 				}
 
 				for i, exp := range expectedPoints {
@@ -480,7 +797,7 @@ func TestCTG_UploadTOCO(t *testing.T) {
 	influx := lib.GetInfluxDB()
 
 	truncate := func(req *http.Request) {
-		F.Truncate(db, "measurement", "patient", "measurement_terminal")
+		F.Truncate(db, "measurement", "patient", "measurement_terminal", "hospital_key")
 		influx.Delete("spiker", time.Unix(0, 0), time.Now(), "")
 	}
 
@@ -614,7 +931,7 @@ func TestCTG_UploadTOCO(t *testing.T) {
 					assert.EqualValues(t, exp.id, measurements[i+4].Id)
 					assert.EqualValues(t, exp.pid, measurements[i+4].PatientId)
 					assert.EqualValues(t, exp.tid, measurements[i+4].TerminalId)
-					assert.EqualValues(t, exp.code, measurements[i+4].Code)
+					assert.EqualValues(t, exp.code, openCode(t, db, 2, measurements[i+4].Code))
 					assert.EqualValues(t, beginTime.Add(time.Duration(exp.b)*time.Second).UnixNano(), measurements[i+4].FirstTime.UnixNano())
 					assert.EqualValues(t, beginTime.Add(time.Duration(exp.e)*time.Second).UnixNano(), measurements[i+4].LastTime.UnixNano())
 				}
@@ -635,15 +952,15 @@ func TestCTG_UploadTOCO(t *testing.T) {
 				assert.EqualValues(t, 9, len(points))
 
 				expectedPoints := []model.TOCO{
-					model.TOCO{5, "m0", "t0", 10, beginTime.Add(time.Duration(1)*time.Second)},
-					model.TOCO{5, "m0", "t0", 20, beginTime.Add(time.Duration(2)*time.Second)},
-					model.TOCO{5, "m0", "t0", 30, beginTime.Add(time.Duration(3)*time.Second)},
-					model.TOCO{6, "m5", "t2", 40, beginTime.Add(time.Duration(4)*time.Second)},
-					model.TOCO{6, "m5", "t2", 50, beginTime.Add(time.Duration(5)*time.Second)},
-					model.TOCO{2, "m2", "t2", 60, beginTime.Add(time.Duration(6)*time.Second)},
-					model.TOCO{2, "m2", "t2", 70, beginTime.Add(time.Duration(7)*time.Second)},
-					model.TOCO{7, "m1", "t5", 80, beginTime.Add(time.Duration(8)*time.Second)},
-					model.TOCO{8, "m6", "t1", 90, beginTime.Add(time.Duration(9)*time.Second)},
+					model.TOCO{5, "", "t0", 10, beginTime.Add(time.Duration(1)*time.Second)},
+					model.TOCO{5, "", "t0", 20, beginTime.Add(time.Duration(2)*time.Second)},
+					model.TOCO{5, "", "t0", 30, beginTime.Add(time.Duration(3)*time.Second)},
+					model.TOCO{6, "", "t2", 40, beginTime.Add(time.Duration(4)*time.Second)},
+					model.TOCO{6, "", "t2", 50, beginTime.Add(time.Duration(5)*time.Second)},
+					model.TOCO{2, "", "t2", 60, beginTime.Add(time.Duration(6)*time.Second)},
+					model.TOCO{2, "", "t2", 70, beginTime.Add(time.Duration(7)*time.Second)},
+					model.TOCO{7, "", "t5", 80, beginTime.Add(time.Duration(8)*time.Second)},
+					model.TOCO{8, "", "t1", 90, beginTime.Add(time.Duration(9)*time.Second)},
 				}
 
 				for i, exp := range expectedPoints {
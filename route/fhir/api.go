@@ -0,0 +1,43 @@
+package fhir
+
+import (
+	"github.com/labstack/echo/v4"
+
+	app_middleware "github.com/spiker/spiker-server/route/middleware"
+	"github.com/spiker/spiker-server/route/shared"
+)
+
+// FHIR R4の読み取りAPIを登録する。認証はモニターAPIと同じく、医師のトークンを用いる。
+func RegisterAPI(e *echo.Echo) {
+	router := e.Group("/fhir")
+
+	router.Use(app_middleware.DoctorAuthentication()...)
+
+	registerAPIs(router)
+}
+
+func registerAPIs(router *echo.Group) {
+	// 患者。
+	router.GET("/Patient", shared.C(searchPatients))
+	router.GET("/Patient/:id", shared.C(readPatient))
+
+	// 計測端末。
+	router.GET("/Device", shared.C(searchDevices))
+	router.GET("/Device/:id", shared.C(readDevice))
+
+	// 計測記録。
+	router.GET("/Encounter", shared.C(searchEncounters))
+	router.GET("/Encounter/:id", shared.C(readEncounter))
+
+	// 心拍、TOCO。
+	router.GET("/Observation", shared.C(searchObservations))
+	router.GET("/Observation/:id", shared.C(readObservation))
+
+	// 診断。
+	router.GET("/DiagnosticReport", shared.C(searchDiagnosticReports))
+	router.GET("/DiagnosticReport/:id", shared.C(readDiagnosticReport))
+
+	// 自動診断イベント。
+	router.GET("/RiskAssessment", shared.C(searchRiskAssessments))
+	router.GET("/RiskAssessment/:id", shared.C(readRiskAssessment))
+}
@@ -0,0 +1,15 @@
+package fhir
+
+import (
+	"github.com/labstack/echo/v4"
+
+	"github.com/spiker/spiker-server/test"
+)
+
+func testHandler() *echo.Echo {
+	e := test.TestHandler()
+
+	RegisterAPI(e)
+
+	return e
+}
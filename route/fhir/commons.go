@@ -0,0 +1,109 @@
+package fhir
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	v "github.com/go-ozzo/ozzo-validation/v4"
+
+	C "github.com/spiker/spiker-server/constant"
+	fhir_ "github.com/spiker/spiker-server/lib/fhir"
+	"github.com/spiker/spiker-server/model"
+	S "github.com/spiker/spiker-server/service"
+	"github.com/spiker/spiker-server/route/shared"
+)
+
+func currentHospital(c *shared.Context) *model.Hospital {
+	return c.Get(shared.ContextMeKey).(*model.HospitalDoctor).Hospital
+}
+
+func invalidParameter(name string, e error) error {
+	return C.NewBadRequestError(
+		"invalid_parameter",
+		fmt.Sprintf("Search parameter '%s' is invalid: %s", name, e.Error()),
+		map[string]interface{}{},
+	)
+}
+
+// 検索パラメータを解析する。
+//
+// patient、encounterはIDもしくは"種別/ID"の形式、hospitalは病院UUIDもしくは"Organization/UUID"の形式とする。
+// dateは複数指定でき、いずれも満たす期間とする。
+func parseSearch(c *shared.Context) (*S.FHIRSearch, error) {
+	params := c.QueryParams()
+
+	search := &S.FHIRSearch{
+		Count: C.FHIRDefaultCount,
+		Offset: 0,
+	}
+
+	if value := params.Get("hospital"); value != "" {
+		if uuid, e := fhir_.ParseReference("Organization", value); e != nil {
+			return nil, invalidParameter("hospital", e)
+		} else {
+			search.HospitalUuid = &uuid
+		}
+	}
+
+	if value := params.Get("patient"); value != "" {
+		if id, e := fhir_.ParseIntReference("Patient", value); e != nil {
+			return nil, invalidParameter("patient", e)
+		} else {
+			search.PatientId = &id
+		}
+	}
+
+	if value := params.Get("encounter"); value != "" {
+		if id, e := fhir_.ParseIntReference("Encounter", value); e != nil {
+			return nil, invalidParameter("encounter", e)
+		} else {
+			search.MeasurementId = &id
+		}
+	}
+
+	if values := params["date"]; len(values) > 0 {
+		if begin, end, e := fhir_.ParseDateRange(values, shared.CurrentLocation); e != nil {
+			return nil, invalidParameter("date", e)
+		} else {
+			search.Begin, search.End = begin, end
+		}
+	}
+
+	if value := params.Get("_count"); value != "" {
+		if i, e := strconv.Atoi(value); e != nil {
+			return nil, invalidParameter("_count", e)
+		} else {
+			search.Count = i
+		}
+	}
+
+	if value := params.Get("_offset"); value != "" {
+		if i, e := strconv.Atoi(value); e != nil {
+			return nil, invalidParameter("_offset", e)
+		} else {
+			search.Offset = i
+		}
+	}
+
+	if e := (v.Errors{
+		"_count": v.Validate(search.Count, v.Min(0), v.Max(C.FHIRMaximumCount)),
+		"_offset": v.Validate(search.Offset, v.Min(0)),
+	}).Filter(); e != nil {
+		return nil, e
+	}
+
+	return search, nil
+}
+
+// リソースをFHIRのJSON形式で返す。
+func sendResource(c *shared.Context, resource interface{}) error {
+	bs, err := json.Marshal(resource)
+
+	if err != nil {
+		return err
+	}
+
+	return c.Blob(http.StatusOK, fhir_.ContentType, bs)
+}
@@ -0,0 +1,54 @@
+package fhir
+
+import (
+	S "github.com/spiker/spiker-server/service"
+	"github.com/spiker/spiker-server/route/shared"
+)
+
+// searchDevices godoc
+// @summary 計測端末をFHIRのDeviceとして検索する。
+// @tags [fhir] Device
+// @produce application/fhir+json
+// @param Authorization header string true "Bearerトークン。"
+// @param hospital query string false "病院UUID。"
+// @param _count query int false "最大取得件数。"
+// @param _offset query int false "取得オフセット。"
+// @success 200 {object} fhir_.Bundle "Deviceの検索結果。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /fhir/Device [get]
+func searchDevices(c *shared.Context) error {
+	search, err := parseSearch(c)
+
+	if err != nil {
+		return err
+	}
+
+	service := shared.CreateService(S.FHIRService{}, c).(*S.FHIRService)
+
+	if r, e := service.SearchDevices(currentHospital(c), search); e != nil {
+		return e
+	} else {
+		return sendResource(c, r)
+	}
+}
+
+// readDevice godoc
+// @summary 計測端末をFHIRのDeviceとして取得する。
+// @tags [fhir] Device
+// @produce application/fhir+json
+// @param Authorization header string true "Bearerトークン。"
+// @param id path int true "計測端末ID。"
+// @success 200 {object} fhir_.Device "Device。"
+// @failure 404 {object} shared.ErrorResponse "計測端末が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /fhir/Device/{id} [get]
+func readDevice(c *shared.Context) error {
+	service := shared.CreateService(S.FHIRService{}, c).(*S.FHIRService)
+
+	if r, e := service.FetchDevice(currentHospital(c), c.IntParam("id")); e != nil {
+		return e
+	} else {
+		return sendResource(c, r)
+	}
+}
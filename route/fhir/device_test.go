@@ -0,0 +1,64 @@
+package fhir
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/test"
+	F "github.com/spiker/spiker-server/test/fixture"
+)
+
+func TestFHIRDevice(t *testing.T) {
+	auth := (&F.MonitorFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	beginTime := time.Date(2021, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "端末検索",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/Device",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &bundleResponse{}).(*bundleResponse)
+
+				assert.EqualValues(t, 1, res.Total)
+				assert.Equal(t, []string{"1"}, res.ids())
+				assert.Equal(t, "terminal-0001", res.Entry[0].Resource["identifier"].([]interface{})[0].(map[string]interface{})["value"])
+			},
+		},
+		{
+			Name:    "端末取得",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/Device/1",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &resourceResponse{}).(*resourceResponse)
+
+				assert.Equal(t, "Device", res.ResourceType)
+			},
+		},
+		{
+			Name:    "病院が違う",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/Device/2",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		prepareFHIR(t, db, beginTime)
+	})
+}
@@ -0,0 +1,58 @@
+package fhir
+
+import (
+	S "github.com/spiker/spiker-server/service"
+	"github.com/spiker/spiker-server/route/shared"
+)
+
+// searchDiagnosticReports godoc
+// @summary 診断をFHIRのDiagnosticReportとして検索する。
+// @description `date` が指定されている場合、その期間に重なる診断に絞り込む。最大リスクを結論コードとする。
+// @tags [fhir] DiagnosticReport
+// @produce application/fhir+json
+// @param Authorization header string true "Bearerトークン。"
+// @param patient query string false "患者ID。"
+// @param encounter query string false "計測記録ID。"
+// @param date query []string false "期間。接頭辞ge、gt、le、lt、eqを付けられる。"
+// @param hospital query string false "病院UUID。"
+// @param _count query int false "最大取得件数。"
+// @param _offset query int false "取得オフセット。"
+// @success 200 {object} fhir_.Bundle "DiagnosticReportの検索結果。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /fhir/DiagnosticReport [get]
+func searchDiagnosticReports(c *shared.Context) error {
+	search, err := parseSearch(c)
+
+	if err != nil {
+		return err
+	}
+
+	service := shared.CreateService(S.FHIRService{}, c).(*S.FHIRService)
+
+	if r, e := service.SearchDiagnosticReports(currentHospital(c), search); e != nil {
+		return e
+	} else {
+		return sendResource(c, r)
+	}
+}
+
+// readDiagnosticReport godoc
+// @summary 診断をFHIRのDiagnosticReportとして取得する。
+// @tags [fhir] DiagnosticReport
+// @produce application/fhir+json
+// @param Authorization header string true "Bearerトークン。"
+// @param id path int true "診断ID。"
+// @success 200 {object} fhir_.DiagnosticReport "DiagnosticReport。"
+// @failure 404 {object} shared.ErrorResponse "診断が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /fhir/DiagnosticReport/{id} [get]
+func readDiagnosticReport(c *shared.Context) error {
+	service := shared.CreateService(S.FHIRService{}, c).(*S.FHIRService)
+
+	if r, e := service.FetchDiagnosticReport(currentHospital(c), c.IntParam("id")); e != nil {
+		return e
+	} else {
+		return sendResource(c, r)
+	}
+}
@@ -0,0 +1,87 @@
+package fhir
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/test"
+	F "github.com/spiker/spiker-server/test/fixture"
+)
+
+func TestFHIRDiagnosticReport(t *testing.T) {
+	auth := (&F.MonitorFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	beginTime := time.Date(2021, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "診断検索",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/DiagnosticReport",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &bundleResponse{}).(*bundleResponse)
+
+				assert.EqualValues(t, 2, res.Total)
+				assert.Equal(t, []string{"1", "2"}, res.ids())
+
+				report := res.Entry[0].Resource
+
+				assert.Equal(t, "Encounter/1", report["encounter"].(map[string]interface{})["reference"])
+				assert.Equal(t, "algorithm-0001", report["code"].(map[string]interface{})["coding"].([]interface{})[0].(map[string]interface{})["code"])
+				assert.Equal(t, "3", report["conclusionCode"].([]interface{})[0].(map[string]interface{})["coding"].([]interface{})[0].(map[string]interface{})["code"])
+			},
+		},
+		{
+			Name:    "患者と期間で絞り込み",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/DiagnosticReport",
+			Query:   func(q url.Values) {
+				q.Add("patient", "1")
+				q.Add("date", "ge2021-01-02T04:30:00Z")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &bundleResponse{}).(*bundleResponse)
+
+				assert.Equal(t, []string{"2"}, res.ids())
+			},
+		},
+		{
+			Name:    "診断取得",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/DiagnosticReport/2",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &resourceResponse{}).(*resourceResponse)
+
+				assert.Equal(t, "DiagnosticReport", res.ResourceType)
+				assert.Equal(t, "2", res.Id)
+			},
+		},
+		{
+			Name:    "病院が違う",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/DiagnosticReport/3",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		prepareFHIR(t, db, beginTime)
+	})
+}
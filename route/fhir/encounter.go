@@ -0,0 +1,57 @@
+package fhir
+
+import (
+	S "github.com/spiker/spiker-server/service"
+	"github.com/spiker/spiker-server/route/shared"
+)
+
+// searchEncounters godoc
+// @summary 計測記録をFHIRのEncounterとして検索する。
+// @description `date` が指定されている場合、その期間にデータのある計測に絞り込む。計測端末は拡張としてDeviceを参照する。
+// @tags [fhir] Encounter
+// @produce application/fhir+json
+// @param Authorization header string true "Bearerトークン。"
+// @param patient query string false "患者ID。"
+// @param date query []string false "期間。接頭辞ge、gt、le、lt、eqを付けられる。"
+// @param hospital query string false "病院UUID。"
+// @param _count query int false "最大取得件数。"
+// @param _offset query int false "取得オフセット。"
+// @success 200 {object} fhir_.Bundle "Encounterの検索結果。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /fhir/Encounter [get]
+func searchEncounters(c *shared.Context) error {
+	search, err := parseSearch(c)
+
+	if err != nil {
+		return err
+	}
+
+	service := shared.CreateService(S.FHIRService{}, c).(*S.FHIRService)
+
+	if r, e := service.SearchEncounters(currentHospital(c), search); e != nil {
+		return e
+	} else {
+		return sendResource(c, r)
+	}
+}
+
+// readEncounter godoc
+// @summary 計測記録をFHIRのEncounterとして取得する。
+// @tags [fhir] Encounter
+// @produce application/fhir+json
+// @param Authorization header string true "Bearerトークン。"
+// @param id path int true "計測記録ID。"
+// @success 200 {object} fhir_.Encounter "Encounter。"
+// @failure 404 {object} shared.ErrorResponse "計測記録が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /fhir/Encounter/{id} [get]
+func readEncounter(c *shared.Context) error {
+	service := shared.CreateService(S.FHIRService{}, c).(*S.FHIRService)
+
+	if r, e := service.FetchEncounter(currentHospital(c), c.IntParam("id")); e != nil {
+		return e
+	} else {
+		return sendResource(c, r)
+	}
+}
@@ -0,0 +1,148 @@
+package fhir
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/test"
+	F "github.com/spiker/spiker-server/test/fixture"
+)
+
+func TestFHIREncounter_Search(t *testing.T) {
+	auth := (&F.MonitorFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	beginTime := time.Date(2021, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "計測検索",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/Encounter",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &bundleResponse{}).(*bundleResponse)
+
+				assert.EqualValues(t, 3, res.Total)
+				assert.Equal(t, []string{"1", "2", "3"}, res.ids())
+
+				encounter := res.Entry[0].Resource
+
+				assert.Equal(t, "Encounter", encounter["resourceType"])
+				assert.Equal(t, "Patient/1", encounter["subject"].(map[string]interface{})["reference"])
+				assert.Equal(t, "measurement-0001", encounter["identifier"].([]interface{})[0].(map[string]interface{})["value"])
+				assert.Equal(t, "Device/1", encounter["extension"].([]interface{})[0].(map[string]interface{})["valueReference"].(map[string]interface{})["reference"])
+			},
+		},
+		{
+			Name:    "患者と期間で絞り込み",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/Encounter",
+			Query:   func(q url.Values) {
+				q.Add("patient", "Patient/1")
+				q.Add("date", "ge2021-01-02T04:30:00Z")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &bundleResponse{}).(*bundleResponse)
+
+				assert.EqualValues(t, 1, res.Total)
+				assert.Equal(t, []string{"2"}, res.ids())
+			},
+		},
+		{
+			Name:    "件数とオフセット",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/Encounter",
+			Query:   func(q url.Values) {
+				q.Add("_count", "1")
+				q.Add("_offset", "1")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &bundleResponse{}).(*bundleResponse)
+
+				assert.EqualValues(t, 3, res.Total)
+				assert.Equal(t, []string{"2"}, res.ids())
+			},
+		},
+		{
+			Name:    "他の病院の患者",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/Encounter",
+			Query:   func(q url.Values) {
+				q.Add("patient", "2")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &bundleResponse{}).(*bundleResponse)
+
+				assert.EqualValues(t, 0, res.Total)
+			},
+		},
+		{
+			Name:    "患者の参照が不正",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/Encounter",
+			Query:   func(q url.Values) {
+				q.Add("patient", "Device/1")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		prepareFHIR(t, db, beginTime)
+	})
+}
+
+func TestFHIREncounter_Read(t *testing.T) {
+	auth := (&F.MonitorFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	beginTime := time.Date(2021, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "計測取得",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/Encounter/1",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &resourceResponse{}).(*resourceResponse)
+
+				assert.Equal(t, "Encounter", res.ResourceType)
+				assert.Equal(t, "1", res.Id)
+			},
+		},
+		{
+			Name:    "病院が違う",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/Encounter/4",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		prepareFHIR(t, db, beginTime)
+	})
+}
@@ -0,0 +1,75 @@
+package fhir
+
+import (
+	"strings"
+
+	S "github.com/spiker/spiker-server/service"
+	"github.com/spiker/spiker-server/route/shared"
+)
+
+// searchObservations godoc
+// @summary 計測記録の心拍(FHR1、FHR2)とTOCOを、FHIRのObservationとして検索する。
+// @description 値は1秒毎のSampledDataとし、値の無い秒はEとする。件数とオフセットは計測記録に対して適用し、計測記録毎にチャネル数分のObservationを返す。
+// @description 出力期間は計測のデータの期間に収め、エクスポートと同じ上限を超える場合は末尾からの期間とする。データの無い計測記録は含めず、総数にも数えない。
+// @tags [fhir] Observation
+// @produce application/fhir+json
+// @param Authorization header string true "Bearerトークン。"
+// @param patient query string false "患者ID。"
+// @param encounter query string false "計測記録ID。"
+// @param code query string false "チャネル。fhr1、fhr2、ucのカンマ区切り。"
+// @param date query []string false "期間。接頭辞ge、gt、le、lt、eqを付けられる。"
+// @param hospital query string false "病院UUID。"
+// @param _count query int false "最大取得件数。計測記録単位で、10件を超える場合は10件とする。"
+// @param _offset query int false "取得オフセット。"
+// @success 200 {object} fhir_.Bundle "Observationの検索結果。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /fhir/Observation [get]
+func searchObservations(c *shared.Context) error {
+	search, err := parseSearch(c)
+
+	if err != nil {
+		return err
+	}
+
+	// トークンの"system|code"形式はコードのみを用いる。
+	codes := []string{}
+
+	if value := c.QueryParam("code"); value != "" {
+		for _, code := range strings.Split(value, ",") {
+			if i := strings.LastIndex(code, "|"); i >= 0 {
+				code = code[i+1:]
+			}
+			codes = append(codes, strings.ToLower(code))
+		}
+	}
+
+	service := shared.CreateService(S.FHIRService{}, c).(*S.FHIRService)
+
+	if r, e := service.SearchObservations(currentHospital(c), search, codes); e != nil {
+		return e
+	} else {
+		return sendResource(c, r)
+	}
+}
+
+// readObservation godoc
+// @summary 計測記録のチャネルを、計測全体のFHIRのObservationとして取得する。
+// @tags [fhir] Observation
+// @produce application/fhir+json
+// @param Authorization header string true "Bearerトークン。"
+// @param id path string true "計測記録IDとチャネルを-で繋いだもの。例: 12-fhr1"
+// @success 200 {object} fhir_.Observation "Observation。"
+// @failure 400 {object} shared.ErrorResponse "計測の期間が上限を超える。"
+// @failure 404 {object} shared.ErrorResponse "計測記録が存在しない、もしくはデータが無い。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /fhir/Observation/{id} [get]
+func readObservation(c *shared.Context) error {
+	service := shared.CreateService(S.FHIRService{}, c).(*S.FHIRService)
+
+	if r, e := service.FetchObservation(currentHospital(c), c.Param("id")); e != nil {
+		return e
+	} else {
+		return sendResource(c, r)
+	}
+}
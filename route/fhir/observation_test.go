@@ -0,0 +1,158 @@
+package fhir
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/test"
+	F "github.com/spiker/spiker-server/test/fixture"
+)
+
+func TestFHIRObservation(t *testing.T) {
+	auth := (&F.MonitorFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	beginTime := time.Date(2021, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	sampledData := func(resource map[string]interface{}) string {
+		return resource["valueSampledData"].(map[string]interface{})["data"].(string)
+	}
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "計測とチャネルで検索",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/Observation",
+			Query:   func(q url.Values) {
+				q.Add("encounter", "Encounter/1")
+				q.Add("code", "urn:spiker:ctg-channel|fhr1")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &bundleResponse{}).(*bundleResponse)
+
+				assert.Equal(t, []string{"1-fhr1"}, res.ids())
+				assert.Equal(t, "140 141 142 143 144 E 146 147 148 149", sampledData(res.Entry[0].Resource))
+			},
+		},
+		{
+			Name:    "全チャネル",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/Observation",
+			Query:   func(q url.Values) {
+				q.Add("encounter", "1")
+				q.Add("date", "ge2021-01-02T03:04:07Z")
+				q.Add("date", "lt2021-01-02T03:04:10Z")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &bundleResponse{}).(*bundleResponse)
+
+				assert.Equal(t, []string{"1-fhr1", "1-fhr2", "1-uc"}, res.ids())
+				assert.Equal(t, "142 143 144", sampledData(res.Entry[0].Resource))
+				assert.Equal(t, "E E E", sampledData(res.Entry[1].Resource))
+				assert.Equal(t, "22 23 24", sampledData(res.Entry[2].Resource))
+			},
+		},
+		{
+			Name:    "データの無い計測は総数に含めない",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/Observation",
+			Query:   func(q url.Values) {
+				q.Add("code", "fhr1")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &bundleResponse{}).(*bundleResponse)
+
+				assert.Equal(t, []string{"1-fhr1", "2-fhr1"}, res.ids())
+				assert.EqualValues(t, 2, res.Total)
+			},
+		},
+		{
+			Name:    "病院UUIDが違う",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/Observation",
+			Query:   func(q url.Values) {
+				q.Add("hospital", "unknown")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &bundleResponse{}).(*bundleResponse)
+
+				assert.EqualValues(t, 0, len(res.Entry))
+				assert.EqualValues(t, 0, res.Total)
+			},
+		},
+		{
+			Name:    "他の病院の計測",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/Observation",
+			Query:   func(q url.Values) {
+				q.Add("encounter", "4")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &bundleResponse{}).(*bundleResponse)
+
+				assert.EqualValues(t, 0, len(res.Entry))
+			},
+		},
+		{
+			Name:    "計測全体を取得",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/Observation/1-uc",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &map[string]interface{}{}).(*map[string]interface{})
+
+				assert.Equal(t, "1-uc", (*res)["id"])
+				assert.Equal(t, "20 21 22 23 24 E 26 27 28 29", sampledData(*res))
+			},
+		},
+		{
+			Name:    "データの無い計測",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/Observation/3-fhr1",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+		{
+			Name:    "チャネルが無い",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/Observation/1-spo2",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+		{
+			Name:    "病院が違う",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/Observation/4-fhr1",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		prepareFHIR(t, db, beginTime)
+	})
+}
@@ -0,0 +1,66 @@
+package fhir
+
+import (
+	fhir_ "github.com/spiker/spiker-server/lib/fhir"
+	S "github.com/spiker/spiker-server/service"
+	"github.com/spiker/spiker-server/route/shared"
+)
+
+// searchPatients godoc
+// @summary 患者をFHIRのPatientとして検索する。
+// @description `date` が指定されている場合、その期間に計測のあった患者に絞り込む。
+// @tags [fhir] Patient
+// @produce application/fhir+json
+// @param Authorization header string true "Bearerトークン。"
+// @param _id query string false "患者ID。"
+// @param date query []string false "期間。接頭辞ge、gt、le、lt、eqを付けられる。"
+// @param hospital query string false "病院UUID。"
+// @param _count query int false "最大取得件数。"
+// @param _offset query int false "取得オフセット。"
+// @success 200 {object} fhir_.Bundle "Patientの検索結果。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /fhir/Patient [get]
+func searchPatients(c *shared.Context) error {
+	search, err := parseSearch(c)
+
+	if err != nil {
+		return err
+	}
+
+	if value := c.QueryParam("_id"); value != "" {
+		if id, e := fhir_.ParseIntReference("Patient", value); e != nil {
+			return invalidParameter("_id", e)
+		} else {
+			search.PatientId = &id
+		}
+	}
+
+	service := shared.CreateService(S.FHIRService{}, c).(*S.FHIRService)
+
+	if r, e := service.SearchPatients(currentHospital(c), search); e != nil {
+		return e
+	} else {
+		return sendResource(c, r)
+	}
+}
+
+// readPatient godoc
+// @summary 患者をFHIRのPatientとして取得する。
+// @tags [fhir] Patient
+// @produce application/fhir+json
+// @param Authorization header string true "Bearerトークン。"
+// @param id path int true "患者ID。"
+// @success 200 {object} fhir_.Patient "Patient。"
+// @failure 404 {object} shared.ErrorResponse "患者が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /fhir/Patient/{id} [get]
+func readPatient(c *shared.Context) error {
+	service := shared.CreateService(S.FHIRService{}, c).(*S.FHIRService)
+
+	if r, e := service.FetchPatient(currentHospital(c), c.IntParam("id")); e != nil {
+		return e
+	} else {
+		return sendResource(c, r)
+	}
+}
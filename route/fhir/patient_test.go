@@ -0,0 +1,279 @@
+package fhir
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/gorp.v2"
+
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/test"
+	F "github.com/spiker/spiker-server/test/fixture"
+)
+
+type bundleResponse struct {
+	ResourceType string `json:"resourceType"`
+	Total        int64  `json:"total"`
+	Entry        []struct {
+		Resource map[string]interface{} `json:"resource"`
+	} `json:"entry"`
+}
+
+type resourceResponse struct {
+	ResourceType string `json:"resourceType"`
+	Id           string `json:"id"`
+}
+
+func (b *bundleResponse) ids() []string {
+	ids := []string{}
+
+	for _, e := range b.Entry {
+		ids = append(ids, e.Resource["id"].(string))
+	}
+
+	return ids
+}
+
+// FHIR APIのテストデータを作成する。
+//
+// h - p - m
+// 1 - 1 - [1,2,3]
+// 2 - 2 - [4]
+// 3 - 3 - []
+//
+// 計測1はbeginTimeから10秒、計測2はbeginTimeの1時間後から1時間のデータを持ち、計測3はデータを持たない。
+func prepareFHIR(t *testing.T, db *gorp.DbMap, beginTime time.Time) {
+	F.Truncate(db, "diagnosis_algorithm", "computed_diagnosis", "diagnosis", "computed_event", "measurement_terminal", "patient", "measurement")
+
+	patients := F.Insert(db, model.Patient{}, 0, 3, func(i int, r F.Record) {
+		r["HospitalId"] = i
+		r["Name"] = fmt.Sprintf("patient-%04d", i)
+	}).([]*model.Patient)
+
+	terminals := F.Insert(db, model.MeasurementTerminal{}, 0, 3, func(i int, r F.Record) {
+		r["HospitalId"] = i
+		r["Code"] = fmt.Sprintf("terminal-%04d", i)
+	}).([]*model.MeasurementTerminal)
+
+	firstTimes := []interface{}{}
+	lastTimes := []interface{}{}
+
+	for _, r := range [][2]time.Duration{{0, 9 * time.Second}, {time.Hour, 2 * time.Hour}, {-1, -1}, {0, time.Hour}} {
+		if r[0] < 0 {
+			firstTimes = append(firstTimes, nil)
+			lastTimes = append(lastTimes, nil)
+		} else {
+			firstTimes = append(firstTimes, beginTime.Add(r[0]))
+			lastTimes = append(lastTimes, beginTime.Add(r[1]))
+		}
+	}
+
+	measurements := F.Insert(db, model.Measurement{}, 0, 4, func(i int, r F.Record) {
+		r["Code"] = fmt.Sprintf("measurement-%04d", i)
+		r["PatientId"] = F.If(i <= 3, patients[0].Id, patients[1].Id)
+		r["TerminalId"] = F.If(i <= 3, terminals[0].Id, terminals[1].Id)
+		r["FirstTime"] = firstTimes[i-1]
+		r["LastTime"] = lastTimes[i-1]
+	}).([]*model.Measurement)
+
+	// m - d
+	// 1 - [1,2]
+	// 4 - [3]
+	diagnoses := F.Insert(db, model.Diagnosis{}, 0, 3, func(i int, r F.Record) {
+		r["MeasurementId"] = measurements[F.If(i <= 2, 0, 3).(int)].Id
+		r["MaximumRisk"] = i + 2
+		r["RangeFrom"] = beginTime.Add(time.Duration(i-1) * time.Hour)
+		r["RangeUntil"] = beginTime.Add(time.Duration(i) * time.Hour)
+	}).([]*model.Diagnosis)
+
+	algorithms := F.Insert(db, model.DiagnosisAlgorithm{}, 0, 1, func(i int, r F.Record) {
+		r["Name"] = "algorithm-0001"
+	}).([]*model.DiagnosisAlgorithm)
+
+	F.Insert(db, model.ComputedDiagnosis{}, 0, 3, func(i int, r F.Record) {
+		r["DiagnosisId"] = diagnoses[i-1].Id
+		r["AlgorithmId"] = algorithms[0].Id
+	})
+
+	// m - ce
+	// 1 - [1,2(非表示)]
+	// 4 - [3]
+	F.Insert(db, model.ComputedEvent{}, 0, 3, func(i int, r F.Record) {
+		r["MeasurementId"] = measurements[F.If(i <= 2, 0, 3).(int)].Id
+		r["Risk"] = i + 2
+		r["IsHidden"] = i == 2
+		r["RangeFrom"] = beginTime.Add(time.Duration(i-1) * time.Hour)
+		r["RangeUntil"] = beginTime.Add(time.Duration(i-1)*time.Hour + time.Duration(30)*time.Minute)
+		r["Parameters"] = model.JSON([]byte("{}"))
+	})
+
+	influx := lib.GetInfluxDB()
+
+	assert.NoError(t, influx.Delete("spiker", time.Unix(0, 0), time.Now().Add(time.Duration(24*365*100)*time.Hour), ""))
+
+	points := []lib.Point{}
+
+	// 計測1の5秒目は値を持たない。
+	for i := 0; i < 10; i++ {
+		if i == 5 {
+			continue
+		}
+		points = append(points, &model.HeartRate{
+			MeasurementId: measurements[0].Id, PatientCode: "p", MachineCode: "m",
+			Value: 140 + i, Timestamp: beginTime.Add(time.Duration(i) * time.Second),
+		})
+		points = append(points, &model.TOCO{
+			MeasurementId: measurements[0].Id, PatientCode: "p", MachineCode: "m",
+			Value: 20 + i, Timestamp: beginTime.Add(time.Duration(i) * time.Second),
+		})
+	}
+
+	influx.Insert("spiker", points...)
+}
+
+func TestFHIRPatient_Search(t *testing.T) {
+	auth := (&F.MonitorFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	beginTime := time.Date(2021, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "患者検索",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/Patient",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.Contains(t, rec.Header().Get("Content-Type"), "application/fhir+json")
+
+				res := F.FromJsonResponse(t, rec, &bundleResponse{}).(*bundleResponse)
+
+				assert.Equal(t, "Bundle", res.ResourceType)
+				assert.EqualValues(t, 1, res.Total)
+				assert.Equal(t, []string{"1"}, res.ids())
+
+				patient := res.Entry[0].Resource
+
+				assert.Equal(t, "Patient", patient["resourceType"])
+				assert.Equal(t, "patient-0001", patient["name"].([]interface{})[0].(map[string]interface{})["text"])
+				assert.Equal(t, "Organization/hospital-0001", patient["managingOrganization"].(map[string]interface{})["reference"])
+			},
+		},
+		{
+			Name:    "期間で絞り込み",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/Patient",
+			Query:   func(q url.Values) {
+				q.Add("date", "ge2021-01-03")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &bundleResponse{}).(*bundleResponse)
+
+				assert.EqualValues(t, 0, res.Total)
+			},
+		},
+		{
+			Name:    "IDで絞り込み",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/Patient",
+			Query:   func(q url.Values) {
+				q.Add("_id", "2")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &bundleResponse{}).(*bundleResponse)
+
+				// 他の病院の患者は含まれない。
+				assert.EqualValues(t, 0, res.Total)
+			},
+		},
+		{
+			Name:    "病院が違う",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/Patient",
+			Query:   func(q url.Values) {
+				q.Add("hospital", "Organization/hospital-0002")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &bundleResponse{}).(*bundleResponse)
+
+				assert.EqualValues(t, 0, res.Total)
+				assert.EqualValues(t, 0, len(res.Entry))
+			},
+		},
+		{
+			Name:    "日付が不正",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/Patient",
+			Query:   func(q url.Values) {
+				q.Add("date", "2021/01/02")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			Name:    "認証が無い",
+			Method:  http.MethodGet,
+			Path:    "/fhir/Patient",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.NotEqual(t, http.StatusOK, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		prepareFHIR(t, db, beginTime)
+	})
+}
+
+func TestFHIRPatient_Read(t *testing.T) {
+	auth := (&F.MonitorFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	beginTime := time.Date(2021, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "患者取得",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/Patient/1",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &resourceResponse{}).(*resourceResponse)
+
+				assert.Equal(t, "Patient", res.ResourceType)
+				assert.Equal(t, "1", res.Id)
+			},
+		},
+		{
+			Name:    "病院が違う",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/Patient/2",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		prepareFHIR(t, db, beginTime)
+	})
+}
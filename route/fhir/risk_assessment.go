@@ -0,0 +1,58 @@
+package fhir
+
+import (
+	S "github.com/spiker/spiker-server/service"
+	"github.com/spiker/spiker-server/route/shared"
+)
+
+// searchRiskAssessments godoc
+// @summary 自動診断イベントをFHIRのRiskAssessmentとして検索する。
+// @description 非表示のイベントは含めない。`date` が指定されている場合、その期間に重なるイベントに絞り込む。
+// @tags [fhir] RiskAssessment
+// @produce application/fhir+json
+// @param Authorization header string true "Bearerトークン。"
+// @param patient query string false "患者ID。"
+// @param encounter query string false "計測記録ID。"
+// @param date query []string false "期間。接頭辞ge、gt、le、lt、eqを付けられる。"
+// @param hospital query string false "病院UUID。"
+// @param _count query int false "最大取得件数。"
+// @param _offset query int false "取得オフセット。"
+// @success 200 {object} fhir_.Bundle "RiskAssessmentの検索結果。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /fhir/RiskAssessment [get]
+func searchRiskAssessments(c *shared.Context) error {
+	search, err := parseSearch(c)
+
+	if err != nil {
+		return err
+	}
+
+	service := shared.CreateService(S.FHIRService{}, c).(*S.FHIRService)
+
+	if r, e := service.SearchRiskAssessments(currentHospital(c), search); e != nil {
+		return e
+	} else {
+		return sendResource(c, r)
+	}
+}
+
+// readRiskAssessment godoc
+// @summary 自動診断イベントをFHIRのRiskAssessmentとして取得する。
+// @tags [fhir] RiskAssessment
+// @produce application/fhir+json
+// @param Authorization header string true "Bearerトークン。"
+// @param id path int true "自動診断イベントID。"
+// @success 200 {object} fhir_.RiskAssessment "RiskAssessment。"
+// @failure 404 {object} shared.ErrorResponse "イベントが存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /fhir/RiskAssessment/{id} [get]
+func readRiskAssessment(c *shared.Context) error {
+	service := shared.CreateService(S.FHIRService{}, c).(*S.FHIRService)
+
+	if r, e := service.FetchRiskAssessment(currentHospital(c), c.IntParam("id")); e != nil {
+		return e
+	} else {
+		return sendResource(c, r)
+	}
+}
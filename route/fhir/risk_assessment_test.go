@@ -0,0 +1,79 @@
+package fhir
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/test"
+	F "github.com/spiker/spiker-server/test/fixture"
+)
+
+func TestFHIRRiskAssessment(t *testing.T) {
+	auth := (&F.MonitorFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	beginTime := time.Date(2021, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "イベント検索",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/RiskAssessment",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &bundleResponse{}).(*bundleResponse)
+
+				// 非表示のイベントは含まれない。
+				assert.EqualValues(t, 1, res.Total)
+				assert.Equal(t, []string{"1"}, res.ids())
+
+				prediction := res.Entry[0].Resource["prediction"].([]interface{})[0].(map[string]interface{})
+				coding := prediction["qualitativeRisk"].(map[string]interface{})["coding"].([]interface{})[0].(map[string]interface{})
+
+				assert.Equal(t, "3", coding["code"])
+				assert.Equal(t, "R3", coding["display"])
+			},
+		},
+		{
+			Name:    "イベント取得",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/RiskAssessment/1",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &resourceResponse{}).(*resourceResponse)
+
+				assert.Equal(t, "RiskAssessment", res.ResourceType)
+			},
+		},
+		{
+			Name:    "非表示のイベント",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/RiskAssessment/2",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+		{
+			Name:    "病院が違う",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/fhir/RiskAssessment/3",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		prepareFHIR(t, db, beginTime)
+	})
+}
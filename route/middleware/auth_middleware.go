@@ -3,12 +3,19 @@ package middleware
 import (
 	"regexp"
 
+	jwt_ "github.com/dgrijalva/jwt-go"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/lib"
+	S "github.com/spiker/spiker-server/service"
+	"github.com/spiker/spiker-server/route/shared"
 )
 
 const (
 	authScheme string = "Bearer"
+	jwtTokenKey string = "token"
 )
 
 func jwtFromHeader(c echo.Context, header string, authScheme string) (string, error) {
@@ -40,3 +47,50 @@ func (p SkipPatterns) Match(c echo.Context) bool {
 	}
 	return false
 }
+
+// 医師のトークンを検証し、認証した医師をコンテキストに設定するミドルウェア。モニターAPIとFHIR APIで共用する。
+func DoctorAuthentication() []echo.MiddlewareFunc {
+	return []echo.MiddlewareFunc{
+		middleware.JWTWithConfig(middleware.JWTConfig{
+			ContextKey: jwtTokenKey,
+			SigningKey: []byte(lib.GetSecret()),
+		}),
+		func(next echo.HandlerFunc) echo.HandlerFunc {
+			return func(c echo.Context) error {
+				oldToken, ok := c.Get(jwtTokenKey).(*jwt_.Token)
+
+				if !ok {
+					return C.NewUnauthorizedError(
+						"token_not_found",
+						"Token was parsed but missed unexpectedly",
+						map[string]interface{}{},
+					)
+				}
+
+				me, err := lib.ConvertAndAuthenticate(oldToken, func(authId string, version string) (interface{}, error) {
+					service := shared.CreateService(S.DoctorService{}, c).(*S.DoctorService)
+
+					doctor, err := service.Authenticate(authId, version)
+
+					return doctor, err
+				})
+
+				if err != nil {
+					if e, ok := err.(C.AppError); ok {
+						return e
+					} else {
+						return C.NewUnauthorizedError(
+							"Unauthorized",
+							e.Error(),
+							map[string]interface{}{},
+						)
+					}
+				}
+
+				c.Set(shared.ContextMeKey, me)
+
+				return next(c)
+			}
+		},
+	}
+}
@@ -0,0 +1,143 @@
+package monitor
+
+import (
+	"net/http"
+
+	v "github.com/go-ozzo/ozzo-validation/v4"
+
+	"github.com/spiker/spiker-server/model"
+	S "github.com/spiker/spiker-server/service"
+	"github.com/spiker/spiker-server/route/shared"
+)
+
+type listAdmissionsResponse struct {
+	Admissions []*model.TerminalAdmission `json:"admissions"`
+}
+
+// listAdmissions godoc
+// @summary 計測端末に割り当て中の患者一覧を取得する。
+// @tags [monitor] Admission
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @success 200 {object} listAdmissionsResponse "割り当て一覧。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /1/admissions [get]
+func listAdmissions(c *shared.Context) error {
+	me := c.Get(shared.ContextMeKey).(*model.HospitalDoctor)
+
+	service := shared.CreateService(S.AdmissionService{}, c).(*S.AdmissionService)
+
+	results, err := service.List(me.Hospital.Id)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &listAdmissionsResponse{results})
+}
+
+type fetchAdmissionResponse struct {
+	Admission *model.TerminalAdmission `json:"admission"`
+}
+
+// fetchAdmission godoc
+// @summary 計測端末に割り当て中の患者を取得する。
+// @description 割り当てられていない場合、`admission`はnullとなる。
+// @tags [monitor] Admission
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param terminal_id path int true "計測端末ID。"
+// @success 200 {object} fetchAdmissionResponse "割り当て。"
+// @failure 404 {object} shared.ErrorResponse "計測端末が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /1/terminals/{terminal_id}/admission [get]
+func fetchAdmission(c *shared.Context) error {
+	me := c.Get(shared.ContextMeKey).(*model.HospitalDoctor)
+
+	id := c.IntParam("terminal_id")
+
+	service := shared.CreateService(S.AdmissionService{}, c).(*S.AdmissionService)
+
+	result, err := service.Fetch(me.Hospital.Id, id)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &fetchAdmissionResponse{result})
+}
+
+type admitPatientBody struct {
+	PatientId   *int    `json:"patientId"`
+	WristbandId *string `json:"wristbandId" maxLength:"128"`
+	Name        *string `json:"name" maxLength:"64"`
+}
+
+// admitPatient godoc
+// @summary 患者を計測端末に割り当てる。
+// @description 退院するまでの間、端末から送られた計測データは端末側の患者IDに関わらず割り当てた患者の計測となる。`patientId`を指定した場合は既存の患者を、`wristbandId`を指定した場合はその識別子を持つ患者を割り当て、識別子を持つ患者がいない場合やいずれも指定しない場合は新規の患者を登録して割り当てる。
+// @tags [monitor] Admission
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param terminal_id path int true "計測端末ID。"
+// @param admission body admitPatientBody true "割り当てる患者。"
+// @success 201 {object} model.TerminalAdmission "割り当て。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー、もしくは端末に割り当て中の患者がいる。"
+// @failure 404 {object} shared.ErrorResponse "計測端末もしくは患者が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /1/terminals/{terminal_id}/admission [post]
+func admitPatient(c *shared.Context) error {
+	me := c.Get(shared.ContextMeKey).(*model.HospitalDoctor)
+
+	id := c.IntParam("terminal_id")
+
+	body := &admitPatientBody{}
+
+	if e := c.Bind(body); e != nil {
+		return e
+	}
+
+	if e := (v.Errors{
+		"wristbandId": v.Validate(body.WristbandId, v.When(body.PatientId != nil, v.Nil), v.NilOrNotEmpty, v.RuneLength(1, 128)),
+		"name": v.Validate(body.Name, v.When(body.PatientId != nil, v.Nil), v.RuneLength(0, 64)),
+	}).Filter(); e != nil {
+		return e
+	}
+
+	service := shared.CreateService(S.AdmissionTxService{}, c).(*S.AdmissionTxService)
+
+	result, err := service.Admit(me, id, body.PatientId, body.WristbandId, body.Name)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, result)
+}
+
+// dischargePatient godoc
+// @summary 患者を計測端末から退院させる。
+// @description 割り当て中に開始した計測は完了状態となり、以降の計測データは端末側の患者IDの計測となる。
+// @tags [monitor] Admission
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param terminal_id path int true "計測端末ID。"
+// @success 200 {object} model.TerminalAdmission "退院した割り当て。"
+// @failure 404 {object} shared.ErrorResponse "計測端末が存在しない、もしくは割り当て中の患者がいない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /1/terminals/{terminal_id}/admission [delete]
+func dischargePatient(c *shared.Context) error {
+	me := c.Get(shared.ContextMeKey).(*model.HospitalDoctor)
+
+	id := c.IntParam("terminal_id")
+
+	service := shared.CreateService(S.AdmissionTxService{}, c).(*S.AdmissionTxService)
+
+	result, err := service.Discharge(me.Hospital.Id, id)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,223 @@
+package monitor
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/test"
+	F "github.com/spiker/spiker-server/test/fixture"
+)
+
+func TestMonitorAdmission(t *testing.T) {
+	auth := (&F.MonitorFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "割り当て一覧",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/admissions",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &listAdmissionsResponse{}).(*listAdmissionsResponse)
+
+				assert.EqualValues(t, 1, len(res.Admissions))
+				assert.EqualValues(t, 2, res.Admissions[0].TerminalId)
+				assert.EqualValues(t, 1, res.Admissions[0].PatientId)
+			},
+		},
+		{
+			Name:    "割り当て中",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/terminals/2/admission",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &fetchAdmissionResponse{}).(*fetchAdmissionResponse)
+
+				assert.EqualValues(t, 1, res.Admission.PatientId)
+			},
+		},
+		{
+			Name:    "割り当て無し",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/terminals/1/admission",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &fetchAdmissionResponse{}).(*fetchAdmissionResponse)
+
+				assert.Nil(t, res.Admission)
+			},
+		},
+		{
+			Name:    "既存の患者を割り当て",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/terminals/1/admission",
+			Body:    test.JsonBody(map[string]interface{}{"patientId": 3}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusCreated, rec.Code)
+				res := F.FromJsonResponse(t, rec, &model.TerminalAdmission{}).(*model.TerminalAdmission)
+
+				assert.EqualValues(t, 1, res.TerminalId)
+				assert.EqualValues(t, 3, res.PatientId)
+				assert.Nil(t, res.MeasurementId)
+				assert.Nil(t, res.DischargedAt)
+			},
+		},
+		{
+			Name:    "リストバンドで割り当て",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/terminals/1/admission",
+			Body:    test.JsonBody(map[string]interface{}{"wristbandId": "W-0001"}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusCreated, rec.Code)
+				res := F.FromJsonResponse(t, rec, &model.TerminalAdmission{}).(*model.TerminalAdmission)
+
+				assert.EqualValues(t, 3, res.PatientId)
+				assert.EqualValues(t, 3, F.Count(t, db, "patient", nil))
+			},
+		},
+		{
+			Name:    "未登録のリストバンドで割り当て",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/terminals/1/admission",
+			Body:    test.JsonBody(map[string]interface{}{"wristbandId": "W-0002", "name": "患者"}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusCreated, rec.Code)
+				res := F.FromJsonResponse(t, rec, &model.TerminalAdmission{}).(*model.TerminalAdmission)
+
+				assert.EqualValues(t, 4, res.PatientId)
+				assert.EqualValues(t, 4, F.Count(t, db, "patient", nil))
+				assert.EqualValues(t, 2, F.Count(t, db, "patient_identifier", nil))
+			},
+		},
+		{
+			Name:    "新規の患者を割り当て",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/terminals/1/admission",
+			Body:    test.JsonBody(map[string]interface{}{}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusCreated, rec.Code)
+				res := F.FromJsonResponse(t, rec, &model.TerminalAdmission{}).(*model.TerminalAdmission)
+
+				assert.EqualValues(t, 4, res.PatientId)
+			},
+		},
+		{
+			Name:    "割り当て中の端末",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/terminals/2/admission",
+			Body:    test.JsonBody(map[string]interface{}{"patientId": 3}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			Name:    "患者の病院が違う",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/terminals/1/admission",
+			Body:    test.JsonBody(map[string]interface{}{"patientId": 2}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+		{
+			Name:    "患者とリストバンドを両方指定",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/terminals/1/admission",
+			Body:    test.JsonBody(map[string]interface{}{"patientId": 3, "wristbandId": "W-0001"}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			Name:    "端末の病院が違う",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/terminals/3/admission",
+			Body:    test.JsonBody(map[string]interface{}{"patientId": 3}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+		{
+			Name:    "退院",
+			Method:  http.MethodDelete,
+			Token:   auth.Token(0),
+			Path:    "/1/terminals/2/admission",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &model.TerminalAdmission{}).(*model.TerminalAdmission)
+
+				assert.NotNil(t, res.DischargedAt)
+
+				if a, e := db.Get(model.Measurement{}, 1); e != nil {
+					assert.FailNow(t, e.Error())
+				} else {
+					m := a.(*model.Measurement)
+					assert.EqualValues(t, true, m.IsClosed)
+					assert.NotNil(t, m.ClosedAt)
+				}
+			},
+		},
+		{
+			Name:    "割り当て無しで退院",
+			Method:  http.MethodDelete,
+			Token:   auth.Token(0),
+			Path:    "/1/terminals/1/admission",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		F.Truncate(db, "measurement_terminal", "patient", "patient_key", "hospital_key", "measurement", "patient_identifier", "terminal_admission")
+
+		// h - p
+		// 1 - [1,3]
+		// 2 - [2]
+		F.Insert(db, model.Patient{}, 0, 3, func(i int, r F.Record) {
+			r["HospitalId"] = F.If(i%2 == 1, 1, 2)
+		})
+
+		// h - t
+		// 1 - [1,2]
+		// 2 - [3]
+		F.Insert(db, model.MeasurementTerminal{}, 0, 3, func(i int, r F.Record) {
+			r["HospitalId"] = F.If(i <= 2, 1, 2)
+			r["Code"] = fmt.Sprintf("t%d", i)
+		})
+
+		F.Insert(db, model.Measurement{}, 0, 1, func(i int, r F.Record) {
+			r["Code"] = "admission-1"
+			r["PatientId"] = 1
+			r["TerminalId"] = 2
+			r["IsClosed"] = false
+		})
+
+		measurementId := 1
+
+		db.Insert(
+			&model.TerminalAdmission{HospitalId: 1, TerminalId: 2, PatientId: 1, DoctorId: 1, MeasurementId: &measurementId, AdmittedAt: time.Now()},
+			&model.PatientIdentifier{HospitalId: 1, PatientId: 3, Value: "W-0001", CreatedAt: time.Now()},
+		)
+	})
+}
@@ -26,6 +26,7 @@ type collectAlertsResponse struct {
 // @summary アラートのためのポーリングを受け付ける。
 // @description 指定した計測それぞれに関する最新のアノテーションを取得する。
 // @description レスポンスは、区間の古い順のアノテーションリスト。計測との対応は各アノテーションの`measurement`からとる。
+// @description 各アノテーションの`isAlert`は、病院がアラートに用いるガイドラインでアラート対象となるかを表す。
 // @tags [monitor] Alert
 // @produce json
 // @param Authorization header string true "Bearerトークン。"
@@ -54,9 +55,11 @@ func collectAlerts(c *shared.Context) error {
 		}
 	}
 
+	me := c.Get(shared.ContextMeKey).(*model.HospitalDoctor)
+
 	service := shared.CreateService(S.EventService{}, c).(*S.EventService)
 
-	annotations, err := service.ListAlertEvents(query.Measurements)
+	annotations, err := service.ListAlertEvents(me.Hospital.Id, query.Measurements)
 
 	if err != nil {
 		return err
@@ -13,7 +13,7 @@ import (
 	"gopkg.in/gorp.v2"
 	"github.com/stretchr/testify/assert"
 
-	//C "github.com/spiker/spiker-server/constant"
+	C "github.com/spiker/spiker-server/constant"
 	//"github.com/spiker/spiker-server/route/shared"
 	"github.com/spiker/spiker-server/lib"
 	"github.com/spiker/spiker-server/model"
@@ -22,7 +22,7 @@ import (
 )
 
 func prepareAlerts(t *testing.T, db *gorp.DbMap, beginTime time.Time) func(int, *model.AnnotatedEventEntity) {
-	F.Truncate(db, "annotated_event", "computed_event", "measurement_terminal", "patient", "measurement")
+	F.Truncate(db, "hospital_guideline", "computed_event_classification", "annotated_event", "computed_event", "measurement_terminal", "patient", "measurement")
 
 	patients := F.Insert(db, model.Patient{}, 0, 3, func(i int, r F.Record) {
 		r["HospitalId"] = i
@@ -243,6 +243,12 @@ func TestMonitorAlert_Collect(t *testing.T) {
 				for i, m := range res.Annotations {
 					verifyAnnotated(expected[i], m)
 				}
+
+				// JSOGではリスク3以上がアラート対象。
+				alerts := []bool{false, false, true, false}
+				for i, m := range res.Annotations {
+					assert.EqualValues(t, alerts[i], *m.IsAlert)
+				}
 			},
 		},
 		{
@@ -335,6 +341,56 @@ func TestMonitorAlert_Collect(t *testing.T) {
 				}
 			},
 		},
+		{
+			Name:    "FIGOの病院",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/alerts",
+			Query:   func(q url.Values) {
+				q.Add("measurements", "1")
+				q.Add("measurements", "2")
+				q.Add("measurements", "3")
+				q.Add("measurements", "4")
+				q.Add("measurements", "5")
+			},
+			Prepare: func(req *http.Request) {
+				F.Truncate(db, "measurement_alert", "hospital_guideline", "computed_event_classification")
+
+				_, err := db.Exec(`UPDATE annotated_event SET is_closed = false`)
+				assert.NoError(t, err)
+				_, err = db.Exec(`UPDATE measurement SET is_closed = false`)
+				assert.NoError(t, err)
+
+				assert.NoError(t, db.Insert(&model.HospitalGuideline{HospitalId: 1, Guideline: string(C.GuidelineFIGO), ModifiedAt: time.Now()}))
+
+				// イベント10のリスクを変更しないアノテーション15は、イベントの分類(正常)で判定する。
+				_, err = db.Exec(`UPDATE annotated_event SET risk = 5 WHERE id = 15`)
+				assert.NoError(t, err)
+
+				// イベント9のリスクを変更したアノテーション14は、リスク4を分類レベルに対応付けて判定する。
+				for _, ceid := range []int{9, 10} {
+					assert.NoError(t, db.Insert(&model.ComputedEventClassification{
+						ComputedEventId: ceid,
+						Guideline: string(C.GuidelineFIGO),
+						Category: C.CategoryNormal,
+						Level: 1,
+					}))
+				}
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &collectAlertsResponse{}).(*collectAlertsResponse)
+
+				assert.EqualValues(t, 4, len(res.Annotations))
+
+				expected := []int{19, 17, 14, 15}
+				alerts := []bool{false, false, true, false}
+				for i, m := range res.Annotations {
+					assert.EqualValues(t, expected[i], m.Id)
+					assert.EqualValues(t, alerts[i], *m.IsAlert)
+				}
+			},
+		},
 		{
 			Name:    "計測指定なし",
 			Method:  http.MethodGet,
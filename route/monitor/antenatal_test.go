@@ -0,0 +1,177 @@
+package monitor
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/test"
+	F "github.com/spiker/spiker-server/test/fixture"
+)
+
+func TestMonitorAntenatal_Analyze(t *testing.T) {
+	auth := (&F.MonitorFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+	influx := lib.GetInfluxDB()
+
+	beginTime := time.Date(2021, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "期間指定解析",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/1/antenatal_reports",
+			Body:    test.JsonBody(map[string]interface{}{
+				"from": "2021-01-02T03:04:05Z",
+				"until": "2021-01-02T03:24:05Z",
+			}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusCreated, rec.Code)
+				res := F.FromJsonResponse(t, rec, &model.AntenatalReport{}).(*model.AntenatalReport)
+
+				assert.EqualValues(t, 1, res.MeasurementId)
+				assert.EqualValues(t, 140, *res.BasalHeartRate)
+				assert.EqualValues(t, 0, *res.ShortTermVariability)
+				assert.False(t, res.CriteriaMet)
+
+				assert.EqualValues(t, 1, F.Count(t, db, "antenatal_report", nil))
+			},
+		},
+		{
+			Name:    "期間が長すぎる",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/1/antenatal_reports",
+			Body:    test.JsonBody(map[string]interface{}{
+				"from": "2021-01-02T03:04:05Z",
+				"until": "2021-01-02T05:04:05Z",
+			}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			Name:    "他病院の計測",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/2/antenatal_reports",
+			Body:    test.JsonBody(map[string]interface{}{}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		F.Truncate(db, "antenatal_report", "measurement_terminal", "patient", "measurement")
+
+		patients := F.Insert(db, model.Patient{}, 0, 3, func(i int, r F.Record) {
+			r["HospitalId"] = i
+		}).([]*model.Patient)
+
+		terminals := F.Insert(db, model.MeasurementTerminal{}, 0, 3, func(i int, r F.Record) {
+			r["HospitalId"] = i
+		}).([]*model.MeasurementTerminal)
+
+		measurements := F.Insert(db, model.Measurement{}, 0, 2, func(i int, r F.Record) {
+			r["Code"] = fmt.Sprintf("measurement-%04d", i)
+			r["PatientId"] = patients[i-1].Id
+			r["TerminalId"] = terminals[i-1].Id
+		}).([]*model.Measurement)
+
+		assert.NoError(t, influx.Delete("spiker", time.Unix(0, 0), time.Now().Add(time.Duration(24*365*100)*time.Hour), ""))
+
+		points := []lib.Point{}
+
+		for i := 0; i < 20*60; i++ {
+			points = append(points, &model.HeartRate{
+				MeasurementId: measurements[0].Id,
+				PatientCode: "p",
+				MachineCode: "m",
+				Value: 140,
+				Timestamp: beginTime.Add(time.Duration(i)*time.Second),
+			})
+		}
+
+		influx.Insert("spiker", points...)
+	})
+}
+
+func TestMonitorAntenatal_List(t *testing.T) {
+	auth := (&F.MonitorFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "一覧",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/1/antenatal_reports",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &listAntenatalReportsResponse{}).(*listAntenatalReportsResponse)
+
+				assert.EqualValues(t, 2, res.Total)
+				assert.EqualValues(t, 2, len(res.Reports))
+				assert.EqualValues(t, 2, res.Reports[0].Id)
+				assert.EqualValues(t, 1, res.Reports[1].Id)
+			},
+		},
+		{
+			Name:    "取得",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/1/antenatal_reports/2",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &model.AntenatalReport{}).(*model.AntenatalReport)
+
+				assert.EqualValues(t, 2, res.Id)
+			},
+		},
+		{
+			Name:    "別の計測の結果",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/1/antenatal_reports/3",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		F.Truncate(db, "antenatal_report", "measurement_terminal", "patient", "measurement")
+
+		patients := F.Insert(db, model.Patient{}, 0, 3, func(i int, r F.Record) {
+			r["HospitalId"] = 1
+		}).([]*model.Patient)
+
+		terminals := F.Insert(db, model.MeasurementTerminal{}, 0, 3, func(i int, r F.Record) {
+			r["HospitalId"] = 1
+		}).([]*model.MeasurementTerminal)
+
+		F.Insert(db, model.Measurement{}, 0, 2, func(i int, r F.Record) {
+			r["Code"] = fmt.Sprintf("measurement-%04d", i)
+			r["PatientId"] = patients[i-1].Id
+			r["TerminalId"] = terminals[i-1].Id
+		})
+
+		createdAt := time.Date(2021, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+		F.Insert(db, model.AntenatalReport{}, 0, 3, func(i int, r F.Record) {
+			r["MeasurementId"] = F.If(i <= 2, 1, 2)
+			r["Reasons"] = model.JSON([]byte(`[]`))
+			r["CreatedAt"] = createdAt.Add(time.Duration(i)*time.Minute)
+		})
+	})
+}
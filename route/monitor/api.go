@@ -4,68 +4,17 @@ import (
 	//"fmt"
 
 	"github.com/labstack/echo/v4"
-	//"github.com/golang-jwt/jwt"
-	"github.com/labstack/echo/v4/middleware"
-	jwt_ "github.com/dgrijalva/jwt-go"
 
-	C "github.com/spiker/spiker-server/constant"
-	"github.com/spiker/spiker-server/lib"
-	S "github.com/spiker/spiker-server/service"
+	app_middleware "github.com/spiker/spiker-server/route/middleware"
 	"github.com/spiker/spiker-server/route/shared"
 )
 
-const (
-	jwtTokenKey = "token"
-)
-
 func RegisterAPI(e *echo.Echo) {
 	e.POST("/1/login", shared.C(login))
 
 	router := e.Group("/1")
 
-	router.Use(
-		middleware.JWTWithConfig(middleware.JWTConfig{
-			ContextKey: jwtTokenKey,
-			SigningKey: []byte(lib.GetSecret()),
-		}),
-		func(next echo.HandlerFunc) echo.HandlerFunc {
-			return func(c echo.Context) error {
-				oldToken, ok := c.Get(jwtTokenKey).(*jwt_.Token)
-
-				if !ok {
-					return C.NewUnauthorizedError(
-						"token_not_found",
-						"Token was parsed but missed unexpectedly",
-						map[string]interface{}{},
-					)
-				}
-
-				me, err := lib.ConvertAndAuthenticate(oldToken, func(authId string, version string) (interface{}, error) {
-					service := shared.CreateService(S.DoctorService{}, c).(*S.DoctorService)
-
-					doctor, err := service.Authenticate(authId, version)
-
-					return doctor, err
-				})
-
-				if err != nil {
-					if e, ok := err.(C.AppError); ok {
-						return e
-					} else {
-						return C.NewUnauthorizedError(
-							"Unauthorized",
-							e.Error(),
-							map[string]interface{}{},
-						)
-					}
-				}
-
-				c.Set(shared.ContextMeKey, me)
-
-				return next(c)
-			}
-		},
-	)
+	router.Use(app_middleware.DoctorAuthentication()...)
 
 	registerAPIs(router)
 }
@@ -78,18 +27,35 @@ func registerAPIs(router *echo.Group) {
 	router.GET("/patients", shared.C(listPatients))
 	router.GET("/patients/:patient_id", shared.C(fetchPatient))
 	router.PUT("/patients/:patient_id", shared.C(updatePatient))
+	router.POST("/patients/:patient_id/merge", shared.C(mergePatient))
+	router.GET("/patients/:patient_id/identifiers", shared.C(listPatientIdentifiers))
+	router.POST("/patients/:patient_id/identifiers", shared.C(addPatientIdentifier))
+	router.DELETE("/patients/:patient_id/identifiers/:identifier_id", shared.C(removePatientIdentifier))
+
+	// 検索。
+	router.GET("/search", shared.C(search))
 
 	// 計測端末。
 	router.GET("/terminals", shared.C(listTerminals))
 	router.GET("/terminals/:terminal_id", shared.C(fetchTerminal))
 	router.PUT("/terminals/:terminal_id", shared.C(updateTerminal))
 
+	// 計測端末への患者の割り当て。
+	router.GET("/admissions", shared.C(listAdmissions))
+	router.GET("/terminals/:terminal_id/admission", shared.C(fetchAdmission))
+	router.POST("/terminals/:terminal_id/admission", shared.C(admitPatient))
+	router.DELETE("/terminals/:terminal_id/admission", shared.C(dischargePatient))
+
 	// 計測記録。
 	router.GET("/measurements", shared.C(listMeasurements))
 	router.GET("/measurements/:measurement_id", shared.C(fetchMeasurement))
 	router.GET("/measurements/:measurement_id/silent", shared.C(getSilentState))
 	router.POST("/measurements/:measurement_id/silent", shared.C(setSilentState))
 	router.POST("/measurements/:measurement_id/close", shared.C(closeMeasurement))
+	router.POST("/measurements/:measurement_id/reopen", shared.C(reopenMeasurement))
+	router.POST("/measurements/:measurement_id/split", shared.C(splitMeasurement))
+	router.POST("/measurements/:measurement_id/merge", shared.C(mergeMeasurement))
+	router.PUT("/measurements/:measurement_id/patient", shared.C(attachPatient))
 
 	// イベント。
 	router.GET("/measurements/:measurement_id/events", shared.C(listComputedEvents))
@@ -101,6 +67,27 @@ func registerAPIs(router *echo.Group) {
 	// データ。
 	router.GET("/measurements/:measurement_id/heartrates", shared.C(listHeartRate))
 	router.GET("/measurements/:measurement_id/tocos", shared.C(listTOCO))
+	router.GET("/measurements/:measurement_id/contractions", shared.C(listContractions))
+	router.GET("/data", shared.C(listBatchData))
+
+	// 記録紙。
+	router.GET("/measurements/:measurement_id/report", shared.C(renderReport))
+
+	// エクスポート。
+	router.GET("/measurements/:measurement_id/export", shared.C(exportMeasurement))
+	router.GET("/export", shared.C(exportMeasurements))
+
+	// 臨床イベント。
+	router.GET("/measurements/:measurement_id/clinical_events", shared.C(listClinicalEvents))
+	router.GET("/measurements/:measurement_id/clinical_events/:clinical_event_id", shared.C(fetchClinicalEvent))
+	router.POST("/measurements/:measurement_id/clinical_events", shared.C(createClinicalEvent))
+	router.PUT("/measurements/:measurement_id/clinical_events/:clinical_event_id", shared.C(updateClinicalEvent))
+	router.DELETE("/measurements/:measurement_id/clinical_events/:clinical_event_id", shared.C(deleteClinicalEvent))
+
+	// NST解析。
+	router.GET("/measurements/:measurement_id/antenatal_reports", shared.C(listAntenatalReports))
+	router.POST("/measurements/:measurement_id/antenatal_reports", shared.C(analyzeAntenatal))
+	router.GET("/measurements/:measurement_id/antenatal_reports/:report_id", shared.C(fetchAntenatalReport))
 
 	// アラート。
 	router.GET("/alerts", shared.C(collectAlerts))
@@ -0,0 +1,224 @@
+package monitor
+
+import (
+	"net/http"
+	"time"
+
+	v "github.com/go-ozzo/ozzo-validation/v4"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/model"
+	S "github.com/spiker/spiker-server/service"
+	"github.com/spiker/spiker-server/route/shared"
+)
+
+type listClinicalEventsQuery struct {
+	Minutes int       `query:"minutes"`
+	End     time.Time `query:"end"`
+}
+
+type listClinicalEventsResponse struct {
+	ClinicalEvents []*model.ClinicalEvent `json:"clinicalEvents"`
+}
+
+// listClinicalEvents godoc
+// @summary ある計測に関する、期間内の臨床イベントを新しい順に取得する。
+// @description 終了日時を持つイベントは、期間と重なるものを含む。
+// @tags [monitor] ClinicalEvent
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param measurement_id path int true "計測記録ID。"
+// @param minutes query int false "期間長(分)。"
+// @param end query string false "末尾日時。RFC3339形式。"
+// @success 200 {object} listClinicalEventsResponse "臨床イベント一覧。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
+// @failure 404 {object} shared.ErrorResponse "計測が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /1/measurements/{measurement_id}/clinical_events [get]
+func listClinicalEvents(c *shared.Context) error {
+	measurementId := c.IntParam("measurement_id")
+
+	if e := checkMeasurementAccess(c, measurementId); e != nil {
+		return e
+	}
+
+	query := &listClinicalEventsQuery{}
+
+	if e := c.Bind(query); e != nil {
+		return e
+	}
+
+	service := shared.CreateService(S.ClinicalEventService{}, c).(*S.ClinicalEventService)
+
+	begin := query.End.Add(time.Duration(-query.Minutes)*time.Minute)
+
+	results, err := service.ListInRange(measurementId, begin, query.End)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &listClinicalEventsResponse{
+		ClinicalEvents: results,
+	})
+}
+
+// fetchClinicalEvent godoc
+// @summary 臨床イベントを取得する。
+// @tags [monitor] ClinicalEvent
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param measurement_id path int true "計測記録ID。"
+// @param clinical_event_id path int true "臨床イベントID。"
+// @success 200 {object} model.ClinicalEvent "臨床イベント情報。"
+// @failure 404 {object} shared.ErrorResponse "臨床イベントが存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /1/measurements/{measurement_id}/clinical_events/{clinical_event_id} [get]
+func fetchClinicalEvent(c *shared.Context) error {
+	measurementId := c.IntParam("measurement_id")
+	id := c.IntParam("clinical_event_id")
+
+	if e := checkMeasurementAccess(c, measurementId); e != nil {
+		return e
+	}
+
+	service := shared.CreateService(S.ClinicalEventService{}, c).(*S.ClinicalEventService)
+
+	result, err := service.Fetch(measurementId, id)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+type clinicalEventBody struct {
+	Category   C.ClinicalEventCategory `json:"category"`
+	OccurredAt time.Time               `json:"occurredAt"`
+	EndedAt    *time.Time              `json:"endedAt"`
+	Memo       string                  `json:"memo" maxLength:"2000"`
+}
+
+func (body *clinicalEventBody) validate() error {
+	categories := []interface{}{}
+	for _, x := range C.ClinicalEventCategories {
+		categories = append(categories, x)
+	}
+
+	return (v.Errors{
+		"category": v.Validate(body.Category, v.Required, v.In(categories...)),
+		"occurredAt": v.Validate(body.OccurredAt, v.Required),
+		"memo": v.Validate(body.Memo, v.RuneLength(0, 2000)),
+	}).Filter()
+}
+
+// createClinicalEvent godoc
+// @summary ある計測に対して臨床イベントを登録する。
+// @tags [monitor] ClinicalEvent
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param measurement_id path int true "計測記録ID。"
+// @param clinical_event body clinicalEventBody true "臨床イベント情報。"
+// @success 201 {object} model.ClinicalEvent "登録した臨床イベント情報。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
+// @failure 404 {object} shared.ErrorResponse "計測が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /1/measurements/{measurement_id}/clinical_events [post]
+func createClinicalEvent(c *shared.Context) error {
+	me := c.Get(shared.ContextMeKey).(*model.HospitalDoctor)
+
+	measurementId := c.IntParam("measurement_id")
+
+	if e := checkMeasurementAccess(c, measurementId); e != nil {
+		return e
+	}
+
+	body := &clinicalEventBody{}
+
+	if e := c.Bind(body); e != nil {
+		return e
+	} else if e := body.validate(); e != nil {
+		return e
+	}
+
+	service := shared.CreateService(S.ClinicalEventTxService{}, c).(*S.ClinicalEventTxService)
+
+	result, err := service.Create(measurementId, me.Doctor.Id, body.Category, body.OccurredAt, body.EndedAt, body.Memo)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, result)
+}
+
+// updateClinicalEvent godoc
+// @summary 臨床イベントを更新する。
+// @tags [monitor] ClinicalEvent
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param measurement_id path int true "計測記録ID。"
+// @param clinical_event_id path int true "臨床イベントID。"
+// @param clinical_event body clinicalEventBody true "臨床イベント情報。"
+// @success 200 {object} model.ClinicalEvent "更新した臨床イベント情報。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
+// @failure 404 {object} shared.ErrorResponse "臨床イベントが存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /1/measurements/{measurement_id}/clinical_events/{clinical_event_id} [put]
+func updateClinicalEvent(c *shared.Context) error {
+	me := c.Get(shared.ContextMeKey).(*model.HospitalDoctor)
+
+	measurementId := c.IntParam("measurement_id")
+	id := c.IntParam("clinical_event_id")
+
+	if e := checkMeasurementAccess(c, measurementId); e != nil {
+		return e
+	}
+
+	body := &clinicalEventBody{}
+
+	if e := c.Bind(body); e != nil {
+		return e
+	} else if e := body.validate(); e != nil {
+		return e
+	}
+
+	service := shared.CreateService(S.ClinicalEventTxService{}, c).(*S.ClinicalEventTxService)
+
+	result, err := service.Update(measurementId, id, me.Doctor.Id, body.Category, body.OccurredAt, body.EndedAt, body.Memo)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// deleteClinicalEvent godoc
+// @summary 臨床イベントを削除する。
+// @tags [monitor] ClinicalEvent
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param measurement_id path int true "計測記録ID。"
+// @param clinical_event_id path int true "臨床イベントID。"
+// @success 204 "処理に成功。"
+// @failure 404 {object} shared.ErrorResponse "臨床イベントが存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /1/measurements/{measurement_id}/clinical_events/{clinical_event_id} [delete]
+func deleteClinicalEvent(c *shared.Context) error {
+	measurementId := c.IntParam("measurement_id")
+	id := c.IntParam("clinical_event_id")
+
+	if e := checkMeasurementAccess(c, measurementId); e != nil {
+		return e
+	}
+
+	service := shared.CreateService(S.ClinicalEventTxService{}, c).(*S.ClinicalEventTxService)
+
+	if e := service.Delete(measurementId, id); e != nil {
+		return e
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
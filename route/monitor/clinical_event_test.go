@@ -0,0 +1,181 @@
+package monitor
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/test"
+	F "github.com/spiker/spiker-server/test/fixture"
+)
+
+func TestMonitorClinicalEvent(t *testing.T) {
+	auth := (&F.MonitorFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	baseTime := time.Date(2021, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "登録",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/1/clinical_events",
+			Body:    test.JsonBody(map[string]interface{}{
+				"category": "OXYTOCIN_START",
+				"occurredAt": "2021-01-02T03:10:05Z",
+				"memo": "2mU/min",
+			}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusCreated, rec.Code)
+				res := F.FromJsonResponse(t, rec, &model.ClinicalEvent{}).(*model.ClinicalEvent)
+
+				assert.EqualValues(t, 3, res.Id)
+				assert.EqualValues(t, "OXYTOCIN_START", res.Category)
+				assert.EqualValues(t, "2mU/min", res.Memo)
+				assert.Nil(t, res.EndedAt)
+
+				assert.EqualValues(t, 3, F.Count(t, db, "clinical_event", nil))
+			},
+		},
+		{
+			Name:    "不正な種別",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/1/clinical_events",
+			Body:    test.JsonBody(map[string]interface{}{
+				"category": "UNKNOWN",
+				"occurredAt": "2021-01-02T03:10:05Z",
+			}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			Name:    "終了日時が発生日時より前",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/1/clinical_events",
+			Body:    test.JsonBody(map[string]interface{}{
+				"category": "EPIDURAL",
+				"occurredAt": "2021-01-02T03:10:05Z",
+				"endedAt": "2021-01-02T03:00:05Z",
+			}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			Name:    "期間内の一覧",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/1/clinical_events?minutes=10&end=2021-01-02T03:14:05Z",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &listClinicalEventsResponse{}).(*listClinicalEventsResponse)
+
+				// 硬膜外麻酔は期間前に開始しているが、期間と重なる。
+				assert.EqualValues(t, 2, len(res.ClinicalEvents))
+				assert.EqualValues(t, 2, res.ClinicalEvents[0].Id)
+				assert.EqualValues(t, 1, res.ClinicalEvents[1].Id)
+			},
+		},
+		{
+			Name:    "イベント一覧に含まれる",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/1/events?minutes=10&end=2021-01-02T03:14:05Z",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &listComputedEventsResponse{}).(*listComputedEventsResponse)
+
+				assert.EqualValues(t, 2, len(res.ClinicalEvents))
+			},
+		},
+		{
+			Name:    "更新",
+			Method:  http.MethodPut,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/1/clinical_events/2",
+			Body:    test.JsonBody(map[string]interface{}{
+				"category": "VAGINAL_EXAM",
+				"occurredAt": "2021-01-02T03:12:05Z",
+				"memo": "8cm",
+			}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &model.ClinicalEvent{}).(*model.ClinicalEvent)
+
+				assert.EqualValues(t, "VAGINAL_EXAM", res.Category)
+				assert.EqualValues(t, "8cm", res.Memo)
+				assert.NotNil(t, res.DoctorId)
+			},
+		},
+		{
+			Name:    "存在しないイベント",
+			Method:  http.MethodPut,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/1/clinical_events/3",
+			Body:    test.JsonBody(map[string]interface{}{
+				"category": "OTHER",
+				"occurredAt": "2021-01-02T03:12:05Z",
+			}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+		{
+			Name:    "削除",
+			Method:  http.MethodDelete,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/1/clinical_events/1",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNoContent, rec.Code)
+				assert.EqualValues(t, 2, F.Count(t, db, "clinical_event", nil))
+			},
+		},
+		{
+			Name:    "他病院の計測",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/2/clinical_events",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		F.Truncate(db, "clinical_event", "measurement_terminal", "patient", "measurement")
+
+		patients := F.Insert(db, model.Patient{}, 0, 3, func(i int, r F.Record) {
+			r["HospitalId"] = i
+		}).([]*model.Patient)
+
+		terminals := F.Insert(db, model.MeasurementTerminal{}, 0, 3, func(i int, r F.Record) {
+			r["HospitalId"] = i
+		}).([]*model.MeasurementTerminal)
+
+		measurements := F.Insert(db, model.Measurement{}, 0, 3, func(i int, r F.Record) {
+			r["Code"] = fmt.Sprintf("measurement-%04d", i)
+			r["PatientId"] = patients[i-1].Id
+			r["TerminalId"] = terminals[i-1].Id
+		}).([]*model.Measurement)
+
+		endedAt := baseTime.Add(time.Duration(30)*time.Minute)
+
+		F.Insert(db, model.ClinicalEvent{}, 0, 2, func(i int, r F.Record) {
+			r["MeasurementId"] = measurements[0].Id
+			r["Category"] = F.If(i == 1, "EPIDURAL", "MEMBRANE_RUPTURE")
+			r["OccurredAt"] = baseTime.Add(time.Duration(i*5)*time.Minute - time.Duration(10)*time.Minute)
+			r["EndedAt"] = F.If(i == 1, &endedAt, nil)
+		})
+	})
+}
@@ -69,6 +69,45 @@ func TestMonitorData_ListHeartRate(t *testing.T) {
 				}
 			},
 		},
+		{
+			Name:    "心拍集約取得",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/2/heartrates",
+			Query:   func(q url.Values) {
+				q.Add("resolution", "20")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &listHeartRateResponse{}).(*listHeartRateResponse)
+
+				assert.EqualValues(t, 20, *res.Resolution)
+
+				// 03:04:00から03:20:40までの20秒毎の51期間。
+				assert.EqualValues(t, 51, len(res.Envelopes))
+				assert.EqualValues(t, 51, len(res.Records))
+
+				// 先頭の期間は+1,+5,+9,+13秒。
+				first := res.Envelopes[0]
+				assert.EqualValues(t, 1001, first.Minimum)
+				assert.EqualValues(t, 1013, first.Maximum)
+				assert.EqualValues(t, 1007, first.Mean)
+				assert.EqualValues(t, time.Date(2021, time.January, 2, 3, 4, 0, 0, time.UTC).UnixNano() / 1000000, first.ObservedAt)
+				assert.EqualValues(t, 1007, res.Records[0].Value)
+			},
+		},
+		{
+			Name:    "集約期間が不正",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/2/heartrates",
+			Query:   func(q url.Values) {
+				q.Add("resolution", "0")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
 		{
 			Name:    "計測が無い",
 			Method:  http.MethodGet,
@@ -249,3 +288,177 @@ func TestMonitorData_ListTOCO(t *testing.T) {
 		influx.Insert("spiker", points...)
 	})
 }
+
+func TestMonitorData_ListBatchData(t *testing.T) {
+	auth := (&F.MonitorFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+	influx := lib.GetInfluxDB()
+
+	beginTime := time.Date(2021, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "複数計測、複数チャネル取得",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/data",
+			Query:   func(q url.Values) {
+				q.Add("measurements", "2")
+				q.Add("measurements", "1")
+				q.Add("channels", "heartrate")
+				q.Add("channels", "toco")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &listBatchDataResponse{}).(*listBatchDataResponse)
+
+				assert.EqualValues(t, 4, len(res.Series))
+
+				expected := []struct {
+					measurementId int
+					channel       string
+					offset        int
+				}{
+					{2, "heartrate", 1001},
+					{2, "toco", 3001},
+					{1, "heartrate", 1000},
+					{1, "toco", 3000},
+				}
+
+				for i, s := range res.Series {
+					assert.EqualValues(t, expected[i].measurementId, s.MeasurementId)
+					assert.EqualValues(t, expected[i].channel, s.Channel)
+					assert.EqualValues(t, 250, len(s.Records))
+
+					for j, r := range s.Records {
+						assert.EqualValues(t, expected[i].offset+(j*4), r.Value)
+					}
+				}
+			},
+		},
+		{
+			Name:    "期間指定取得",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/data",
+			Query:   func(q url.Values) {
+				q.Add("measurements", "1")
+				q.Add("channels", "heartrate")
+				q.Add("minutes", "5")
+				q.Add("end", "2021-01-02T03:10:00+00:00")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &listBatchDataResponse{}).(*listBatchDataResponse)
+
+				assert.EqualValues(t, 1, len(res.Series))
+
+				// 先頭:+55秒, 末尾:+355秒 -> +56,+60,...,+352
+				assert.EqualValues(t, 75, len(res.Series[0].Records))
+				assert.EqualValues(t, 1056, res.Series[0].Records[0].Value)
+			},
+		},
+		{
+			Name:    "期間が上限を超える",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/data",
+			Query:   func(q url.Values) {
+				q.Add("measurements", "1")
+				q.Add("channels", "heartrate")
+				q.Add("minutes", "361")
+				q.Add("end", "2021-01-02T03:10:00+00:00")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			Name:    "チャネルが不正",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/data",
+			Query:   func(q url.Values) {
+				q.Add("measurements", "1")
+				q.Add("channels", "fhr2")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			Name:    "計測の指定が無い",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/data",
+			Query:   func(q url.Values) {
+				q.Add("channels", "heartrate")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			Name:    "病院が違う計測を含む",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/data",
+			Query:   func(q url.Values) {
+				q.Add("measurements", "1")
+				q.Add("measurements", "4")
+				q.Add("channels", "heartrate")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		F.Truncate(db, "measurement_terminal", "patient", "measurement")
+
+		patients := F.Insert(db, model.Patient{}, 0, 3, func(i int, r F.Record) {
+			r["HospitalId"] = i
+		}).([]*model.Patient)
+
+		terminals := F.Insert(db, model.MeasurementTerminal{}, 0, 3, func(i int, r F.Record) {
+			r["HospitalId"] = i
+		}).([]*model.MeasurementTerminal)
+
+		// h - m
+		// 1 - [1,2,3]
+		// 2 - [4]
+		// 3 - []
+		measurements := F.Insert(db, model.Measurement{}, 0, 4, func(i int, r F.Record) {
+			r["Code"] = fmt.Sprintf("measurement-%04d", i)
+			r["PatientId"] = F.If(i <= 3, patients[0].Id, patients[1].Id)
+			r["TerminalId"] = F.If(i <= 3, terminals[0].Id, terminals[1].Id)
+			r["FirstTime"] = beginTime.Add(time.Duration(i-1)*time.Second)
+			r["LastTime"] = beginTime.Add(time.Duration(995+i)*time.Second)
+		}).([]*model.Measurement)
+
+		assert.NoError(t, influx.Delete("spiker", time.Unix(0, 0), time.Now().Add(time.Duration(24*365*100)*time.Hour), ""))
+
+		points := []lib.Point{}
+
+		for i := 0; i < 1000; i++ {
+			points = append(points, &model.HeartRate{
+				MeasurementId: measurements[i%4].Id,
+				PatientCode: "p",
+				MachineCode: "m",
+				Value: 1000+i,
+				Timestamp: beginTime.Add(time.Duration(i)*time.Second),
+			})
+			points = append(points, &model.TOCO{
+				MeasurementId: measurements[i%4].Id,
+				PatientCode: "p",
+				MachineCode: "m",
+				Value: 3000+i,
+				Timestamp: beginTime.Add(time.Duration(i)*time.Second),
+			})
+		}
+
+		influx.Insert("spiker", points...)
+	})
+}
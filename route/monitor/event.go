@@ -21,11 +21,13 @@ type listComputedEventsQuery struct {
 }
 
 type listComputedEventsResponse struct {
-	Events []*model.ComputedEventEntity `json:"events"`
+	Events         []*model.ComputedEventEntity `json:"events"`
+	ClinicalEvents []*model.ClinicalEvent       `json:"clinicalEvents"`
 }
 
 // listComputedEvents godoc
 // @summary ある計測に関する、期間内の自動診断イベントを新しい順に取得する。
+// @description 期間内の臨床イベントを併せて返す。
 // @tags [monitor] Event
 // @produce json
 // @param Authorization header string true "Bearerトークン。"
@@ -60,13 +62,24 @@ func listComputedEvents(c *shared.Context) error {
 		return err
 	}
 
+	// 期間内の臨床イベントを併せて返す。
+	clinicalService := shared.CreateService(S.ClinicalEventService{}, c).(*S.ClinicalEventService)
+
+	clinicalEvents, err := clinicalService.ListInRange(measurementId, begin, query.End)
+
+	if err != nil {
+		return err
+	}
+
 	return c.JSON(http.StatusOK, &listComputedEventsResponse{
 		Events: results,
+		ClinicalEvents: clinicalEvents,
 	})
 }
 
 // fetchComputedEvent godoc
 // @summary 自動診断イベントを取得する。
+// @description スナップショットが保存されたイベントは、その署名付きURLを含む。
 // @tags [monitor] Event
 // @produce json
 // @param Authorization header string true "Bearerトークン。"
@@ -101,11 +114,13 @@ type listAnnotatedEventsQuery struct {
 }
 
 type listAnnotatedEventsResponse struct {
-	Annotations []*model.AnnotatedEventEntity `json:"annotations"`
+	Annotations    []*model.AnnotatedEventEntity `json:"annotations"`
+	ClinicalEvents []*model.ClinicalEvent        `json:"clinicalEvents"`
 }
 
 // listAnnotatedEvents godoc
 // @summary ある計測に関するアノテーションを新しい順に取得する。
+// @description 期間内の臨床イベントを併せて返す。
 // @tags [monitor] Event
 // @produce json
 // @param Authorization header string true "Bearerトークン。"
@@ -140,8 +155,18 @@ func listAnnotatedEvents(c *shared.Context) error {
 		return err
 	}
 
+	// 期間内の臨床イベントを併せて返す。
+	clinicalService := shared.CreateService(S.ClinicalEventService{}, c).(*S.ClinicalEventService)
+
+	clinicalEvents, err := clinicalService.ListInRange(measurementId, begin, query.End)
+
+	if err != nil {
+		return err
+	}
+
 	return c.JSON(http.StatusOK, &listAnnotatedEventsResponse{
 		Annotations: results,
+		ClinicalEvents: clinicalEvents,
 	})
 }
 
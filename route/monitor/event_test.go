@@ -22,7 +22,7 @@ import (
 )
 
 func prepareEvents(t *testing.T, db *gorp.DbMap, beginTime time.Time) (func(int, *model.ComputedEventEntity), func(int, *model.AnnotatedEventEntity)) {
-	F.Truncate(db, "annotated_event", "computed_event", "measurement_terminal", "patient", "measurement")
+	F.Truncate(db, "event_snapshot", "annotated_event", "computed_event", "measurement_terminal", "patient", "measurement")
 
 	patients := F.Insert(db, model.Patient{}, 0, 3, func(i int, r F.Record) {
 		r["HospitalId"] = i
@@ -81,6 +81,17 @@ func prepareEvents(t *testing.T, db *gorp.DbMap, beginTime time.Time) (func(int,
 		r["IsClosed"] = false
 	})
 
+	// ce1のみスナップショットを持つ。
+	if e := db.Insert(&model.EventSnapshot{
+		ComputedEventId: 1,
+		StorageKey: "snapshots/1/1.png",
+		RangeFrom: beginTime.Add(time.Duration(9)*time.Hour),
+		RangeUntil: beginTime.Add(time.Duration(9)*time.Hour + time.Duration(30)*time.Minute),
+		CreatedAt: beginTime,
+	}); e != nil {
+		t.Fatal(e)
+	}
+
 	verifyComputed := func(id int, actual *model.ComputedEventEntity) {
 		assert.EqualValues(t, id, actual.Id)
 
@@ -110,6 +121,13 @@ func prepareEvents(t *testing.T, db *gorp.DbMap, beginTime time.Time) (func(int,
 			assert.EqualValues(t, 0, len(actual.Annotations))
 		}
 
+		if id == 1 {
+			assert.NotNil(t, actual.SnapshotUrl)
+			assert.Contains(t, *actual.SnapshotUrl, "snapshots/1/1.png")
+		} else {
+			assert.Nil(t, actual.SnapshotUrl)
+		}
+
 		mm := actual.Measurement
 		assert.NotNil(t, mm)
 
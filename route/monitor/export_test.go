@@ -0,0 +1,187 @@
+package monitor
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/test"
+	F "github.com/spiker/spiker-server/test/fixture"
+)
+
+func TestMonitorExport_Export(t *testing.T) {
+	auth := (&F.MonitorFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+	influx := lib.GetInfluxDB()
+
+	beginTime := time.Date(2021, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "EDF+で出力",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/1/export",
+			Query:   func(q url.Values) {
+				q.Add("format", "edf")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.Contains(t, rec.Header().Get("Content-Disposition"), "ctg-1.edf")
+
+				bs := rec.Body.Bytes()
+
+				// FHR1、FHR2、UCと注釈の4信号、10秒分のレコード。
+				assert.Equal(t, "EDF+C", strings.TrimSpace(string(bs[192:236])))
+				assert.Equal(t, "10", strings.TrimSpace(string(bs[236:244])))
+				assert.Equal(t, "4", strings.TrimSpace(string(bs[252:256])))
+				assert.Equal(t, "FHR1", strings.TrimSpace(string(bs[256:272])))
+				assert.Contains(t, string(bs), "Computed #1 R4")
+			},
+		},
+		{
+			Name:    "CSVで出力",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/1/export",
+			Query:   func(q url.Values) {
+				q.Add("format", "csv")
+				q.Add("from", "2021-01-02T03:04:05+00:00")
+				q.Add("until", "2021-01-02T03:04:07+00:00")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.Contains(t, rec.Header().Get("Content-Type"), "text/csv")
+
+				lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+
+				assert.Equal(t, 3, len(lines))
+				assert.Equal(t, "measurement_id,measurement_code,time,FHR1 [bpm],FHR2 [bpm],UC [a.u.]", lines[0])
+				assert.Equal(t, "1,measurement-0001,2021-01-02T12:04:05+09:00,140,150,20", lines[1])
+			},
+		},
+		{
+			Name:    "複数の計測をEDF+で出力",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/export",
+			Query:   func(q url.Values) {
+				q.Add("measurements", "1")
+				q.Add("measurements", "3")
+				q.Add("format", "edf")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.Equal(t, "application/zip", rec.Header().Get("Content-Type"))
+
+				r, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+
+				assert.NoError(t, err)
+				assert.Equal(t, 2, len(r.File))
+				assert.Equal(t, "ctg-1.edf", r.File[0].Name)
+				assert.Equal(t, "ctg-3.edf", r.File[1].Name)
+			},
+		},
+		{
+			Name:    "形式が不正",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/1/export",
+			Query:   func(q url.Values) {
+				q.Add("format", "xml")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			Name:    "病院が違う",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/export",
+			Query:   func(q url.Values) {
+				q.Add("measurements", "1")
+				q.Add("measurements", "2")
+				q.Add("format", "csv")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		F.Truncate(db, "annotated_event", "computed_event", "measurement_terminal", "patient", "measurement")
+
+		patients := F.Insert(db, model.Patient{}, 0, 2, func(i int, r F.Record) {
+			r["HospitalId"] = i
+		}).([]*model.Patient)
+
+		terminals := F.Insert(db, model.MeasurementTerminal{}, 0, 2, func(i int, r F.Record) {
+			r["HospitalId"] = i
+		}).([]*model.MeasurementTerminal)
+
+		// h - m
+		// 1 - [1,3]
+		// 2 - [2]
+		measurements := F.Insert(db, model.Measurement{}, 0, 3, func(i int, r F.Record) {
+			r["Code"] = fmt.Sprintf("measurement-%04d", i)
+			r["PatientId"] = patients[F.If(i == 2, 1, 0).(int)].Id
+			r["TerminalId"] = terminals[F.If(i == 2, 1, 0).(int)].Id
+			r["FirstTime"] = beginTime
+			r["LastTime"] = beginTime.Add(time.Duration(9)*time.Second)
+		}).([]*model.Measurement)
+
+		F.Insert(db, model.ComputedEvent{}, 0, 1, func(i int, r F.Record) {
+			r["MeasurementId"] = measurements[0].Id
+			r["Risk"] = 4
+			r["IsHidden"] = false
+			r["RangeFrom"] = beginTime.Add(time.Duration(2)*time.Second)
+			r["RangeUntil"] = beginTime.Add(time.Duration(5)*time.Second)
+			r["Parameters"] = model.JSON([]byte("{}"))
+		})
+
+		assert.NoError(t, influx.Delete("spiker", time.Unix(0, 0), time.Now().Add(time.Duration(24*365*100)*time.Hour), ""))
+
+		points := []lib.Point{}
+
+		for _, m := range measurements {
+			for i := 0; i < 10; i++ {
+				points = append(points, &model.HeartRate{
+					MeasurementId: m.Id,
+					PatientCode: m.Code,
+					MachineCode: "m",
+					Value: 140,
+					Timestamp: beginTime.Add(time.Duration(i)*time.Second),
+				})
+				points = append(points, &model.SecondHeartRate{
+					MeasurementId: m.Id,
+					PatientCode: m.Code,
+					MachineCode: "m",
+					Value: 150,
+					Timestamp: beginTime.Add(time.Duration(i)*time.Second),
+				})
+				points = append(points, &model.TOCO{
+					MeasurementId: m.Id,
+					PatientCode: m.Code,
+					MachineCode: "m",
+					Value: 20,
+					Timestamp: beginTime.Add(time.Duration(i)*time.Second),
+				})
+			}
+		}
+
+		influx.Insert("spiker", points...)
+	})
+}
@@ -0,0 +1,244 @@
+package monitor
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/resource/influxdb"
+	"github.com/spiker/spiker-server/test"
+	F "github.com/spiker/spiker-server/test/fixture"
+)
+
+func TestMonitorMeasurement_SplitMerge(t *testing.T) {
+	auth := (&F.MonitorFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+	influx := lib.GetInfluxDB()
+
+	beginTime := time.Date(2021, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	at := func(sec int) time.Time {
+		return beginTime.Add(time.Duration(sec)*time.Second)
+	}
+
+	// 計測の計測データの件数。
+	countData := func(t *testing.T, id int) int {
+		records, err := influxdb.ListRecords(influx, id, at(0), at(60))
+		assert.NoError(t, err)
+		return len(records)
+	}
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "分割",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/1/split",
+			Body:    test.JsonBody(map[string]interface{}{
+				"at": at(5).Format(time.RFC3339),
+			}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusCreated, rec.Code)
+				res := F.FromJsonResponse(t, rec, &model.Measurement{}).(*model.Measurement)
+
+				assert.EqualValues(t, 4, res.Id)
+				assert.EqualValues(t, 1, res.PatientId)
+				assert.EqualValues(t, "measurement-0001", res.Code)
+				assert.EqualValues(t, false, res.IsClosed)
+				assert.EqualValues(t, at(5).UnixNano(), res.FirstTime.UnixNano())
+				assert.EqualValues(t, at(9).UnixNano(), res.LastTime.UnixNano())
+
+				if a, e := db.Get(model.Measurement{}, 1); e != nil {
+					assert.FailNow(t, e.Error())
+				} else {
+					m := a.(*model.Measurement)
+					assert.EqualValues(t, true, m.IsClosed)
+					assert.EqualValues(t, at(0).UnixNano(), m.FirstTime.UnixNano())
+					assert.EqualValues(t, at(4).UnixNano(), m.LastTime.UnixNano())
+				}
+
+				events := []*model.ClinicalEvent{}
+				F.Select(t, db, &events, F.NewQuery("").Asc("id"))
+
+				assert.EqualValues(t, 1, events[0].MeasurementId)
+				assert.EqualValues(t, 4, events[1].MeasurementId)
+
+				// 注釈は参照する算出イベントと同じ計測に残る。
+				computed := []*model.ComputedEvent{}
+				F.Select(t, db, &computed, F.NewQuery("").Asc("id"))
+				annotated := []*model.AnnotatedEvent{}
+				F.Select(t, db, &annotated, F.NewQuery("").Asc("id"))
+
+				assert.EqualValues(t, 1, computed[0].MeasurementId)
+				assert.EqualValues(t, 1, annotated[0].MeasurementId)
+				assert.EqualValues(t, 4, annotated[1].MeasurementId)
+
+				assert.EqualValues(t, 0, F.Count(t, db, "measurement_retag", nil))
+
+				assert.EqualValues(t, 5, countData(t, 1))
+				assert.EqualValues(t, 5, countData(t, 4))
+			},
+		},
+		{
+			Name:    "秒未満を含む日時で分割",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/1/split",
+			Body:    test.JsonBody(map[string]interface{}{
+				"at": at(5).Add(time.Duration(500)*time.Millisecond).Format(time.RFC3339Nano),
+			}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusCreated, rec.Code)
+				res := F.FromJsonResponse(t, rec, &model.Measurement{}).(*model.Measurement)
+
+				assert.EqualValues(t, at(6).UnixNano(), res.FirstTime.UnixNano())
+
+				// 分割する日時と同じ秒の前のデータは、元の計測にのみ残る。
+				assert.EqualValues(t, 6, countData(t, 1))
+				assert.EqualValues(t, 4, countData(t, 4))
+			},
+		},
+		{
+			Name:    "分割する日時以降にデータが無い",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/1/split",
+			Body:    test.JsonBody(map[string]interface{}{
+				"at": at(30).Format(time.RFC3339),
+			}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+				assert.EqualValues(t, 10, countData(t, 1))
+			},
+		},
+		{
+			Name:    "統合",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/1/merge",
+			Body:    test.JsonBody(map[string]interface{}{
+				"measurementId": 2,
+			}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &model.Measurement{}).(*model.Measurement)
+
+				assert.EqualValues(t, 1, res.Id)
+				assert.EqualValues(t, at(0).UnixNano(), res.FirstTime.UnixNano())
+				assert.EqualValues(t, at(29).UnixNano(), res.LastTime.UnixNano())
+
+				assert.EqualValues(t, 2, F.Count(t, db, "measurement", nil))
+
+				events := []*model.ClinicalEvent{}
+				F.Select(t, db, &events, F.NewQuery("").Asc("id"))
+
+				for _, e := range events {
+					assert.EqualValues(t, 1, e.MeasurementId)
+				}
+
+				assert.EqualValues(t, 20, countData(t, 1))
+				assert.EqualValues(t, 0, countData(t, 2))
+			},
+		},
+		{
+			Name:    "同じ計測",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/1/merge",
+			Body:    test.JsonBody(map[string]interface{}{
+				"measurementId": 1,
+			}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			Name:    "病院が違う",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/1/merge",
+			Body:    test.JsonBody(map[string]interface{}{
+				"measurementId": 3,
+			}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+				assert.EqualValues(t, 3, F.Count(t, db, "measurement", nil))
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		F.Truncate(db, "annotated_event", "computed_event", "measurement_retag", "clinical_event", "terminal_admission", "measurement_archive", "measurement_terminal", "patient", "measurement")
+
+		patients := F.Insert(db, model.Patient{}, 0, 2, func(i int, r F.Record) {
+			r["HospitalId"] = i
+		}).([]*model.Patient)
+
+		terminals := F.Insert(db, model.MeasurementTerminal{}, 0, 2, func(i int, r F.Record) {
+			r["HospitalId"] = i
+		}).([]*model.MeasurementTerminal)
+
+		// h - m
+		// 1 - [1(0~9秒),2(20~29秒)]
+		// 2 - [3(0~9秒)]
+		measurements := F.Insert(db, model.Measurement{}, 0, 3, func(i int, r F.Record) {
+			offset := F.If(i == 2, 20, 0).(int)
+
+			r["Code"] = fmt.Sprintf("measurement-%04d", i)
+			r["PatientId"] = patients[F.If(i <= 2, 0, 1).(int)].Id
+			r["TerminalId"] = terminals[F.If(i <= 2, 0, 1).(int)].Id
+			r["FirstTime"] = at(offset)
+			r["LastTime"] = at(offset + 9)
+		}).([]*model.Measurement)
+
+		F.Insert(db, model.ClinicalEvent{}, 0, 3, func(i int, r F.Record) {
+			r["MeasurementId"] = measurements[F.If(i <= 2, 0, 1).(int)].Id
+			r["Category"] = "MEMBRANE_RUPTURE"
+			r["OccurredAt"] = at(F.If(i == 1, 2, F.If(i == 2, 7, 25)).(int))
+		})
+
+		// ce - ae
+		// 1(3~6秒) - [1(6~7秒)]
+		// -        - [2(7~8秒)]
+		F.Insert(db, model.ComputedEvent{}, 0, 1, func(i int, r F.Record) {
+			r["MeasurementId"] = measurements[0].Id
+			r["IsHidden"] = false
+			r["RangeFrom"] = at(3)
+			r["RangeUntil"] = at(6)
+			r["Parameters"] = model.JSON([]byte("{}"))
+		})
+
+		F.Insert(db, model.AnnotatedEvent{}, 0, 2, func(i int, r F.Record) {
+			r["MeasurementId"] = measurements[0].Id
+			r["ComputedEventId"] = F.If(i == 1, 1, nil)
+			r["RangeFrom"] = at(5 + i)
+			r["RangeUntil"] = at(6 + i)
+			r["CreatedAt"] = at(5 + i)
+		})
+
+		assert.NoError(t, influx.Delete("spiker", time.Unix(0, 0), time.Now().Add(time.Duration(24*365*100)*time.Hour), ""))
+
+		points := []lib.Point{}
+
+		for _, m := range measurements {
+			for i := 0; i < 10; i++ {
+				points = append(points, &model.HeartRate{
+					MeasurementId: m.Id,
+					PatientCode: "p",
+					MachineCode: "m",
+					Value: 1000+i,
+					Timestamp: m.FirstTime.Add(time.Duration(i)*time.Second),
+				})
+			}
+		}
+
+		influx.Insert("spiker", points...)
+	})
+}
@@ -1,6 +1,7 @@
 package monitor
 
 import (
+	"encoding/base64"
 	"fmt"
 	//"database/sql"
 	"net/http"
@@ -120,10 +121,219 @@ func TestMonitorMeasurement_List(t *testing.T) {
 				}
 			},
 		},
+		{
+			Name:    "ソート指定",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements",
+			Query:   func(q url.Values) {
+				q.Add("limit", "3")
+				q.Add("sort", "id")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &listMeasurementsResponse{}).(*listMeasurementsResponse)
+
+				assert.EqualValues(t, 9, res.Total)
+				assert.NotNil(t, res.NextCursor)
+
+				assert.EqualValues(t, 3, len(res.Measurements))
+
+				expected := []int{1, 2, 3}
+				for i, m := range res.Measurements {
+					verify(expected[i], m)
+				}
+			},
+		},
+		{
+			Name:    "カーソル指定",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements",
+			Query:   func(q url.Values) {
+				q.Add("limit", "3")
+				q.Add("offset", "5")
+				q.Add("sort", "id")
+				q.Add("cursor", base64.RawURLEncoding.EncodeToString([]byte(`{"sort":"id","values":["3"]}`)))
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &listMeasurementsResponse{}).(*listMeasurementsResponse)
+
+				assert.EqualValues(t, -1, res.Total)
+				assert.NotNil(t, res.NextCursor)
+
+				assert.EqualValues(t, 3, len(res.Measurements))
+
+				expected := []int{5, 6, 7}
+				for i, m := range res.Measurements {
+					verify(expected[i], m)
+				}
+			},
+		},
+		{
+			Name:    "カーソル指定で末尾まで",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements",
+			Query:   func(q url.Values) {
+				q.Add("sort", "id")
+				q.Add("cursor", base64.RawURLEncoding.EncodeToString([]byte(`{"sort":"id","values":["7"]}`)))
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &listMeasurementsResponse{}).(*listMeasurementsResponse)
+
+				assert.EqualValues(t, -1, res.Total)
+				assert.Nil(t, res.NextCursor)
+
+				expected := []int{8, 9, 10}
+				assert.EqualValues(t, len(expected), len(res.Measurements))
+				for i, m := range res.Measurements {
+					verify(expected[i], m)
+				}
+			},
+		},
+		{
+			Name:    "ソートと異なるカーソル",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements",
+			Query:   func(q url.Values) {
+				q.Add("sort", "-id")
+				q.Add("cursor", base64.RawURLEncoding.EncodeToString([]byte(`{"sort":"id","values":["3"]}`)))
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			Name:    "不正なソート",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements",
+			Query:   func(q url.Values) {
+				q.Add("sort", "code")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			Name:    "状態指定",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements",
+			Query:   func(q url.Values) {
+				q.Add("state", "closed")
+			},
+			Prepare: func(req *http.Request) {
+				if _, e := db.Exec(`UPDATE measurement SET is_closed = (id IN (2, 3, 8))`); e != nil {
+					assert.FailNow(t, e.Error())
+				}
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &listMeasurementsResponse{}).(*listMeasurementsResponse)
+
+				assert.EqualValues(t, 3, res.Total)
+
+				expected := []int{3, 2, 8}
+				assert.EqualValues(t, len(expected), len(res.Measurements))
+				for i, m := range res.Measurements {
+					verify(expected[i], m)
+				}
+			},
+		},
+		{
+			Name:    "期間指定",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements",
+			Query:   func(q url.Values) {
+				q.Add("from", "2021-01-01T10:00:00Z")
+				q.Add("until", "2021-01-01T12:00:00Z")
+			},
+			Prepare: func(req *http.Request) {
+				// 1: 09:00-11:00, 5: 11:30-13:00, 6: 12:30-14:00, 他: データなし
+				if _, e := db.Exec(`UPDATE measurement SET
+						first_time = CASE id
+							WHEN 1 THEN '2021-01-01T09:00:00Z'::timestamptz
+							WHEN 5 THEN '2021-01-01T11:30:00Z'::timestamptz
+							WHEN 6 THEN '2021-01-01T12:30:00Z'::timestamptz
+						END,
+						last_time = CASE id
+							WHEN 1 THEN '2021-01-01T11:00:00Z'::timestamptz
+							WHEN 5 THEN '2021-01-01T13:00:00Z'::timestamptz
+							WHEN 6 THEN '2021-01-01T14:00:00Z'::timestamptz
+						END`); e != nil {
+					assert.FailNow(t, e.Error())
+				}
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &listMeasurementsResponse{}).(*listMeasurementsResponse)
+
+				assert.EqualValues(t, 2, res.Total)
+
+				expected := []int{5, 1}
+				assert.EqualValues(t, len(expected), len(res.Measurements))
+				for i, m := range res.Measurements {
+					verify(expected[i], m)
+				}
+			},
+		},
+		{
+			Name:    "リスク範囲指定",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements",
+			Query:   func(q url.Values) {
+				q.Add("risk", "2")
+				q.Add("maxRisk", "3")
+			},
+			Prepare: func(req *http.Request) {
+				// 1: 2, 2: 3, 3: 4, 5: 3 (非表示の5を含む), 他: イベントなし
+				ces := []struct{mid int; risk int; hidden bool}{
+					{1, 2, false}, {2, 1, false}, {2, 3, false}, {3, 4, false}, {5, 3, false}, {5, 5, true},
+				}
+				F.Insert(db, model.ComputedEvent{}, 0, len(ces), func(i int, r F.Record) {
+					r["MeasurementId"] = ces[i-1].mid
+					r["Risk"] = ces[i-1].risk
+					r["IsHidden"] = ces[i-1].hidden
+					r["Parameters"] = model.JSON([]byte("{}"))
+				})
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &listMeasurementsResponse{}).(*listMeasurementsResponse)
+
+				assert.EqualValues(t, 3, res.Total)
+
+				expected := []int{5, 2, 1}
+				assert.EqualValues(t, len(expected), len(res.Measurements))
+				for i, m := range res.Measurements {
+					verify(expected[i], m)
+				}
+			},
+		},
+		{
+			Name:    "リスクの下限が上限を超える",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements",
+			Query:   func(q url.Values) {
+				q.Add("risk", "4")
+				q.Add("maxRisk", "3")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
 	}
 
 	httpTests.Run(testHandler(), t, func() {
-		F.Truncate(db, "measurement_terminal", "patient", "measurement")
+		F.Truncate(db, "measurement_terminal", "patient", "measurement", "computed_event")
 
 		// h - p
 		// 1 - [1,2,3]
@@ -208,7 +418,7 @@ func TestMonitorMeasurement_Fetch(t *testing.T) {
 	}
 
 	httpTests.Run(testHandler(), t, func() {
-		F.Truncate(db, "measurement_terminal", "patient", "measurement")
+		F.Truncate(db, "measurement_terminal", "patient", "measurement", "computed_event")
 
 		// h - p
 		// 1 - [1,2,3]
@@ -299,7 +509,7 @@ func TestMonitorMeasurement_GetSilent(t *testing.T) {
 	}
 
 	httpTests.Run(testHandler(), t, func() {
-		F.Truncate(db, "measurement_terminal", "patient", "measurement")
+		F.Truncate(db, "measurement_terminal", "patient", "measurement", "computed_event")
 
 		F.Insert(db, model.Patient{}, 0, 3, func(i int, r F.Record) {
 			r["HospitalId"] = i
@@ -380,7 +590,7 @@ func TestMonitorMeasurement_SetSilent(t *testing.T) {
 	}
 
 	httpTests.Run(testHandler(), t, func() {
-		F.Truncate(db, "measurement_terminal", "patient", "measurement")
+		F.Truncate(db, "measurement_terminal", "patient", "measurement", "computed_event")
 
 		F.Insert(db, model.Patient{}, 0, 3, func(i int, r F.Record) {
 			r["HospitalId"] = i
@@ -452,7 +662,7 @@ func TestMonitorMeasurement_Close(t *testing.T) {
 	}
 
 	httpTests.Run(testHandler(), t, func() {
-		F.Truncate(db, "measurement_terminal", "patient", "measurement")
+		F.Truncate(db, "measurement_terminal", "patient", "measurement", "computed_event")
 
 		F.Insert(db, model.Patient{}, 0, 3, func(i int, r F.Record) {
 			r["HospitalId"] = i
@@ -469,4 +679,88 @@ func TestMonitorMeasurement_Close(t *testing.T) {
 			r["IsClosed"] = false
 		})
 	})
+}
+
+func TestMonitorMeasurement_Reopen(t *testing.T) {
+	auth := (&F.MonitorFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "計測再開",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/1/reopen",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNoContent, rec.Code)
+
+				if a, e := db.Get(model.Measurement{}, 1); e != nil {
+					assert.FailNow(t, e.Error())
+				} else {
+					m := a.(*model.Measurement)
+					assert.EqualValues(t, false, m.IsClosed)
+					assert.Nil(t, m.ClosingMemo)
+					assert.Nil(t, m.ClosedAt)
+				}
+			},
+		},
+		{
+			Name:    "完了していない",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/3/reopen",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			Name:    "アーカイブ済み",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/2/reopen",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+				if a, e := db.Get(model.Measurement{}, 2); e != nil {
+					assert.FailNow(t, e.Error())
+				} else {
+					assert.EqualValues(t, true, a.(*model.Measurement).IsClosed)
+				}
+			},
+		},
+		{
+			Name:    "病院が違う",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/4/reopen",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		F.Truncate(db, "measurement_terminal", "patient", "measurement", "measurement_archive")
+
+		F.Insert(db, model.Patient{}, 0, 3, func(i int, r F.Record) {
+			r["HospitalId"] = i
+		})
+
+		F.Insert(db, model.MeasurementTerminal{}, 0, 3, func(i int, r F.Record) {
+			r["HospitalId"] = 3
+		})
+
+		now := time.Now()
+
+		F.Insert(db, model.Measurement{}, 0, 5, func(i int, r F.Record) {
+			r["Code"] = fmt.Sprintf("m%04d", i)
+			r["PatientId"] = F.If(i <= 3, 1, F.If(i <= 4, 2, 3))
+			r["TerminalId"] = F.If(i <= 3, 1, F.If(i <= 4, 2, 3))
+			r["IsClosed"] = i != 3
+			r["ClosedAt"] = F.If(i != 3, &now, nil)
+		})
+
+		db.Insert(&model.MeasurementArchive{MeasurementId: 2, StorageKey: "archive/2", ArchivedAt: now})
+	})
 }
\ No newline at end of file
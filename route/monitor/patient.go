@@ -17,15 +17,18 @@ import (
 type listPatientsQuery struct {
 	Minutes *int       `query:"minutes"`
 	End     *time.Time `query:"end"`
-	Limit   int `query:"limit"`
-	Offset  int `query:"offset"`
+	Limit   int        `query:"limit"`
+	Offset  int        `query:"offset"`
+	Cursor  string     `query:"cursor"`
+	Sort    string     `query:"sort"`
 }
 
 type listPatientsResponse struct {
 	Patients []*model.Patient `json:"patients"`
-	Total    int64            `json:"total"`
-	Limit    int              `json:"limit"`
-	Offset   int              `json:"offset"`
+	Total      int64            `json:"total"`
+	Limit      int              `json:"limit"`
+	Offset     int              `json:"offset"`
+	NextCursor *string          `json:"nextCursor"`
 }
 
 // listPatients godoc
@@ -38,6 +41,8 @@ type listPatientsResponse struct {
 // @param end query string false "末尾日時。RFC3339形式。"
 // @param limit query int false "最大取得件数。"
 // @param offset query int false "取得オフセット。"
+// @param cursor query string false "前の一覧の`nextCursor`。指定した場合`offset`は無視し、`total`は数えず-1となる。"
+// @param sort query string false "ソートする列。`id` `createdAt` `lastTime`のいずれかで、降順の場合は先頭に`-`を付ける。既定は`-lastTime`。"
 // @success 200 {object} listPatientsResponse "患者一覧。"
 // @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
 // @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
@@ -45,7 +50,7 @@ type listPatientsResponse struct {
 func listPatients(c *shared.Context) error {
 	me := c.Get(shared.ContextMeKey).(*model.HospitalDoctor)
 
-	query := &listPatientsQuery{nil, nil, 100, 0}
+	query := &listPatientsQuery{Limit: 100}
 
 	if e := c.Bind(query); e != nil {
 		return e
@@ -54,6 +59,7 @@ func listPatients(c *shared.Context) error {
 	if e := (v.Errors{
 		"limit": v.Validate(query.Limit, v.Min(0)),
 		"offset": v.Validate(query.Offset, v.Min(0)),
+		"sort": v.Validate(query.Sort, v.In(shared.SortSpecs(C.PatientSorts)...)),
 	}).Filter(); e != nil {
 		return e
 	}
@@ -75,7 +81,12 @@ func listPatients(c *shared.Context) error {
 
 	service := shared.CreateService(S.PatientService{}, c).(*S.PatientService)
 
-	results, total, err := service.List(me.Hospital.Id, begin, end, query.Limit, query.Offset)
+	results, info, err := service.List(me.Hospital.Id, begin, end, &model.Paging{
+		Limit:  query.Limit,
+		Offset: query.Offset,
+		Cursor: query.Cursor,
+		Sort:   query.Sort,
+	})
 
 	if err != nil {
 		return err
@@ -83,9 +94,10 @@ func listPatients(c *shared.Context) error {
 
 	return c.JSON(http.StatusOK, &listPatientsResponse{
 		Patients: results,
-		Total: total,
+		Total: info.Total,
 		Limit: query.Limit,
 		Offset: query.Offset,
+		NextCursor: info.NextCursor,
 	})
 }
 
@@ -0,0 +1,216 @@
+package monitor
+
+import (
+	"net/http"
+
+	v "github.com/go-ozzo/ozzo-validation/v4"
+
+	"github.com/spiker/spiker-server/model"
+	S "github.com/spiker/spiker-server/service"
+	"github.com/spiker/spiker-server/route/shared"
+)
+
+type attachPatientBody struct {
+	PatientId int `json:"patientId"`
+}
+
+// attachPatient godoc
+// @summary 計測を既存の患者に紐付ける。
+// @description 計測が新規の患者として登録された場合などに、同じ病院の別の患者の計測として付け替える。
+// @tags [monitor] Measurement
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param measurement_id path int true "計測記録ID。"
+// @param patient body attachPatientBody true "紐付ける患者。"
+// @success 200 {object} model.Measurement "紐付けた計測。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
+// @failure 404 {object} shared.ErrorResponse "計測もしくは患者が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /1/measurements/{measurement_id}/patient [put]
+func attachPatient(c *shared.Context) error {
+	me := c.Get(shared.ContextMeKey).(*model.HospitalDoctor)
+
+	id := c.IntParam("measurement_id")
+
+	body := &attachPatientBody{}
+
+	if e := c.Bind(body); e != nil {
+		return e
+	}
+
+	if e := (v.Errors{
+		"patientId": v.Validate(body.PatientId, v.Required),
+	}).Filter(); e != nil {
+		return e
+	}
+
+	service := shared.CreateService(S.MeasurementTxService{}, c).(*S.MeasurementTxService)
+
+	if e := service.CheckUpdateByDoctor(me, id); e != nil {
+		return e
+	}
+
+	result, err := service.AttachPatient(id, body.PatientId, me.Hospital.Id)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+type mergePatientBody struct {
+	PatientId int `json:"patientId"`
+}
+
+// mergePatient godoc
+// @summary 重複して登録された患者を統合する。
+// @description 指定した患者の計測と識別子をパスの患者に移し、指定した患者を削除する。パスの患者に値が無い項目は、指定した患者の値を引き継ぐ。
+// @tags [monitor] Patient
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param patient_id path int true "統合先の患者ID。"
+// @param patient body mergePatientBody true "統合元の患者。"
+// @success 200 {object} model.PatientMerge "統合の記録。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
+// @failure 404 {object} shared.ErrorResponse "患者が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /1/patients/{patient_id}/merge [post]
+func mergePatient(c *shared.Context) error {
+	me := c.Get(shared.ContextMeKey).(*model.HospitalDoctor)
+
+	id := c.IntParam("patient_id")
+
+	body := &mergePatientBody{}
+
+	if e := c.Bind(body); e != nil {
+		return e
+	}
+
+	if e := (v.Errors{
+		"patientId": v.Validate(body.PatientId, v.Required),
+	}).Filter(); e != nil {
+		return e
+	}
+
+	service := shared.CreateService(S.PatientTxService{}, c).(*S.PatientTxService)
+
+	result, err := service.Merge(me, id, body.PatientId)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+type listPatientIdentifiersResponse struct {
+	Identifiers []*model.PatientIdentifier `json:"identifiers"`
+}
+
+// listPatientIdentifiers godoc
+// @summary 患者の識別子一覧を取得する。
+// @tags [monitor] Patient
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param patient_id path int true "患者ID。"
+// @success 200 {object} listPatientIdentifiersResponse "識別子一覧。"
+// @failure 404 {object} shared.ErrorResponse "患者が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /1/patients/{patient_id}/identifiers [get]
+func listPatientIdentifiers(c *shared.Context) error {
+	me := c.Get(shared.ContextMeKey).(*model.HospitalDoctor)
+
+	id := c.IntParam("patient_id")
+
+	service := shared.CreateService(S.PatientService{}, c).(*S.PatientService)
+
+	if e := service.CheckAccessByDoctor(me, id); e != nil {
+		return e
+	}
+
+	result, err := service.ListIdentifiers(id)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &listPatientIdentifiersResponse{result})
+}
+
+type addPatientIdentifierBody struct {
+	Value string `json:"value" maxLength:"128"`
+}
+
+// addPatientIdentifier godoc
+// @summary 患者に識別子を登録する。
+// @description 病院の識別規則で計測コードから得た識別子が登録済みの場合、新規の計測はその患者の計測となる。
+// @tags [monitor] Patient
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param patient_id path int true "患者ID。"
+// @param identifier body addPatientIdentifierBody true "識別子。"
+// @success 201 {object} model.PatientIdentifier "登録した識別子。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー、もしくは識別子が登録済み。"
+// @failure 404 {object} shared.ErrorResponse "患者が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /1/patients/{patient_id}/identifiers [post]
+func addPatientIdentifier(c *shared.Context) error {
+	me := c.Get(shared.ContextMeKey).(*model.HospitalDoctor)
+
+	id := c.IntParam("patient_id")
+
+	body := &addPatientIdentifierBody{}
+
+	if e := c.Bind(body); e != nil {
+		return e
+	}
+
+	if e := (v.Errors{
+		"value": v.Validate(body.Value, v.Required, v.RuneLength(1, 128)),
+	}).Filter(); e != nil {
+		return e
+	}
+
+	service := shared.CreateService(S.PatientTxService{}, c).(*S.PatientTxService)
+
+	if e := service.CheckUpdateByDoctor(me, id); e != nil {
+		return e
+	}
+
+	result, err := service.AddIdentifier(id, me.Hospital.Id, body.Value)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, result)
+}
+
+// removePatientIdentifier godoc
+// @summary 患者の識別子を削除する。
+// @tags [monitor] Patient
+// @param Authorization header string true "Bearerトークン。"
+// @param patient_id path int true "患者ID。"
+// @param identifier_id path int true "識別子ID。"
+// @success 204 "処理に成功。"
+// @failure 404 {object} shared.ErrorResponse "患者もしくは識別子が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /1/patients/{patient_id}/identifiers/{identifier_id} [delete]
+func removePatientIdentifier(c *shared.Context) error {
+	me := c.Get(shared.ContextMeKey).(*model.HospitalDoctor)
+
+	id := c.IntParam("patient_id")
+
+	service := shared.CreateService(S.PatientTxService{}, c).(*S.PatientTxService)
+
+	if e := service.CheckUpdateByDoctor(me, id); e != nil {
+		return e
+	}
+
+	if e := service.RemoveIdentifier(id, c.IntParam("identifier_id")); e != nil {
+		return e
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,297 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/gorp.v2"
+
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/test"
+	F "github.com/spiker/spiker-server/test/fixture"
+)
+
+func preparePatientIdentity(db *gorp.DbMap) {
+	F.Truncate(db, "measurement_terminal", "patient", "patient_key", "hospital_key", "measurement", "patient_identifier", "patient_merge")
+
+	// h - p
+	// 1 - [1,3,5]
+	// 2 - [2,4]
+	F.Insert(db, model.Patient{}, 0, 5, func(i int, r F.Record) {
+		r["HospitalId"] = F.If(i%2 == 1, 1, 2)
+		r["Age"] = 30 + i
+		r["NumChildren"] = F.If(i == 3, 2, nil)
+		r["Memo"] = F.If(i == 3, "メモ", "")
+	})
+
+	F.Insert(db, model.MeasurementTerminal{}, 0, 2, func(i int, r F.Record) {
+		r["HospitalId"] = i
+	})
+
+	// p - m
+	// 1 - [1]
+	// 3 - [2,3]
+	// 2 - [4]
+	F.Insert(db, model.Measurement{}, 0, 4, func(i int, r F.Record) {
+		r["Code"] = fmt.Sprintf("m%04d", i)
+		r["PatientId"] = F.If(i == 1, 1, F.If(i <= 3, 3, 2))
+		r["TerminalId"] = F.If(i <= 3, 1, 2)
+	})
+
+	F.Insert(db, model.PatientIdentifier{}, 0, 2, func(i int, r F.Record) {
+		r["HospitalId"] = F.If(i == 1, 1, 2)
+		r["PatientId"] = F.If(i == 1, 3, 2)
+		r["Value"] = fmt.Sprintf("X-%04d", i)
+	})
+}
+
+func TestMonitorPatient_Merge(t *testing.T) {
+	auth := (&F.MonitorFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "統合",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/patients/1/merge",
+			Body:    test.JsonBody(map[string]interface{}{"patientId": 3}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &model.PatientMerge{}).(*model.PatientMerge)
+
+				assert.EqualValues(t, 1, res.PatientId)
+				assert.EqualValues(t, 3, res.MergedPatientId)
+				assert.EqualValues(t, 2, res.Measurements)
+
+				fields := []string{}
+				json.Unmarshal(res.Fields, &fields)
+				assert.EqualValues(t, []string{"numChildren", "memo"}, fields)
+
+				if a, e := db.Get(model.Patient{}, 1); e != nil {
+					assert.FailNow(t, e.Error())
+				} else {
+					p := a.(*model.Patient)
+					assert.EqualValues(t, 31, *p.Age)
+					assert.EqualValues(t, 2, *p.NumChildren)
+					assert.EqualValues(t, "メモ", p.Memo)
+				}
+
+				if a, e := db.Get(model.Patient{}, 3); e != nil {
+					assert.FailNow(t, e.Error())
+				} else {
+					assert.Nil(t, a)
+				}
+
+				for _, id := range []int{1, 2, 3} {
+					if a, e := db.Get(model.Measurement{}, id); e != nil {
+						assert.FailNow(t, e.Error())
+					} else {
+						assert.EqualValues(t, 1, a.(*model.Measurement).PatientId)
+					}
+				}
+
+				if a, e := db.Get(model.PatientIdentifier{}, 1); e != nil {
+					assert.FailNow(t, e.Error())
+				} else {
+					assert.EqualValues(t, 1, a.(*model.PatientIdentifier).PatientId)
+				}
+
+				assert.EqualValues(t, 1, F.Count(t, db, "patient_merge", nil))
+			},
+		},
+		{
+			Name:    "同じ患者",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/patients/1/merge",
+			Body:    test.JsonBody(map[string]interface{}{"patientId": 1}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			Name:    "統合元の病院が違う",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/patients/1/merge",
+			Body:    test.JsonBody(map[string]interface{}{"patientId": 2}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+				assert.EqualValues(t, 5, F.Count(t, db, "patient", nil))
+			},
+		},
+		{
+			Name:    "統合先の病院が違う",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/patients/2/merge",
+			Body:    test.JsonBody(map[string]interface{}{"patientId": 1}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		preparePatientIdentity(db)
+	})
+}
+
+func TestMonitorMeasurement_AttachPatient(t *testing.T) {
+	auth := (&F.MonitorFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "紐付け",
+			Method:  http.MethodPut,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/1/patient",
+			Body:    test.JsonBody(map[string]interface{}{"patientId": 5}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &model.Measurement{}).(*model.Measurement)
+
+				assert.EqualValues(t, 5, res.PatientId)
+				assert.EqualValues(t, "m0001", res.Code)
+
+				if a, e := db.Get(model.Measurement{}, 1); e != nil {
+					assert.FailNow(t, e.Error())
+				} else {
+					assert.EqualValues(t, 5, a.(*model.Measurement).PatientId)
+				}
+			},
+		},
+		{
+			Name:    "患者の病院が違う",
+			Method:  http.MethodPut,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/1/patient",
+			Body:    test.JsonBody(map[string]interface{}{"patientId": 2}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+		{
+			Name:    "計測の病院が違う",
+			Method:  http.MethodPut,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/4/patient",
+			Body:    test.JsonBody(map[string]interface{}{"patientId": 1}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+		{
+			Name:    "患者の指定が無い",
+			Method:  http.MethodPut,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/1/patient",
+			Body:    test.JsonBody(map[string]interface{}{}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		preparePatientIdentity(db)
+	})
+}
+
+func TestMonitorPatient_Identifiers(t *testing.T) {
+	auth := (&F.MonitorFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "一覧",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/patients/3/identifiers",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &listPatientIdentifiersResponse{}).(*listPatientIdentifiersResponse)
+
+				assert.EqualValues(t, 1, len(res.Identifiers))
+				assert.EqualValues(t, "X-0001", res.Identifiers[0].Value)
+			},
+		},
+		{
+			Name:    "登録",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/patients/1/identifiers",
+			Body:    test.JsonBody(map[string]interface{}{"value": "A-0001"}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusCreated, rec.Code)
+				res := F.FromJsonResponse(t, rec, &model.PatientIdentifier{}).(*model.PatientIdentifier)
+
+				assert.EqualValues(t, 1, res.PatientId)
+				assert.EqualValues(t, "A-0001", res.Value)
+				assert.EqualValues(t, 3, F.Count(t, db, "patient_identifier", nil))
+			},
+		},
+		{
+			Name:    "登録済み",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/patients/1/identifiers",
+			Body:    test.JsonBody(map[string]interface{}{"value": "X-0001"}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			Name:    "他の病院で登録済み",
+			Method:  http.MethodPost,
+			Token:   auth.Token(0),
+			Path:    "/1/patients/1/identifiers",
+			Body:    test.JsonBody(map[string]interface{}{"value": "X-0002"}),
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusCreated, rec.Code)
+			},
+		},
+		{
+			Name:    "削除",
+			Method:  http.MethodDelete,
+			Token:   auth.Token(0),
+			Path:    "/1/patients/3/identifiers/1",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNoContent, rec.Code)
+				assert.EqualValues(t, 1, F.Count(t, db, "patient_identifier", nil))
+			},
+		},
+		{
+			Name:    "他の患者の識別子",
+			Method:  http.MethodDelete,
+			Token:   auth.Token(0),
+			Path:    "/1/patients/1/identifiers/1",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+		{
+			Name:    "病院が違う",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/patients/2/identifiers",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		preparePatientIdentity(db)
+	})
+}
@@ -14,6 +14,7 @@ import (
 
 	//"github.com/spiker/spiker-server/route/shared"
 	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/lib/phi"
 	"github.com/spiker/spiker-server/model"
 	"github.com/spiker/spiker-server/test"
 	F "github.com/spiker/spiker-server/test/fixture"
@@ -312,6 +313,10 @@ func TestMonitorPatient_Update(t *testing.T) {
 					assert.EqualValues(t, "メモ", patient.Memo)
 				}
 
+				// 氏名は暗号化して保存される。
+				assert.True(t, phi.IsSealed(*actual.Name))
+				actual.Name = res.Name
+
 				verify(res)
 				verify(actual)
 			},
@@ -361,6 +366,10 @@ func TestMonitorPatient_Update(t *testing.T) {
 					assert.EqualValues(t, "", patient.Memo)
 				}
 
+				// 氏名は暗号化して保存される。
+				assert.True(t, phi.IsSealed(*actual.Name))
+				actual.Name = res.Name
+
 				verify(res)
 				verify(actual)
 			},
@@ -424,7 +433,7 @@ func TestMonitorPatient_Update(t *testing.T) {
 	)
 
 	httpTests.Run(testHandler(), t, func() {
-		F.Truncate(db, "patient")
+		F.Truncate(db, "patient", "patient_key", "hospital_key")
 
 		// h - p
 		// 1 - [1,3,5]
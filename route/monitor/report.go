@@ -0,0 +1,78 @@
+package monitor
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	v "github.com/go-ozzo/ozzo-validation/v4"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/lib/strip"
+	S "github.com/spiker/spiker-server/service"
+	"github.com/spiker/spiker-server/route/shared"
+)
+
+type renderReportQuery struct {
+	From  *time.Time `query:"from"`
+	Until *time.Time `query:"until"`
+	Speed *int       `query:"speed"`
+}
+
+// renderReport godoc
+// @summary 計測の指定期間をCTG記録紙としてPDFで出力する。
+// @description 心拍、TOCO、自動診断イベント、アノテーション、診断のリスクを記録紙の方眼上に描画し、患者と端末の情報、ページ番号、計測の終了メモを記載する。
+// @tags [monitor] Report
+// @produce application/pdf
+// @param Authorization header string true "Bearerトークン。"
+// @param measurement_id path int true "計測記録ID。"
+// @param from query string false "先頭日時。RFC3339形式。省略時は計測の先頭から。"
+// @param until query string false "末尾日時。RFC3339形式。省略時は計測の末尾まで。"
+// @param speed query int false "記録紙の送り速度(cm/分)。1、2、3のいずれか。既定値は3。"
+// @success 200 {file} file "CTG記録紙のPDF。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
+// @failure 404 {object} shared.ErrorResponse "計測記録が存在しない。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /1/measurements/{measurement_id}/report [get]
+func renderReport(c *shared.Context) error {
+	measurementId := c.IntParam("measurement_id")
+
+	if e := checkMeasurementAccess(c, measurementId); e != nil {
+		return e
+	}
+
+	query := &renderReportQuery{}
+
+	if e := c.Bind(query); e != nil {
+		return e
+	}
+
+	speeds := []interface{}{}
+	for _, s := range strip.Speeds {
+		speeds = append(speeds, s)
+	}
+
+	if e := (v.Errors{
+		"speed": v.Validate(query.Speed, v.In(speeds...)),
+	}).Filter(); e != nil {
+		return e
+	}
+
+	speed := C.ReportDefaultSpeed
+
+	if query.Speed != nil {
+		speed = *query.Speed
+	}
+
+	service := shared.CreateService(S.ReportService{}, c).(*S.ReportService)
+
+	result, err := service.RenderStrip(measurementId, query.From, query.Until, speed, shared.CurrentLocation)
+
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="ctg-%d.pdf"`, measurementId))
+
+	return c.Blob(http.StatusOK, "application/pdf", result)
+}
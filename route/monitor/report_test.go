@@ -0,0 +1,123 @@
+package monitor
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/test"
+	F "github.com/spiker/spiker-server/test/fixture"
+)
+
+func TestMonitorReport_Render(t *testing.T) {
+	auth := (&F.MonitorFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+	influx := lib.GetInfluxDB()
+
+	beginTime := time.Date(2021, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "期間指定で出力",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/1/report",
+			Query:   func(q url.Values) {
+				q.Add("from", "2021-01-02T03:04:05+00:00")
+				q.Add("until", "2021-01-02T03:20:05+00:00")
+				q.Add("speed", "1")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.Equal(t, "application/pdf", rec.Header().Get("Content-Type"))
+				assert.True(t, bytes.HasPrefix(rec.Body.Bytes(), []byte("%PDF-")))
+				// 1cm/分では1ページに26分。
+				assert.Contains(t, rec.Body.String(), "/Count 1")
+			},
+		},
+		{
+			Name:    "送り速度が不正",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/1/report",
+			Query:   func(q url.Values) {
+				q.Add("speed", "4")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			Name:    "期間が長すぎる",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/1/report",
+			Query:   func(q url.Values) {
+				q.Add("from", "2021-01-01T00:00:00+00:00")
+				q.Add("until", "2021-01-03T00:00:00+00:00")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			Name:    "病院が違う",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/measurements/2/report",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		F.Truncate(db, "measurement_terminal", "patient", "measurement")
+
+		patients := F.Insert(db, model.Patient{}, 0, 2, func(i int, r F.Record) {
+			r["HospitalId"] = i
+		}).([]*model.Patient)
+
+		terminals := F.Insert(db, model.MeasurementTerminal{}, 0, 2, func(i int, r F.Record) {
+			r["HospitalId"] = i
+		}).([]*model.MeasurementTerminal)
+
+		measurements := F.Insert(db, model.Measurement{}, 0, 2, func(i int, r F.Record) {
+			r["Code"] = fmt.Sprintf("measurement-%04d", i)
+			r["PatientId"] = patients[i-1].Id
+			r["TerminalId"] = terminals[i-1].Id
+		}).([]*model.Measurement)
+
+		assert.NoError(t, influx.Delete("spiker", time.Unix(0, 0), time.Now().Add(time.Duration(24*365*100)*time.Hour), ""))
+
+		points := []lib.Point{}
+
+		for i := 0; i < 600; i++ {
+			points = append(points, &model.HeartRate{
+				MeasurementId: measurements[0].Id,
+				PatientCode: "p",
+				MachineCode: "m",
+				Value: 140,
+				Timestamp: beginTime.Add(time.Duration(i)*time.Second),
+			})
+			points = append(points, &model.TOCO{
+				MeasurementId: measurements[0].Id,
+				PatientCode: "p",
+				MachineCode: "m",
+				Value: 20,
+				Timestamp: beginTime.Add(time.Duration(i)*time.Second),
+			})
+		}
+
+		influx.Insert("spiker", points...)
+	})
+}
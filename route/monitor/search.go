@@ -0,0 +1,56 @@
+package monitor
+
+import (
+	"net/http"
+
+	v "github.com/go-ozzo/ozzo-validation/v4"
+
+	"github.com/spiker/spiker-server/model"
+	S "github.com/spiker/spiker-server/service"
+	"github.com/spiker/spiker-server/route/shared"
+)
+
+type searchQuery struct {
+	Q     string `query:"q"`
+	Limit int    `query:"limit"`
+}
+
+// search godoc
+// @summary 院内の患者、計測記録、計測端末、診断を検索する。
+// @description 検索文字列を空白で区切り、全ての語を含むものを種類毎に取得する。全角・半角、大文字・小文字、ひらがな・カタカナは区別しない。
+// @description 患者は氏名とメモ、計測記録は計測コードと完了時のメモ、計測端末はコードと名前とメモ、診断はメモを対象とする。計測コードは前方一致する。
+// @tags [monitor] Search
+// @produce json
+// @param Authorization header string true "Bearerトークン。"
+// @param q query string true "検索文字列。"
+// @param limit query int false "種類毎の最大取得件数。"
+// @success 200 {object} model.SearchResult "検索結果。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
+// @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
+// @router /1/search [get]
+func search(c *shared.Context) error {
+	me := c.Get(shared.ContextMeKey).(*model.HospitalDoctor)
+
+	query := &searchQuery{"", 20}
+
+	if e := c.Bind(query); e != nil {
+		return e
+	}
+
+	if e := (v.Errors{
+		"q": v.Validate(query.Q, v.Required, v.RuneLength(1, 100)),
+		"limit": v.Validate(query.Limit, v.Min(1), v.Max(100)),
+	}).Filter(); e != nil {
+		return e
+	}
+
+	service := shared.CreateService(S.SearchService{}, c).(*S.SearchService)
+
+	result, err := service.Search(me.Hospital.Id, query.Q, query.Limit)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
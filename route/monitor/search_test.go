@@ -0,0 +1,247 @@
+package monitor
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/test"
+	F "github.com/spiker/spiker-server/test/fixture"
+)
+
+func TestMonitorSearch(t *testing.T) {
+	auth := (&F.MonitorFixture{}).Generate(3)
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	ids := func(result *model.SearchResult) ([]int, []int, []int, []int) {
+		patients, measurements, terminals, diagnoses := []int{}, []int{}, []int{}, []int{}
+		for _, r := range result.Patients {
+			patients = append(patients, r.Id)
+		}
+		for _, r := range result.Measurements {
+			measurements = append(measurements, r.Id)
+		}
+		for _, r := range result.Terminals {
+			terminals = append(terminals, r.Id)
+		}
+		for _, r := range result.Diagnoses {
+			diagnoses = append(diagnoses, r.Id)
+		}
+		return patients, measurements, terminals, diagnoses
+	}
+
+	httpTests := test.HttpTests{
+		{
+			Name:    "氏名",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/search",
+			Query:   func(q url.Values) {
+				q.Add("q", "山田")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &model.SearchResult{}).(*model.SearchResult)
+
+				patients, measurements, terminals, diagnoses := ids(res)
+
+				assert.EqualValues(t, []int{2, 1}, patients)
+				assert.EqualValues(t, []int{}, measurements)
+				assert.EqualValues(t, []int{}, terminals)
+				assert.EqualValues(t, []int{}, diagnoses)
+			},
+		},
+		{
+			Name:    "複数の語とカナの表記",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/search",
+			Query:   func(q url.Values) {
+				q.Add("q", "山田　はなこ")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &model.SearchResult{}).(*model.SearchResult)
+
+				patients, _, _, _ := ids(res)
+
+				assert.EqualValues(t, []int{1}, patients)
+				assert.EqualValues(t, "山田 ハナコ", *res.Patients[0].Name)
+			},
+		},
+		{
+			Name:    "患者のメモ",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/search",
+			Query:   func(q url.Values) {
+				q.Add("q", "ヤマダ")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &model.SearchResult{}).(*model.SearchResult)
+
+				patients, _, _, _ := ids(res)
+
+				assert.EqualValues(t, []int{3}, patients)
+			},
+		},
+		{
+			Name:    "計測コードの前方一致",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/search",
+			Query:   func(q url.Values) {
+				q.Add("q", "Ｍ-000")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &model.SearchResult{}).(*model.SearchResult)
+
+				_, measurements, _, _ := ids(res)
+
+				assert.EqualValues(t, []int{3, 2, 1}, measurements)
+				assert.EqualValues(t, "m-0003", res.Measurements[0].Code)
+			},
+		},
+		{
+			Name:    "完了時のメモ",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/search",
+			Query:   func(q url.Values) {
+				q.Add("q", "transfer")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &model.SearchResult{}).(*model.SearchResult)
+
+				_, measurements, _, _ := ids(res)
+
+				assert.EqualValues(t, []int{2}, measurements)
+			},
+		},
+		{
+			Name:    "計測端末と診断のメモ",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/search",
+			Query:   func(q url.Values) {
+				q.Add("q", "徐脈")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &model.SearchResult{}).(*model.SearchResult)
+
+				patients, measurements, terminals, diagnoses := ids(res)
+
+				assert.EqualValues(t, []int{}, patients)
+				assert.EqualValues(t, []int{}, measurements)
+				assert.EqualValues(t, []int{2}, terminals)
+				assert.EqualValues(t, []int{1}, diagnoses)
+			},
+		},
+		{
+			Name:    "件数指定",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/search",
+			Query:   func(q url.Values) {
+				q.Add("q", "m-000")
+				q.Add("limit", "2")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				res := F.FromJsonResponse(t, rec, &model.SearchResult{}).(*model.SearchResult)
+
+				_, measurements, _, _ := ids(res)
+
+				assert.EqualValues(t, []int{3, 2}, measurements)
+			},
+		},
+		{
+			Name:    "空白のみ",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/search",
+			Query:   func(q url.Values) {
+				q.Add("q", "　 ")
+			},
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			Name:    "検索文字列なし",
+			Method:  http.MethodGet,
+			Token:   auth.Token(0),
+			Path:    "/1/search",
+			Check:   func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+	}
+
+	httpTests.Run(testHandler(), t, func() {
+		F.Truncate(db, "diagnosis", "measurement", "measurement_terminal", "patient")
+
+		// p - h - 氏名        - メモ
+		// 1 - 1 - 山田 ハナコ -
+		// 2 - 1 - 山田 太郎   - 経過観察
+		// 3 - 1 - 佐藤 花子   - やまだ産院より転院
+		// 4 - 2 - 山田 次郎   -
+		ps := []struct{h int; name string; memo string}{
+			{1, "山田 ハナコ", ""}, {1, "山田 太郎", "経過観察"}, {1, "佐藤 花子", "やまだ産院より転院"}, {2, "山田 次郎", ""},
+		}
+		F.Insert(db, model.Patient{}, 0, len(ps), func(i int, r F.Record) {
+			r["HospitalId"] = ps[i-1].h
+			r["Name"] = ps[i-1].name
+			r["Memo"] = ps[i-1].memo
+		})
+
+		// t - h - メモ
+		// 1 - 1 -
+		// 2 - 1 - 徐脈の誤検知が多い
+		// 3 - 2 - 徐脈
+		F.Insert(db, model.MeasurementTerminal{}, 0, 3, func(i int, r F.Record) {
+			r["HospitalId"] = F.If(i <= 2, 1, 2)
+			r["Code"] = fmt.Sprintf("terminal-%04d", i)
+			r["Memo"] = F.If(i == 2, "徐脈の誤検知が多い", F.If(i == 3, "徐脈", ""))
+		})
+
+		// m - p - t - 完了時のメモ
+		// 1 - 1 - 1 -
+		// 2 - 2 - 1 - transfer to NICU
+		// 3 - 3 - 2 -
+		// 4 - 4 - 3 - transfer
+		now := time.Now()
+		F.Insert(db, model.Measurement{}, 0, 4, func(i int, r F.Record) {
+			r["Code"] = fmt.Sprintf("m-%04d", i)
+			r["PatientId"] = i
+			r["TerminalId"] = F.If(i <= 2, 1, i-1)
+			r["CreatedAt"] = now.Add(time.Duration(i)*time.Hour)
+			if i == 2 {
+				r["ClosingMemo"] = "transfer to NICU"
+			} else if i == 4 {
+				r["ClosingMemo"] = "transfer"
+			}
+		})
+
+		// d - m - メモ
+		// 1 - 1 - 遅発一過性徐脈
+		// 2 - 4 - 徐脈
+		F.Insert(db, model.Diagnosis{}, 0, 2, func(i int, r F.Record) {
+			r["MeasurementId"] = F.If(i == 1, 1, 4)
+			r["Memo"] = F.If(i == 1, "遅発一過性徐脈", "徐脈")
+			r["CreatedAt"] = now
+		})
+	})
+}
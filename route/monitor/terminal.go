@@ -6,7 +6,7 @@ import (
 
 	v "github.com/go-ozzo/ozzo-validation/v4"
 
-	//C "github.com/spiker/spiker-server/constant"
+	C "github.com/spiker/spiker-server/constant"
 	"github.com/spiker/spiker-server/model"
 	S "github.com/spiker/spiker-server/service"
 	//"github.com/spiker/spiker-server/resource/rds"
@@ -17,13 +17,16 @@ import (
 type listTerminalsQuery struct {
 	Limit  int    `query:"limit"`
 	Offset int    `query:"offset"`
+	Cursor string `query:"cursor"`
+	Sort   string `query:"sort"`
 }
 
 type listTerminalsResponse struct {
 	Terminals []*model.MeasurementTerminal `json:"terminals"`
-	Total     int64                        `json:"total"`
-	Limit     int                          `json:"limit"`
-	Offset    int                          `json:"offset"`
+	Total      int64                        `json:"total"`
+	Limit      int                          `json:"limit"`
+	Offset     int                          `json:"offset"`
+	NextCursor *string                      `json:"nextCursor"`
 }
 
 // listTerminals godoc
@@ -33,13 +36,16 @@ type listTerminalsResponse struct {
 // @param Authorization header string true "Bearerトークン。"
 // @param limit query int false "最大取得件数。"
 // @param offset query int false "取得オフセット。"
+// @param cursor query string false "前の一覧の`nextCursor`。指定した場合`offset`は無視し、`total`は数えず-1となる。"
+// @param sort query string false "ソートする列。`id` `code` `name`のいずれかで、降順の場合は先頭に`-`を付ける。既定は`id`。"
 // @success 200 {object} listTerminalsResponse "計測端末一覧。"
+// @failure 400 {object} shared.ErrorResponse "バリデーションエラー。"
 // @failure 500 {object} shared.ErrorResponse "サーバエラーが発生。"
 // @router /1/terminals [get]
 func listTerminals(c *shared.Context) error {
 	me := c.Get(shared.ContextMeKey).(*model.HospitalDoctor)
 
-	query := &listTerminalsQuery{100, 0}
+	query := &listTerminalsQuery{Limit: 100}
 
 	if e := c.Bind(query); e != nil {
 		return e
@@ -48,13 +54,19 @@ func listTerminals(c *shared.Context) error {
 	if e := (v.Errors{
 		"limit": v.Validate(query.Limit, v.Min(0)),
 		"offset": v.Validate(query.Offset, v.Min(0)),
+		"sort": v.Validate(query.Sort, v.In(shared.SortSpecs(C.TerminalSorts)...)),
 	}).Filter(); e != nil {
 		return e
 	}
 
-	service := shared.CreateService(S.InstrumentService{}, c).(*S.InstrumentService)
+	service := shared.CreateService(S.TerminalService{}, c).(*S.TerminalService)
 
-	results, total, err := service.List(me.Hospital.Id, query.Limit, query.Offset)
+	results, info, err := service.List(me.Hospital.Id, &model.Paging{
+		Limit:  query.Limit,
+		Offset: query.Offset,
+		Cursor: query.Cursor,
+		Sort:   query.Sort,
+	})
 
 	if err != nil {
 		return err
@@ -62,9 +74,10 @@ func listTerminals(c *shared.Context) error {
 
 	return c.JSON(http.StatusOK, &listTerminalsResponse{
 		Terminals: results,
-		Total: total,
+		Total: info.Total,
 		Limit: query.Limit,
 		Offset: query.Offset,
+		NextCursor: info.NextCursor,
 	})
 }
 
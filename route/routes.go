@@ -8,6 +8,7 @@ import (
 	"github.com/spiker/spiker-server/route/annotation"
 	"github.com/spiker/spiker-server/route/asset"
 	"github.com/spiker/spiker-server/route/ctg"
+	"github.com/spiker/spiker-server/route/fhir"
 	app_middleware "github.com/spiker/spiker-server/route/middleware"
 	"github.com/spiker/spiker-server/route/monitor"
 	"github.com/spiker/spiker-server/route/shared"
@@ -32,6 +33,7 @@ func NewHandler() *echo.Echo {
 	ctg.RegisterAPI(e)
 	annotation.RegisterAPI(e)
 	asset.RegisterAPI(e)
+	fhir.RegisterAPI(e)
 
 	return e
 }
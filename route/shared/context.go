@@ -14,6 +14,7 @@ import (
 const (
 	contextTxDatabaseKey    string = "tx_db"
 	contextReadDatabaseKey         = "read_db"
+	contextAfterCommitKey          = "after_commit"
 	ContextSessionLoggerKey        = "session_logger"
 	ContextFirebaseTokenKey        = "firebase_token"
 	ContextI18NLangKey             = "lang_key"
@@ -80,9 +81,40 @@ func (c *Context) Commit() {
 	db := c.Get(contextTxDatabaseKey)
 	if db != nil {
 		if _db, ok := db.(*gorp.Transaction); ok {
-			_db.Commit()
+			if e := _db.Commit(); e != nil {
+				return
+			}
 		}
 	}
+	c.runAfterCommit()
+}
+
+// コミットした後に実行する処理を登録する。ロールバックした場合は実行しない。
+// 処理はそれぞれ新しいトランザクションで実行し、エラーを返した場合はロールバックしてログに残す。
+func (c *Context) AfterCommit(f func(c *Context) error) {
+	hooks, _ := c.Get(contextAfterCommitKey).([]func(c *Context) error)
+	c.Set(contextAfterCommitKey, append(hooks, f))
+}
+
+func (c *Context) runAfterCommit() {
+	hooks, _ := c.Get(contextAfterCommitKey).([]func(c *Context) error)
+
+	c.Set(contextAfterCommitKey, nil)
+
+	for _, f := range hooks {
+		c.Set(contextTxDatabaseKey, nil)
+
+		if e := f(c); e != nil {
+			logrus.WithFields(logrus.Fields{
+				"error": e,
+			}).Warning("after commit failed")
+			c.Rollback()
+		} else {
+			c.Commit()
+		}
+	}
+
+	c.Set(contextTxDatabaseKey, nil)
 }
 
 func (c *Context) Rollback() {
@@ -8,6 +8,7 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/sirupsen/logrus"
 	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/resource/storage"
 	S "github.com/spiker/spiker-server/service"
 	"gopkg.in/gorp.v2"
 )
@@ -57,6 +58,11 @@ func CreateService(obj interface{}, c echo.Context) interface{} {
 			valueField.Set(reflect.ValueOf(
 				lib.GetInfluxDB(),
 			))
+		} else if valueType == reflect.TypeOf((*storage.Storage)(nil)).Elem() {
+			// ストレージが設定されていない場合はnilのままとする。
+			if s := storage.UserStorage(); s != nil {
+				valueField.Set(reflect.ValueOf(s))
+			}
 		} else if valueType == reflect.TypeOf(lib.Localizer{}) {
 			localizer := c.Get(ContextI18NLangKey).(*lib.Localizer)
 			valueField.Set(reflect.ValueOf(localizer))
@@ -9,3 +9,14 @@ func ValidateImage(body []byte) (string, error) {
 	_, format, err := image.DecodeConfig(bytes.NewReader(body))
 	return format, err
 }
+
+// ソートに指定できる値を、昇順と"-"を付けた降順の両方について列挙する。
+func SortSpecs(names []string) []interface{} {
+	specs := []interface{}{}
+
+	for _, n := range names {
+		specs = append(specs, n, "-"+n)
+	}
+
+	return specs
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spiker/spiker-server/config"
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/lib/hl7"
+	S "github.com/spiker/spiker-server/service"
+)
+
+// 1つのメッセージの最大バイト数。
+const maximumFrameSize = 1024 * 1024
+
+type Args struct {
+	hospitalUuid string
+	address      string
+	location     *time.Location
+	allowed      []*net.IPNet
+}
+
+func parseArgs() (*Args, error) {
+	address := flag.String("l", "127.0.0.1:2575", "Address to listen for MLLP connections")
+	zone := flag.String("z", "Asia/Tokyo", "Time zone of datetimes without offset in messages")
+	allow := flag.String("a", "", "Comma separated IP addresses or CIDRs of peers allowed to connect (required unless listening on loopback)")
+
+	flag.Parse()
+
+	args := flag.Args()
+
+	if len(args) != 1 {
+		return nil, fmt.Errorf("usage: go run script/adt_listener/main.go [-l address] [-z zone] [-a peers] [hospital_uuid]")
+	}
+
+	location, err := time.LoadLocation(*zone)
+
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := []*net.IPNet{}
+
+	for _, v := range strings.Split(*allow, ",") {
+		v = strings.TrimSpace(v)
+
+		if v == "" {
+			continue
+		}
+
+		if !strings.Contains(v, "/") {
+			if ip := net.ParseIP(v); ip == nil {
+				return nil, fmt.Errorf("Invalid peer address: %s", v)
+			} else if ip.To4() != nil {
+				v += "/32"
+			} else {
+				v += "/128"
+			}
+		}
+
+		if _, n, e := net.ParseCIDR(v); e != nil {
+			return nil, fmt.Errorf("Invalid peer address: %s", v)
+		} else {
+			allowed = append(allowed, n)
+		}
+	}
+
+	// 患者情報を受け取るため、ループバック以外で待ち受ける場合は接続元を限定する。
+	if len(allowed) == 0 {
+		if host, _, e := net.SplitHostPort(*address); e != nil {
+			return nil, e
+		} else if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			return nil, fmt.Errorf("Peers must be specified with -a to listen on %s", *address)
+		}
+	}
+
+	return &Args{args[0], *address, location, allowed}, nil
+}
+
+// 接続元が許可されているかを返す。許可する接続元が指定されていない場合はループバックで待ち受けているため、全て許可する。
+func isAllowed(addr net.Addr, allowed []*net.IPNet) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	tcp, ok := addr.(*net.TCPAddr)
+
+	if !ok {
+		return false
+	}
+
+	for _, n := range allowed {
+		if n.Contains(tcp.IP) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ADTメッセージを病院の患者に反映する。コミットに失敗した場合もエラーとし、送信元に再送させる。
+func apply(hospitalId int, adt *hl7.ADT, now time.Time) error {
+	tx, err := lib.GetDB(lib.WriteDBKey).Begin()
+
+	if err != nil {
+		return err
+	}
+
+	service := &S.ADTTxService{
+		Service: nil,
+		DB: tx,
+	}
+
+	if _, e := service.Apply(hospitalId, adt, now); e != nil {
+		tx.Rollback()
+		return e
+	}
+
+	return tx.Commit()
+}
+
+// 受信したメッセージを処理し、応答を返す。患者情報を含むため、ログにはイベント種別とメッセージ制御IDのみを残す。
+func handle(data []byte, hospitalId int, location *time.Location) []byte {
+	now := time.Now()
+
+	message, err := hl7.Parse(data)
+
+	if err != nil {
+		log.Printf("Rejected a message: %v", err)
+		return hl7.Ack(nil, hl7.AckReject, err.Error(), now)
+	}
+
+	adt, err := hl7.ParseADT(message, location)
+
+	if err != nil {
+		log.Printf("Rejected message %s: %v", message.ControlId(), err)
+		return hl7.Ack(message, hl7.AckReject, err.Error(), now)
+	}
+
+	if e := apply(hospitalId, adt, now); e != nil {
+		log.Printf("Failed to apply %s message %s: %v", adt.Event, adt.ControlId, e)
+		return hl7.Ack(message, hl7.AckError, e.Error(), now)
+	}
+
+	log.Printf("Applied %s message %s", adt.Event, adt.ControlId)
+
+	return hl7.Ack(message, hl7.AckAccept, "", now)
+}
+
+func serve(conn net.Conn, hospitalId int, location *time.Location, allowed []*net.IPNet) {
+	defer conn.Close()
+
+	if !isAllowed(conn.RemoteAddr(), allowed) {
+		log.Printf("Refused a connection from %s", conn.RemoteAddr())
+		return
+	}
+
+	reader := bufio.NewReader(conn)
+
+	for {
+		data, err := hl7.ReadFrame(reader, maximumFrameSize)
+
+		if err == hl7.ErrFrameTooLarge {
+			// 残りのデータからメッセージの区切りを判断できないため、拒否を返して切断する。
+			log.Printf("Rejected a message from %s: %v", conn.RemoteAddr(), err)
+			hl7.WriteFrame(conn, hl7.Ack(nil, hl7.AckReject, err.Error(), time.Now()))
+			return
+		} else if err != nil {
+			if err != io.EOF {
+				log.Printf("Failed to read from %s: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+
+		if e := hl7.WriteFrame(conn, handle(data, hospitalId, location)); e != nil {
+			log.Printf("Failed to write to %s: %v", conn.RemoteAddr(), e)
+			return
+		}
+	}
+}
+
+// HL7 v2のADTメッセージをMLLPで受信し、病院の患者を登録、更新する。
+func main() {
+	// REVIEW 環境固定。
+	os.Setenv("SERVER_ENV", "prod")
+
+	var args *Args
+
+	if a, e := parseArgs(); e != nil {
+		log.Fatal(e)
+	} else {
+		args = a
+	}
+
+	// 設定
+	config.SetupAll()
+
+	hs := &S.HospitalService{
+		Service: nil,
+		DB: lib.GetDB(lib.WriteDBKey),
+	}
+
+	hospital, err := hs.InquireByUuid(args.hospitalUuid)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	listener, err := net.Listen("tcp", args.address)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Listening ADT messages for hospital %d on %s", hospital.Id, args.address)
+
+	for {
+		conn, err := listener.Accept()
+
+		if err != nil {
+			log.Printf("Failed to accept a connection: %v", err)
+			continue
+		}
+
+		go serve(conn, hospital.Id, args.location, args.allowed)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/spiker/spiker-server/config"
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/resource/storage"
+	S "github.com/spiker/spiker-server/service"
+)
+
+// 病院の保存期間を過ぎた計測の計測データをストレージにアーカイブし、InfluxDBから削除する。
+func main() {
+	// REVIEW 環境固定。
+	os.Setenv("SERVER_ENV", "prod")
+
+	// 設定
+	config.SetupAll()
+
+	service := &S.MeasurementArchiveService{
+		Service: nil,
+		DB: lib.GetDB(lib.WriteDBKey),
+		Influx: lib.GetInfluxDB(),
+		Storage: storage.UserStorage(),
+	}
+
+	now := time.Now()
+
+	measurements, err := service.Candidates(now)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	failed := false
+
+	for _, m := range measurements {
+		if r, e := service.Archive(m, now); e != nil {
+			failed = true
+			log.Printf("Failed to archive measurement %d: %v", m.Id, e)
+		} else {
+			log.Printf("Succeeded to archive measurement %d: %d records, %d computed events, %d annotated events", m.Id, r.Records, r.ComputedEvents, r.AnnotatedEvents)
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
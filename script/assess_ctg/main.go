@@ -11,6 +11,7 @@ import (
 	"github.com/spiker/spiker-server/config"
 	"github.com/spiker/spiker-server/lib"
 	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/resource/storage"
 	S "github.com/spiker/spiker-server/service"
 )
 
@@ -222,8 +223,10 @@ func main() {
 			log.Println(e)
 		} else if !status {
 			tx.Rollback()
+		} else if e := tx.Commit(); e != nil {
+			log.Printf("Failed to commit diagnoses: %v", e)
 		} else {
-			tx.Commit()
+			captureSnapshots(diagnoses, diagnosisTime)
 		}
 	}()
 
@@ -236,4 +239,24 @@ func main() {
 	} else {
 		log.Printf("Succeeded to register %d diagnoses", len(diagnoses))
 	}
-}
\ No newline at end of file
+}
+
+// 登録された診断の計測について、高リスクのイベントのスナップショットを保存する。
+func captureSnapshots(diagnoses []*model.DiagnosisEntity, diagnosisTime time.Time) {
+	measurementIds := []int{}
+
+	for _, d := range diagnoses {
+		measurementIds = append(measurementIds, d.MeasurementId)
+	}
+
+	if snapshots, e := (&S.SnapshotService{
+		Service: nil,
+		DB: lib.GetDB(lib.WriteDBKey),
+		Influx: lib.GetInfluxDB(),
+		Storage: storage.UserStorage(),
+	}).CaptureForMeasurements(measurementIds, diagnosisTime, time.Local); e != nil {
+		log.Printf("Failed to capture snapshots: %v", e)
+	} else {
+		log.Printf("Succeeded to capture %d snapshots", len(snapshots))
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/spiker/spiker-server/config"
+	"github.com/spiker/spiker-server/lib"
+	S "github.com/spiker/spiker-server/service"
+)
+
+// 病院の無受信期間を過ぎた計測を自動的に完了状態にする。
+func main() {
+	// REVIEW 環境固定。
+	os.Setenv("SERVER_ENV", "prod")
+
+	// 設定
+	config.SetupAll()
+
+	tx, err := lib.GetDB(lib.WriteDBKey).Begin()
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	service := &S.MeasurementTxService{
+		Service: nil,
+		DB: tx,
+		Influx: lib.GetInfluxDB(),
+	}
+
+	measurements, err := service.CloseInactive(time.Now())
+
+	if err != nil {
+		tx.Rollback()
+		log.Fatal(err)
+	}
+
+	if e := tx.Commit(); e != nil {
+		log.Fatal(e)
+	}
+
+	for _, m := range measurements {
+		log.Printf("Closed inactive measurement %d", m.Id)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/spiker/spiker-server/config"
+	"github.com/spiker/spiker-server/lib"
+)
+
+// 病院内の検索で用いるインデックス。
+// 部分一致(ILIKE)にはpg_trgmのGINインデックスを、メモの全文検索にはto_tsvector('simple', ...)の式インデックスを用いる。
+// 式インデックスはresource/rds/search.goの条件と同じ式とする。
+var statements = []string{
+	`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+
+	`CREATE INDEX IF NOT EXISTS patient_name_trgm_idx ON patient USING gin (name gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS patient_memo_trgm_idx ON patient USING gin (memo gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS measurement_code_trgm_idx ON measurement USING gin (code gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS measurement_closing_memo_trgm_idx ON measurement USING gin (closing_memo gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS measurement_terminal_code_trgm_idx ON measurement_terminal USING gin (code gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS measurement_terminal_name_trgm_idx ON measurement_terminal USING gin (name gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS measurement_terminal_memo_trgm_idx ON measurement_terminal USING gin (memo gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS diagnosis_memo_trgm_idx ON diagnosis USING gin (memo gin_trgm_ops)`,
+
+	`CREATE INDEX IF NOT EXISTS patient_memo_fts_idx ON patient USING gin (to_tsvector('simple', memo))`,
+	`CREATE INDEX IF NOT EXISTS measurement_closing_memo_fts_idx ON measurement USING gin (to_tsvector('simple', closing_memo))`,
+	`CREATE INDEX IF NOT EXISTS measurement_terminal_memo_fts_idx ON measurement_terminal USING gin (to_tsvector('simple', memo))`,
+	`CREATE INDEX IF NOT EXISTS diagnosis_memo_fts_idx ON diagnosis USING gin (to_tsvector('simple', memo))`,
+
+	// 暗号化された氏名の索引は索引の値から患者を引く。
+	`CREATE INDEX IF NOT EXISTS patient_name_token_token_idx ON patient_name_token (token, patient_id)`,
+}
+
+// 病院内の検索で用いるインデックスを作成する。作成済みのインデックスはそのままとする。
+func main() {
+	// REVIEW 環境固定。
+	os.Setenv("SERVER_ENV", "prod")
+
+	// 設定
+	config.SetupAll()
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	for _, s := range statements {
+		if _, e := db.Exec(s); e != nil {
+			log.Fatalf("Failed to execute %s: %v", s, e)
+		}
+	}
+
+	log.Println("Created search indexes")
+}
@@ -1,99 +1,182 @@
 package main
 
 import (
-	"encoding/csv"
+	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"net/http"
+	"net/url"
 	"os"
-	//"time"
+	"strconv"
+	"time"
 
-	"github.com/spiker/spiker-server/config"
 	C "github.com/spiker/spiker-server/constant"
-	"github.com/spiker/spiker-server/lib"
-	//"github.com/spiker/spiker-server/model"
-	S "github.com/spiker/spiker-server/service"
 )
 
-type ExportValue struct {
-	PatientCode string
-	FHR1        int
-	UC          int
-	Timestamp   int64
-}
+// アノテーターとしてログインし、アクセストークンを取得する。
+func login(args *Args) (string, error) {
+	body, err := json.Marshal(map[string]string{
+		"loginId": args.loginId,
+		"password": args.password,
+	})
 
-func fetchData(hospitalUuid string, measurementCode string) ([]*ExportValue, error) {
-	db := lib.GetDB(lib.ReadDBKey)
+	if err != nil {
+		return "", err
+	}
 
-	// 病院取得。
-	hospital, err := (&S.HospitalService{
-		Service: nil,
-		DB: db,
-	}).InquireByUuid(hospitalUuid)
+	res, err := http.Post(args.server + "/annotation/login", "application/json", bytes.NewBuffer(body))
 
 	if err != nil {
-		return nil, err
+		return "", err
+	}
+
+	defer res.Body.Close()
+
+	content, err := ioutil.ReadAll(res.Body)
+
+	if err != nil {
+		return "", err
+	} else if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Failed to login: %s %s", res.Status, string(content))
+	}
+
+	result := struct {
+		AccessToken string `json:"accessToken"`
+	}{}
+
+	if e := json.Unmarshal(content, &result); e != nil {
+		return "", e
+	}
+
+	return result.AccessToken, nil
+}
+
+// 認証付きのエクスポートAPIから出力ファイルを取得する。アクセス権の確認や監査はサーバ側で行われる。
+func export(args *Args, token string) ([]byte, error) {
+	query := url.Values{}
+	query.Add("format", string(args.format))
+
+	for _, id := range args.measurementIds {
+		query.Add("measurements", strconv.Itoa(id))
+	}
+
+	if args.from != nil {
+		query.Add("from", args.from.Format(time.RFC3339))
+	}
+	if args.until != nil {
+		query.Add("until", args.until.Format(time.RFC3339))
 	}
 
-	// 計測取得。
-	measurement, err := (&S.MeasurementService{
-		Service: nil,
-		DB: db,
-	}).InquireByCode(hospital.Id, measurementCode)
+	endpoint := fmt.Sprintf("%s/annotation/hospitals/%s/export?%s", args.server, url.PathEscape(args.hospitalUuid), query.Encode())
+
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
 
 	if err != nil {
 		return nil, err
 	}
 
-	// 心拍。
-	service := &S.DataService{
-		Service: nil,
-		DB: db,
-		Influx: lib.GetInfluxDB(),
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+	res, err := (&http.Client{}).Do(req)
+
+	if err != nil {
+		return nil, err
 	}
 
-	values := []*ExportValue{}
+	defer res.Body.Close()
 
-	if hrs, e := service.ListCTGData(measurement.Id, C.MeasurementTypeHeartRate, nil, nil); e != nil {
-		return nil, e
-	} else if ucs, e := service.ListCTGData(measurement.Id, C.MeasurementTypeTOCO, nil, nil); e != nil {
-		return nil, e
-	} else if len(hrs) != len(ucs) {
-		return nil, fmt.Errorf("Sizes of FHR1(%d) and UC(%d) are not the same.")
-	} else {
-		for i, hr := range hrs {
-			values = append(values, &ExportValue{
-				PatientCode: measurementCode,
-				FHR1: hr.Value,
-				UC: ucs[i].Value,
-				Timestamp: hr.ObservedAt,
-			})
-		}
+	content, err := ioutil.ReadAll(res.Body)
 
-		return values, nil
+	if err != nil {
+		return nil, err
+	} else if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Failed to export: %s %s", res.Status, string(content))
 	}
+
+	return content, nil
 }
 
 type Args struct {
-	hospitalUuid    string
-	measurementCode string
+	server         string
+	loginId        string
+	password       string
+	hospitalUuid   string
+	measurementIds []int
+	format         C.ExportFormat
+	output         string
+	from           *time.Time
+	until          *time.Time
 }
 
 func parseArgs() (*Args, error) {
+	var server, format, output, from, until string
+
+	flag.StringVar(&server, "server", "http://localhost:1323", "Base URL of the API server")
+	flag.StringVar(&format, "format", string(C.ExportFormatCSV), "Output format, edf or csv")
+	flag.StringVar(&output, "o", "", "Output file (default: stdout)")
+	flag.StringVar(&from, "from", "", "Beginning of the range in RFC3339 format (default: first data)")
+	flag.StringVar(&until, "until", "", "End of the range in RFC3339 format (default: last data)")
+
 	flag.Parse()
 
 	args := flag.Args()
 
-	if len(args) != 2 {
-		return nil, fmt.Errorf("usage: go run script/export_ctg/main.go [hospital_uuid] [measurement_code]")
+	if len(args) < 2 {
+		return nil, fmt.Errorf("usage: SPIKER_LOGIN_ID=... SPIKER_PASSWORD=... go run script/export_ctg/main.go [-server url] [-format edf|csv] [-o file] [-from time] [-until time] [hospital_uuid] [measurement_id...]")
+	}
+
+	// 認証情報はコマンド履歴に残らないよう環境変数で受け取る。
+	result := &Args{
+		server: server,
+		loginId: os.Getenv("SPIKER_LOGIN_ID"),
+		password: os.Getenv("SPIKER_PASSWORD"),
+		hospitalUuid: args[0],
+		measurementIds: []int{},
+		format: C.ExportFormat(format),
+		output: output,
+	}
+
+	if result.loginId == "" || result.password == "" {
+		return nil, fmt.Errorf("SPIKER_LOGIN_ID and SPIKER_PASSWORD must be set")
+	}
+
+	for _, arg := range args[1:] {
+		if id, e := strconv.Atoi(arg); e != nil {
+			return nil, fmt.Errorf("Invalid measurement id: %s", arg)
+		} else {
+			result.measurementIds = append(result.measurementIds, id)
+		}
+	}
+
+	parseTime := func(value string) (*time.Time, error) {
+		if value == "" {
+			return nil, nil
+		} else if t, e := time.Parse(time.RFC3339, value); e != nil {
+			return nil, e
+		} else {
+			return &t, nil
+		}
+	}
+
+	if t, e := parseTime(from); e != nil {
+		return nil, e
+	} else {
+		result.from = t
 	}
 
-	return &Args{args[0], args[1]}, nil
+	if t, e := parseTime(until); e != nil {
+		return nil, e
+	} else {
+		result.until = t
+	}
+
+	return result, nil
 }
 
 func main() {
-	os.Setenv("SERVER_ENV", "prod")
-
 	// 引数
 	var args *Args
 
@@ -103,30 +186,21 @@ func main() {
 		args = a
 	}
 
-	// 設定
-	config.SetupAll()
+	// 認証
+	token, err := login(args)
 
-	// データ取得
-	if values, e := fetchData(args.hospitalUuid, args.measurementCode); e != nil {
-		log.Fatal(e)
-	} else {
-		rows := [][]string{}
-
-		for _, v := range values {
-			rows = append(rows, []string{
-				v.PatientCode,
-				fmt.Sprintf("%d", v.FHR1),
-				fmt.Sprintf("%d", v.UC),
-				fmt.Sprintf("%d", v.Timestamp),
-			})
-		}
-
-		w := csv.NewWriter(os.Stdout)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-		if e := w.WriteAll(rows); e != nil {
+	// 出力
+	if content, e := export(args, token); e != nil {
+		log.Fatal(e)
+	} else if args.output == "" {
+		if _, e := os.Stdout.Write(content); e != nil {
 			log.Fatal(e)
 		}
-
-		w.Flush()
+	} else if e := ioutil.WriteFile(args.output, content, 0644); e != nil {
+		log.Fatal(e)
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/spiker/spiker-server/config"
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/resource/storage"
+	S "github.com/spiker/spiker-server/service"
+)
+
+// 未実行の研究用データセットの出力ジョブを順に実行する。
+func main() {
+	// REVIEW 環境固定。
+	os.Setenv("SERVER_ENV", "prod")
+
+	// 設定
+	config.SetupAll()
+
+	research := config.ResearchConfig()
+
+	// 鍵が無い、または短い場合は、ジョブを取得する前に中止する。
+	if e := research.Validate(); e != nil {
+		log.Fatal(e)
+	}
+
+	service := &S.ResearchExportService{
+		Service: nil,
+		DB: lib.GetDB(lib.WriteDBKey),
+		Influx: lib.GetInfluxDB(),
+		Storage: storage.UserStorage(),
+	}
+
+	jobs, err := service.Claim(time.Now())
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	failed := false
+
+	for _, job := range jobs {
+		if e := service.Execute(job, research.Secret, research.ShiftDays); e != nil {
+			failed = true
+			log.Printf("Failed to export research dataset %d: %v", job.Id, e)
+		} else {
+			log.Printf("Succeeded to export research dataset %d: %d patients, %d measurements", job.Id, job.Patients, job.Measurements)
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
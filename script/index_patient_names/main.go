@@ -0,0 +1,46 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/spiker/spiker-server/config"
+	"github.com/spiker/spiker-server/lib"
+	S "github.com/spiker/spiker-server/service"
+)
+
+// 1回の問い合わせで処理する患者の数。
+const pageSize = 100
+
+// 索引の導入前に暗号化された患者の氏名に、検索用の索引を作成する。
+//
+// 索引の無い患者のみを処理するため、途中で失敗しても再度実行すればよい。
+func main() {
+	// REVIEW 環境固定。
+	os.Setenv("SERVER_ENV", "prod")
+
+	// 設定
+	config.SetupAll()
+
+	service := &S.PatientNameIndexService{
+		Service: nil,
+		DB: lib.GetDB(lib.WriteDBKey),
+	}
+
+	afterId, total := 0, 0
+
+	for {
+		lastId, count, err := service.Index(afterId, pageSize)
+
+		if err != nil {
+			log.Fatal(err)
+		} else if lastId == afterId {
+			break
+		}
+
+		afterId = lastId
+		total += count
+	}
+
+	log.Printf("Indexed names of %d patients", total)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/spiker/spiker-server/config"
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/resource/storage"
+	S "github.com/spiker/spiker-server/service"
+)
+
+// 1回の実行で処理する計測の最大数。
+const purgeLimit = 100
+
+// 消去した患者の計測について、削除待ちの計測データ、スナップショット画像、アーカイブを削除する。
+func main() {
+	// REVIEW 環境固定。
+	os.Setenv("SERVER_ENV", "prod")
+
+	// 設定
+	config.SetupAll()
+
+	service := &S.MeasurementPurgeService{
+		Service: nil,
+		DB: lib.GetDB(lib.WriteDBKey),
+		Influx: lib.GetInfluxDB(),
+		Storage: storage.UserStorage(),
+	}
+
+	if count, e := service.Purge(purgeLimit); e != nil {
+		log.Fatal(e)
+	} else {
+		log.Printf("Purged %d measurements", count)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/spiker/spiker-server/config"
+	"github.com/spiker/spiker-server/lib"
+	S "github.com/spiker/spiker-server/service"
+)
+
+// 分割、統合の後に付け替えられなかった計測データを付け替える。
+func main() {
+	// REVIEW 環境固定。
+	os.Setenv("SERVER_ENV", "prod")
+
+	// 設定
+	config.SetupAll()
+
+	tx, err := lib.GetDB(lib.WriteDBKey).Begin()
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	service := &S.MeasurementTxService{
+		Service: nil,
+		DB: tx,
+		Influx: lib.GetInfluxDB(),
+	}
+
+	count, err := service.RetagPending()
+
+	if err != nil {
+		tx.Rollback()
+		log.Fatal(err)
+	}
+
+	if e := tx.Commit(); e != nil {
+		log.Fatal(e)
+	}
+
+	log.Printf("Retagged %d requests", count)
+}
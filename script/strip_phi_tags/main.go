@@ -0,0 +1,55 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/spiker/spiker-server/config"
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/resource/rds"
+	S "github.com/spiker/spiker-server/service"
+)
+
+// 1回の問い合わせで取得する計測の数。
+const pageSize = 100
+
+// 患者情報のタグを除く前に記録された計測データから、平文の計測コードのタグを除く。
+//
+// 処理済みの系列は対象にならないため、途中で失敗しても再度実行すればよい。
+func main() {
+	// REVIEW 環境固定。
+	os.Setenv("SERVER_ENV", "prod")
+
+	// 設定
+	config.SetupAll()
+
+	db := lib.GetDB(lib.WriteDBKey)
+
+	service := &S.PHITagService{
+		Service: nil,
+		DB: db,
+		Influx: lib.GetInfluxDB(),
+	}
+
+	afterId := 0
+
+	for {
+		ids, err := rds.ListMeasurementIdsWithData(db, afterId, pageSize)
+
+		if err != nil {
+			log.Fatal(err)
+		} else if len(ids) == 0 {
+			break
+		}
+
+		for _, id := range ids {
+			if count, e := service.Strip(id); e != nil {
+				log.Fatalf("Failed to strip tags of measurement %d: %v", id, e)
+			} else if count > 0 {
+				log.Printf("Stripped tags from %d records of measurement %d", count, id)
+			}
+		}
+
+		afterId = ids[len(ids)-1]
+	}
+}
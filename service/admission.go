@@ -0,0 +1,209 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/gorp.v2"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/resource/rds"
+)
+
+type AdmissionService struct {
+	*Service
+	DB *gorp.DbMap
+}
+
+type AdmissionTxService struct {
+	*Service
+	DB *gorp.Transaction
+}
+
+// 割り当て中に開始する計測の計測コード。端末側の患者IDと区別できるよう、割り当てのIDから生成する。
+func admissionCode(admission *model.TerminalAdmission) string {
+	return fmt.Sprintf("admission-%d", admission.Id)
+}
+
+// 病院内で割り当て中の患者一覧を取得する。
+func (s *AdmissionService) List(hospitalId int) ([]*model.TerminalAdmission, error) {
+	if r, e := rds.ListActiveAdmissions(s.DB, hospitalId); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else {
+		return r, nil
+	}
+}
+
+// 端末に割り当て中の患者を取得する。割り当てられていない場合はnilを返す。
+func (s *AdmissionService) Fetch(hospitalId int, terminalId int) (*model.TerminalAdmission, error) {
+	if _, e := inquireHospitalTerminal(s.DB, hospitalId, terminalId); e != nil {
+		return nil, e
+	}
+
+	if r, e := rds.FetchActiveAdmission(s.DB, terminalId); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else {
+		return r, nil
+	}
+}
+
+// 患者を端末に割り当てる。
+//
+// patientIdを指定した場合は既存の患者を、wristbandIdを指定した場合はその識別子を持つ患者を割り当てる。
+// 識別子を持つ患者がいない場合やいずれも指定しない場合は、新規の患者を登録して割り当てる。
+// 退院するまでの間、端末から送られた計測データは割り当てた患者の計測となる。
+func (s *AdmissionTxService) Admit(
+	me *model.HospitalDoctor,
+	terminalId int,
+	patientId *int,
+	wristbandId *string,
+	name *string,
+) (*model.TerminalAdmission, error) {
+	hospitalId := me.Hospital.Id
+
+	if _, e := inquireHospitalTerminal(s.DB, hospitalId, terminalId); e != nil {
+		return nil, e
+	}
+
+	if r, e := rds.FetchActiveAdmission(s.DB, terminalId); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else if r != nil {
+		return nil, C.NewBadRequestError(
+			"terminal_already_admitted",
+			fmt.Sprintf("Patient %d is already admitted to terminal %d", r.PatientId, terminalId),
+			map[string]interface{}{},
+		)
+	}
+
+	keyring := newPHIKeyring(s.DB)
+
+	now := time.Now()
+
+	var patient *model.Patient = nil
+
+	if patientId != nil {
+		if r, e := rds.InquirePatient(s.DB, *patientId); e != nil {
+			return nil, C.DB_OPERATION_ERROR(e)
+		} else if r == nil || r.HospitalId != hospitalId {
+			return nil, C.NewNotFoundError(
+				"patient_not_found",
+				fmt.Sprintf("Patient %d is not found", *patientId),
+				map[string]interface{}{},
+			)
+		} else {
+			patient = r
+		}
+	} else if wristbandId != nil {
+		if r, e := inquireIdentifier(s.DB, keyring, hospitalId, *wristbandId); e != nil {
+			return nil, e
+		} else if r != nil {
+			patient = &model.Patient{Id: r.PatientId, HospitalId: hospitalId}
+		}
+	}
+
+	if patient == nil {
+		patient = &model.Patient{
+			HospitalId: hospitalId,
+			CreatedAt:  now,
+			ModifiedAt: now,
+		}
+
+		if e := s.DB.Insert(patient); e != nil {
+			return nil, C.DB_OPERATION_ERROR(e)
+		}
+
+		// 氏名は患者の鍵で暗号化するため、患者を登録してから設定する。
+		if name != nil {
+			patient.Name = name
+
+			if e := keyring.sealPatient(patient); e != nil {
+				return nil, e
+			}
+
+			if _, e := s.DB.Update(patient); e != nil {
+				return nil, C.DB_OPERATION_ERROR(e)
+			}
+		}
+
+		if wristbandId != nil {
+			if _, e := (&PatientTxService{s.Service, s.DB}).AddIdentifier(patient.Id, hospitalId, *wristbandId); e != nil {
+				return nil, e
+			}
+		}
+	}
+
+	admission := &model.TerminalAdmission{
+		HospitalId: hospitalId,
+		TerminalId: terminalId,
+		PatientId:  patient.Id,
+		DoctorId:   me.Doctor.Id,
+		AdmittedAt: now,
+	}
+
+	if e := s.DB.Insert(admission); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	return admission, nil
+}
+
+// 患者を端末から退院させ、割り当て中に開始した計測を完了状態にする。
+func (s *AdmissionTxService) Discharge(
+	hospitalId int,
+	terminalId int,
+) (*model.TerminalAdmission, error) {
+	if _, e := inquireHospitalTerminal(s.DB, hospitalId, terminalId); e != nil {
+		return nil, e
+	}
+
+	admission, err := rds.FetchActiveAdmission(s.DB, terminalId)
+
+	if err != nil {
+		return nil, C.DB_OPERATION_ERROR(err)
+	} else if admission == nil {
+		return nil, C.NewNotFoundError(
+			"admission_not_found",
+			fmt.Sprintf("No patient is admitted to terminal %d", terminalId),
+			map[string]interface{}{},
+		)
+	}
+
+	now := time.Now()
+
+	admission.DischargedAt = &now
+
+	if _, e := s.DB.Update(admission); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	if admission.MeasurementId != nil {
+		if mm, e := rds.InquireMeasurement(s.DB, *admission.MeasurementId); e != nil {
+			return nil, C.DB_OPERATION_ERROR(e)
+		} else if mm != nil && !mm.IsClosed {
+			mm.IsClosed = true
+			mm.ClosedAt = &now
+			mm.ModifiedAt = now
+
+			if _, e := s.DB.Update(mm); e != nil {
+				return nil, C.DB_OPERATION_ERROR(e)
+			}
+		}
+	}
+
+	return admission, nil
+}
+
+func inquireHospitalTerminal(db model.QueryExecutor, hospitalId int, id int) (*model.MeasurementTerminal, error) {
+	if r, e := rds.FetchTerminal(db, id); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else if r == nil || r.HospitalId != hospitalId {
+		return nil, C.NewNotFoundError(
+			"terminal_not_found",
+			fmt.Sprintf("Terminal %d is not found", id),
+			map[string]interface{}{},
+		)
+	} else {
+		return r, nil
+	}
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/gorp.v2"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/lib/hl7"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/resource/rds"
+)
+
+type ADTTxService struct {
+	*Service
+	DB *gorp.Transaction
+}
+
+// ADTメッセージの内容を病院の患者に反映する。反映した患者を返す。
+//
+// 患者は病院の患者ID(MRN)を識別子として特定し、A01(入院)、A04(登録)、A08(更新)では無ければ登録した上で氏名、年齢、在胎日数を更新する。
+// A03(退院)では登録済みの患者を更新し、端末への割り当てを全て退院させる。登録されていない場合は何もせずnilを返す。
+// A40(統合)では統合元の患者を統合先の患者に統合する。統合先が登録されていない場合は、統合元の患者に統合先のMRNを登録する。
+func (s *ADTTxService) Apply(
+	hospitalId int,
+	adt *hl7.ADT,
+	now time.Time,
+) (*model.Patient, error) {
+	keyring := newPHIKeyring(s.DB)
+
+	switch adt.Event {
+	case "A01", "A04", "A08":
+		return s.register(keyring, hospitalId, adt, now)
+
+	case "A03":
+		patient, err := s.inquireByMRN(keyring, hospitalId, adt.MRN)
+
+		if err != nil || patient == nil {
+			return nil, err
+		}
+
+		admissions, err := rds.ListPatientActiveAdmissions(s.DB, patient.Id)
+
+		if err != nil {
+			return nil, C.DB_OPERATION_ERROR(err)
+		}
+
+		for _, a := range admissions {
+			if _, e := (&AdmissionTxService{s.Service, s.DB}).Discharge(hospitalId, a.TerminalId); e != nil {
+				return nil, e
+			}
+		}
+
+		return s.updateDemographics(keyring, patient, adt, now)
+
+	case "A40":
+		if adt.PriorMRN == "" {
+			return nil, C.NewBadRequestError(
+				"adt_prior_mrn_missing",
+				"Prior patient identifier (MRG-1) is required to merge patients",
+				map[string]interface{}{},
+			)
+		}
+
+		prior, err := s.inquireByMRN(keyring, hospitalId, adt.PriorMRN)
+
+		if err != nil {
+			return nil, err
+		} else if prior == nil {
+			return s.register(keyring, hospitalId, adt, now)
+		}
+
+		patient, err := s.inquireByMRN(keyring, hospitalId, adt.MRN)
+
+		if err != nil {
+			return nil, err
+		}
+
+		patients := &PatientTxService{s.Service, s.DB}
+
+		if patient == nil {
+			if _, e := patients.AddIdentifier(prior.Id, hospitalId, adt.MRN); e != nil {
+				return nil, e
+			}
+
+			patient = prior
+		} else if patient.Id != prior.Id {
+			if _, e := patients.merge(hospitalId, nil, patient.Id, prior.Id); e != nil {
+				return nil, e
+			}
+
+			// 統合により氏名が暗号化し直されているため、改めて取得する。
+			if r, e := rds.InquirePatient(s.DB, patient.Id); e != nil {
+				return nil, C.DB_OPERATION_ERROR(e)
+			} else {
+				patient = r
+			}
+		}
+
+		return s.updateDemographics(keyring, patient, adt, now)
+
+	default:
+		return nil, C.NewBadRequestError(
+			"unsupported_adt_event",
+			fmt.Sprintf("ADT event %s is not supported", adt.Event),
+			map[string]interface{}{},
+		)
+	}
+}
+
+// MRNを持つ患者を取得し、無ければ登録した上で患者情報を更新する。
+func (s *ADTTxService) register(
+	keyring *phiKeyring,
+	hospitalId int,
+	adt *hl7.ADT,
+	now time.Time,
+) (*model.Patient, error) {
+	patient, err := s.inquireByMRN(keyring, hospitalId, adt.MRN)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if patient == nil {
+		patient = &model.Patient{
+			HospitalId: hospitalId,
+			CreatedAt:  now,
+			ModifiedAt: now,
+		}
+
+		if e := s.DB.Insert(patient); e != nil {
+			return nil, C.DB_OPERATION_ERROR(e)
+		}
+
+		if _, e := (&PatientTxService{s.Service, s.DB}).AddIdentifier(patient.Id, hospitalId, adt.MRN); e != nil {
+			return nil, e
+		}
+	}
+
+	return s.updateDemographics(keyring, patient, adt, now)
+}
+
+// 患者の氏名、年齢、在胎日数のうち、メッセージに含まれるものを更新する。
+func (s *ADTTxService) updateDemographics(
+	keyring *phiKeyring,
+	patient *model.Patient,
+	adt *hl7.ADT,
+	now time.Time,
+) (*model.Patient, error) {
+	if name := adt.Name(); name != nil {
+		patient.Name = name
+	}
+
+	at := now
+
+	if adt.RecordedAt != nil {
+		at = *adt.RecordedAt
+	}
+
+	if age := adt.Age(at); age != nil {
+		patient.Age = age
+	}
+
+	if adt.GestationalDays != nil {
+		patient.GestationalDays = adt.GestationalDays
+	}
+
+	patient.ModifiedAt = now
+
+	if e := keyring.sealPatient(patient); e != nil {
+		return nil, e
+	}
+
+	if _, e := s.DB.Update(patient); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	if e := keyring.revealPatients(patient); e != nil {
+		return nil, e
+	}
+
+	return patient, nil
+}
+
+// MRNを識別子に持つ患者を取得する。登録されていない場合はnilを返す。
+func (s *ADTTxService) inquireByMRN(
+	keyring *phiKeyring,
+	hospitalId int,
+	mrn string,
+) (*model.Patient, error) {
+	if r, e := inquireIdentifier(s.DB, keyring, hospitalId, mrn); e != nil {
+		return nil, e
+	} else if r == nil {
+		return nil, nil
+	} else if p, e := rds.InquirePatient(s.DB, r.PatientId); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else {
+		return p, nil
+	}
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/lib/hl7"
+	"github.com/spiker/spiker-server/model"
+	F "github.com/spiker/spiker-server/test/fixture"
+)
+
+func TestServiceADT_Apply(t *testing.T) {
+	db := lib.GetDB(lib.WriteDBKey)
+
+	F.Truncate(db, "patient", "patient_key", "hospital_key", "patient_identifier", "patient_merge", "terminal_admission", "measurement_terminal", "hospital")
+
+	hospitals := F.Insert(db, model.Hospital{}, 0, 1, func(i int, r F.Record) {
+	}).([]*model.Hospital)
+
+	terminals := F.Insert(db, model.MeasurementTerminal{}, 0, 1, func(i int, r F.Record) {
+		r["HospitalId"] = hospitals[0].Id
+	}).([]*model.MeasurementTerminal)
+
+	hospitalId := hospitals[0].Id
+
+	jst := time.FixedZone("JST", 9*60*60)
+	now := time.Date(2024, time.March, 2, 12, 0, 0, 0, jst)
+
+	tx, err := db.Begin()
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defer tx.Rollback()
+
+	service := &ADTTxService{nil, tx}
+
+	apply := func(segments ...string) (*model.Patient, error) {
+		m, err := hl7.Parse([]byte(strings.Join(segments, "\r")))
+
+		if err != nil {
+			return nil, err
+		}
+
+		adt, err := hl7.ParseADT(m, jst)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return service.Apply(hospitalId, adt, now)
+	}
+
+	count := func(table string) int64 {
+		c, e := tx.SelectInt(`SELECT COUNT(*) FROM ` + table)
+		assert.NoError(t, e)
+		return c
+	}
+
+	var first *model.Patient
+
+	t.Run("登録", func(t *testing.T) {
+		p, err := apply(
+			`MSH|^~\&|HIS|HOSP|SPIKER|HOSP|20240302100000||ADT^A04|MSG0001|P|2.5`,
+			`PID|1||0001^^^HOSP^MR||山田^花子||19900415`,
+			`OBX|1|NM|49051-6^Gestational age in weeks^LN||37|wk`,
+		)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.EqualValues(t, "山田 花子", *p.Name)
+		assert.EqualValues(t, 33, *p.Age)
+		assert.EqualValues(t, 259, *p.GestationalDays)
+		assert.EqualValues(t, 1, count("patient"))
+		assert.EqualValues(t, 1, count("patient_identifier"))
+
+		first = p
+	})
+
+	t.Run("更新", func(t *testing.T) {
+		p, err := apply(
+			`MSH|^~\&|HIS|HOSP|SPIKER|HOSP|20240302100000||ADT^A08|MSG0002|P|2.5`,
+			`PID|1||0001^^^HOSP^MR`,
+			`OBX|1|NM|49051-6^Gestational age in weeks^LN||38|wk`,
+		)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.EqualValues(t, first.Id, p.Id)
+		assert.EqualValues(t, "山田 花子", *p.Name)
+		assert.EqualValues(t, 266, *p.GestationalDays)
+		assert.EqualValues(t, 1, count("patient"))
+	})
+
+	t.Run("統合", func(t *testing.T) {
+		prior, err := apply(
+			`MSH|^~\&|HIS|HOSP|SPIKER|HOSP|20240302100000||ADT^A01|MSG0003|P|2.5`,
+			`PID|1||0002^^^HOSP^MR||山田^はなこ`,
+		)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.EqualValues(t, 2, count("patient"))
+
+		p, err := apply(
+			`MSH|^~\&|HIS|HOSP|SPIKER|HOSP|20240302100000||ADT^A40|MSG0004|P|2.5`,
+			`PID|1||0001^^^HOSP^MR`,
+			`MRG|0002`,
+		)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.EqualValues(t, first.Id, p.Id)
+		assert.EqualValues(t, "山田 花子", *p.Name)
+		assert.EqualValues(t, 1, count("patient"))
+		assert.EqualValues(t, 2, count("patient_identifier"))
+
+		merge := model.PatientMerge{}
+
+		if assert.NoError(t, tx.SelectOne(&merge, `SELECT * FROM patient_merge`)) {
+			assert.EqualValues(t, first.Id, merge.PatientId)
+			assert.EqualValues(t, prior.Id, merge.MergedPatientId)
+			assert.Nil(t, merge.DoctorId)
+		}
+	})
+
+	t.Run("退院", func(t *testing.T) {
+		if !assert.NoError(t, tx.Insert(&model.TerminalAdmission{
+			HospitalId: hospitalId,
+			TerminalId: terminals[0].Id,
+			PatientId:  first.Id,
+			AdmittedAt: now,
+		})) {
+			return
+		}
+
+		// 統合元のMRNでも特定できる。
+		p, err := apply(
+			`MSH|^~\&|HIS|HOSP|SPIKER|HOSP|20240302100000||ADT^A03|MSG0005|P|2.5`,
+			`PID|1||0002^^^HOSP^MR`,
+		)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.EqualValues(t, first.Id, p.Id)
+
+		c, err := tx.SelectInt(`SELECT COUNT(*) FROM terminal_admission WHERE discharged_at IS NULL`)
+
+		assert.NoError(t, err)
+		assert.EqualValues(t, 0, c)
+	})
+
+	t.Run("登録されていない患者の退院", func(t *testing.T) {
+		p, err := apply(
+			`MSH|^~\&|HIS|HOSP|SPIKER|HOSP|20240302100000||ADT^A03|MSG0006|P|2.5`,
+			`PID|1||9999^^^HOSP^MR`,
+		)
+
+		assert.NoError(t, err)
+		assert.Nil(t, p)
+		assert.EqualValues(t, 1, count("patient"))
+	})
+
+	t.Run("対応していないイベント", func(t *testing.T) {
+		_, err := apply(
+			`MSH|^~\&|HIS|HOSP|SPIKER|HOSP|20240302100000||ADT^A31|MSG0007|P|2.5`,
+			`PID|1||0001^^^HOSP^MR`,
+		)
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/gorp.v2"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/resource/rds"
+)
+
+type AgreementService struct {
+	*Service
+	DB *gorp.DbMap
+}
+
+// アラート閾値による二値判定の一致度。
+type AlertAgreement struct {
+	Threshold     int      `json:"threshold"`
+	TruePositive  int      `json:"truePositive"`
+	FalsePositive int      `json:"falsePositive"`
+	FalseNegative int      `json:"falseNegative"`
+	TrueNegative  int      `json:"trueNegative"`
+	Sensitivity   *float64 `json:"sensitivity"`
+	Specificity   *float64 `json:"specificity"`
+}
+
+// 自動診断イベントとアノテーションの期間の重なり。
+type TemporalOverlap struct {
+	Pairs      int      `json:"pairs"`
+	MeanRatio  *float64 `json:"meanRatio"`
+	TotalRatio *float64 `json:"totalRatio"`
+}
+
+// アルゴリズムとアノテーターの一致度。
+// 混同行列は自動診断のリスクを行、アノテーションのリスクを列とし、行の添字0は自動診断イベントが無いこと、列の添字0はアノテーションが無いことを表す。
+// レビュー済みの計測でアノテーションの無い自動診断イベントは、アノテーターがイベント無しと判断したものとして列0に数える。
+// 未レビューの計測の自動診断イベントは判断が無いため集計に含めず、件数のみをUnreviewedに示す。
+type AgreementReport struct {
+	Algorithm       *model.DiagnosisAlgorithm `json:"algorithm"`
+	Total           int                       `json:"total"`
+	Skipped         int                       `json:"skipped"`
+	Unreviewed      int                       `json:"unreviewed"`
+	Levels          []int                     `json:"levels"`
+	ConfusionMatrix [][]int                   `json:"confusionMatrix"`
+	Alert           *AlertAgreement           `json:"alert"`
+	Kappa           *float64                  `json:"kappa"`
+	Overlap         *TemporalOverlap          `json:"overlap"`
+}
+
+// アルゴリズムとアノテーターの一致度を集計する。
+func (s *AgreementService) Compute(
+	name string,
+	version string,
+	hospitalId *int,
+	begin *time.Time,
+	end *time.Time,
+) (*AgreementReport, error) {
+	var algorithm *model.DiagnosisAlgorithm
+
+	if r, e := rds.FetchAlgorithmByName(s.DB, name, version); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else if r == nil {
+		return nil, C.NewNotFoundError(
+			"algorithm_not_found",
+			fmt.Sprintf("Algorithm %s (%s) is not found", name, version),
+			map[string]interface{}{},
+		)
+	} else {
+		algorithm = r
+	}
+
+	pairs, err := rds.ListEventAgreementPairs(s.DB, algorithm.Id, hospitalId, begin, end)
+
+	if err != nil {
+		return nil, C.DB_OPERATION_ERROR(err)
+	}
+
+	report := computeAgreement(pairs, C.AlertRiskThreshold)
+	report.Algorithm = algorithm
+
+	if n, e := rds.CountUnreviewedComputedEvents(s.DB, algorithm.Id, hospitalId, begin, end); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else {
+		report.Unreviewed = n
+	}
+
+	return report, nil
+}
+
+// 組の一覧から一致度を計算する。リスクを持たないアノテーションは集計から除外し、アノテーションの無い自動診断イベントはイベント無しと判断されたものとする。
+func computeAgreement(
+	pairs []*model.EventAgreementEntity,
+	threshold int,
+) *AgreementReport {
+	levels := []int{0}
+	for r := C.RiskMinimum; r <= C.RiskMaximum; r++ {
+		levels = append(levels, r)
+	}
+
+	matrix := make([][]int, len(levels))
+	for i := range matrix {
+		matrix[i] = make([]int, len(levels))
+	}
+
+	report := &AgreementReport{
+		Levels: levels,
+		ConfusionMatrix: matrix,
+		Alert: &AlertAgreement{Threshold: threshold},
+		Overlap: &TemporalOverlap{},
+	}
+
+	// 範囲外のリスクは最も近いレベルに丸める。
+	levelIndex := func(risk *int) int {
+		if risk == nil {
+			return 0
+		} else if *risk < C.RiskMinimum {
+			return 1
+		} else if *risk > C.RiskMaximum {
+			return len(levels) - 1
+		} else {
+			return *risk - C.RiskMinimum + 1
+		}
+	}
+
+	ratioSum := 0.0
+	var overlapSum, unionSum time.Duration
+
+	for _, p := range pairs {
+		if p.Annotated != nil && p.Annotated.Risk == nil {
+			report.Skipped++
+			continue
+		}
+
+		report.Total++
+
+		var computedRisk, annotatedRisk *int = nil, nil
+		if p.Computed != nil {
+			computedRisk = p.Computed.Risk
+		}
+		if p.Annotated != nil {
+			annotatedRisk = p.Annotated.Risk
+		}
+
+		ci := levelIndex(computedRisk)
+		ai := levelIndex(annotatedRisk)
+
+		matrix[ci][ai]++
+
+		computedAlert := ci > 0 && levels[ci] >= threshold
+		annotatedAlert := ai > 0 && levels[ai] >= threshold
+
+		if computedAlert && annotatedAlert {
+			report.Alert.TruePositive++
+		} else if computedAlert {
+			report.Alert.FalsePositive++
+		} else if annotatedAlert {
+			report.Alert.FalseNegative++
+		} else {
+			report.Alert.TrueNegative++
+		}
+
+		if p.Computed != nil && p.Annotated != nil {
+			overlap, union := rangeOverlap(
+				p.Computed.RangeFrom, p.Computed.RangeUntil,
+				p.Annotated.RangeFrom, p.Annotated.RangeUntil,
+			)
+
+			if union > 0 {
+				report.Overlap.Pairs++
+				ratioSum += float64(overlap) / float64(union)
+				overlapSum += overlap
+				unionSum += union
+			}
+		}
+	}
+
+	report.Alert.Sensitivity = safeRatio(report.Alert.TruePositive, report.Alert.TruePositive+report.Alert.FalseNegative)
+	report.Alert.Specificity = safeRatio(report.Alert.TrueNegative, report.Alert.TrueNegative+report.Alert.FalsePositive)
+
+	report.Kappa = cohenKappa(matrix)
+
+	if report.Overlap.Pairs > 0 {
+		mean := ratioSum / float64(report.Overlap.Pairs)
+		total := float64(overlapSum) / float64(unionSum)
+		report.Overlap.MeanRatio = &mean
+		report.Overlap.TotalRatio = &total
+	}
+
+	return report
+}
+
+// 2つの期間の重なりと和の長さを返す。
+func rangeOverlap(
+	from1 time.Time,
+	until1 time.Time,
+	from2 time.Time,
+	until2 time.Time,
+) (time.Duration, time.Duration) {
+	begin, end := from1, until1
+	if from2.After(begin) {
+		begin = from2
+	}
+	if until2.Before(end) {
+		end = until2
+	}
+
+	overlap := end.Sub(begin)
+	if overlap < 0 {
+		overlap = 0
+	}
+
+	union := until1.Sub(from1) + until2.Sub(from2) - overlap
+
+	return overlap, union
+}
+
+// 混同行列からCohenのカッパ係数を計算する。偶然一致率が1の場合は定義されないためnilを返す。
+func cohenKappa(matrix [][]int) *float64 {
+	total := 0
+	agreed := 0
+	rows := make([]int, len(matrix))
+	cols := make([]int, len(matrix))
+
+	for i, row := range matrix {
+		for j, n := range row {
+			total += n
+			rows[i] += n
+			cols[j] += n
+			if i == j {
+				agreed += n
+			}
+		}
+	}
+
+	if total == 0 {
+		return nil
+	}
+
+	po := float64(agreed) / float64(total)
+	pe := 0.0
+	for i := range rows {
+		pe += float64(rows[i]) * float64(cols[i]) / float64(total) / float64(total)
+	}
+
+	if pe >= 1.0 {
+		return nil
+	}
+
+	kappa := (po - pe) / (1.0 - pe)
+
+	return &kappa
+}
+
+func safeRatio(numerator int, denominator int) *float64 {
+	if denominator == 0 {
+		return nil
+	}
+
+	r := float64(numerator) / float64(denominator)
+
+	return &r
+}
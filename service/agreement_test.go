@@ -0,0 +1,114 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spiker/spiker-server/model"
+)
+
+func TestServiceAgreement_computeAgreement(t *testing.T) {
+	base := time.Date(2021, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	minutes := func(m int) time.Time {
+		return base.Add(time.Duration(m) * time.Minute)
+	}
+
+	risk := func(r int) *int {
+		return &r
+	}
+
+	pair := func(computed *int, annotated *int, cf, cu, af, au int) *model.EventAgreementEntity {
+		p := &model.EventAgreementEntity{
+			Annotated: &model.AnnotatedEvent{Risk: annotated, RangeFrom: minutes(af), RangeUntil: minutes(au)},
+		}
+		if computed != nil {
+			p.Computed = &model.ComputedEvent{Risk: computed, RangeFrom: minutes(cf), RangeUntil: minutes(cu)}
+		}
+		return p
+	}
+
+	t.Run("一致度", func(t *testing.T) {
+		pairs := []*model.EventAgreementEntity{
+			// 完全に一致。
+			pair(risk(4), risk(4), 0, 10, 0, 10),
+			// リスク一致、期間は半分重なる。
+			pair(risk(1), risk(1), 0, 10, 5, 15),
+			// 偽陽性。
+			pair(risk(3), risk(2), 0, 10, 10, 20),
+			// 見逃し。
+			pair(nil, risk(5), 0, 0, 0, 10),
+			// リスクの無いアノテーションは除外。
+			pair(risk(2), nil, 0, 10, 0, 10),
+		}
+
+		r := computeAgreement(pairs, 3)
+
+		assert.EqualValues(t, 4, r.Total)
+		assert.EqualValues(t, 1, r.Skipped)
+		assert.EqualValues(t, []int{0, 1, 2, 3, 4, 5}, r.Levels)
+
+		assert.EqualValues(t, 1, r.ConfusionMatrix[4][4])
+		assert.EqualValues(t, 1, r.ConfusionMatrix[1][1])
+		assert.EqualValues(t, 1, r.ConfusionMatrix[3][2])
+		assert.EqualValues(t, 1, r.ConfusionMatrix[0][5])
+
+		assert.EqualValues(t, 1, r.Alert.TruePositive)
+		assert.EqualValues(t, 1, r.Alert.FalsePositive)
+		assert.EqualValues(t, 1, r.Alert.FalseNegative)
+		assert.EqualValues(t, 1, r.Alert.TrueNegative)
+		assert.InDelta(t, 0.5, *r.Alert.Sensitivity, 1e-9)
+		assert.InDelta(t, 0.5, *r.Alert.Specificity, 1e-9)
+
+		// po = 2/4, pe = (1*1 + 1*1) / 16。
+		assert.InDelta(t, (0.5-0.125)/(1-0.125), *r.Kappa, 1e-9)
+
+		assert.EqualValues(t, 3, r.Overlap.Pairs)
+		assert.InDelta(t, (1.0+5.0/15.0+0.0)/3.0, *r.Overlap.MeanRatio, 1e-9)
+		assert.InDelta(t, 15.0/45.0, *r.Overlap.TotalRatio, 1e-9)
+	})
+
+	t.Run("アノテーションの無いイベント", func(t *testing.T) {
+		unannotated := func(computed int) *model.EventAgreementEntity {
+			return &model.EventAgreementEntity{
+				Computed: &model.ComputedEvent{Risk: risk(computed), RangeFrom: minutes(0), RangeUntil: minutes(10)},
+			}
+		}
+
+		pairs := []*model.EventAgreementEntity{
+			pair(risk(4), risk(4), 0, 10, 0, 10),
+			// アノテーターがイベント無しと判断したものとして、偽陽性と真陰性に数える。
+			unannotated(4),
+			unannotated(1),
+		}
+
+		r := computeAgreement(pairs, 3)
+
+		assert.EqualValues(t, 3, r.Total)
+		assert.EqualValues(t, 0, r.Skipped)
+
+		assert.EqualValues(t, 1, r.ConfusionMatrix[4][4])
+		assert.EqualValues(t, 1, r.ConfusionMatrix[4][0])
+		assert.EqualValues(t, 1, r.ConfusionMatrix[1][0])
+
+		assert.EqualValues(t, 1, r.Alert.TruePositive)
+		assert.EqualValues(t, 1, r.Alert.FalsePositive)
+		assert.EqualValues(t, 0, r.Alert.FalseNegative)
+		assert.EqualValues(t, 1, r.Alert.TrueNegative)
+		assert.InDelta(t, 0.5, *r.Alert.Specificity, 1e-9)
+
+		assert.EqualValues(t, 1, r.Overlap.Pairs)
+	})
+
+	t.Run("対象無し", func(t *testing.T) {
+		r := computeAgreement([]*model.EventAgreementEntity{}, 3)
+
+		assert.EqualValues(t, 0, r.Total)
+		assert.Nil(t, r.Alert.Sensitivity)
+		assert.Nil(t, r.Alert.Specificity)
+		assert.Nil(t, r.Kappa)
+		assert.Nil(t, r.Overlap.MeanRatio)
+	})
+}
@@ -0,0 +1,412 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"gopkg.in/gorp.v2"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/resource/influxdb"
+	"github.com/spiker/spiker-server/resource/rds"
+)
+
+type AntenatalService struct {
+	*Service
+	DB *gorp.DbMap
+}
+
+type AntenatalTxService struct {
+	*Service
+	DB     *gorp.Transaction
+	Influx lib.InfluxDBClient
+}
+
+// 計測のNST解析結果を新しい順に取得する。
+func (s *AntenatalService) List(
+	measurementId int,
+	limit int,
+	offset int,
+) ([]*model.AntenatalReport, int64, error) {
+	if r, t, e := rds.ListAntenatalReports(s.DB, measurementId, limit, offset); e != nil {
+		return nil, 0, C.DB_OPERATION_ERROR(e)
+	} else {
+		return r, t, nil
+	}
+}
+
+// 計測のNST解析結果を取得する。
+func (s *AntenatalService) Fetch(
+	measurementId int,
+	id int,
+) (*model.AntenatalReport, error) {
+	if r, e := rds.FetchAntenatalReport(s.DB, measurementId, id); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else if r == nil {
+		return nil, C.NewNotFoundError(
+			"antenatal_report_not_found",
+			fmt.Sprintf("Antenatal report %d is not found", id),
+			map[string]interface{}{},
+		)
+	} else {
+		return r, nil
+	}
+}
+
+// 計測の心拍データに対してNST解析を行い、結果を登録する。
+//
+// untilを省略した場合は計測の最終データ日時、fromを省略した場合はそこから解析期間の上限だけ遡った日時、
+// ただし計測の開始日時より前にはならない日時とする。
+func (s *AntenatalTxService) Analyze(
+	measurementId int,
+	from *time.Time,
+	until *time.Time,
+) (*model.AntenatalReport, error) {
+	var measurement *model.Measurement
+
+	if r, e := rds.InquireMeasurement(s.DB, measurementId); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else if r == nil {
+		return nil, C.NewNotFoundError(
+			"measurement_not_found",
+			fmt.Sprintf("Measurement %d is not found", measurementId),
+			map[string]interface{}{},
+		)
+	} else {
+		measurement = r
+	}
+
+	now := time.Now()
+
+	var end time.Time
+
+	if until != nil {
+		end = *until
+	} else if _, last := measurementDataRange(measurement); last != nil {
+		end = *last
+	} else {
+		end = now
+	}
+
+	var begin time.Time
+
+	if from != nil {
+		begin = *from
+	} else {
+		begin = end.Add(-C.AntenatalMaximumDuration)
+
+		if measurement.FirstTime != nil && measurement.FirstTime.After(begin) {
+			begin = *measurement.FirstTime
+		}
+	}
+
+	if !begin.Before(end) {
+		return nil, C.NewBadRequestError(
+			"invalid_range",
+			fmt.Sprintf("Analysis range must not be empty"),
+			map[string]interface{}{},
+		)
+	} else if end.Sub(begin) > C.AntenatalMaximumDuration {
+		return nil, C.NewBadRequestError(
+			"range_too_long",
+			fmt.Sprintf("Analysis range must be within %s", C.AntenatalMaximumDuration),
+			map[string]interface{}{},
+		)
+	}
+
+	heartRates, err := influxdb.ListHeartRate(s.Influx, measurementId, begin, end)
+
+	if err != nil {
+		return nil, C.INFLUXDB_OPERATION_ERROR(err)
+	}
+
+	report := analyzeAntenatal(heartRates, begin, end)
+
+	report.MeasurementId = measurementId
+	report.CreatedAt = now
+
+	if e := s.DB.Insert(report); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	return report, nil
+}
+
+// 心拍の時系列からDawes-Redman基準に準じた指標を算出する。
+//
+// 心拍数を拍動間隔(ms)に変換して1/16分毎のエポックで平均し、エポック間の差の平均をSTV、
+// 1分毎のエポックの範囲の平均をLTVとする。有効な心拍を含まないエポックは信号損失とみなす。
+func analyzeAntenatal(
+	heartRates []*model.HeartRate,
+	begin time.Time,
+	end time.Time,
+) *model.AntenatalReport {
+	report := &model.AntenatalReport{
+		RangeFrom: begin,
+		RangeUntil: end,
+	}
+
+	reasons := []string{}
+
+	epochs := epochIntervals(heartRates, begin, end)
+
+	valid := 0
+	for _, e := range epochs {
+		if e != nil {
+			valid++
+		}
+	}
+
+	if len(epochs) > 0 {
+		report.SignalLoss = 1 - float64(valid) / float64(len(epochs))
+	}
+
+	largeDeceleration := false
+
+	if valid > 0 {
+		// 基準心拍数は有効なエポックの中央値とする。
+		rates := []float64{}
+		for _, e := range epochs {
+			if e != nil {
+				rates = append(rates, 60000 / *e)
+			}
+		}
+		sort.Float64s(rates)
+
+		basal := rates[len(rates)/2]
+		report.BasalHeartRate = &basal
+
+		// 1分毎の変動。
+		stvs := []float64{}
+		ranges := []float64{}
+		highs := []bool{}
+		lows := []bool{}
+
+		for m := 0; (m+1)*C.AntenatalEpochsPerMinute <= len(epochs); m++ {
+			minute := epochs[m*C.AntenatalEpochsPerMinute : (m+1)*C.AntenatalEpochsPerMinute]
+
+			values := []float64{}
+			diffSum := 0.0
+			diffCount := 0
+
+			for j, e := range minute {
+				if e == nil {
+					continue
+				}
+
+				values = append(values, *e)
+
+				if j > 0 && minute[j-1] != nil {
+					diffSum += math.Abs(*e - *minute[j-1])
+					diffCount++
+				}
+			}
+
+			// 半数以上のエポックが欠損した分は評価しない。
+			if len(values) * 2 < C.AntenatalEpochsPerMinute {
+				highs = append(highs, false)
+				lows = append(lows, false)
+				continue
+			}
+
+			sort.Float64s(values)
+			r := values[len(values)-1] - values[0]
+
+			ranges = append(ranges, r)
+			highs = append(highs, r >= C.AntenatalHighVariation)
+			lows = append(lows, r < C.AntenatalLowVariation)
+
+			if diffCount > 0 {
+				stvs = append(stvs, diffSum / float64(diffCount))
+			}
+		}
+
+		report.ShortTermVariability = average(stvs)
+		report.LongTermVariability = average(ranges)
+		report.HighVariationMinutes, report.HighVariationEpisodes = variationEpisodes(highs)
+		report.LowVariationMinutes, _ = variationEpisodes(lows)
+
+		// 一過性頻脈。
+		for _, run := range epochRuns(epochs, func(rate float64) bool {
+			return rate > basal + C.AntenatalAccelerationBpm
+		}) {
+			if time.Duration(len(run)) * C.AntenatalEpoch >= C.AntenatalAccelerationDuration {
+				report.Accelerations++
+			}
+		}
+
+		// 一過性徐脈。
+		for _, run := range epochRuns(epochs, func(rate float64) bool {
+			return rate < basal - C.AntenatalDecelerationBpm
+		}) {
+			if time.Duration(len(run)) * C.AntenatalEpoch >= C.AntenatalDecelerationDuration {
+				report.Decelerations++
+
+				lostBeats := 0.0
+				for _, rate := range run {
+					lostBeats += (basal - rate) * C.AntenatalEpoch.Minutes()
+				}
+
+				if lostBeats > C.AntenatalMaximumLostBeats {
+					largeDeceleration = true
+				}
+			}
+		}
+	}
+
+	// 基準の判定。
+	if end.Sub(begin) < C.AntenatalMinimumDuration {
+		reasons = append(reasons, C.AntenatalReasonInsufficientDuration)
+	}
+
+	if valid == 0 || report.SignalLoss > C.AntenatalMaximumSignalLoss {
+		reasons = append(reasons, C.AntenatalReasonSignalLoss)
+	}
+
+	if b := report.BasalHeartRate; b == nil || *b < C.AntenatalMinimumBasalRate || *b > C.AntenatalMaximumBasalRate {
+		reasons = append(reasons, C.AntenatalReasonBasalRate)
+	}
+
+	if report.HighVariationEpisodes == 0 {
+		reasons = append(reasons, C.AntenatalReasonNoHighVariation)
+	}
+
+	if stv := report.ShortTermVariability; stv == nil ||
+		*stv < C.AntenatalMinimumSTV ||
+		(report.Accelerations == 0 && *stv < C.AntenatalMinimumSTVWithoutAcceleration) {
+		reasons = append(reasons, C.AntenatalReasonLowSTV)
+	}
+
+	if largeDeceleration {
+		reasons = append(reasons, C.AntenatalReasonLargeDeceleration)
+	}
+
+	report.CriteriaMet = len(reasons) == 0
+
+	bs, _ := json.Marshal(reasons)
+	report.Reasons = model.JSON(bs)
+
+	return report
+}
+
+// エポック毎の平均拍動間隔(ms)を算出する。有効な心拍を含まないエポックはnilとなる。
+func epochIntervals(
+	heartRates []*model.HeartRate,
+	begin time.Time,
+	end time.Time,
+) []*float64 {
+	count := int(math.Ceil(float64(end.Sub(begin)) / float64(C.AntenatalEpoch)))
+
+	if count < 0 {
+		count = 0
+	}
+
+	sums := make([]float64, count)
+	nums := make([]int, count)
+
+	for _, hr := range heartRates {
+		if hr.Timestamp.Before(begin) || !hr.Timestamp.Before(end) {
+			continue
+		}
+
+		if hr.Value < C.AntenatalMinimumBpm || hr.Value > C.AntenatalMaximumBpm {
+			continue
+		}
+
+		i := int(hr.Timestamp.Sub(begin) / C.AntenatalEpoch)
+
+		sums[i] += 60000 / float64(hr.Value)
+		nums[i]++
+	}
+
+	results := make([]*float64, count)
+
+	for i := range results {
+		if nums[i] > 0 {
+			v := sums[i] / float64(nums[i])
+			results[i] = &v
+		}
+	}
+
+	return results
+}
+
+// 条件を満たす心拍数が連続するエポックの区間を、心拍数の配列として返す。欠損したエポックで区間は途切れる。
+func epochRuns(epochs []*float64, matches func(float64) bool) [][]float64 {
+	runs := [][]float64{}
+	current := []float64{}
+
+	for _, e := range epochs {
+		if e != nil && matches(60000 / *e) {
+			current = append(current, 60000 / *e)
+		} else if len(current) > 0 {
+			runs = append(runs, current)
+			current = []float64{}
+		}
+	}
+
+	if len(current) > 0 {
+		runs = append(runs, current)
+	}
+
+	return runs
+}
+
+// 連続する一定分数のうち既定の分数以上が該当する区間をエピソードとし、エピソードに含まれる該当分数とエピソード数を返す。
+// 重なり合う区間は1つのエピソードとみなす。
+func variationEpisodes(flags []bool) (int, int) {
+	marked := make([]bool, len(flags))
+	episodes := 0
+	lastEnd := -1
+
+	for w := 0; w+C.AntenatalEpisodeWindow <= len(flags); w++ {
+		count := 0
+		for _, f := range flags[w : w+C.AntenatalEpisodeWindow] {
+			if f {
+				count++
+			}
+		}
+
+		if count < C.AntenatalEpisodeMinutes {
+			continue
+		}
+
+		if w > lastEnd {
+			episodes++
+		}
+		lastEnd = w + C.AntenatalEpisodeWindow - 1
+
+		for i := w; i <= lastEnd; i++ {
+			marked[i] = marked[i] || flags[i]
+		}
+	}
+
+	minutes := 0
+	for _, m := range marked {
+		if m {
+			minutes++
+		}
+	}
+
+	return minutes, episodes
+}
+
+func average(values []float64) *float64 {
+	if len(values) == 0 {
+		return nil
+	}
+
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+
+	result := sum / float64(len(values))
+
+	return &result
+}
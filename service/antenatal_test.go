@@ -0,0 +1,125 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/model"
+)
+
+func TestServiceAntenatal_analyzeAntenatal(t *testing.T) {
+	base := time.Date(2021, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	// 1秒毎の心拍。
+	generate := func(seconds int, rate func(i int) int) []*model.HeartRate {
+		hrs := []*model.HeartRate{}
+		for i := 0; i < seconds; i++ {
+			hrs = append(hrs, &model.HeartRate{MeasurementId: 1, Value: rate(i), Timestamp: base.Add(time.Duration(i) * time.Second)})
+		}
+		return hrs
+	}
+
+	reasons := func(r *model.AntenatalReport) []string {
+		values := []string{}
+		assert.NoError(t, json.Unmarshal(r.Reasons, &values))
+		return values
+	}
+
+	t.Run("基準を満たす", func(t *testing.T) {
+		// 140bpmを中心に8秒周期で上下し、5分毎に30秒間の一過性頻脈を持つ。
+		hrs := generate(20*60, func(i int) int {
+			if i%300 >= 120 && i%300 < 150 {
+				return 165
+			} else if (i/4)%2 == 0 {
+				return 132
+			} else {
+				return 148
+			}
+		})
+
+		r := analyzeAntenatal(hrs, base, base.Add(20*time.Minute))
+
+		assert.EqualValues(t, 0, r.SignalLoss)
+		assert.InDelta(t, 140, *r.BasalHeartRate, 8)
+		assert.Greater(t, *r.ShortTermVariability, C.AntenatalMinimumSTVWithoutAcceleration)
+		assert.Greater(t, *r.LongTermVariability, C.AntenatalHighVariation)
+		assert.EqualValues(t, 20, r.HighVariationMinutes)
+		assert.EqualValues(t, 1, r.HighVariationEpisodes)
+		assert.EqualValues(t, 0, r.LowVariationMinutes)
+		assert.EqualValues(t, 4, r.Accelerations)
+		assert.EqualValues(t, 0, r.Decelerations)
+		assert.True(t, r.CriteriaMet)
+		assert.Empty(t, reasons(r))
+	})
+
+	t.Run("変動が乏しく一過性徐脈を伴う", func(t *testing.T) {
+		// 140bpmで一定、10分目から90秒間110bpm。
+		hrs := generate(20*60, func(i int) int {
+			if i >= 600 && i < 690 {
+				return 110
+			}
+			return 140
+		})
+
+		r := analyzeAntenatal(hrs, base, base.Add(20*time.Minute))
+
+		assert.EqualValues(t, 140, *r.BasalHeartRate)
+		assert.EqualValues(t, 0, *r.ShortTermVariability)
+		assert.EqualValues(t, 0, r.HighVariationEpisodes)
+		assert.EqualValues(t, 1, r.Decelerations)
+		assert.False(t, r.CriteriaMet)
+		assert.EqualValues(t, []string{
+			C.AntenatalReasonNoHighVariation,
+			C.AntenatalReasonLowSTV,
+			C.AntenatalReasonLargeDeceleration,
+		}, reasons(r))
+	})
+
+	t.Run("記録が短い", func(t *testing.T) {
+		hrs := generate(5*60, func(i int) int {
+			return 140
+		})
+
+		r := analyzeAntenatal(hrs, base, base.Add(5*time.Minute))
+
+		assert.False(t, r.CriteriaMet)
+		assert.Contains(t, reasons(r), C.AntenatalReasonInsufficientDuration)
+	})
+
+	t.Run("信号損失", func(t *testing.T) {
+		hrs := generate(20*60, func(i int) int {
+			return 0
+		})
+
+		r := analyzeAntenatal(hrs, base, base.Add(20*time.Minute))
+
+		assert.EqualValues(t, 1, r.SignalLoss)
+		assert.Nil(t, r.BasalHeartRate)
+		assert.Nil(t, r.ShortTermVariability)
+		assert.False(t, r.CriteriaMet)
+		assert.EqualValues(t, []string{
+			C.AntenatalReasonSignalLoss,
+			C.AntenatalReasonBasalRate,
+			C.AntenatalReasonNoHighVariation,
+			C.AntenatalReasonLowSTV,
+		}, reasons(r))
+	})
+}
+
+func TestServiceAntenatal_variationEpisodes(t *testing.T) {
+	o, x := true, false
+
+	minutes, episodes := variationEpisodes([]bool{o, o, x, o, o, o, x, x, x, x, x, x, o, o, o, o, o, x})
+
+	assert.EqualValues(t, 10, minutes)
+	assert.EqualValues(t, 2, episodes)
+
+	minutes, episodes = variationEpisodes([]bool{o, o, x, o, x, o, o})
+
+	assert.EqualValues(t, 0, minutes)
+	assert.EqualValues(t, 0, episodes)
+}
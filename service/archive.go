@@ -0,0 +1,354 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"gopkg.in/gorp.v2"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/resource/influxdb"
+	"github.com/spiker/spiker-server/resource/rds"
+	"github.com/spiker/spiker-server/resource/storage"
+)
+
+type MeasurementArchiveService struct {
+	*Service
+	DB      *gorp.DbMap
+	Influx  lib.InfluxDBClient
+	Storage storage.Storage
+}
+
+type MeasurementArchiveTxService struct {
+	*Service
+	DB      *gorp.Transaction
+	Influx  lib.InfluxDBClient
+	Storage storage.Storage
+}
+
+// 保存期間を過ぎ、アーカイブの対象となる計測を取得する。
+func (s *MeasurementArchiveService) Candidates(now time.Time) ([]*model.Measurement, error) {
+	if r, e := rds.ListArchivableMeasurements(s.DB, now, now.Add(-C.RetentionRestoredDuration)); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else {
+		return r, nil
+	}
+}
+
+// 計測の計測データとイベントを圧縮してストレージに保存した上で、InfluxDBから計測データを削除する。
+//
+// イベントは計測一覧や注釈の画面で引き続き参照するため、データベースに残す。アーカイブには参照用の複製のみを含め、
+// 復元時もイベントは復元しない。計測データと異なり患者情報や波形を含まないため、保存期間の対象外とする。
+// 復元後に再びアーカイブする場合は、アーカイブを上書きする。
+func (s *MeasurementArchiveService) Archive(measurement *model.Measurement, now time.Time) (*model.MeasurementArchive, error) {
+	if s.Storage == nil {
+		return nil, fmt.Errorf("Storage is not configured")
+	}
+
+	records := []*lib.PointRecord{}
+
+	// 計測データの期間は計測の最初と最後のデータの日時とする。
+	var begin, end time.Time
+
+	if measurement.FirstTime != nil && measurement.LastTime != nil {
+		begin = *measurement.FirstTime
+		end = measurement.LastTime.Add(time.Second)
+
+		if r, e := influxdb.ListRecords(s.Influx, measurement.Id, begin, end); e != nil {
+			return nil, C.INFLUXDB_OPERATION_ERROR(e)
+		} else {
+			records = r
+		}
+	}
+
+	events := &archivedEvents{}
+
+	if r, e := rds.ListComputedEventsInRange(s.DB, measurement.Id, time.Unix(0, 0), now, true); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else {
+		events.ComputedEvents = r
+	}
+
+	if r, e := rds.ListAnnotatedEventsInRange(s.DB, measurement.Id, time.Unix(0, 0), now); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else {
+		events.AnnotatedEvents = r
+	}
+
+	key := archiveStorageKey(measurement.Id)
+
+	if bs, e := encodeArchivedRecords(records); e != nil {
+		return nil, e
+	} else if e := s.Storage.Put(key+"/"+archiveSignalsFile, bs); e != nil {
+		return nil, C.STORAGE_OPERATION_ERROR(e)
+	}
+
+	if bs, e := gzipArchive(func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(events)
+	}); e != nil {
+		return nil, e
+	} else if e := s.Storage.Put(key+"/"+archiveEventsFile, bs); e != nil {
+		return nil, C.STORAGE_OPERATION_ERROR(e)
+	}
+
+	archive, err := rds.FetchMeasurementArchive(s.DB, measurement.Id)
+
+	if err != nil {
+		return nil, C.DB_OPERATION_ERROR(err)
+	}
+
+	record := &model.MeasurementArchive{
+		MeasurementId: measurement.Id,
+		StorageKey: key,
+		Records: len(records),
+		ComputedEvents: len(events.ComputedEvents),
+		AnnotatedEvents: len(events.AnnotatedEvents),
+		ArchivedAt: now,
+		RestoredAt: nil,
+	}
+
+	// 計測データを削除する前にアーカイブを記録し、削除後にアーカイブの所在が失われないようにする。
+	if archive == nil {
+		if e := s.DB.Insert(record); e != nil {
+			return nil, C.DB_OPERATION_ERROR(e)
+		}
+	} else {
+		if _, e := s.DB.Update(record); e != nil {
+			return nil, C.DB_OPERATION_ERROR(e)
+		}
+	}
+
+	// 削除に失敗した場合も計測データはアーカイブと同じ内容のため、復元時に重複して登録されることはない。
+	if len(records) > 0 {
+		if e := s.Influx.Delete("spiker", begin, end, fmt.Sprintf(`measurement_id="%d"`, measurement.Id)); e != nil {
+			return nil, C.INFLUXDB_OPERATION_ERROR(e)
+		}
+	}
+
+	return record, nil
+}
+
+// アーカイブした計測データをInfluxDBに復元する。
+// 復元した計測データは、一定期間の後に再びアーカイブの対象となる。
+func (s *MeasurementArchiveTxService) Restore(measurementId int, now time.Time) (*model.MeasurementArchive, error) {
+	archive, err := rds.FetchMeasurementArchive(s.DB, measurementId)
+
+	if err != nil {
+		return nil, C.DB_OPERATION_ERROR(err)
+	} else if archive == nil {
+		return nil, C.NewNotFoundError(
+			"archive_not_found",
+			fmt.Sprintf("Measurement %d is not archived", measurementId),
+			map[string]interface{}{},
+		)
+	} else if archive.RestoredAt != nil {
+		return nil, C.NewBadRequestError(
+			"archive_already_restored",
+			fmt.Sprintf("Archive of measurement %d is already restored", measurementId),
+			map[string]interface{}{},
+		)
+	}
+
+	if s.Storage == nil {
+		return nil, fmt.Errorf("Storage is not configured")
+	}
+
+	bs, err := s.Storage.Get(archive.StorageKey + "/" + archiveSignalsFile)
+
+	if err != nil {
+		return nil, C.STORAGE_OPERATION_ERROR(err)
+	}
+
+	points, err := decodeArchivedRecords(bs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if errors := s.Influx.Insert("spiker", model.OpaquePoints(points...)...); len(errors) > 0 {
+		return nil, C.INFLUXDB_OPERATION_ERROR(errors[0])
+	}
+
+	archive.RestoredAt = &now
+
+	if _, e := s.DB.Update(archive); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	return archive, nil
+}
+
+const (
+	archiveSignalsFile = "signals.jsonl.gz"
+	archiveEventsFile  = "events.json.gz"
+)
+
+// 計測のアーカイブを保存するストレージ上のディレクトリ。
+func archiveStorageKey(measurementId int) string {
+	return fmt.Sprintf("archives/%d", measurementId)
+}
+
+// アーカイブするイベント。
+type archivedEvents struct {
+	ComputedEvents  []*model.ComputedEventEntity  `json:"computedEvents"`
+	AnnotatedEvents []*model.AnnotatedEventEntity `json:"annotatedEvents"`
+}
+
+// アーカイブする計測データの1フィールド分のレコード。値は文字列とし、復元時に同じ型で登録できるよう型を持つ。
+type archivedRecord struct {
+	Measurement string            `json:"measurement"`
+	Tags        map[string]string `json:"tags"`
+	Field       string            `json:"field"`
+	Type        string            `json:"type"`
+	Value       string            `json:"value"`
+	Timestamp   time.Time         `json:"timestamp"`
+}
+
+func newArchivedRecord(r *lib.PointRecord) (*archivedRecord, error) {
+	record := &archivedRecord{
+		Measurement: r.Measurement,
+		Tags: map[string]string{},
+		Field: r.Field,
+		Timestamp: r.Timestamp,
+	}
+
+	for k, v := range r.Tags {
+		record.Tags[k] = v
+	}
+
+	model.StripPHITags(record.Tags)
+
+	switch v := r.Value.(type) {
+	case int64:
+		record.Type, record.Value = "int", strconv.FormatInt(v, 10)
+	case uint64:
+		record.Type, record.Value = "uint", strconv.FormatUint(v, 10)
+	case float64:
+		record.Type, record.Value = "float", strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		record.Type, record.Value = "bool", strconv.FormatBool(v)
+	case string:
+		record.Type, record.Value = "string", v
+	default:
+		return nil, fmt.Errorf("Unsupported value for %s.%s: %v", r.Measurement, r.Field, r.Value)
+	}
+
+	return record, nil
+}
+
+func (r *archivedRecord) value() (interface{}, error) {
+	switch r.Type {
+	case "int":
+		return strconv.ParseInt(r.Value, 10, 64)
+	case "uint":
+		return strconv.ParseUint(r.Value, 10, 64)
+	case "float":
+		return strconv.ParseFloat(r.Value, 64)
+	case "bool":
+		return strconv.ParseBool(r.Value)
+	case "string":
+		return r.Value, nil
+	default:
+		return nil, fmt.Errorf("Unsupported type for %s.%s: %s", r.Measurement, r.Field, r.Type)
+	}
+}
+
+// アーカイブから復元する計測データ。
+type archivedPoint struct {
+	record *archivedRecord
+	value  interface{}
+}
+
+func (p *archivedPoint) Measurement() string {
+	return p.record.Measurement
+}
+
+func (p *archivedPoint) FromRecord(record *lib.PointRecord) error {
+	return fmt.Errorf("Archived point can not be read from InfluxDB")
+}
+
+func (p *archivedPoint) ToRecord(record *lib.SchemaRecord) {
+	record.Measurement = p.record.Measurement
+	for k, v := range p.record.Tags {
+		record.Tags[k] = v
+	}
+	record.Fields[p.record.Field] = p.value
+	record.Timestamp = p.record.Timestamp
+}
+
+// 計測データのレコードを1行1レコードのJSONとして圧縮する。
+func encodeArchivedRecords(records []*lib.PointRecord) ([]byte, error) {
+	return gzipArchive(func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+
+		for _, r := range records {
+			if record, e := newArchivedRecord(r); e != nil {
+				return e
+			} else if e := encoder.Encode(record); e != nil {
+				return e
+			}
+		}
+
+		return nil
+	})
+}
+
+// 圧縮した計測データのレコードを、InfluxDBに登録できる計測データに戻す。
+func decodeArchivedRecords(bs []byte) ([]lib.Point, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(bs))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer reader.Close()
+
+	points := []lib.Point{}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		record := &archivedRecord{}
+
+		if e := json.Unmarshal(scanner.Bytes(), record); e != nil {
+			return nil, e
+		}
+
+		if v, e := record.value(); e != nil {
+			return nil, e
+		} else {
+			points = append(points, &archivedPoint{record, v})
+		}
+	}
+
+	if e := scanner.Err(); e != nil {
+		return nil, e
+	}
+
+	return points, nil
+}
+
+func gzipArchive(write func(io.Writer) error) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+
+	writer := gzip.NewWriter(buffer)
+
+	if e := write(writer); e != nil {
+		return nil, e
+	}
+
+	if e := writer.Close(); e != nil {
+		return nil, e
+	}
+
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spiker/spiker-server/lib"
+)
+
+func TestServiceArchive_Records(t *testing.T) {
+	timestamp := time.Date(2024, 1, 1, 0, 0, 0, 5, time.UTC)
+
+	records := []*lib.PointRecord{
+		{"heartrate", map[string]string{"measurement_id": "3", "patient_code": "p-0001"}, "value", int64(140), timestamp},
+		{"float", map[string]string{"measurement_id": "3"}, "value", 1.5, timestamp.Add(time.Second)},
+		{"uint", map[string]string{"measurement_id": "3"}, "value", uint64(7), timestamp.Add(time.Second)},
+		{"bool", map[string]string{"measurement_id": "3"}, "flag", true, timestamp.Add(time.Second)},
+		{"string", map[string]string{"measurement_id": "3"}, "label", "a", timestamp.Add(time.Second)},
+	}
+
+	bs, err := encodeArchivedRecords(records)
+
+	assert.NoError(t, err)
+
+	points, err := decodeArchivedRecords(bs)
+
+	assert.NoError(t, err)
+
+	if assert.Equal(t, len(records), len(points)) {
+		for i, p := range points {
+			record := lib.SchemaRecord{"", map[string]string{}, map[string]interface{}{}, time.Unix(0, 0)}
+
+			p.ToRecord(&record)
+
+			// 値の型も含めて復元される。
+			assert.Equal(t, records[i].Measurement, p.Measurement())
+			assert.Equal(t, records[i].Value, record.Fields[records[i].Field])
+			assert.True(t, records[i].Timestamp.Equal(record.Timestamp))
+			// 患者コードはアーカイブに含まれない。
+			assert.Equal(t, map[string]string{"measurement_id": "3"}, record.Tags)
+		}
+	}
+
+	_, err = encodeArchivedRecords([]*lib.PointRecord{
+		{"bytes", map[string]string{}, "value", []byte("a"), timestamp},
+	})
+
+	assert.Error(t, err)
+}
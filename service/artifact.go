@@ -0,0 +1,155 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/lib/artifact"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/resource/influxdb"
+)
+
+// アーチファクト検出時に、登録データの前に参照する既存データの期間。
+const artifactContextDuration time.Duration = time.Duration(5) * time.Minute
+
+// 新たに登録する心拍のアーチファクトを検出する。
+// 半減、倍加、母体心拍は前後の心拍に依存するため、計測毎に登録済みの直前のデータを含めて判定し、
+// 登録データのアーチファクトに加えて、判定が変わった登録済みのデータのアーチファクトを返す。
+// InfluxDBでは同じ日時の点は上書きされるため、返したアーチファクトはそのまま登録すればよい。
+func detectHeartRateArtifacts(
+	influx lib.InfluxDBClient,
+	heartRates []*model.HeartRate,
+) ([]*model.HeartRateArtifact, error) {
+	// 計測IDを一意にしたリスト。
+	ids := []int{}
+	// 計測IDから登録データへのマップ。
+	batches := map[int][]*model.HeartRate{}
+
+	for _, hr := range heartRates {
+		if _, be := batches[hr.MeasurementId]; !be {
+			ids = append(ids, hr.MeasurementId)
+		}
+		batches[hr.MeasurementId] = append(batches[hr.MeasurementId], hr)
+	}
+
+	results := []*model.HeartRateArtifact{}
+
+	for _, id := range ids {
+		batch := sortHeartRates(batches[id])
+
+		begin := batch[0].Timestamp.Add(-artifactContextDuration)
+
+		context, err := influxdb.ListHeartRate(influx, id, begin, batch[0].Timestamp)
+
+		if err != nil {
+			return nil, err
+		}
+
+		stored, err := influxdb.ListHeartRateArtifacts(influx, id, begin, batch[0].Timestamp)
+
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, detectBatchArtifacts(id, context, stored, batch)...)
+	}
+
+	return results, nil
+}
+
+// 登録済みの直前の心拍とそのアーチファクトを引き継いで、日時順に並んだ登録データのアーチファクトを検出する。
+// 登録済みの心拍は、新たにアーチファクトと判定された種別がある場合のみ、判定済みの種別と合わせて返す。
+// 登録済みの心拍の判定は登録データの手前で途切れているため、既存の判定を取り消すことはしない。
+func detectBatchArtifacts(
+	measurementId int,
+	context []*model.HeartRate,
+	stored []*model.HeartRateArtifact,
+	batch []*model.HeartRate,
+) []*model.HeartRateArtifact {
+	storedFlags := map[int64]artifact.Flag{}
+
+	for _, a := range stored {
+		storedFlags[a.Timestamp.UnixNano()] |= artifact.Flag(a.Flags)
+	}
+
+	samples := []artifact.Sample{}
+	known := []artifact.Flag{}
+	timestamps := []time.Time{}
+
+	for _, hr := range context {
+		if hr.Timestamp.Before(batch[0].Timestamp) {
+			samples = append(samples, artifact.Sample{Value: hr.Value, Timestamp: hr.Timestamp})
+			known = append(known, storedFlags[hr.Timestamp.UnixNano()])
+			timestamps = append(timestamps, hr.Timestamp)
+		}
+	}
+
+	offset := len(samples)
+
+	for _, hr := range batch {
+		samples = append(samples, artifact.Sample{Value: hr.Value, Timestamp: hr.Timestamp})
+		timestamps = append(timestamps, hr.Timestamp)
+	}
+
+	results := []*model.HeartRateArtifact{}
+
+	for i, f := range artifact.DetectContinued(samples, known) {
+		if i < offset {
+			if f &^ known[i] == artifact.FlagNone {
+				continue
+			}
+
+			f |= known[i]
+		} else if f == artifact.FlagNone {
+			continue
+		}
+
+		results = append(results, &model.HeartRateArtifact{
+			MeasurementId: measurementId,
+			Flags: int(f),
+			Timestamp: timestamps[i],
+		})
+	}
+
+	return results
+}
+
+// 心拍を日時の古い順に並べ替えた新しいスライスを返す。
+func sortHeartRates(heartRates []*model.HeartRate) []*model.HeartRate {
+	results := append([]*model.HeartRate{}, heartRates...)
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Timestamp.Before(results[j].Timestamp)
+	})
+
+	return results
+}
+
+// アーチファクトを除いた心拍を返す。半減、倍加は補正し、信号損失、母体心拍は取り除く。
+func cleanHeartRates(
+	heartRates []*model.HeartRate,
+	artifacts []*model.HeartRateArtifact,
+) []*model.HeartRate {
+	flags := map[int64]artifact.Flag{}
+
+	for _, a := range artifacts {
+		flags[a.Timestamp.UnixNano()] |= artifact.Flag(a.Flags)
+	}
+
+	results := []*model.HeartRate{}
+
+	for _, hr := range heartRates {
+		f, be := flags[hr.Timestamp.UnixNano()]
+
+		if !be {
+			results = append(results, hr)
+		} else if v, ok := artifact.Correct(hr.Value, f); ok {
+			corrected := *hr
+			corrected.Value = v
+			results = append(results, &corrected)
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spiker/spiker-server/lib/artifact"
+	"github.com/spiker/spiker-server/model"
+)
+
+func TestServiceArtifact_cleanHeartRates(t *testing.T) {
+	base := time.Date(2021, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	seconds := func(s int) time.Time {
+		return base.Add(time.Duration(s) * time.Second)
+	}
+
+	heartRates := []*model.HeartRate{}
+
+	for i, v := range []int{140, 70, 280, 0, 90, 141} {
+		heartRates = append(heartRates, &model.HeartRate{MeasurementId: 1, Value: v, Timestamp: seconds(i)})
+	}
+
+	artifacts := []*model.HeartRateArtifact{
+		{MeasurementId: 1, Flags: int(artifact.FlagHalving), Timestamp: seconds(1)},
+		{MeasurementId: 1, Flags: int(artifact.FlagDoubling), Timestamp: seconds(2)},
+		{MeasurementId: 1, Flags: int(artifact.FlagLoss), Timestamp: seconds(3)},
+		{MeasurementId: 1, Flags: int(artifact.FlagMaternal), Timestamp: seconds(4)},
+	}
+
+	results := cleanHeartRates(heartRates, artifacts)
+
+	assert.EqualValues(t, 4, len(results))
+	assert.EqualValues(t, []int{140, 140, 140, 141}, []int{results[0].Value, results[1].Value, results[2].Value, results[3].Value})
+	assert.EqualValues(t, seconds(0), results[0].Timestamp)
+	assert.EqualValues(t, seconds(1), results[1].Timestamp)
+	assert.EqualValues(t, seconds(2), results[2].Timestamp)
+	assert.EqualValues(t, seconds(5), results[3].Timestamp)
+
+	// 元の心拍は変更されない。
+	assert.EqualValues(t, 70, heartRates[1].Value)
+}
+
+func TestServiceArtifact_detectBatchArtifacts(t *testing.T) {
+	base := time.Date(2021, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	seconds := func(s int) time.Time {
+		return base.Add(time.Duration(s) * time.Second)
+	}
+
+	// fromの秒から1秒おきの心拍。
+	generate := func(from int, values ...int) []*model.HeartRate {
+		results := []*model.HeartRate{}
+		for i, v := range values {
+			results = append(results, &model.HeartRate{MeasurementId: 1, Value: v, Timestamp: seconds(from + i)})
+		}
+		return results
+	}
+
+	repeat := func(value int, count int) []int {
+		values := []int{}
+		for i := 0; i < count; i++ {
+			values = append(values, value)
+		}
+		return values
+	}
+
+	t.Run("2回の登録にまたがる母体心拍", func(t *testing.T) {
+		first := generate(0, append(repeat(140, 30), repeat(85, 60)...)...)
+
+		// 1回目の登録では、母体心拍とみなす持続時間に達していない。
+		stored := detectBatchArtifacts(1, nil, nil, first)
+
+		assert.EqualValues(t, 0, len(stored))
+
+		second := generate(90, append(repeat(85, 90), repeat(140, 30)...)...)
+
+		// 2回目の登録で、1回目の登録の区間の始まりから母体心拍とする。
+		results := detectBatchArtifacts(1, first, stored, second)
+
+		assert.EqualValues(t, 150, len(results))
+
+		for i, a := range results {
+			assert.EqualValues(t, seconds(30 + i), a.Timestamp)
+			assert.EqualValues(t, artifact.FlagMaternal, a.Flags)
+		}
+
+		// 区間の始まりが直前のデータに含まれなくても、判定済みの母体心拍に続く登録データは母体心拍とする。
+		third := generate(210, append(repeat(85, 30), repeat(140, 30)...)...)
+
+		results = detectBatchArtifacts(1, generate(90, repeat(85, 90)...), results[60:], third)
+
+		assert.EqualValues(t, 30, len(results))
+
+		for i, a := range results {
+			assert.EqualValues(t, seconds(210 + i), a.Timestamp)
+			assert.EqualValues(t, artifact.FlagMaternal, a.Flags)
+		}
+	})
+
+	t.Run("次の登録で元に戻る半減", func(t *testing.T) {
+		first := generate(0, append(repeat(140, 30), 70, 70)...)
+
+		// 元に戻るまでは半減とみなさない。
+		stored := detectBatchArtifacts(1, nil, nil, first)
+
+		assert.EqualValues(t, 0, len(stored))
+
+		results := detectBatchArtifacts(1, first, stored, generate(32, repeat(140, 10)...))
+
+		assert.EqualValues(t, 2, len(results))
+		assert.EqualValues(t, seconds(30), results[0].Timestamp)
+		assert.EqualValues(t, seconds(31), results[1].Timestamp)
+		assert.EqualValues(t, artifact.FlagHalving, results[0].Flags)
+		assert.EqualValues(t, artifact.FlagHalving, results[1].Flags)
+	})
+
+	t.Run("判定済みのアーチファクトは取り消さない", func(t *testing.T) {
+		context := generate(0, repeat(140, 10)...)
+		stored := []*model.HeartRateArtifact{
+			{MeasurementId: 1, Flags: int(artifact.FlagLoss), Timestamp: seconds(5)},
+		}
+
+		results := detectBatchArtifacts(1, context, stored, generate(10, repeat(140, 10)...))
+
+		assert.EqualValues(t, 0, len(results))
+	})
+}
@@ -192,10 +192,18 @@ func (s *AssessmentService) Execute(
 		ModifiedAt: now,
 	}
 
+	// TOCOから子宮収縮を検出。
+	var contractions []*model.Contraction = nil
+
+	if len(measurement.TOCOs) > 0 {
+		contractions = detectContractions(measurement.TOCOs)
+	}
+
 	return &model.DiagnosisEntity{
 		Diagnosis: diagnosis,
 		Algorithm: algorithm,
 		Contents: contents,
+		Contractions: contractions,
 	}, nil
 }
 
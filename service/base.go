@@ -2,8 +2,24 @@ package service
 
 import (
 	log "github.com/sirupsen/logrus"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/resource/rds"
 )
 
 type Service struct {
 	Log *log.Entry
 }
+
+// 一覧取得時のエラーを変換する。ソートやカーソルの指定が不正な場合はバリデーションエラーとする。
+func listError(e error) error {
+	if e == rds.ErrInvalidSort || e == rds.ErrInvalidCursor {
+		return C.NewBadRequestError(
+			"invalid_paging",
+			e.Error(),
+			map[string]interface{}{},
+		)
+	} else {
+		return C.DB_OPERATION_ERROR(e)
+	}
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"log"
+	"time"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/resource/rds"
+)
+
+// 計測における指定日時以前の最新の基線を取得する。基線細変動を持たない場合はnilを返す。
+func findLatestBaseline(
+	db model.QueryExecutor,
+	measurementId int,
+	current time.Time,
+) (*C.Baseline, error) {
+	if c, e := rds.FindLatestContent(db, measurementId, current); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else if c != nil {
+		var values interface{}
+		if ps, e := c.Parameters.UnmarshalObject(); e == nil {
+			values = ps
+		}
+
+		if event, e := readEvents(lib.AsJson(values)); e != nil {
+			log.Printf("Unexpected parameters are found in DiagnosisContent #%d.\n", c.Id)
+			log.Println(e.Error())
+		} else if bl, ok := event.(*C.Baseline); ok {
+			if bl.Variability != nil {
+				return bl, nil
+			} else {
+				log.Printf("DiagnosisContent #%d has Baseline-XXX parameter but does not have variability.\n", c.Id)
+			}
+		} else {
+			log.Printf("DiagnosisContent #%d has Baseline-XXX parameter but does not represent baseline event.\n", c.Id)
+		}
+	}
+
+	return nil, nil
+}
+
+// 診断項目を時系列順に走査し、直前の基線を文脈としてJSOG以外の各ガイドラインの分類を算出する。
+// 基線はbandの在胎週数の区分に応じて判定し直される。
+// frequencyは10分あたりの子宮収縮回数、timingsは診断項目と同じ順序で並ぶ一過性徐脈の時間関係で、いずれも省略できる。
+// 結果は診断項目と同じ順序で並ぶ。
+func classifyContents(
+	band C.GestationalBand,
+	latestBaseline *C.Baseline,
+	contents []*model.DiagnosisContent,
+	frequency *float64,
+	timings []*model.DecelerationTiming,
+) [][]*C.Classification {
+	results := [][]*C.Classification{}
+
+	latestBaseline = band.Interpret(latestBaseline)
+
+	for i, c := range contents {
+		findings := &C.CTGFindings{
+			Baseline: latestBaseline,
+			Contractions: frequency,
+		}
+
+		if i < len(timings) && timings[i] != nil {
+			timing := C.DecelerationTiming(timings[i].Timing)
+			findings.Timing = &timing
+		}
+
+		var values interface{}
+		if ps, e := c.Parameters.UnmarshalObject(); e == nil {
+			values = ps
+		}
+
+		if event, e := readEvents(lib.AsJson(values)); e == nil && event != nil {
+			switch evt := event.(type) {
+			case *C.Baseline:
+				if evt.Variability != nil {
+					latestBaseline = band.Interpret(evt)
+					findings.Baseline = latestBaseline
+				}
+			case *C.Deceleration:
+				t := evt.Type
+				findings.Deceleration = &t
+			case *C.Acceleration:
+				t := evt.Type
+				findings.Acceleration = &t
+			}
+		}
+
+		classifications := []*C.Classification{}
+
+		for _, g := range C.ClassifiedGuidelines {
+			if r := C.Classify(g, findings); r != nil {
+				classifications = append(classifications, r)
+			}
+		}
+
+		results = append(results, classifications)
+	}
+
+	return results
+}
+
+// 診断項目の分類を登録する。診断項目は登録済みでなければならない。
+func registerContentClassifications(
+	db model.QueryExecutor,
+	contents []*model.DiagnosisContent,
+	classifications [][]*C.Classification,
+) ([]*model.DiagnosisContentClassification, error) {
+	records := []*model.DiagnosisContentClassification{}
+	holder := []interface{}{}
+
+	for i, c := range contents {
+		for _, r := range classifications[i] {
+			record := &model.DiagnosisContentClassification{
+				DiagnosisContentId: c.Id,
+				Guideline: string(r.Guideline),
+				Category: r.Category,
+				Level: r.Level,
+			}
+			records = append(records, record)
+			holder = append(holder, record)
+		}
+	}
+
+	if len(holder) > 0 {
+		if e := db.Insert(holder...); e != nil {
+			return nil, C.DB_OPERATION_ERROR(e)
+		}
+	}
+
+	return records, nil
+}
+
+// 自動診断イベントの分類を、最後に接続された診断項目の分類で置き換える。
+func mergeEventClassifications(
+	db model.QueryExecutor,
+	contents []*model.DiagnosisContent,
+	classifications [][]*C.Classification,
+	merged map[int]*model.ComputedEvent,
+) error {
+	events := []*model.ComputedEvent{}
+	latest := map[int][]*C.Classification{}
+
+	for i, c := range contents {
+		if evt, be := merged[c.Id]; be {
+			if _, exists := latest[evt.Id]; !exists {
+				events = append(events, evt)
+			}
+			latest[evt.Id] = classifications[i]
+		}
+	}
+
+	for _, evt := range events {
+		records := []*model.ComputedEventClassification{}
+
+		for _, r := range latest[evt.Id] {
+			records = append(records, &model.ComputedEventClassification{
+				Guideline: string(r.Guideline),
+				Category: r.Category,
+				Level: r.Level,
+			})
+		}
+
+		if e := rds.ReplaceEventClassifications(db, evt.Id, records); e != nil {
+			return C.DB_OPERATION_ERROR(e)
+		}
+	}
+
+	return nil
+}
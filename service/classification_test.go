@@ -0,0 +1,148 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/model"
+)
+
+func TestServiceClassification_classifyContents(t *testing.T) {
+	content := func(params map[string]interface{}) *model.DiagnosisContent {
+		bs, _ := json.Marshal(params)
+		return &model.DiagnosisContent{Parameters: model.JSON(bs)}
+	}
+
+	categories := func(results []*C.Classification) map[C.Guideline]string {
+		m := map[C.Guideline]string{}
+		for _, r := range results {
+			m[r.Guideline] = r.Category
+		}
+		return m
+	}
+
+	t.Run("正常", func(t *testing.T) {
+		results := classifyContents(C.GestationalBandUnknown, nil, []*model.DiagnosisContent{
+			content(map[string]interface{}{
+				string(C.CTG_BaselineNormal): 140,
+				string(C.CTG_BaselineVariabilityNormal): 10,
+			}),
+			content(map[string]interface{}{
+				string(C.CTG_DecelerationED): 1,
+			}),
+		}, nil, nil)
+
+		assert.Len(t, results, 2)
+
+		for _, r := range results {
+			assert.EqualValues(t, map[C.Guideline]string{
+				C.GuidelineFIGO: C.CategoryNormal,
+				C.GuidelineNICE: C.CategoryNormal,
+				C.GuidelineACOG: C.CategoryI,
+			}, categories(r))
+		}
+	})
+
+	t.Run("基線が無い", func(t *testing.T) {
+		results := classifyContents(C.GestationalBandUnknown, nil, []*model.DiagnosisContent{
+			content(map[string]interface{}{
+				string(C.CTG_DecelerationHiLD): 1,
+			}),
+		}, nil, nil)
+
+		assert.Len(t, results, 1)
+		assert.Empty(t, results[0])
+	})
+
+	t.Run("前回の基線を文脈に利用", func(t *testing.T) {
+		latest := &C.Baseline{
+			Type: C.CTG_BaselineNormal,
+			Value: 140,
+			Variability: &C.BaselineVariability{Type: C.CTG_BaselineVariabilityLost, Value: 0},
+		}
+
+		results := classifyContents(C.GestationalBandUnknown, latest, []*model.DiagnosisContent{
+			content(map[string]interface{}{
+				string(C.CTG_DecelerationLowLD): 1,
+			}),
+		}, nil, nil)
+
+		assert.EqualValues(t, map[C.Guideline]string{
+			C.GuidelineFIGO: C.CategoryPathological,
+			C.GuidelineNICE: C.CategoryPathological,
+			C.GuidelineACOG: C.CategoryIII,
+		}, categories(results[0]))
+	})
+
+	t.Run("非安心所見の数で異なる", func(t *testing.T) {
+		results := classifyContents(C.GestationalBandUnknown, nil, []*model.DiagnosisContent{
+			content(map[string]interface{}{
+				string(C.CTG_BaselineAcceleration): 170,
+				string(C.CTG_BaselineVariabilityNormal): 10,
+			}),
+			content(map[string]interface{}{
+				string(C.CTG_DecelerationLowVD): 1,
+			}),
+		}, nil, nil)
+
+		assert.EqualValues(t, map[C.Guideline]string{
+			C.GuidelineFIGO: C.CategorySuspicious,
+			C.GuidelineNICE: C.CategorySuspicious,
+			C.GuidelineACOG: C.CategoryII,
+		}, categories(results[0]))
+
+		assert.EqualValues(t, map[C.Guideline]string{
+			C.GuidelineFIGO: C.CategorySuspicious,
+			C.GuidelineNICE: C.CategoryPathological,
+			C.GuidelineACOG: C.CategoryII,
+		}, categories(results[1]))
+	})
+
+	t.Run("一過性頻脈を伴う基線細変動の減少", func(t *testing.T) {
+		latest := &C.Baseline{
+			Type: C.CTG_BaselineNormal,
+			Value: 140,
+			Variability: &C.BaselineVariability{Type: C.CTG_BaselineVariabilityDecrease, Value: 3},
+		}
+
+		results := classifyContents(C.GestationalBandUnknown, latest, []*model.DiagnosisContent{
+			content(map[string]interface{}{
+				string(C.CTG_DecelerationNone): 1,
+			}),
+			content(map[string]interface{}{
+				string(C.CTG_Acceleration): 1,
+			}),
+		}, nil, nil)
+
+		assert.EqualValues(t, map[C.Guideline]string{
+			C.GuidelineFIGO: C.CategorySuspicious,
+			C.GuidelineNICE: C.CategorySuspicious,
+			C.GuidelineACOG: C.CategoryII,
+		}, categories(results[0]))
+
+		assert.EqualValues(t, map[C.Guideline]string{
+			C.GuidelineFIGO: C.CategoryNormal,
+			C.GuidelineNICE: C.CategoryNormal,
+			C.GuidelineACOG: C.CategoryII,
+		}, categories(results[1]))
+	})
+
+	t.Run("頻収縮", func(t *testing.T) {
+		contractions := 6.0
+
+		r := C.Classify(C.GuidelineFIGO, &C.CTGFindings{
+			Baseline: &C.Baseline{
+				Type: C.CTG_BaselineNormal,
+				Value: 140,
+				Variability: &C.BaselineVariability{Type: C.CTG_BaselineVariabilityNormal, Value: 10},
+			},
+			Contractions: &contractions,
+		})
+
+		assert.EqualValues(t, C.CategorySuspicious, r.Category)
+		assert.EqualValues(t, 2, r.Level)
+	})
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/gorp.v2"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/resource/rds"
+)
+
+type ClinicalEventService struct {
+	*Service
+	DB *gorp.DbMap
+}
+
+type ClinicalEventTxService struct {
+	*Service
+	DB *gorp.Transaction
+}
+
+// 計測における期間内の臨床イベントを新しい順に取得する。
+func (s *ClinicalEventService) ListInRange(
+	measurementId int,
+	begin time.Time,
+	end time.Time,
+) ([]*model.ClinicalEvent, error) {
+	if r, e := rds.ListClinicalEventsInRange(s.DB, measurementId, begin, end); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else {
+		return r, nil
+	}
+}
+
+// 計測の臨床イベントを取得する。
+func (s *ClinicalEventService) Fetch(
+	measurementId int,
+	id int,
+) (*model.ClinicalEvent, error) {
+	return fetchClinicalEvent(s.DB, measurementId, id)
+}
+
+// 計測に臨床イベントを登録する。
+func (s *ClinicalEventTxService) Create(
+	measurementId int,
+	doctorId int,
+	category C.ClinicalEventCategory,
+	occurredAt time.Time,
+	endedAt *time.Time,
+	memo string,
+) (*model.ClinicalEvent, error) {
+	if e := validateClinicalEventRange(occurredAt, endedAt); e != nil {
+		return nil, e
+	}
+
+	now := time.Now()
+
+	record := &model.ClinicalEvent{
+		MeasurementId: measurementId,
+		DoctorId: &doctorId,
+		Category: string(category),
+		OccurredAt: occurredAt,
+		EndedAt: endedAt,
+		Memo: memo,
+		CreatedAt: now,
+		ModifiedAt: now,
+	}
+
+	if e := s.DB.Insert(record); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	return record, nil
+}
+
+// 計測の臨床イベントを更新する。
+func (s *ClinicalEventTxService) Update(
+	measurementId int,
+	id int,
+	doctorId int,
+	category C.ClinicalEventCategory,
+	occurredAt time.Time,
+	endedAt *time.Time,
+	memo string,
+) (*model.ClinicalEvent, error) {
+	record, err := fetchClinicalEvent(s.DB, measurementId, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if e := validateClinicalEventRange(occurredAt, endedAt); e != nil {
+		return nil, e
+	}
+
+	record.DoctorId = &doctorId
+	record.Category = string(category)
+	record.OccurredAt = occurredAt
+	record.EndedAt = endedAt
+	record.Memo = memo
+	record.ModifiedAt = time.Now()
+
+	if _, e := s.DB.Update(record); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	return record, nil
+}
+
+// 計測の臨床イベントを削除する。
+func (s *ClinicalEventTxService) Delete(
+	measurementId int,
+	id int,
+) error {
+	record, err := fetchClinicalEvent(s.DB, measurementId, id)
+
+	if err != nil {
+		return err
+	}
+
+	if _, e := s.DB.Delete(record); e != nil {
+		return C.DB_OPERATION_ERROR(e)
+	}
+
+	return nil
+}
+
+func fetchClinicalEvent(
+	db model.QueryExecutor,
+	measurementId int,
+	id int,
+) (*model.ClinicalEvent, error) {
+	if r, e := rds.FetchClinicalEvent(db, measurementId, id); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else if r == nil {
+		return nil, C.NewNotFoundError(
+			"clinical_event_not_found",
+			fmt.Sprintf("Clinical event #%d is not found in measurement #%d", id, measurementId),
+			map[string]interface{}{},
+		)
+	} else {
+		return r, nil
+	}
+}
+
+func validateClinicalEventRange(occurredAt time.Time, endedAt *time.Time) error {
+	if endedAt != nil && endedAt.Before(occurredAt) {
+		return C.NewBadRequestError(
+			"invalid_range",
+			fmt.Sprintf("End of clinical event must not be before its occurrence"),
+			map[string]interface{}{},
+		)
+	}
+	return nil
+}
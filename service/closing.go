@@ -0,0 +1,122 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/resource/rds"
+)
+
+// 無受信期間を過ぎた計測を、システムによる完了メモを付けて完了状態にする。完了した計測を返す。
+// 自動完了しない設定の病院の計測は対象としない。
+func (s *MeasurementTxService) CloseInactive(now time.Time) ([]*model.Measurement, error) {
+	records, err := rds.ListInactiveMeasurements(s.DB, now, C.AutoCloseDefaultMinutes)
+
+	if err != nil {
+		return nil, C.DB_OPERATION_ERROR(err)
+	}
+
+	memo := C.AutoCloseMemo
+
+	for _, mm := range records {
+		mm.IsClosed = true
+		mm.ClosingMemo = &memo
+		mm.ClosedAt = &now
+		mm.ModifiedAt = now
+
+		if _, e := s.DB.Update(mm); e != nil {
+			return nil, C.DB_OPERATION_ERROR(e)
+		}
+	}
+
+	return records, nil
+}
+
+// 完了した計測を計測中に戻す。アーカイブされ、復元されていない計測は戻せない。
+func (s *MeasurementTxService) Reopen(id int) error {
+	mm, err := inquireEditableMeasurement(s.DB, id)
+
+	if err != nil {
+		return err
+	} else if !mm.IsClosed {
+		return C.NewBadRequestError(
+			"measurement_not_closed",
+			fmt.Sprintf("Measurement %d is not closed", id),
+			map[string]interface{}{},
+		)
+	}
+
+	mm.IsClosed = false
+	mm.ClosingMemo = nil
+	mm.ClosedAt = nil
+	mm.ModifiedAt = time.Now()
+
+	if _, e := s.DB.Update(mm); e != nil {
+		return C.DB_OPERATION_ERROR(e)
+	}
+
+	return nil
+}
+
+// 病院の計測の自動完了の設定を取得する。設定されていない場合はnilを返し、既定の無受信期間で自動完了する。
+func (s *HospitalService) FetchAutoClose(id int) (*model.HospitalAutoClose, error) {
+	if _, e := inquireHospital(s.DB, id); e != nil {
+		return nil, e
+	}
+
+	if r, e := rds.FetchHospitalAutoClose(s.DB, id); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else {
+		return r, nil
+	}
+}
+
+// 病院の計測の自動完了を設定する。disabledの場合は自動完了しない設定とし、minutesがnilの場合は設定を削除して既定の無受信期間に戻す。
+func (s *HospitalTxService) UpdateAutoClose(id int, minutes *int, disabled bool) (*model.HospitalAutoClose, error) {
+	if _, e := inquireHospital(s.DB, id); e != nil {
+		return nil, e
+	}
+
+	if _, e := s.DB.Exec(`DELETE FROM hospital_auto_close WHERE hospital_id = $1`, id); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	if !disabled && minutes == nil {
+		return nil, nil
+	}
+
+	record := &model.HospitalAutoClose{
+		HospitalId: id,
+		Minutes: minutes,
+		ModifiedAt: time.Now(),
+	}
+
+	if disabled {
+		record.Minutes = nil
+	}
+
+	if e := s.DB.Insert(record); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	return record, nil
+}
+
+// 病院の無受信期間を取得する。設定されていない場合は既定値とし、自動完了しない設定の場合はnilを返す。
+func inactivityPeriod(db model.QueryExecutor, hospitalId int) (*time.Duration, error) {
+	minutes := C.AutoCloseDefaultMinutes
+
+	if r, e := rds.FetchHospitalAutoClose(db, hospitalId); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else if r != nil && r.Minutes == nil {
+		return nil, nil
+	} else if r != nil {
+		minutes = *r.Minutes
+	}
+
+	period := time.Duration(minutes) * time.Minute
+
+	return &period, nil
+}
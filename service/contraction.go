@@ -0,0 +1,326 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"gopkg.in/gorp.v2"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/resource/rds"
+)
+
+type ContractionService struct {
+	*Service
+	DB *gorp.DbMap
+}
+
+// ある計測内の子宮収縮を古い順に取得する。
+func (s *ContractionService) List(
+	measurementId int,
+	begin *time.Time,
+	end *time.Time,
+) ([]*model.Contraction, error) {
+	var measurement *model.Measurement
+
+	if r, e := rds.InquireMeasurement(s.DB, measurementId); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else if r == nil {
+		return nil, C.NewNotFoundError(
+			"measurement_not_found",
+			fmt.Sprintf("Measurement %d is not found", measurementId),
+			map[string]interface{}{},
+		)
+	} else {
+		measurement = r
+	}
+
+	if begin == nil {
+		if measurement.FirstTime != nil {
+			begin = measurement.FirstTime
+		} else {
+			return []*model.Contraction{}, nil
+		}
+	}
+
+	if end == nil {
+		infinity := time.Now()
+		end = &infinity
+	}
+
+	if r, e := rds.ListContractions(s.DB, measurementId, *begin, *end); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else {
+		return r, nil
+	}
+}
+
+// TOCOの時系列から子宮収縮を検出する。
+//
+// 静止圧を分位点から推定し、静止圧からの上昇幅が閾値以上となる区間を収縮とする。
+// 閾値未満の短い中断は一続きの収縮とみなし、持続時間が短すぎる区間は除く。
+// データの先頭または末尾で途切れている収縮は、開始、終了が定まらないため除く。
+func detectContractions(tocos []*model.TOCO) []*model.Contraction {
+	results := []*model.Contraction{}
+
+	if len(tocos) == 0 {
+		return results
+	}
+
+	resting := restingTone(tocos)
+	threshold := resting + C.ContractionThreshold
+
+	closeSegment := func(start int, last int) {
+		if start == 0 || last == len(tocos)-1 {
+			return
+		}
+
+		if tocos[last].Timestamp.Sub(tocos[start].Timestamp) < C.ContractionMinimumDuration {
+			return
+		}
+
+		peak := start
+		for i := start; i <= last; i++ {
+			if tocos[i].Value > tocos[peak].Value {
+				peak = i
+			}
+		}
+
+		results = append(results, &model.Contraction{
+			MeasurementId: tocos[start].MeasurementId,
+			OnsetAt: tocos[start].Timestamp,
+			PeakAt: tocos[peak].Timestamp,
+			EndAt: tocos[last].Timestamp,
+			Amplitude: tocos[peak].Value - resting,
+		})
+	}
+
+	start := -1
+	last := -1
+
+	for i, t := range tocos {
+		if t.Value < threshold {
+			continue
+		}
+
+		if start >= 0 && t.Timestamp.Sub(tocos[last].Timestamp) > C.ContractionMergingGap {
+			closeSegment(start, last)
+			start = -1
+		}
+
+		if start < 0 {
+			start = i
+		}
+		last = i
+	}
+
+	if start >= 0 {
+		closeSegment(start, last)
+	}
+
+	return results
+}
+
+// TOCOの静止圧を推定する。
+func restingTone(tocos []*model.TOCO) int {
+	values := []int{}
+
+	for _, t := range tocos {
+		values = append(values, t.Value)
+	}
+
+	sort.Ints(values)
+
+	return values[int(float64(len(values)-1) * C.ContractionRestingQuantile)]
+}
+
+// 早発、遅発一過性徐脈について、子宮収縮との時間関係を判定する。
+// 結果は診断項目と同じ順序で並び、判定できない項目はnilとなる。
+//
+// 一過性徐脈の最下点は記録されないため、診断項目の期間の中央を最下点とみなし、
+// 期間の重なる収縮のうち頂点が最も近いものとの時間差で判定する。
+func decelerationTimings(
+	contents []*model.DiagnosisContent,
+	contractions []*model.Contraction,
+) []*model.DecelerationTiming {
+	results := []*model.DecelerationTiming{}
+
+	for _, c := range contents {
+		var timing *model.DecelerationTiming = nil
+
+		var values interface{}
+		if ps, e := c.Parameters.UnmarshalObject(); e == nil {
+			values = ps
+		}
+
+		if event, e := readEvents(lib.AsJson(values)); e == nil {
+			if d, ok := event.(*C.Deceleration); ok && isTimedDeceleration(d.Type) {
+				nadir := c.RangeFrom.Add(c.RangeUntil.Sub(c.RangeFrom) / 2)
+
+				var nearest *model.Contraction = nil
+
+				for _, uc := range contractions {
+					if uc.OnsetAt.After(c.RangeUntil) || uc.EndAt.Before(c.RangeFrom) {
+						continue
+					}
+
+					if nearest == nil || absDuration(nadir.Sub(uc.PeakAt)) < absDuration(nadir.Sub(nearest.PeakAt)) {
+						nearest = uc
+					}
+				}
+
+				if nearest != nil {
+					lag := nadir.Sub(nearest.PeakAt)
+
+					timing = &model.DecelerationTiming{
+						Timing: string(C.DecelerationTimingEarly),
+						Lag: lag.Seconds(),
+					}
+
+					if lag >= C.DecelerationLateLag {
+						timing.Timing = string(C.DecelerationTimingLate)
+					}
+				}
+			}
+		}
+
+		results = append(results, timing)
+	}
+
+	return results
+}
+
+func isTimedDeceleration(t C.DecelerationType) bool {
+	switch t {
+	case C.CTG_DecelerationED, C.CTG_DecelerationLowLD, C.CTG_DecelerationHiLD:
+		return true
+	default:
+		return false
+	}
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// 自動診断で検出された子宮収縮を保存し、診断時点の収縮頻度を登録する。
+// 収縮が検出されていない診断ではnilを返す。
+func registerContractions(
+	db model.QueryExecutor,
+	entity *model.DiagnosisEntity,
+) (*model.DiagnosisContraction, error) {
+	if entity.Contractions == nil {
+		return nil, nil
+	}
+
+	diagnosis := entity.Diagnosis
+
+	if e := rds.ReplaceContractions(
+		db, diagnosis.MeasurementId, diagnosis.RangeFrom, diagnosis.RangeUntil, entity.Contractions,
+	); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	count, err := rds.CountContractions(
+		db, diagnosis.MeasurementId, diagnosis.RangeUntil.Add(-C.ContractionAveragingDuration), diagnosis.RangeUntil,
+	)
+
+	if err != nil {
+		return nil, C.DB_OPERATION_ERROR(err)
+	}
+
+	record := &model.DiagnosisContraction{
+		DiagnosisId: diagnosis.Id,
+		Count: len(entity.Contractions),
+		Frequency: float64(count) * float64(C.ContractionFrequencyUnit) / float64(C.ContractionAveragingDuration),
+	}
+
+	if e := db.Insert(record); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	entity.Contraction = record
+
+	return record, nil
+}
+
+// 一過性徐脈の時間関係を登録する。診断項目は登録済みでなければならない。
+func registerDecelerationTimings(
+	db model.QueryExecutor,
+	contents []*model.DiagnosisContent,
+	timings []*model.DecelerationTiming,
+) ([]*model.DecelerationTiming, error) {
+	records := []*model.DecelerationTiming{}
+	holder := []interface{}{}
+
+	for i, c := range contents {
+		if t := timings[i]; t != nil {
+			t.DiagnosisContentId = c.Id
+			records = append(records, t)
+			holder = append(holder, t)
+		}
+	}
+
+	if len(holder) > 0 {
+		if e := db.Insert(holder...); e != nil {
+			return nil, C.DB_OPERATION_ERROR(e)
+		}
+	}
+
+	return records, nil
+}
+
+// 頻収縮の自動診断イベントを登録する。
+func raiseTachysystole(
+	db model.QueryExecutor,
+	diagnosis *model.Diagnosis,
+	frequency float64,
+	now time.Time,
+) error {
+	params, _ := json.Marshal(map[string]interface{}{
+		C.CTG_Tachysystole: frequency,
+	})
+
+	risk := C.TachysystoleRisk
+
+	event, err := rds.MergeTachysystole(db, &model.ComputedEvent{
+		MeasurementId: diagnosis.MeasurementId,
+		Risk: &risk,
+		Memo: "",
+		Parameters: model.JSON(params),
+		IsHidden: false,
+		RangeFrom: diagnosis.RangeUntil.Add(-C.ContractionAveragingDuration),
+		RangeUntil: diagnosis.RangeUntil,
+		CreatedAt: now,
+		ModifiedAt: now,
+	})
+
+	if err != nil {
+		return C.DB_OPERATION_ERROR(err)
+	}
+
+	records := []*model.ComputedEventClassification{}
+
+	for _, g := range C.ClassifiedGuidelines {
+		if r := C.ClassifyTachysystole(g); r != nil {
+			records = append(records, &model.ComputedEventClassification{
+				Guideline: string(r.Guideline),
+				Category: r.Category,
+				Level: r.Level,
+			})
+		}
+	}
+
+	if e := rds.ReplaceEventClassifications(db, event.Id, records); e != nil {
+		return C.DB_OPERATION_ERROR(e)
+	}
+
+	return nil
+}
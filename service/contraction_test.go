@@ -0,0 +1,127 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/model"
+)
+
+func TestServiceContraction_detectContractions(t *testing.T) {
+	base := time.Date(2021, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	seconds := func(s int) time.Time {
+		return base.Add(time.Duration(s) * time.Second)
+	}
+
+	// 1秒毎のTOCO。静止圧10に、指定区間で三角形状の上昇を加える。
+	generate := func(length int, peaks ...[3]int) []*model.TOCO {
+		tocos := []*model.TOCO{}
+
+		for i := 0; i < length; i++ {
+			value := 10
+
+			for _, p := range peaks {
+				onset, peak, end := p[0], p[1], p[2]
+
+				if i >= onset && i <= peak {
+					value = 10 + 50 * (i - onset) / (peak - onset)
+				} else if i > peak && i <= end {
+					value = 10 + 50 * (end - i) / (end - peak)
+				}
+			}
+
+			tocos = append(tocos, &model.TOCO{MeasurementId: 1, Value: value, Timestamp: seconds(i)})
+		}
+
+		return tocos
+	}
+
+	t.Run("収縮の検出", func(t *testing.T) {
+		results := detectContractions(generate(600, [3]int{60, 90, 120}, [3]int{300, 330, 360}))
+
+		assert.Len(t, results, 2)
+
+		// 静止圧+15に達するのは開始から9秒後、終了の9秒前。
+		assert.EqualValues(t, 1, results[0].MeasurementId)
+		assert.EqualValues(t, seconds(69), results[0].OnsetAt)
+		assert.EqualValues(t, seconds(90), results[0].PeakAt)
+		assert.EqualValues(t, seconds(111), results[0].EndAt)
+		assert.EqualValues(t, 50, results[0].Amplitude)
+
+		assert.EqualValues(t, seconds(330), results[1].PeakAt)
+	})
+
+	t.Run("短すぎる上昇は除く", func(t *testing.T) {
+		results := detectContractions(generate(600, [3]int{60, 70, 80}))
+
+		assert.Len(t, results, 0)
+	})
+
+	t.Run("データ端で途切れる収縮は除く", func(t *testing.T) {
+		results := detectContractions(generate(600, [3]int{-30, 0, 30}, [3]int{300, 330, 360}, [3]int{570, 600, 630}))
+
+		assert.Len(t, results, 1)
+		assert.EqualValues(t, seconds(330), results[0].PeakAt)
+	})
+
+	t.Run("データが無い", func(t *testing.T) {
+		assert.Len(t, detectContractions([]*model.TOCO{}), 0)
+	})
+}
+
+func TestServiceContraction_decelerationTimings(t *testing.T) {
+	base := time.Date(2021, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	seconds := func(s int) time.Time {
+		return base.Add(time.Duration(s) * time.Second)
+	}
+
+	content := func(evt C.DecelerationType, from, until int) *model.DiagnosisContent {
+		bs, _ := json.Marshal(map[string]interface{}{string(evt): 1})
+		return &model.DiagnosisContent{RangeFrom: seconds(from), RangeUntil: seconds(until), Parameters: model.JSON(bs)}
+	}
+
+	contractions := []*model.Contraction{
+		{OnsetAt: seconds(60), PeakAt: seconds(90), EndAt: seconds(120)},
+		{OnsetAt: seconds(300), PeakAt: seconds(330), EndAt: seconds(360)},
+	}
+
+	contents := []*model.DiagnosisContent{
+		// 頂点と中央が一致。
+		content(C.CTG_DecelerationED, 70, 110),
+		// 頂点から40秒遅れる。
+		content(C.CTG_DecelerationED, 340, 400),
+		// 対象外の一過性徐脈。
+		content(C.CTG_DecelerationLowVD, 70, 110),
+		// 重なる収縮が無い。
+		content(C.CTG_DecelerationLowLD, 400, 450),
+	}
+
+	results := decelerationTimings(contents, contractions)
+
+	assert.Len(t, results, 4)
+
+	assert.EqualValues(t, C.DecelerationTimingEarly, results[0].Timing)
+	assert.EqualValues(t, 0, results[0].Lag)
+
+	assert.EqualValues(t, C.DecelerationTimingLate, results[1].Timing)
+	assert.EqualValues(t, 40, results[1].Lag)
+
+	assert.Nil(t, results[2])
+	assert.Nil(t, results[3])
+
+	// 時間関係による分類の変化。
+	classifications := classifyContents(C.GestationalBandUnknown, &C.Baseline{
+		Type: C.CTG_BaselineNormal,
+		Value: 140,
+		Variability: &C.BaselineVariability{Type: C.CTG_BaselineVariabilityNormal, Value: 10},
+	}, contents[0:2], nil, results[0:2])
+
+	assert.EqualValues(t, C.CategoryI, classifications[0][2].Category)
+	assert.EqualValues(t, C.CategoryII, classifications[1][2].Category)
+}
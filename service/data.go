@@ -2,6 +2,7 @@ package service
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"time"
 
@@ -33,12 +34,339 @@ func (s *DataService) ListCTGData(
 	begin *time.Time,
 	end *time.Time,
 ) ([]*model.SensorValue, error) {
+	begin, end, err := s.dataRange(measurementId, begin, end)
+
+	if err != nil {
+		return nil, err
+	}
+
+	results := []*model.SensorValue{}
+
+	switch measurementType {
+	case C.MeasurementTypeHeartRate:
+		if values, e := influxdb.ListHeartRate(s.Influx, measurementId, *begin, *end); e != nil {
+			return nil, C.INFLUXDB_OPERATION_ERROR(e)
+		} else {
+			for _, v := range values {
+				results = append(results, &model.SensorValue{v.Value, int64(v.Timestamp.UnixNano() / 1000000)})
+			}
+		}
+	case C.MeasurementTypeTOCO:
+		if values, e := influxdb.ListTOCO(s.Influx, measurementId, *begin, *end); e != nil {
+			return nil, C.INFLUXDB_OPERATION_ERROR(e)
+		} else {
+			for _, v := range values {
+				results = append(results, &model.SensorValue{v.Value, int64(v.Timestamp.UnixNano() / 1000000)})
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// 計測と種別毎のセンサ値。
+type DataSeries struct {
+	MeasurementId int                  `json:"measurementId"`
+	Channel       C.MeasurementType    `json:"channel"`
+	Records       []*model.SensorValue `json:"records"`
+}
+
+// 複数の計測の指定種別のデータを一括で古い順に取得する。
+// 結果は計測、種別の指定順に並び、データの無い組み合わせも空の系列として含む。
+// beginを省略した場合は計測の最初のデータのうち最も古い日時、endを省略した場合は最後のデータのうち最も新しい日時までとする。
+// 期間は上限を超えられず、beginを省略した場合は末尾から上限までの期間に収める。
+func (s *DataService) ListBatchCTGData(
+	measurementIds []int,
+	measurementTypes []C.MeasurementType,
+	begin *time.Time,
+	end *time.Time,
+) ([]*DataSeries, error) {
+	measurements, err := rds.InquireMeasurementsByIds(s.DB, measurementIds)
+
+	if err != nil {
+		return nil, C.DB_OPERATION_ERROR(err)
+	}
+
+	measurementMap := map[int]*model.Measurement{}
+
+	for _, m := range measurements {
+		measurementMap[m.Id] = m
+	}
+
+	ids := []int{}
+
+	for _, id := range measurementIds {
+		if _, be := measurementMap[id]; !be {
+			return nil, C.NewNotFoundError(
+				"measurement_not_found",
+				fmt.Sprintf("Measurement %d is not found", id),
+				map[string]interface{}{},
+			)
+		}
+
+		if !containsInt(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+
+	types := []C.MeasurementType{}
+
+	for _, t := range measurementTypes {
+		if !containsMeasurementType(types, t) {
+			types = append(types, t)
+		}
+	}
+
+	if end == nil {
+		for _, m := range measurements {
+			if _, last := measurementDataRange(m); last != nil && (end == nil || last.After(*end)) {
+				end = last
+			}
+		}
+
+		if end == nil {
+			infinity := time.Now()
+			end = &infinity
+		}
+	}
+
+	if begin == nil {
+		for _, m := range measurements {
+			if m.FirstTime != nil && (begin == nil || m.FirstTime.Before(*begin)) {
+				begin = m.FirstTime
+			}
+		}
+
+		if limit := end.Add(-C.DataBatchMaximumDuration); begin == nil || begin.Before(limit) {
+			begin = &limit
+		}
+	}
+
+	if end.Sub(*begin) > C.DataBatchMaximumDuration {
+		return nil, C.NewBadRequestError(
+			"range_too_long",
+			fmt.Sprintf("Data range must be within %s", C.DataBatchMaximumDuration),
+			map[string]interface{}{},
+		)
+	}
+
+	points, err := influxdb.ListSensorPoints(s.Influx, types, ids, *begin, *end)
+
+	if err != nil {
+		return nil, C.INFLUXDB_OPERATION_ERROR(err)
+	}
+
+	return groupSensorPoints(ids, types, points), nil
+}
+
+// 計測と種別毎にデータを系列にまとめる。
+func groupSensorPoints(
+	measurementIds []int,
+	measurementTypes []C.MeasurementType,
+	points []lib.Point,
+) []*DataSeries {
+	results := []*DataSeries{}
+	seriesMap := map[int]map[C.MeasurementType]*DataSeries{}
+
+	for _, id := range measurementIds {
+		seriesMap[id] = map[C.MeasurementType]*DataSeries{}
+
+		for _, t := range measurementTypes {
+			series := &DataSeries{id, t, []*model.SensorValue{}}
+			seriesMap[id][t] = series
+			results = append(results, series)
+		}
+	}
+
+	appendValue := func(measurementId int, measurementType C.MeasurementType, value int, timestamp time.Time) {
+		if series, be := seriesMap[measurementId][measurementType]; be {
+			series.Records = append(series.Records, &model.SensorValue{value, int64(timestamp.UnixNano() / 1000000)})
+		}
+	}
+
+	for _, p := range points {
+		switch v := p.(type) {
+		case *model.HeartRate:
+			appendValue(v.MeasurementId, C.MeasurementTypeHeartRate, v.Value, v.Timestamp)
+		case *model.TOCO:
+			appendValue(v.MeasurementId, C.MeasurementTypeTOCO, v.Value, v.Timestamp)
+		}
+	}
+
+	return results
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsMeasurementType(values []C.MeasurementType, value C.MeasurementType) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// アーチファクトを補正、除去した心拍データを古い順に取得する。
+func (s *DataService) ListCleanedHeartRate(
+	measurementId int,
+	begin *time.Time,
+	end *time.Time,
+) ([]*model.SensorValue, error) {
+	begin, end, err := s.dataRange(measurementId, begin, end)
+
+	if err != nil {
+		return nil, err
+	}
+
+	heartRates, err := influxdb.ListHeartRate(s.Influx, measurementId, *begin, *end)
+
+	if err != nil {
+		return nil, C.INFLUXDB_OPERATION_ERROR(err)
+	}
+
+	artifacts, err := influxdb.ListHeartRateArtifacts(s.Influx, measurementId, *begin, *end)
+
+	if err != nil {
+		return nil, C.INFLUXDB_OPERATION_ERROR(err)
+	}
+
+	results := []*model.SensorValue{}
+
+	for _, v := range cleanHeartRates(heartRates, artifacts) {
+		results = append(results, &model.SensorValue{v.Value, int64(v.Timestamp.UnixNano() / 1000000)})
+	}
+
+	return results, nil
+}
+
+// ある計測内の指定種別のデータを一定期間毎の最小、最大、平均に集約して古い順に取得する。
+// 集約期間は秒単位で、resolutionを省略した場合はmaxPoints以下の点数となる最短の期間とする。
+// cleanedがtrueの場合、アーチファクトを補正、除去した心拍を集約する。
+func (s *DataService) ListCTGEnvelopes(
+	measurementId int,
+	measurementType C.MeasurementType,
+	begin *time.Time,
+	end *time.Time,
+	resolution *int,
+	maxPoints *int,
+	cleaned bool,
+) ([]*model.SensorEnvelope, time.Duration, error) {
+	begin, end, err := s.dataRange(measurementId, begin, end)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	window := envelopeWindow(*begin, *end, resolution, maxPoints)
+
+	if cleaned && measurementType == C.MeasurementTypeHeartRate {
+		values, e := s.ListCleanedHeartRate(measurementId, begin, end)
+
+		if e != nil {
+			return nil, 0, e
+		}
+
+		return aggregateSensorValues(values, window), window, nil
+	}
+
+	if r, e := influxdb.ListSensorEnvelopes(s.Influx, measurementType, measurementId, *begin, *end, window); e != nil {
+		return nil, 0, C.INFLUXDB_OPERATION_ERROR(e)
+	} else {
+		return r, window, nil
+	}
+}
+
+// 集約期間を決定する。いずれの指定も無い場合は1秒とする。
+func envelopeWindow(
+	begin time.Time,
+	end time.Time,
+	resolution *int,
+	maxPoints *int,
+) time.Duration {
+	seconds := 1
+
+	if resolution != nil {
+		seconds = *resolution
+	} else if maxPoints != nil && *maxPoints > 0 {
+		span := int(math.Ceil(end.Sub(begin).Seconds()))
+		seconds = (span + *maxPoints - 1) / *maxPoints
+	}
+
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// センサ値を一定期間毎の最小、最大、平均に集約する。期間はUnixエポックを起点に区切る。
+func aggregateSensorValues(values []*model.SensorValue, window time.Duration) []*model.SensorEnvelope {
+	results := []*model.SensorEnvelope{}
+
+	unit := window.Milliseconds()
+
+	var current *model.SensorEnvelope = nil
+	sum := 0
+	count := 0
+
+	flush := func() {
+		if current != nil {
+			current.Mean = float64(sum) / float64(count)
+			results = append(results, current)
+		}
+	}
+
+	for _, v := range values {
+		observedAt := v.ObservedAt - v.ObservedAt % unit
+
+		if current == nil || current.ObservedAt != observedAt {
+			flush()
+
+			current = &model.SensorEnvelope{
+				Minimum: v.Value,
+				Maximum: v.Value,
+				ObservedAt: observedAt,
+			}
+			sum = 0
+			count = 0
+		}
+
+		if v.Value < current.Minimum {
+			current.Minimum = v.Value
+		}
+		if v.Value > current.Maximum {
+			current.Maximum = v.Value
+		}
+
+		sum += v.Value
+		count++
+	}
+
+	flush()
+
+	return results
+}
+
+// データ取得期間を決定する。省略された場合は計測の最初、最後のデータを含む期間とする。
+func (s *DataService) dataRange(
+	measurementId int,
+	begin *time.Time,
+	end *time.Time,
+) (*time.Time, *time.Time, error) {
 	var measurement *model.Measurement
 
 	if r, e := rds.InquireMeasurement(s.DB, measurementId); e != nil {
-		return nil, C.DB_OPERATION_ERROR(e)
+		return nil, nil, C.DB_OPERATION_ERROR(e)
 	} else if r == nil {
-		return nil, C.NewNotFoundError(
+		return nil, nil, C.NewNotFoundError(
 			"measurement_not_found",
 			fmt.Sprintf("Measurement %d is not found", measurementId),
 			map[string]interface{}{},
@@ -57,10 +385,8 @@ func (s *DataService) ListCTGData(
 	}
 
 	if end == nil {
-		if measurement.LastTime != nil {
-			// 右閉の秒単位検索となるため、1秒進めて最後のデータを含める。
-			last := measurement.LastTime.Add(time.Second) 
-			end = &last
+		if _, last := measurementDataRange(measurement); last != nil {
+			end = last
 		} else {
 			// 過去のデータしか存在しえない。
 			infinity := time.Now()
@@ -68,28 +394,7 @@ func (s *DataService) ListCTGData(
 		}
 	}
 
-	results := []*model.SensorValue{}
-
-	switch measurementType {
-	case C.MeasurementTypeHeartRate:
-		if values, e := influxdb.ListHeartRate(s.Influx, measurementId, *begin, *end); e != nil {
-			return nil, C.INFLUXDB_OPERATION_ERROR(e)
-		} else {
-			for _, v := range values {
-				results = append(results, &model.SensorValue{v.Value, int64(v.Timestamp.UnixNano() / 1000000)})
-			}
-		}
-	case C.MeasurementTypeTOCO:
-		if values, e := influxdb.ListTOCO(s.Influx, measurementId, *begin, *end); e != nil {
-			return nil, C.INFLUXDB_OPERATION_ERROR(e)
-		} else {
-			for _, v := range values {
-				results = append(results, &model.SensorValue{v.Value, int64(v.Timestamp.UnixNano() / 1000000)})
-			}
-		}
-	}
-
-	return results, nil
+	return begin, end, nil
 }
 
 type CTGData struct {
@@ -105,6 +410,17 @@ func (d CTGData) GetFHR1() (int, error) {
 	return strconv.Atoi(d.FHR1)
 }
 
+// FHR2を取得する。単胎の端末は値を送らないため、空の場合は値が無いものとする。
+func (d CTGData) GetFHR2() (int, bool, error) {
+	if d.FHR2 == "" {
+		return 0, false, nil
+	} else if v, e := strconv.Atoi(d.FHR2); e != nil {
+		return 0, false, e
+	} else {
+		return v, true, nil
+	}
+}
+
 func (d CTGData) GetUC() (int, error) {
 	return strconv.Atoi(d.UC)
 }
@@ -135,6 +451,7 @@ func (s *DataTxService) RegisterCTGData(hospitalId int, ctgData []CTGData) (*CTG
 	}
 
 	heartrateRecords := []lib.Point{}
+	secondHeartrateRecords := []lib.Point{}
 	tocoRecords := []lib.Point{}
 
 	type patientEntry struct {
@@ -148,7 +465,48 @@ func (s *DataTxService) RegisterCTGData(hospitalId int, ctgData []CTGData) (*CTG
 	// 患者コードからエントリへのマップ。
 	entries := map[string]*patientEntry{}
 
+	// 患者が割り当てられた端末のデータは、端末側の患者IDに関わらず割り当ての計測コードの計測とする。
+	// 端末コードから割り当てへのマップ。
+	admissionMap := map[string]*model.TerminalAdmission{}
+	// 計測コードから割り当てへのマップ。
+	admissionCodes := map[string]*model.TerminalAdmission{}
+
+	machines := []string{}
+	machineSet := map[string]bool{}
+
 	for _, data := range ctgData {
+		if !machineSet[data.MachineId] {
+			machineSet[data.MachineId] = true
+			machines = append(machines, data.MachineId)
+		}
+	}
+
+	if terminals, e := rds.InquireTerminalsByCodes(s.DB, hospitalId, machines); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else {
+		terminalIds := []int{}
+		terminalCodes := map[int]string{}
+
+		for _, t := range terminals {
+			terminalIds = append(terminalIds, t.Id)
+			terminalCodes[t.Id] = t.Code
+		}
+
+		if admissions, e := rds.InquireActiveAdmissions(s.DB, terminalIds); e != nil {
+			return nil, C.DB_OPERATION_ERROR(e)
+		} else {
+			for _, a := range admissions {
+				admissionMap[terminalCodes[a.TerminalId]] = a
+				admissionCodes[admissionCode(a)] = a
+			}
+		}
+	}
+
+	for _, data := range ctgData {
+		if a, be := admissionMap[data.MachineId]; be {
+			data.PatientId = admissionCode(a)
+		}
+
 		if fhr1, e := data.GetFHR1(); e != nil {
 			return nil, C.NewBadRequestError(
 				"Invalid FHR1",
@@ -167,12 +525,23 @@ func (s *DataTxService) RegisterCTGData(hospitalId int, ctgData []CTGData) (*CTG
 				fmt.Sprintf("Timestamp must be in formatted as an integer but '%s'", data.Timestamp),
 				map[string]interface{}{},
 			)
+		} else if fhr2, be, e := data.GetFHR2(); e != nil {
+			return nil, C.NewBadRequestError(
+				"Invalid FHR2",
+				fmt.Sprintf("FHR2 must be in formatted as an integer but '%s'", data.FHR2),
+				map[string]interface{}{},
+			)
 		} else {
 			observedAt := time.Unix(ts / 1000, (ts % 1000) * 1000000)
 
 			heartrateRecords = append(heartrateRecords, &model.HeartRate{
 				0, data.PatientId, data.MachineId, fhr1, observedAt,
 			})
+			if be {
+				secondHeartrateRecords = append(secondHeartrateRecords, &model.SecondHeartRate{
+					0, data.PatientId, data.MachineId, fhr2, observedAt,
+				})
+			}
 			tocoRecords = append(tocoRecords, &model.TOCO{
 				0, data.PatientId, data.MachineId, uc, observedAt,
 			})
@@ -193,34 +562,120 @@ func (s *DataTxService) RegisterCTGData(hospitalId int, ctgData []CTGData) (*CTG
 
 	now := time.Now()
 
-	// 既存の計測を取得。
+	// 既存の計測を取得。計測コードは暗号化されているため、元のコードで対応付ける。
+	keyring := newPHIKeyring(s.DB)
+
 	measurementMap := map[string]*model.Measurement{}
 
-	if measurements, e := rds.InquireMeasurementsByCodes(s.DB, hospitalId, codes); e != nil {
+	candidates, origins, err := keyring.codeCandidates(hospitalId, codes)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if measurements, e := rds.InquireMeasurementsByCodes(s.DB, hospitalId, candidates); e != nil {
 		return nil, C.DB_OPERATION_ERROR(e)
 	} else {
+		// 同じコードの計測が複数ある場合は、最新の計測を対象とする。
 		for _, m := range measurements {
-			measurementMap[m.Code] = m
+			if _, be := measurementMap[origins[m.Code]]; !be {
+				measurementMap[origins[m.Code]] = m
+			}
+		}
+	}
+
+	// 完了した計測のコードに無受信期間を空けてデータが届いた場合は、完了した計測を延長せずに同じ患者の新規の計測とする。
+	// 自動完了しない設定の病院では、完了した計測を延長する。
+	reopenedMap := map[string]*model.Patient{}
+
+	if len(measurementMap) > 0 {
+		period, err := inactivityPeriod(s.DB, hospitalId)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for c, m := range measurementMap {
+			if period != nil && m.IsClosed && m.LastTime != nil && !entries[c].begin.Before(m.LastTime.Add(*period)) {
+				delete(measurementMap, c)
+				reopenedMap[c] = &model.Patient{Id: m.PatientId, HospitalId: hospitalId}
+			}
 		}
 	}
 
 	// 新規患者登録。
-	// 新規の計測は新規の患者を生成し、patientモデルは新規計測作成時にしか利用しないため、既存のpatientで埋める必要はない。
+	// 新規の計測は、病院の識別規則で計測コードから得た識別子が登録済みならばその患者の計測とし、それ以外は新規の患者を生成する。
+	// patientモデルは新規計測作成時にIDを参照するのみのため、既存の患者はIDのみを埋める。
 	patientMap := map[string]*model.Patient{}
 
-	newPatients := []interface{}{}
+	newCodes := []string{}
 
 	for _, c := range codes {
-		// 現状では、新規の計測は必ず新規の患者を生成する。
-		if _, be := measurementMap[c]; !be {
-			p := &model.Patient{
-				HospitalId: hospitalId,
-				CreatedAt:  now,
-				ModifiedAt: now,
-			}
+		if _, be := measurementMap[c]; be {
+			continue
+		}
 
+		if p, be := reopenedMap[c]; be {
 			patientMap[c] = p
-			newPatients = append(newPatients, p)
+		} else if a, be := admissionCodes[c]; be {
+			// 割り当て中の端末の計測は、割り当てた患者の計測とする。
+			patientMap[c] = &model.Patient{Id: a.PatientId, HospitalId: hospitalId}
+		} else {
+			newCodes = append(newCodes, c)
+		}
+	}
+
+	identifierMap, err := extractPatientIdentifiers(s.DB, hospitalId, newCodes)
+
+	if err != nil {
+		return nil, err
+	}
+
+	identifiedMap := map[string]*model.Patient{}
+	identifierValues := []string{}
+
+	for _, c := range newCodes {
+		if i, be := identifierMap[c]; be {
+			identifierValues = append(identifierValues, i)
+		}
+	}
+
+	if candidates, origins, e := keyring.codeCandidates(hospitalId, identifierValues); e != nil {
+		return nil, e
+	} else if identifiers, e := rds.InquirePatientIdentifiersByValues(s.DB, hospitalId, candidates); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else {
+		for _, i := range identifiers {
+			identifiedMap[origins[i.Value]] = &model.Patient{Id: i.PatientId, HospitalId: hospitalId}
+		}
+	}
+
+	newPatients := []interface{}{}
+	newIdentifiers := []string{}
+
+	for _, c := range newCodes {
+		i, identified := identifierMap[c]
+
+		if identified {
+			if p, be := identifiedMap[i]; be {
+				patientMap[c] = p
+				continue
+			}
+		}
+
+		p := &model.Patient{
+			HospitalId: hospitalId,
+			CreatedAt:  now,
+			ModifiedAt: now,
+		}
+
+		patientMap[c] = p
+		newPatients = append(newPatients, p)
+
+		// 同じ識別子を持つ計測コードは、同じ新規の患者の計測とする。
+		if identified {
+			identifiedMap[i] = p
+			newIdentifiers = append(newIdentifiers, i)
 		}
 	}
 
@@ -228,6 +683,23 @@ func (s *DataTxService) RegisterCTGData(hospitalId int, ctgData []CTGData) (*CTG
 		return nil, C.DB_OPERATION_ERROR(e)
 	}
 
+	for _, i := range newIdentifiers {
+		sealed, err := keyring.sealCode(hospitalId, i)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if e := s.DB.Insert(&model.PatientIdentifier{
+			HospitalId: hospitalId,
+			PatientId:  identifiedMap[i].Id,
+			Value:      sealed,
+			CreatedAt:  now,
+		}); e != nil {
+			return nil, C.DB_OPERATION_ERROR(e)
+		}
+	}
+
 	// 新規端末登録。
 	terminalMap := map[string]*model.MeasurementTerminal{}
 	terminalCodes := []string{}
@@ -274,7 +746,16 @@ func (s *DataTxService) RegisterCTGData(hospitalId int, ctgData []CTGData) (*CTG
 
 	for _, c := range codes {
 		entry := entries[c]
+
+		// 暗号化前に登録された計測コードも、更新時に暗号化する。
+		code, err := keyring.sealCode(hospitalId, c)
+
+		if err != nil {
+			return nil, err
+		}
+
 		if m, be := measurementMap[c]; be {
+			m.Code = code
 			if m.FirstTime == nil || m.FirstTime.After(entry.begin) {
 				m.FirstTime = &entry.begin
 			}
@@ -285,7 +766,7 @@ func (s *DataTxService) RegisterCTGData(hospitalId int, ctgData []CTGData) (*CTG
 			updMeasurements = append(updMeasurements, m)
 		} else {
 			m := &model.Measurement{
-				Code:       c,
+				Code:       code,
 				PatientId:  patientMap[c].Id,
 				TerminalId: terminalMap[entry.machine].Id,
 				FirstTime:  &entry.begin,
@@ -306,6 +787,17 @@ func (s *DataTxService) RegisterCTGData(hospitalId int, ctgData []CTGData) (*CTG
 		return nil, C.DB_OPERATION_ERROR(e)
 	}
 
+	// 割り当て中に開始した計測を記録する。
+	for c, a := range admissionCodes {
+		if m, be := measurementMap[c]; be && (a.MeasurementId == nil || *a.MeasurementId != m.Id) {
+			a.MeasurementId = &m.Id
+
+			if _, e := s.DB.Update(a); e != nil {
+				return nil, C.DB_OPERATION_ERROR(e)
+			}
+		}
+	}
+
 	// InfluxDB用モデルに計測記録IDを移す。
 	for _, r := range heartrateRecords {
 		p := r.(*model.HeartRate)
@@ -317,16 +809,41 @@ func (s *DataTxService) RegisterCTGData(hospitalId int, ctgData []CTGData) (*CTG
 		p.MeasurementId = measurementMap[p.PatientCode].Id
 	}
 
-	// InfluxDBに登録。
-	errors := s.Influx.Insert("spiker", heartrateRecords...)
+	for _, r := range secondHeartrateRecords {
+		p := r.(*model.SecondHeartRate)
+		p.MeasurementId = measurementMap[p.PatientCode].Id
+	}
+
+	// 心拍のアーチファクトを検出。
+	artifactRecords := []lib.Point{}
+
+	heartRates := []*model.HeartRate{}
+	for _, r := range heartrateRecords {
+		heartRates = append(heartRates, r.(*model.HeartRate))
+	}
+
+	if artifacts, e := detectHeartRateArtifacts(s.Influx, heartRates); e != nil {
+		return nil, C.INFLUXDB_OPERATION_ERROR(e)
+	} else {
+		for _, a := range artifacts {
+			artifactRecords = append(artifactRecords, a)
+		}
+	}
+
+	// InfluxDBに登録。患者コードはタグに含めない。
+	errors := s.Influx.Insert("spiker", model.OpaquePoints(heartrateRecords...)...)
 
 	stats.SuccessFHR1 = len(heartrateRecords) - len(errors)
 	stats.FailureFHR1 = append(stats.FailureFHR1, errors...)
 
-	errors = s.Influx.Insert("spiker", tocoRecords...)
+	errors = s.Influx.Insert("spiker", model.OpaquePoints(tocoRecords...)...)
 
 	stats.SuccessUC = len(tocoRecords) - len(errors)
 	stats.FailureUC = append(stats.FailureUC, errors...)
 
+	// アーチファクトとFHR2の登録失敗は心拍の登録失敗として扱う。
+	stats.FailureFHR1 = append(stats.FailureFHR1, s.Influx.Insert("spiker", artifactRecords...)...)
+	stats.FailureFHR1 = append(stats.FailureFHR1, s.Influx.Insert("spiker", secondHeartrateRecords...)...)
+
 	return stats, nil
 }
\ No newline at end of file
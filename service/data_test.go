@@ -0,0 +1,95 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/model"
+)
+
+func TestServiceData_envelopeWindow(t *testing.T) {
+	begin := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := begin.Add(time.Duration(12) * time.Hour)
+
+	value := func(v int) *int {
+		return &v
+	}
+
+	assert.EqualValues(t, time.Second, envelopeWindow(begin, end, nil, nil))
+	assert.EqualValues(t, time.Duration(5) * time.Second, envelopeWindow(begin, end, value(5), value(10)))
+	// 12時間を1000点以下とするため44秒。
+	assert.EqualValues(t, time.Duration(44) * time.Second, envelopeWindow(begin, end, nil, value(1000)))
+	// 点数がデータ数より多い場合は1秒。
+	assert.EqualValues(t, time.Second, envelopeWindow(begin, end, nil, value(100000)))
+}
+
+func TestServiceData_aggregateSensorValues(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano() / 1000000
+
+	values := []*model.SensorValue{
+		&model.SensorValue{140, base + 1000},
+		&model.SensorValue{90, base + 4000},
+		&model.SensorValue{150, base + 9000},
+		// 期間をまたぐ。
+		&model.SensorValue{130, base + 10000},
+		// データの無い期間は含まない。
+		&model.SensorValue{120, base + 35000},
+		&model.SensorValue{125, base + 36000},
+	}
+
+	results := aggregateSensorValues(values, time.Duration(10) * time.Second)
+
+	assert.Len(t, results, 3)
+
+	assert.EqualValues(t, base, results[0].ObservedAt)
+	assert.EqualValues(t, 90, results[0].Minimum)
+	assert.EqualValues(t, 150, results[0].Maximum)
+	assert.InDelta(t, 126.67, results[0].Mean, 0.01)
+
+	assert.EqualValues(t, base + 10000, results[1].ObservedAt)
+	assert.EqualValues(t, 130, results[1].Minimum)
+	assert.EqualValues(t, 130, results[1].Maximum)
+
+	assert.EqualValues(t, base + 30000, results[2].ObservedAt)
+	assert.EqualValues(t, 122.5, results[2].Mean)
+
+	assert.Len(t, aggregateSensorValues([]*model.SensorValue{}, time.Second), 0)
+}
+
+func TestServiceData_groupSensorPoints(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	points := []lib.Point{
+		&model.HeartRate{MeasurementId: 2, Value: 140, Timestamp: base},
+		&model.TOCO{MeasurementId: 1, Value: 20, Timestamp: base},
+		&model.HeartRate{MeasurementId: 1, Value: 135, Timestamp: base.Add(time.Second)},
+		// 指定外の計測は含まない。
+		&model.HeartRate{MeasurementId: 3, Value: 150, Timestamp: base},
+	}
+
+	results := groupSensorPoints(
+		[]int{2, 1},
+		[]C.MeasurementType{C.MeasurementTypeHeartRate, C.MeasurementTypeTOCO},
+		points,
+	)
+
+	assert.Len(t, results, 4)
+
+	assert.EqualValues(t, 2, results[0].MeasurementId)
+	assert.EqualValues(t, C.MeasurementTypeHeartRate, results[0].Channel)
+	assert.Len(t, results[0].Records, 1)
+	assert.EqualValues(t, 140, results[0].Records[0].Value)
+	assert.EqualValues(t, base.UnixNano() / 1000000, results[0].Records[0].ObservedAt)
+
+	// データの無い組み合わせは空の系列。
+	assert.EqualValues(t, C.MeasurementTypeTOCO, results[1].Channel)
+	assert.Len(t, results[1].Records, 0)
+
+	assert.EqualValues(t, 1, results[2].MeasurementId)
+	assert.EqualValues(t, 135, results[2].Records[0].Value)
+	assert.EqualValues(t, 20, results[3].Records[0].Value)
+}
@@ -3,7 +3,6 @@ package service
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"sort"
 	"time"
 
@@ -54,7 +53,7 @@ func (s *DiagnosisService) FetchByMeasurement(
 	}
 }
 
-// ある病院において、指定した診断以降の既定値以上のリスクを持つ診断を全て取得する。
+// ある病院において、指定した診断以降の、病院のガイドラインでアラート対象となる診断を全て取得する。
 func (s *DiagnosisService) ListFollowings(
 	hospitalId int,
 	latest *int,
@@ -73,7 +72,14 @@ func (s *DiagnosisService) ListFollowings(
 		beginTime = time.Now().Add(-C.AlertBackingDuration)
 	}
 
-	if ds, e := rds.ListFollowingDiagnoses(s.DB, hospitalId, C.AlertRiskThreshold, beginTime); e != nil {
+	// 病院がアラートに用いるガイドライン。
+	guideline, err := rds.FetchHospitalGuideline(s.DB, hospitalId)
+
+	if err != nil {
+		return nil, C.DB_OPERATION_ERROR(err)
+	}
+
+	if ds, e := rds.ListFollowingDiagnoses(s.DB, hospitalId, guideline, guideline.AlertLevel(), beginTime); e != nil {
 		return nil, C.DB_OPERATION_ERROR(e)
 	} else {
 		return ds, nil
@@ -124,6 +130,41 @@ func (s *DiagnosisTxService) Register(
 ) error {
 	now := time.Now()
 
+	// 在胎週数の区分を決定し、早産の区分では基線を判定し直してリスクを算出し直す。
+	gestations := []*model.DiagnosisGestation{}
+	riskTables := []*model.RiskTable{}
+
+	for _, de := range entities {
+		gestation, err := gestationForMeasurement(s.DB, de.Diagnosis.MeasurementId)
+
+		if err != nil {
+			return err
+		}
+
+		gestations = append(gestations, gestation)
+
+		var riskTable *model.RiskTable = nil
+
+		if band := C.GestationalBand(gestation.Band); band.Reinterprets() && len(de.Contents) > 0 {
+			table, matrices, err := riskMatricesForMeasurement(s.DB, de.Diagnosis.MeasurementId)
+
+			if err != nil {
+				return err
+			}
+
+			latestBaseline, err := findLatestBaseline(s.DB, de.Diagnosis.MeasurementId, de.Contents[0].RangeFrom)
+
+			if err != nil {
+				return err
+			}
+
+			de.Diagnosis.MaximumRisk = reassessRisks(band, matrices, latestBaseline, de.Contents)
+			riskTable = table
+		}
+
+		riskTables = append(riskTables, riskTable)
+	}
+
 	diagnoses := []interface{}{}
 
 	for _, e := range entities {
@@ -160,9 +201,75 @@ func (s *DiagnosisTxService) Register(
 		return C.DB_OPERATION_ERROR(e)
 	}
 
-	for _, de := range entities {
-		if e := rds.MergeNewDiagnosis(s.DB, de.Diagnosis.MeasurementId, de.Contents, now); e != nil {
+	for i, de := range entities {
+		if e := registerGestation(s.DB, de.Diagnosis.Id, gestations[i]); e != nil {
+			return e
+		}
+
+		de.Gestation = gestations[i]
+
+		// リスクを算出し直した場合は、用いたリスクテーブルを記録する。
+		if riskTables[i] != nil {
+			if e := s.DB.Insert(&model.DiagnosisRiskTable{
+				DiagnosisId: de.Diagnosis.Id,
+				RiskTableId: riskTables[i].Id,
+			}); e != nil {
+				return C.DB_OPERATION_ERROR(e)
+			}
+
+			de.RiskTable = riskTables[i]
+		}
+
+		// 子宮収縮を保存し、一過性徐脈との時間関係を登録する。
+		contraction, err := registerContractions(s.DB, de)
+
+		if err != nil {
+			return err
+		}
+
+		var frequency *float64 = nil
+
+		if contraction != nil {
+			frequency = &contraction.Frequency
+		}
+
+		timings := decelerationTimings(de.Contents, de.Contractions)
+
+		registered, err := registerDecelerationTimings(s.DB, de.Contents, timings)
+
+		if err != nil {
+			return err
+		}
+
+		de.Timings = registered
+
+		if C.IsTachysystole(frequency) {
+			if e := raiseTachysystole(s.DB, de.Diagnosis, *frequency, now); e != nil {
+				return e
+			}
+		}
+
+		if len(de.Contents) == 0 {
+			continue
+		}
+
+		// ガイドライン別の分類を登録し、イベントに反映する。
+		latestBaseline, err := findLatestBaseline(s.DB, de.Diagnosis.MeasurementId, de.Contents[0].RangeFrom)
+
+		if err != nil {
+			return err
+		}
+
+		classifications := classifyContents(C.GestationalBand(gestations[i].Band), latestBaseline, de.Contents, frequency, timings)
+
+		if _, e := registerContentClassifications(s.DB, de.Contents, classifications); e != nil {
+			return e
+		}
+
+		if merged, e := rds.MergeNewDiagnosis(s.DB, de.Diagnosis.MeasurementId, de.Contents, now); e != nil {
 			return C.DB_OPERATION_ERROR(e)
+		} else if e := mergeEventClassifications(s.DB, de.Contents, classifications, merged); e != nil {
+			return e
 		}
 	}
 
@@ -192,26 +299,40 @@ func (s *DiagnosisTxService) RegisterByAnnotator(
 
 	contents := []*model.DiagnosisContent{}
 
-	var latestBaseline *C.Baseline = nil
+	// 病院で利用するリスクテーブルを取得。
+	riskTable, matrices, err := riskMatricesForMeasurement(s.DB, measurementId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(items) == 0 {
+		return nil, C.NewBadRequestError(
+			"empty_contents",
+			fmt.Sprintf("Diagnosis must have at least one content"),
+			map[string]interface{}{},
+		)
+	}
+
+	// 在胎週数の区分を決定。
+	gestation, err := gestationForMeasurement(s.DB, measurementId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	band := C.GestationalBand(gestation.Band)
 
 	// 計測における前回のベースラインを取得。
-	if c, e := rds.FindLatestContent(s.DB, measurementId, items[0].RangeFrom); e != nil {
-		return nil, C.DB_OPERATION_ERROR(e)
-	} else if c != nil {
-		if event, e := readEvents(lib.AsJson(c.Parameters)); e != nil {
-			log.Printf("Unexpected parameters are found in DiagnosisContent #%d.\n", c.Id)
-			log.Println(e.Error())
-		} else if bl, ok := event.(*C.Baseline); ok {
-			if bl.Variability != nil {
-				latestBaseline = bl
-			} else {
-				log.Printf("DiagnosisContent #%d has Baseline-XXX parameter but does not have variability.\n", c.Id)
-			}
-		} else {
-			log.Printf("DiagnosisContent #%d has Baseline-XXX parameter but does not represent baseline event.\n", c.Id)
-		}
+	latestBaseline, err := findLatestBaseline(s.DB, measurementId, items[0].RangeFrom)
+
+	if err != nil {
+		return nil, err
 	}
 
+	// 分類の文脈となる基線。
+	contextBaseline := latestBaseline
+
 	// 最後の基線BPM
 	var baselineBpm *int = nil
 	// 最大のリスク値
@@ -253,7 +374,8 @@ func (s *DiagnosisTxService) RegisterByAnnotator(
 			case *C.Baseline:
 				// リスクを算出し、現在の基線パラメータを更新。
 				if evt.Variability != nil {
-					if r := C.GetRisk(evt.Type, evt.Variability.Type, C.CTG_DecelerationNone); r >= 0 {
+					interpreted := band.Interpret(evt)
+					if r := matrices.GetRisk(interpreted.Type, interpreted.Variability.Type, C.CTG_DecelerationNone); r >= 0 {
 						risk = &r
 					}
 					latestBaseline = evt
@@ -262,7 +384,8 @@ func (s *DiagnosisTxService) RegisterByAnnotator(
 			case *C.Deceleration:
 				// リスクを算出。
 				if latestBaseline != nil {
-					if r := C.GetRisk(latestBaseline.Type, latestBaseline.Variability.Type, evt.Type); r >= 0 {
+					interpreted := band.Interpret(latestBaseline)
+					if r := matrices.GetRisk(interpreted.Type, interpreted.Variability.Type, evt.Type); r >= 0 {
 						risk = &r
 					}
 				}
@@ -317,6 +440,26 @@ func (s *DiagnosisTxService) RegisterByAnnotator(
 		}
 	}
 
+	if e := registerGestation(s.DB, diagnosis.Id, gestation); e != nil {
+		return nil, e
+	}
+
+	// ガイドライン別の分類を登録。
+	classifications, err := registerContentClassifications(s.DB, contents, classifyContents(band, contextBaseline, contents, nil, nil))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if riskTable != nil {
+		if e := s.DB.Insert(&model.DiagnosisRiskTable{
+			DiagnosisId: diagnosis.Id,
+			RiskTableId: riskTable.Id,
+		}); e != nil {
+			return nil, C.DB_OPERATION_ERROR(e)
+		}
+	}
+
 	//ad := &model.AnnotatedDiagnosis{
 	//	DiagnosisId: diagnosis.Id,
 	//	AnnotatorId: annotatorId,
@@ -326,7 +469,15 @@ func (s *DiagnosisTxService) RegisterByAnnotator(
 	//	return nil, C.DB_OPERATION_ERROR(e)
 	//}
 
-	return &model.DiagnosisEntity{diagnosis, nil, contents}, nil
+	return &model.DiagnosisEntity{
+		Diagnosis: diagnosis,
+		Algorithm: nil,
+		Contents: contents,
+		RiskTable: riskTable,
+		Classifications: classifications,
+		Timings: []*model.DecelerationTiming{},
+		Gestation: gestation,
+	}, nil
 }
 
 // 診断を更新する。
@@ -52,12 +52,12 @@ func (s *DoctorService) Authenticate(authId string, version string) (*model.Hosp
 	}
 }
 
-// 病院内の医者をID順に取得する。
-func (s *DoctorService) List(hospitalId int, limit int, offset int) ([]*model.Doctor, int64, error) {
-	if r, t, e := rds.ListDoctorsInHospital(s.DB, hospitalId, limit, offset); e != nil {
-		return nil, 0, C.DB_OPERATION_ERROR(e)
+// 病院内の医者一覧を取得する。
+func (s *DoctorService) List(hospitalId int, paging *model.Paging) ([]*model.Doctor, *model.PageInfo, error) {
+	if r, p, e := rds.ListDoctorsInHospital(s.DB, hospitalId, paging); e != nil {
+		return nil, nil, listError(e)
 	} else {
-		return r, t, nil
+		return r, p, nil
 	}
 }
 
@@ -9,11 +9,13 @@ import (
 	C "github.com/spiker/spiker-server/constant"
 	"github.com/spiker/spiker-server/model"
 	"github.com/spiker/spiker-server/resource/rds"
+	"github.com/spiker/spiker-server/resource/storage"
 )
 
 type EventService struct {
 	*Service
-	DB *gorp.DbMap
+	DB      *gorp.DbMap
+	Storage storage.Storage
 }
 
 type EventTxService struct {
@@ -67,6 +69,10 @@ func (s *EventService) FetchComputedEvent(
 			fmt.Sprintf("Computed event #%d is not found", eventId),
 			map[string]interface{}{},
 		)
+	} else if e := feedSnapshotUrls(s.DB, s.Storage, r); e != nil {
+		return nil, e
+	} else if e := newPHIKeyring(s.DB).revealComputedEvents(r); e != nil {
+		return nil, e
 	} else {
 		return r, nil
 	}
@@ -83,6 +89,8 @@ func (s *EventService) FetchAnnotatedEvent(
 			fmt.Sprintf("Annotated event #%d is not found", eventId),
 			map[string]interface{}{},
 		)
+	} else if e := newPHIKeyring(s.DB).revealAnnotatedEvents(r); e != nil {
+		return nil, e
 	} else {
 		return r, nil
 	}
@@ -96,6 +104,10 @@ func (s *EventService) ListComputedEventsInRange(
 ) ([]*model.ComputedEventEntity, error) {
 	if r, e := rds.ListComputedEventsInRange(s.DB, measurementId, begin, end, hiddenAlso); e != nil {
 		return nil, C.DB_OPERATION_ERROR(e)
+	} else if e := feedSnapshotUrls(s.DB, s.Storage, r...); e != nil {
+		return nil, e
+	} else if e := newPHIKeyring(s.DB).revealComputedEvents(r...); e != nil {
+		return nil, e
 	} else {
 		return r, nil
 	}
@@ -108,6 +120,8 @@ func (s *EventService) ListAnnotatedEventsInRange(
 ) ([]*model.AnnotatedEventEntity, error) {
 	if r, e := rds.ListAnnotatedEventsInRange(s.DB, measurementId, begin, end); e != nil {
 		return nil, C.DB_OPERATION_ERROR(e)
+	} else if e := newPHIKeyring(s.DB).revealAnnotatedEvents(r...); e != nil {
+		return nil, e
 	} else {
 		return r, nil
 	}
@@ -136,20 +150,40 @@ func (s *EventService) ListUnreadComputedEvents(
 
 	measurementFrom := now.Add(-C.AlertMeasurementInterval)
 
-	if r, e := rds.ListUnreadComputedEvents(s.DB, hospitalId, C.AlertRiskThreshold, beginTime, measurementFrom); e != nil {
+	// 病院がアラートに用いるガイドライン。
+	guideline, err := rds.FetchHospitalGuideline(s.DB, hospitalId)
+
+	if err != nil {
+		return nil, C.DB_OPERATION_ERROR(err)
+	}
+
+	if r, e := rds.ListUnreadComputedEvents(s.DB, hospitalId, guideline, guideline.AlertLevel(), beginTime, measurementFrom); e != nil {
 		return nil, C.DB_OPERATION_ERROR(e)
+	} else if e := feedSnapshotUrls(s.DB, s.Storage, r...); e != nil {
+		return nil, e
+	} else if e := newPHIKeyring(s.DB).revealComputedEvents(r...); e != nil {
+		return nil, e
 	} else {
 		return r, nil
 	}
 }
 
 // 病院に対するアラート用のイベントを古い順に取得する。
+// 各アノテーションには、病院がアラートに用いるガイドラインでアラート対象となるかがセットされる。
 func (s *EventService) ListAlertEvents(
+	hospitalId int,
 	measurements []int,
 ) ([]*model.AnnotatedEventEntity, error) {
 	now := time.Now()
 
-	if entities, e := rds.FetchLatestAnnotationsForMeasurements(s.DB, measurements, now); e != nil {
+	// 病院がアラートに用いるガイドライン。
+	guideline, err := rds.FetchHospitalGuideline(s.DB, hospitalId)
+
+	if err != nil {
+		return nil, C.DB_OPERATION_ERROR(err)
+	}
+
+	if entities, e := rds.FetchLatestAnnotationsForMeasurements(s.DB, measurements, guideline, guideline.AlertLevel(), now); e != nil {
 		return nil, C.DB_OPERATION_ERROR(e)
 	} else {
 		results := []*model.AnnotatedEventEntity{}
@@ -160,6 +194,10 @@ func (s *EventService) ListAlertEvents(
 			}
 		}
 
+		if e := newPHIKeyring(s.DB).revealAnnotatedEvents(results...); e != nil {
+			return nil, e
+		}
+
 		return results, nil
 	}
 }
@@ -209,8 +247,10 @@ func (s *EventTxService) RegisterAnnotated(
 		return nil, C.DB_OPERATION_ERROR(e)
 	}
 
-	// リスクが規定値以上ならばサイレントを解除。
-	if risk >= C.AlertRiskThreshold {
+	// 病院のガイドラインでアラート対象ならばサイレントを解除。
+	if b, e := rds.IsAlertAnnotation(s.DB, record.Id); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else if b {
 		if e := rds.StopCurrentSilent(s.DB, measurementId, now); e != nil {
 			return nil, C.DB_OPERATION_ERROR(e)
 		}
@@ -248,8 +288,10 @@ func (s *EventTxService) UpdateAnnotated(
 		return C.DB_OPERATION_ERROR(e)
 	}
 
-	// リスクが規定値以上ならばサイレントを解除。
-	if risk >= C.AlertRiskThreshold {
+	// 病院のガイドラインでアラート対象ならばサイレントを解除。
+	if b, e := rds.IsAlertAnnotation(s.DB, record.Id); e != nil {
+		return C.DB_OPERATION_ERROR(e)
+	} else if b {
 		if e := rds.StopCurrentSilent(s.DB, record.MeasurementId, now); e != nil {
 			return C.DB_OPERATION_ERROR(e)
 		}
@@ -0,0 +1,415 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"time"
+
+	"gopkg.in/gorp.v2"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/lib/edf"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/resource/influxdb"
+	"github.com/spiker/spiker-server/resource/rds"
+)
+
+type ExportService struct {
+	*Service
+	DB     *gorp.DbMap
+	Influx lib.InfluxDBClient
+}
+
+// 出力されたファイル。
+type ExportedFile struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
+// 出力するチャネル。EDF+の信号とCSVの列は、この順に並ぶ。
+type exportChannel struct {
+	Label      string
+	Transducer string
+	Unit       string
+	Minimum    int
+	Maximum    int
+}
+
+var exportChannels = []exportChannel{
+	{"FHR1", "Ultrasound", "bpm", 0, 300},
+	{"FHR2", "Ultrasound", "bpm", 0, 300},
+	{"UC", "TOCO", "a.u.", 0, 127},
+}
+
+// 出力する計測の内容。チャネル毎の値は出力期間の先頭からの秒毎に並び、値の無い秒はnil。
+type exportedMeasurement struct {
+	entity    *model.MeasurementEntity
+	begin     time.Time
+	end       time.Time
+	channels  [][]*int
+	computed  []*model.ComputedEventEntity
+	annotated []*model.AnnotatedEventEntity
+}
+
+// 計測の指定期間の心拍(FHR1、FHR2)とTOCOを、指定形式で出力する。
+//
+// EDF+形式では、計測毎に自動診断イベントとアノテーションを注釈として含むファイルを作成し、複数の計測はzipにまとめる。
+// CSV形式では、単位を含むヘッダ行に続いて、全計測の値を計測、日時の順に1秒1行で並べる。
+// beginを省略した場合は各計測の最初のデータ日時、endを省略した場合は最後のデータ日時までとする。日時はlocationのタイムゾーンで記載する。
+func (s *ExportService) Export(
+	format C.ExportFormat,
+	measurementIds []int,
+	begin *time.Time,
+	end *time.Time,
+	location *time.Location,
+) (*ExportedFile, error) {
+	measurements := []*exportedMeasurement{}
+
+	for _, id := range measurementIds {
+		if m, e := s.collect(id, begin, end); e != nil {
+			return nil, e
+		} else {
+			measurements = append(measurements, m)
+		}
+	}
+
+	switch format {
+	case C.ExportFormatEDF:
+		if len(measurements) == 1 {
+			m := measurements[0]
+
+			if content, e := renderEDF(m, location); e != nil {
+				return nil, e
+			} else {
+				return &ExportedFile{fmt.Sprintf("ctg-%d.edf", m.entity.Id), "application/octet-stream", content}, nil
+			}
+		}
+
+		buf := &bytes.Buffer{}
+		w := zip.NewWriter(buf)
+
+		for _, m := range measurements {
+			content, err := renderEDF(m, location)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if f, e := w.Create(fmt.Sprintf("ctg-%d.edf", m.entity.Id)); e != nil {
+				return nil, e
+			} else if _, e := f.Write(content); e != nil {
+				return nil, e
+			}
+		}
+
+		if e := w.Close(); e != nil {
+			return nil, e
+		}
+
+		return &ExportedFile{"ctg.zip", "application/zip", buf.Bytes()}, nil
+	case C.ExportFormatCSV:
+		if content, e := renderCSV(measurements, location); e != nil {
+			return nil, e
+		} else {
+			return &ExportedFile{"ctg.csv", "text/csv", content}, nil
+		}
+	default:
+		return nil, C.NewBadRequestError(
+			"invalid_format",
+			fmt.Sprintf("Export format '%s' is not supported", format),
+			map[string]interface{}{},
+		)
+	}
+}
+
+// 出力する内容を収集する。
+func (s *ExportService) collect(
+	measurementId int,
+	begin *time.Time,
+	end *time.Time,
+) (*exportedMeasurement, error) {
+	var entity *model.MeasurementEntity
+
+	if r, e := rds.FetchMeasurment(s.DB, measurementId); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else if r == nil {
+		return nil, C.NewNotFoundError(
+			"measurement_not_found",
+			fmt.Sprintf("Measurement %d is not found", measurementId),
+			map[string]interface{}{},
+		)
+	} else if e := newPHIKeyring(s.DB).revealEntities(r); e != nil {
+		return nil, e
+	} else {
+		entity = r
+	}
+
+	if begin == nil {
+		begin = entity.FirstTime
+	}
+
+	if end == nil {
+		_, end = measurementDataRange(entity.Measurement)
+	}
+
+	if begin == nil || end == nil {
+		return nil, C.NewBadRequestError(
+			"no_data",
+			fmt.Sprintf("Measurement %d has no data", measurementId),
+			map[string]interface{}{},
+		)
+	}
+
+	if !begin.Before(*end) {
+		return nil, C.NewBadRequestError(
+			"invalid_range",
+			"Export range must not be empty",
+			map[string]interface{}{},
+		)
+	} else if end.Sub(*begin) > C.ExportMaximumDuration {
+		return nil, C.NewBadRequestError(
+			"range_too_long",
+			fmt.Sprintf("Export range must be within %s", C.ExportMaximumDuration),
+			map[string]interface{}{},
+		)
+	}
+
+	// 秒単位で記録するため、先頭を秒に切り捨てる。
+	from := begin.Truncate(time.Second)
+
+	channels, err := s.collectChannels(measurementId, from, *end)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := &exportedMeasurement{
+		entity: entity,
+		begin: from,
+		end: *end,
+		channels: channels,
+	}
+
+	if events, e := rds.ListComputedEventsInRange(s.DB, measurementId, *begin, *end, false); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else {
+		result.computed = events
+	}
+
+	if events, e := rds.ListAnnotatedEventsInRange(s.DB, measurementId, *begin, *end); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else {
+		result.annotated = events
+	}
+
+	return result, nil
+}
+
+// 計測のfromからendまでの各チャネルの値を、fromからの秒毎に取得する。値の無い秒はnilとなる。
+func (s *ExportService) collectChannels(
+	measurementId int,
+	from time.Time,
+	end time.Time,
+) ([][]*int, error) {
+	count := int(end.Sub(from) / time.Second)
+
+	if end.Sub(from) % time.Second > 0 {
+		count++
+	}
+
+	channels := [][]*int{}
+
+	for range exportChannels {
+		channels = append(channels, make([]*int, count))
+	}
+
+	put := func(channel int, value int, timestamp time.Time) {
+		index := int(timestamp.Sub(from) / time.Second)
+
+		if index >= 0 && index < count {
+			v := value
+			channels[channel][index] = &v
+		}
+	}
+
+	if values, e := influxdb.ListHeartRate(s.Influx, measurementId, from, end); e != nil {
+		return nil, C.INFLUXDB_OPERATION_ERROR(e)
+	} else {
+		for _, v := range values {
+			put(0, v.Value, v.Timestamp)
+		}
+	}
+
+	if values, e := influxdb.ListSecondHeartRate(s.Influx, measurementId, from, end); e != nil {
+		return nil, C.INFLUXDB_OPERATION_ERROR(e)
+	} else {
+		for _, v := range values {
+			put(1, v.Value, v.Timestamp)
+		}
+	}
+
+	if values, e := influxdb.ListTOCO(s.Influx, measurementId, from, end); e != nil {
+		return nil, C.INFLUXDB_OPERATION_ERROR(e)
+	} else {
+		for _, v := range values {
+			put(2, v.Value, v.Timestamp)
+		}
+	}
+
+	return channels, nil
+}
+
+// イベントの期間を出力期間内に収めた注釈を作成する。
+func exportAnnotation(m *exportedMeasurement, rangeFrom time.Time, rangeUntil time.Time, text string) *edf.Annotation {
+	if rangeFrom.Before(m.begin) {
+		rangeFrom = m.begin
+	}
+	if rangeUntil.After(m.end) {
+		rangeUntil = m.end
+	}
+
+	duration := rangeUntil.Sub(rangeFrom)
+
+	if duration < 0 {
+		duration = 0
+	}
+
+	return &edf.Annotation{Onset: rangeFrom.Sub(m.begin), Duration: duration, Text: text}
+}
+
+// 計測をEDF+形式で出力する。EDFの信号は欠損を表せないため、値の無い秒は0とし、
+// 受信値の0と区別できるよう、チャネル毎に値の無い区間を"Gap <チャネル名>"の注釈として含める。
+func renderEDF(m *exportedMeasurement, location *time.Location) ([]byte, error) {
+	recording := &edf.Recording{
+		PatientCode: m.entity.Code,
+		Equipment: m.entity.Terminal.Code,
+		Start: m.begin.In(location),
+		Signals: []*edf.Signal{},
+		Annotations: []*edf.Annotation{},
+	}
+
+	for i, ch := range exportChannels {
+		samples := make([]int, len(m.channels[i]))
+
+		for j, v := range m.channels[i] {
+			if v != nil {
+				samples[j] = *v
+			}
+		}
+
+		recording.Signals = append(recording.Signals, &edf.Signal{
+			Label: ch.Label,
+			Transducer: ch.Transducer,
+			Dimension: ch.Unit,
+			PhysicalMinimum: ch.Minimum,
+			PhysicalMaximum: ch.Maximum,
+			Samples: samples,
+		})
+	}
+
+	for i, ch := range exportChannels {
+		for _, gap := range channelGaps(m.channels[i]) {
+			recording.Annotations = append(recording.Annotations, &edf.Annotation{
+				Onset: time.Duration(gap[0]) * time.Second,
+				Duration: time.Duration(gap[1] - gap[0]) * time.Second,
+				Text: fmt.Sprintf("Gap %s", ch.Label),
+			})
+		}
+	}
+
+	for _, ev := range m.computed {
+		recording.Annotations = append(recording.Annotations, exportAnnotation(
+			m, ev.RangeFrom, ev.RangeUntil, fmt.Sprintf("Computed #%d %s", ev.Id, riskLabel(ev.Risk)),
+		))
+	}
+
+	for _, ev := range m.annotated {
+		text := fmt.Sprintf("Annotated #%d %s", ev.Id, riskLabel(ev.Risk))
+
+		if ev.Memo != "" {
+			text += " " + ev.Memo
+		}
+
+		recording.Annotations = append(recording.Annotations, exportAnnotation(m, ev.RangeFrom, ev.RangeUntil, text))
+	}
+
+	return recording.Bytes()
+}
+
+// 値の無い秒が連続する区間を、先頭からの秒の左閉右開の組で返す。
+func channelGaps(values []*int) [][2]int {
+	gaps := [][2]int{}
+	start := -1
+
+	for i, v := range values {
+		if v == nil && start < 0 {
+			start = i
+		} else if v != nil && start >= 0 {
+			gaps = append(gaps, [2]int{start, i})
+			start = -1
+		}
+	}
+
+	if start >= 0 {
+		gaps = append(gaps, [2]int{start, len(values)})
+	}
+
+	return gaps
+}
+
+// 計測をCSV形式で出力する。いずれのチャネルにも値の無い秒は出力せず、値の無いチャネルは空欄とする。
+func renderCSV(measurements []*exportedMeasurement, location *time.Location) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+
+	header := []string{"measurement_id", "measurement_code", "time"}
+
+	for _, ch := range exportChannels {
+		header = append(header, fmt.Sprintf("%s [%s]", ch.Label, ch.Unit))
+	}
+
+	if e := w.Write(header); e != nil {
+		return nil, e
+	}
+
+	for _, m := range measurements {
+		for i := range m.channels[0] {
+			row := []string{
+				fmt.Sprintf("%d", m.entity.Id),
+				m.entity.Code,
+				m.begin.Add(time.Duration(i) * time.Second).In(location).Format(time.RFC3339),
+			}
+
+			present := false
+
+			for _, values := range m.channels {
+				if v := values[i]; v != nil {
+					row = append(row, fmt.Sprintf("%d", *v))
+					present = true
+				} else {
+					row = append(row, "")
+				}
+			}
+
+			if !present {
+				continue
+			}
+
+			if e := w.Write(row); e != nil {
+				return nil, e
+			}
+		}
+	}
+
+	w.Flush()
+
+	if e := w.Error(); e != nil {
+		return nil, e
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spiker/spiker-server/model"
+)
+
+func exportFixture() *exportedMeasurement {
+	begin := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	value := func(v int) *int {
+		return &v
+	}
+
+	risk := 4
+
+	return &exportedMeasurement{
+		entity: &model.MeasurementEntity{
+			Measurement: &model.Measurement{Id: 3, Code: "m-0003"},
+			Terminal: &model.MeasurementTerminal{Code: "t-0001"},
+			Patient: &model.Patient{},
+		},
+		begin: begin,
+		end: begin.Add(time.Duration(3) * time.Second),
+		channels: [][]*int{
+			{value(140), nil, value(142)},
+			{nil, nil, value(130)},
+			{value(10), nil, value(12)},
+		},
+		computed: []*model.ComputedEventEntity{
+			&model.ComputedEventEntity{ComputedEvent: &model.ComputedEvent{
+				Id: 7, Risk: &risk, RangeFrom: begin.Add(-time.Minute), RangeUntil: begin.Add(time.Second),
+			}},
+		},
+		annotated: []*model.AnnotatedEventEntity{},
+	}
+}
+
+func TestServiceExport_renderCSV(t *testing.T) {
+	bs, err := renderCSV([]*exportedMeasurement{exportFixture()}, time.UTC)
+
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(bs)), "\n")
+
+	assert.Equal(t, []string{
+		"measurement_id,measurement_code,time,FHR1 [bpm],FHR2 [bpm],UC [a.u.]",
+		// 値の無い秒は出力しない。
+		"3,m-0003,2024-01-01T00:00:00Z,140,,10",
+		"3,m-0003,2024-01-01T00:00:02Z,142,130,12",
+	}, lines)
+}
+
+func TestServiceExport_renderEDF(t *testing.T) {
+	bs, err := renderEDF(exportFixture(), time.UTC)
+
+	assert.NoError(t, err)
+
+	// 3信号と注釈、3秒分のレコード。
+	assert.Equal(t, "EDF+C", strings.TrimSpace(string(bs[192:236])))
+	assert.Equal(t, "3", strings.TrimSpace(string(bs[236:244])))
+	assert.Equal(t, "4", strings.TrimSpace(string(bs[252:256])))
+
+	// 計測開始前から続くイベントは先頭からの注釈となる。
+	assert.Contains(t, string(bs), "+0\x151\x14Computed #7 R4\x14")
+
+	// 値の無い区間はチャネル毎の注釈となる。
+	assert.Contains(t, string(bs), "+1\x151\x14Gap FHR1\x14")
+	assert.Contains(t, string(bs), "+0\x152\x14Gap FHR2\x14")
+	assert.Contains(t, string(bs), "+1\x151\x14Gap UC\x14")
+}
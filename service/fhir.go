@@ -0,0 +1,621 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/gorp.v2"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/lib/fhir"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/resource/rds"
+)
+
+type FHIRService struct {
+	*Service
+	DB     *gorp.DbMap
+	Influx lib.InfluxDBClient
+}
+
+// FHIRの検索条件。nilの条件は絞り込まない。
+type FHIRSearch struct {
+	// 病院UUID。ログイン中の医師の病院と異なる場合、検索結果は空となる。
+	HospitalUuid  *string
+	PatientId     *int
+	MeasurementId *int
+	Begin         *time.Time
+	End           *time.Time
+	Count         int
+	Offset        int
+}
+
+const (
+	fhirSystemChannel   string = "urn:spiker:ctg-channel"
+	fhirSystemAlgorithm string = "urn:spiker:diagnosis-algorithm"
+)
+
+// 検索条件の病院が対象の病院と一致するか。
+func (search *FHIRSearch) matchHospital(hospital *model.Hospital) bool {
+	return search.HospitalUuid == nil || *search.HospitalUuid == hospital.Uuid
+}
+
+func fhirOrganization(hospital *model.Hospital) *fhir.Reference {
+	return fhir.NewReference("Organization", hospital.Uuid, hospital.Name)
+}
+
+func fhirPeriod(from *time.Time, until *time.Time) *fhir.Period {
+	return &fhir.Period{Start: from, End: until}
+}
+
+func fhirMeta(modifiedAt time.Time) *fhir.Meta {
+	return &fhir.Meta{LastUpdated: &modifiedAt}
+}
+
+func fhirRisk(risk *int) []*fhir.CodeableConcept {
+	if risk == nil {
+		return nil
+	}
+	return []*fhir.CodeableConcept{
+		{Coding: []*fhir.Coding{{System: fhir.SystemRisk, Code: strconv.Itoa(*risk), Display: riskLabel(risk)}}},
+	}
+}
+
+// 患者をFHIRのPatientに変換する。
+func fhirPatient(patient *model.Patient, hospital *model.Hospital) *fhir.Patient {
+	resource := &fhir.Patient{
+		ResourceType: "Patient",
+		Id: strconv.Itoa(patient.Id),
+		Meta: fhirMeta(patient.ModifiedAt),
+		Gender: "female",
+		ManagingOrganization: fhirOrganization(hospital),
+	}
+
+	if patient.Name != nil && *patient.Name != "" {
+		resource.Name = []*fhir.HumanName{{Text: *patient.Name}}
+	}
+
+	return resource
+}
+
+// 計測端末をFHIRのDeviceに変換する。
+func fhirDevice(terminal *model.MeasurementTerminal, hospital *model.Hospital) *fhir.Device {
+	resource := &fhir.Device{
+		ResourceType: "Device",
+		Id: strconv.Itoa(terminal.Id),
+		Meta: fhirMeta(terminal.ModifiedAt),
+		Identifier: []*fhir.Identifier{{System: fhir.SystemTerminalCode, Value: terminal.Code}},
+		Owner: fhirOrganization(hospital),
+	}
+
+	if terminal.Name != "" {
+		resource.DeviceName = []*fhir.DeviceName{{Name: terminal.Name, Type: "user-friendly-name"}}
+	}
+
+	if terminal.Memo != "" {
+		resource.Note = []*fhir.Annotation{{Text: terminal.Memo}}
+	}
+
+	return resource
+}
+
+// 計測をFHIRのEncounterに変換する。計測端末は拡張として参照する。
+func fhirEncounter(entity *model.MeasurementEntity, hospital *model.Hospital) *fhir.Encounter {
+	status := "in-progress"
+
+	if entity.IsClosed {
+		status = "finished"
+	}
+
+	return &fhir.Encounter{
+		ResourceType: "Encounter",
+		Id: strconv.Itoa(entity.Id),
+		Meta: fhirMeta(entity.ModifiedAt),
+		Identifier: []*fhir.Identifier{{System: fhir.SystemMeasurementCode, Value: entity.Code}},
+		Status: status,
+		Class: &fhir.Coding{System: fhir.SystemActCode, Code: "IMP", Display: "inpatient encounter"},
+		Subject: fhir.NewReference("Patient", entity.PatientId, ""),
+		Period: fhirPeriod(entity.FirstTime, entity.LastTime),
+		ServiceProvider: fhirOrganization(hospital),
+		Extension: []*fhir.Extension{
+			{Url: fhir.ExtensionEncounterDevice, ValueReference: fhir.NewReference("Device", entity.TerminalId, entity.Terminal.Code)},
+		},
+	}
+}
+
+// 診断をFHIRのDiagnosticReportに変換する。
+func fhirDiagnosticReport(entity *model.DiagnosisEntity, measurement *model.Measurement) *fhir.DiagnosticReport {
+	code := &fhir.CodeableConcept{Text: "CTG assessment"}
+
+	if entity.Algorithm != nil && entity.Algorithm.Id != 0 {
+		code.Coding = []*fhir.Coding{{System: fhirSystemAlgorithm, Code: entity.Algorithm.Name}}
+	}
+
+	createdAt := entity.CreatedAt
+
+	return &fhir.DiagnosticReport{
+		ResourceType: "DiagnosticReport",
+		Id: strconv.Itoa(entity.Id),
+		Meta: fhirMeta(entity.ModifiedAt),
+		Status: "final",
+		Code: code,
+		Subject: fhir.NewReference("Patient", measurement.PatientId, ""),
+		Encounter: fhir.NewReference("Encounter", measurement.Id, measurement.Code),
+		EffectivePeriod: fhirPeriod(&entity.RangeFrom, &entity.RangeUntil),
+		Issued: &createdAt,
+		Conclusion: entity.Memo,
+		ConclusionCode: fhirRisk(entity.MaximumRisk),
+	}
+}
+
+// 自動診断イベントをFHIRのRiskAssessmentに変換する。
+func fhirRiskAssessment(entity *model.ComputedEventEntity) *fhir.RiskAssessment {
+	resource := &fhir.RiskAssessment{
+		ResourceType: "RiskAssessment",
+		Id: strconv.Itoa(entity.Id),
+		Meta: fhirMeta(entity.ModifiedAt),
+		Status: "final",
+		Subject: fhir.NewReference("Patient", entity.Measurement.PatientId, ""),
+		Encounter: fhir.NewReference("Encounter", entity.Measurement.Id, entity.Measurement.Code),
+		OccurrencePeriod: fhirPeriod(&entity.RangeFrom, &entity.RangeUntil),
+	}
+
+	if risks := fhirRisk(entity.Risk); risks != nil {
+		resource.Prediction = []*fhir.Prediction{{QualitativeRisk: risks[0]}}
+	}
+
+	if entity.Memo != "" {
+		resource.Note = []*fhir.Annotation{{Text: entity.Memo}}
+	}
+
+	return resource
+}
+
+// 計測のチャネル毎の値をFHIRのObservationに変換する。値は1秒毎のSampledDataとし、値の無い秒はEとする。
+func fhirObservations(m *exportedMeasurement, channels []int) []interface{} {
+	status := "preliminary"
+
+	if m.entity.IsClosed {
+		status = "final"
+	}
+
+	results := []interface{}{}
+
+	for _, i := range channels {
+		ch := exportChannels[i]
+
+		data := make([]string, len(m.channels[i]))
+
+		for j, v := range m.channels[i] {
+			if v != nil {
+				data[j] = strconv.Itoa(*v)
+			} else {
+				data[j] = "E"
+			}
+		}
+
+		lower := float64(ch.Minimum)
+		upper := float64(ch.Maximum)
+
+		unit := &fhir.Quantity{Value: 0, Unit: ch.Unit, System: fhir.SystemUCUM, Code: "/min"}
+
+		if ch.Unit != "bpm" {
+			unit.Code = "[arb'U]"
+		}
+
+		begin := m.begin
+		end := m.end
+
+		results = append(results, &fhir.Observation{
+			ResourceType: "Observation",
+			Id: fmt.Sprintf("%d-%s", m.entity.Id, strings.ToLower(ch.Label)),
+			Status: status,
+			Category: []*fhir.CodeableConcept{
+				{Coding: []*fhir.Coding{{System: fhir.SystemObservationCategory, Code: "procedure"}}},
+			},
+			Code: &fhir.CodeableConcept{
+				Coding: []*fhir.Coding{{System: fhirSystemChannel, Code: strings.ToLower(ch.Label), Display: ch.Label}},
+				Text: ch.Label,
+			},
+			Subject: fhir.NewReference("Patient", m.entity.PatientId, ""),
+			Encounter: fhir.NewReference("Encounter", m.entity.Id, m.entity.Code),
+			EffectivePeriod: fhirPeriod(&begin, &end),
+			Device: fhir.NewReference("Device", m.entity.TerminalId, m.entity.Terminal.Code),
+			ValueSampledData: &fhir.SampledData{
+				Origin: unit,
+				Period: 1000,
+				LowerLimit: &lower,
+				UpperLimit: &upper,
+				Dimensions: 1,
+				Data: strings.Join(data, " "),
+			},
+		})
+	}
+
+	return results
+}
+
+// Observationのコードからチャネルの位置を取得する。
+func fhirChannel(code string) (int, bool) {
+	for i, ch := range exportChannels {
+		if strings.ToLower(ch.Label) == code {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func fhirNotFound(resourceType string, id interface{}) error {
+	return C.NewNotFoundError(
+		"resource_not_found",
+		fmt.Sprintf("%s %v is not found", resourceType, id),
+		map[string]interface{}{},
+	)
+}
+
+// 病院内の患者をFHIRのPatientとして取得する。
+func (s *FHIRService) FetchPatient(hospital *model.Hospital, id int) (*fhir.Patient, error) {
+	if r, e := rds.FetchPatient(s.DB, id); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else if r == nil || r.HospitalId != hospital.Id {
+		return nil, fhirNotFound("Patient", id)
+	} else if e := newPHIKeyring(s.DB).revealPatients(r); e != nil {
+		return nil, e
+	} else {
+		return fhirPatient(r, hospital), nil
+	}
+}
+
+// 病院内の患者を検索する。期間はその期間に計測のある患者に絞り込む。
+func (s *FHIRService) SearchPatients(hospital *model.Hospital, search *FHIRSearch) (*fhir.Bundle, error) {
+	if !search.matchHospital(hospital) {
+		return fhir.NewSearchset(0), nil
+	}
+
+	if search.PatientId != nil {
+		if r, e := rds.FetchPatient(s.DB, *search.PatientId); e != nil {
+			return nil, C.DB_OPERATION_ERROR(e)
+		} else if r == nil || r.HospitalId != hospital.Id {
+			return fhir.NewSearchset(0), nil
+		} else if e := newPHIKeyring(s.DB).revealPatients(r); e != nil {
+			return nil, e
+		} else {
+			return fhir.NewSearchset(1, fhirPatient(r, hospital)), nil
+		}
+	}
+
+	records, info, err := rds.ListPatients(s.DB, hospital.Id, search.Begin, search.End, &model.Paging{Limit: search.Count, Offset: search.Offset})
+
+	if err != nil {
+		return nil, C.DB_OPERATION_ERROR(err)
+	} else if e := newPHIKeyring(s.DB).revealPatients(records...); e != nil {
+		return nil, e
+	}
+
+	resources := []interface{}{}
+
+	for _, r := range records {
+		resources = append(resources, fhirPatient(r, hospital))
+	}
+
+	return fhir.NewSearchset(info.Total, resources...), nil
+}
+
+// 病院内の計測端末をFHIRのDeviceとして取得する。
+func (s *FHIRService) FetchDevice(hospital *model.Hospital, id int) (*fhir.Device, error) {
+	if r, e := rds.FetchTerminal(s.DB, id); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else if r == nil || r.HospitalId != hospital.Id {
+		return nil, fhirNotFound("Device", id)
+	} else {
+		return fhirDevice(r, hospital), nil
+	}
+}
+
+// 病院内の計測端末を検索する。
+func (s *FHIRService) SearchDevices(hospital *model.Hospital, search *FHIRSearch) (*fhir.Bundle, error) {
+	if !search.matchHospital(hospital) {
+		return fhir.NewSearchset(0), nil
+	}
+
+	records, info, err := rds.ListTerminals(s.DB, hospital.Id, &model.Paging{Limit: search.Count, Offset: search.Offset})
+
+	if err != nil {
+		return nil, C.DB_OPERATION_ERROR(err)
+	}
+
+	resources := []interface{}{}
+
+	for _, r := range records {
+		resources = append(resources, fhirDevice(r, hospital))
+	}
+
+	return fhir.NewSearchset(info.Total, resources...), nil
+}
+
+// 病院内の計測を取得する。存在しない場合や病院が異なる場合はnilを返す。
+func (s *FHIRService) inquireMeasurement(hospital *model.Hospital, id int) (*model.MeasurementEntity, error) {
+	if r, e := rds.FetchMeasurment(s.DB, id); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else if r == nil || r.Patient.HospitalId != hospital.Id {
+		return nil, nil
+	} else if e := newPHIKeyring(s.DB).revealEntities(r); e != nil {
+		return nil, e
+	} else {
+		return r, nil
+	}
+}
+
+// 病院内の計測をFHIRのEncounterとして取得する。
+func (s *FHIRService) FetchEncounter(hospital *model.Hospital, id int) (*fhir.Encounter, error) {
+	if r, e := s.inquireMeasurement(hospital, id); e != nil {
+		return nil, e
+	} else if r == nil {
+		return nil, fhirNotFound("Encounter", id)
+	} else {
+		return fhirEncounter(r, hospital), nil
+	}
+}
+
+// 病院内の計測を検索する。期間は計測のデータの期間が重なるものとする。
+func (s *FHIRService) SearchEncounters(hospital *model.Hospital, search *FHIRSearch) (*fhir.Bundle, error) {
+	if !search.matchHospital(hospital) {
+		return fhir.NewSearchset(0), nil
+	}
+
+	records, total, err := rds.SearchMeasurements(s.DB, hospital.Id, search.PatientId, search.Begin, search.End, false, search.Count, search.Offset)
+
+	if err != nil {
+		return nil, C.DB_OPERATION_ERROR(err)
+	} else if e := newPHIKeyring(s.DB).revealEntities(records...); e != nil {
+		return nil, e
+	}
+
+	resources := []interface{}{}
+
+	for _, r := range records {
+		resources = append(resources, fhirEncounter(r, hospital))
+	}
+
+	return fhir.NewSearchset(total, resources...), nil
+}
+
+// 計測の値を収集する。期間は計測のデータの期間に収め、重ならない場合はnilを返す。
+// 期間がエクスポートの上限を超える場合は、末尾から上限までの期間とする。
+func (s *FHIRService) collectObservation(
+	entity *model.MeasurementEntity,
+	begin *time.Time,
+	end *time.Time,
+) (*exportedMeasurement, error) {
+	first, last := measurementDataRange(entity.Measurement)
+
+	if first == nil || last == nil {
+		return nil, nil
+	}
+
+	if begin == nil || begin.Before(*first) {
+		begin = first
+	}
+
+	if end == nil || end.After(*last) {
+		end = last
+	}
+
+	if !begin.Before(*end) {
+		return nil, nil
+	}
+
+	if limit := end.Add(-C.ExportMaximumDuration); begin.Before(limit) {
+		begin = &limit
+	}
+
+	return (&ExportService{s.Service, s.DB, s.Influx}).collect(entity.Id, begin, end)
+}
+
+// 病院内の計測のチャネルをFHIRのObservationとして取得する。IDは"計測ID-チャネル"の形式とする。
+func (s *FHIRService) FetchObservation(hospital *model.Hospital, id string) (*fhir.Observation, error) {
+	var measurementId, channel int
+
+	if i := strings.Index(id, "-"); i < 0 {
+		return nil, fhirNotFound("Observation", id)
+	} else if mid, e := strconv.Atoi(id[:i]); e != nil {
+		return nil, fhirNotFound("Observation", id)
+	} else if ch, ok := fhirChannel(id[i+1:]); !ok {
+		return nil, fhirNotFound("Observation", id)
+	} else {
+		measurementId, channel = mid, ch
+	}
+
+	entity, err := s.inquireMeasurement(hospital, measurementId)
+
+	if err != nil {
+		return nil, err
+	} else if entity == nil {
+		return nil, fhirNotFound("Observation", id)
+	}
+
+	if m, e := s.collectObservation(entity, nil, nil); e != nil {
+		return nil, e
+	} else if m == nil {
+		return nil, fhirNotFound("Observation", id)
+	} else {
+		return fhirObservations(m, []int{channel})[0].(*fhir.Observation), nil
+	}
+}
+
+// 病院内の計測のチャネルを検索する。
+//
+// 件数とオフセットは計測に対して適用し、計測毎に指定されたチャネルのObservationを返す。codesが空の場合は全チャネルとする。
+// Observationは期間中の全ての値を含むため、件数はFHIRObservationMaximumCountを上限とする。
+// 期間は計測のデータの期間に収め、データの無い計測は含めず、総数にも数えない。
+func (s *FHIRService) SearchObservations(
+	hospital *model.Hospital,
+	search *FHIRSearch,
+	codes []string,
+) (*fhir.Bundle, error) {
+	if !search.matchHospital(hospital) {
+		return fhir.NewSearchset(0), nil
+	}
+
+	count := search.Count
+
+	if count > C.FHIRObservationMaximumCount {
+		count = C.FHIRObservationMaximumCount
+	}
+
+	channels := []int{}
+
+	for _, code := range codes {
+		if ch, ok := fhirChannel(code); ok {
+			channels = append(channels, ch)
+		}
+	}
+
+	if len(codes) == 0 {
+		for i := range exportChannels {
+			channels = append(channels, i)
+		}
+	} else if len(channels) == 0 {
+		return fhir.NewSearchset(0), nil
+	}
+
+	var measurements []*model.MeasurementEntity
+	var total int64
+
+	if search.MeasurementId != nil {
+		if r, e := s.inquireMeasurement(hospital, *search.MeasurementId); e != nil {
+			return nil, e
+		} else if r == nil || (search.PatientId != nil && r.PatientId != *search.PatientId) {
+			return fhir.NewSearchset(0), nil
+		} else {
+			measurements = []*model.MeasurementEntity{r}
+		}
+	} else if rs, t, e := rds.SearchMeasurements(s.DB, hospital.Id, search.PatientId, search.Begin, search.End, true, count, search.Offset); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else if e := newPHIKeyring(s.DB).revealEntities(rs...); e != nil {
+		return nil, e
+	} else {
+		measurements, total = rs, t
+	}
+
+	resources := []interface{}{}
+
+	for _, entity := range measurements {
+		if m, e := s.collectObservation(entity, search.Begin, search.End); e != nil {
+			return nil, e
+		} else if m != nil {
+			resources = append(resources, fhirObservations(m, channels)...)
+
+			if search.MeasurementId != nil {
+				total = 1
+			}
+		}
+	}
+
+	return fhir.NewSearchset(total * int64(len(channels)), resources...), nil
+}
+
+// 病院内の診断をFHIRのDiagnosticReportとして取得する。
+func (s *FHIRService) FetchDiagnosticReport(hospital *model.Hospital, id int) (*fhir.DiagnosticReport, error) {
+	var entity *model.DiagnosisEntity
+
+	if r, e := rds.FetchDiagnosis(s.DB, id); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else if r == nil {
+		return nil, fhirNotFound("DiagnosticReport", id)
+	} else {
+		entity = r
+	}
+
+	if r, e := s.inquireMeasurement(hospital, entity.MeasurementId); e != nil {
+		return nil, e
+	} else if r == nil {
+		return nil, fhirNotFound("DiagnosticReport", id)
+	} else {
+		return fhirDiagnosticReport(entity, r.Measurement), nil
+	}
+}
+
+// 病院内の診断を検索する。期間は診断の期間が重なるものとする。
+func (s *FHIRService) SearchDiagnosticReports(hospital *model.Hospital, search *FHIRSearch) (*fhir.Bundle, error) {
+	if !search.matchHospital(hospital) {
+		return fhir.NewSearchset(0), nil
+	}
+
+	records, total, err := rds.SearchDiagnoses(
+		s.DB, hospital.Id, search.PatientId, search.MeasurementId, search.Begin, search.End, search.Count, search.Offset,
+	)
+
+	if err != nil {
+		return nil, C.DB_OPERATION_ERROR(err)
+	}
+
+	ids := []int{}
+
+	for _, r := range records {
+		ids = append(ids, r.MeasurementId)
+	}
+
+	measurementMap := map[int]*model.Measurement{}
+
+	if ms, e := rds.InquireMeasurementsByIds(s.DB, ids); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else {
+		for _, m := range ms {
+			measurementMap[m.Id] = m
+		}
+	}
+
+	resources := []interface{}{}
+
+	for _, r := range records {
+		resources = append(resources, fhirDiagnosticReport(r, measurementMap[r.MeasurementId]))
+	}
+
+	return fhir.NewSearchset(total, resources...), nil
+}
+
+// 病院内の非表示でない自動診断イベントをFHIRのRiskAssessmentとして取得する。
+func (s *FHIRService) FetchRiskAssessment(hospital *model.Hospital, id int) (*fhir.RiskAssessment, error) {
+	if r, e := rds.FetchComputedEvent(s.DB, id); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else if r == nil || r.IsHidden {
+		return nil, fhirNotFound("RiskAssessment", id)
+	} else if ok, e := rds.CheckMeasurementAccessByHospital(s.DB, hospital.Id, r.MeasurementId); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else if !ok {
+		return nil, fhirNotFound("RiskAssessment", id)
+	} else if e := newPHIKeyring(s.DB).revealComputedEvents(r); e != nil {
+		return nil, e
+	} else {
+		return fhirRiskAssessment(r), nil
+	}
+}
+
+// 病院内の非表示でない自動診断イベントを検索する。期間はイベントの期間が重なるものとする。
+func (s *FHIRService) SearchRiskAssessments(hospital *model.Hospital, search *FHIRSearch) (*fhir.Bundle, error) {
+	if !search.matchHospital(hospital) {
+		return fhir.NewSearchset(0), nil
+	}
+
+	records, total, err := rds.SearchComputedEvents(
+		s.DB, hospital.Id, search.PatientId, search.MeasurementId, search.Begin, search.End, search.Count, search.Offset,
+	)
+
+	if err != nil {
+		return nil, C.DB_OPERATION_ERROR(err)
+	} else if e := newPHIKeyring(s.DB).revealComputedEvents(records...); e != nil {
+		return nil, e
+	}
+
+	resources := []interface{}{}
+
+	for _, r := range records {
+		resources = append(resources, fhirRiskAssessment(r))
+	}
+
+	return fhir.NewSearchset(total, resources...), nil
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spiker/spiker-server/lib/fhir"
+	"github.com/spiker/spiker-server/model"
+)
+
+func TestServiceFHIR_fhirObservations(t *testing.T) {
+	m := exportFixture()
+	m.entity.Measurement.PatientId = 2
+	m.entity.Measurement.TerminalId = 5
+
+	resources := fhirObservations(m, []int{0, 2})
+
+	assert.Equal(t, 2, len(resources))
+
+	fhr := resources[0].(*fhir.Observation)
+
+	assert.Equal(t, "3-fhr1", fhr.Id)
+	assert.Equal(t, "preliminary", fhr.Status)
+	assert.Equal(t, "Patient/2", fhr.Subject.Reference)
+	assert.Equal(t, "Encounter/3", fhr.Encounter.Reference)
+	assert.Equal(t, "Device/5", fhr.Device.Reference)
+	assert.Equal(t, "/min", fhr.ValueSampledData.Origin.Code)
+	assert.EqualValues(t, 1000, fhr.ValueSampledData.Period)
+	// 値の無い秒はEとなる。
+	assert.Equal(t, "140 E 142", fhr.ValueSampledData.Data)
+
+	uc := resources[1].(*fhir.Observation)
+
+	assert.Equal(t, "3-uc", uc.Id)
+	assert.Equal(t, "[arb'U]", uc.ValueSampledData.Origin.Code)
+	assert.Equal(t, "10 E 12", uc.ValueSampledData.Data)
+	assert.EqualValues(t, 127, *uc.ValueSampledData.UpperLimit)
+}
+
+func TestServiceFHIR_fhirChannel(t *testing.T) {
+	if i, ok := fhirChannel("fhr2"); assert.True(t, ok) {
+		assert.Equal(t, 1, i)
+	}
+
+	_, ok := fhirChannel("spo2")
+	assert.False(t, ok)
+}
+
+func TestServiceFHIR_fhirRiskAssessment(t *testing.T) {
+	begin := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	risk := 4
+
+	resource := fhirRiskAssessment(&model.ComputedEventEntity{
+		ComputedEvent: &model.ComputedEvent{
+			Id: 7, Risk: &risk, Memo: "memo", RangeFrom: begin, RangeUntil: begin.Add(time.Minute),
+		},
+		Measurement: &model.Measurement{Id: 3, PatientId: 2, Code: "m-0003"},
+	})
+
+	assert.Equal(t, "7", resource.Id)
+	assert.Equal(t, "Patient/2", resource.Subject.Reference)
+	assert.Equal(t, "Encounter/3", resource.Encounter.Reference)
+	assert.Equal(t, "4", resource.Prediction[0].QualitativeRisk.Coding[0].Code)
+	assert.Equal(t, "R4", resource.Prediction[0].QualitativeRisk.Coding[0].Display)
+	assert.Equal(t, "memo", resource.Note[0].Text)
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/resource/rds"
+)
+
+// 計測の患者の在胎日数から、判読基準とする在胎週数の区分を決定する。
+// 返される記録は診断IDを持たない。
+func gestationForMeasurement(
+	db model.QueryExecutor,
+	measurementId int,
+) (*model.DiagnosisGestation, error) {
+	days, err := rds.FetchGestationalDays(db, measurementId)
+
+	if err != nil {
+		return nil, C.DB_OPERATION_ERROR(err)
+	}
+
+	return &model.DiagnosisGestation{
+		GestationalDays: days,
+		Band: string(C.GestationalBandOf(days)),
+	}, nil
+}
+
+// 診断に在胎週数の区分を登録する。
+func registerGestation(
+	db model.QueryExecutor,
+	diagnosisId int,
+	gestation *model.DiagnosisGestation,
+) error {
+	gestation.DiagnosisId = diagnosisId
+
+	if e := db.Insert(gestation); e != nil {
+		return C.DB_OPERATION_ERROR(e)
+	}
+
+	return nil
+}
+
+// 在胎週数の区分に応じて判定し直した基線により、診断項目のリスクを算出し直す。
+// 算出できない項目は自動診断のリスクを維持する。戻り値は算出し直した後の最大のリスク値。
+func reassessRisks(
+	band C.GestationalBand,
+	matrices *C.RiskMatrices,
+	latestBaseline *C.Baseline,
+	contents []*model.DiagnosisContent,
+) *int {
+	latestBaseline = band.Interpret(latestBaseline)
+
+	var maximumRisk *int = nil
+
+	for _, c := range contents {
+		var values interface{}
+		if ps, e := c.Parameters.UnmarshalObject(); e == nil {
+			values = ps
+		}
+
+		if event, e := readEvents(lib.AsJson(values)); e == nil && event != nil {
+			switch evt := event.(type) {
+			case *C.Baseline:
+				if evt.Variability != nil {
+					latestBaseline = band.Interpret(evt)
+
+					if r := matrices.GetRisk(latestBaseline.Type, latestBaseline.Variability.Type, C.CTG_DecelerationNone); r >= 0 {
+						c.Risk = &r
+					}
+				}
+			case *C.Deceleration:
+				if latestBaseline != nil && latestBaseline.Variability != nil {
+					if r := matrices.GetRisk(latestBaseline.Type, latestBaseline.Variability.Type, evt.Type); r >= 0 {
+						c.Risk = &r
+					}
+				}
+			}
+		}
+
+		if c.Risk != nil && (maximumRisk == nil || *c.Risk > *maximumRisk) {
+			maximumRisk = c.Risk
+		}
+	}
+
+	return maximumRisk
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/model"
+)
+
+func TestServiceGestation_reassessRisks(t *testing.T) {
+	params := func(values map[string]interface{}) model.JSON {
+		bs, _ := json.Marshal(values)
+		return model.JSON(bs)
+	}
+
+	risk := func(r int) *int {
+		return &r
+	}
+
+	// 正期産の基準では頻脈、基線細変動減少と判定される基線。
+	contents := func() []*model.DiagnosisContent {
+		return []*model.DiagnosisContent{
+			&model.DiagnosisContent{
+				Risk: risk(5),
+				Parameters: params(map[string]interface{}{
+					string(C.CTG_BaselineAcceleration): 165,
+					string(C.CTG_BaselineVariabilityDecrease): 4,
+				}),
+			},
+			&model.DiagnosisContent{
+				Risk: risk(5),
+				Parameters: params(map[string]interface{}{
+					string(C.CTG_DecelerationED): 1,
+				}),
+			},
+			&model.DiagnosisContent{
+				Risk: risk(2),
+				Parameters: params(map[string]interface{}{
+					"Unknown": 1,
+				}),
+			},
+		}
+	}
+
+	t.Run("早期早産では正常と判定し直す", func(t *testing.T) {
+		cs := contents()
+
+		maximum := reassessRisks(C.GestationalBandEarlyPreterm, C.DefaultRiskMatrices, nil, cs)
+
+		normal := C.GetRisk(C.CTG_BaselineNormal, C.CTG_BaselineVariabilityNormal, C.CTG_DecelerationNone)
+		early := C.GetRisk(C.CTG_BaselineNormal, C.CTG_BaselineVariabilityNormal, C.CTG_DecelerationED)
+
+		assert.EqualValues(t, normal, *cs[0].Risk)
+		assert.EqualValues(t, early, *cs[1].Risk)
+		// 算出できない項目は維持。
+		assert.EqualValues(t, 2, *cs[2].Risk)
+
+		expected := 2
+		for _, r := range []int{normal, early} {
+			if r > expected {
+				expected = r
+			}
+		}
+		assert.EqualValues(t, expected, *maximum)
+	})
+
+	t.Run("早産では基線のみ正常と判定し直す", func(t *testing.T) {
+		cs := contents()
+
+		reassessRisks(C.GestationalBandPreterm, C.DefaultRiskMatrices, nil, cs)
+
+		assert.EqualValues(t, C.GetRisk(C.CTG_BaselineNormal, C.CTG_BaselineVariabilityDecrease, C.CTG_DecelerationNone), *cs[0].Risk)
+		assert.EqualValues(t, C.GetRisk(C.CTG_BaselineNormal, C.CTG_BaselineVariabilityDecrease, C.CTG_DecelerationED), *cs[1].Risk)
+	})
+}
+
+func TestServiceGestation_Interpret(t *testing.T) {
+	days := func(d int) *int {
+		return &d
+	}
+
+	assert.EqualValues(t, C.GestationalBandUnknown, C.GestationalBandOf(nil))
+	assert.EqualValues(t, C.GestationalBandEarlyPreterm, C.GestationalBandOf(days(223)))
+	assert.EqualValues(t, C.GestationalBandPreterm, C.GestationalBandOf(days(224)))
+	assert.EqualValues(t, C.GestationalBandPreterm, C.GestationalBandOf(days(258)))
+	assert.EqualValues(t, C.GestationalBandTerm, C.GestationalBandOf(days(259)))
+
+	baseline := &C.Baseline{
+		Type: C.CTG_BaselineAcceleration,
+		Value: 165,
+		Variability: &C.BaselineVariability{C.CTG_BaselineVariabilitySinusoidal, 4},
+	}
+
+	// 正期産、不明では判定し直さない。
+	assert.True(t, baseline == C.GestationalBandTerm.Interpret(baseline))
+	assert.True(t, baseline == C.GestationalBandUnknown.Interpret(baseline))
+
+	interpreted := C.GestationalBandEarlyPreterm.Interpret(baseline)
+
+	assert.EqualValues(t, C.CTG_BaselineNormal, interpreted.Type)
+	assert.EqualValues(t, 165, interpreted.Value)
+	// 正弦波様は維持。
+	assert.EqualValues(t, C.CTG_BaselineVariabilitySinusoidal, interpreted.Variability.Type)
+	// 元の基線は変更されない。
+	assert.EqualValues(t, C.CTG_BaselineAcceleration, baseline.Type)
+
+	assert.EqualValues(t, C.CTG_BaselineHiDeceleration, C.GestationalBandPreterm.Interpret(&C.Baseline{Value: 75}).Type)
+	assert.EqualValues(t, C.CTG_BaselineDeceleration, C.GestationalBandEarlyPreterm.Interpret(&C.Baseline{Value: 115}).Type)
+	assert.EqualValues(t, C.CTG_BaselineAcceleration, C.GestationalBandPreterm.Interpret(&C.Baseline{Value: 170}).Type)
+}
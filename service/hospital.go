@@ -22,12 +22,12 @@ type HospitalTxService struct {
 	DB *gorp.Transaction
 }
 
-// 病院をID順に取得する。
-func (s *HospitalService) List(limit int, offset int) ([]*model.Hospital, int64, error) {
-	if r, t, e := rds.ListHospitals(s.DB, limit, offset); e != nil {
-		return nil, 0, C.DB_OPERATION_ERROR(e)
+// 病院一覧を取得する。
+func (s *HospitalService) List(paging *model.Paging) ([]*model.Hospital, *model.PageInfo, error) {
+	if r, p, e := rds.ListHospitals(s.DB, paging); e != nil {
+		return nil, nil, listError(e)
 	} else {
-		return r, t, nil
+		return r, p, nil
 	}
 }
 
@@ -120,6 +120,78 @@ func (s *HospitalTxService) Delete(id int) error {
 	return nil
 }
 
+// 病院でアラートに利用するガイドラインを取得する。
+func (s *HospitalService) FetchGuideline(id int) (C.Guideline, error) {
+	if _, e := inquireHospital(s.DB, id); e != nil {
+		return "", e
+	}
+
+	if r, e := rds.FetchHospitalGuideline(s.DB, id); e != nil {
+		return "", C.DB_OPERATION_ERROR(e)
+	} else {
+		return r, nil
+	}
+}
+
+// 病院でアラートに利用するガイドラインを設定する。
+func (s *HospitalTxService) UpdateGuideline(id int, guideline C.Guideline) (C.Guideline, error) {
+	if _, e := inquireHospital(s.DB, id); e != nil {
+		return "", e
+	}
+
+	if _, e := s.DB.Exec(`DELETE FROM hospital_guideline WHERE hospital_id = $1`, id); e != nil {
+		return "", C.DB_OPERATION_ERROR(e)
+	}
+
+	if e := s.DB.Insert(&model.HospitalGuideline{
+		HospitalId: id,
+		Guideline: string(guideline),
+		ModifiedAt: time.Now(),
+	}); e != nil {
+		return "", C.DB_OPERATION_ERROR(e)
+	}
+
+	return guideline, nil
+}
+
+// 病院の計測データの保存期間(年)を取得する。設定されていない場合はnilとなり、計測データを削除しない。
+func (s *HospitalService) FetchRetention(id int) (*int, error) {
+	if _, e := inquireHospital(s.DB, id); e != nil {
+		return nil, e
+	}
+
+	if r, e := rds.FetchHospitalRetention(s.DB, id); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else if r == nil {
+		return nil, nil
+	} else {
+		return &r.Years, nil
+	}
+}
+
+// 病院の計測データの保存期間(年)を設定する。nilの場合は設定を削除する。
+func (s *HospitalTxService) UpdateRetention(id int, years *int) (*int, error) {
+	if _, e := inquireHospital(s.DB, id); e != nil {
+		return nil, e
+	}
+
+	if _, e := s.DB.Exec(`DELETE FROM hospital_retention WHERE hospital_id = $1`, id); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	if years != nil {
+		if e := s.DB.Insert(&model.HospitalRetention{
+			HospitalId: id,
+			Years: *years,
+			ModifiedAt: time.Now(),
+		}); e != nil {
+			return nil, C.DB_OPERATION_ERROR(e)
+		}
+	}
+
+	return years, nil
+}
+
 func inquireHospital(db model.QueryExecutor, id int) (*model.Hospital, error) {
 	if r, e := rds.FetchHospital(db, id); e != nil {
 		return nil, C.DB_OPERATION_ERROR(e)
@@ -9,19 +9,19 @@ import (
 	C "github.com/spiker/spiker-server/constant"
 	"github.com/spiker/spiker-server/lib"
 	"github.com/spiker/spiker-server/model"
-	"github.com/spiker/spiker-server/resource/rds"
 	"github.com/spiker/spiker-server/resource/influxdb"
+	"github.com/spiker/spiker-server/resource/rds"
 )
 
 type MeasurementService struct {
 	*Service
-	DB *gorp.DbMap
+	DB     *gorp.DbMap
 	Influx lib.InfluxDBClient
 }
 
 type MeasurementTxService struct {
 	*Service
-	DB *gorp.Transaction
+	DB     *gorp.Transaction
 	Influx lib.InfluxDBClient
 }
 
@@ -43,28 +43,36 @@ func (s *MeasurementService) CheckAccessByDoctor(
 	}
 }
 
-// 患者に関する計測記録一覧を取得する。
+// 条件に合う計測記録一覧を取得する。
 // TODO 医師によるアクセス制限を必要に応じて追加。
 func (s *MeasurementService) List(
 	hospitalId int,
-	patientId *int,
-	terminalId *int,
-	limit int,
-	offset int,
-) ([]*model.MeasurementEntity, int64, error) {
-	if r, t, e := rds.ListMeasurements(s.DB, hospitalId, patientId, terminalId, limit, offset); e != nil {
-		return nil, 0, C.DB_OPERATION_ERROR(e)
+	filter *model.MeasurementFilter,
+	paging *model.Paging,
+) ([]*model.MeasurementEntity, *model.PageInfo, error) {
+	if r, p, e := rds.ListMeasurements(s.DB, hospitalId, filter, paging); e != nil {
+		return nil, nil, listError(e)
+	} else if e := newPHIKeyring(s.DB).revealEntities(r...); e != nil {
+		return nil, nil, e
 	} else {
-		return r, t, nil
+		return r, p, nil
 	}
 }
 
-// 病院内の計測をコードから取得する。
+// 病院内の計測をコードから取得する。同じコードの計測が複数ある場合は最新の計測を返す。
 func (s *MeasurementService) InquireByCode(
 	hospitalId int,
 	code string,
 ) (*model.Measurement, error) {
-	if ms, e := rds.InquireMeasurementsByCodes(s.DB, hospitalId, []string{code}); e != nil {
+	keyring := newPHIKeyring(s.DB)
+
+	candidates, _, err := keyring.codeCandidates(hospitalId, []string{code})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if ms, e := rds.InquireMeasurementsByCodes(s.DB, hospitalId, candidates); e != nil {
 		return nil, C.DB_OPERATION_ERROR(e)
 	} else if len(ms) == 0 {
 		return nil, C.NewNotFoundError(
@@ -72,6 +80,8 @@ func (s *MeasurementService) InquireByCode(
 			fmt.Sprintf("Measurement %s is not found in hospital %d", code, hospitalId),
 			map[string]interface{}{},
 		)
+	} else if e := keyring.revealMeasurements(ms[0]); e != nil {
+		return nil, e
 	} else {
 		return ms[0], nil
 	}
@@ -107,6 +117,8 @@ func (s *MeasurementService) Fetch(
 			fmt.Sprintf("Measurement %d is not found", id),
 			map[string]interface{}{},
 		)
+	} else if e := newPHIKeyring(s.DB).revealEntities(r); e != nil {
+		return nil, e
 	} else {
 		return r, nil
 	}
@@ -127,11 +139,15 @@ func (s *MeasurementService) CollectForAssessment(
 	}
 
 	// それぞれの計測のduration分のデータを取得する。
+	// 心拍はアーチファクトを補正、除去したものとする。
+	// 持続する低下や下限を下回る心拍はアーチファクトとみなされないため、補正後も検査の対象となる。
 	for _, m := range measurements {
 		if vs, e := influxdb.ListHeartRate(s.Influx, m.Id, diagnosisTime.Add(-duration), diagnosisTime); e != nil {
 			return nil, e
+		} else if as, e := influxdb.ListHeartRateArtifacts(s.Influx, m.Id, diagnosisTime.Add(-duration), diagnosisTime); e != nil {
+			return nil, e
 		} else {
-			m.HeartRates = vs
+			m.HeartRates = cleanHeartRates(vs, as)
 		}
 
 		if vs, e := influxdb.ListTOCO(s.Influx, m.Id, diagnosisTime.Add(-duration), diagnosisTime); e != nil {
@@ -205,23 +221,29 @@ func (s *MeasurementTxService) EnsureNotExists(
 	code string,
 	removeIfExists bool,
 ) error {
-	// 既存の計測を削除。
-	if ms, e := rds.InquireMeasurementsByCodes(s.DB, hospitalId, []string{code}); e != nil {
+	candidates, _, err := newPHIKeyring(s.DB).codeCandidates(hospitalId, []string{code})
+
+	if err != nil {
+		return err
+	}
+
+	// 既存の計測を削除。自動完了後に同じコードで開始した計測も含め、全て削除する。
+	if ms, e := rds.InquireMeasurementsByCodes(s.DB, hospitalId, candidates); e != nil {
 		return e
-	} else if len(ms) > 1 {
-		return fmt.Errorf("%d measurements whose codes are '%s' are found in hospital #%d", len(ms), code, hospitalId)
-	} else if len(ms) == 1 {
+	} else if len(ms) > 0 {
 		if !removeIfExists {
 			return fmt.Errorf("Measurement code '%s' already exists in hospital #%d", code, hospitalId)
 		}
 
-		if _, e := s.DB.Delete(ms[0]); e != nil {
-			return e
-		}
+		for _, m := range ms {
+			if _, e := s.DB.Delete(m); e != nil {
+				return e
+			}
 
-		// 計測IDに対する全てのデータを削除する。
-		if e := s.Influx.DeleteAll("spiker", fmt.Sprintf(`measurement_id="%d"`, ms[0].Id)); e != nil {
-			return e
+			// 計測IDに対する全てのデータを削除する。
+			if e := s.Influx.DeleteAll("spiker", fmt.Sprintf(`measurement_id="%d"`, m.Id)); e != nil {
+				return e
+			}
 		}
 	}
 
@@ -257,4 +279,17 @@ func (s *MeasurementTxService) Close(
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// 計測のデータの検索期間。検索は右閉の秒単位となるため、終端は1秒進めて最後のデータを含める。
+// データを受信していない計測では、それぞれnilを返す。
+func measurementDataRange(m *model.Measurement) (*time.Time, *time.Time) {
+	var end *time.Time
+
+	if m.LastTime != nil {
+		last := m.LastTime.Add(time.Second)
+		end = &last
+	}
+
+	return m.FirstTime, end
+}
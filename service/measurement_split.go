@@ -0,0 +1,347 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/resource/influxdb"
+	"github.com/spiker/spiker-server/resource/rds"
+)
+
+// 計測を日時で分割する。日時以降の計測データ、診断及びイベントを新規の計測に移し、新規の計測を返す。
+//
+// 新規の計測は元の計測と同じ計測コード、患者、端末とし、以降に届くデータは新規の計測に記録される。別の患者の計測であれば、分割後に患者を紐付け直す。
+// 元の計測は完了状態とし、元の計測の完了状態は新規の計測が引き継ぐ。
+// 計測データは付け替え待ちとして登録し、コミットした後にRetagPendingで新規の計測に付け替える。
+func (s *MeasurementTxService) Split(
+	id int,
+	at time.Time,
+) (*model.Measurement, error) {
+	mm, err := inquireEditableMeasurement(s.DB, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	records, _, end, err := listMeasurementData(s.Influx, mm)
+
+	if err != nil {
+		return nil, err
+	}
+
+	before, after := []*lib.PointRecord{}, []*lib.PointRecord{}
+
+	for _, r := range records {
+		if r.Timestamp.Before(at) {
+			before = append(before, r)
+		} else {
+			after = append(after, r)
+		}
+	}
+
+	if len(before) == 0 || len(after) == 0 {
+		return nil, C.NewBadRequestError(
+			"invalid_split_time",
+			fmt.Sprintf("Measurement %d has no data before or after %s", id, at.Format(time.RFC3339)),
+			map[string]interface{}{},
+		)
+	}
+
+	now := time.Now()
+
+	first, last := dataPeriod(after)
+
+	split := &model.Measurement{
+		Code:        mm.Code,
+		PatientId:   mm.PatientId,
+		TerminalId:  mm.TerminalId,
+		FirstTime:   first,
+		LastTime:    last,
+		IsClosed:    mm.IsClosed,
+		ClosingMemo: mm.ClosingMemo,
+		ClosedAt:    mm.ClosedAt,
+		CreatedAt:   now,
+		ModifiedAt:  now,
+	}
+
+	if e := s.DB.Insert(split); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	mm.FirstTime, mm.LastTime = dataPeriod(before)
+
+	if !mm.IsClosed {
+		mm.IsClosed = true
+		mm.ClosedAt = &now
+	}
+
+	mm.ModifiedAt = now
+
+	if _, e := s.DB.Update(mm); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	if e := rds.MoveMeasurementRecords(s.DB, id, split.Id, &at); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	// 割り当て中の端末の以降のデータは、新規の計測に記録する。
+	if e := rds.MoveAdmissionMeasurement(s.DB, id, split.Id, true); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	if e := s.DB.Insert(&model.MeasurementRetag{
+		FromMeasurementId: id,
+		ToMeasurementId: split.Id,
+		BeginTime: at,
+		EndTime: end,
+		RequestedAt: now,
+	}); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	if e := newPHIKeyring(s.DB).revealMeasurements(split); e != nil {
+		return nil, e
+	}
+
+	return split, nil
+}
+
+// 計測を統合する。mergedIdの計測の計測データ、診断及びイベントをidの計測に移し、mergedIdの計測を削除する。
+//
+// 統合後の計測期間は両方の計測を含む期間とし、いずれかが計測中であれば計測中とする。患者はidの計測の患者のままとする。
+// 計測データは付け替え待ちとして登録し、コミットした後にRetagPendingでidの計測に付け替える。
+func (s *MeasurementTxService) Merge(
+	id int,
+	mergedId int,
+) (*model.Measurement, error) {
+	if id == mergedId {
+		return nil, C.NewBadRequestError(
+			"measurement_merge_same",
+			fmt.Sprintf("Measurement %d cannot be merged into itself", id),
+			map[string]interface{}{},
+		)
+	}
+
+	mm, err := inquireEditableMeasurement(s.DB, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	merged, err := inquireEditableMeasurement(s.DB, mergedId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	hospitals := []int{}
+
+	for _, pid := range []int{mm.PatientId, merged.PatientId} {
+		if r, e := rds.InquirePatient(s.DB, pid); e != nil {
+			return nil, C.DB_OPERATION_ERROR(e)
+		} else if r == nil {
+			return nil, fmt.Errorf("Patient %d of measurement is not found", pid)
+		} else {
+			hospitals = append(hospitals, r.HospitalId)
+		}
+	}
+
+	if hospitals[0] != hospitals[1] {
+		return nil, C.NewBadRequestError(
+			"measurement_merge_hospital",
+			fmt.Sprintf("Measurements %d and %d belong to different hospitals", id, mergedId),
+			map[string]interface{}{},
+		)
+	}
+
+	records, begin, end, err := listMeasurementData(s.Influx, merged)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if merged.FirstTime != nil && (mm.FirstTime == nil || merged.FirstTime.Before(*mm.FirstTime)) {
+		mm.FirstTime = merged.FirstTime
+	}
+
+	if merged.LastTime != nil && (mm.LastTime == nil || merged.LastTime.After(*mm.LastTime)) {
+		mm.LastTime = merged.LastTime
+	}
+
+	if mm.IsClosed && !merged.IsClosed {
+		mm.IsClosed = false
+		mm.ClosingMemo = nil
+		mm.ClosedAt = nil
+	}
+
+	mm.ModifiedAt = time.Now()
+
+	if _, e := s.DB.Update(mm); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	if e := rds.MoveMeasurementRecords(s.DB, mergedId, id, nil); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	if e := rds.DeleteMergedMeasurement(s.DB, id, mergedId); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	if len(records) > 0 {
+		if e := s.DB.Insert(&model.MeasurementRetag{
+			FromMeasurementId: mergedId,
+			ToMeasurementId: id,
+			BeginTime: begin,
+			EndTime: end,
+			RequestedAt: mm.ModifiedAt,
+		}); e != nil {
+			return nil, C.DB_OPERATION_ERROR(e)
+		}
+	}
+
+	if e := newPHIKeyring(s.DB).revealMeasurements(mm); e != nil {
+		return nil, e
+	}
+
+	return mm, nil
+}
+
+// 編集の対象とする計測を取得する。アーカイブされ、復元されていない計測は計測データが無いため編集できない。
+func inquireEditableMeasurement(db model.QueryExecutor, id int) (*model.Measurement, error) {
+	mm, err := rds.InquireMeasurement(db, id)
+
+	if err != nil {
+		return nil, C.DB_OPERATION_ERROR(err)
+	} else if mm == nil {
+		return nil, C.NewNotFoundError(
+			"measurement_not_found",
+			fmt.Sprintf("Measurement %d is not found", id),
+			map[string]interface{}{},
+		)
+	}
+
+	if r, e := rds.FetchMeasurementArchive(db, id); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else if r != nil && r.RestoredAt == nil {
+		return nil, C.NewBadRequestError(
+			"measurement_archived",
+			fmt.Sprintf("Measurement %d is archived and must be restored first", id),
+			map[string]interface{}{},
+		)
+	}
+
+	return mm, nil
+}
+
+// 計測の全ての計測データと、その検索期間を取得する。
+func listMeasurementData(
+	influx lib.InfluxDBClient,
+	mm *model.Measurement,
+) ([]*lib.PointRecord, time.Time, time.Time, error) {
+	first, last := measurementDataRange(mm)
+
+	if first == nil || last == nil {
+		return []*lib.PointRecord{}, time.Time{}, time.Time{}, nil
+	}
+
+	begin := *first
+	end := *last
+
+	if r, e := influxdb.ListRecords(influx, mm.Id, begin, end); e != nil {
+		return nil, begin, end, C.INFLUXDB_OPERATION_ERROR(e)
+	} else {
+		return r, begin, end, nil
+	}
+}
+
+// 計測データの最初と最後の日時。
+func dataPeriod(records []*lib.PointRecord) (*time.Time, *time.Time) {
+	var first, last *time.Time
+
+	for _, r := range records {
+		t := r.Timestamp
+
+		if first == nil || t.Before(*first) {
+			first = &t
+		}
+		if last == nil || t.After(*last) {
+			last = &t
+		}
+	}
+
+	return first, last
+}
+
+// 分割、統合で付け替え待ちとなった計測データの計測IDを付け替え、付け替えた件数を返す。
+//
+// 計測データはトランザクション外のため、分割、統合をコミットした後に別のトランザクションで実行する。
+// 付け替えは繰り返し実行できるため、失敗した場合は付け替え待ちに残し、次回の実行で再び付け替える。
+func (s *MeasurementTxService) RetagPending() (int, error) {
+	retags, err := rds.ListMeasurementRetags(s.DB)
+
+	if err != nil {
+		return 0, C.DB_OPERATION_ERROR(err)
+	}
+
+	count := 0
+
+	for _, r := range retags {
+		records, err := influxdb.ListRecords(s.Influx, r.FromMeasurementId, r.BeginTime, r.EndTime)
+
+		if err != nil {
+			return count, C.INFLUXDB_OPERATION_ERROR(err)
+		}
+
+		if len(records) > 0 {
+			if e := retagMeasurementData(s.Influx, records, r.FromMeasurementId, r.ToMeasurementId, r.BeginTime, r.EndTime); e != nil {
+				return count, e
+			}
+		}
+
+		if e := rds.DeleteMeasurementRetag(s.DB, r.Id); e != nil {
+			return count, C.DB_OPERATION_ERROR(e)
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
+// 計測データの計測IDを付け替える。
+// InfluxDBではタグを更新できないため、付け替えたデータを登録してから元の計測IDのbeginからendまでのデータを削除する。
+func retagMeasurementData(
+	influx lib.InfluxDBClient,
+	records []*lib.PointRecord,
+	from int,
+	to int,
+	begin time.Time,
+	end time.Time,
+) error {
+	points := []lib.Point{}
+
+	for _, r := range records {
+		if record, e := newArchivedRecord(r); e != nil {
+			return e
+		} else {
+			record.Tags["measurement_id"] = strconv.Itoa(to)
+			points = append(points, &archivedPoint{record, r.Value})
+		}
+	}
+
+	if errors := influx.Insert("spiker", model.OpaquePoints(points...)...); len(errors) > 0 {
+		return C.INFLUXDB_OPERATION_ERROR(errors[0])
+	}
+
+	if e := influx.Delete("spiker", begin, end, fmt.Sprintf(`measurement_id="%d"`, from)); e != nil {
+		return C.INFLUXDB_OPERATION_ERROR(e)
+	}
+
+	return nil
+}
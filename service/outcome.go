@@ -0,0 +1,422 @@
+package service
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"gopkg.in/gorp.v2"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/resource/rds"
+)
+
+type OutcomeService struct {
+	*Service
+	DB *gorp.DbMap
+}
+
+// 数値の転帰の分布。値を持たない患者は含まない。
+type NumericDistribution struct {
+	Count   int      `json:"count"`
+	Mean    *float64 `json:"mean"`
+	Median  *float64 `json:"median"`
+	Minimum *float64 `json:"minimum"`
+	Maximum *float64 `json:"maximum"`
+}
+
+// 二値の転帰の分布。値を持たない患者は含まない。
+type BinaryDistribution struct {
+	Count    int      `json:"count"`
+	Positive int      `json:"positive"`
+	Rate     *float64 `json:"rate"`
+}
+
+// 患者とアルゴリズムの組毎の転帰。
+// RiskSecondsは出生前の集計期間に各リスク値であった秒数で、添字0はリスクを持つ診断項目の無い期間を表す。
+type OutcomeRecord struct {
+	Patient     *model.Patient
+	Algorithm   *model.DiagnosisAlgorithm
+	MaximumRisk int
+	RiskSeconds []float64
+}
+
+// アルゴリズムと最大リスク毎の転帰の分布。MeanRiskSecondsはRiskSecondsの患者平均。
+type OutcomeGroup struct {
+	Algorithm         *model.DiagnosisAlgorithm `json:"algorithm"`
+	MaximumRisk       int                       `json:"maximumRisk"`
+	Patients          int                       `json:"patients"`
+	MeanRiskSeconds   []float64                 `json:"meanRiskSeconds"`
+	ApgarScore1Min    *NumericDistribution      `json:"apgarScore1min"`
+	ApgarScore5Min    *NumericDistribution      `json:"apgarScore5min"`
+	UmbilicalBlood    *NumericDistribution      `json:"umbilicalBlood"`
+	BirthWeight       *NumericDistribution      `json:"birthWeight"`
+	EmergencyCesarean *BinaryDistribution       `json:"emergencyCesarean"`
+	InstrumentalLabor *BinaryDistribution       `json:"instrumentalLabor"`
+}
+
+// 転帰分析の結果。Unassessedは出生日時を持つが自動診断のリスクを持たない患者の数。
+type OutcomeReport struct {
+	Minutes    int              `json:"minutes"`
+	Patients   int              `json:"patients"`
+	Unassessed int              `json:"unassessed"`
+	Groups     []*OutcomeGroup  `json:"groups"`
+	Records    []*OutcomeRecord `json:"-"`
+}
+
+// 出生日時が期間内にある患者について、自動診断のリスクと転帰の関係を集計する。
+// minutesは出生前にリスク値毎の時間を集計する期間(分)。
+func (s *OutcomeService) Compute(
+	hospitalId *int,
+	begin *time.Time,
+	end *time.Time,
+	minutes int,
+) (*OutcomeReport, error) {
+	patients, err := rds.ListBornPatients(s.DB, hospitalId, begin, end)
+
+	if err != nil {
+		return nil, C.DB_OPERATION_ERROR(err)
+	}
+
+	patientIds := []int{}
+
+	for _, p := range patients {
+		patientIds = append(patientIds, p.Id)
+	}
+
+	contents, err := rds.ListOutcomeContents(s.DB, patientIds)
+
+	if err != nil {
+		return nil, C.DB_OPERATION_ERROR(err)
+	}
+
+	algorithmIds := []int{}
+	algorithmSet := map[int]bool{}
+
+	for _, c := range contents {
+		if !algorithmSet[c.AlgorithmId] {
+			algorithmSet[c.AlgorithmId] = true
+			algorithmIds = append(algorithmIds, c.AlgorithmId)
+		}
+	}
+
+	algorithms, err := rds.ListAlgorithmsByIds(s.DB, algorithmIds)
+
+	if err != nil {
+		return nil, C.DB_OPERATION_ERROR(err)
+	}
+
+	records := outcomeRecords(patients, algorithms, contents, time.Duration(minutes)*time.Minute)
+
+	assessed := map[int]bool{}
+
+	for _, r := range records {
+		assessed[r.Patient.Id] = true
+	}
+
+	return &OutcomeReport{
+		Minutes: minutes,
+		Patients: len(patients),
+		Unassessed: len(patients) - len(assessed),
+		Groups: groupOutcomes(records),
+		Records: records,
+	}, nil
+}
+
+// 患者とアルゴリズムの組毎に、最大リスクと出生前のリスク値毎の時間を算出する。
+// 結果はアルゴリズムの並び順、患者の並び順に並ぶ。
+func outcomeRecords(
+	patients []*model.Patient,
+	algorithms []*model.DiagnosisAlgorithm,
+	contents []*model.OutcomeContent,
+	window time.Duration,
+) []*OutcomeRecord {
+	// アルゴリズムID、患者IDから診断項目へのマップ。
+	contentMap := map[int]map[int][]*model.OutcomeContent{}
+
+	for _, c := range contents {
+		if c.Risk < C.RiskMinimum || c.Risk > C.RiskMaximum {
+			continue
+		}
+
+		if _, be := contentMap[c.AlgorithmId]; !be {
+			contentMap[c.AlgorithmId] = map[int][]*model.OutcomeContent{}
+		}
+
+		contentMap[c.AlgorithmId][c.PatientId] = append(contentMap[c.AlgorithmId][c.PatientId], c)
+	}
+
+	records := []*OutcomeRecord{}
+
+	for _, a := range algorithms {
+		for _, p := range patients {
+			cs, be := contentMap[a.Id][p.Id]
+
+			if !be {
+				continue
+			}
+
+			maximum := C.RiskMinimum
+			for _, c := range cs {
+				if c.Risk > maximum {
+					maximum = c.Risk
+				}
+			}
+
+			records = append(records, &OutcomeRecord{
+				Patient: p,
+				Algorithm: a,
+				MaximumRisk: maximum,
+				RiskSeconds: riskSeconds(cs, p.BirthDatetime.Add(-window), *p.BirthDatetime),
+			})
+		}
+	}
+
+	return records
+}
+
+// 期間内の各時点でのリスク値を、その時点を含む診断項目の最大のリスクとし、リスク値毎の秒数を算出する。
+func riskSeconds(
+	contents []*model.OutcomeContent,
+	begin time.Time,
+	end time.Time,
+) []float64 {
+	results := make([]float64, C.RiskMaximum+1)
+
+	points := []time.Time{begin, end}
+
+	for _, c := range contents {
+		for _, t := range []time.Time{c.RangeFrom, c.RangeUntil} {
+			if t.After(begin) && t.Before(end) {
+				points = append(points, t)
+			}
+		}
+	}
+
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Before(points[j])
+	})
+
+	for i := 0; i+1 < len(points); i++ {
+		from, until := points[i], points[i+1]
+
+		if !from.Before(until) {
+			continue
+		}
+
+		risk := 0
+
+		for _, c := range contents {
+			if !c.RangeFrom.After(from) && !c.RangeUntil.Before(until) && c.Risk > risk {
+				risk = c.Risk
+			}
+		}
+
+		results[risk] += until.Sub(from).Seconds()
+	}
+
+	return results
+}
+
+// 転帰をアルゴリズムと最大リスク毎に集計する。結果はアルゴリズムの出現順、最大リスクの昇順に並ぶ。
+func groupOutcomes(records []*OutcomeRecord) []*OutcomeGroup {
+	type groupKey struct {
+		algorithmId int
+		risk        int
+	}
+
+	keys := []groupKey{}
+	members := map[groupKey][]*OutcomeRecord{}
+	algorithmOrder := map[int]int{}
+
+	for _, r := range records {
+		if _, be := algorithmOrder[r.Algorithm.Id]; !be {
+			algorithmOrder[r.Algorithm.Id] = len(algorithmOrder)
+		}
+
+		k := groupKey{r.Algorithm.Id, r.MaximumRisk}
+
+		if _, be := members[k]; !be {
+			keys = append(keys, k)
+		}
+
+		members[k] = append(members[k], r)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if algorithmOrder[keys[i].algorithmId] != algorithmOrder[keys[j].algorithmId] {
+			return algorithmOrder[keys[i].algorithmId] < algorithmOrder[keys[j].algorithmId]
+		}
+		return keys[i].risk < keys[j].risk
+	})
+
+	groups := []*OutcomeGroup{}
+
+	for _, k := range keys {
+		rs := members[k]
+
+		meanSeconds := make([]float64, C.RiskMaximum+1)
+
+		apgar1 := []*int{}
+		apgar5 := []*int{}
+		umbilical := []*int{}
+		weight := []*int{}
+		cesarean := []*bool{}
+		instrumental := []*bool{}
+
+		for _, r := range rs {
+			for i, s := range r.RiskSeconds {
+				meanSeconds[i] += s / float64(len(rs))
+			}
+
+			apgar1 = append(apgar1, r.Patient.ApgarScore1Min)
+			apgar5 = append(apgar5, r.Patient.ApgarScore5Min)
+			umbilical = append(umbilical, r.Patient.UmbilicalBlood)
+			weight = append(weight, r.Patient.BirthWeight)
+			cesarean = append(cesarean, r.Patient.EmergencyCesarean)
+			instrumental = append(instrumental, r.Patient.InstrumentalLabor)
+		}
+
+		groups = append(groups, &OutcomeGroup{
+			Algorithm: rs[0].Algorithm,
+			MaximumRisk: k.risk,
+			Patients: len(rs),
+			MeanRiskSeconds: meanSeconds,
+			ApgarScore1Min: numericDistribution(apgar1),
+			ApgarScore5Min: numericDistribution(apgar5),
+			UmbilicalBlood: numericDistribution(umbilical),
+			BirthWeight: numericDistribution(weight),
+			EmergencyCesarean: binaryDistribution(cesarean),
+			InstrumentalLabor: binaryDistribution(instrumental),
+		})
+	}
+
+	return groups
+}
+
+func numericDistribution(values []*int) *NumericDistribution {
+	vs := []float64{}
+
+	for _, v := range values {
+		if v != nil {
+			vs = append(vs, float64(*v))
+		}
+	}
+
+	result := &NumericDistribution{Count: len(vs)}
+
+	if len(vs) == 0 {
+		return result
+	}
+
+	sort.Float64s(vs)
+
+	var median float64
+	if len(vs) % 2 == 0 {
+		median = (vs[len(vs)/2-1] + vs[len(vs)/2]) / 2
+	} else {
+		median = vs[len(vs)/2]
+	}
+
+	minimum := vs[0]
+	maximum := vs[len(vs)-1]
+
+	result.Mean = average(vs)
+	result.Median = &median
+	result.Minimum = &minimum
+	result.Maximum = &maximum
+
+	return result
+}
+
+func binaryDistribution(values []*bool) *BinaryDistribution {
+	result := &BinaryDistribution{}
+
+	for _, v := range values {
+		if v != nil {
+			result.Count++
+
+			if *v {
+				result.Positive++
+			}
+		}
+	}
+
+	if result.Count > 0 {
+		rate := float64(result.Positive) / float64(result.Count)
+		result.Rate = &rate
+	}
+
+	return result
+}
+
+// 患者とアルゴリズムの組毎の転帰をCSV形式で出力する。値の無い項目は空とする。
+func (r *OutcomeReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{
+		"hospital_id", "patient_id", "birth_datetime", "algorithm", "version", "maximum_risk", "seconds_without_risk",
+	}
+
+	for risk := C.RiskMinimum; risk <= C.RiskMaximum; risk++ {
+		header = append(header, fmt.Sprintf("seconds_at_risk_%d", risk))
+	}
+
+	header = append(header,
+		"apgar_score_1min", "apgar_score_5min", "umbilical_blood", "birth_weight", "emergency_cesarean", "instrumental_labor",
+	)
+
+	if e := writer.Write(header); e != nil {
+		return e
+	}
+
+	optionalInt := func(v *int) string {
+		if v == nil {
+			return ""
+		}
+		return strconv.Itoa(*v)
+	}
+
+	optionalBool := func(v *bool) string {
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatBool(*v)
+	}
+
+	for _, rec := range r.Records {
+		row := []string{
+			strconv.Itoa(rec.Patient.HospitalId),
+			strconv.Itoa(rec.Patient.Id),
+			rec.Patient.BirthDatetime.Format(time.RFC3339),
+			rec.Algorithm.Name,
+			rec.Algorithm.Version,
+			strconv.Itoa(rec.MaximumRisk),
+		}
+
+		for _, s := range rec.RiskSeconds {
+			row = append(row, strconv.FormatFloat(s, 'f', -1, 64))
+		}
+
+		row = append(row,
+			optionalInt(rec.Patient.ApgarScore1Min),
+			optionalInt(rec.Patient.ApgarScore5Min),
+			optionalInt(rec.Patient.UmbilicalBlood),
+			optionalInt(rec.Patient.BirthWeight),
+			optionalBool(rec.Patient.EmergencyCesarean),
+			optionalBool(rec.Patient.InstrumentalLabor),
+		)
+
+		if e := writer.Write(row); e != nil {
+			return e
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spiker/spiker-server/model"
+)
+
+func TestServiceOutcome_riskSeconds(t *testing.T) {
+	birth := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	begin := birth.Add(-time.Duration(30) * time.Minute)
+
+	at := func(minutes int) time.Time {
+		return birth.Add(time.Duration(minutes) * time.Minute)
+	}
+
+	contents := []*model.OutcomeContent{
+		// 期間前から始まる項目は期間内の部分のみ数える。
+		&model.OutcomeContent{Risk: 2, RangeFrom: at(-40), RangeUntil: at(-20)},
+		// 重なる区間は高い方のリスクとする。
+		&model.OutcomeContent{Risk: 4, RangeFrom: at(-25), RangeUntil: at(-15)},
+		&model.OutcomeContent{Risk: 3, RangeFrom: at(-10), RangeUntil: at(10)},
+	}
+
+	results := riskSeconds(contents, begin, birth)
+
+	assert.Len(t, results, 6)
+	assert.EqualValues(t, 5 * 60, results[0])
+	assert.EqualValues(t, 0, results[1])
+	assert.EqualValues(t, 5 * 60, results[2])
+	assert.EqualValues(t, 10 * 60, results[3])
+	assert.EqualValues(t, 10 * 60, results[4])
+	assert.EqualValues(t, 0, results[5])
+
+	// 項目が無ければ全てリスク無しとなる。
+	assert.EqualValues(t, 30 * 60, riskSeconds([]*model.OutcomeContent{}, begin, birth)[0])
+}
+
+func TestServiceOutcome_groupOutcomes(t *testing.T) {
+	birth := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	intPtr := func(v int) *int {
+		return &v
+	}
+
+	boolPtr := func(v bool) *bool {
+		return &v
+	}
+
+	patients := []*model.Patient{
+		&model.Patient{Id: 1, HospitalId: 1, BirthDatetime: &birth, ApgarScore1Min: intPtr(8), EmergencyCesarean: boolPtr(false)},
+		&model.Patient{Id: 2, HospitalId: 1, BirthDatetime: &birth, ApgarScore1Min: intPtr(4), EmergencyCesarean: boolPtr(true)},
+		&model.Patient{Id: 3, HospitalId: 1, BirthDatetime: &birth, ApgarScore1Min: intPtr(6)},
+		// リスクを持つ診断項目の無い患者。
+		&model.Patient{Id: 4, HospitalId: 1, BirthDatetime: &birth},
+	}
+
+	algorithms := []*model.DiagnosisAlgorithm{
+		&model.DiagnosisAlgorithm{Id: 10, Name: "spiker", Version: "1.0"},
+		&model.DiagnosisAlgorithm{Id: 11, Name: "spiker", Version: "2.0"},
+	}
+
+	content := func(patientId int, algorithmId int, risk int) *model.OutcomeContent {
+		return &model.OutcomeContent{
+			PatientId: patientId,
+			AlgorithmId: algorithmId,
+			Risk: risk,
+			RangeFrom: birth.Add(-time.Duration(10) * time.Minute),
+			RangeUntil: birth,
+		}
+	}
+
+	contents := []*model.OutcomeContent{
+		content(1, 10, 1),
+		content(1, 10, 2),
+		content(2, 10, 5),
+		content(3, 10, 5),
+		content(1, 11, 3),
+	}
+
+	records := outcomeRecords(patients, algorithms, contents, time.Duration(30) * time.Minute)
+
+	assert.Len(t, records, 4)
+	assert.EqualValues(t, 2, records[0].MaximumRisk)
+	assert.EqualValues(t, 20 * 60, records[0].RiskSeconds[0])
+	assert.EqualValues(t, 10 * 60, records[0].RiskSeconds[2])
+
+	groups := groupOutcomes(records)
+
+	assert.Len(t, groups, 3)
+
+	assert.EqualValues(t, 10, groups[0].Algorithm.Id)
+	assert.EqualValues(t, 2, groups[0].MaximumRisk)
+	assert.EqualValues(t, 1, groups[0].Patients)
+
+	high := groups[1]
+	assert.EqualValues(t, 10, high.Algorithm.Id)
+	assert.EqualValues(t, 5, high.MaximumRisk)
+	assert.EqualValues(t, 2, high.Patients)
+	assert.EqualValues(t, 10 * 60, high.MeanRiskSeconds[5])
+	assert.EqualValues(t, 2, high.ApgarScore1Min.Count)
+	assert.EqualValues(t, 5, *high.ApgarScore1Min.Mean)
+	assert.EqualValues(t, 5, *high.ApgarScore1Min.Median)
+	assert.EqualValues(t, 4, *high.ApgarScore1Min.Minimum)
+	assert.EqualValues(t, 6, *high.ApgarScore1Min.Maximum)
+	assert.EqualValues(t, 0, high.BirthWeight.Count)
+	assert.Nil(t, high.BirthWeight.Mean)
+	// 値を持たない患者は含まない。
+	assert.EqualValues(t, 1, high.EmergencyCesarean.Count)
+	assert.EqualValues(t, 1, high.EmergencyCesarean.Positive)
+	assert.EqualValues(t, 1, *high.EmergencyCesarean.Rate)
+
+	assert.EqualValues(t, 11, groups[2].Algorithm.Id)
+	assert.EqualValues(t, 3, groups[2].MaximumRisk)
+
+	report := &OutcomeReport{Records: records}
+	buf := &bytes.Buffer{}
+
+	assert.NoError(t, report.WriteCSV(buf))
+
+	rows, err := csv.NewReader(buf).ReadAll()
+
+	assert.NoError(t, err)
+	assert.Len(t, rows, 5)
+	assert.EqualValues(t, "patient_id", rows[0][1])
+	assert.EqualValues(t, []string{"1", "1", "2024-01-01T12:00:00Z", "spiker", "1.0", "2"}, rows[1][:6])
+	// 値の無い転帰は空とする。
+	assert.EqualValues(t, "", rows[3][len(rows[3])-1])
+}
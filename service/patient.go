@@ -26,13 +26,14 @@ func (s *PatientService) List(
 	hospitalId int,
 	begin *time.Time,
 	end *time.Time,
-	limit int,
-	offset int,
-) ([]*model.Patient, int64, error) {
-	if r, t, e := rds.ListPatients(s.DB, hospitalId, begin, end, limit, offset); e != nil {
-		return nil, 0, C.DB_OPERATION_ERROR(e)
+	paging *model.Paging,
+) ([]*model.Patient, *model.PageInfo, error) {
+	if r, p, e := rds.ListPatients(s.DB, hospitalId, begin, end, paging); e != nil {
+		return nil, nil, listError(e)
+	} else if e := newPHIKeyring(s.DB).revealPatients(r...); e != nil {
+		return nil, nil, e
 	} else {
-		return r, t, nil
+		return r, p, nil
 	}
 }
 
@@ -50,6 +51,8 @@ func (s *PatientService) Fetch(
 			fmt.Sprintf("Patient %d is not found", id),
 			map[string]interface{}{},
 		)
+	} else if e := newPHIKeyring(s.DB).revealPatients(r); e != nil {
+		return nil, e
 	} else {
 		return r, nil
 	}
@@ -142,12 +145,23 @@ func (s *PatientTxService) Update(
 	patient.Memo = memo
 	patient.ModifiedAt = time.Now()
 
+	// 氏名は患者の鍵で暗号化して保存する。
+	keyring := newPHIKeyring(s.DB)
+
+	if e := keyring.sealPatient(patient); e != nil {
+		return nil, e
+	}
+
 	if _, e := s.DB.Update(patient); e != nil {
 		return nil, C.DB_OPERATION_ERROR(e)
 	}
 
 	patient, _ = rds.InquirePatient(s.DB, id)
 
+	if e := keyring.revealPatients(patient); e != nil {
+		return nil, e
+	}
+
 	return patient, nil
 }
 
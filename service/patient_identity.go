@@ -0,0 +1,360 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/resource/rds"
+)
+
+// 患者の識別子一覧を取得する。
+func (s *PatientService) ListIdentifiers(id int) ([]*model.PatientIdentifier, error) {
+	records, err := rds.ListPatientIdentifiers(s.DB, id)
+
+	if err != nil {
+		return nil, C.DB_OPERATION_ERROR(err)
+	}
+
+	if e := newPHIKeyring(s.DB).revealIdentifiers(records...); e != nil {
+		return nil, e
+	}
+
+	return records, nil
+}
+
+// 患者に識別子を登録する。病院内で既に登録されている識別子は登録できない。
+func (s *PatientTxService) AddIdentifier(
+	id int,
+	hospitalId int,
+	value string,
+) (*model.PatientIdentifier, error) {
+	keyring := newPHIKeyring(s.DB)
+
+	if r, e := inquireIdentifier(s.DB, keyring, hospitalId, value); e != nil {
+		return nil, e
+	} else if r != nil {
+		return nil, C.NewBadRequestError(
+			"identifier_already_registered",
+			fmt.Sprintf("Identifier is already registered to patient %d", r.PatientId),
+			map[string]interface{}{},
+		)
+	}
+
+	sealed, err := keyring.sealCode(hospitalId, value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	record := &model.PatientIdentifier{
+		HospitalId: hospitalId,
+		PatientId:  id,
+		Value:      sealed,
+		CreatedAt:  time.Now(),
+	}
+
+	if e := s.DB.Insert(record); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	record.Value = value
+
+	return record, nil
+}
+
+// 患者の識別子を削除する。
+func (s *PatientTxService) RemoveIdentifier(
+	id int,
+	identifierId int,
+) error {
+	if r, e := rds.FetchPatientIdentifier(s.DB, identifierId); e != nil {
+		return C.DB_OPERATION_ERROR(e)
+	} else if r == nil || r.PatientId != id {
+		return C.NewNotFoundError(
+			"identifier_not_found",
+			fmt.Sprintf("Identifier %d is not found", identifierId),
+			map[string]interface{}{},
+		)
+	} else if _, e := s.DB.Delete(r); e != nil {
+		return C.DB_OPERATION_ERROR(e)
+	}
+
+	return nil
+}
+
+// 重複して登録された患者を統合する。
+//
+// mergedIdの患者の計測と識別子をidの患者に移し、mergedIdの患者を削除する。
+// 統合先に値が無い項目は統合元の値を引き継ぎ、統合したことと引き継いだ項目を記録する。
+func (s *PatientTxService) Merge(
+	me *model.HospitalDoctor,
+	id int,
+	mergedId int,
+) (*model.PatientMerge, error) {
+	return s.merge(me.Hospital.Id, &me.Doctor.Id, id, mergedId)
+}
+
+func (s *PatientTxService) merge(
+	hospitalId int,
+	doctorId *int,
+	id int,
+	mergedId int,
+) (*model.PatientMerge, error) {
+	if id == mergedId {
+		return nil, C.NewBadRequestError(
+			"patient_merge_same",
+			fmt.Sprintf("Patient %d cannot be merged into itself", id),
+			map[string]interface{}{},
+		)
+	}
+
+	patients := []*model.Patient{}
+
+	for _, pid := range []int{id, mergedId} {
+		if r, e := rds.InquirePatient(s.DB, pid); e != nil {
+			return nil, C.DB_OPERATION_ERROR(e)
+		} else if r == nil || r.HospitalId != hospitalId {
+			return nil, C.NewNotFoundError(
+				"patient_not_found",
+				fmt.Sprintf("Patient %d is not found", pid),
+				map[string]interface{}{},
+			)
+		} else {
+			patients = append(patients, r)
+		}
+	}
+
+	patient, merged := patients[0], patients[1]
+
+	// 氏名は患者毎の鍵で暗号化されているため、復号してから統合先の鍵で暗号化し直す。
+	keyring := newPHIKeyring(s.DB)
+
+	if e := keyring.revealPatients(patient, merged); e != nil {
+		return nil, e
+	}
+
+	fields := []string{}
+
+	take := func(name string, absent bool, copy func()) {
+		if absent {
+			copy()
+			fields = append(fields, name)
+		}
+	}
+
+	take("name", patient.Name == nil && merged.Name != nil, func() { patient.Name = merged.Name })
+	take("age", patient.Age == nil && merged.Age != nil, func() { patient.Age = merged.Age })
+	take("numChildren", patient.NumChildren == nil && merged.NumChildren != nil, func() { patient.NumChildren = merged.NumChildren })
+	take("cesareanScar", patient.CesareanScar == nil && merged.CesareanScar != nil, func() { patient.CesareanScar = merged.CesareanScar })
+	take("deliveryTime", patient.DeliveryTime == nil && merged.DeliveryTime != nil, func() { patient.DeliveryTime = merged.DeliveryTime })
+	take("bloodLoss", patient.BloodLoss == nil && merged.BloodLoss != nil, func() { patient.BloodLoss = merged.BloodLoss })
+	take("birthWeight", patient.BirthWeight == nil && merged.BirthWeight != nil, func() { patient.BirthWeight = merged.BirthWeight })
+	take("birthDatetime", patient.BirthDatetime == nil && merged.BirthDatetime != nil, func() { patient.BirthDatetime = merged.BirthDatetime })
+	take("gestationalDays", patient.GestationalDays == nil && merged.GestationalDays != nil, func() { patient.GestationalDays = merged.GestationalDays })
+	take("apgarScore1min", patient.ApgarScore1Min == nil && merged.ApgarScore1Min != nil, func() { patient.ApgarScore1Min = merged.ApgarScore1Min })
+	take("apgarScore5min", patient.ApgarScore5Min == nil && merged.ApgarScore5Min != nil, func() { patient.ApgarScore5Min = merged.ApgarScore5Min })
+	take("umbilicalBlood", patient.UmbilicalBlood == nil && merged.UmbilicalBlood != nil, func() { patient.UmbilicalBlood = merged.UmbilicalBlood })
+	take("emergencyCesarean", patient.EmergencyCesarean == nil && merged.EmergencyCesarean != nil, func() { patient.EmergencyCesarean = merged.EmergencyCesarean })
+	take("instrumentalLabor", patient.InstrumentalLabor == nil && merged.InstrumentalLabor != nil, func() { patient.InstrumentalLabor = merged.InstrumentalLabor })
+	take("memo", patient.Memo == "" && merged.Memo != "", func() { patient.Memo = merged.Memo })
+
+	now := time.Now()
+
+	patient.ModifiedAt = now
+
+	if e := keyring.sealPatient(patient); e != nil {
+		return nil, e
+	}
+
+	if _, e := s.DB.Update(patient); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	count, err := rds.MovePatientMeasurements(s.DB, mergedId, id)
+
+	if err != nil {
+		return nil, C.DB_OPERATION_ERROR(err)
+	}
+
+	if e := rds.DeleteMergedPatient(s.DB, id, mergedId); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	bs, err := json.Marshal(fields)
+
+	if err != nil {
+		return nil, err
+	}
+
+	record := &model.PatientMerge{
+		HospitalId:      hospitalId,
+		PatientId:       id,
+		MergedPatientId: mergedId,
+		DoctorId:        doctorId,
+		Measurements:    int(count),
+		Fields:          model.JSON(bs),
+		MergedAt:        now,
+	}
+
+	if e := s.DB.Insert(record); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	return record, nil
+}
+
+// 計測を病院内の既存の患者に紐付ける。
+func (s *MeasurementTxService) AttachPatient(
+	id int,
+	patientId int,
+	hospitalId int,
+) (*model.Measurement, error) {
+	if r, e := rds.InquirePatient(s.DB, patientId); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else if r == nil || r.HospitalId != hospitalId {
+		return nil, C.NewNotFoundError(
+			"patient_not_found",
+			fmt.Sprintf("Patient %d is not found", patientId),
+			map[string]interface{}{},
+		)
+	}
+
+	mm, err := rds.InquireMeasurement(s.DB, id)
+
+	if err != nil {
+		return nil, C.DB_OPERATION_ERROR(err)
+	} else if mm == nil {
+		return nil, C.NewNotFoundError(
+			"measurement_not_found",
+			fmt.Sprintf("Measurement %d is not found", id),
+			map[string]interface{}{},
+		)
+	}
+
+	mm.PatientId = patientId
+	mm.ModifiedAt = time.Now()
+
+	if _, e := s.DB.Update(mm); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	if e := newPHIKeyring(s.DB).revealMeasurements(mm); e != nil {
+		return nil, e
+	}
+
+	return mm, nil
+}
+
+// 病院の患者の識別規則を取得する。設定されていない場合はnilを返す。
+func (s *HospitalService) FetchPatientRule(id int) (*string, error) {
+	if _, e := inquireHospital(s.DB, id); e != nil {
+		return nil, e
+	}
+
+	if r, e := rds.FetchHospitalPatientRule(s.DB, id); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else if r == nil {
+		return nil, nil
+	} else {
+		return &r.Pattern, nil
+	}
+}
+
+// 病院の患者の識別規則を設定する。nilの場合は設定を削除する。
+func (s *HospitalTxService) UpdatePatientRule(id int, pattern *string) (*string, error) {
+	if _, e := inquireHospital(s.DB, id); e != nil {
+		return nil, e
+	}
+
+	if pattern != nil {
+		if _, e := regexp.Compile(*pattern); e != nil {
+			return nil, C.NewBadRequestError(
+				"invalid_patient_rule",
+				fmt.Sprintf("Pattern is not a valid regular expression: %v", e),
+				map[string]interface{}{},
+			)
+		}
+	}
+
+	if _, e := s.DB.Exec(`DELETE FROM hospital_patient_rule WHERE hospital_id = $1`, id); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	if pattern != nil {
+		if e := s.DB.Insert(&model.HospitalPatientRule{
+			HospitalId: id,
+			Pattern: *pattern,
+			ModifiedAt: time.Now(),
+		}); e != nil {
+			return nil, C.DB_OPERATION_ERROR(e)
+		}
+	}
+
+	return pattern, nil
+}
+
+// 病院の識別規則に従い、計測コードから患者の識別子を抽出する。
+// 規則に一致したコードのみ、コードから識別子へのマップに含める。
+func extractPatientIdentifiers(
+	db model.QueryExecutor,
+	hospitalId int,
+	codes []string,
+) (map[string]string, error) {
+	identifiers := map[string]string{}
+
+	if len(codes) == 0 {
+		return identifiers, nil
+	}
+
+	rule, err := rds.FetchHospitalPatientRule(db, hospitalId)
+
+	if err != nil {
+		return nil, C.DB_OPERATION_ERROR(err)
+	} else if rule == nil {
+		return identifiers, nil
+	}
+
+	pattern, err := regexp.Compile(rule.Pattern)
+
+	if err != nil {
+		return nil, fmt.Errorf("Patient rule of hospital %d is invalid: %v", hospitalId, err)
+	}
+
+	for _, c := range codes {
+		if m := pattern.FindStringSubmatch(c); m == nil {
+			continue
+		} else if len(m) > 1 && m[1] != "" {
+			identifiers[c] = m[1]
+		} else if m[0] != "" {
+			identifiers[c] = m[0]
+		}
+	}
+
+	return identifiers, nil
+}
+
+// 病院内で登録済みの識別子を値から取得する。登録されていない場合はnilを返す。
+func inquireIdentifier(
+	db model.QueryExecutor,
+	keyring *phiKeyring,
+	hospitalId int,
+	value string,
+) (*model.PatientIdentifier, error) {
+	candidates, _, err := keyring.codeCandidates(hospitalId, []string{value})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if r, e := rds.InquirePatientIdentifiersByValues(db, hospitalId, candidates); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else if len(r) == 0 {
+		return nil, nil
+	} else {
+		return r[0], nil
+	}
+}
@@ -0,0 +1,638 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gopkg.in/gorp.v2"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/lib/phi"
+	"github.com/spiker/spiker-server/lib/search"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/resource/rds"
+	"github.com/spiker/spiker-server/resource/storage"
+)
+
+// 患者情報の暗号鍵を取得、生成する。取得した鍵は破棄されるまで保持する。
+//
+// 計測コードは端末から送られる値で検索するため、病院の鍵で決定的に暗号化する。
+// 患者の氏名は患者毎の鍵で暗号化し、鍵を削除することで復号できなくする。
+// 暗号化されていない値は、暗号化の導入前に登録された値としてそのまま扱う。
+type phiKeyring struct {
+	db        model.QueryExecutor
+	hospitals map[int][]byte
+	patients  map[int][]byte
+}
+
+func newPHIKeyring(db model.QueryExecutor) *phiKeyring {
+	return &phiKeyring{db, map[int][]byte{}, map[int][]byte{}}
+}
+
+// 病院の鍵を取得する。createがtrueの場合は無ければ生成し、falseの場合は無ければnilを返す。
+func (k *phiKeyring) hospitalKey(hospitalId int, create bool) ([]byte, error) {
+	if key, be := k.hospitals[hospitalId]; be {
+		return key, nil
+	}
+
+	record, err := rds.FetchHospitalKey(k.db, hospitalId)
+
+	if err != nil {
+		return nil, C.DB_OPERATION_ERROR(err)
+	}
+
+	if record == nil {
+		if !create {
+			return nil, nil
+		}
+
+		key, err := phi.GenerateKey()
+
+		if err != nil {
+			return nil, err
+		}
+
+		wrapped, err := phi.WrapKey(key)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if e := rds.InsertHospitalKeyIfAbsent(k.db, &model.HospitalKey{hospitalId, wrapped, time.Now()}); e != nil {
+			return nil, C.DB_OPERATION_ERROR(e)
+		}
+
+		// 同時に生成された場合に備え、登録されている鍵を改めて取得する。
+		if r, e := rds.FetchHospitalKey(k.db, hospitalId); e != nil {
+			return nil, C.DB_OPERATION_ERROR(e)
+		} else {
+			record = r
+		}
+	}
+
+	key, err := phi.UnwrapKey(record.WrappedKey)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unwrap the key of hospital %d: %v", hospitalId, err)
+	}
+
+	k.hospitals[hospitalId] = key
+
+	return key, nil
+}
+
+// 患者の鍵を取得する。createがtrueの場合は無ければ生成し、falseの場合は無ければnilを返す。
+func (k *phiKeyring) patientKey(patient *model.Patient, create bool) ([]byte, error) {
+	if key, be := k.patients[patient.Id]; be {
+		return key, nil
+	}
+
+	if e := k.loadPatientKeys(patient); e != nil {
+		return nil, e
+	}
+
+	if key, be := k.patients[patient.Id]; be || !create {
+		return key, nil
+	}
+
+	hospitalKey, err := k.hospitalKey(patient.HospitalId, true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := phi.GenerateKey()
+
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, err := phi.Seal(hospitalKey, string(key))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if e := rds.InsertPatientKeyIfAbsent(k.db, &model.PatientKey{patient.Id, wrapped, time.Now()}); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	delete(k.patients, patient.Id)
+
+	// 同時に生成された場合に備え、登録されている鍵を改めて取得する。
+	if e := k.loadPatientKeys(patient); e != nil {
+		return nil, e
+	}
+
+	return k.patients[patient.Id], nil
+}
+
+// 保持していない患者の鍵をまとめて取得する。鍵の無い患者はnilの鍵を保持する。
+func (k *phiKeyring) loadPatientKeys(patients ...*model.Patient) error {
+	ids := []int{}
+	hospitals := map[int]int{}
+
+	for _, p := range patients {
+		if _, be := k.patients[p.Id]; !be {
+			ids = append(ids, p.Id)
+			hospitals[p.Id] = p.HospitalId
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	records, err := rds.ListPatientKeys(k.db, ids)
+
+	if err != nil {
+		return C.DB_OPERATION_ERROR(err)
+	}
+
+	for _, id := range ids {
+		k.patients[id] = nil
+	}
+
+	for _, r := range records {
+		hospitalKey, err := k.hospitalKey(hospitals[r.PatientId], false)
+
+		if err != nil {
+			return err
+		} else if hospitalKey == nil {
+			return fmt.Errorf("Key of hospital %d is not found", hospitals[r.PatientId])
+		}
+
+		if key, e := phi.Open(hospitalKey, r.WrappedKey); e != nil {
+			return fmt.Errorf("Failed to unwrap the key of patient %d: %v", r.PatientId, e)
+		} else {
+			k.patients[r.PatientId] = []byte(key)
+		}
+	}
+
+	return nil
+}
+
+// 計測コードを暗号化する。暗号化が無効な場合はそのまま返す。
+func (k *phiKeyring) sealCode(hospitalId int, code string) (string, error) {
+	if !phi.Enabled() {
+		return code, nil
+	}
+
+	key, err := k.hospitalKey(hospitalId, true)
+
+	if err != nil {
+		return "", err
+	}
+
+	return phi.SealDeterministic(key, code)
+}
+
+// 計測コードの検索に用いる値と、その値から元のコードへのマップを作成する。
+// 暗号化の導入前に登録された計測も検索できるよう、暗号化した値と平文の両方を含める。
+func (k *phiKeyring) codeCandidates(hospitalId int, codes []string) ([]string, map[string]string, error) {
+	candidates := []string{}
+	origins := map[string]string{}
+
+	var key []byte = nil
+
+	if phi.Enabled() {
+		if r, e := k.hospitalKey(hospitalId, false); e != nil {
+			return nil, nil, e
+		} else {
+			key = r
+		}
+	}
+
+	for _, c := range codes {
+		candidates = append(candidates, c)
+		origins[c] = c
+
+		if key != nil {
+			if sealed, e := phi.SealDeterministic(key, c); e != nil {
+				return nil, nil, e
+			} else {
+				candidates = append(candidates, sealed)
+				origins[sealed] = c
+			}
+		}
+	}
+
+	return candidates, origins, nil
+}
+
+// 患者の氏名を暗号化し、検索用の索引を更新する。暗号化が無効な場合は何もしない。
+func (k *phiKeyring) sealPatient(patient *model.Patient) error {
+	if !phi.Enabled() || (patient.Name != nil && phi.IsSealed(*patient.Name)) {
+		return nil
+	}
+
+	if patient.Name == nil {
+		if e := rds.ReplacePatientNameTokens(k.db, patient.Id, []string{}); e != nil {
+			return C.DB_OPERATION_ERROR(e)
+		}
+
+		return nil
+	}
+
+	key, err := k.patientKey(patient, true)
+
+	if err != nil {
+		return err
+	}
+
+	tokens, err := k.nameTokens(patient.HospitalId, search.Grams(*patient.Name))
+
+	if err != nil {
+		return err
+	}
+
+	if e := rds.ReplacePatientNameTokens(k.db, patient.Id, tokens); e != nil {
+		return C.DB_OPERATION_ERROR(e)
+	}
+
+	if sealed, e := phi.Seal(key, *patient.Name); e != nil {
+		return e
+	} else {
+		patient.Name = &sealed
+	}
+
+	return nil
+}
+
+// 氏名の索引語から、病院の鍵による索引を作成する。鍵が無い場合は暗号化された氏名も無いため、空とする。
+func (k *phiKeyring) nameTokens(hospitalId int, grams []string) ([]string, error) {
+	key, err := k.hospitalKey(hospitalId, false)
+
+	if err != nil {
+		return nil, err
+	} else if key == nil {
+		return []string{}, nil
+	}
+
+	tokens := []string{}
+
+	for _, g := range grams {
+		tokens = append(tokens, phi.BlindIndex(key, g))
+	}
+
+	return tokens, nil
+}
+
+// 患者の氏名を復号する。鍵が削除された患者の氏名はnilとなる。
+func (k *phiKeyring) revealPatients(patients ...*model.Patient) error {
+	sealed := []*model.Patient{}
+
+	for _, p := range patients {
+		if p != nil && p.Name != nil && phi.IsSealed(*p.Name) {
+			sealed = append(sealed, p)
+		}
+	}
+
+	if e := k.loadPatientKeys(sealed...); e != nil {
+		return e
+	}
+
+	for _, p := range sealed {
+		key := k.patients[p.Id]
+
+		if key == nil {
+			p.Name = nil
+		} else if name, e := phi.Open(key, *p.Name); e != nil {
+			return e
+		} else {
+			p.Name = &name
+		}
+	}
+
+	return nil
+}
+
+// 計測コードを復号する。病院は患者から調べる。
+func (k *phiKeyring) revealMeasurements(measurements ...*model.Measurement) error {
+	sealed := []*model.Measurement{}
+	patientIds := []int{}
+
+	for _, m := range measurements {
+		if m != nil && phi.IsSealed(m.Code) {
+			sealed = append(sealed, m)
+			patientIds = append(patientIds, m.PatientId)
+		}
+	}
+
+	if len(sealed) == 0 {
+		return nil
+	}
+
+	hospitals, err := rds.MapPatientHospitals(k.db, patientIds)
+
+	if err != nil {
+		return C.DB_OPERATION_ERROR(err)
+	}
+
+	for _, m := range sealed {
+		if key, e := k.hospitalKey(hospitals[m.PatientId], false); e != nil {
+			return e
+		} else if key == nil {
+			return fmt.Errorf("Key of hospital %d is not found", hospitals[m.PatientId])
+		} else if code, e := phi.Open(key, m.Code); e != nil {
+			return e
+		} else {
+			m.Code = code
+		}
+	}
+
+	return nil
+}
+
+// 計測エンティティの計測コードと患者の氏名を復号する。
+func (k *phiKeyring) revealEntities(entities ...*model.MeasurementEntity) error {
+	measurements := []*model.Measurement{}
+	patients := []*model.Patient{}
+
+	for _, e := range entities {
+		measurements = append(measurements, e.Measurement)
+		patients = append(patients, e.Patient)
+	}
+
+	if e := k.revealMeasurements(measurements...); e != nil {
+		return e
+	}
+
+	return k.revealPatients(patients...)
+}
+
+// 自動診断イベントの計測コードを復号する。
+func (k *phiKeyring) revealComputedEvents(entities ...*model.ComputedEventEntity) error {
+	measurements := []*model.Measurement{}
+
+	for _, e := range entities {
+		measurements = append(measurements, e.Measurement)
+	}
+
+	return k.revealMeasurements(measurements...)
+}
+
+// アノテーションの計測コードを復号する。
+func (k *phiKeyring) revealAnnotatedEvents(entities ...*model.AnnotatedEventEntity) error {
+	measurements := []*model.Measurement{}
+
+	for _, e := range entities {
+		measurements = append(measurements, e.Measurement)
+	}
+
+	return k.revealMeasurements(measurements...)
+}
+
+// 患者の識別子を復号する。
+func (k *phiKeyring) revealIdentifiers(identifiers ...*model.PatientIdentifier) error {
+	for _, i := range identifiers {
+		if !phi.IsSealed(i.Value) {
+			continue
+		}
+
+		if key, e := k.hospitalKey(i.HospitalId, false); e != nil {
+			return e
+		} else if key == nil {
+			return fmt.Errorf("Key of hospital %d is not found", i.HospitalId)
+		} else if value, e := phi.Open(key, i.Value); e != nil {
+			return e
+		} else {
+			i.Value = value
+		}
+	}
+
+	return nil
+}
+
+type PatientErasureTxService struct {
+	*Service
+	DB *gorp.Transaction
+}
+
+// 患者を消去する。
+//
+// 患者の鍵を削除して暗号化された氏名を復号できなくした上で、患者と計測に関するレコードを削除し、消去したことのみを記録する。
+// InfluxDBの計測データ、スナップショット画像、計測データのアーカイブはトランザクション外のため、ここでは削除待ちとして登録し、
+// コミット後にMeasurementPurgeService.Purgeで削除する。
+func (s *PatientErasureTxService) Erase(
+	administratorId int,
+	hospitalId int,
+	patientId int,
+) (*model.PatientErasure, error) {
+	if r, e := rds.InquirePatient(s.DB, patientId); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else if r == nil || r.HospitalId != hospitalId {
+		return nil, C.NewNotFoundError(
+			"patient_not_found",
+			fmt.Sprintf("Patient %d is not found in hospital %d", patientId, hospitalId),
+			map[string]interface{}{},
+		)
+	}
+
+	measurementIds, err := rds.ListPatientMeasurementIds(s.DB, patientId)
+
+	if err != nil {
+		return nil, C.DB_OPERATION_ERROR(err)
+	}
+
+	if e := rds.ErasePatient(s.DB, patientId, measurementIds); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	now := time.Now()
+
+	purges := []interface{}{}
+
+	for _, id := range measurementIds {
+		purges = append(purges, &model.MeasurementPurge{MeasurementId: id, RequestedAt: now})
+	}
+
+	if len(purges) > 0 {
+		if e := s.DB.Insert(purges...); e != nil {
+			return nil, C.DB_OPERATION_ERROR(e)
+		}
+	}
+
+	record := &model.PatientErasure{
+		HospitalId:      hospitalId,
+		PatientId:       patientId,
+		AdministratorId: administratorId,
+		Measurements:    len(measurementIds),
+		ErasedAt:        now,
+	}
+
+	if e := s.DB.Insert(record); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	return record, nil
+}
+
+type MeasurementPurgeService struct {
+	*Service
+	DB      *gorp.DbMap
+	Influx  lib.InfluxDBClient
+	Storage storage.Storage
+}
+
+// 削除待ちの計測のInfluxDBの計測データ、スナップショット画像、計測データのアーカイブを削除し、削除できた件数を返す。
+// 削除は冪等なため、失敗した計測は削除待ちに残し、次回の実行で再び削除する。
+func (s *MeasurementPurgeService) Purge(limit int) (int, error) {
+	purges, err := rds.ListMeasurementPurges(s.DB, limit)
+
+	if err != nil {
+		return 0, C.DB_OPERATION_ERROR(err)
+	}
+
+	count := 0
+
+	for _, p := range purges {
+		if e := s.purge(p.MeasurementId); e != nil {
+			log.Printf("Failed to purge data of measurement %d: %v\n", p.MeasurementId, e)
+			continue
+		}
+
+		if e := rds.DeleteMeasurementPurge(s.DB, p.MeasurementId); e != nil {
+			return count, C.DB_OPERATION_ERROR(e)
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
+func (s *MeasurementPurgeService) purge(measurementId int) error {
+	if e := s.Influx.DeleteAll("spiker", fmt.Sprintf(`measurement_id="%d"`, measurementId)); e != nil {
+		return C.INFLUXDB_OPERATION_ERROR(e)
+	}
+
+	if s.Storage != nil {
+		if e := s.Storage.DeleteByPrefix(fmt.Sprintf("snapshots/%d/", measurementId)); e != nil {
+			return C.STORAGE_OPERATION_ERROR(e)
+		}
+
+		if e := s.Storage.DeleteByPrefix(archiveStorageKey(measurementId) + "/"); e != nil {
+			return C.STORAGE_OPERATION_ERROR(e)
+		}
+	}
+
+	return nil
+}
+
+type PHITagService struct {
+	*Service
+	DB     *gorp.DbMap
+	Influx lib.InfluxDBClient
+}
+
+// 患者情報のタグを除く前に記録された計測データから、患者情報のタグを除く。
+//
+// タグを持つレコードをタグを除いて登録し直した後、元のタグを持つ系列だけを削除する。
+// 途中で失敗しても、再度実行すれば残った系列が処理されるため、繰り返し実行できる。除いたレコード数を返す。
+func (s *PHITagService) Strip(measurementId int) (int, error) {
+	var mm *model.Measurement
+
+	if r, e := rds.InquireMeasurement(s.DB, measurementId); e != nil {
+		return 0, C.DB_OPERATION_ERROR(e)
+	} else if r == nil {
+		return 0, C.NewNotFoundError(
+			"measurement_not_found",
+			fmt.Sprintf("Measurement %d is not found", measurementId),
+			map[string]interface{}{},
+		)
+	} else {
+		mm = r
+	}
+
+	records, begin, end, err := listMeasurementData(s.Influx, mm)
+
+	if err != nil {
+		return 0, err
+	}
+
+	points := []lib.Point{}
+	codes := map[string]bool{}
+
+	for _, r := range records {
+		code := r.Tags["patient_code"]
+
+		if code == "" {
+			continue
+		}
+
+		if record, e := newArchivedRecord(r); e != nil {
+			return 0, e
+		} else if value, e := record.value(); e != nil {
+			return 0, e
+		} else {
+			points = append(points, &archivedPoint{record, value})
+		}
+
+		codes[code] = true
+	}
+
+	if len(points) == 0 {
+		return 0, nil
+	}
+
+	if errors := s.Influx.Insert("spiker", model.OpaquePoints(points...)...); len(errors) > 0 {
+		return 0, C.INFLUXDB_OPERATION_ERROR(errors[0])
+	}
+
+	for code := range codes {
+		predicate := fmt.Sprintf(`measurement_id="%d" AND patient_code="%s"`, measurementId, strings.ReplaceAll(code, `"`, `\"`))
+
+		if e := s.Influx.Delete("spiker", begin, end, predicate); e != nil {
+			return 0, C.INFLUXDB_OPERATION_ERROR(e)
+		}
+	}
+
+	return len(points), nil
+}
+
+type PatientNameIndexService struct {
+	*Service
+	DB *gorp.DbMap
+}
+
+// 索引の導入前に暗号化された患者の氏名に、検索用の索引を作成する。
+// afterIdより大きいIDの患者から最大limit人を処理し、最後に処理した患者のIDと、索引を作成した人数を返す。
+// 処理する患者が無い場合、IDはafterIdのままとなる。
+func (s *PatientNameIndexService) Index(afterId int, limit int) (int, int, error) {
+	patients, err := rds.ListSealedPatientsWithoutNameTokens(s.DB, afterId, limit)
+
+	if err != nil {
+		return afterId, 0, C.DB_OPERATION_ERROR(err)
+	}
+
+	keyring := newPHIKeyring(s.DB)
+
+	if e := keyring.revealPatients(patients...); e != nil {
+		return afterId, 0, e
+	}
+
+	count := 0
+
+	for _, p := range patients {
+		afterId = p.Id
+
+		// 鍵が削除された患者の氏名は復号できない。
+		if p.Name == nil {
+			continue
+		}
+
+		if tokens, e := keyring.nameTokens(p.HospitalId, search.Grams(*p.Name)); e != nil {
+			return afterId, count, e
+		} else if e := rds.ReplacePatientNameTokens(s.DB, p.Id, tokens); e != nil {
+			return afterId, count, C.DB_OPERATION_ERROR(e)
+		}
+
+		count++
+	}
+
+	return afterId, count, nil
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/gorp.v2"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/lib/strip"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/resource/influxdb"
+	"github.com/spiker/spiker-server/resource/rds"
+)
+
+type ReportService struct {
+	*Service
+	DB     *gorp.DbMap
+	Influx lib.InfluxDBClient
+}
+
+// 計測の指定期間をCTG記録紙としてPDFに出力する。
+//
+// beginを省略した場合は計測の最初のデータ日時、endを省略した場合は最後のデータ日時までとする。
+// 日時はlocationのタイムゾーンで記載する。
+func (s *ReportService) RenderStrip(
+	measurementId int,
+	begin *time.Time,
+	end *time.Time,
+	speed int,
+	location *time.Location,
+) ([]byte, error) {
+	chart, err := s.collectChart(measurementId, begin, end)
+
+	if err != nil {
+		return nil, err
+	}
+
+	chart.Location = location
+
+	return strip.RenderPDF(chart, speed)
+}
+
+// 記録紙に描画する内容を収集する。
+func (s *ReportService) collectChart(
+	measurementId int,
+	begin *time.Time,
+	end *time.Time,
+) (*strip.Chart, error) {
+	var entity *model.MeasurementEntity
+
+	if r, e := rds.FetchMeasurment(s.DB, measurementId); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else if r == nil {
+		return nil, C.NewNotFoundError(
+			"measurement_not_found",
+			fmt.Sprintf("Measurement %d is not found", measurementId),
+			map[string]interface{}{},
+		)
+	} else if e := newPHIKeyring(s.DB).revealEntities(r); e != nil {
+		return nil, e
+	} else {
+		entity = r
+	}
+
+	if begin == nil {
+		begin = entity.FirstTime
+	}
+
+	if end == nil {
+		_, end = measurementDataRange(entity.Measurement)
+	}
+
+	if begin == nil || end == nil {
+		return nil, C.NewBadRequestError(
+			"no_data",
+			fmt.Sprintf("Measurement %d has no data", measurementId),
+			map[string]interface{}{},
+		)
+	}
+
+	if !begin.Before(*end) {
+		return nil, C.NewBadRequestError(
+			"invalid_range",
+			"Report range must not be empty",
+			map[string]interface{}{},
+		)
+	} else if end.Sub(*begin) > C.ReportMaximumDuration {
+		return nil, C.NewBadRequestError(
+			"range_too_long",
+			fmt.Sprintf("Report range must be within %s", C.ReportMaximumDuration),
+			map[string]interface{}{},
+		)
+	}
+
+	chart := &strip.Chart{
+		Title: fmt.Sprintf("CTG記録 %s", entity.Code),
+		Headers: measurementHeaders(entity),
+		Begin: *begin,
+		End: *end,
+		HeartRates: []strip.Sample{},
+		TOCOs: []strip.Sample{},
+		ComputedEvents: []strip.Event{},
+		AnnotatedEvents: []strip.Event{},
+		Risks: []strip.RiskRange{},
+		CreatedAt: time.Now(),
+	}
+
+	if entity.ClosingMemo != nil && *entity.ClosingMemo != "" {
+		chart.Memo = fmt.Sprintf("終了メモ: %s", *entity.ClosingMemo)
+	}
+
+	if values, e := influxdb.ListHeartRate(s.Influx, measurementId, *begin, *end); e != nil {
+		return nil, C.INFLUXDB_OPERATION_ERROR(e)
+	} else {
+		for _, v := range values {
+			chart.HeartRates = append(chart.HeartRates, strip.Sample{Value: v.Value, Timestamp: v.Timestamp})
+		}
+	}
+
+	if values, e := influxdb.ListTOCO(s.Influx, measurementId, *begin, *end); e != nil {
+		return nil, C.INFLUXDB_OPERATION_ERROR(e)
+	} else {
+		for _, v := range values {
+			chart.TOCOs = append(chart.TOCOs, strip.Sample{Value: v.Value, Timestamp: v.Timestamp})
+		}
+	}
+
+	if events, e := rds.ListComputedEventsInRange(s.DB, measurementId, *begin, *end, false); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else {
+		for _, ev := range events {
+			chart.ComputedEvents = append(chart.ComputedEvents, strip.Event{
+				Label: riskLabel(ev.Risk),
+				Risk: ev.Risk,
+				RangeFrom: ev.RangeFrom,
+				RangeUntil: ev.RangeUntil,
+			})
+		}
+	}
+
+	if events, e := rds.ListAnnotatedEventsInRange(s.DB, measurementId, *begin, *end); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else {
+		for _, ev := range events {
+			chart.AnnotatedEvents = append(chart.AnnotatedEvents, strip.Event{
+				Label: riskLabel(ev.Risk),
+				Risk: ev.Risk,
+				RangeFrom: ev.RangeFrom,
+				RangeUntil: ev.RangeUntil,
+			})
+		}
+	}
+
+	if diagnoses, e := rds.ListDiagnoses(s.DB, measurementId); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else {
+		chart.Risks = riskRanges(diagnoses, *begin, *end)
+	}
+
+	return chart, nil
+}
+
+// 記録紙のヘッダに記載する患者と端末の情報。
+func measurementHeaders(entity *model.MeasurementEntity) []string {
+	patient := entity.Patient
+
+	name := "-"
+	if patient.Name != nil && *patient.Name != "" {
+		name = *patient.Name
+	}
+
+	line := fmt.Sprintf("患者: %s (ID %d)", name, patient.Id)
+
+	if patient.Age != nil {
+		line += fmt.Sprintf("  年齢: %d", *patient.Age)
+	}
+
+	if patient.GestationalDays != nil {
+		line += fmt.Sprintf("  妊娠週数: %d週%d日", *patient.GestationalDays / 7, *patient.GestationalDays % 7)
+	}
+
+	return []string{
+		line,
+		fmt.Sprintf("端末: %s (%s)  計測: %s", entity.Terminal.Name, entity.Terminal.Code, entity.Code),
+	}
+}
+
+func riskLabel(risk *int) string {
+	if risk == nil {
+		return ""
+	}
+	return fmt.Sprintf("R%d", *risk)
+}
+
+// 期間に重なる診断の最大リスクを区間とする。新しい診断を後に並べ、重なる部分は新しい診断で上書きされるようにする。
+func riskRanges(
+	diagnoses []*model.DiagnosisEntity,
+	begin time.Time,
+	end time.Time,
+) []strip.RiskRange {
+	results := []strip.RiskRange{}
+
+	for i := len(diagnoses) - 1; i >= 0; i-- {
+		d := diagnoses[i]
+
+		if d.MaximumRisk == nil || !d.RangeFrom.Before(end) || !d.RangeUntil.After(begin) {
+			continue
+		}
+
+		results = append(results, strip.RiskRange{Risk: *d.MaximumRisk, RangeFrom: d.RangeFrom, RangeUntil: d.RangeUntil})
+	}
+
+	return results
+}
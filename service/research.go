@@ -0,0 +1,787 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/gorp.v2"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/resource/rds"
+	"github.com/spiker/spiker-server/resource/storage"
+)
+
+type ResearchExportService struct {
+	*Service
+	DB      *gorp.DbMap
+	Influx  lib.InfluxDBClient
+	Storage storage.Storage
+}
+
+type ResearchExportTxService struct {
+	*Service
+	DB *gorp.Transaction
+}
+
+// 出力ジョブを新しい順に取得する。
+func (s *ResearchExportService) List(limit int, offset int) ([]*model.ResearchExportEntity, int64, error) {
+	records, total, err := rds.ListResearchExports(s.DB, limit, offset)
+
+	if err != nil {
+		return nil, 0, C.DB_OPERATION_ERROR(err)
+	}
+
+	results := []*model.ResearchExportEntity{}
+
+	for _, r := range records {
+		if entity, e := s.entity(r); e != nil {
+			return nil, 0, e
+		} else {
+			results = append(results, entity)
+		}
+	}
+
+	return results, total, nil
+}
+
+// 出力ジョブをIDから取得する。完了したジョブはデータセットの署名付きURLを持つ。
+func (s *ResearchExportService) Fetch(id int) (*model.ResearchExportEntity, error) {
+	record, err := inquireResearchExport(s.DB, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.entity(record)
+}
+
+func (s *ResearchExportService) entity(record *model.ResearchExport) (*model.ResearchExportEntity, error) {
+	entity := &model.ResearchExportEntity{record, nil}
+
+	if s.Storage != nil && record.StorageKey != nil && record.State == string(C.ResearchExportCompleted) {
+		if url, e := s.Storage.PresignedUrl(*record.StorageKey); e != nil {
+			return nil, C.STORAGE_OPERATION_ERROR(e)
+		} else {
+			entity.DownloadUrl = &url
+		}
+	}
+
+	return entity, nil
+}
+
+// 未実行の出力ジョブを実行中として取得する。他のプロセスが実行中としたジョブは含まれない。
+func (s *ResearchExportService) Claim(now time.Time) ([]*model.ResearchExport, error) {
+	records, err := rds.ListPendingResearchExports(s.DB)
+
+	if err != nil {
+		return nil, C.DB_OPERATION_ERROR(err)
+	}
+
+	results := []*model.ResearchExport{}
+
+	for _, r := range records {
+		if ok, e := rds.ClaimResearchExport(s.DB, r.Id, now); e != nil {
+			return nil, C.DB_OPERATION_ERROR(e)
+		} else if ok {
+			r.State = string(C.ResearchExportRunning)
+			r.StartedAt = &now
+			r.ModifiedAt = now
+			results = append(results, r)
+		}
+	}
+
+	return results, nil
+}
+
+// 実行中の出力ジョブについて、データセットを作成してストレージに保存する。
+//
+// 患者、計測、病院のIDはsecretを鍵とする仮名に置き換え、日時は患者毎に-shiftDaysからshiftDaysの範囲の日数だけずらす。
+// 仮名とずらす日数はsecretとIDから決まるため、同じsecretで出力したデータセット間では一致する。
+// 失敗した場合はジョブを失敗とし、エラー内容を記録する。
+func (s *ResearchExportService) Execute(job *model.ResearchExport, secret string, shiftDays int) error {
+	if s.Storage == nil {
+		return s.finish(job, nil, fmt.Errorf("Storage is not configured"))
+	}
+
+	if secret == "" {
+		return s.finish(job, nil, fmt.Errorf("Research secret is not configured"))
+	}
+
+	hospitalIds := []int{}
+
+	if e := json.Unmarshal([]byte(job.HospitalIds), &hospitalIds); e != nil {
+		return s.finish(job, nil, e)
+	}
+
+	measurements, err := rds.ListResearchMeasurements(s.DB, hospitalIds, job.RangeFrom, job.RangeUntil)
+
+	if err != nil {
+		return s.finish(job, nil, C.DB_OPERATION_ERROR(err))
+	}
+
+	bundle := newResearchBundle(&researchPseudonymizer{[]byte(secret), shiftDays})
+
+	for _, m := range measurements {
+		if e := s.collect(bundle, m, job.RangeFrom, job.RangeUntil); e != nil {
+			return s.finish(job, nil, e)
+		}
+	}
+
+	content, err := bundle.Bytes(job, time.Now())
+
+	if err != nil {
+		return s.finish(job, nil, err)
+	}
+
+	key := fmt.Sprintf("research/%d.zip", job.Id)
+
+	if e := s.Storage.Put(key, content); e != nil {
+		return s.finish(job, nil, C.STORAGE_OPERATION_ERROR(e))
+	}
+
+	job.Patients = len(bundle.patients)
+	job.Measurements = len(bundle.measurements)
+
+	return s.finish(job, &key, nil)
+}
+
+// 出力ジョブを完了もしくは失敗とする。失敗した場合は元のエラーを返す。
+func (s *ResearchExportService) finish(job *model.ResearchExport, key *string, cause error) error {
+	now := time.Now()
+
+	job.StorageKey = key
+	job.CompletedAt = &now
+	job.ModifiedAt = now
+
+	if cause != nil {
+		message := cause.Error()
+		job.State = string(C.ResearchExportFailed)
+		job.Error = &message
+	} else {
+		job.State = string(C.ResearchExportCompleted)
+		job.Error = nil
+	}
+
+	if _, e := s.DB.Update(job); e != nil {
+		return C.DB_OPERATION_ERROR(e)
+	}
+
+	return cause
+}
+
+// 計測の出力期間内の信号、診断、イベントをデータセットに追加する。
+func (s *ResearchExportService) collect(
+	bundle *researchBundle,
+	entity *model.MeasurementEntity,
+	rangeFrom *time.Time,
+	rangeUntil *time.Time,
+) error {
+	first, last := measurementDataRange(entity.Measurement)
+
+	begin := *first
+	end := *last
+
+	if rangeFrom != nil && rangeFrom.After(begin) {
+		begin = *rangeFrom
+	}
+	if rangeUntil != nil && rangeUntil.Before(end) {
+		end = *rangeUntil
+	}
+
+	begin = begin.Truncate(time.Second)
+
+	if !begin.Before(end) {
+		return nil
+	}
+
+	m := bundle.addMeasurement(entity, begin, end)
+
+	// 長時間の計測でメモリを圧迫しないよう、出力の上限期間毎に取得する。
+	export := &ExportService{s.Service, s.DB, s.Influx}
+
+	for from := begin; from.Before(end); from = from.Add(C.ExportMaximumDuration) {
+		until := from.Add(C.ExportMaximumDuration)
+
+		if until.After(end) {
+			until = end
+		}
+
+		if channels, e := export.collectChannels(entity.Id, from, until); e != nil {
+			return e
+		} else if e := bundle.addSignals(m, from, channels); e != nil {
+			return e
+		}
+	}
+
+	if diagnoses, e := rds.ListDiagnoses(s.DB, entity.Id); e != nil {
+		return C.DB_OPERATION_ERROR(e)
+	} else {
+		// 新しい順に取得されるため、古い順に追加する。
+		for i := len(diagnoses) - 1; i >= 0; i-- {
+			d := diagnoses[i]
+
+			if d.RangeFrom.Before(end) && !d.RangeUntil.Before(begin) {
+				bundle.addDiagnosis(m, d)
+			}
+		}
+	}
+
+	if events, e := rds.ListComputedEventsInRange(s.DB, entity.Id, begin, end, false); e != nil {
+		return C.DB_OPERATION_ERROR(e)
+	} else {
+		for i := len(events) - 1; i >= 0; i-- {
+			bundle.addComputedEvent(m, events[i].ComputedEvent)
+		}
+	}
+
+	if events, e := rds.ListAnnotatedEventsInRange(s.DB, entity.Id, begin, end); e != nil {
+		return C.DB_OPERATION_ERROR(e)
+	} else {
+		for i := len(events) - 1; i >= 0; i-- {
+			bundle.addAnnotatedEvent(m, events[i].AnnotatedEvent)
+		}
+	}
+
+	return nil
+}
+
+// 出力ジョブを登録する。対象の病院は全て存在しなければならない。
+func (s *ResearchExportTxService) Create(
+	administratorId int,
+	hospitalIds []int,
+	rangeFrom *time.Time,
+	rangeUntil *time.Time,
+) (*model.ResearchExport, error) {
+	ids := []int{}
+	exists := map[int]bool{}
+
+	for _, id := range hospitalIds {
+		if exists[id] {
+			continue
+		}
+
+		if _, e := inquireHospital(s.DB, id); e != nil {
+			return nil, e
+		}
+
+		exists[id] = true
+		ids = append(ids, id)
+	}
+
+	values, err := json.Marshal(ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	record := &model.ResearchExport{
+		AdministratorId: administratorId,
+		HospitalIds: model.JSON(values),
+		RangeFrom: rangeFrom,
+		RangeUntil: rangeUntil,
+		State: string(C.ResearchExportPending),
+		CreatedAt: now,
+		ModifiedAt: now,
+	}
+
+	if e := s.DB.Insert(record); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	return record, nil
+}
+
+func inquireResearchExport(db model.QueryExecutor, id int) (*model.ResearchExport, error) {
+	if r, e := rds.InquireResearchExport(db, id); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else if r == nil {
+		return nil, C.NewNotFoundError(
+			"research_export_not_found",
+			fmt.Sprintf("Research export %d is not found", id),
+			map[string]interface{}{},
+		)
+	} else {
+		return r, nil
+	}
+}
+
+// IDを仮名に置き換え、日時をずらす。
+type researchPseudonymizer struct {
+	secret    []byte
+	shiftDays int
+}
+
+func (p *researchPseudonymizer) digest(kind string, id int) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte(fmt.Sprintf("%s:%d", kind, id)))
+	return mac.Sum(nil)
+}
+
+// 種別毎の接頭辞とHMACの先頭から仮名を作成する。
+func (p *researchPseudonymizer) pseudonym(prefix string, kind string, id int) string {
+	return prefix + hex.EncodeToString(p.digest(kind, id)[:8])
+}
+
+func (p *researchPseudonymizer) Patient(id int) string {
+	return p.pseudonym("P", "patient", id)
+}
+
+func (p *researchPseudonymizer) Measurement(id int) string {
+	return p.pseudonym("M", "measurement", id)
+}
+
+func (p *researchPseudonymizer) Hospital(id int) string {
+	return p.pseudonym("H", "hospital", id)
+}
+
+// 患者の日時をずらす期間。日単位とし、時刻と曜日以外の周期は保たれない。
+func (p *researchPseudonymizer) Shift(patientId int) time.Duration {
+	if p.shiftDays <= 0 {
+		return 0
+	}
+
+	n := binary.BigEndian.Uint64(p.digest("shift", patientId)[:8])
+	days := int(n%uint64(2*p.shiftDays+1)) - p.shiftDays
+
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// データセットの計測。
+type researchMeasurement struct {
+	entity    *model.MeasurementEntity
+	pseudonym string
+	shift     time.Duration
+	begin     time.Time
+	end       time.Time
+	signals   *bytes.Buffer
+	writer    *csv.Writer
+	rows      int
+}
+
+// データセット。計測、診断、イベントは追加した順に出力し、診断とイベントには出力内の連番を振る。
+type researchBundle struct {
+	pseudonymizer *researchPseudonymizer
+	patients      []*model.Patient
+	patientMap    map[int]bool
+	measurements  []*researchMeasurement
+	diagnoses     [][]string
+	computed      [][]string
+	computedMap   map[int]int
+	annotated     [][]string
+}
+
+func newResearchBundle(pseudonymizer *researchPseudonymizer) *researchBundle {
+	return &researchBundle{
+		pseudonymizer: pseudonymizer,
+		patients: []*model.Patient{},
+		patientMap: map[int]bool{},
+		measurements: []*researchMeasurement{},
+		diagnoses: [][]string{},
+		computed: [][]string{},
+		computedMap: map[int]int{},
+		annotated: [][]string{},
+	}
+}
+
+func researchTime(t *time.Time, shift time.Duration) string {
+	if t == nil {
+		return ""
+	}
+	return t.Add(shift).UTC().Format(time.RFC3339)
+}
+
+func researchInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
+
+func researchBool(v *bool) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatBool(*v)
+}
+
+func (b *researchBundle) addMeasurement(entity *model.MeasurementEntity, begin time.Time, end time.Time) *researchMeasurement {
+	if !b.patientMap[entity.Patient.Id] {
+		b.patientMap[entity.Patient.Id] = true
+		b.patients = append(b.patients, entity.Patient)
+	}
+
+	buf := &bytes.Buffer{}
+
+	m := &researchMeasurement{
+		entity: entity,
+		pseudonym: b.pseudonymizer.Measurement(entity.Id),
+		shift: b.pseudonymizer.Shift(entity.Patient.Id),
+		begin: begin,
+		end: end,
+		signals: buf,
+		writer: csv.NewWriter(buf),
+	}
+
+	header := []string{"time"}
+
+	for _, ch := range exportChannels {
+		header = append(header, strings.ToLower(ch.Label))
+	}
+
+	m.writer.Write(header)
+
+	b.measurements = append(b.measurements, m)
+
+	return m
+}
+
+// fromからの秒毎の値を信号に追加する。いずれのチャネルにも値の無い秒は出力しない。
+func (b *researchBundle) addSignals(m *researchMeasurement, from time.Time, channels [][]*int) error {
+	for i := range channels[0] {
+		t := from.Add(time.Duration(i) * time.Second)
+		row := []string{researchTime(&t, m.shift)}
+		present := false
+
+		for _, values := range channels {
+			row = append(row, researchInt(values[i]))
+			present = present || values[i] != nil
+		}
+
+		if !present {
+			continue
+		}
+
+		if e := m.writer.Write(row); e != nil {
+			return e
+		}
+
+		m.rows++
+	}
+
+	return nil
+}
+
+func (b *researchBundle) addDiagnosis(m *researchMeasurement, d *model.DiagnosisEntity) {
+	algorithm, version := "", ""
+
+	if d.Algorithm != nil {
+		algorithm, version = d.Algorithm.Name, d.Algorithm.Version
+	}
+
+	b.diagnoses = append(b.diagnoses, []string{
+		strconv.Itoa(len(b.diagnoses) + 1),
+		m.pseudonym,
+		algorithm,
+		version,
+		researchInt(d.BaselineBpm),
+		researchInt(d.MaximumRisk),
+		researchTime(&d.RangeFrom, m.shift),
+		researchTime(&d.RangeUntil, m.shift),
+	})
+}
+
+func (b *researchBundle) addComputedEvent(m *researchMeasurement, ev *model.ComputedEvent) {
+	number := len(b.computed) + 1
+	b.computedMap[ev.Id] = number
+
+	b.computed = append(b.computed, []string{
+		strconv.Itoa(number),
+		m.pseudonym,
+		researchInt(ev.Risk),
+		researchTime(&ev.RangeFrom, m.shift),
+		researchTime(&ev.RangeUntil, m.shift),
+	})
+}
+
+// アノテーションを追加する。メモは自由記述で個人を特定しうるため出力しない。
+func (b *researchBundle) addAnnotatedEvent(m *researchMeasurement, ev *model.AnnotatedEvent) {
+	computed := ""
+
+	if ev.ComputedEventId != nil {
+		if n, be := b.computedMap[*ev.ComputedEventId]; be {
+			computed = strconv.Itoa(n)
+		}
+	}
+
+	b.annotated = append(b.annotated, []string{
+		strconv.Itoa(len(b.annotated) + 1),
+		m.pseudonym,
+		computed,
+		researchInt(ev.Risk),
+		researchTime(&ev.RangeFrom, m.shift),
+		researchTime(&ev.RangeUntil, m.shift),
+	})
+}
+
+// データセットの列。
+type researchColumn struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Unit        string `json:"unit,omitempty"`
+	Description string `json:"description"`
+}
+
+// データセットのファイル。
+type researchFile struct {
+	Path        string            `json:"path"`
+	Description string            `json:"description"`
+	Rows        int               `json:"rows"`
+	Columns     []*researchColumn `json:"columns"`
+}
+
+// データセットの内容を記載するマニフェスト。
+type researchManifest struct {
+	Version      int             `json:"version"`
+	ExportId     int             `json:"exportId"`
+	CreatedAt    time.Time       `json:"createdAt"`
+	Hospitals    []string        `json:"hospitals"`
+	Patients     int             `json:"patients"`
+	Measurements int             `json:"measurements"`
+	TimeShift    string          `json:"timeShift"`
+	Files        []*researchFile `json:"files"`
+}
+
+var (
+	researchPatientColumns = []*researchColumn{
+		{"patient", "string", "", "Pseudonymous patient identifier"},
+		{"hospital", "string", "", "Pseudonymous hospital identifier"},
+		{"age", "integer", "year", "Maternal age"},
+		{"num_children", "integer", "", "Number of previous deliveries"},
+		{"cesarean_scar", "boolean", "", "Presence of a previous cesarean scar"},
+		{"delivery_time", "integer", "min", "Duration of labor"},
+		{"blood_loss", "integer", "mL", "Blood loss at delivery"},
+		{"birth_datetime", "datetime", "", "Time-shifted birth date and time (RFC 3339, UTC)"},
+		{"gestational_days", "integer", "day", "Gestational age at birth"},
+		{"birth_weight", "integer", "g", "Birth weight"},
+		{"apgar_score_1min", "integer", "", "Apgar score at 1 minute"},
+		{"apgar_score_5min", "integer", "", "Apgar score at 5 minutes"},
+		{"umbilical_blood", "integer", "", "Umbilical artery blood gas value as registered"},
+		{"emergency_cesarean", "boolean", "", "Emergency cesarean delivery"},
+		{"instrumental_labor", "boolean", "", "Instrumental delivery"},
+	}
+	researchMeasurementColumns = []*researchColumn{
+		{"measurement", "string", "", "Pseudonymous measurement identifier"},
+		{"patient", "string", "", "Pseudonymous patient identifier"},
+		{"hospital", "string", "", "Pseudonymous hospital identifier"},
+		{"begin", "datetime", "", "Time-shifted beginning of the exported signals (RFC 3339, UTC)"},
+		{"end", "datetime", "", "Time-shifted end of the exported signals, exclusive (RFC 3339, UTC)"},
+		{"is_closed", "boolean", "", "Whether the measurement was closed"},
+		{"signal_file", "string", "", "Path of the signal file in this bundle"},
+	}
+	researchDiagnosisColumns = []*researchColumn{
+		{"diagnosis", "integer", "", "Sequential number within this bundle"},
+		{"measurement", "string", "", "Pseudonymous measurement identifier"},
+		{"algorithm", "string", "", "Name of the diagnosis algorithm, empty for manual diagnoses"},
+		{"algorithm_version", "string", "", "Version of the diagnosis algorithm"},
+		{"baseline_bpm", "integer", "bpm", "Baseline fetal heart rate"},
+		{"maximum_risk", "integer", "", "Maximum risk level within the diagnosis range"},
+		{"range_from", "datetime", "", "Time-shifted beginning of the diagnosis range (RFC 3339, UTC)"},
+		{"range_until", "datetime", "", "Time-shifted end of the diagnosis range (RFC 3339, UTC)"},
+	}
+	researchComputedEventColumns = []*researchColumn{
+		{"event", "integer", "", "Sequential number within this bundle"},
+		{"measurement", "string", "", "Pseudonymous measurement identifier"},
+		{"risk", "integer", "", "Risk level"},
+		{"range_from", "datetime", "", "Time-shifted beginning of the event (RFC 3339, UTC)"},
+		{"range_until", "datetime", "", "Time-shifted end of the event (RFC 3339, UTC)"},
+	}
+	researchAnnotatedEventColumns = []*researchColumn{
+		{"annotation", "integer", "", "Sequential number within this bundle"},
+		{"measurement", "string", "", "Pseudonymous measurement identifier"},
+		{"event", "integer", "", "Number of the annotated computed event in computed_events.csv, empty if none"},
+		{"risk", "integer", "", "Risk level given by the annotator"},
+		{"range_from", "datetime", "", "Time-shifted beginning of the annotation (RFC 3339, UTC)"},
+		{"range_until", "datetime", "", "Time-shifted end of the annotation (RFC 3339, UTC)"},
+	}
+)
+
+func researchHeader(columns []*researchColumn) []string {
+	header := []string{}
+
+	for _, c := range columns {
+		header = append(header, c.Name)
+	}
+
+	return header
+}
+
+func researchSignalColumns() []*researchColumn {
+	columns := []*researchColumn{
+		{"time", "datetime", "", "Time-shifted time of the sample (RFC 3339, UTC)"},
+	}
+
+	for _, ch := range exportChannels {
+		columns = append(columns, &researchColumn{
+			strings.ToLower(ch.Label),
+			"integer",
+			ch.Unit,
+			fmt.Sprintf("%s (%s), empty if missing", ch.Label, ch.Transducer),
+		})
+	}
+
+	return columns
+}
+
+func writeResearchCSV(w io.Writer, columns []*researchColumn, rows [][]string) error {
+	writer := csv.NewWriter(w)
+
+	if e := writer.Write(researchHeader(columns)); e != nil {
+		return e
+	}
+
+	if e := writer.WriteAll(rows); e != nil {
+		return e
+	}
+
+	return writer.Error()
+}
+
+// データセットをzip形式で出力する。
+func (b *researchBundle) Bytes(job *model.ResearchExport, now time.Time) ([]byte, error) {
+	p := b.pseudonymizer
+
+	patients := [][]string{}
+
+	for _, r := range b.patients {
+		shift := p.Shift(r.Id)
+
+		patients = append(patients, []string{
+			p.Patient(r.Id),
+			p.Hospital(r.HospitalId),
+			researchInt(r.Age),
+			researchInt(r.NumChildren),
+			researchBool(r.CesareanScar),
+			researchInt(r.DeliveryTime),
+			researchInt(r.BloodLoss),
+			researchTime(r.BirthDatetime, shift),
+			researchInt(r.GestationalDays),
+			researchInt(r.BirthWeight),
+			researchInt(r.ApgarScore1Min),
+			researchInt(r.ApgarScore5Min),
+			researchInt(r.UmbilicalBlood),
+			researchBool(r.EmergencyCesarean),
+			researchBool(r.InstrumentalLabor),
+		})
+	}
+
+	measurements := [][]string{}
+
+	for _, m := range b.measurements {
+		measurements = append(measurements, []string{
+			m.pseudonym,
+			p.Patient(m.entity.Patient.Id),
+			p.Hospital(m.entity.Patient.HospitalId),
+			researchTime(&m.begin, m.shift),
+			researchTime(&m.end, m.shift),
+			strconv.FormatBool(m.entity.IsClosed),
+			fmt.Sprintf("signals/%s.csv", m.pseudonym),
+		})
+	}
+
+	hospitalIds := []int{}
+
+	if e := json.Unmarshal([]byte(job.HospitalIds), &hospitalIds); e != nil {
+		return nil, e
+	}
+
+	manifest := &researchManifest{
+		Version: C.ResearchExportVersion,
+		ExportId: job.Id,
+		CreatedAt: now.UTC(),
+		Hospitals: []string{},
+		Patients: len(b.patients),
+		Measurements: len(b.measurements),
+		TimeShift: "All times of a patient are shifted by the same whole number of days, which differs between patients. Durations and times of day are preserved.",
+		Files: []*researchFile{
+			{"patients.csv", "Patients and delivery outcomes", len(patients), researchPatientColumns},
+			{"measurements.csv", "CTG measurements", len(measurements), researchMeasurementColumns},
+			{"diagnoses.csv", "Diagnoses overlapping the exported signals", len(b.diagnoses), researchDiagnosisColumns},
+			{"computed_events.csv", "Events detected by diagnosis algorithms", len(b.computed), researchComputedEventColumns},
+			{"annotated_events.csv", "Events annotated by experts", len(b.annotated), researchAnnotatedEventColumns},
+		},
+	}
+
+	for _, id := range hospitalIds {
+		manifest.Hospitals = append(manifest.Hospitals, p.Hospital(id))
+	}
+
+	for _, m := range b.measurements {
+		manifest.Files = append(manifest.Files, &researchFile{
+			fmt.Sprintf("signals/%s.csv", m.pseudonym),
+			"Signals sampled every second, omitting seconds without any value",
+			m.rows,
+			researchSignalColumns(),
+		})
+	}
+
+	buf := &bytes.Buffer{}
+	w := zip.NewWriter(buf)
+
+	put := func(path string, write func(io.Writer) error) error {
+		if f, e := w.Create(path); e != nil {
+			return e
+		} else {
+			return write(f)
+		}
+	}
+
+	if e := put("manifest.json", func(f io.Writer) error {
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(manifest)
+	}); e != nil {
+		return nil, e
+	}
+
+	for _, f := range []struct {
+		path    string
+		columns []*researchColumn
+		rows    [][]string
+	}{
+		{"patients.csv", researchPatientColumns, patients},
+		{"measurements.csv", researchMeasurementColumns, measurements},
+		{"diagnoses.csv", researchDiagnosisColumns, b.diagnoses},
+		{"computed_events.csv", researchComputedEventColumns, b.computed},
+		{"annotated_events.csv", researchAnnotatedEventColumns, b.annotated},
+	} {
+		if e := put(f.path, func(w io.Writer) error { return writeResearchCSV(w, f.columns, f.rows) }); e != nil {
+			return nil, e
+		}
+	}
+
+	for _, m := range b.measurements {
+		m.writer.Flush()
+
+		if e := m.writer.Error(); e != nil {
+			return nil, e
+		}
+
+		if e := put(fmt.Sprintf("signals/%s.csv", m.pseudonym), func(w io.Writer) error {
+			_, e := w.Write(m.signals.Bytes())
+			return e
+		}); e != nil {
+			return nil, e
+		}
+	}
+
+	if e := w.Close(); e != nil {
+		return nil, e
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spiker/spiker-server/model"
+)
+
+func TestServiceResearch_researchPseudonymizer(t *testing.T) {
+	p := &researchPseudonymizer{[]byte("secret"), 30}
+
+	// 同じ鍵とIDからは同じ仮名となる。
+	assert.Equal(t, p.Patient(1), (&researchPseudonymizer{[]byte("secret"), 30}).Patient(1))
+	assert.NotEqual(t, p.Patient(1), (&researchPseudonymizer{[]byte("other"), 30}).Patient(1))
+	assert.NotEqual(t, p.Patient(1), p.Patient(2))
+
+	assert.True(t, strings.HasPrefix(p.Patient(1), "P"))
+	assert.True(t, strings.HasPrefix(p.Measurement(1), "M"))
+	assert.True(t, strings.HasPrefix(p.Hospital(1), "H"))
+	assert.Equal(t, 17, len(p.Patient(1)))
+
+	for id := 1; id <= 100; id++ {
+		shift := p.Shift(id)
+
+		assert.Equal(t, time.Duration(0), shift%(24*time.Hour))
+		assert.True(t, shift >= -30*24*time.Hour && shift <= 30*24*time.Hour)
+	}
+
+	assert.Equal(t, time.Duration(0), (&researchPseudonymizer{[]byte("secret"), 0}).Shift(1))
+}
+
+func TestServiceResearch_Bytes(t *testing.T) {
+	p := &researchPseudonymizer{[]byte("secret"), 30}
+	bundle := newResearchBundle(p)
+
+	begin := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	birth := begin.Add(time.Hour)
+	weight := 3000
+	risk := 4
+	computedId := 7
+
+	value := func(v int) *int {
+		return &v
+	}
+
+	name := "Name"
+
+	entity := &model.MeasurementEntity{
+		Measurement: &model.Measurement{Id: 3, Code: "m-0003", PatientId: 2},
+		Terminal: &model.MeasurementTerminal{Code: "t-0001"},
+		Patient: &model.Patient{Id: 2, HospitalId: 1, Name: &name, BirthDatetime: &birth, BirthWeight: &weight},
+	}
+
+	m := bundle.addMeasurement(entity, begin, begin.Add(3*time.Second))
+
+	assert.NoError(t, bundle.addSignals(m, begin, [][]*int{
+		{value(140), nil, value(142)},
+		{nil, nil, value(130)},
+		{value(10), nil, value(12)},
+	}))
+
+	bundle.addComputedEvent(m, &model.ComputedEvent{
+		Id: computedId, Risk: &risk, Memo: "memo", RangeFrom: begin, RangeUntil: begin.Add(time.Second),
+	})
+	bundle.addAnnotatedEvent(m, &model.AnnotatedEvent{
+		Id: 9, ComputedEventId: &computedId, Risk: &risk, Memo: "memo", RangeFrom: begin, RangeUntil: begin.Add(time.Second),
+	})
+
+	bs, err := bundle.Bytes(&model.ResearchExport{Id: 5, HospitalIds: model.JSON("[1]")}, begin)
+
+	assert.NoError(t, err)
+
+	reader, err := zip.NewReader(bytes.NewReader(bs), int64(len(bs)))
+
+	assert.NoError(t, err)
+
+	files := map[string]string{}
+
+	for _, f := range reader.File {
+		r, _ := f.Open()
+		content, _ := ioutil.ReadAll(r)
+		r.Close()
+		files[f.Name] = string(content)
+	}
+
+	shifted := func(t time.Time) string {
+		return t.Add(p.Shift(2)).Format(time.RFC3339)
+	}
+
+	manifest := &researchManifest{}
+
+	if assert.NoError(t, json.Unmarshal([]byte(files["manifest.json"]), manifest)) {
+		assert.Equal(t, 5, manifest.ExportId)
+		assert.Equal(t, []string{p.Hospital(1)}, manifest.Hospitals)
+		assert.Equal(t, 1, manifest.Patients)
+		assert.Equal(t, 1, manifest.Measurements)
+		assert.Equal(t, 6, len(manifest.Files))
+		assert.Equal(t, "signals/"+p.Measurement(3)+".csv", manifest.Files[5].Path)
+		assert.Equal(t, 2, manifest.Files[5].Rows)
+	}
+
+	assert.Equal(t, []string{
+		"time,fhr1,fhr2,uc",
+		// 値の無い秒は出力しない。
+		shifted(begin) + ",140,,10",
+		shifted(begin.Add(2*time.Second)) + ",142,130,12",
+	}, strings.Split(strings.TrimSpace(files["signals/"+p.Measurement(3)+".csv"]), "\n"))
+
+	patients := strings.Split(strings.TrimSpace(files["patients.csv"]), "\n")
+
+	assert.Equal(t, 2, len(patients))
+	assert.True(t, strings.HasPrefix(patients[1], p.Patient(2)+","+p.Hospital(1)+","))
+	assert.Contains(t, patients[1], shifted(birth))
+	assert.NotContains(t, patients[1], name)
+
+	assert.Equal(t, []string{
+		"annotation,measurement,event,risk,range_from,range_until",
+		"1," + p.Measurement(3) + ",1,4," + shifted(begin) + "," + shifted(begin.Add(time.Second)),
+	}, strings.Split(strings.TrimSpace(files["annotated_events.csv"]), "\n"))
+
+	// 元のIDやコード、メモは含まれない。
+	for path, content := range files {
+		assert.NotContains(t, content, "m-0003", path)
+		assert.NotContains(t, content, "memo", path)
+	}
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gopkg.in/gorp.v2"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/resource/rds"
+)
+
+type RiskTableService struct {
+	*Service
+	DB *gorp.DbMap
+}
+
+type RiskTableTxService struct {
+	*Service
+	DB *gorp.Transaction
+}
+
+// リスクテーブルを名前、バージョン順に取得する。
+func (s *RiskTableService) List(name *string, limit int, offset int) ([]*model.RiskTable, int64, error) {
+	if r, t, e := rds.ListRiskTables(s.DB, name, limit, offset); e != nil {
+		return nil, 0, C.DB_OPERATION_ERROR(e)
+	} else {
+		return r, t, nil
+	}
+}
+
+// リスクテーブルをIDから取得する。
+func (s *RiskTableService) Fetch(id int) (*model.RiskTable, error) {
+	return inquireRiskTable(s.DB, id)
+}
+
+// 病院で利用するリスクテーブルを取得する。設定されていない場合はnilを返す。
+func (s *RiskTableService) FetchForHospital(hospitalId int) (*model.RiskTable, error) {
+	if _, e := inquireHospital(s.DB, hospitalId); e != nil {
+		return nil, e
+	}
+
+	if r, e := rds.FetchHospitalRiskTable(s.DB, hospitalId); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else {
+		return r, nil
+	}
+}
+
+// リスクテーブルを登録する。同じ名前のテーブルがあれば、その次のバージョンとして登録する。
+func (s *RiskTableTxService) Create(name string, memo string, matrices *C.RiskMatrices) (*model.RiskTable, error) {
+	now := time.Now()
+
+	version := 0
+
+	if v, e := rds.FetchLatestRiskTableVersion(s.DB, name); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else {
+		version = v + 1
+	}
+
+	values, err := json.Marshal(matrices)
+
+	if err != nil {
+		return nil, C.NewBadRequestError(
+			"invalid_risk_matrices",
+			err.Error(),
+			map[string]interface{}{},
+		)
+	}
+
+	record := &model.RiskTable{
+		Name: name,
+		Version: version,
+		Matrices: model.JSON(values),
+		Memo: memo,
+		CreatedAt: now,
+		ModifiedAt: now,
+	}
+
+	if e := s.DB.Insert(record); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	return record, nil
+}
+
+// リスクテーブルのメモを更新する。判定表は変更できない。
+func (s *RiskTableTxService) Update(id int, memo string) (*model.RiskTable, error) {
+	record, err := inquireRiskTable(s.DB, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	record.Memo = memo
+	record.ModifiedAt = time.Now()
+
+	if _, e := s.DB.Update(record); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	return record, nil
+}
+
+// リスクテーブルを削除する。病院や診断から参照されているテーブルは削除できない。
+func (s *RiskTableTxService) Delete(id int) error {
+	record, err := inquireRiskTable(s.DB, id)
+
+	if err != nil {
+		return err
+	}
+
+	if b, e := rds.IsRiskTableReferred(s.DB, id); e != nil {
+		return C.DB_OPERATION_ERROR(e)
+	} else if b {
+		return C.NewBadRequestError(
+			"risk_table_in_use",
+			fmt.Sprintf("Risk table %d is referred by hospitals or diagnoses", id),
+			map[string]interface{}{},
+		)
+	}
+
+	if _, e := s.DB.Delete(record); e != nil {
+		return C.DB_OPERATION_ERROR(e)
+	}
+
+	return nil
+}
+
+// 病院で利用するリスクテーブルを設定する。nilを指定すると既定の判定表を利用するようになる。
+func (s *RiskTableTxService) SelectForHospital(hospitalId int, riskTableId *int) (*model.RiskTable, error) {
+	if _, e := inquireHospital(s.DB, hospitalId); e != nil {
+		return nil, e
+	}
+
+	if _, e := s.DB.Exec(`DELETE FROM hospital_risk_table WHERE hospital_id = $1`, hospitalId); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	if riskTableId == nil {
+		return nil, nil
+	}
+
+	record, err := inquireRiskTable(s.DB, *riskTableId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if e := s.DB.Insert(&model.HospitalRiskTable{
+		HospitalId: hospitalId,
+		RiskTableId: record.Id,
+		ModifiedAt: time.Now(),
+	}); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	}
+
+	return record, nil
+}
+
+func inquireRiskTable(db model.QueryExecutor, id int) (*model.RiskTable, error) {
+	if r, e := rds.FetchRiskTable(db, id); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else if r == nil {
+		return nil, C.NewNotFoundError(
+			"risk_table_not_found",
+			fmt.Sprintf("Risk table %d is not found", id),
+			map[string]interface{}{},
+		)
+	} else {
+		return r, nil
+	}
+}
+
+// 計測に適用するリスクテーブルと判定表を取得する。テーブルが設定されていない場合は既定の判定表を返す。
+func riskMatricesForMeasurement(db model.QueryExecutor, measurementId int) (*model.RiskTable, *C.RiskMatrices, error) {
+	if r, e := rds.FetchRiskTableForMeasurement(db, measurementId); e != nil {
+		return nil, nil, C.DB_OPERATION_ERROR(e)
+	} else if r == nil {
+		return nil, C.DefaultRiskMatrices, nil
+	} else {
+		matrices := &C.RiskMatrices{}
+
+		if e := json.Unmarshal(r.Matrices, matrices); e != nil {
+			return nil, nil, C.NewInternalServerError(
+				"invalid_risk_table",
+				fmt.Sprintf("Risk table %d has invalid matrices: %s", r.Id, e.Error()),
+				map[string]interface{}{},
+			)
+		} else if e := matrices.Validate(); e != nil {
+			return nil, nil, C.NewInternalServerError(
+				"invalid_risk_table",
+				fmt.Sprintf("Risk table %d has invalid matrices: %s", r.Id, e.Error()),
+				map[string]interface{}{},
+			)
+		}
+
+		return r, matrices, nil
+	}
+}
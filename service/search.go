@@ -0,0 +1,104 @@
+package service
+
+import (
+	"strings"
+
+	"gopkg.in/gorp.v2"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/lib/search"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/resource/rds"
+)
+
+type SearchService struct {
+	*Service
+	DB *gorp.DbMap
+}
+
+// 病院内の患者、計測、計測端末、診断を検索する。種類毎に最大limit件を取得する。
+//
+// 検索文字列は正規化した上で空白で区切り、全ての語を含むものを検索する。
+// 暗号化された患者の氏名は索引で絞り込んだ患者を復号して照合し、暗号化された計測コードは検索文字列との完全一致のみ検索する。
+func (s *SearchService) Search(
+	hospitalId int,
+	query string,
+	limit int,
+) (*model.SearchResult, error) {
+	terms := search.Terms(query)
+
+	if len(terms) == 0 {
+		return nil, C.NewBadRequestError(
+			"empty_search_query",
+			"Search query has no terms",
+			map[string]interface{}{},
+		)
+	}
+
+	keyring := newPHIKeyring(s.DB)
+
+	nameMatched := []int{}
+
+	// 暗号化された氏名は索引で候補を絞り込んでから復号して照合する。
+	grams := []string{}
+
+	for _, t := range terms {
+		grams = append(grams, search.TermGrams(t)...)
+	}
+
+	tokens, err := keyring.nameTokens(hospitalId, grams)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if r, e := rds.ListPatientsWithSealedName(s.DB, hospitalId, tokens); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else if e := keyring.revealPatients(r...); e != nil {
+		return nil, e
+	} else {
+		for _, p := range r {
+			if p.Name != nil && search.Match(*p.Name, terms) {
+				nameMatched = append(nameMatched, p.Id)
+			}
+		}
+	}
+
+	codes, _, err := keyring.codeCandidates(hospitalId, []string{strings.TrimSpace(query)})
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := &model.SearchResult{}
+
+	if r, e := rds.SearchPatients(s.DB, hospitalId, terms, nameMatched, limit); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else if e := keyring.revealPatients(r...); e != nil {
+		return nil, e
+	} else {
+		result.Patients = r
+	}
+
+	if r, e := rds.SearchMeasurementsByTerms(s.DB, hospitalId, terms, codes, limit); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else if e := keyring.revealEntities(r...); e != nil {
+		return nil, e
+	} else {
+		result.Measurements = r
+	}
+
+	if r, e := rds.SearchTerminals(s.DB, hospitalId, terms, limit); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else {
+		result.Terminals = r
+	}
+
+	if r, e := rds.SearchDiagnosisMemos(s.DB, hospitalId, terms, limit); e != nil {
+		return nil, C.DB_OPERATION_ERROR(e)
+	} else {
+		result.Diagnoses = r
+	}
+
+	return result, nil
+}
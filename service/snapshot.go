@@ -0,0 +1,145 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"gopkg.in/gorp.v2"
+
+	C "github.com/spiker/spiker-server/constant"
+	"github.com/spiker/spiker-server/lib"
+	"github.com/spiker/spiker-server/lib/strip"
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/resource/rds"
+	"github.com/spiker/spiker-server/resource/storage"
+)
+
+type SnapshotService struct {
+	*Service
+	DB      *gorp.DbMap
+	Influx  lib.InfluxDBClient
+	Storage storage.Storage
+}
+
+// 計測の高リスクな自動診断イベントのうち、スナップショットを持たないものについて、周辺のCTGをPNG画像として保存する。
+//
+// スナップショットはイベント期間の中央を中心とするSnapshotDurationの期間とする。ただしnowより後のデータは存在しないため、
+// 末尾がnowを超える場合はnowまでの期間とする。一度保存したスナップショットは、後にデータが修正されても更新しない。
+// 高リスクかは病院がアラートに用いるガイドラインで判定する。個々のイベントの描画、保存に失敗した場合は記録して次に進み、
+// スナップショットを持たないままのイベントは次回の実行で再び対象となる。
+func (s *SnapshotService) CaptureForMeasurements(
+	measurementIds []int,
+	now time.Time,
+	location *time.Location,
+) ([]*model.EventSnapshot, error) {
+	events := []*model.ComputedEvent{}
+
+	// 病院がアラートに用いるガイドライン毎に、そのアラート対象となるイベントを取得する。
+	for _, g := range C.Guidelines {
+		if r, e := rds.ListEventsWithoutSnapshot(s.DB, measurementIds, g, g.AlertLevel()); e != nil {
+			return nil, C.DB_OPERATION_ERROR(e)
+		} else {
+			events = append(events, r...)
+		}
+	}
+
+	report := &ReportService{s.Service, s.DB, s.Influx}
+
+	results := []*model.EventSnapshot{}
+
+	for _, ev := range events {
+		center := ev.RangeFrom.Add(ev.RangeUntil.Sub(ev.RangeFrom) / 2)
+
+		end := center.Add(C.SnapshotDuration / 2)
+		if end.After(now) {
+			end = now
+		}
+		begin := end.Add(-C.SnapshotDuration)
+
+		chart, err := report.collectChart(ev.MeasurementId, &begin, &end)
+
+		if err != nil {
+			log.Printf("Failed to collect chart for snapshot of ComputedEvent #%d: %v\n", ev.Id, err)
+			continue
+		}
+
+		// 画像のフォントはASCIIのみを描画できるため、ヘッダを差し替える。
+		chart.Title = fmt.Sprintf("CTG snapshot: event #%d", ev.Id)
+		chart.Headers = []string{fmt.Sprintf("Measurement #%d  Risk %s", ev.MeasurementId, riskLabel(ev.Risk))}
+		chart.Memo = ""
+		chart.CreatedAt = now
+		chart.Location = location
+
+		content, err := strip.RenderPNG(chart, C.SnapshotSpeed, C.SnapshotDotsPerMM)
+
+		if err != nil {
+			log.Printf("Failed to render snapshot of ComputedEvent #%d: %v\n", ev.Id, err)
+			continue
+		}
+
+		key := fmt.Sprintf("snapshots/%d/%d.png", ev.MeasurementId, ev.Id)
+
+		if e := s.Storage.Put(key, content); e != nil {
+			log.Printf("Failed to store snapshot of ComputedEvent #%d: %v\n", ev.Id, e)
+			continue
+		}
+
+		snapshot := &model.EventSnapshot{
+			ComputedEventId: ev.Id,
+			StorageKey: key,
+			RangeFrom: begin,
+			RangeUntil: end,
+			CreatedAt: now,
+		}
+
+		if e := s.DB.Insert(snapshot); e != nil {
+			return nil, C.DB_OPERATION_ERROR(e)
+		}
+
+		results = append(results, snapshot)
+	}
+
+	return results, nil
+}
+
+// 自動診断イベントにスナップショットの署名付きURLをセットする。ストレージが設定されていない場合は何もしない。
+func feedSnapshotUrls(
+	db model.QueryExecutor,
+	store storage.Storage,
+	entities ...*model.ComputedEventEntity,
+) error {
+	if store == nil || len(entities) == 0 {
+		return nil
+	}
+
+	ids := []int{}
+
+	for _, e := range entities {
+		ids = append(ids, e.ComputedEvent.Id)
+	}
+
+	snapshots, err := rds.ListEventSnapshots(db, ids)
+
+	if err != nil {
+		return C.DB_OPERATION_ERROR(err)
+	}
+
+	keyMap := map[int]string{}
+
+	for _, s := range snapshots {
+		keyMap[s.ComputedEventId] = s.StorageKey
+	}
+
+	for _, e := range entities {
+		if key, be := keyMap[e.ComputedEvent.Id]; be {
+			if url, err := store.PresignedUrl(key); err != nil {
+				return C.STORAGE_OPERATION_ERROR(err)
+			} else {
+				e.SnapshotUrl = &url
+			}
+		}
+	}
+
+	return nil
+}
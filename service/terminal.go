@@ -0,0 +1,25 @@
+package service
+
+import (
+	"gopkg.in/gorp.v2"
+
+	"github.com/spiker/spiker-server/model"
+	"github.com/spiker/spiker-server/resource/rds"
+)
+
+type TerminalService struct {
+	*Service
+	DB *gorp.DbMap
+}
+
+// 病院内の計測端末一覧を取得する。
+func (s *TerminalService) List(
+	hospitalId int,
+	paging *model.Paging,
+) ([]*model.MeasurementTerminal, *model.PageInfo, error) {
+	if r, p, e := rds.ListTerminals(s.DB, hospitalId, paging); e != nil {
+		return nil, nil, listError(e)
+	} else {
+		return r, p, nil
+	}
+}